@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"context"
+	"time"
+)
+
+// ThinkBudget Bot 最多使用回合計時器的比例，保留時間給牌桌處理與廣播
+const ThinkBudget = 0.5
+
+// maxThinkTime 單次決策的思考時間上限
+const maxThinkTime = 3 * time.Second
+
+// Act 在回合計時器內取得 Bot 的合法動作
+//
+// 決策在獨立 goroutine 中執行；超過思考時間仍未回傳時，改用安全動作
+// （可過牌則過牌，否則蓋牌），確保牌桌不會因 Bot 卡住。
+func Act(ctx context.Context, bot Bot, view *View, turnTimeout time.Duration) Action {
+	think := time.Duration(float64(turnTimeout) * ThinkBudget)
+	if think <= 0 || think > maxThinkTime {
+		think = maxThinkTime
+	}
+	ctx, cancel := context.WithTimeout(ctx, think)
+	defer cancel()
+
+	result := make(chan Action, 1)
+	go func() {
+		result <- bot.Decide(ctx, view)
+	}()
+
+	select {
+	case a := <-result:
+		return Normalize(view, a)
+	case <-ctx.Done():
+		return Normalize(view, passive(view, false))
+	}
+}
+
+// Normalize 將動作修正為符合目前牌局的合法動作
+func Normalize(view *View, a Action) Action {
+	switch a.Type {
+	case ActionFold:
+		if view.ToCall <= 0 {
+			return Action{Type: ActionCheck}
+		}
+		return a
+	case ActionCheck:
+		if view.ToCall > 0 {
+			return Action{Type: ActionFold}
+		}
+		return a
+	case ActionCall:
+		if view.ToCall <= 0 {
+			return Action{Type: ActionCheck}
+		}
+		if view.ToCall >= view.Stack {
+			return Action{Type: ActionAllIn, Amount: view.Stack}
+		}
+		return Action{Type: ActionCall, Amount: view.ToCall}
+	case ActionRaise:
+		amount := a.Amount
+		if amount < view.MinRaise {
+			amount = view.MinRaise
+		}
+		if amount >= view.Stack {
+			if view.Stack <= view.ToCall {
+				return Normalize(view, Action{Type: ActionCall})
+			}
+			return Action{Type: ActionAllIn, Amount: view.Stack}
+		}
+		return Action{Type: ActionRaise, Amount: amount}
+	case ActionAllIn:
+		if view.Stack <= 0 {
+			return Normalize(view, Action{Type: ActionCheck})
+		}
+		return Action{Type: ActionAllIn, Amount: view.Stack}
+	}
+	return Normalize(view, Action{Type: ActionFold})
+}
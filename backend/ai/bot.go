@@ -0,0 +1,182 @@
+// Package ai 德州撲克／梭哈 AI 玩家
+//
+// Bot 介面只依據 View（該座位可見的牌局資訊）做出決策，不接觸資料庫或錢包，
+// 由牌桌服務負責呼叫、計時與結算。每個 Bot 實例僅供單一牌桌使用。
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"nexus-gaming-backend/engine/cards"
+)
+
+// Difficulty AI 難度（對應 game_rooms.ai_difficulty）
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyMedium Difficulty = "medium"
+	DifficultyHard   Difficulty = "hard"
+	DifficultyExpert Difficulty = "expert"
+)
+
+// 支援的遊戲類型（對應 games.game_type）
+const (
+	GameTexasHoldem = "texas_holdem"
+	GameStudPoker   = "stud_poker"
+)
+
+// ActionType 動作類型
+type ActionType string
+
+const (
+	ActionFold  ActionType = "fold"
+	ActionCheck ActionType = "check"
+	ActionCall  ActionType = "call"
+	ActionRaise ActionType = "raise" // 無人下注時即為 bet
+	ActionAllIn ActionType = "all_in"
+)
+
+// Action 決策結果
+type Action struct {
+	Type   ActionType `json:"type"`
+	Amount float64    `json:"amount,omitempty"` // 本次投入的籌碼（call／raise／all_in）
+}
+
+// Opponent 對手可見資訊
+type Opponent struct {
+	Seat    int          `json:"seat"`
+	Stack   float64      `json:"stack"`
+	UpCards []cards.Card `json:"up_cards,omitempty"` // 梭哈明牌
+	Folded  bool         `json:"folded"`
+	AllIn   bool         `json:"all_in"`
+}
+
+// View 輪到 Bot 行動時的牌局資訊（僅包含該座位可見的內容）
+type View struct {
+	GameType  string       `json:"game_type"`
+	Round     int          `json:"round"`      // 下注輪（德州：0=翻牌前 ... 3=河牌；梭哈：0=第二張 ... 3=第五張）
+	Seat      int          `json:"seat"`       // 自己的座位
+	HoleCards []cards.Card `json:"hole_cards"` // 自己的暗牌
+	UpCards   []cards.Card `json:"up_cards"`   // 自己的明牌（梭哈）
+	Board     []cards.Card `json:"board"`      // 公共牌（德州）
+	Opponents []Opponent   `json:"opponents"`
+	Pot       float64      `json:"pot"`       // 目前底池（含本輪已下注）
+	ToCall    float64      `json:"to_call"`   // 跟注所需金額
+	MinRaise  float64      `json:"min_raise"` // 最小加注時需投入的金額（含跟注部分）
+	Stack     float64      `json:"stack"`     // 自己剩餘籌碼
+	BigBlind  float64      `json:"big_blind"` // 大盲（梭哈為底注）
+	Position  int          `json:"position"`  // 本輪行動順序（0 為最先行動）
+	Players   int          `json:"players"`   // 本手仍在牌局中的人數（含自己）
+}
+
+// ActiveOpponents 尚未蓋牌的對手數
+func (v *View) ActiveOpponents() int {
+	n := 0
+	for _, o := range v.Opponents {
+		if !o.Folded {
+			n++
+		}
+	}
+	return n
+}
+
+// PotOdds 跟注所需的最低勝率
+func (v *View) PotOdds() float64 {
+	if v.ToCall <= 0 {
+		return 0
+	}
+	return v.ToCall / (v.Pot + v.ToCall)
+}
+
+// Params 策略參數（game_configs 的 ai_strategy）
+type Params struct {
+	Aggression float64 `json:"aggression"` // 0~1，越高越常下注／加注
+	BluffRate  float64 `json:"bluff_rate"` // 0~1，無牌力時詐唬的機率
+}
+
+// DefaultParams 預設策略參數（與 game_configs 預設值一致）
+var DefaultParams = Params{Aggression: 0.6, BluffRate: 0.15}
+
+// ParseParams 解析 ai_strategy 設定，缺漏欄位使用預設值
+func ParseParams(raw []byte) (Params, error) {
+	p := DefaultParams
+	if len(raw) == 0 {
+		return p, nil
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return DefaultParams, fmt.Errorf("ai_strategy 格式錯誤: %v", err)
+	}
+	p.Aggression = clamp(p.Aggression, 0, 1)
+	p.BluffRate = clamp(p.BluffRate, 0, 1)
+	return p, nil
+}
+
+// Bot AI 玩家介面
+type Bot interface {
+	// Strategy 策略代碼
+	Strategy() string
+	// Difficulty 難度
+	Difficulty() Difficulty
+	// Decide 依可見資訊做出決策；必須在 ctx 結束前回傳
+	Decide(ctx context.Context, view *View) Action
+}
+
+// Factory Bot 建構函式
+type Factory func(params Params, seed int64) Bot
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[Difficulty]Factory{}
+)
+
+// Register 註冊（或取代）某個難度使用的 Bot 實作
+func Register(d Difficulty, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[d] = f
+}
+
+// New 依難度建立 Bot
+func New(d Difficulty, params Params, seed int64) (Bot, error) {
+	registryMu.RLock()
+	f, ok := registry[d]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支援的 AI 難度: %s", d)
+	}
+	return f(params, seed), nil
+}
+
+// Difficulties 已註冊的難度
+func Difficulties() []Difficulty {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	list := make([]Difficulty, 0, len(registry))
+	for d := range registry {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+func init() {
+	Register(DifficultyEasy, NewRuleBot)
+	Register(DifficultyMedium, NewPotOddsBot)
+	Register(DifficultyHard, NewEquityBot)
+	Register(DifficultyExpert, NewExpertBot)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"math/rand"
+
+	"nexus-gaming-backend/engine/cards"
+)
+
+// 各遊戲最終手牌張數
+const (
+	holdemBoardSize = 5
+	studHandSize    = 5
+)
+
+// Equity 以蒙地卡羅模擬估算對所有未蓋牌對手的勝率（平手依人數分配）
+//
+// 對手的暗牌與未發出的牌皆從剩餘牌堆隨機抽取；ctx 結束時提早停止並以已完成的
+// 樣本計算，回傳值為 (勝率, 實際模擬次數)。
+func Equity(ctx context.Context, view *View, iterations int, rnd *rand.Rand) (float64, int) {
+	own := append(append([]cards.Card{}, view.HoleCards...), view.UpCards...)
+	dead := cards.MaskOf(own...) | cards.MaskOf(view.Board...)
+	var opponents []Opponent
+	for _, o := range view.Opponents {
+		dead |= cards.MaskOf(o.UpCards...)
+		if !o.Folded {
+			opponents = append(opponents, o)
+		}
+	}
+	if len(opponents) == 0 {
+		return 1, 0
+	}
+
+	deck := dead.Remaining()
+	stud := view.GameType == GameStudPoker
+
+	// 計算每次模擬需要抽取的張數
+	need := 0
+	if stud {
+		need += studHandSize - len(own)
+		for _, o := range opponents {
+			need += studHandSize - len(o.UpCards)
+		}
+	} else {
+		need += holdemBoardSize - len(view.Board) + 2*len(opponents)
+	}
+	if need > len(deck) || need < 0 {
+		return 0, 0
+	}
+
+	hand := make([]cards.Card, 0, 7)
+	board := make([]cards.Card, 0, holdemBoardSize)
+	total, done := 0.0, 0
+	for i := 0; i < iterations; i++ {
+		if i&63 == 63 && ctx.Err() != nil {
+			break
+		}
+
+		// 部分 Fisher-Yates：只洗出需要的前 need 張
+		for j := 0; j < need; j++ {
+			k := j + rnd.Intn(len(deck)-j)
+			deck[j], deck[k] = deck[k], deck[j]
+		}
+		drawn := deck[:need]
+
+		var mine cards.HandValue
+		best, ties := cards.HandValue(0), 0
+		if stud {
+			hand = append(append(hand[:0], own...), drawn[:studHandSize-len(own)]...)
+			mine = cards.Evaluate(hand)
+			drawn = drawn[studHandSize-len(own):]
+			for _, o := range opponents {
+				n := studHandSize - len(o.UpCards)
+				hand = append(append(hand[:0], o.UpCards...), drawn[:n]...)
+				drawn = drawn[n:]
+				best, ties = compareBest(cards.Evaluate(hand), best, ties)
+			}
+		} else {
+			n := holdemBoardSize - len(view.Board)
+			board = append(append(board[:0], view.Board...), drawn[:n]...)
+			drawn = drawn[n:]
+			hand = append(append(hand[:0], view.HoleCards...), board...)
+			mine = cards.Evaluate(hand)
+			for range opponents {
+				hand = append(append(hand[:0], drawn[0], drawn[1]), board...)
+				drawn = drawn[2:]
+				best, ties = compareBest(cards.Evaluate(hand), best, ties)
+			}
+		}
+
+		switch {
+		case mine > best:
+			total++
+		case mine == best:
+			total += 1 / float64(ties+1)
+		}
+		done++
+	}
+
+	if done == 0 {
+		return 0, 0
+	}
+	return total / float64(done), done
+}
+
+// compareBest 更新對手中的最佳牌力與同分人數
+func compareBest(v, best cards.HandValue, ties int) (cards.HandValue, int) {
+	switch {
+	case v > best:
+		return v, 1
+	case v == best:
+		return best, ties + 1
+	}
+	return best, ties
+}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// 策略代碼
+const (
+	StrategyRuleBased = "rule_based"     // 依牌型規則行動
+	StrategyPotOdds   = "rule_pot_odds"  // 規則估計勝率 + 底池賠率
+	StrategyEquity    = "mc_equity"      // 蒙地卡羅勝率 + 底池賠率
+	StrategyExpert    = "mc_equity_plus" // 蒙地卡羅勝率 + 底池／隱含賠率 + 位置與下注尺度
+)
+
+// 蒙地卡羅模擬次數
+const (
+	equityIterations = 600
+	expertIterations = 3000
+)
+
+// base 各策略共用的狀態（亂數來源非並行安全，以互斥鎖保護）
+type base struct {
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	params Params
+}
+
+func newBase(params Params, seed int64) base {
+	return base{rnd: rand.New(rand.NewSource(seed)), params: params}
+}
+
+// chance 以機率 p 回傳 true
+func (b *base) chance(p float64) bool {
+	return b.rnd.Float64() < p
+}
+
+// betSize 依底池比例計算下注／加注金額
+func betSize(view *View, potFraction float64) float64 {
+	amount := view.ToCall + (view.Pot+view.ToCall)*potFraction
+	if amount < view.MinRaise {
+		amount = view.MinRaise
+	}
+	if view.BigBlind > 0 {
+		amount = math.Ceil(amount/view.BigBlind) * view.BigBlind
+	}
+	return amount
+}
+
+func raise(view *View, potFraction float64) Action {
+	return Action{Type: ActionRaise, Amount: betSize(view, potFraction)}
+}
+
+// passive 不加注時的動作：可過牌就過牌，否則依 call 決定跟注或蓋牌
+func passive(view *View, call bool) Action {
+	if view.ToCall <= 0 {
+		return Action{Type: ActionCheck}
+	}
+	if call {
+		return Action{Type: ActionCall, Amount: view.ToCall}
+	}
+	return Action{Type: ActionFold}
+}
+
+// RuleBot 初級：只看自己的牌型，依固定門檻行動
+type RuleBot struct{ base }
+
+// NewRuleBot 建立初級 Bot
+func NewRuleBot(params Params, seed int64) Bot {
+	return &RuleBot{newBase(params, seed)}
+}
+
+func (b *RuleBot) Strategy() string       { return StrategyRuleBased }
+func (b *RuleBot) Difficulty() Difficulty { return DifficultyEasy }
+
+// Decide 強牌加注、中等牌跟注、弱牌只在跟注金額很小時跟注
+func (b *RuleBot) Decide(ctx context.Context, view *View) Action {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := madeStrength(view)
+	if view.GameType == GameTexasHoldem && view.Round == 0 {
+		s = preflopStrength(view.HoleCards)
+	}
+
+	switch {
+	case s >= 0.7 && b.chance(0.5+0.5*b.params.Aggression):
+		return raise(view, 0.5)
+	case s >= 0.45:
+		return passive(view, true)
+	case s >= 0.25:
+		return passive(view, view.ToCall <= 2*view.BigBlind)
+	case view.ToCall <= 0 && b.chance(b.params.BluffRate*0.3):
+		return raise(view, 0.5)
+	}
+	return passive(view, false)
+}
+
+// PotOddsBot 中級：以規則估計勝率（含聽牌補牌），與底池賠率比較
+type PotOddsBot struct{ base }
+
+// NewPotOddsBot 建立中級 Bot
+func NewPotOddsBot(params Params, seed int64) Bot {
+	return &PotOddsBot{newBase(params, seed)}
+}
+
+func (b *PotOddsBot) Strategy() string       { return StrategyPotOdds }
+func (b *PotOddsBot) Difficulty() Difficulty { return DifficultyMedium }
+
+// Decide 勝率高於加注門檻時加注，高於底池賠率時跟注
+func (b *PotOddsBot) Decide(ctx context.Context, view *View) Action {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	eq := heuristicEquity(view)
+	raiseAt := 0.6 + (1-b.params.Aggression)*0.2
+
+	if eq >= raiseAt {
+		return raise(view, 0.5+0.25*b.params.Aggression)
+	}
+	if view.ToCall <= 0 && view.Position >= view.Players-1 && b.chance(b.params.BluffRate) {
+		return raise(view, 0.5)
+	}
+	return passive(view, eq >= view.PotOdds())
+}
+
+// EquityBot 高級：蒙地卡羅模擬勝率，與底池賠率比較
+type EquityBot struct{ base }
+
+// NewEquityBot 建立高級 Bot
+func NewEquityBot(params Params, seed int64) Bot {
+	return &EquityBot{newBase(params, seed)}
+}
+
+func (b *EquityBot) Strategy() string       { return StrategyEquity }
+func (b *EquityBot) Difficulty() Difficulty { return DifficultyHard }
+
+// Decide 依模擬勝率決定下注、跟注或蓋牌
+func (b *EquityBot) Decide(ctx context.Context, view *View) Action {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	eq, n := Equity(ctx, view, equityIterations, b.rnd)
+	if n == 0 {
+		eq = heuristicEquity(view)
+	}
+
+	fair := 1 / float64(view.ActiveOpponents()+1)
+	raiseAt := fair + (1-fair)*(0.45-0.2*b.params.Aggression)
+
+	if eq >= raiseAt {
+		return raise(view, 0.5+0.5*(eq-raiseAt))
+	}
+	if view.ToCall <= 0 && b.chance(b.params.BluffRate) {
+		return raise(view, 0.5)
+	}
+	return passive(view, eq >= view.PotOdds())
+}
+
+// ExpertBot 專家：模擬勝率 + 底池與隱含賠率、位置、籌碼深度與混合策略
+type ExpertBot struct{ base }
+
+// NewExpertBot 建立專家 Bot
+func NewExpertBot(params Params, seed int64) Bot {
+	return &ExpertBot{newBase(params, seed)}
+}
+
+func (b *ExpertBot) Strategy() string       { return StrategyExpert }
+func (b *ExpertBot) Difficulty() Difficulty { return DifficultyExpert }
+
+// Decide 以勝率決定價值下注尺度，聽牌時考慮隱含賠率與半詐唬
+func (b *ExpertBot) Decide(ctx context.Context, view *View) Action {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	eq, n := Equity(ctx, view, expertIterations, b.rnd)
+	if n == 0 {
+		eq = heuristicEquity(view)
+	}
+
+	opponents := view.ActiveOpponents()
+	fair := 1 / float64(opponents+1)
+	inPosition := view.Position >= view.Players-1
+	toCome := cardsToCome(view)
+	draw := outs(view)
+
+	// 隱含賠率：仍有牌要發且聽牌時，預期後續可再贏得部分籌碼
+	required := view.PotOdds()
+	if toCome > 0 && draw >= 8 && view.ToCall > 0 {
+		implied := view.Pot * 0.5
+		if implied > view.Stack {
+			implied = view.Stack
+		}
+		required = view.ToCall / (view.Pot + view.ToCall + implied)
+	}
+
+	// 籌碼承諾：跟注金額已佔剩餘籌碼大半時，只要有賠率就全下
+	if view.ToCall > 0 && view.ToCall >= view.Stack*0.5 && eq >= required {
+		return Action{Type: ActionAllIn, Amount: view.Stack}
+	}
+
+	// 價值下注：勝率越高下注越大，邊際情況以機率混合避免被讀牌
+	valueAt := fair + (1-fair)*(0.35-0.15*b.params.Aggression)
+	if inPosition {
+		valueAt -= 0.03
+	}
+	if eq >= valueAt {
+		margin := (eq - valueAt) / (1 - valueAt + 1e-9)
+		if b.chance(0.6 + 0.4*margin) {
+			return raise(view, 0.33+0.67*margin)
+		}
+		return passive(view, true)
+	}
+
+	// 半詐唬：強聽牌在有位置或對手少時主動加注
+	if toCome > 0 && draw >= 8 && (inPosition || opponents == 1) && b.chance(b.params.Aggression*0.6) {
+		return raise(view, 0.6)
+	}
+
+	// 純詐唬：無人下注且對手少、位置有利時
+	if view.ToCall <= 0 && opponents <= 2 && inPosition && b.chance(b.params.BluffRate) {
+		return raise(view, 0.5)
+	}
+
+	return passive(view, eq >= required)
+}
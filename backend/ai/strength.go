@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"math/bits"
+
+	"nexus-gaming-backend/engine/cards"
+)
+
+// ownCards 自己的所有牌（德州含公共牌）
+func ownCards(view *View) []cards.Card {
+	list := make([]cards.Card, 0, 7)
+	list = append(list, view.HoleCards...)
+	list = append(list, view.UpCards...)
+	return append(list, view.Board...)
+}
+
+// cardsToCome 尚未發出、會影響自己牌型的張數
+func cardsToCome(view *View) int {
+	if view.GameType == GameStudPoker {
+		return studHandSize - len(view.HoleCards) - len(view.UpCards)
+	}
+	return holdemBoardSize - len(view.Board)
+}
+
+// preflopStrength 德州翻牌前起手牌強度（Chen 公式正規化為 0~1）
+func preflopStrength(hole []cards.Card) float64 {
+	if len(hole) != 2 {
+		return 0
+	}
+	hi, lo := hole[0], hole[1]
+	if lo.Rank() > hi.Rank() {
+		hi, lo = lo, hi
+	}
+
+	score := chenPoints(hi.Rank())
+	gap := hi.Rank() - lo.Rank() - 1
+	if gap < 0 {
+		score *= 2
+		if score < 5 {
+			score = 5
+		}
+	} else {
+		switch {
+		case gap == 1:
+			score--
+		case gap == 2:
+			score -= 2
+		case gap == 3:
+			score -= 4
+		case gap >= 4:
+			score -= 5
+		}
+		if gap <= 1 && hi.Rank() < 10 {
+			score++
+		}
+	}
+	if hi.Suit() == lo.Suit() {
+		score += 2
+	}
+	return clamp(score/20, 0, 1)
+}
+
+// chenPoints Chen 公式高牌分數
+func chenPoints(rank int) float64 {
+	switch rank {
+	case 12:
+		return 10
+	case 11:
+		return 8
+	case 10:
+		return 7
+	case 9:
+		return 6
+	}
+	return float64(rank+2) / 2
+}
+
+// madeStrength 目前成牌強度（0~1 的粗略估計）
+func madeStrength(view *View) float64 {
+	own := ownCards(view)
+	v := cards.Evaluate(own)
+	top := float64((v>>16)&0xf) / 13
+
+	var s float64
+	switch v.Category() {
+	case cards.HighCard:
+		s = 0.05 + 0.15*top
+	case cards.OnePair:
+		s = 0.3 + 0.15*top
+	case cards.TwoPair:
+		s = 0.6 + 0.05*top
+	case cards.ThreeOfAKind:
+		s = 0.72 + 0.05*top
+	case cards.Straight:
+		s = 0.8
+	case cards.Flush:
+		s = 0.85
+	case cards.FullHouse:
+		s = 0.92
+	case cards.FourOfAKind:
+		s = 0.97
+	default:
+		s = 1
+	}
+
+	// 德州：牌型完全來自公共牌時，實際牌力大幅降低
+	if len(view.Board) >= 3 && cards.Evaluate(view.Board).Category() >= v.Category() {
+		s *= 0.5
+	}
+	return s
+}
+
+// outs 估算聽牌的補牌數（同花聽牌 9 張、兩頭順 8 張、卡順 4 張）
+func outs(view *View) int {
+	if cardsToCome(view) <= 0 {
+		return 0
+	}
+	own := ownCards(view)
+	if cards.Evaluate(own).Category() >= cards.Straight {
+		return 0
+	}
+
+	// bit 0 為 A（當 1 使用），bit 1..13 依序為 2..A
+	var suits [4]int
+	var ranks uint16
+	for _, c := range own {
+		suits[c.Suit()]++
+		ranks |= 1 << uint(c.Rank()+1)
+	}
+	if ranks&(1<<13) != 0 {
+		ranks |= 1
+	}
+
+	n := 0
+	for _, cnt := range suits {
+		if cnt == 4 {
+			n = 9
+		}
+	}
+
+	straight := 0
+	for low := 0; low <= 9; low++ {
+		window := (ranks >> uint(low)) & 0x1f
+		if bits.OnesCount16(window) == 4 {
+			if window == 0x0f || window == 0x1e {
+				straight = 8
+				break
+			}
+			straight = 4
+		}
+	}
+	if n > 0 && straight > 0 {
+		return n + straight - 2 // 扣除重複計算的同花順補牌
+	}
+	return n + straight
+}
+
+// drawEquity 以「2 與 4 法則」換算聽牌勝率
+func drawEquity(view *View) float64 {
+	o := outs(view)
+	if o == 0 {
+		return 0
+	}
+	per := 0.02
+	if cardsToCome(view) >= 2 {
+		per = 0.04
+	}
+	return clamp(float64(o)*per, 0, 0.6)
+}
+
+// heuristicEquity 不經模擬的勝率估計（成牌強度依對手數折減，並考慮聽牌）
+func heuristicEquity(view *View) float64 {
+	s := madeStrength(view)
+	if view.GameType == GameTexasHoldem && view.Round == 0 {
+		s = preflopStrength(view.HoleCards)
+	}
+	opp := view.ActiveOpponents()
+	eq := s
+	for i := 1; i < opp; i++ {
+		eq *= 0.5 + 0.5*s
+	}
+	if d := drawEquity(view); d > eq {
+		eq = d
+	}
+	return eq
+}
@@ -0,0 +1,69 @@
+// slotsrtp 離線計算老虎機設定的理論 RTP
+//
+// 用法：
+//
+//	go run ./cmd/slotsrtp -config reels.json -target 0.96 -tolerance 0.005
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"nexus-gaming-backend/engine/slots"
+)
+
+func main() {
+	path := flag.String("config", "", "老虎機設定 JSON 檔案（game_configs.config_value 內容）")
+	target := flag.Float64("target", 0, "目標 RTP（如 0.965，0 表示不比對）")
+	tolerance := flag.Float64("tolerance", 0.005, "容許差距")
+	maxCombos := flag.Int64("max-combos", slots.DefaultMaxCombinations, "窮舉組合數上限")
+	spins := flag.Int64("spins", slots.DefaultSimulationSpins, "蒙地卡羅模擬次數")
+	seed := flag.Int64("seed", 0, "模擬亂數種子")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		log.Fatalf("讀取設定檔失敗: %v", err)
+	}
+	cfg, err := slots.ParseConfig(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	machine, err := slots.NewMachine(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := machine.CalculateRTP(slots.RTPOptions{
+		MaxCombinations: *maxCombos,
+		Spins:           *spins,
+		Seed:            *seed,
+	})
+	if err != nil {
+		log.Fatalf("RTP 計算失敗: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+
+	if *target > 0 {
+		if err := slots.CheckRTP(report, *target, *tolerance); err != nil {
+			var deviation *slots.RTPDeviationError
+			if errors.As(err, &deviation) {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "RTP 在容許範圍內（目標 %.4f ± %.4f）\n", *target, *tolerance)
+	}
+}
@@ -12,12 +12,19 @@ import (
 
 // Config 應用程式配置結構
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Redis    RedisConfig    `json:"redis"`
-	JWT      JWTConfig      `json:"jwt"`
-	Security SecurityConfig `json:"security"`
-	Game     GameConfig     `json:"game"`
+	Server         ServerConfig         `json:"server"`
+	Database       DatabaseConfig       `json:"database"`
+	Redis          RedisConfig          `json:"redis"`
+	JWT            JWTConfig            `json:"jwt"`
+	Security       SecurityConfig       `json:"security"`
+	Game           GameConfig           `json:"game"`
+	Wallet         WalletConfig         `json:"wallet"`
+	Payment        PaymentConfig        `json:"payment"`
+	Review         ReviewConfig         `json:"review"`
+	Reconciliation ReconciliationConfig `json:"reconciliation"`
+	Bonus          BonusConfig          `json:"bonus"`
+	Vault          VaultConfig          `json:"vault"`
+	Agent          AgentConfig          `json:"agent"`
 }
 
 // ServerConfig 伺服器配置
@@ -70,12 +77,78 @@ type SecurityConfig struct {
 
 // GameConfig 遊戲配置
 type GameConfig struct {
-	DefaultCurrency       string  `json:"default_currency"`
-	MinBetAmount          float64 `json:"min_bet_amount"`
-	MaxBetAmount          float64 `json:"max_bet_amount"`
-	HouseEdge             float64 `json:"house_edge"`
-	MaxPlayersPerTable    int     `json:"max_players_per_table"`
-	SessionTimeoutMinutes int     `json:"session_timeout_minutes"`
+	DefaultCurrency       string        `json:"default_currency"`
+	MinBetAmount          float64       `json:"min_bet_amount"`
+	MaxBetAmount          float64       `json:"max_bet_amount"`
+	HouseEdge             float64       `json:"house_edge"`
+	MaxPlayersPerTable    int           `json:"max_players_per_table"`
+	SessionTimeoutMinutes int           `json:"session_timeout_minutes"`
+	SlotsRTPTolerance     float64       `json:"slots_rtp_tolerance"`   // 老虎機計算 RTP 與 games.rtp_rate 的容許差距
+	SlotsRTPMaxCombos     int           `json:"slots_rtp_max_combos"`  // 老虎機 RTP 窮舉組合數上限
+	SlotsRTPSimSpins      int           `json:"slots_rtp_sim_spins"`   // 老虎機 RTP 蒙地卡羅模擬次數（API 內同步驗證，上限 200,000）
+	AIEnabled             bool          `json:"ai_enabled"`            // 是否允許 AI 玩家補位（房間仍需啟用 ai_enabled）
+	TurnTimeout           time.Duration `json:"turn_timeout"`          // 每位玩家的行動時間
+	PracticeBalance       float64       `json:"practice_balance"`      // 練習場遊戲幣的起始與自動補充額度
+	PracticeReplenishAt   float64       `json:"practice_replenish_at"` // 練習場遊戲幣低於此值時自動補充
+}
+
+// WalletConfig 錢包配置
+type WalletConfig struct {
+	WithdrawalHoldTTL time.Duration `json:"withdrawal_hold_ttl"` // 提領凍結的有效期限，逾期未審核即釋放並取消申請
+	HoldSweepInterval time.Duration `json:"hold_sweep_interval"` // 逾期凍結檢查間隔
+}
+
+// PaymentConfig 支付閘道配置
+type PaymentConfig struct {
+	FakeGatewayEnabled   bool          `json:"fake_gateway_enabled"`   // 是否啟用內建模擬閘道（本機與測試環境）
+	FakeGatewaySecret    string        `json:"-"`                      // 模擬閘道回呼簽章金鑰（無預設值，未設定時不註冊模擬閘道）
+	WebhookTolerance     time.Duration `json:"webhook_tolerance"`      // 回呼時間戳容許誤差（防止重送舊回呼）
+	DepositExpireAfter   time.Duration `json:"deposit_expire_after"`   // 儲值訂單付款期限
+	DepositSweepInterval time.Duration `json:"deposit_sweep_interval"` // 逾期儲值訂單檢查間隔
+	PayoutProvider       string        `json:"payout_provider"`        // 提領出款使用的支付提供商（空字串表示不自動出款）
+}
+
+// ReviewConfig 提領審核規則配置（金額以預設幣別計）
+type ReviewConfig struct {
+	AutoApproveMax      float64       `json:"auto_approve_max"`      // 自動核准的金額上限
+	SecondApprovalAbove float64       `json:"second_approval_above"` // 超過此金額需兩位審核員核准
+	MinAccountAge       time.Duration `json:"min_account_age"`       // 自動核准所需的帳號年資
+	MinVerification     string        `json:"min_verification"`      // 自動核准所需的驗證等級（none, email, phone, identity）
+	CycleWindow         time.Duration `json:"cycle_window"`          // 檢查儲值後未遊戲即提領的期間
+	CycleMinTurnover    float64       `json:"cycle_min_turnover"`    // 期間內下注額須達儲值額的倍數
+	RiskReviewScore     float64       `json:"risk_review_score"`     // 風險分數達此值轉入風險審核
+}
+
+// ReconciliationConfig 對帳結帳配置
+type ReconciliationConfig struct {
+	Interval      time.Duration `json:"interval"`       // 自動結帳檢查間隔
+	MaxMismatches int           `json:"max_mismatches"` // 每份報表保存的差異明細上限
+}
+
+// BonusConfig 紅利引擎配置
+type BonusConfig struct {
+	SweepInterval       time.Duration `json:"sweep_interval"`        // 逾期紅利與會員等級紅利的檢查間隔
+	ConsumePolicy       string        `json:"consume_policy"`        // 活動未指定時的紅利動用順序（bonus_first, cash_first）
+	ForfeitOnWithdrawal bool          `json:"forfeit_on_withdrawal"` // 流水未完成即提領時沒收紅利
+}
+
+// VaultConfig 敏感資料信封加密配置
+type VaultConfig struct {
+	MasterKeys     string `json:"-"`          // 主金鑰列表（ID:base64 以逗號分隔，輪替期間保留舊金鑰）
+	ActiveKey      string `json:"active_key"` // 加密與重新包裝使用的主金鑰ID
+	FingerprintKey string `json:"-"`          // 比對相同帳號的指紋金鑰（不可輪替）
+	RotateBatch    int    `json:"rotate_batch"`
+}
+
+// AgentConfig 代理商與經銷商管理配置
+type AgentConfig struct {
+	ContractSweepInterval time.Duration `json:"contract_sweep_interval"` // 合約到期停用的檢查間隔
+	MaxDepth              int           `json:"max_depth"`               // 代理商層級上限（總公司為 0）
+	OverrideLevels        int           `json:"override_levels"`         // 上級代理商差額佣金的層數上限（0 表示不限）
+	SettlementInterval    time.Duration `json:"settlement_interval"`     // 結算期間結帳的檢查間隔
+	PerformanceInterval   time.Duration `json:"performance_interval"`    // 每日業績統計的重建間隔（每次重建前一日與當日）
+	CreditWarningRatio    float64       `json:"credit_warning_ratio"`    // 信用額度使用率達此比例時發出警告
+	CreditCriticalRatio   float64       `json:"credit_critical_ratio"`   // 信用額度使用率達此比例時發出嚴重警示
 }
 
 // 全域配置實例
@@ -134,6 +207,58 @@ func LoadConfig() (*Config, error) {
 			HouseEdge:             getFloatEnv("HOUSE_EDGE", 0.025),
 			MaxPlayersPerTable:    getIntEnv("MAX_PLAYERS_PER_TABLE", 6),
 			SessionTimeoutMinutes: getIntEnv("GAME_SESSION_TIMEOUT", 30),
+			SlotsRTPTolerance:     getFloatEnv("SLOTS_RTP_TOLERANCE", 0.005),
+			SlotsRTPMaxCombos:     getIntEnv("SLOTS_RTP_MAX_COMBOS", 5000000),
+			SlotsRTPSimSpins:      getIntEnv("SLOTS_RTP_SIM_SPINS", 200000),
+			AIEnabled:             getEnv("AI_ENABLED", "true") == "true",
+			TurnTimeout:           getDurationEnv("GAME_TURN_TIMEOUT", 20*time.Second),
+			PracticeBalance:       getFloatEnv("PRACTICE_BALANCE", 10000),
+			PracticeReplenishAt:   getFloatEnv("PRACTICE_REPLENISH_AT", 100),
+		},
+		Wallet: WalletConfig{
+			WithdrawalHoldTTL: getDurationEnv("WITHDRAWAL_HOLD_TTL", 72*time.Hour),
+			HoldSweepInterval: getDurationEnv("HOLD_SWEEP_INTERVAL", time.Minute),
+		},
+		Payment: PaymentConfig{
+			FakeGatewayEnabled:   getEnv("PAYMENT_FAKE_GATEWAY_ENABLED", "false") == "true",
+			FakeGatewaySecret:    getEnv("PAYMENT_FAKE_GATEWAY_SECRET", ""),
+			WebhookTolerance:     getDurationEnv("PAYMENT_WEBHOOK_TOLERANCE", 5*time.Minute),
+			DepositExpireAfter:   getDurationEnv("DEPOSIT_EXPIRE_AFTER", 30*time.Minute),
+			DepositSweepInterval: getDurationEnv("DEPOSIT_SWEEP_INTERVAL", time.Minute),
+			PayoutProvider:       getEnv("PAYOUT_PROVIDER", ""),
+		},
+		Review: ReviewConfig{
+			AutoApproveMax:      getFloatEnv("WITHDRAWAL_AUTO_APPROVE_MAX", 10000),
+			SecondApprovalAbove: getFloatEnv("WITHDRAWAL_SECOND_APPROVAL_ABOVE", 50000),
+			MinAccountAge:       getDurationEnv("WITHDRAWAL_MIN_ACCOUNT_AGE", 72*time.Hour),
+			MinVerification:     getEnv("WITHDRAWAL_MIN_VERIFICATION", "phone"),
+			CycleWindow:         getDurationEnv("WITHDRAWAL_CYCLE_WINDOW", 24*time.Hour),
+			CycleMinTurnover:    getFloatEnv("WITHDRAWAL_CYCLE_MIN_TURNOVER", 1),
+			RiskReviewScore:     getFloatEnv("WITHDRAWAL_RISK_REVIEW_SCORE", 60),
+		},
+		Reconciliation: ReconciliationConfig{
+			Interval:      getDurationEnv("RECONCILIATION_INTERVAL", time.Hour),
+			MaxMismatches: getIntEnv("RECONCILIATION_MAX_MISMATCHES", 500),
+		},
+		Bonus: BonusConfig{
+			SweepInterval:       getDurationEnv("BONUS_SWEEP_INTERVAL", 5*time.Minute),
+			ConsumePolicy:       getEnv("BONUS_CONSUME_POLICY", "cash_first"),
+			ForfeitOnWithdrawal: getEnv("BONUS_FORFEIT_ON_WITHDRAWAL", "true") == "true",
+		},
+		Vault: VaultConfig{
+			MasterKeys:     getEnv("VAULT_MASTER_KEYS", ""),
+			ActiveKey:      getEnv("VAULT_ACTIVE_KEY", ""),
+			FingerprintKey: getEnv("VAULT_FINGERPRINT_KEY", ""),
+			RotateBatch:    getIntEnv("VAULT_ROTATE_BATCH", 200),
+		},
+		Agent: AgentConfig{
+			ContractSweepInterval: getDurationEnv("AGENT_CONTRACT_SWEEP_INTERVAL", time.Hour),
+			MaxDepth:              getIntEnv("AGENT_MAX_DEPTH", 5),
+			OverrideLevels:        getIntEnv("AGENT_COMMISSION_OVERRIDE_LEVELS", 0),
+			SettlementInterval:    getDurationEnv("AGENT_SETTLEMENT_INTERVAL", time.Hour),
+			PerformanceInterval:   getDurationEnv("AGENT_PERFORMANCE_INTERVAL", 15*time.Minute),
+			CreditWarningRatio:    getFloatEnv("AGENT_CREDIT_WARNING_RATIO", 0.8),
+			CreditCriticalRatio:   getFloatEnv("AGENT_CREDIT_CRITICAL_RATIO", 0.95),
 		},
 	}
 
@@ -1,85 +1,656 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+
+	"nexus-gaming-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-// 代理商管理相關
-func GetAgents(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetAgents endpoint not implemented yet", "NOT_IMPLEMENTED")
+// AgentController 代理商與經銷商管理控制器
+type AgentController struct {
+	agentService *services.AgentService
+}
+
+// NewAgentController 建立新的代理商控制器
+func NewAgentController() *AgentController {
+	return &AgentController{agentService: services.NewAgentService()}
+}
+
+// AgentListRequest 代理商列表查詢
+type AgentListRequest struct {
+	Status        string `form:"status" binding:"omitempty,oneof=active inactive suspended terminated"`
+	ParentAgentID int    `form:"parent_agent_id"`
+	Keyword       string `form:"keyword"`
+	Page          int    `form:"page"`
+	Limit         int    `form:"limit"`
+}
+
+// AgentRequest 代理商資料（日期格式為 YYYY-MM-DD，省略銀行帳戶時不變更）
+type AgentRequest struct {
+	AgentName         string            `json:"agent_name" binding:"required,max=100"`
+	ContactPerson     string            `json:"contact_person" binding:"required,max=100"`
+	Email             string            `json:"email" binding:"required,email,max=255"`
+	Phone             string            `json:"phone" binding:"max=20"`
+	Address           string            `json:"address"`
+	BusinessLicense   string            `json:"business_license" binding:"max=100"`
+	TaxID             string            `json:"tax_id" binding:"max=50"`
+	BankAccount       map[string]string `json:"bank_account"`
+	ContractStartDate string            `json:"contract_start_date" binding:"omitempty,datetime=2006-01-02"`
+	ContractEndDate   string            `json:"contract_end_date" binding:"omitempty,datetime=2006-01-02"`
+	CommissionRate    float64           `json:"commission_rate" binding:"min=0,max=1"`
+	MaxDealers        int               `json:"max_dealers" binding:"min=0"` // 0 表示不限
+	Notes             string            `json:"notes"`
+}
+
+// CreateAgentRequest 建立代理商請求（一併建立代理商角色的登入帳號）
+type CreateAgentRequest struct {
+	AgentRequest
+	AgentCode     string `json:"agent_code" binding:"required,alphanum,max=32"`
+	ParentAgentID *int   `json:"parent_agent_id"` // 省略時為總公司
+	Username      string `json:"username" binding:"required,min=3,max=100"`
+	Password      string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// DealerListRequest 經銷商列表查詢
+type DealerListRequest struct {
+	AgentID int    `form:"agent_id"`
+	Status  string `form:"status" binding:"omitempty,oneof=active inactive suspended terminated"`
+	Keyword string `form:"keyword"`
+	Page    int    `form:"page"`
+	Limit   int    `form:"limit"`
+}
+
+// DealerRequest 經銷商資料（省略身分證號碼或銀行帳戶時不變更）
+type DealerRequest struct {
+	DealerName     string            `json:"dealer_name" binding:"required,max=100"`
+	ContactPerson  string            `json:"contact_person" binding:"required,max=100"`
+	Email          string            `json:"email" binding:"required,email,max=255"`
+	Phone          string            `json:"phone" binding:"max=20"`
+	Address        string            `json:"address"`
+	IDNumber       string            `json:"id_number" binding:"max=50"`
+	BankAccount    map[string]string `json:"bank_account"`
+	CommissionRate float64           `json:"commission_rate" binding:"min=0,max=1"`
+	MaxPlayers     int               `json:"max_players" binding:"min=0"` // 0 表示不限
+	Territory      string            `json:"territory" binding:"max=255"`
+	Notes          string            `json:"notes"`
+}
+
+// CreateDealerRequest 建立經銷商請求（一併建立經銷商角色的登入帳號）
+type CreateDealerRequest struct {
+	DealerRequest
+	DealerCode string `json:"dealer_code" binding:"required,alphanum,max=32"`
+	Username   string `json:"username" binding:"required,min=3,max=100"`
+	Password   string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// PlayerReassignmentRequest 玩家移轉方式（reassign 需指定經銷商或代理商；release 改為直屬總公司）
+type PlayerReassignmentRequest struct {
+	Action         string `json:"action" binding:"omitempty,oneof=reassign release"`
+	TargetDealerID *int   `json:"target_dealer_id"`
+	TargetAgentID  *int   `json:"target_agent_id"`
+}
+
+// AgentStatusRequest 變更代理商狀態請求（終止時需指定經銷商與玩家的移轉方式）
+type AgentStatusRequest struct {
+	Status              string                    `json:"status" binding:"required,oneof=active inactive suspended terminated"`
+	Reason              string                    `json:"reason" binding:"max=255"`
+	DealerAction        string                    `json:"dealer_action" binding:"omitempty,oneof=reassign terminate"`
+	DealerTargetAgentID *int                      `json:"dealer_target_agent_id"`
+	Players             PlayerReassignmentRequest `json:"players"`
+}
+
+// MoveAgentRequest 變更上級代理商請求
+type MoveAgentRequest struct {
+	ParentAgentID int `json:"parent_agent_id" binding:"required,min=1"`
+}
+
+// AgentTreeRequest 層級樹查詢（省略 root_id 時以總公司為根）
+type AgentTreeRequest struct {
+	RootID         int  `form:"root_id" binding:"min=0"`
+	IncludeDealers bool `form:"include_dealers"`
+}
+
+// AgentDescendantsRequest 下級查詢（depth 為 0 表示不限層級）
+type AgentDescendantsRequest struct {
+	Type  string `form:"type" binding:"omitempty,oneof=agent dealer"`
+	Depth int    `form:"depth" binding:"min=0"`
+}
+
+// DealerStatusRequest 變更經銷商狀態請求（終止時需指定玩家的移轉方式）
+type DealerStatusRequest struct {
+	Status  string                    `json:"status" binding:"required,oneof=active inactive suspended terminated"`
+	Reason  string                    `json:"reason" binding:"max=255"`
+	Players PlayerReassignmentRequest `json:"players"`
+}
+
+// GetAgents 代理商列表
+func (ac *AgentController) GetAgents(c *gin.Context) {
+	var req AgentListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
+	list, total, err := ac.agentService.List(services.AgentFilter{
+		Status:        req.Status,
+		ParentAgentID: req.ParentAgentID,
+		Keyword:       req.Keyword,
+		Page:          req.Page,
+		Limit:         req.Limit,
+	})
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"agents": list, "pagination": pagination(req.Page, req.Limit, total)}, "代理商列表獲取成功")
+}
+
+// GetAgent 代理商詳情（含狀態異動記錄）
+func (ac *AgentController) GetAgent(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	agent, err := ac.agentService.Get(id)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	logs, err := ac.agentService.StatusLogs("agent", id)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"agent": agent, "status_logs": logs}, "代理商獲取成功")
+}
+
+// CreateAgent 建立代理商與其登入帳號（合約尚未開始或已結束時建立為停用）
+func (ac *AgentController) CreateAgent(c *gin.Context) {
+	var req CreateAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	in := req.AgentRequest.input()
+	in.AgentCode, in.ParentAgentID = req.AgentCode, req.ParentAgentID
+	in.Username, in.Password = req.Username, req.Password
+	operatorID := c.GetInt("user_id")
+	agent, err := ac.agentService.Create(in, &operatorID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, agent, "代理商已建立")
+}
+
+// UpdateAgent 更新代理商資料
+func (ac *AgentController) UpdateAgent(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	var req AgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	agent, err := ac.agentService.Update(id, req.input())
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, agent, "代理商已更新")
+}
+
+// DeleteAgent 刪除尚未營運的代理商（已有經銷商、玩家或結算記錄者請改為終止）
+func (ac *AgentController) DeleteAgent(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	if err := ac.agentService.Delete(id); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, nil, "代理商已刪除")
+}
+
+// UpdateAgentStatus 變更代理商狀態；終止時依指定方式移轉或終止其經銷商並移轉其玩家
+func (ac *AgentController) UpdateAgentStatus(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	var req AgentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	if req.Status != services.AgentStatusTerminated {
+		agent, err := ac.agentService.UpdateStatus(id, req.Status, req.Reason, &operatorID)
+		if err != nil {
+			ac.handleError(c, err)
+			return
+		}
+		SuccessResponse(c, agent, "代理商狀態已更新")
+		return
+	}
+	agent, result, err := ac.agentService.Terminate(id, services.AgentTermination{
+		DealerAction:        req.DealerAction,
+		DealerTargetAgentID: req.DealerTargetAgentID,
+		Players:             req.Players.plan(),
+	}, req.Reason, &operatorID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"agent": agent, "reassignment": result}, "代理商已終止")
+}
+
+// MoveAgent 變更代理商的上級代理商（整個下級子樹一併移動）
+func (ac *AgentController) MoveAgent(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	var req MoveAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	agent, err := ac.agentService.MoveAgent(id, req.ParentAgentID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, agent, "上級代理商已變更")
+}
+
+// GetAgentTree 代理商層級樹
+func (ac *AgentController) GetAgentTree(c *gin.Context) {
+	var req AgentTreeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	tree, err := ac.agentService.Hierarchy.Tree(req.RootID, req.IncludeDealers)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, tree, "獲取代理商層級樹成功")
+}
+
+// GetAgentAncestors 代理商的所有上級代理商
+func (ac *AgentController) GetAgentAncestors(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	list, err := ac.agentService.Hierarchy.Ancestors(id)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "獲取上級代理商成功")
+}
+
+// GetAgentDescendants 代理商的下級代理商與經銷商
+func (ac *AgentController) GetAgentDescendants(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	var req AgentDescendantsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	list, err := ac.agentService.Hierarchy.Descendants(id, req.Type, req.Depth)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "獲取下級成功")
+}
+
+// GetAgentSubtreeStats 代理商及其所有下級的彙總
+func (ac *AgentController) GetAgentSubtreeStats(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	stats, err := ac.agentService.Hierarchy.Stats(id)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, stats, "獲取下級彙總成功")
+}
+
+// RebuildHierarchy 依上級代理商與經銷商所屬代理商重建層級結構
+func (ac *AgentController) RebuildHierarchy(c *gin.Context) {
+	n, err := ac.agentService.Hierarchy.Rebuild()
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"agents": n}, "層級結構已重建")
 }
 
-func GetAgent(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetAgent endpoint not implemented yet", "NOT_IMPLEMENTED")
+// GetAgentDealers 代理商的經銷商列表
+func (ac *AgentController) GetAgentDealers(c *gin.Context) {
+	id, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	var req DealerListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if _, err := ac.agentService.Get(id); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	req.AgentID = id
+	ac.listDealers(c, req)
 }
 
-func CreateAgent(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "CreateAgent endpoint not implemented yet", "NOT_IMPLEMENTED")
+// CreateDealer 在代理商下建立經銷商與其登入帳號
+func (ac *AgentController) CreateDealer(c *gin.Context) {
+	agentID, ok := agentParam(c)
+	if !ok {
+		return
+	}
+	var req CreateDealerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	in := req.DealerRequest.input()
+	in.DealerCode, in.Username, in.Password = req.DealerCode, req.Username, req.Password
+	operatorID := c.GetInt("user_id")
+	dealer, err := ac.agentService.CreateDealer(agentID, in, &operatorID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, dealer, "經銷商已建立")
 }
 
-func UpdateAgent(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "UpdateAgent endpoint not implemented yet", "NOT_IMPLEMENTED")
+// GetDealers 經銷商列表
+func (ac *AgentController) GetDealers(c *gin.Context) {
+	var req DealerListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	ac.listDealers(c, req)
 }
 
-func DeleteAgent(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "DeleteAgent endpoint not implemented yet", "NOT_IMPLEMENTED")
+func (ac *AgentController) listDealers(c *gin.Context, req DealerListRequest) {
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
+	list, total, err := ac.agentService.Dealers(services.DealerFilter{
+		AgentID: req.AgentID,
+		Status:  req.Status,
+		Keyword: req.Keyword,
+		Page:    req.Page,
+		Limit:   req.Limit,
+	})
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"dealers": list, "pagination": pagination(req.Page, req.Limit, total)}, "經銷商列表獲取成功")
 }
 
-func UpdateAgentStatus(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "UpdateAgentStatus endpoint not implemented yet", "NOT_IMPLEMENTED")
+// GetDealer 經銷商詳情（含狀態異動記錄）
+func (ac *AgentController) GetDealer(c *gin.Context) {
+	id, ok := dealerParam(c)
+	if !ok {
+		return
+	}
+	dealer, err := ac.agentService.GetDealer(id)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	logs, err := ac.agentService.StatusLogs("dealer", id)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"dealer": dealer, "status_logs": logs}, "經銷商獲取成功")
 }
 
-func GetAgentDealers(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetAgentDealers endpoint not implemented yet", "NOT_IMPLEMENTED")
+// UpdateDealer 更新經銷商資料
+func (ac *AgentController) UpdateDealer(c *gin.Context) {
+	id, ok := dealerParam(c)
+	if !ok {
+		return
+	}
+	var req DealerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	dealer, err := ac.agentService.UpdateDealer(id, req.input())
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, dealer, "經銷商已更新")
 }
 
-func CreateDealer(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "CreateDealer endpoint not implemented yet", "NOT_IMPLEMENTED")
+// DeleteDealer 刪除尚未營運的經銷商（已有玩家或結算記錄者請改為終止）
+func (ac *AgentController) DeleteDealer(c *gin.Context) {
+	id, ok := dealerParam(c)
+	if !ok {
+		return
+	}
+	if err := ac.agentService.DeleteDealer(id); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, nil, "經銷商已刪除")
 }
 
-func GetAgentCommission(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetAgentCommission endpoint not implemented yet", "NOT_IMPLEMENTED")
+// UpdateDealerStatus 變更經銷商狀態；終止時依指定方式移轉其玩家
+func (ac *AgentController) UpdateDealerStatus(c *gin.Context) {
+	id, ok := dealerParam(c)
+	if !ok {
+		return
+	}
+	var req DealerStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	if req.Status != services.AgentStatusTerminated {
+		dealer, err := ac.agentService.UpdateDealerStatus(id, req.Status, req.Reason, &operatorID)
+		if err != nil {
+			ac.handleError(c, err)
+			return
+		}
+		SuccessResponse(c, dealer, "經銷商狀態已更新")
+		return
+	}
+	dealer, result, err := ac.agentService.TerminateDealer(id, req.Players.plan(), req.Reason, &operatorID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"dealer": dealer, "reassignment": result}, "經銷商已終止")
 }
 
-func UpdateAgentCommission(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "UpdateAgentCommission endpoint not implemented yet", "NOT_IMPLEMENTED")
+// GetDealerPlayers 經銷商的玩家列表
+func (ac *AgentController) GetDealerPlayers(c *gin.Context) {
+	id, ok := dealerParam(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	page, limit = normalizePage(page, limit)
+	list, total, err := ac.agentService.DealerPlayers(id, c.Query("status"), page, limit)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"players": list, "pagination": pagination(page, limit, total)}, "經銷商玩家列表獲取成功")
 }
 
-func GetAgentSettlements(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetAgentSettlements endpoint not implemented yet", "NOT_IMPLEMENTED")
+// input 轉換為服務層的代理商資料
+func (r *AgentRequest) input() services.AgentInput {
+	return services.AgentInput{
+		AgentName:         r.AgentName,
+		ContactPerson:     r.ContactPerson,
+		Email:             r.Email,
+		Phone:             r.Phone,
+		Address:           r.Address,
+		BusinessLicense:   r.BusinessLicense,
+		TaxID:             r.TaxID,
+		BankAccount:       r.BankAccount,
+		ContractStartDate: parseDateParam(r.ContractStartDate),
+		ContractEndDate:   parseDateParam(r.ContractEndDate),
+		CommissionRate:    r.CommissionRate,
+		MaxDealers:        r.MaxDealers,
+		Notes:             r.Notes,
+	}
 }
 
-// 經銷商管理相關
-func GetDealers(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetDealers endpoint not implemented yet", "NOT_IMPLEMENTED")
+// input 轉換為服務層的經銷商資料
+func (r *DealerRequest) input() services.DealerInput {
+	return services.DealerInput{
+		DealerName:     r.DealerName,
+		ContactPerson:  r.ContactPerson,
+		Email:          r.Email,
+		Phone:          r.Phone,
+		Address:        r.Address,
+		IDNumber:       r.IDNumber,
+		BankAccount:    r.BankAccount,
+		CommissionRate: r.CommissionRate,
+		MaxPlayers:     r.MaxPlayers,
+		Territory:      r.Territory,
+		Notes:          r.Notes,
+	}
 }
 
-func GetDealer(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetDealer endpoint not implemented yet", "NOT_IMPLEMENTED")
+// plan 轉換為服務層的玩家移轉方式
+func (r PlayerReassignmentRequest) plan() services.PlayerReassignment {
+	return services.PlayerReassignment{Action: r.Action, TargetDealerID: r.TargetDealerID, TargetAgentID: r.TargetAgentID}
 }
 
-func UpdateDealer(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "UpdateDealer endpoint not implemented yet", "NOT_IMPLEMENTED")
+// parseDateParam 解析已驗證格式的 YYYY-MM-DD 日期（空字串為 nil）
+func parseDateParam(v string) *time.Time {
+	if v == "" {
+		return nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return nil
+	}
+	return &t
 }
 
-func DeleteDealer(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "DeleteDealer endpoint not implemented yet", "NOT_IMPLEMENTED")
+func normalizePage(page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
 }
 
-func UpdateDealerStatus(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "UpdateDealerStatus endpoint not implemented yet", "NOT_IMPLEMENTED")
+func pagination(page, limit int, total int64) gin.H {
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	return gin.H{
+		"page":         page,
+		"limit":        limit,
+		"total":        total,
+		"total_pages":  totalPages,
+		"has_next":     page < totalPages,
+		"has_previous": page > 1,
+	}
 }
 
-func GetDealerCommission(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetDealerCommission endpoint not implemented yet", "NOT_IMPLEMENTED")
+func agentParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的代理商ID", "INVALID_ID")
+		return 0, false
+	}
+	return id, true
 }
 
-func GetDealerSettlements(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetDealerSettlements endpoint not implemented yet", "NOT_IMPLEMENTED")
+func dealerParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的經銷商ID", "INVALID_ID")
+		return 0, false
+	}
+	return id, true
 }
 
-func GetDealerPlayers(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetDealerPlayers endpoint not implemented yet", "NOT_IMPLEMENTED")
+func (ac *AgentController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAgentNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "AGENT_NOT_FOUND")
+	case errors.Is(err, services.ErrDealerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "DEALER_NOT_FOUND")
+	case errors.Is(err, services.ErrAgentCodeExists), errors.Is(err, services.ErrDealerCodeExists),
+		errors.Is(err, services.ErrLoginExists):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "DUPLICATE_ENTRY")
+	case errors.Is(err, services.ErrStatusTransition):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INVALID_STATUS_TRANSITION")
+	case errors.Is(err, services.ErrContractDates):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_CONTRACT_DATES")
+	case errors.Is(err, services.ErrOutsideContract):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "OUTSIDE_CONTRACT")
+	case errors.Is(err, services.ErrAgentNotOperable), errors.Is(err, services.ErrParentAgentNotOperable),
+		errors.Is(err, services.ErrDealerNotOperable):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "NOT_OPERABLE")
+	case errors.Is(err, services.ErrDealerLimit):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "DEALER_LIMIT_REACHED")
+	case errors.Is(err, services.ErrPlayerLimit):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "PLAYER_LIMIT_REACHED")
+	case errors.Is(err, services.ErrCompanyAgent):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "COMPANY_AGENT")
+	case errors.Is(err, services.ErrAgentHasSubAgents):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "HAS_SUB_AGENTS")
+	case errors.Is(err, services.ErrReassignmentRequired):
+		ErrorResponse(c, http.StatusUnprocessableEntity, err.Error(), "REASSIGNMENT_REQUIRED")
+	case errors.Is(err, services.ErrInvalidReassignment):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_REASSIGNMENT")
+	case errors.Is(err, services.ErrAgentInUse), errors.Is(err, services.ErrDealerInUse):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "IN_USE")
+	case errors.Is(err, services.ErrHierarchyCycle):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "HIERARCHY_CYCLE")
+	case errors.Is(err, services.ErrHierarchyTooDeep):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "HIERARCHY_TOO_DEEP")
+	case errors.Is(err, services.ErrHierarchyRootMove):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "COMPANY_AGENT")
+	case errors.Is(err, services.ErrCompanyAgentUnavailable):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "COMPANY_AGENT_UNAVAILABLE")
+	case errors.Is(err, services.ErrVaultUnavailable):
+		ErrorResponse(c, http.StatusServiceUnavailable, err.Error(), "VAULT_UNAVAILABLE")
+	case errors.Is(err, services.ErrTransferPlayers), errors.Is(err, services.ErrTransferTarget),
+		errors.Is(err, services.ErrTransferEffective):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_TRANSFER")
+	case errors.Is(err, services.ErrTransferPlayerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PLAYER_NOT_FOUND")
+	case errors.Is(err, services.ErrTransferLocked):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "COMMISSION_LOCKED")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "代理商或經銷商操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
 }
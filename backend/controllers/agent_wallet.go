@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AgentWalletController 代理商與經銷商籌碼錢包與信用額度控制器
+type AgentWalletController struct {
+	walletService      *services.AgentWalletService
+	fxService          *services.FXService
+	idempotencyService *services.IdempotencyService
+}
+
+// NewAgentWalletController 建立新的代理商錢包控制器
+func NewAgentWalletController() *AgentWalletController {
+	return &AgentWalletController{
+		walletService:      services.NewAgentWalletService(),
+		fxService:          services.NewFXService(),
+		idempotencyService: services.NewIdempotencyService(),
+	}
+}
+
+// CreditLimitRequest 設定信用額度（0 表示不可賒帳）
+type CreditLimitRequest struct {
+	Currency    string       `json:"currency" binding:"omitempty,len=3"` // 省略時為預設幣別
+	CreditLimit money.Amount `json:"credit_limit"`
+}
+
+// ChipPurchaseRequest 向總公司購買籌碼
+type ChipPurchaseRequest struct {
+	Currency    string       `json:"currency" binding:"omitempty,len=3"`
+	Amount      money.Amount `json:"amount" binding:"required"`
+	Payment     string       `json:"payment" binding:"required,oneof=prepaid credit"`
+	Description string       `json:"description" binding:"max=255"`
+}
+
+// CreditRepaymentRequest 償還信用額度
+type CreditRepaymentRequest struct {
+	Currency    string       `json:"currency" binding:"omitempty,len=3"`
+	Amount      money.Amount `json:"amount" binding:"required"`
+	Description string       `json:"description" binding:"max=255"`
+}
+
+// AgentPlayerTransferRequest 與玩家之間的籌碼移轉（deposit 為轉給玩家，withdrawal 為自玩家收回）
+type AgentPlayerTransferRequest struct {
+	PlayerID    int64        `json:"player_id" binding:"required"`
+	Currency    string       `json:"currency" binding:"omitempty,len=3"`
+	Amount      money.Amount `json:"amount" binding:"required"`
+	Direction   string       `json:"direction" binding:"required,oneof=deposit withdrawal"`
+	Description string       `json:"description" binding:"max=255"`
+}
+
+// AgentWalletTransactionListRequest 錢包異動查詢
+type AgentWalletTransactionListRequest struct {
+	Currency string `form:"currency" binding:"omitempty,len=3"`
+	Type     string `form:"type" binding:"omitempty,oneof=chip_purchase credit_draw credit_repayment player_deposit player_withdrawal settlement_netting"`
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+// CreditAlertListRequest 信用額度警示查詢
+type CreditAlertListRequest struct {
+	OwnerType    string `form:"owner_type" binding:"omitempty,oneof=agent dealer"`
+	OwnerID      int    `form:"owner_id"`
+	Level        string `form:"level" binding:"omitempty,oneof=warning critical"`
+	Acknowledged string `form:"acknowledged" binding:"omitempty,oneof=true false"`
+	Page         int    `form:"page"`
+	Limit        int    `form:"limit"`
+}
+
+// GetAgentWallet 代理商各幣別的籌碼與信用額度
+func (wc *AgentWalletController) GetAgentWallet(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		wc.getWallet(c, services.NodeAgent, id)
+	}
+}
+
+// GetDealerWallet 經銷商各幣別的籌碼與信用額度
+func (wc *AgentWalletController) GetDealerWallet(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		wc.getWallet(c, services.NodeDealer, id)
+	}
+}
+
+// SetAgentCreditLimit 設定代理商信用額度
+func (wc *AgentWalletController) SetAgentCreditLimit(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		wc.setCreditLimit(c, services.NodeAgent, id)
+	}
+}
+
+// SetDealerCreditLimit 設定經銷商信用額度
+func (wc *AgentWalletController) SetDealerCreditLimit(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		wc.setCreditLimit(c, services.NodeDealer, id)
+	}
+}
+
+// PurchaseAgentChips 代理商向總公司購買籌碼（支援 Idempotency-Key）
+func (wc *AgentWalletController) PurchaseAgentChips(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		wc.purchaseChips(c, services.NodeAgent, id)
+	}
+}
+
+// PurchaseDealerChips 經銷商向總公司購買籌碼（支援 Idempotency-Key）
+func (wc *AgentWalletController) PurchaseDealerChips(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		wc.purchaseChips(c, services.NodeDealer, id)
+	}
+}
+
+// RepayAgentCredit 代理商償還信用額度（支援 Idempotency-Key）
+func (wc *AgentWalletController) RepayAgentCredit(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		wc.repayCredit(c, services.NodeAgent, id)
+	}
+}
+
+// RepayDealerCredit 經銷商償還信用額度（支援 Idempotency-Key）
+func (wc *AgentWalletController) RepayDealerCredit(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		wc.repayCredit(c, services.NodeDealer, id)
+	}
+}
+
+// TransferAgentPlayerChips 代理商與直屬玩家之間的籌碼移轉（支援 Idempotency-Key）
+func (wc *AgentWalletController) TransferAgentPlayerChips(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		wc.transferPlayerChips(c, services.NodeAgent, id)
+	}
+}
+
+// TransferDealerPlayerChips 經銷商與所屬玩家之間的籌碼移轉（支援 Idempotency-Key）
+func (wc *AgentWalletController) TransferDealerPlayerChips(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		wc.transferPlayerChips(c, services.NodeDealer, id)
+	}
+}
+
+// GetAgentWalletTransactions 代理商錢包異動記錄
+func (wc *AgentWalletController) GetAgentWalletTransactions(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		wc.listTransactions(c, services.NodeAgent, id)
+	}
+}
+
+// GetDealerWalletTransactions 經銷商錢包異動記錄
+func (wc *AgentWalletController) GetDealerWalletTransactions(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		wc.listTransactions(c, services.NodeDealer, id)
+	}
+}
+
+// GetCreditAlerts 信用額度使用率警示列表
+func (wc *AgentWalletController) GetCreditAlerts(c *gin.Context) {
+	var req CreditAlertListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	page, limit := normalizePage(req.Page, req.Limit)
+	filter := services.CreditAlertFilter{
+		OwnerType: req.OwnerType,
+		OwnerID:   req.OwnerID,
+		Level:     req.Level,
+		Page:      page,
+		Limit:     limit,
+	}
+	if req.Acknowledged != "" {
+		acknowledged := req.Acknowledged == "true"
+		filter.Acknowledged = &acknowledged
+	}
+	list, total, err := wc.walletService.Alerts(filter)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"alerts": list, "pagination": pagination(page, limit, total)}, "獲取信用額度警示成功")
+}
+
+// AcknowledgeCreditAlert 確認信用額度警示
+func (wc *AgentWalletController) AcknowledgeCreditAlert(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorResponse(c, http.StatusBadRequest, "警示ID格式錯誤", "INVALID_ID")
+		return
+	}
+	alert, err := wc.walletService.AcknowledgeAlert(id, c.GetInt("user_id"))
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, alert, "警示已確認")
+}
+
+func (wc *AgentWalletController) getWallet(c *gin.Context, ownerType string, id int) {
+	wallets, err := wc.walletService.Wallets(ownerType, id)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"owner_type": ownerType, "owner_id": id, "wallets": wallets}, "獲取錢包成功")
+}
+
+func (wc *AgentWalletController) setCreditLimit(c *gin.Context, ownerType string, id int) {
+	var req CreditLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	currency, ok := wc.currency(c, req.Currency)
+	if !ok {
+		return
+	}
+	wallet, err := wc.walletService.SetCreditLimit(ownerType, id, currency, req.CreditLimit)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, wallet, "信用額度已更新")
+}
+
+func (wc *AgentWalletController) purchaseChips(c *gin.Context, ownerType string, id int) {
+	var req ChipPurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	currency, ok := wc.currency(c, req.Currency)
+	if !ok {
+		return
+	}
+	req.Currency = currency
+	operatorID := c.GetInt("user_id")
+	if req.Description == "" {
+		req.Description = map[string]string{services.ChipPrepaid: "預付購買籌碼", services.ChipCredit: "賒帳購買籌碼"}[req.Payment]
+	}
+	scope := fmt.Sprintf("%s_chip_purchase:%d", ownerType, id)
+	body, replayed, err := wc.idempotencyService.Run(scope, c.GetHeader(IdempotencyKeyHeader), req, func(tx *sql.Tx) (interface{}, error) {
+		return wc.walletService.PurchaseChipsTx(tx, ownerType, id, services.ChipPurchase{
+			Currency:    req.Currency,
+			Amount:      req.Amount,
+			Payment:     req.Payment,
+			Description: req.Description,
+			OperatorID:  &operatorID,
+		})
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	SuccessResponse(c, body, "籌碼購買成功")
+}
+
+func (wc *AgentWalletController) repayCredit(c *gin.Context, ownerType string, id int) {
+	var req CreditRepaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	currency, ok := wc.currency(c, req.Currency)
+	if !ok {
+		return
+	}
+	req.Currency = currency
+	operatorID := c.GetInt("user_id")
+	if req.Description == "" {
+		req.Description = "償還信用額度"
+	}
+	scope := fmt.Sprintf("%s_credit_repayment:%d", ownerType, id)
+	body, replayed, err := wc.idempotencyService.Run(scope, c.GetHeader(IdempotencyKeyHeader), req, func(tx *sql.Tx) (interface{}, error) {
+		return wc.walletService.RepayCreditTx(tx, ownerType, id, req.Currency, req.Amount, req.Description, &operatorID)
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	SuccessResponse(c, body, "還款成功")
+}
+
+func (wc *AgentWalletController) transferPlayerChips(c *gin.Context, ownerType string, id int) {
+	var req AgentPlayerTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	currency, ok := wc.currency(c, req.Currency)
+	if !ok {
+		return
+	}
+	req.Currency = currency
+	operatorID := c.GetInt("user_id")
+	if req.Description == "" {
+		req.Description = map[string]string{"deposit": "籌碼轉入玩家", "withdrawal": "自玩家收回籌碼"}[req.Direction]
+	}
+	scope := fmt.Sprintf("%s_player_transfer:%d", ownerType, id)
+	body, replayed, err := wc.idempotencyService.Run(scope, c.GetHeader(IdempotencyKeyHeader), req, func(tx *sql.Tx) (interface{}, error) {
+		return wc.walletService.TransferPlayerTx(tx, ownerType, id, services.AgentPlayerTransfer{
+			PlayerID:    req.PlayerID,
+			Currency:    req.Currency,
+			Amount:      req.Amount,
+			Direction:   req.Direction,
+			Description: req.Description,
+			OperatorID:  &operatorID,
+		})
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	SuccessResponse(c, body, "籌碼移轉成功")
+}
+
+func (wc *AgentWalletController) listTransactions(c *gin.Context, ownerType string, id int) {
+	var req AgentWalletTransactionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	page, limit := normalizePage(req.Page, req.Limit)
+	list, total, err := wc.walletService.Transactions(services.AgentWalletFilter{
+		OwnerType: ownerType,
+		OwnerID:   id,
+		Currency:  strings.ToUpper(req.Currency),
+		Type:      req.Type,
+		Page:      page,
+		Limit:     limit,
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"transactions": list, "pagination": pagination(page, limit, total)}, "獲取錢包異動成功")
+}
+
+// currency 正規化幣別（省略時為預設幣別）並檢查是否為啟用中的幣別
+func (wc *AgentWalletController) currency(c *gin.Context, code string) (string, bool) {
+	code = wc.walletService.Wallet.CurrencyOf(strings.ToUpper(code))
+	if err := wc.fxService.CheckCurrency(code); err != nil {
+		wc.handleError(c, err)
+		return "", false
+	}
+	return code, true
+}
+
+func (wc *AgentWalletController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRateNotFound), errors.Is(err, services.ErrCurrencyUnsupported):
+		handleFXError(c, err)
+	case errors.Is(err, services.ErrAgentNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "AGENT_NOT_FOUND")
+	case errors.Is(err, services.ErrDealerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "DEALER_NOT_FOUND")
+	case errors.Is(err, services.ErrTransferPlayerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PLAYER_NOT_FOUND")
+	case errors.Is(err, services.ErrCreditAlertNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "ALERT_NOT_FOUND")
+	case errors.Is(err, services.ErrAgentNotOperable), errors.Is(err, services.ErrDealerNotOperable),
+		errors.Is(err, services.ErrPlayerNotTransferable):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "NOT_OPERABLE")
+	case errors.Is(err, services.ErrPlayerNotOwned):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "PLAYER_NOT_OWNED")
+	case errors.Is(err, services.ErrCreditLimitExceeded), errors.Is(err, services.ErrCreditLimitAboveParent),
+		errors.Is(err, services.ErrCreditLimitBelowChildren):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "CREDIT_LIMIT")
+	case errors.Is(err, services.ErrInsufficientChips), errors.Is(err, services.ErrInsufficientBalance),
+		errors.Is(err, services.ErrWalletNotFound):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INSUFFICIENT_BALANCE")
+	case errors.Is(err, services.ErrRepaymentExceedsCredit), errors.Is(err, services.ErrCompanyWallet):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INVALID_OPERATION")
+	case errors.Is(err, services.ErrInvalidAmount), errors.Is(err, services.ErrInvalidCreditLimit):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_AMOUNT")
+	case errors.Is(err, services.ErrChipPayment), errors.Is(err, services.ErrTransferDirection),
+		errors.Is(err, services.ErrAgentWalletOwner):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_REQUEST")
+	case errors.Is(err, services.ErrIdempotencyKeyInvalid):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_IDEMPOTENCY_KEY")
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		ErrorResponse(c, http.StatusUnprocessableEntity, err.Error(), "IDEMPOTENCY_KEY_REUSED")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "代理商錢包操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
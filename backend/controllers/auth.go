@@ -313,6 +313,38 @@ func (ac *AuthController) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// PlayerAuthMiddleware 玩家身份驗證中介軟體（驗證玩家 Token，設置 player_id）
+func (ac *AuthController) PlayerAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, APIResponse{
+				Success: false,
+				Message: "缺少玩家 Token",
+				Data:    gin.H{"error": "MISSING_TOKEN"},
+			})
+			c.Abort()
+			return
+		}
+
+		claims, err := ac.authService.ValidatePlayerToken(tokenParts[1])
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, APIResponse{
+				Success: false,
+				Message: "玩家 Token 驗證失敗",
+				Data:    gin.H{"error": err.Error()},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("player_id", claims.PlayerID)
+		c.Set("player_username", claims.Username)
+
+		c.Next()
+	}
+}
+
 // AdminPermissionMiddleware 管理員權限檢查中介軟體
 func (ac *AuthController) AdminPermissionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
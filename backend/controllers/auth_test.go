@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nexus-gaming-backend/models"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAuthTestRouter(t *testing.T) (*gin.Engine, *services.AuthService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	auth := &services.AuthService{JWTSecret: "test-secret"}
+	ac := &AuthController{authService: auth}
+
+	r := gin.New()
+	r.GET("/backoffice", ac.AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/player", ac.PlayerAuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r, auth
+}
+
+func doAuthRequest(r *gin.Engine, path, token string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthMiddlewareRejectsPlayerToken(t *testing.T) {
+	r, auth := newAuthTestRouter(t)
+	token, err := auth.GeneratePlayerToken(42, "player42", time.Hour)
+	if err != nil {
+		t.Fatalf("GeneratePlayerToken: %v", err)
+	}
+
+	if code := doAuthRequest(r, "/backoffice", token); code != http.StatusUnauthorized {
+		t.Fatalf("player token on back-office route: got %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := doAuthRequest(r, "/player", token); code != http.StatusOK {
+		t.Fatalf("player token on player route: got %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestPlayerAuthMiddlewareRejectsBackofficeToken(t *testing.T) {
+	r, auth := newAuthTestRouter(t)
+	token, err := auth.GenerateToken(&models.User{ID: 1, Username: "admin", Role: &models.Role{Name: "admin"}})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	if code := doAuthRequest(r, "/player", token); code != http.StatusUnauthorized {
+		t.Fatalf("back-office token on player route: got %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := doAuthRequest(r, "/backoffice", token); code != http.StatusOK {
+		t.Fatalf("back-office token on back-office route: got %d, want %d", code, http.StatusOK)
+	}
+}
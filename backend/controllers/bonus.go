@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BonusController 紅利活動與玩家紅利控制器
+type BonusController struct {
+	bonusService *services.BonusService
+}
+
+// NewBonusController 建立新的紅利控制器
+func NewBonusController() *BonusController {
+	return &BonusController{bonusService: services.NewBonusService()}
+}
+
+// BonusListRequest 紅利列表查詢
+type BonusListRequest struct {
+	PlayerID int64  `form:"player_id"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending active completed forfeited expired"`
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+// GrantBonusRequest 人工發放紅利請求（指定活動時未填的欄位沿用活動設定）
+type GrantBonusRequest struct {
+	PlayerID            int64        `json:"player_id" binding:"required"`
+	CampaignID          *int         `json:"campaign_id"`
+	BonusType           string       `json:"bonus_type" binding:"omitempty,oneof=welcome deposit cashback referral loyalty promotion manual"`
+	Amount              money.Amount `json:"amount"`
+	Currency            string       `json:"currency" binding:"omitempty,len=3"`
+	WageringRequirement *float64     `json:"wagering_requirement" binding:"omitempty,min=0,max=999"`
+	ValidDays           *int         `json:"valid_days" binding:"omitempty,min=0"`
+	ConsumePolicy       string       `json:"consume_policy" binding:"omitempty,oneof=bonus_first cash_first"`
+	SourceReference     string       `json:"source_reference" binding:"max=100"`
+	Description         string       `json:"description"`
+}
+
+// BonusCampaignRequest 建立或更新紅利活動請求
+type BonusCampaignRequest struct {
+	CampaignCode        string        `json:"campaign_code" binding:"required,max=50"`
+	Name                string        `json:"name" binding:"required,max=100"`
+	Description         string        `json:"description"`
+	TriggerType         string        `json:"trigger_type" binding:"required,oneof=first_deposit referral loyalty_tier manual"`
+	BonusType           string        `json:"bonus_type" binding:"required,oneof=welcome deposit cashback referral loyalty promotion manual"`
+	Currency            string        `json:"currency" binding:"omitempty,len=3"`
+	FixedAmount         money.Amount  `json:"fixed_amount"`
+	Percent             float64       `json:"percent" binding:"min=0,max=999"`
+	MaxAmount           *money.Amount `json:"max_amount"`
+	MinDeposit          money.Amount  `json:"min_deposit"`
+	LoyaltyTier         *int          `json:"loyalty_tier" binding:"omitempty,min=1"`
+	WageringRequirement float64       `json:"wagering_requirement" binding:"min=0,max=999"`
+	ValidDays           int           `json:"valid_days" binding:"min=0"`
+	ConsumePolicy       string        `json:"consume_policy" binding:"omitempty,oneof=bonus_first cash_first"`
+	StartsAt            *time.Time    `json:"starts_at"`
+	EndsAt              *time.Time    `json:"ends_at"`
+	Status              string        `json:"status" binding:"omitempty,oneof=active paused ended"`
+}
+
+// ContributionRequest 遊戲流水貢獻比例請求
+type ContributionRequest struct {
+	ContributionPct float64 `json:"contribution_pct" binding:"min=0,max=100"`
+}
+
+// GetBonuses 紅利列表
+func (bc *BonusController) GetBonuses(c *gin.Context) {
+	var req BonusListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	list, total, err := bc.bonusService.List(services.BonusFilter{
+		PlayerID: req.PlayerID,
+		Status:   req.Status,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	totalPages := int((total + int64(req.Limit) - 1) / int64(req.Limit))
+	SuccessResponse(c, gin.H{
+		"bonuses": list,
+		"pagination": gin.H{
+			"page":         req.Page,
+			"limit":        req.Limit,
+			"total":        total,
+			"total_pages":  totalPages,
+			"has_next":     req.Page < totalPages,
+			"has_previous": req.Page > 1,
+		},
+	}, "紅利列表獲取成功")
+}
+
+// GetBonus 紅利詳情（含異動記錄）
+func (bc *BonusController) GetBonus(c *gin.Context) {
+	b, err := bc.bonusService.Get(c.Param("id"))
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	events, err := bc.bonusService.Events(b.BonusID)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"bonus": b, "events": events}, "紅利獲取成功")
+}
+
+// GrantBonus 人工發放紅利
+func (bc *BonusController) GrantBonus(c *gin.Context) {
+	var req GrantBonusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	grant := services.BonusGrant{
+		PlayerID:        req.PlayerID,
+		CampaignID:      req.CampaignID,
+		BonusType:       "manual",
+		Amount:          req.Amount,
+		Currency:        strings.ToUpper(req.Currency),
+		ConsumePolicy:   req.ConsumePolicy,
+		SourceReference: req.SourceReference,
+		Description:     req.Description,
+		GrantedBy:       &operatorID,
+	}
+	if req.CampaignID != nil {
+		campaign, err := bc.bonusService.GetCampaign(*req.CampaignID)
+		if err != nil {
+			bc.handleError(c, err)
+			return
+		}
+		grant.BonusType, grant.Currency = campaign.BonusType, campaign.Currency
+		grant.WageringRequirement, grant.ValidDays = campaign.WageringRequirement, campaign.ValidDays
+		if grant.Amount == 0 {
+			grant.Amount = campaign.FixedAmount
+		}
+		if grant.ConsumePolicy == "" {
+			grant.ConsumePolicy = campaign.ConsumePolicy
+		}
+		if grant.Description == "" {
+			grant.Description = campaign.Name
+		}
+	}
+	if req.BonusType != "" {
+		grant.BonusType = req.BonusType
+	}
+	if req.WageringRequirement != nil {
+		grant.WageringRequirement = *req.WageringRequirement
+	}
+	if req.ValidDays != nil {
+		grant.ValidDays = *req.ValidDays
+	}
+	if !grant.Amount.IsPositive() {
+		ErrorResponse(c, http.StatusBadRequest, services.ErrInvalidAmount.Error(), "INVALID_AMOUNT")
+		return
+	}
+	b, err := bc.bonusService.Grant(grant)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, b, "紅利已發放")
+}
+
+// ForfeitBonus 人工沒收有效紅利（已帶入牌桌的部分於離座時一併沒收）
+func (bc *BonusController) ForfeitBonus(c *gin.Context) {
+	operatorID := c.GetInt("user_id")
+	b, err := bc.bonusService.Forfeit(c.Param("id"), &operatorID)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, b, "紅利已沒收")
+}
+
+// GetCampaigns 紅利活動列表（status 篩選活動狀態）
+func (bc *BonusController) GetCampaigns(c *gin.Context) {
+	list, err := bc.bonusService.Campaigns(c.Query("status"))
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "紅利活動列表獲取成功")
+}
+
+// CreateCampaign 建立紅利活動
+func (bc *BonusController) CreateCampaign(c *gin.Context) {
+	var req BonusCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	campaign := req.campaign()
+	operatorID := c.GetInt("user_id")
+	campaign.CreatedBy = &operatorID
+	saved, err := bc.bonusService.SaveCampaign(campaign)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, saved, "紅利活動已建立")
+}
+
+// UpdateCampaign 更新紅利活動（活動代碼不可修改；已發放的紅利不受影響）
+func (bc *BonusController) UpdateCampaign(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的活動ID", "INVALID_ID")
+		return
+	}
+	var req BonusCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	campaign := req.campaign()
+	campaign.ID = id
+	saved, err := bc.bonusService.SaveCampaign(campaign)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, saved, "紅利活動已更新")
+}
+
+// GetContributions 各遊戲類型的流水貢獻比例
+func (bc *BonusController) GetContributions(c *gin.Context) {
+	list, err := bc.bonusService.Contributions()
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "流水貢獻比例獲取成功")
+}
+
+// SetContribution 設定遊戲類型的流水貢獻比例
+func (bc *BonusController) SetContribution(c *gin.Context) {
+	var req ContributionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	gameType := c.Param("game_type")
+	operatorID := c.GetInt("user_id")
+	if err := bc.bonusService.SetContribution(gameType, req.ContributionPct, &operatorID); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, services.GameContribution{GameType: gameType, ContributionPct: req.ContributionPct}, "流水貢獻比例已更新")
+}
+
+// campaign 轉換為服務層的活動設定
+func (r *BonusCampaignRequest) campaign() *services.BonusCampaign {
+	return &services.BonusCampaign{
+		CampaignCode:        r.CampaignCode,
+		Name:                r.Name,
+		Description:         r.Description,
+		TriggerType:         r.TriggerType,
+		BonusType:           r.BonusType,
+		Currency:            strings.ToUpper(r.Currency),
+		FixedAmount:         r.FixedAmount,
+		Percent:             r.Percent,
+		MaxAmount:           r.MaxAmount,
+		MinDeposit:          r.MinDeposit,
+		LoyaltyTier:         r.LoyaltyTier,
+		WageringRequirement: r.WageringRequirement,
+		ValidDays:           r.ValidDays,
+		ConsumePolicy:       r.ConsumePolicy,
+		StartsAt:            r.StartsAt,
+		EndsAt:              r.EndsAt,
+		Status:              r.Status,
+	}
+}
+
+func (bc *BonusController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrBonusNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "BONUS_NOT_FOUND")
+	case errors.Is(err, services.ErrCampaignNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "CAMPAIGN_NOT_FOUND")
+	case errors.Is(err, services.ErrPlayerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PLAYER_NOT_FOUND")
+	case errors.Is(err, services.ErrPlayerInactive):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "PLAYER_INACTIVE")
+	case errors.Is(err, services.ErrBonusNotActive):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "BONUS_NOT_ACTIVE")
+	case errors.Is(err, services.ErrBonusDuplicate):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "BONUS_DUPLICATE")
+	case errors.Is(err, services.ErrCampaignCode):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "CAMPAIGN_CODE_EXISTS")
+	case errors.Is(err, services.ErrCampaignInvalid), errors.Is(err, services.ErrCampaignTier):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_CAMPAIGN")
+	case errors.Is(err, services.ErrInvalidAmount):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_AMOUNT")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "紅利處理失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommissionController 分潤配置與佣金計算控制器
+type CommissionController struct {
+	commissionService *services.CommissionService
+}
+
+// NewCommissionController 建立新的分潤控制器
+func NewCommissionController() *CommissionController {
+	return &CommissionController{commissionService: services.NewCommissionService()}
+}
+
+// CommissionConfigRequest 新增分潤配置（日期格式為 YYYY-MM-DD；同一遊戲類型的現行配置於生效前一日失效）
+type CommissionConfigRequest struct {
+	ConfigName        string                    `json:"config_name" binding:"required,max=100"`
+	GameType          string                    `json:"game_type" binding:"omitempty,oneof=all texas_holdem stud_poker baccarat blackjack roulette slots"`
+	CommissionType    string                    `json:"commission_type" binding:"required,oneof=revenue_share cpa hybrid"`
+	RevenueShareRate  float64                   `json:"revenue_share_rate" binding:"min=0,max=1"`
+	CPAAmount         money.Amount              `json:"cpa_amount"`
+	MinPlayers        int                       `json:"min_players" binding:"min=0"`
+	MinRevenue        money.Amount              `json:"min_revenue"`
+	Tiers             []services.CommissionTier `json:"tiers"`
+	NegativeCarryover *bool                     `json:"negative_carryover"` // 省略時為結轉
+	SettlementPeriod  string                    `json:"settlement_period" binding:"omitempty,oneof=daily weekly monthly"`
+	EffectiveFrom     string                    `json:"effective_from" binding:"required,datetime=2006-01-02"`
+	EffectiveTo       string                    `json:"effective_to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// CommissionListRequest 佣金記錄或計算批次查詢
+type CommissionListRequest struct {
+	Status      string `form:"status" binding:"omitempty,oneof=pending confirmed paid cancelled"`
+	PeriodStart string `form:"period_start" binding:"omitempty,datetime=2006-01-02"`
+	PeriodEnd   string `form:"period_end" binding:"omitempty,datetime=2006-01-02"`
+	TargetType  string `form:"target_type" binding:"omitempty,oneof=agent dealer"`
+	TargetID    int    `form:"target_id"`
+	Page        int    `form:"page"`
+	Limit       int    `form:"limit"`
+}
+
+// CalculateCommissionRequest 計算佣金請求（省略對象時計算期間內有交易的所有代理商與經銷商）
+type CalculateCommissionRequest struct {
+	PeriodStart string `json:"period_start" binding:"required,datetime=2006-01-02"`
+	PeriodEnd   string `json:"period_end" binding:"required,datetime=2006-01-02"`
+	TargetType  string `json:"target_type" binding:"omitempty,oneof=agent dealer"`
+	TargetID    int    `json:"target_id" binding:"required_with=TargetType"`
+}
+
+// GetAgentCommission 代理商的分潤配置與佣金合計
+func (cc *CommissionController) GetAgentCommission(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		cc.getCommission(c, services.NodeAgent, id)
+	}
+}
+
+// UpdateAgentCommission 新增代理商的分潤配置
+func (cc *CommissionController) UpdateAgentCommission(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		cc.saveConfig(c, services.NodeAgent, id)
+	}
+}
+
+// DisableAgentCommission 停用代理商的分潤配置
+func (cc *CommissionController) DisableAgentCommission(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		cc.disableConfig(c, services.NodeAgent, id)
+	}
+}
+
+// GetAgentCommissions 代理商受益的佣金記錄（含下級貢獻的差額佣金）
+func (cc *CommissionController) GetAgentCommissions(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		cc.listCommissions(c, services.NodeAgent, id)
+	}
+}
+
+// GetDealerCommission 經銷商的分潤配置與佣金合計
+func (cc *CommissionController) GetDealerCommission(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		cc.getCommission(c, services.NodeDealer, id)
+	}
+}
+
+// UpdateDealerCommission 新增經銷商的分潤配置
+func (cc *CommissionController) UpdateDealerCommission(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		cc.saveConfig(c, services.NodeDealer, id)
+	}
+}
+
+// DisableDealerCommission 停用經銷商的分潤配置
+func (cc *CommissionController) DisableDealerCommission(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		cc.disableConfig(c, services.NodeDealer, id)
+	}
+}
+
+// GetDealerCommissions 經銷商受益的佣金記錄
+func (cc *CommissionController) GetDealerCommissions(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		cc.listCommissions(c, services.NodeDealer, id)
+	}
+}
+
+// CalculateCommissions 計算已結束期間的佣金（同期間未確認的結果整批取代）
+func (cc *CommissionController) CalculateCommissions(c *gin.Context) {
+	var req CalculateCommissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	var target *services.CommissionTarget
+	if req.TargetType != "" {
+		target = &services.CommissionTarget{Type: req.TargetType, ID: req.TargetID}
+	}
+	operatorID := c.GetInt("user_id")
+	result, err := cc.commissionService.Calculate(services.CommissionPeriod{
+		Start: *parseDateParam(req.PeriodStart),
+		End:   *parseDateParam(req.PeriodEnd),
+	}, target, &operatorID)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, result, "佣金計算完成")
+}
+
+// GetCommissionRuns 分潤計算批次列表
+func (cc *CommissionController) GetCommissionRuns(c *gin.Context) {
+	var req CommissionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	f := req.filter()
+	list, total, err := cc.commissionService.Runs(f)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"runs": list, "pagination": pagination(f.Page, f.Limit, total)}, "獲取分潤計算批次成功")
+}
+
+// GetCommissionRun 分潤計算批次與其佣金記錄
+func (cc *CommissionController) GetCommissionRun(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorResponse(c, http.StatusBadRequest, "計算批次ID格式錯誤", "INVALID_ID")
+		return
+	}
+	var req CommissionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	run, err := cc.commissionService.Run(id)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	page, limit := normalizePage(req.Page, req.Limit)
+	list, total, err := cc.commissionService.Commissions(services.CommissionFilter{
+		RunID: id, Status: req.Status, Page: page, Limit: limit,
+	})
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"run":         run,
+		"commissions": list,
+		"pagination":  pagination(page, limit, total),
+	}, "獲取分潤計算批次成功")
+}
+
+func (cc *CommissionController) getCommission(c *gin.Context, targetType string, id int) {
+	configs, err := cc.commissionService.Configs(targetType, id)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	totals, err := cc.commissionService.Totals(targetType, id)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"configs": configs, "totals": totals}, "獲取分潤配置成功")
+}
+
+func (cc *CommissionController) saveConfig(c *gin.Context, targetType string, id int) {
+	var req CommissionConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	carryover := true
+	if req.NegativeCarryover != nil {
+		carryover = *req.NegativeCarryover
+	}
+	operatorID := c.GetInt("user_id")
+	cfg, err := cc.commissionService.SaveConfig(targetType, id, services.CommissionConfigInput{
+		ConfigName:        req.ConfigName,
+		GameType:          req.GameType,
+		CommissionType:    req.CommissionType,
+		RevenueShareRate:  req.RevenueShareRate,
+		CPAAmount:         req.CPAAmount,
+		MinPlayers:        req.MinPlayers,
+		MinRevenue:        req.MinRevenue,
+		Tiers:             req.Tiers,
+		NegativeCarryover: carryover,
+		SettlementPeriod:  req.SettlementPeriod,
+		EffectiveFrom:     *parseDateParam(req.EffectiveFrom),
+		EffectiveTo:       parseDateParam(req.EffectiveTo),
+	}, &operatorID)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, cfg, "分潤配置已新增")
+}
+
+func (cc *CommissionController) disableConfig(c *gin.Context, targetType string, id int) {
+	configID, err := strconv.Atoi(c.Param("config_id"))
+	if err != nil || configID <= 0 {
+		ErrorResponse(c, http.StatusBadRequest, "分潤配置ID格式錯誤", "INVALID_ID")
+		return
+	}
+	if err := cc.commissionService.DisableConfig(targetType, id, configID); err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, nil, "分潤配置已停用")
+}
+
+func (cc *CommissionController) listCommissions(c *gin.Context, targetType string, id int) {
+	var req CommissionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	req.TargetType, req.TargetID = targetType, id
+	f := req.filter()
+	list, total, err := cc.commissionService.Commissions(f)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"commissions": list, "pagination": pagination(f.Page, f.Limit, total)}, "獲取佣金記錄成功")
+}
+
+func (r *CommissionListRequest) filter() services.CommissionFilter {
+	page, limit := normalizePage(r.Page, r.Limit)
+	return services.CommissionFilter{
+		TargetType:  r.TargetType,
+		TargetID:    r.TargetID,
+		Status:      r.Status,
+		PeriodStart: parseDateParam(r.PeriodStart),
+		PeriodEnd:   parseDateParam(r.PeriodEnd),
+		Page:        page,
+		Limit:       limit,
+	}
+}
+
+func (cc *CommissionController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAgentNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "AGENT_NOT_FOUND")
+	case errors.Is(err, services.ErrDealerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "DEALER_NOT_FOUND")
+	case errors.Is(err, services.ErrCommissionConfigNotFound), errors.Is(err, services.ErrCommissionRunNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "NOT_FOUND")
+	case errors.Is(err, services.ErrInvalidCommissionConfig), errors.Is(err, services.ErrCommissionPeriod):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_REQUEST")
+	case errors.Is(err, services.ErrCommissionTargetInvalid):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INVALID_TARGET")
+	case errors.Is(err, services.ErrCommissionLocked):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "COMMISSION_LOCKED")
+	case errors.Is(err, services.ErrCommissionOverlap), errors.Is(err, services.ErrCommissionLaterPeriod):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "PERIOD_CONFLICT")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "分潤操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/payment"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBody 回呼內容大小上限
+const maxWebhookBody = 1 << 20
+
+// DepositController 儲值與支付閘道回呼控制器
+type DepositController struct {
+	depositService *services.DepositService
+}
+
+// NewDepositController 建立新的儲值控制器
+func NewDepositController() *DepositController {
+	return &DepositController{depositService: services.NewDepositService()}
+}
+
+// DepositListRequest 儲值訂單列表查詢
+type DepositListRequest struct {
+	PlayerID int64  `form:"player_id"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending processing completed failed cancelled expired"`
+	Provider string `form:"provider"`
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+// CreateDepositRequest 建立儲值訂單請求
+type CreateDepositRequest struct {
+	PlayerID        int64        `json:"player_id" binding:"required"`
+	Amount          money.Amount `json:"amount" binding:"required"`
+	Currency        string       `json:"currency" binding:"omitempty,len=3"` // 省略時為預設幣別
+	PaymentMethod   string       `json:"payment_method" binding:"required,oneof=credit_card debit_card bank_transfer e_wallet cryptocurrency voucher"`
+	PaymentProvider string       `json:"payment_provider" binding:"required,max=50"`
+}
+
+// CompleteFakePaymentRequest 模擬閘道付款結果
+type CompleteFakePaymentRequest struct {
+	Status string `json:"status" binding:"required,oneof=succeeded failed"`
+}
+
+// GetDeposits 儲值訂單列表
+func (dc *DepositController) GetDeposits(c *gin.Context) {
+	var req DepositListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	list, total, err := dc.depositService.List(services.DepositFilter{
+		PlayerID: req.PlayerID,
+		Status:   req.Status,
+		Provider: req.Provider,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		dc.handleError(c, err)
+		return
+	}
+	totalPages := int((total + int64(req.Limit) - 1) / int64(req.Limit))
+	SuccessResponse(c, gin.H{
+		"deposits":  list,
+		"providers": dc.depositService.Gateways.Names(),
+		"pagination": gin.H{
+			"page":         req.Page,
+			"limit":        req.Limit,
+			"total":        total,
+			"total_pages":  totalPages,
+			"has_next":     req.Page < totalPages,
+			"has_previous": req.Page > 1,
+		},
+	}, "儲值訂單列表獲取成功")
+}
+
+// CreateDeposit 建立儲值訂單並向閘道建立付款意圖（回傳付款網址，入帳待閘道回呼）
+func (dc *DepositController) CreateDeposit(c *gin.Context) {
+	var req CreateDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if !req.Amount.IsPositive() {
+		ErrorResponse(c, http.StatusBadRequest, services.ErrInvalidAmount.Error(), "INVALID_AMOUNT")
+		return
+	}
+	d, err := dc.depositService.Create(services.DepositRequest{
+		PlayerID:        req.PlayerID,
+		Amount:          req.Amount,
+		Currency:        strings.ToUpper(req.Currency),
+		PaymentMethod:   req.PaymentMethod,
+		PaymentProvider: req.PaymentProvider,
+		IPAddress:       c.ClientIP(),
+		UserAgent:       c.Request.UserAgent(),
+	})
+	if err != nil {
+		dc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, d, "儲值訂單已建立")
+}
+
+// ConfirmDeposit 主動向閘道查詢並更新訂單狀態（回呼遺失時使用）
+func (dc *DepositController) ConfirmDeposit(c *gin.Context) {
+	d, result, err := dc.depositService.Confirm(c.Param("id"))
+	if err != nil {
+		dc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"deposit": d, "result": result}, "儲值訂單狀態已更新")
+}
+
+// HandleWebhook 支付閘道回呼（公開端點，以簽章驗證來源）
+func (dc *DepositController) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "讀取回呼內容失敗", "INVALID_REQUEST")
+		return
+	}
+	result, err := dc.depositService.HandleWebhook(c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		dc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"result": result}, "回呼已處理")
+}
+
+// CompleteFakePayment 模擬玩家在模擬閘道完成付款，並將產生的已簽章回呼送入回呼處理流程
+func (dc *DepositController) CompleteFakePayment(c *gin.Context) {
+	var req CompleteFakePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	gw, err := dc.depositService.Gateways.Get(payment.FakeProvider)
+	if err != nil {
+		dc.handleError(c, err)
+		return
+	}
+	header, body, err := gw.(*payment.FakeGateway).Complete(c.Param("gateway_tx_id"), payment.Status(req.Status))
+	if err != nil {
+		dc.handleError(c, err)
+		return
+	}
+	result, err := dc.depositService.HandleWebhook(payment.FakeProvider, header, body)
+	if err != nil {
+		dc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"result": result}, "模擬付款已完成")
+}
+
+func (dc *DepositController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDepositNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "DEPOSIT_NOT_FOUND")
+	case errors.Is(err, payment.ErrUnknownProvider):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "UNKNOWN_PROVIDER")
+	case errors.Is(err, payment.ErrTransactionUnknown):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "GATEWAY_TRANSACTION_NOT_FOUND")
+	case errors.Is(err, payment.ErrInvalidSignature):
+		ErrorResponse(c, http.StatusUnauthorized, err.Error(), "INVALID_SIGNATURE")
+	case errors.Is(err, payment.ErrStaleCallback):
+		ErrorResponse(c, http.StatusUnauthorized, err.Error(), "STALE_CALLBACK")
+	case errors.Is(err, payment.ErrMalformedCallback):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "MALFORMED_CALLBACK")
+	case errors.Is(err, services.ErrGatewayUnavailable):
+		ErrorResponse(c, http.StatusBadGateway, err.Error(), "GATEWAY_UNAVAILABLE")
+	case errors.Is(err, services.ErrPlayerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PLAYER_NOT_FOUND")
+	case errors.Is(err, services.ErrPlayerInactive):
+		ErrorResponse(c, http.StatusForbidden, "玩家帳號狀態不允許此操作", "PLAYER_INACTIVE")
+	case errors.Is(err, services.ErrRateNotFound), errors.Is(err, services.ErrCurrencyUnsupported):
+		handleFXError(c, err)
+	case errors.Is(err, services.ErrInvalidAmount):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_AMOUNT")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "儲值操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
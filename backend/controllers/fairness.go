@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"nexus-gaming-backend/rng"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FairnessController 可驗證公平控制器（公開端點，玩家與第三方皆可驗證）
+type FairnessController struct {
+	fairnessService *services.FairnessService
+}
+
+// NewFairnessController 建立新的可驗證公平控制器
+func NewFairnessController() *FairnessController {
+	return &FairnessController{
+		fairnessService: services.NewFairnessService(),
+	}
+}
+
+// VerifyRequest 自行提供種子的驗證請求
+type VerifyRequest struct {
+	ServerSeed     string          `json:"server_seed" binding:"required"`
+	ServerSeedHash string          `json:"server_seed_hash" binding:"required"`
+	ClientSeeds    []string        `json:"client_seeds"`
+	Nonce          int64           `json:"nonce"`
+	Outcome        rng.OutcomeSpec `json:"outcome" binding:"required"`
+}
+
+// GetSessionFairness 取得場次的公平性承諾（揭露前不含伺服器種子）
+func (fc *FairnessController) GetSessionFairness(c *gin.Context) {
+	commitment, err := fc.fairnessService.GetByCode(c.Param("code"))
+	if err != nil {
+		fc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, commitment.Public(), "公平性承諾獲取成功")
+}
+
+// VerifySession 以場次儲存的已揭露種子重算並比對結果
+func (fc *FairnessController) VerifySession(c *gin.Context) {
+	result, matches, err := fc.fairnessService.VerifySession(c.Param("code"))
+	if err != nil {
+		fc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"verification":    result,
+		"outcome_matches": matches,
+		"verified":        result.HashMatches && matches,
+	}, "場次驗證完成")
+}
+
+// Verify 依請求提供的種子重算結果（不需查詢資料庫）
+func (fc *FairnessController) Verify(c *gin.Context) {
+	var req VerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+
+	result, err := rng.Verify(req.ServerSeed, req.ServerSeedHash, req.ClientSeeds, req.Nonce, req.Outcome)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_REQUEST")
+		return
+	}
+	SuccessResponse(c, result, "驗證完成")
+}
+
+func (fc *FairnessController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "SESSION_NOT_FOUND")
+	case errors.Is(err, services.ErrFairnessNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "FAIRNESS_NOT_FOUND")
+	case errors.Is(err, services.ErrFairnessNotRevealed):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "SEED_NOT_REVEALED")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "查詢公平性資料失敗: "+err.Error(), "DATABASE_ERROR")
+	}
+}
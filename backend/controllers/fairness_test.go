@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nexus-gaming-backend/rng"
+
+	"github.com/gin-gonic/gin"
+)
+
+func postVerify(t *testing.T, clientSeeds []string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/fairness/verify", (&FairnessController{}).Verify)
+
+	body, err := json.Marshal(VerifyRequest{
+		ServerSeed:     "seed",
+		ServerSeedHash: rng.HashSeed("seed"),
+		ClientSeeds:    clientSeeds,
+		Nonce:          1,
+		Outcome:        rng.OutcomeSpec{Type: rng.OutcomeDeck},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/fairness/verify", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestVerifyRejectsClientSeedLimits(t *testing.T) {
+	tooMany := make([]string, 17)
+	for i := range tooMany {
+		tooMany[i] = "s"
+	}
+	tests := []struct {
+		name  string
+		seeds []string
+		want  int
+	}{
+		{"within limits", []string{"alice", "bob"}, http.StatusOK},
+		{"too many seeds", tooMany, http.StatusBadRequest},
+		{"seed too long", []string{strings.Repeat("x", 65)}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code := postVerify(t, tt.seeds); code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FXController 幣別與匯率控制器
+type FXController struct {
+	fxService *services.FXService
+}
+
+// NewFXController 建立新的匯率控制器
+func NewFXController() *FXController {
+	return &FXController{fxService: services.NewFXService()}
+}
+
+// RateOverrideRequest 覆寫匯率請求
+type RateOverrideRequest struct {
+	Base        string     `json:"base" binding:"required,len=3"`
+	Quote       string     `json:"quote" binding:"required,len=3"`
+	Rate        money.Rate `json:"rate" binding:"required"`
+	EffectiveAt *time.Time `json:"effective_at"` // 省略時立即生效
+	ExpiresAt   *time.Time `json:"expires_at"`   // 省略時直到下一筆覆寫
+	Note        string     `json:"note" binding:"max=255"`
+}
+
+// GetCurrencies 幣別列表
+func (fc *FXController) GetCurrencies(c *gin.Context) {
+	list, err := fc.fxService.Currencies()
+	if err != nil {
+		handleFXError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "幣別列表獲取成功")
+}
+
+// GetRates 匯率查詢：指定 base 與 quote 時回傳該幣別對，否則列出所有幣別對預設幣別的匯率（at 可指定歷史時間，RFC3339）
+func (fc *FXController) GetRates(c *gin.Context) {
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "時間格式錯誤，請使用 RFC3339", "INVALID_TIME")
+			return
+		}
+		at = t
+	}
+	base, quote := c.Query("base"), c.Query("quote")
+	if base != "" || quote != "" {
+		if base == "" || quote == "" {
+			ErrorResponse(c, http.StatusBadRequest, "base 與 quote 必須同時指定", "INVALID_REQUEST")
+			return
+		}
+		rate, err := fc.fxService.Rate(base, quote, at)
+		if err != nil {
+			handleFXError(c, err)
+			return
+		}
+		SuccessResponse(c, rate, "匯率獲取成功")
+		return
+	}
+	rates, err := fc.fxService.Rates(at)
+	if err != nil {
+		handleFXError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"base_currency": fc.fxService.Wallet.Currency,
+		"at":            at,
+		"rates":         rates,
+	}, "匯率列表獲取成功")
+}
+
+// SetRateOverride 管理員覆寫匯率（新增一筆覆寫紀錄，優先於靜態匯率）
+func (fc *FXController) SetRateOverride(c *gin.Context) {
+	var req RateOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	o := services.RateOverride{
+		Base:        req.Base,
+		Quote:       req.Quote,
+		Rate:        req.Rate,
+		EffectiveAt: time.Now(),
+		ExpiresAt:   req.ExpiresAt,
+		Note:        req.Note,
+		OperatorID:  &operatorID,
+	}
+	if req.EffectiveAt != nil {
+		o.EffectiveAt = *req.EffectiveAt
+	}
+	if o.ExpiresAt != nil && !o.ExpiresAt.After(o.EffectiveAt) {
+		ErrorResponse(c, http.StatusBadRequest, "失效時間必須晚於生效時間", "INVALID_TIME")
+		return
+	}
+	rate, err := fc.fxService.SetOverride(o)
+	if err != nil {
+		handleFXError(c, err)
+		return
+	}
+	SuccessResponse(c, rate, "匯率已覆寫")
+}
+
+func handleFXError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRateNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "RATE_NOT_FOUND")
+	case errors.Is(err, services.ErrCurrencyUnsupported):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "CURRENCY_UNSUPPORTED")
+	case errors.Is(err, services.ErrSameCurrency):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "SAME_CURRENCY")
+	case errors.Is(err, services.ErrConversionTooSmall), errors.Is(err, services.ErrInvalidAmount):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_AMOUNT")
+	case errors.Is(err, money.ErrInvalidRate):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_RATE")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "匯率操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
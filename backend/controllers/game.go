@@ -1,7 +1,14 @@
 package controllers
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/engine/slots"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,12 +38,181 @@ func UpdateGameStatus(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "UpdateGameStatus endpoint not implemented yet", "NOT_IMPLEMENTED")
 }
 
+// GameConfigItem 遊戲配置項目
+type GameConfigItem struct {
+	ID          int             `json:"id"`
+	ConfigKey   string          `json:"config_key"`
+	ConfigValue json.RawMessage `json:"config_value"`
+	Description *string         `json:"description,omitempty"`
+	IsActive    bool            `json:"is_active"`
+}
+
+// UpdateGameConfigRequest 更新遊戲配置請求
+type UpdateGameConfigRequest struct {
+	ConfigKey   string          `json:"config_key" binding:"required"`
+	ConfigValue json.RawMessage `json:"config_value" binding:"required"`
+	Description *string         `json:"description"`
+	IsActive    *bool           `json:"is_active"`
+}
+
+// GetGameConfig 獲取遊戲配置
 func GetGameConfig(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetGameConfig endpoint not implemented yet", "NOT_IMPLEMENTED")
+	gameID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的遊戲ID", "INVALID_GAME_ID")
+		return
+	}
+
+	db := config.GetDB()
+	if db == nil {
+		ErrorResponse(c, http.StatusInternalServerError, "資料庫連接失敗", "DATABASE_ERROR")
+		return
+	}
+	rows, err := db.Query(`
+		SELECT id, config_key, config_value, description, is_active
+		FROM game_configs
+		WHERE game_id = ?
+		ORDER BY config_key
+	`, gameID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢遊戲配置失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+	defer rows.Close()
+
+	configs := []GameConfigItem{}
+	for rows.Next() {
+		var item GameConfigItem
+		var value []byte
+		if err := rows.Scan(&item.ID, &item.ConfigKey, &value, &item.Description, &item.IsActive); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "掃描遊戲配置失敗: "+err.Error(), "DATABASE_ERROR")
+			return
+		}
+		item.ConfigValue = json.RawMessage(value)
+		configs = append(configs, item)
+	}
+
+	SuccessResponse(c, gin.H{"game_id": gameID, "configs": configs}, "遊戲配置獲取成功")
 }
 
+// UpdateGameConfig 更新遊戲配置（老虎機設定需通過 RTP 驗證）
 func UpdateGameConfig(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "UpdateGameConfig endpoint not implemented yet", "NOT_IMPLEMENTED")
+	gameID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的遊戲ID", "INVALID_GAME_ID")
+		return
+	}
+
+	var req UpdateGameConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if !json.Valid(req.ConfigValue) {
+		ErrorResponse(c, http.StatusBadRequest, "config_value 必須為合法 JSON", "INVALID_REQUEST")
+		return
+	}
+
+	db := config.GetDB()
+	if db == nil {
+		ErrorResponse(c, http.StatusInternalServerError, "資料庫連接失敗", "DATABASE_ERROR")
+		return
+	}
+
+	var gameType string
+	var rtpRate float64
+	err = db.QueryRow("SELECT game_type, rtp_rate FROM games WHERE id = ?", gameID).Scan(&gameType, &rtpRate)
+	if err == sql.ErrNoRows {
+		ErrorResponse(c, http.StatusNotFound, "遊戲不存在", "GAME_NOT_FOUND")
+		return
+	} else if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢遊戲資料失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+
+	// 老虎機設定：計算 RTP 並與 games.rtp_rate 比對
+	var report *slots.RTPReport
+	if gameType == "slots" && req.ConfigKey == slots.ConfigKey {
+		report, err = validateSlotConfig(req.ConfigValue, rtpRate)
+		if err != nil {
+			var deviation *slots.RTPDeviationError
+			if errors.As(err, &deviation) {
+				c.JSON(http.StatusUnprocessableEntity, APIResponse{
+					Success: false,
+					Message: err.Error(),
+					Data:    gin.H{"rtp_report": report, "target_rtp": rtpRate},
+					Code:    "RTP_OUT_OF_TOLERANCE",
+				})
+				return
+			}
+			ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_SLOT_CONFIG")
+			return
+		}
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO game_configs (game_id, config_key, config_value, description, is_active)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			config_value = VALUES(config_value),
+			description = COALESCE(VALUES(description), description),
+			is_active = VALUES(is_active)
+	`, gameID, req.ConfigKey, string(req.ConfigValue), req.Description, isActive)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "儲存遊戲配置失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+
+	data := gin.H{
+		"game_id":    gameID,
+		"config_key": req.ConfigKey,
+	}
+	if report != nil {
+		data["rtp_report"] = report
+		data["target_rtp"] = rtpRate
+	}
+	SuccessResponse(c, data, "遊戲配置更新成功")
+}
+
+// validateSlotConfig 解析老虎機設定並驗證 RTP
+//
+// 於請求內同步執行：組合數可負擔時窮舉；否則模擬次數不超過 slots.MaxInlineSimulationSpins，
+// 並以模擬的三倍標準誤放寬容許差距，避免小樣本誤判。精確驗證請以 cmd/slotsrtp 離線執行。
+func validateSlotConfig(raw json.RawMessage, targetRTP float64) (*slots.RTPReport, error) {
+	cfg, err := slots.ParseConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	machine, err := slots.NewMachine(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := slots.RTPOptions{}
+	tolerance := 0.005
+	if config.AppConfig != nil {
+		opts.MaxCombinations = int64(config.AppConfig.Game.SlotsRTPMaxCombos)
+		opts.Spins = int64(config.AppConfig.Game.SlotsRTPSimSpins)
+		tolerance = config.AppConfig.Game.SlotsRTPTolerance
+	}
+
+	if opts.Spins <= 0 || opts.Spins > slots.MaxInlineSimulationSpins {
+		opts.Spins = slots.MaxInlineSimulationSpins
+	}
+
+	report, err := machine.CalculateRTP(opts)
+	if err != nil {
+		return nil, err
+	}
+	if report.Method == slots.MethodMonteCarlo {
+		tolerance += 3 * report.StdError
+	}
+	return report, slots.CheckRTP(report, targetRTP, tolerance)
 }
 
 func GetGameOdds(c *gin.Context) {
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 未連線資料庫時（config.DB 為 nil）遊戲設定端點回傳 500 而不是 panic
+func TestGameConfigWithoutDatabase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/games/:id/config", GetGameConfig)
+	r.PUT("/games/:id/config", UpdateGameConfig)
+
+	tests := []struct {
+		method, body string
+	}{
+		{http.MethodGet, ""},
+		{http.MethodPut, `{"config_key":"slot_machine","config_value":{"rows":3}}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/games/1/config", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: status = %d, want %d", tt.method, w.Code, http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandHistoryController 牌局歷史控制器
+type HandHistoryController struct {
+	historyService *services.HandHistoryService
+}
+
+// NewHandHistoryController 建立新的牌局歷史控制器
+func NewHandHistoryController() *HandHistoryController {
+	return &HandHistoryController{
+		historyService: services.NewHandHistoryService(),
+	}
+}
+
+// GetHand 取得完整牌局歷史（管理端，含所有暗牌）
+func (hc *HandHistoryController) GetHand(c *gin.Context) {
+	stored, err := hc.historyService.Get(c.Param("code"))
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, stored, "牌局歷史獲取成功")
+}
+
+// ReplayHand 以紀錄的種子與動作重新執行引擎並比對結果
+func (hc *HandHistoryController) ReplayHand(c *gin.Context) {
+	replay, err := hc.historyService.Replay(c.Param("code"))
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, replay, "牌局重播完成")
+}
+
+// ExportHand 以 PokerStars 相容文字格式匯出牌局歷史（管理端）
+func (hc *HandHistoryController) ExportHand(c *gin.Context) {
+	stored, err := hc.historyService.Get(c.Param("code"))
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+	hc.writeText(c, stored.History.SessionCode, stored.History.Text(-1))
+}
+
+// GetPlayerHand 取得玩家自己參與的牌局歷史（只含自己與攤牌座位的暗牌）
+func (hc *HandHistoryController) GetPlayerHand(c *gin.Context) {
+	stored, _, err := hc.historyService.GetForPlayer(c.Param("code"), c.GetInt64("player_id"))
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, stored, "牌局歷史獲取成功")
+}
+
+// ExportPlayerHand 以文字格式匯出玩家自己參與的牌局
+func (hc *HandHistoryController) ExportPlayerHand(c *gin.Context) {
+	stored, seat, err := hc.historyService.GetForPlayer(c.Param("code"), c.GetInt64("player_id"))
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+	hc.writeText(c, stored.History.SessionCode, stored.History.Text(seat))
+}
+
+func (hc *HandHistoryController) writeText(c *gin.Context, code, text string) {
+	c.Header("Content-Disposition", `attachment; filename="`+code+`.txt"`)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+}
+
+func (hc *HandHistoryController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrHandHistoryNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "HAND_HISTORY_NOT_FOUND")
+	case errors.Is(err, services.ErrNotHandParticipant):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "NOT_PARTICIPANT")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "處理牌局歷史失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LedgerController 複式記帳總帳控制器
+type LedgerController struct {
+	walletService *services.WalletService
+	ledgerService *services.LedgerService
+}
+
+// NewLedgerController 建立新的總帳控制器
+func NewLedgerController() *LedgerController {
+	wallet := services.NewWalletService()
+	return &LedgerController{
+		walletService: wallet,
+		ledgerService: wallet.Ledger,
+	}
+}
+
+// ReverseTransactionRequest 沖銷交易請求
+type ReverseTransactionRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// GetTransactionJournal 取得交易對應的總帳傳票（含分錄與沖銷狀態）
+func (lc *LedgerController) GetTransactionJournal(c *gin.Context) {
+	journal, err := lc.ledgerService.GetByTransaction(c.Param("id"))
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, journal, "傳票獲取成功")
+}
+
+// ReverseTransaction 以沖銷傳票更正一筆交易（原分錄不修改）
+func (lc *LedgerController) ReverseTransaction(c *gin.Context) {
+	var req ReverseTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	txn, err := lc.walletService.Reverse(c.Param("id"), req.Reason, &operatorID)
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, txn, "交易已沖銷")
+}
+
+// VerifyLedger 檢查總帳完整性（傳票借貸平衡、錢包餘額等於總帳合計、凍結餘額等於有效凍結合計）
+func (lc *LedgerController) VerifyLedger(c *gin.Context) {
+	report, err := lc.ledgerService.Verify()
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+	message := "總帳檢查通過"
+	if !report.Balanced {
+		message = "總帳檢查發現不一致"
+	}
+	SuccessResponse(c, report, message)
+}
+
+func (lc *LedgerController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrJournalNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "JOURNAL_NOT_FOUND")
+	case errors.Is(err, services.ErrAlreadyReversed), errors.Is(err, services.ErrReverseReversal):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "ALREADY_REVERSED")
+	case errors.Is(err, services.ErrInsufficientBalance):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INSUFFICIENT_BALANCE")
+	case errors.Is(err, services.ErrUnbalancedJournal):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "UNBALANCED_JOURNAL")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "總帳操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentMethodController 玩家支付方式與加密金鑰管理控制器
+type PaymentMethodController struct {
+	paymentMethodService *services.PaymentMethodService
+	vaultService         *services.VaultService
+}
+
+// NewPaymentMethodController 建立新的支付方式控制器
+func NewPaymentMethodController() *PaymentMethodController {
+	return &PaymentMethodController{
+		paymentMethodService: services.NewPaymentMethodService(),
+		vaultService:         services.NewVaultService(),
+	}
+}
+
+// CreatePaymentMethodRequest 新增支付方式請求
+type CreatePaymentMethodRequest struct {
+	MethodType   string            `json:"method_type" binding:"required,oneof=credit_card debit_card bank_account e_wallet cryptocurrency"`
+	ProviderName string            `json:"provider_name" binding:"required,max=50"`
+	AccountInfo  map[string]string `json:"account_info" binding:"required"`
+	DisplayName  string            `json:"display_name" binding:"max=100"`
+	IsDefault    bool              `json:"is_default"`
+}
+
+// UpdatePaymentMethodRequest 更新支付方式請求（帳戶資訊不可修改）
+type UpdatePaymentMethodRequest struct {
+	ProviderName string `json:"provider_name" binding:"max=50"`
+	DisplayName  string `json:"display_name" binding:"max=100"`
+}
+
+// ReportFraudRequest 通報詐欺請求
+type ReportFraudRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// GetPaymentMethods 玩家的支付方式列表（帳戶資訊遮罩）
+func (pc *PaymentMethodController) GetPaymentMethods(c *gin.Context) {
+	playerID, ok := paymentMethodOwner(c)
+	if !ok {
+		return
+	}
+	list, err := pc.paymentMethodService.List(playerID)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "支付方式列表獲取成功")
+}
+
+// CreatePaymentMethod 新增支付方式（帳戶資訊加密保存，已遭詐欺封鎖的帳號不可新增）
+func (pc *PaymentMethodController) CreatePaymentMethod(c *gin.Context) {
+	playerID, ok := paymentMethodOwner(c)
+	if !ok {
+		return
+	}
+	var req CreatePaymentMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	m, err := pc.paymentMethodService.Create(services.PaymentMethodRequest{
+		PlayerID:     playerID,
+		MethodType:   req.MethodType,
+		ProviderName: req.ProviderName,
+		AccountInfo:  req.AccountInfo,
+		DisplayName:  req.DisplayName,
+		Default:      req.IsDefault,
+	})
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, m, "支付方式已新增")
+}
+
+// UpdatePaymentMethod 更新支付方式的顯示名稱與提供商
+func (pc *PaymentMethodController) UpdatePaymentMethod(c *gin.Context) {
+	playerID, methodID, ok := paymentMethodTarget(c)
+	if !ok {
+		return
+	}
+	var req UpdatePaymentMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	m, err := pc.paymentMethodService.Update(playerID, methodID, req.ProviderName, req.DisplayName)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, m, "支付方式已更新")
+}
+
+// DeletePaymentMethod 刪除支付方式
+func (pc *PaymentMethodController) DeletePaymentMethod(c *gin.Context) {
+	playerID, methodID, ok := paymentMethodTarget(c)
+	if !ok {
+		return
+	}
+	if err := pc.paymentMethodService.Delete(playerID, methodID); err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, nil, "支付方式已刪除")
+}
+
+// SetDefaultPaymentMethod 設為預設支付方式
+func (pc *PaymentMethodController) SetDefaultPaymentMethod(c *gin.Context) {
+	playerID, methodID, ok := paymentMethodTarget(c)
+	if !ok {
+		return
+	}
+	m, err := pc.paymentMethodService.SetDefault(playerID, methodID)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, m, "已設為預設支付方式")
+}
+
+// VerifyPaymentMethod 標記支付方式已驗證
+func (pc *PaymentMethodController) VerifyPaymentMethod(c *gin.Context) {
+	playerID, methodID, ok := paymentMethodTarget(c)
+	if !ok {
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	m, err := pc.paymentMethodService.Verify(playerID, methodID, &operatorID)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, m, "支付方式已驗證")
+}
+
+// ReportPaymentMethodFraud 通報詐欺並封鎖所有玩家的相同帳號
+func (pc *PaymentMethodController) ReportPaymentMethodFraud(c *gin.Context) {
+	playerID, methodID, ok := paymentMethodTarget(c)
+	if !ok {
+		return
+	}
+	var req ReportFraudRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	blocked, err := pc.paymentMethodService.ReportFraud(playerID, methodID, req.Reason, &operatorID)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"blocked_methods": blocked}, "已封鎖此帳號")
+}
+
+// GetBlockedAccounts 詐欺封鎖的帳號列表
+func (pc *PaymentMethodController) GetBlockedAccounts(c *gin.Context) {
+	list, err := pc.paymentMethodService.Blocked()
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "封鎖帳號列表獲取成功")
+}
+
+// UnblockAccount 解除帳號封鎖
+func (pc *PaymentMethodController) UnblockAccount(c *gin.Context) {
+	restored, err := pc.paymentMethodService.Unblock(c.Param("fingerprint"))
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"restored_methods": restored}, "已解除封鎖")
+}
+
+// GetVaultKeys 各加密欄位依主金鑰統計的資料筆數
+func (pc *PaymentMethodController) GetVaultKeys(c *gin.Context) {
+	usage, err := pc.vaultService.Usage()
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, usage, "加密金鑰使用狀況獲取成功")
+}
+
+// RotateVaultKeys 將所有加密欄位重新包裝為目前的主金鑰
+func (pc *PaymentMethodController) RotateVaultKeys(c *gin.Context) {
+	result, err := pc.vaultService.Rotate()
+	if err != nil {
+		if errors.Is(err, services.ErrVaultUnavailable) {
+			pc.handleError(c, err)
+			return
+		}
+		ErrorResponse(c, http.StatusInternalServerError, "金鑰輪替未完成: "+err.Error(), "VAULT_ROTATE_FAILED")
+		return
+	}
+	SuccessResponse(c, result, "金鑰輪替完成")
+}
+
+// paymentMethodOwner 支付方式所屬玩家：管理端取自路徑，玩家端取自玩家 Token
+func paymentMethodOwner(c *gin.Context) (int64, bool) {
+	if c.Param("id") == "" {
+		return c.GetInt64("player_id"), true
+	}
+	playerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的玩家ID", "INVALID_PLAYER_ID")
+		return 0, false
+	}
+	return playerID, true
+}
+
+func paymentMethodTarget(c *gin.Context) (int64, int, bool) {
+	playerID, ok := paymentMethodOwner(c)
+	if !ok {
+		return 0, 0, false
+	}
+	methodID, err := strconv.Atoi(c.Param("method_id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的支付方式ID", "INVALID_PAYMENT_METHOD_ID")
+		return 0, 0, false
+	}
+	return playerID, methodID, true
+}
+
+func (pc *PaymentMethodController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPaymentMethodNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PAYMENT_METHOD_NOT_FOUND")
+	case errors.Is(err, services.ErrPaymentMethodBlocked):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "PAYMENT_METHOD_BLOCKED")
+	case errors.Is(err, services.ErrPaymentMethodInactive):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "PAYMENT_METHOD_INACTIVE")
+	case errors.Is(err, services.ErrAccountNumberRequired), errors.Is(err, services.ErrSensitiveField):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_ACCOUNT_INFO")
+	case errors.Is(err, services.ErrFingerprintNotBlocked):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "ACCOUNT_NOT_BLOCKED")
+	case errors.Is(err, services.ErrVaultUnavailable):
+		ErrorResponse(c, http.StatusServiceUnavailable, err.Error(), "VAULT_UNAVAILABLE")
+	case errors.Is(err, services.ErrPlayerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PLAYER_NOT_FOUND")
+	case errors.Is(err, services.ErrPlayerInactive):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "PLAYER_INACTIVE")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "支付方式操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -12,16 +12,31 @@ import (
 
 	"nexus-gaming-backend/config"
 	"nexus-gaming-backend/models"
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // PlayerController 玩家控制器
-type PlayerController struct{}
+type PlayerController struct {
+	walletService      *services.WalletService
+	holdService        *services.HoldService
+	fxService          *services.FXService
+	idempotencyService *services.IdempotencyService
+}
 
 // NewPlayerController 建立新的玩家控制器
 func NewPlayerController() *PlayerController {
-	return &PlayerController{}
+	holds := services.NewHoldService()
+	fx := services.NewFXService()
+	fx.Wallet = holds.Wallet
+	return &PlayerController{
+		walletService:      holds.Wallet,
+		holdService:        holds,
+		fxService:          fx,
+		idempotencyService: services.NewIdempotencyService(),
+	}
 }
 
 // PlayerListRequest 玩家列表查詢請求
@@ -46,20 +61,23 @@ type PlayerWithBalance struct {
 	Balance float64 `json:"balance"` // 錢包餘額
 }
 
-// PlayerDetailResponse 玩家詳細資訊回應
+// PlayerDetailResponse 玩家詳細資訊回應（除基本資料與預設幣別餘額外，其餘欄位僅在 ?include= 指定時回傳）
 type PlayerDetailResponse struct {
 	PlayerWithBalance
-	Tags         []PlayerTag         `json:"tags"`         // 玩家標籤
-	Restrictions []PlayerRestriction `json:"restrictions"` // 玩家限制
-	Statistics   PlayerStatistics    `json:"statistics"`   // 玩家統計數據
-	RecentGames  []GameParticipation `json:"recent_games"` // 最近遊戲記錄
+	Currency     string                   `json:"currency"`               // 餘額幣別（預設幣別）
+	Tags         []PlayerTag              `json:"tags,omitempty"`         // 玩家標籤
+	Restrictions []PlayerRestriction      `json:"restrictions,omitempty"` // 目前生效的玩家限制
+	Statistics   *PlayerStatistics        `json:"statistics,omitempty"`   // 玩家統計數據
+	RecentGames  []GameParticipation      `json:"recent_games,omitempty"` // 最近遊戲記錄（不含練習場）
+	Wallets      []services.WalletBalance `json:"wallets,omitempty"`      // 各幣別錢包餘額
 }
 
 // PlayerTag 玩家標籤（簡化版）
 type PlayerTag struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
-	Type string `json:"type"`
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Color      string    `json:"color"`
+	AssignedAt time.Time `json:"assigned_at"`
 }
 
 // PlayerRestriction 玩家限制（簡化版）
@@ -71,15 +89,25 @@ type PlayerRestriction struct {
 	ExpiresAt       *time.Time `json:"expires_at"`
 }
 
-// PlayerStatistics 玩家統計數據
+// PlayerStatistics 玩家統計數據（僅計入已結束且非練習場的遊戲參與）
 type PlayerStatistics struct {
-	TotalGames       int     `json:"total_games"`
-	WinRate          float64 `json:"win_rate"`
-	AverageBet       float64 `json:"average_bet"`
-	BiggestWin       float64 `json:"biggest_win"`
-	BiggestLoss      float64 `json:"biggest_loss"`
-	DaysRegistered   int     `json:"days_registered"`
-	LastActivityDays int     `json:"last_activity_days"`
+	TotalGames       int                        `json:"total_games"`
+	WinRate          float64                    `json:"win_rate"`           // 淨結果為正的局數百分比
+	Currencies       []PlayerCurrencyStatistics `json:"currencies"`         // 依幣別的金額統計（不同幣別不可相加）
+	DaysRegistered   int                        `json:"days_registered"`    // 註冊至今天數
+	LastActivityDays int                        `json:"last_activity_days"` // 距最後一次遊戲或登入的天數
+}
+
+// PlayerCurrencyStatistics 玩家單一幣別的金額統計（幣別與遊戲歷史記錄的 currency 一致）
+type PlayerCurrencyStatistics struct {
+	Currency    string       `json:"currency"`
+	Games       int          `json:"games"`
+	TotalBet    money.Amount `json:"total_bet"`
+	TotalWin    money.Amount `json:"total_win"`
+	NetResult   money.Amount `json:"net_result"`
+	AverageBet  money.Amount `json:"average_bet"`  // 每局平均下注（四捨五入至分）
+	BiggestWin  money.Amount `json:"biggest_win"`  // 單局最大淨贏額（無贏局為0）
+	BiggestLoss money.Amount `json:"biggest_loss"` // 單局最大淨輸額，以正數表示（無輸局為0）
 }
 
 // GameParticipation 遊戲參與記錄（簡化版）
@@ -94,9 +122,10 @@ type GameParticipation struct {
 
 // PlayerGamePreferenceRequest 玩家遊戲偏好分析請求
 type PlayerGamePreferenceRequest struct {
-	TimeRange     string `json:"time_range" binding:"required,oneof=7d 30d 90d 180d 365d"` // 分析時間範圍
-	IncludeGraphs *bool  `json:"include_graphs"`                                           // 是否包含圖表資料（預設true）
-	MinGames      *int   `json:"min_games"`                                                // 最少遊戲次數門檻（預設10）
+	TimeRange       string `json:"time_range" binding:"required,oneof=7d 30d 90d 180d 365d"` // 分析時間範圍
+	IncludeGraphs   *bool  `json:"include_graphs"`                                           // 是否包含圖表資料（預設true）
+	MinGames        *int   `json:"min_games"`                                                // 最少遊戲次數門檻（預設10）
+	IncludePractice *bool  `json:"include_practice"`                                         // 遊戲類型統計是否納入練習場（預設false）
 }
 
 // PlayerGamePreferenceResponse 玩家遊戲偏好分析回應
@@ -469,6 +498,10 @@ func (pc *PlayerController) AnalyzePlayerGamePreference(c *gin.Context) {
 		defaultMinGames := 10
 		req.MinGames = &defaultMinGames
 	}
+	if req.IncludePractice == nil {
+		defaultIncludePractice := false
+		req.IncludePractice = &defaultIncludePractice
+	}
 
 	// 獲取資料庫連接
 	db := config.GetDB()
@@ -605,7 +638,7 @@ func (pc *PlayerController) performGamePreferenceAnalysis(db *sql.DB, playerID i
 	}
 
 	// 1. 分析遊戲類型統計
-	gameTypeStats, err := pc.analyzeGameTypeStatistics(db, playerID, startDate, now, *req.MinGames)
+	gameTypeStats, err := pc.analyzeGameTypeStatistics(db, playerID, startDate, now, *req.MinGames, *req.IncludePractice)
 	if err != nil {
 		return nil, fmt.Errorf("分析遊戲類型統計失敗: %v", err)
 	}
@@ -655,28 +688,31 @@ func (pc *PlayerController) performGamePreferenceAnalysis(db *sql.DB, playerID i
 	return analysis, nil
 }
 
-// analyzeGameTypeStatistics 分析遊戲類型統計
-func (pc *PlayerController) analyzeGameTypeStatistics(db *sql.DB, playerID int64, startDate, endDate time.Time, minGames int) ([]GameTypeStatistics, error) {
+// analyzeGameTypeStatistics 分析遊戲類型統計（includePractice 為 false 時排除練習場）
+func (pc *PlayerController) analyzeGameTypeStatistics(db *sql.DB, playerID int64, startDate, endDate time.Time, minGames int, includePractice bool) ([]GameTypeStatistics, error) {
+	practiceFilter := "AND is_practice = FALSE"
+	if includePractice {
+		practiceFilter = ""
+	}
 	query := `
 		SELECT 
-			ps.game_type,
+			game_type,
 			COUNT(*) as games_played,
-			SUM(TIMESTAMPDIFF(MINUTE, ps.session_start, ps.session_end)) as total_time_spent,
-			AVG(TIMESTAMPDIFF(MINUTE, ps.session_start, ps.session_end)) as average_session,
-			SUM(pg.bet_amount) as total_bet_amount,
-			SUM(pg.win_amount) as total_win_amount,
-			AVG(CASE WHEN pg.result = 'win' THEN 1 ELSE 0 END) * 100 as win_rate
-		FROM player_sessions ps
-		LEFT JOIN player_games pg ON ps.id = pg.session_id
-		WHERE ps.player_id = ? 
-		AND ps.session_start BETWEEN ? AND ?
-		AND pg.bet_amount IS NOT NULL
-		GROUP BY ps.game_type
+			SUM(session_duration) / 60 as total_time_spent,
+			AVG(session_duration) / 60 as average_session,
+			SUM(total_bet_amount) as total_bet_amount,
+			SUM(total_win_amount) as total_win_amount,
+			AVG(CASE WHEN net_result > 0 THEN 1 ELSE 0 END) * 100 as win_rate
+		FROM player_game_sessions
+		WHERE player_id = ? 
+		AND start_time BETWEEN ? AND ?
+		` + practiceFilter + `
+		GROUP BY game_type
 		HAVING games_played >= ?
 		ORDER BY games_played DESC
 	`
 
-	rows, err := db.Query(query, playerID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), minGames)
+	rows, err := db.Query(query, playerID, startDate, endDate, minGames)
 	if err != nil {
 		return nil, fmt.Errorf("查詢遊戲類型統計失敗: %v", err)
 	}
@@ -2797,16 +2833,6 @@ func (pc *PlayerController) GetPlayers(c *gin.Context) {
 	SuccessResponse(c, response, "玩家列表獲取成功")
 }
 
-// GetPlayer 獲取單個玩家詳細資訊
-func (pc *PlayerController) GetPlayer(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetPlayer endpoint not implemented yet", "NOT_IMPLEMENTED")
-}
-
-// GetPlayerGameHistory 獲取玩家遊戲歷史
-func (pc *PlayerController) GetPlayerGameHistory(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetPlayerGameHistory endpoint not implemented yet", "NOT_IMPLEMENTED")
-}
-
 // SearchPlayers 搜尋玩家
 func (pc *PlayerController) SearchPlayers(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "SearchPlayers endpoint not implemented yet", "NOT_IMPLEMENTED")
@@ -2837,21 +2863,6 @@ func (pc *PlayerController) UpdatePlayerStatus(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "UpdatePlayerStatus endpoint not implemented yet", "NOT_IMPLEMENTED")
 }
 
-// GetPlayerBalance 獲取玩家餘額
-func (pc *PlayerController) GetPlayerBalance(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetPlayerBalance endpoint not implemented yet", "NOT_IMPLEMENTED")
-}
-
-// DepositPlayerBalance 玩家充值
-func (pc *PlayerController) DepositPlayerBalance(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "DepositPlayerBalance endpoint not implemented yet", "NOT_IMPLEMENTED")
-}
-
-// WithdrawPlayerBalance 玩家提領
-func (pc *PlayerController) WithdrawPlayerBalance(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "WithdrawPlayerBalance endpoint not implemented yet", "NOT_IMPLEMENTED")
-}
-
 // GetPlayerTransactions 獲取玩家交易記錄
 func (pc *PlayerController) GetPlayerTransactions(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "GetPlayerTransactions endpoint not implemented yet", "NOT_IMPLEMENTED")
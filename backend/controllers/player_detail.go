@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 玩家詳細資訊可展開的區塊（?include=tags,restrictions,...；all 表示全部）
+const (
+	PlayerIncludeTags         = "tags"
+	PlayerIncludeRestrictions = "restrictions"
+	PlayerIncludeStatistics   = "statistics"
+	PlayerIncludeRecentGames  = "recent_games"
+	PlayerIncludeWallets      = "wallets"
+)
+
+var playerIncludes = []string{
+	PlayerIncludeTags,
+	PlayerIncludeRestrictions,
+	PlayerIncludeStatistics,
+	PlayerIncludeRecentGames,
+	PlayerIncludeWallets,
+}
+
+const (
+	defaultRecentGames = 10
+	maxRecentGames     = 50
+)
+
+// participationResultSQL 遊戲參與結果（進行中為 playing，否則依淨結果判定 win／loss／push）
+const participationResultSQL = `CASE WHEN gp.status = 'playing' THEN 'playing'
+	WHEN gp.net_result > 0 THEN 'win'
+	WHEN gp.net_result < 0 THEN 'loss'
+	ELSE 'push' END`
+
+// parsePlayerIncludes 解析 include 參數；空字串表示不展開任何區塊
+func parsePlayerIncludes(raw string) (map[string]bool, error) {
+	includes := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if part == "all" {
+			for _, name := range playerIncludes {
+				includes[name] = true
+			}
+			continue
+		}
+		valid := false
+		for _, name := range playerIncludes {
+			if part == name {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("不支援的 include 項目: %s（可用: %s, all）", part, strings.Join(playerIncludes, ", "))
+		}
+		includes[part] = true
+	}
+	return includes, nil
+}
+
+// GetPlayer 獲取單個玩家詳細資訊
+//
+// 預設僅回傳基本資料與預設幣別餘額；標籤、限制、統計、最近遊戲與錢包以 ?include= 展開，
+// 每個區塊固定一次查詢（最多六次），不因資料筆數增加查詢次數。
+func (pc *PlayerController) GetPlayer(c *gin.Context) {
+	playerID, ok := parsePlayerID(c)
+	if !ok {
+		return
+	}
+	includes, err := parsePlayerIncludes(c.Query("include"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_INCLUDE")
+		return
+	}
+	recentLimit := defaultRecentGames
+	if raw := c.Query("recent_limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxRecentGames {
+			ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("recent_limit 必須介於 1 到 %d", maxRecentGames), "VALIDATION_FAILED")
+			return
+		}
+		recentLimit = n
+	}
+
+	db := config.GetDB()
+	if db == nil {
+		ErrorResponse(c, http.StatusInternalServerError, "資料庫連接失敗", "DATABASE_ERROR")
+		return
+	}
+
+	detail, err := pc.loadPlayerDetail(db, playerID)
+	if err == sql.ErrNoRows {
+		ErrorResponse(c, http.StatusNotFound, "玩家不存在", "PLAYER_NOT_FOUND")
+		return
+	} else if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢玩家資料失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+
+	if includes[PlayerIncludeTags] {
+		if detail.Tags, err = loadPlayerTags(db, playerID); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "查詢玩家標籤失敗: "+err.Error(), "DATABASE_ERROR")
+			return
+		}
+	}
+	if includes[PlayerIncludeRestrictions] {
+		if detail.Restrictions, err = loadActivePlayerRestrictions(db, playerID); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "查詢玩家限制失敗: "+err.Error(), "DATABASE_ERROR")
+			return
+		}
+	}
+	if includes[PlayerIncludeStatistics] {
+		if detail.Statistics, err = pc.loadPlayerStatistics(db, detail); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "查詢玩家統計失敗: "+err.Error(), "DATABASE_ERROR")
+			return
+		}
+	}
+	if includes[PlayerIncludeRecentGames] {
+		if detail.RecentGames, err = loadRecentGames(db, playerID, recentLimit); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "查詢最近遊戲記錄失敗: "+err.Error(), "DATABASE_ERROR")
+			return
+		}
+	}
+	if includes[PlayerIncludeWallets] {
+		if detail.Wallets, err = pc.walletService.Balances(playerID); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "查詢玩家錢包失敗: "+err.Error(), "DATABASE_ERROR")
+			return
+		}
+	}
+
+	SuccessResponse(c, detail, "玩家詳細資訊獲取成功")
+}
+
+// loadPlayerDetail 查詢玩家基本資料與預設幣別的可用餘額
+func (pc *PlayerController) loadPlayerDetail(db *sql.DB, playerID int64) (*PlayerDetailResponse, error) {
+	detail := &PlayerDetailResponse{Currency: pc.walletService.Currency}
+	p := &detail.Player
+	err := db.QueryRow(`
+		SELECT p.id, p.player_id, p.username, p.email, p.phone, p.real_name, p.nickname, p.avatar_url,
+		       p.birth_date, p.gender, p.country, COALESCE(p.language, ''), COALESCE(p.timezone, ''),
+		       p.status, p.verification_level, p.risk_level, COALESCE(p.vip_level, 0),
+		       p.referrer_id, p.agent_id, p.dealer_id, p.registration_ip, p.last_login_ip, p.last_login_at,
+		       COALESCE(p.login_count, 0), COALESCE(p.total_deposit, 0), COALESCE(p.total_withdraw, 0),
+		       COALESCE(p.total_bet, 0), COALESCE(p.total_win, 0), p.created_at, p.updated_at, p.deleted_at,
+		       COALESCE(w.balance, 0)
+		FROM players p
+		LEFT JOIN player_wallets w ON w.player_id = p.id AND w.currency = ?
+		WHERE p.id = ?`, detail.Currency, playerID).Scan(
+		&p.ID, &p.PlayerID, &p.Username, &p.Email, &p.Phone, &p.RealName, &p.Nickname, &p.AvatarURL,
+		&p.BirthDate, &p.Gender, &p.Country, &p.Language, &p.Timezone,
+		&p.Status, &p.VerificationLevel, &p.RiskLevel, &p.VIPLevel,
+		&p.ReferrerID, &p.AgentID, &p.DealerID, &p.RegistrationIP, &p.LastLoginIP, &p.LastLoginAt,
+		&p.LoginCount, &p.TotalDeposit, &p.TotalWithdraw,
+		&p.TotalBet, &p.TotalWin, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt,
+		&detail.Balance,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// loadPlayerTags 查詢玩家的所有標籤
+func loadPlayerTags(db *sql.DB, playerID int64) ([]PlayerTag, error) {
+	rows, err := db.Query(`
+		SELECT t.id, t.name, COALESCE(t.color, ''), r.assigned_at
+		FROM player_tag_relations r
+		JOIN player_tags t ON t.id = r.tag_id
+		WHERE r.player_id = ?
+		ORDER BY r.assigned_at, t.id`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []PlayerTag{}
+	for rows.Next() {
+		var tag PlayerTag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Color, &tag.AssignedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// loadActivePlayerRestrictions 查詢目前生效中的玩家限制（已啟用、已開始且尚未結束）
+func loadActivePlayerRestrictions(db *sql.DB, playerID int64) ([]PlayerRestriction, error) {
+	rows, err := db.Query(`
+		SELECT id, restriction_type, CAST(restriction_value AS CHAR), end_time
+		FROM player_restrictions
+		WHERE player_id = ? AND is_active = TRUE
+		  AND (start_time IS NULL OR start_time <= NOW())
+		  AND (end_time IS NULL OR end_time > NOW())
+		ORDER BY restriction_type, id`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	restrictions := []PlayerRestriction{}
+	for rows.Next() {
+		r := PlayerRestriction{IsActive: true}
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&r.ID, &r.RestrictionType, &r.Value, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			r.ExpiresAt = &expiresAt.Time
+		}
+		restrictions = append(restrictions, r)
+	}
+	return restrictions, rows.Err()
+}
+
+// loadPlayerStatistics 以單次彙總查詢計算玩家統計（排除進行中與練習場的遊戲參與）
+//
+// 金額依房間幣別分組加總，未設定幣別的房間歸入預設幣別，與遊戲歷史記錄的 currency 相同。
+func (pc *PlayerController) loadPlayerStatistics(db *sql.DB, detail *PlayerDetailResponse) (*PlayerStatistics, error) {
+	rows, err := db.Query(`
+		SELECT COALESCE(r.currency, ''), COUNT(*),
+		       COALESCE(SUM(CASE WHEN gp.net_result > 0 THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(gp.total_bet), 0), COALESCE(SUM(gp.total_win), 0), COALESCE(SUM(gp.net_result), 0),
+		       COALESCE(MAX(gp.net_result), 0), COALESCE(MIN(gp.net_result), 0),
+		       MAX(gp.join_time)
+		FROM game_participations gp
+		JOIN game_sessions gs ON gs.id = gp.session_id
+		JOIN game_rooms r ON r.id = gs.room_id
+		WHERE gp.player_id = ? AND gp.status <> 'playing' AND gs.session_type <> 'practice'
+		GROUP BY COALESCE(r.currency, '')
+		ORDER BY COUNT(*) DESC`, detail.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := PlayerStatistics{Currencies: []PlayerCurrencyStatistics{}}
+	index := map[string]int{}
+	var wins int
+	lastActivity := detail.CreatedAt
+	for rows.Next() {
+		var (
+			currency       string
+			row            PlayerCurrencyStatistics
+			rowWins        int
+			maxNet, minNet money.Amount
+			lastGame       sql.NullTime
+		)
+		if err := rows.Scan(&currency, &row.Games, &rowWins, &row.TotalBet, &row.TotalWin, &row.NetResult,
+			&maxNet, &minNet, &lastGame); err != nil {
+			return nil, err
+		}
+		stats.TotalGames += row.Games
+		wins += rowWins
+		if lastGame.Valid && lastGame.Time.After(lastActivity) {
+			lastActivity = lastGame.Time
+		}
+
+		// 未設定幣別的房間與明確設定預設幣別的房間合併為同一幣別
+		currency = pc.walletService.CurrencyOf(currency)
+		i, ok := index[currency]
+		if !ok {
+			i = len(stats.Currencies)
+			index[currency] = i
+			stats.Currencies = append(stats.Currencies, PlayerCurrencyStatistics{Currency: currency})
+		}
+		c := &stats.Currencies[i]
+		c.Games += row.Games
+		c.TotalBet += row.TotalBet
+		c.TotalWin += row.TotalWin
+		c.NetResult += row.NetResult
+		if maxNet > c.BiggestWin {
+			c.BiggestWin = maxNet
+		}
+		if loss := -minNet; loss > c.BiggestLoss {
+			c.BiggestLoss = loss
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats.Currencies {
+		c := &stats.Currencies[i]
+		c.AverageBet = averageAmount(c.TotalBet, c.Games)
+	}
+	if stats.TotalGames > 0 {
+		stats.WinRate = math.Round(float64(wins)/float64(stats.TotalGames)*10000) / 100
+	}
+
+	now := time.Now()
+	stats.DaysRegistered = int(now.Sub(detail.CreatedAt).Hours() / 24)
+	if detail.LastLoginAt != nil && detail.LastLoginAt.After(lastActivity) {
+		lastActivity = *detail.LastLoginAt
+	}
+	stats.LastActivityDays = int(now.Sub(lastActivity).Hours() / 24)
+	return &stats, nil
+}
+
+// averageAmount 平均金額，以整數分運算並四捨五入（遠離零）至分
+func averageAmount(total money.Amount, n int) money.Amount {
+	if n <= 0 {
+		return 0
+	}
+	cents, count := total.Cents(), int64(n)
+	if cents < 0 {
+		return -money.FromCents((-cents*2 + count) / (count * 2))
+	}
+	return money.FromCents((cents*2 + count) / (count * 2))
+}
+
+// loadRecentGames 查詢玩家最近的遊戲參與記錄（與統計一致，排除練習場）
+func loadRecentGames(db *sql.DB, playerID int64, limit int) ([]GameParticipation, error) {
+	rows, err := db.Query(`
+		SELECT gp.id, g.game_type, COALESCE(gp.total_bet, 0), COALESCE(gp.total_win, 0),
+		       `+participationResultSQL+`, gp.join_time
+		FROM game_participations gp
+		JOIN game_sessions gs ON gs.id = gp.session_id
+		JOIN games g ON g.id = gs.game_id
+		WHERE gp.player_id = ? AND gs.session_type <> 'practice'
+		ORDER BY gp.join_time DESC, gp.id DESC
+		LIMIT ?`, playerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []GameParticipation{}
+	for rows.Next() {
+		var game GameParticipation
+		if err := rows.Scan(&game.ID, &game.GameType, &game.BetAmount, &game.WinAmount, &game.Result, &game.PlayedAt); err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"testing"
+
+	"nexus-gaming-backend/money"
+)
+
+func TestAverageAmount(t *testing.T) {
+	tests := []struct {
+		total money.Amount
+		n     int
+		want  money.Amount
+	}{
+		{money.FromCents(0), 0, 0},
+		{money.FromCents(1000), 0, 0},
+		{money.FromCents(1000), 4, money.FromCents(250)},
+		{money.FromCents(100), 3, money.FromCents(33)},   // 33.33
+		{money.FromCents(200), 3, money.FromCents(67)},   // 66.67
+		{money.FromCents(5), 2, money.FromCents(3)},      // 2.5 → 3
+		{money.FromCents(-5), 2, money.FromCents(-3)},    // -2.5 → -3
+		{money.FromCents(-200), 3, money.FromCents(-67)}, // -66.67
+	}
+	for _, tt := range tests {
+		if got := averageAmount(tt.total, tt.n); got != tt.want {
+			t.Errorf("averageAmount(%s, %d) = %s, want %s", tt.total, tt.n, got, tt.want)
+		}
+	}
+}
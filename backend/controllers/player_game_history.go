@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/models"
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlayerGameHistoryRequest 玩家遊戲歷史查詢請求
+type PlayerGameHistoryRequest struct {
+	Cursor          string `form:"cursor"`                                                                                        // 上一頁回傳的 next_cursor；空字串表示第一頁
+	Limit           int    `form:"limit"`                                                                                         // 每頁數量，最大100
+	GameType        string `form:"game_type" binding:"omitempty,oneof=texas_holdem stud_poker baccarat blackjack roulette slots"` // 遊戲類型
+	RoomID          int64  `form:"room_id"`                                                                                       // 房間ID
+	Result          string `form:"result" binding:"omitempty,oneof=win loss push playing"`                                        // 結果
+	StartDate       string `form:"start_date"`                                                                                    // 加入開始日期 (YYYY-MM-DD)
+	EndDate         string `form:"end_date"`                                                                                      // 加入結束日期 (YYYY-MM-DD，含當日)
+	IncludePractice bool   `form:"include_practice"`                                                                              // 是否包含練習場記錄（預設排除）
+}
+
+// PlayerGameHistoryEntry 玩家遊戲歷史的單筆記錄（金額直接以 money.Amount 掃描，不經浮點數）
+type PlayerGameHistoryEntry struct {
+	ID           int64                      `json:"id"`
+	SessionID    int64                      `json:"session_id"`
+	SessionType  string                     `json:"session_type"` // normal、tournament 或 practice
+	Practice     bool                       `json:"practice"`     // 練習場記錄（遊戲幣，不計入玩家統計）
+	GameName     string                     `json:"game_name"`
+	GameType     string                     `json:"game_type"`
+	RoomID       int64                      `json:"room_id"`
+	RoomName     string                     `json:"room_name"`
+	Currency     string                     `json:"currency"` // 下注幣別；練習場為遊戲幣
+	SeatNumber   *int                       `json:"seat_number,omitempty"`
+	JoinTime     time.Time                  `json:"join_time"`
+	LeaveTime    *time.Time                 `json:"leave_time,omitempty"`
+	InitialChips money.Amount               `json:"initial_chips"`
+	FinalChips   money.Amount               `json:"final_chips"`
+	TotalBet     money.Amount               `json:"total_bet"`
+	TotalWin     money.Amount               `json:"total_win"`
+	NetResult    money.Amount               `json:"net_result"`
+	Status       models.ParticipationStatus `json:"status"`
+	Duration     *time.Duration             `json:"duration,omitempty"` // 遊戲時長
+}
+
+// GameHistoryPageTotals 單頁遊戲記錄依幣別的合計
+type GameHistoryPageTotals struct {
+	Currency  string       `json:"currency"`
+	Rounds    int          `json:"rounds"`
+	TotalBet  money.Amount `json:"total_bet"`
+	TotalWin  money.Amount `json:"total_win"`
+	NetResult money.Amount `json:"net_result"`
+}
+
+// gameHistoryResultConditions 結果篩選對應的條件（直接比較欄位，不經 CASE 運算式以便使用索引）
+var gameHistoryResultConditions = map[string]string{
+	"win":     "gp.status <> 'playing' AND gp.net_result > 0",
+	"loss":    "gp.status <> 'playing' AND gp.net_result < 0",
+	"push":    "gp.status <> 'playing' AND gp.net_result = 0",
+	"playing": "gp.status = 'playing'",
+}
+
+// encodeGameHistoryCursor 以最後一筆的 (join_time, id) 產生不透明游標
+func encodeGameHistoryCursor(joinTime time.Time, id int64) string {
+	raw := joinTime.Format(time.RFC3339Nano) + "|" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeGameHistoryCursor 解析游標
+func decodeGameHistoryCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, fmt.Errorf("游標格式錯誤")
+	}
+	joinTime, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return joinTime, id, nil
+}
+
+// GetPlayerGameHistory 獲取玩家遊戲歷史
+//
+// 依加入時間由新到舊排列，以 (join_time, id) 做鍵集分頁：下一頁帶入 next_cursor，
+// 查詢成本不隨頁數增加。page_totals 為本頁記錄依幣別分組的下注、贏得與淨結果合計。
+// 預設排除練習場記錄；include_practice=true 時一併列出，並以 practice 標記。
+func (pc *PlayerController) GetPlayerGameHistory(c *gin.Context) {
+	playerID, ok := parsePlayerID(c)
+	if !ok {
+		return
+	}
+	var req PlayerGameHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "參數驗證失敗: "+err.Error(), "VALIDATION_FAILED")
+		return
+	}
+	_, limit := normalizePage(1, req.Limit)
+
+	conditions := []string{"gp.player_id = ?"}
+	args := []interface{}{playerID}
+
+	if !req.IncludePractice {
+		conditions = append(conditions, "gs.session_type <> 'practice'")
+	}
+	if req.GameType != "" {
+		conditions = append(conditions, "g.game_type = ?")
+		args = append(args, req.GameType)
+	}
+	if req.RoomID > 0 {
+		conditions = append(conditions, "gs.room_id = ?")
+		args = append(args, req.RoomID)
+	}
+	if req.Result != "" {
+		conditions = append(conditions, gameHistoryResultConditions[req.Result])
+	}
+	if req.StartDate != "" {
+		start := parseDateParam(req.StartDate)
+		if start == nil {
+			ErrorResponse(c, http.StatusBadRequest, "start_date 格式錯誤，應為 YYYY-MM-DD", "VALIDATION_FAILED")
+			return
+		}
+		conditions = append(conditions, "gp.join_time >= ?")
+		args = append(args, *start)
+	}
+	if req.EndDate != "" {
+		end := parseDateParam(req.EndDate)
+		if end == nil {
+			ErrorResponse(c, http.StatusBadRequest, "end_date 格式錯誤，應為 YYYY-MM-DD", "VALIDATION_FAILED")
+			return
+		}
+		conditions = append(conditions, "gp.join_time < ?")
+		args = append(args, end.AddDate(0, 0, 1))
+	}
+	if req.Cursor != "" {
+		joinTime, id, err := decodeGameHistoryCursor(req.Cursor)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "無效的游標", "INVALID_CURSOR")
+			return
+		}
+		conditions = append(conditions, "(gp.join_time < ? OR (gp.join_time = ? AND gp.id < ?))")
+		args = append(args, joinTime, joinTime, id)
+	}
+
+	db := config.GetDB()
+	if db == nil {
+		ErrorResponse(c, http.StatusInternalServerError, "資料庫連接失敗", "DATABASE_ERROR")
+		return
+	}
+
+	var exists int
+	err := db.QueryRow("SELECT 1 FROM players WHERE id = ?", playerID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		ErrorResponse(c, http.StatusNotFound, "玩家不存在", "PLAYER_NOT_FOUND")
+		return
+	} else if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢玩家資料失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+
+	// 多取一筆以判斷是否還有下一頁
+	args = append(args, limit+1)
+	rows, err := db.Query(`
+		SELECT gp.id, gp.session_id, COALESCE(gs.session_type, 'normal'), gp.seat_number, gp.join_time, gp.leave_time,
+		       COALESCE(gp.initial_chips, 0), COALESCE(gp.final_chips, 0),
+		       COALESCE(gp.total_bet, 0), COALESCE(gp.total_win, 0), COALESCE(gp.net_result, 0), gp.status,
+		       g.name, g.game_type, r.id, r.name, COALESCE(r.currency, '')
+		FROM game_participations gp
+		JOIN game_sessions gs ON gs.id = gp.session_id
+		JOIN game_rooms r ON r.id = gs.room_id
+		JOIN games g ON g.id = gs.game_id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY gp.join_time DESC, gp.id DESC
+		LIMIT ?`, args...)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢遊戲歷史失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+	defer rows.Close()
+
+	history := []PlayerGameHistoryEntry{}
+	for rows.Next() {
+		var h PlayerGameHistoryEntry
+		if err := rows.Scan(
+			&h.ID, &h.SessionID, &h.SessionType, &h.SeatNumber, &h.JoinTime, &h.LeaveTime,
+			&h.InitialChips, &h.FinalChips,
+			&h.TotalBet, &h.TotalWin, &h.NetResult, &h.Status,
+			&h.GameName, &h.GameType, &h.RoomID, &h.RoomName, &h.Currency,
+		); err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, "掃描資料失敗: "+err.Error(), "DATABASE_ERROR")
+			return
+		}
+		h.Practice = h.SessionType == "practice"
+		if h.Practice {
+			h.Currency = services.PracticeCurrency
+		} else {
+			h.Currency = pc.walletService.CurrencyOf(h.Currency)
+		}
+		if h.LeaveTime != nil {
+			d := h.LeaveTime.Sub(h.JoinTime)
+			h.Duration = &d
+		}
+		history = append(history, h)
+	}
+	if err := rows.Err(); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢遊戲歷史失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+
+	hasMore := len(history) > limit
+	if hasMore {
+		history = history[:limit]
+	}
+	var nextCursor string
+	if hasMore {
+		last := history[len(history)-1]
+		nextCursor = encodeGameHistoryCursor(last.JoinTime, last.ID)
+	}
+
+	SuccessResponse(c, gin.H{
+		"player_id":   playerID,
+		"games":       history,
+		"page_totals": gameHistoryPageTotals(history),
+		"pagination": gin.H{
+			"limit":       limit,
+			"next_cursor": nextCursor,
+			"has_more":    hasMore,
+		},
+		"filters": gin.H{
+			"game_type":        req.GameType,
+			"room_id":          req.RoomID,
+			"result":           req.Result,
+			"start_date":       req.StartDate,
+			"end_date":         req.EndDate,
+			"include_practice": req.IncludePractice,
+		},
+	}, "玩家遊戲歷史獲取成功")
+}
+
+// gameHistoryPageTotals 依幣別加總本頁記錄（不同幣別與練習場遊戲幣不可相加），依幣別首次出現的順序排列
+func gameHistoryPageTotals(history []PlayerGameHistoryEntry) []GameHistoryPageTotals {
+	totals := []GameHistoryPageTotals{}
+	index := map[string]int{}
+	for _, h := range history {
+		i, ok := index[h.Currency]
+		if !ok {
+			i = len(totals)
+			index[h.Currency] = i
+			totals = append(totals, GameHistoryPageTotals{Currency: h.Currency})
+		}
+		t := &totals[i]
+		t.Rounds++
+		t.TotalBet += h.TotalBet
+		t.TotalWin += h.TotalWin
+		t.NetResult += h.NetResult
+	}
+	return totals
+}
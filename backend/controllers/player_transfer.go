@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlayerTransferRequest 玩家移轉請求（移至經銷商或代理商直屬其中之一；effective_at 為 RFC3339，省略為立即生效）
+type PlayerTransferRequest struct {
+	PlayerIDs   []int64    `json:"player_ids" binding:"required,min=1,max=500,dive,min=1"`
+	AgentID     *int       `json:"agent_id" binding:"omitempty,min=1"`
+	DealerID    *int       `json:"dealer_id" binding:"omitempty,min=1"`
+	EffectiveAt *time.Time `json:"effective_at"`
+	Reason      string     `json:"reason" binding:"required,max=255"`
+}
+
+// PlayerTransferListRequest 移轉記錄查詢
+type PlayerTransferListRequest struct {
+	PlayerID int64  `form:"player_id"`
+	AgentID  int    `form:"agent_id"`
+	DealerID int    `form:"dealer_id"`
+	BatchID  string `form:"batch_id"`
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+// TransferPlayers 批次移轉玩家，生效時間前的佣金歸原歸屬、之後的歸新歸屬
+func (ac *AgentController) TransferPlayers(c *gin.Context) {
+	var req PlayerTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	result, err := ac.agentService.TransferPlayers(services.PlayerTransferRequest{
+		PlayerIDs:   req.PlayerIDs,
+		AgentID:     req.AgentID,
+		DealerID:    req.DealerID,
+		EffectiveAt: req.EffectiveAt,
+		Reason:      req.Reason,
+	}, &operatorID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, result, "玩家移轉完成")
+}
+
+// GetPlayerTransfers 玩家移轉記錄
+func (ac *AgentController) GetPlayerTransfers(c *gin.Context) {
+	var req PlayerTransferListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	ac.listPlayerTransfers(c, req)
+}
+
+// GetPlayerTransferHistory 單一玩家的移轉記錄
+func (ac *AgentController) GetPlayerTransferHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorResponse(c, http.StatusBadRequest, "無效的玩家ID", "INVALID_ID")
+		return
+	}
+	var req PlayerTransferListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	req.PlayerID = id
+	ac.listPlayerTransfers(c, req)
+}
+
+// GetAgentPlayerTransfers 代理商移出或移入的玩家移轉記錄
+func (ac *AgentController) GetAgentPlayerTransfers(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		var req PlayerTransferListRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+			return
+		}
+		req.AgentID = id
+		ac.listPlayerTransfers(c, req)
+	}
+}
+
+// GetDealerPlayerTransfers 經銷商移出或移入的玩家移轉記錄
+func (ac *AgentController) GetDealerPlayerTransfers(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		var req PlayerTransferListRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+			return
+		}
+		req.DealerID = id
+		ac.listPlayerTransfers(c, req)
+	}
+}
+
+func (ac *AgentController) listPlayerTransfers(c *gin.Context, req PlayerTransferListRequest) {
+	page, limit := normalizePage(req.Page, req.Limit)
+	list, total, err := ac.agentService.PlayerTransfers(services.PlayerTransferFilter{
+		PlayerID: req.PlayerID,
+		AgentID:  req.AgentID,
+		DealerID: req.DealerID,
+		BatchID:  req.BatchID,
+		Page:     page,
+		Limit:    limit,
+	})
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"transfers": list, "pagination": pagination(page, limit, total)}, "獲取玩家移轉記錄成功")
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader 冪等鍵標頭；重試時帶相同的鍵會重播第一次成功的回應
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// PlayerBalanceRequest 玩家儲值／提領請求（金額接受數字或字串，最多兩位小數）
+type PlayerBalanceRequest struct {
+	Amount      money.Amount `json:"amount" binding:"required"`
+	Currency    string       `json:"currency" binding:"omitempty,len=3"` // 省略時為預設幣別
+	ReferenceID string       `json:"reference_id"`
+	Description string       `json:"description"`
+}
+
+// CurrencyConversionRequest 玩家錢包幣別兌換請求（金額為轉出金額）
+type CurrencyConversionRequest struct {
+	FromCurrency string       `json:"from_currency" binding:"required,len=3"`
+	ToCurrency   string       `json:"to_currency" binding:"required,len=3"`
+	Amount       money.Amount `json:"amount" binding:"required"`
+}
+
+// GetPlayerBalance 獲取玩家餘額（各幣別的可用、凍結與總餘額，以及構成凍結餘額的有效凍結）
+func (pc *PlayerController) GetPlayerBalance(c *gin.Context) {
+	playerID, ok := parsePlayerID(c)
+	if !ok {
+		return
+	}
+	balances, err := pc.walletService.Balances(playerID)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢玩家餘額失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+	holds, err := pc.holdService.List(playerID, services.HoldActive)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢錢包凍結失敗: "+err.Error(), "DATABASE_ERROR")
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"player_id": playerID,
+		"wallets":   balances,
+		"holds":     holds,
+	}, "玩家餘額獲取成功")
+}
+
+// DepositPlayerBalance 玩家充值（營運端人工入帳，支援 Idempotency-Key）
+func (pc *PlayerController) DepositPlayerBalance(c *gin.Context) {
+	pc.changeBalance(c, "deposit")
+}
+
+// WithdrawPlayerBalance 玩家提領（營運端人工扣款，支援 Idempotency-Key；只能動用可用餘額）
+func (pc *PlayerController) WithdrawPlayerBalance(c *gin.Context) {
+	pc.changeBalance(c, "withdrawal")
+}
+
+// changeBalance 以冪等鍵執行人工儲值或提領
+func (pc *PlayerController) changeBalance(c *gin.Context, transactionType string) {
+	playerID, ok := parsePlayerID(c)
+	if !ok {
+		return
+	}
+	var req PlayerBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if !req.Amount.IsPositive() {
+		ErrorResponse(c, http.StatusBadRequest, services.ErrInvalidAmount.Error(), "INVALID_AMOUNT")
+		return
+	}
+	req.Currency = pc.walletService.CurrencyOf(strings.ToUpper(req.Currency))
+	if err := pc.fxService.CheckCurrency(req.Currency); err != nil {
+		pc.handleWalletError(c, err)
+		return
+	}
+
+	var status string
+	err := config.GetDB().QueryRow("SELECT status FROM players WHERE id = ?", playerID).Scan(&status)
+	if err == sql.ErrNoRows {
+		ErrorResponse(c, http.StatusNotFound, "玩家不存在", "PLAYER_NOT_FOUND")
+		return
+	} else if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢玩家失敗", "DATABASE_ERROR")
+		return
+	}
+	if status == "deleted" || (transactionType == "withdrawal" && status == "suspended") {
+		ErrorResponse(c, http.StatusForbidden, "玩家帳號狀態不允許此操作", "PLAYER_INACTIVE")
+		return
+	}
+
+	operatorID := c.GetInt("user_id")
+	description := req.Description
+	if description == "" {
+		description = map[string]string{"deposit": "人工儲值", "withdrawal": "人工提領"}[transactionType]
+	}
+	scope := fmt.Sprintf("player_%s:%d", transactionType, playerID)
+	body, replayed, err := pc.idempotencyService.Run(scope, c.GetHeader(IdempotencyKeyHeader), req, func(tx *sql.Tx) (interface{}, error) {
+		entry := services.WalletEntry{
+			PlayerID:      playerID,
+			Currency:      req.Currency,
+			Type:          transactionType,
+			Amount:        req.Amount,
+			ReferenceID:   req.ReferenceID,
+			ReferenceType: "manual_" + transactionType,
+			Description:   description,
+			OperatorID:    &operatorID,
+		}
+		if transactionType == "deposit" {
+			return pc.walletService.CreditTx(tx, entry)
+		}
+		return pc.walletService.DebitTx(tx, entry)
+	})
+	if err != nil {
+		pc.handleWalletError(c, err)
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	message := "儲值成功"
+	if transactionType == "withdrawal" {
+		message = "提領成功"
+	}
+	SuccessResponse(c, body, message)
+}
+
+// parsePlayerID 解析路徑中的玩家ID
+func parsePlayerID(c *gin.Context) (int64, bool) {
+	playerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "無效的玩家ID", "INVALID_PLAYER_ID")
+		return 0, false
+	}
+	return playerID, true
+}
+
+// ConvertPlayerCurrency 玩家錢包幣別兌換（以當下匯率自轉出錢包扣款並存入轉入錢包，支援 Idempotency-Key）
+func (pc *PlayerController) ConvertPlayerCurrency(c *gin.Context) {
+	playerID, ok := parsePlayerID(c)
+	if !ok {
+		return
+	}
+	var req CurrencyConversionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	scope := fmt.Sprintf("player_fx_conversion:%d", playerID)
+	body, replayed, err := pc.idempotencyService.Run(scope, c.GetHeader(IdempotencyKeyHeader), req, func(tx *sql.Tx) (interface{}, error) {
+		return pc.fxService.ConvertTx(tx, services.FXConversionRequest{
+			PlayerID:     playerID,
+			FromCurrency: req.FromCurrency,
+			ToCurrency:   req.ToCurrency,
+			Amount:       req.Amount,
+			OperatorID:   &operatorID,
+		})
+	})
+	if err != nil {
+		pc.handleWalletError(c, err)
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	SuccessResponse(c, body, "幣別兌換成功")
+}
+
+func (pc *PlayerController) handleWalletError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRateNotFound), errors.Is(err, services.ErrCurrencyUnsupported),
+		errors.Is(err, services.ErrSameCurrency), errors.Is(err, services.ErrConversionTooSmall):
+		handleFXError(c, err)
+	case errors.Is(err, services.ErrInsufficientBalance), errors.Is(err, services.ErrWalletNotFound):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INSUFFICIENT_BALANCE")
+	case errors.Is(err, services.ErrInvalidAmount):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_AMOUNT")
+	case errors.Is(err, services.ErrIdempotencyKeyInvalid):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_IDEMPOTENCY_KEY")
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		ErrorResponse(c, http.StatusUnprocessableEntity, err.Error(), "IDEMPOTENCY_KEY_REUSED")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "錢包操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
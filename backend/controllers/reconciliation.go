@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconciliationController 對帳結帳控制器
+type ReconciliationController struct {
+	reconciliationService *services.ReconciliationService
+}
+
+// NewReconciliationController 建立新的對帳控制器
+func NewReconciliationController() *ReconciliationController {
+	return &ReconciliationController{reconciliationService: services.NewReconciliationService()}
+}
+
+// CloseDayRequest 結帳請求
+type CloseDayRequest struct {
+	Date string `json:"date" binding:"required"` // YYYY-MM-DD
+}
+
+// GetDailyReconciliation 日對帳（date 為 YYYY-MM-DD，預設昨日；currency 篩選幣別）
+//
+// 已結帳的日期回傳快照，未結帳的日期為即時試算。
+func (rc *ReconciliationController) GetDailyReconciliation(c *gin.Context) {
+	day := time.Now().AddDate(0, 0, -1)
+	if v := c.Query("date"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "日期格式錯誤，請使用 YYYY-MM-DD", "INVALID_DATE")
+			return
+		}
+		day = t
+	}
+	reports, err := rc.reconciliationService.Daily(day)
+	if err != nil {
+		rc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, filterReports(reports, c.Query("currency")), "日對帳報表獲取成功")
+}
+
+// GetMonthlyReconciliation 月對帳（month 為 YYYY-MM，預設上個月；currency 篩選幣別）
+//
+// 當月已結束且每日皆已結帳時寫入月報快照，否則為試算。
+func (rc *ReconciliationController) GetMonthlyReconciliation(c *gin.Context) {
+	month := time.Now().AddDate(0, -1, 0)
+	if v := c.Query("month"); v != "" {
+		t, err := time.ParseInLocation("2006-01", v, time.Local)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "月份格式錯誤，請使用 YYYY-MM", "INVALID_DATE")
+			return
+		}
+		month = t
+	}
+	operatorID := c.GetInt("user_id")
+	reports, err := rc.reconciliationService.Monthly(month, &operatorID)
+	if err != nil {
+		rc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, filterReports(reports, c.Query("currency")), "月對帳報表獲取成功")
+}
+
+// CloseDay 手動結帳已結束的日期（寫入快照後該日不可再回溯寫入交易）
+func (rc *ReconciliationController) CloseDay(c *gin.Context) {
+	var req CloseDayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	day, err := time.ParseInLocation("2006-01-02", req.Date, time.Local)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "日期格式錯誤，請使用 YYYY-MM-DD", "INVALID_DATE")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	reports, err := rc.reconciliationService.CloseDay(day, &operatorID)
+	if err != nil {
+		rc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, reports, "已結帳")
+}
+
+// filterReports 依幣別篩選報表
+func filterReports(reports []services.ReconciliationReport, currency string) []services.ReconciliationReport {
+	if currency == "" {
+		return reports
+	}
+	filtered := []services.ReconciliationReport{}
+	for _, r := range reports {
+		if strings.EqualFold(r.Currency, currency) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+func (rc *ReconciliationController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPeriodNotEnded):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "PERIOD_NOT_ENDED")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "對帳失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
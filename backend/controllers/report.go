@@ -1,11 +1,146 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ReportController 財務報表控制器
+type ReportController struct {
+	reportService      *services.ReportService
+	performanceService *services.PerformanceService
+}
+
+// NewReportController 建立新的報表控制器
+func NewReportController() *ReportController {
+	return &ReportController{reportService: services.NewReportService(), performanceService: services.NewPerformanceService()}
+}
+
+// maxReportDays 單次報表查詢的最大天數
+const maxReportDays = 366
+
+// PerformanceRollupRequest 重建每日業績統計（省略代理商時重建所有代理商與經銷商）
+type PerformanceRollupRequest struct {
+	StartDate string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" binding:"required,datetime=2006-01-02"`
+	AgentID   *int   `json:"agent_id" binding:"omitempty,min=1"`
+}
+
+// GetRevenueReport 營收報表（start_date、end_date 為 YYYY-MM-DD，預設最近 30 天；
+// reporting_currency 指定合併幣別，各日金額以當日匯率換算）
+func (rc *ReportController) GetRevenueReport(c *gin.Context) {
+	start, end, ok := reportDateRange(c)
+	if !ok {
+		return
+	}
+	report, err := rc.reportService.Revenue(start, end, strings.ToUpper(c.Query("reporting_currency")))
+	if err != nil {
+		handleFXError(c, err)
+		return
+	}
+	SuccessResponse(c, report, "營收報表獲取成功")
+}
+
+// GetAgentPerformanceReport 代理商業績報表（start_date、end_date 為 YYYY-MM-DD，預設最近 30 天），
+// 與前一個等長期間比較並依 rank_by 排名；指定 agent_id 時另含該代理商的每日業績與其經銷商排名
+func (rc *ReportController) GetAgentPerformanceReport(c *gin.Context) {
+	start, end, ok := reportDateRange(c)
+	if !ok {
+		return
+	}
+	f := services.PerformanceReportFilter{Start: start, End: end, RankBy: c.Query("rank_by"), Limit: 20}
+	if v := c.Query("agent_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			ErrorResponse(c, http.StatusBadRequest, "代理商ID格式錯誤", "INVALID_ID")
+			return
+		}
+		f.AgentID = &id
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 100 {
+			ErrorResponse(c, http.StatusBadRequest, "排名筆數必須為 1 至 100", "INVALID_REQUEST")
+			return
+		}
+		f.Limit = limit
+	}
+	report, err := rc.performanceService.Report(f)
+	if err != nil {
+		handlePerformanceError(c, err)
+		return
+	}
+	SuccessResponse(c, report, "代理商業績報表獲取成功")
+}
+
+// RollupAgentPerformance 重建指定期間的每日業績統計（可重複執行，結果相同）
+func (rc *ReportController) RollupAgentPerformance(c *gin.Context) {
+	var req PerformanceRollupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	start, end := *parseDateParam(req.StartDate), *parseDateParam(req.EndDate)
+	if end.Before(start) || end.Sub(start) > maxReportDays*24*time.Hour {
+		ErrorResponse(c, http.StatusBadRequest, "日期區間無效（最多 366 天）", "INVALID_DATE_RANGE")
+		return
+	}
+	n, err := rc.performanceService.Rollup(start, end, req.AgentID)
+	if err != nil {
+		handlePerformanceError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"days": n}, "業績統計已重建")
+}
+
+// reportDateRange 解析報表的日期區間（預設最近 30 天，最多 366 天）
+func reportDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	end := time.Now()
+	start := end.AddDate(0, 0, -29)
+	var err error
+	if v := c.Query("start_date"); v != "" {
+		if start, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "開始日期格式錯誤，請使用 YYYY-MM-DD", "INVALID_DATE")
+			return start, end, false
+		}
+	}
+	if v := c.Query("end_date"); v != "" {
+		if end, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "結束日期格式錯誤，請使用 YYYY-MM-DD", "INVALID_DATE")
+			return start, end, false
+		}
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.Local)
+	if end.Before(start) || end.Sub(start) > maxReportDays*24*time.Hour {
+		ErrorResponse(c, http.StatusBadRequest, "日期區間無效（最多 366 天）", "INVALID_DATE_RANGE")
+		return start, end, false
+	}
+	return start, end, true
+}
+
+func handlePerformanceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAgentNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "AGENT_NOT_FOUND")
+	case errors.Is(err, services.ErrPerformanceRange):
+		ErrorResponse(c, http.StatusBadRequest, err.Error()+"（結束日期不可晚於今日）", "INVALID_DATE_RANGE")
+	case errors.Is(err, services.ErrPerformanceRankBy):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_RANK_BY")
+	case errors.Is(err, services.ErrRateNotFound):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "RATE_NOT_FOUND")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "業績統計失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
+
 // 報表管理相關
 func GetOperationalReports(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "GetOperationalReports endpoint not implemented yet", "NOT_IMPLEMENTED")
@@ -32,10 +167,6 @@ func GetDashboardData(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "GetDashboardData endpoint not implemented yet", "NOT_IMPLEMENTED")
 }
 
-func GetRevenueReport(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetRevenueReport endpoint not implemented yet", "NOT_IMPLEMENTED")
-}
-
 func GetPlayerAnalysisReport(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "GetPlayerAnalysisReport endpoint not implemented yet", "NOT_IMPLEMENTED")
 }
@@ -44,10 +175,6 @@ func GetGamePerformanceReport(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "GetGamePerformanceReport endpoint not implemented yet", "NOT_IMPLEMENTED")
 }
 
-func GetAgentPerformanceReport(c *gin.Context) {
-	ErrorResponse(c, http.StatusNotImplemented, "GetAgentPerformanceReport endpoint not implemented yet", "NOT_IMPLEMENTED")
-}
-
 func GetCommissionSummaryReport(c *gin.Context) {
 	ErrorResponse(c, http.StatusNotImplemented, "GetCommissionSummaryReport endpoint not implemented yet", "NOT_IMPLEMENTED")
 }
@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettlementController 代理商與經銷商結算控制器
+type SettlementController struct {
+	settlementService *services.SettlementService
+}
+
+// NewSettlementController 建立新的結算控制器
+func NewSettlementController() *SettlementController {
+	return &SettlementController{settlementService: services.NewSettlementService()}
+}
+
+// SettlementListRequest 結算列表查詢
+type SettlementListRequest struct {
+	Status      string `form:"status" binding:"omitempty,oneof=pending calculated approved paid disputed"`
+	PeriodStart string `form:"period_start" binding:"omitempty,datetime=2006-01-02"`
+	PeriodEnd   string `form:"period_end" binding:"omitempty,datetime=2006-01-02"`
+	Page        int    `form:"page"`
+	Limit       int    `form:"limit"`
+}
+
+// SettlementAdjustmentRequest 結算調整（負數為扣減）
+type SettlementAdjustmentRequest struct {
+	Amount money.Amount `json:"amount" binding:"required"`
+	Reason string       `json:"reason" binding:"required,max=255"`
+}
+
+// SettlementPaymentRequest 結算支付
+type SettlementPaymentRequest struct {
+	PaymentMethod    string `json:"payment_method" binding:"required,max=50"`
+	PaymentReference string `json:"payment_reference" binding:"required,max=100"`
+}
+
+// SettlementDisputeRequest 結算爭議
+type SettlementDisputeRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// GetAgentSettlements 代理商結算列表
+func (sc *SettlementController) GetAgentSettlements(c *gin.Context) {
+	if id, ok := agentParam(c); ok {
+		sc.listSettlements(c, services.NodeAgent, id)
+	}
+}
+
+// GetDealerSettlements 經銷商結算列表
+func (sc *SettlementController) GetDealerSettlements(c *gin.Context) {
+	if id, ok := dealerParam(c); ok {
+		sc.listSettlements(c, services.NodeDealer, id)
+	}
+}
+
+// CloseSettlements 立即結算所有已結束的期間（排程亦會定期執行）
+func (sc *SettlementController) CloseSettlements(c *gin.Context) {
+	n, err := sc.settlementService.CloseDuePeriods()
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"created": n}, "結算完成")
+}
+
+// GetSettlement 結算與其佣金記錄
+func (sc *SettlementController) GetSettlement(c *gin.Context) {
+	targetType, id, ok := settlementParams(c)
+	if !ok {
+		return
+	}
+	var req CommissionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	st, err := sc.settlementService.Get(targetType, id)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	page, limit := normalizePage(req.Page, req.Limit)
+	list, total, err := sc.settlementService.Commissions.Commissions(services.CommissionFilter{
+		SettlementID: st.SettlementID, Status: req.Status, Page: page, Limit: limit,
+	})
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"settlement":  st,
+		"commissions": list,
+		"pagination":  pagination(page, limit, total),
+	}, "獲取結算成功")
+}
+
+// GetSettlementVersions 結算的所有版本快照
+func (sc *SettlementController) GetSettlementVersions(c *gin.Context) {
+	targetType, id, ok := settlementParams(c)
+	if !ok {
+		return
+	}
+	versions, err := sc.settlementService.Versions(targetType, id)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, versions, "獲取結算版本成功")
+}
+
+// AdjustSettlement 新增結算調整
+func (sc *SettlementController) AdjustSettlement(c *gin.Context) {
+	targetType, id, ok := settlementParams(c)
+	if !ok {
+		return
+	}
+	var req SettlementAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	st, err := sc.settlementService.Adjust(targetType, id, req.Amount, req.Reason, c.GetInt("user_id"))
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, st, "結算調整已新增")
+}
+
+// ApproveSettlement 審核結算
+func (sc *SettlementController) ApproveSettlement(c *gin.Context) {
+	targetType, id, ok := settlementParams(c)
+	if !ok {
+		return
+	}
+	st, err := sc.settlementService.Approve(targetType, id, c.GetInt("user_id"))
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, st, "結算已審核")
+}
+
+// PaySettlement 標記結算已支付
+func (sc *SettlementController) PaySettlement(c *gin.Context) {
+	targetType, id, ok := settlementParams(c)
+	if !ok {
+		return
+	}
+	var req SettlementPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	st, err := sc.settlementService.MarkPaid(targetType, id, services.SettlementPayment{
+		Method:    req.PaymentMethod,
+		Reference: req.PaymentReference,
+	})
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, st, "結算已支付")
+}
+
+// DisputeSettlement 對結算提出爭議
+func (sc *SettlementController) DisputeSettlement(c *gin.Context) {
+	targetType, id, ok := settlementParams(c)
+	if !ok {
+		return
+	}
+	var req SettlementDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	st, err := sc.settlementService.Dispute(targetType, id, req.Reason, c.GetInt("user_id"))
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, st, "已提出結算爭議")
+}
+
+// RecalculateSettlement 重算爭議中的結算，產生新版本
+func (sc *SettlementController) RecalculateSettlement(c *gin.Context) {
+	targetType, id, ok := settlementParams(c)
+	if !ok {
+		return
+	}
+	st, err := sc.settlementService.Recalculate(targetType, id, c.GetInt("user_id"))
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, st, "結算已重算")
+}
+
+func (sc *SettlementController) listSettlements(c *gin.Context, targetType string, id int) {
+	var req SettlementListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	page, limit := normalizePage(req.Page, req.Limit)
+	list, total, err := sc.settlementService.List(services.SettlementFilter{
+		TargetType:  targetType,
+		TargetID:    id,
+		Status:      req.Status,
+		PeriodStart: parseDateParam(req.PeriodStart),
+		PeriodEnd:   parseDateParam(req.PeriodEnd),
+		Page:        page,
+		Limit:       limit,
+	})
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"settlements": list, "pagination": pagination(page, limit, total)}, "獲取結算列表成功")
+}
+
+// settlementParams 解析路徑中的結算類型（agent 或 dealer）與結算ID
+func settlementParams(c *gin.Context) (string, int64, bool) {
+	targetType := c.Param("type")
+	if targetType != services.NodeAgent && targetType != services.NodeDealer {
+		ErrorResponse(c, http.StatusBadRequest, "結算類型必須為 agent 或 dealer", "INVALID_TYPE")
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorResponse(c, http.StatusBadRequest, "結算ID格式錯誤", "INVALID_ID")
+		return "", 0, false
+	}
+	return targetType, id, true
+}
+
+func (sc *SettlementController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAgentNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "AGENT_NOT_FOUND")
+	case errors.Is(err, services.ErrDealerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "DEALER_NOT_FOUND")
+	case errors.Is(err, services.ErrSettlementNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "SETTLEMENT_NOT_FOUND")
+	case errors.Is(err, services.ErrSettlementStatus):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INVALID_STATUS")
+	case errors.Is(err, services.ErrInvalidAdjustment):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_REQUEST")
+	case errors.Is(err, services.ErrCommissionTargetInvalid):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INVALID_TARGET")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "結算操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/services"
+	"nexus-gaming-backend/table"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// playerTokenTTL 玩家牌桌 Token 有效期
+const playerTokenTTL = 12 * time.Hour
+
+// TableController 即時牌桌控制器（WebSocket）
+type TableController struct {
+	hub             *table.Hub
+	practiceHub     *table.Hub
+	practiceService *services.PracticeService
+	authService     *services.AuthService
+	upgrader        websocket.Upgrader
+}
+
+// NewTableController 建立新的牌桌控制器（所有真錢牌桌共用同一個 Hub；練習場使用獨立的 Hub）
+func NewTableController() *TableController {
+	turnTimeout := 20 * time.Second
+	if config.AppConfig != nil && config.AppConfig.Game.TurnTimeout > 0 {
+		turnTimeout = config.AppConfig.Game.TurnTimeout
+	}
+	store := services.NewTableStore()
+	practiceStore := services.NewPracticeStore()
+	return &TableController{
+		hub:             table.NewHub(store, turnTimeout),
+		practiceHub:     table.NewHub(practiceStore, turnTimeout),
+		practiceService: practiceStore.Practice,
+		authService:     services.NewAuthService(),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin,
+		},
+	}
+}
+
+// PlayerTokenRequest 核發玩家 Token 請求
+type PlayerTokenRequest struct {
+	PlayerID int64 `json:"player_id" binding:"required"`
+}
+
+// Connect 建立牌桌 WebSocket 連線（以 ?token= 或 Bearer 標頭提供玩家 Token）
+func (tc *TableController) Connect(c *gin.Context) {
+	tc.connect(c, tc.hub)
+}
+
+// ConnectPractice 建立練習場牌桌 WebSocket 連線（以遊戲幣入座，不影響真錢錢包）
+func (tc *TableController) ConnectPractice(c *gin.Context) {
+	tc.connect(c, tc.practiceHub)
+}
+
+// GetPracticeBalance 取得玩家的練習遊戲幣餘額（低於門檻時自動補充）
+func (tc *TableController) GetPracticeBalance(c *gin.Context) {
+	wallet, err := tc.practiceService.Get(c.GetInt64("player_id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "取得遊戲幣餘額失敗: "+err.Error(), "INTERNAL_ERROR")
+		return
+	}
+	SuccessResponse(c, wallet, "遊戲幣餘額獲取成功")
+}
+
+func (tc *TableController) connect(c *gin.Context, hub *table.Hub) {
+	roomID, err := strconv.ParseInt(c.Param("room_id"), 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "房間ID格式錯誤", "INVALID_ROOM_ID")
+		return
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		ErrorResponse(c, http.StatusUnauthorized, "缺少玩家 Token", "MISSING_TOKEN")
+		return
+	}
+	claims, err := tc.authService.ValidatePlayerToken(token)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, "玩家 Token 驗證失敗", "INVALID_TOKEN")
+		return
+	}
+
+	// 升級前先載入牌桌，房間錯誤仍可用一般 HTTP 回應
+	if _, err := hub.Table(roomID); err != nil {
+		tc.handleError(c, err)
+		return
+	}
+
+	conn, err := tc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	hub.Serve(roomID, conn, claims.PlayerID, claims.Username)
+}
+
+// IssuePlayerToken 為玩家核發牌桌連線 Token（營運端或遊戲大廳呼叫）
+func (tc *TableController) IssuePlayerToken(c *gin.Context) {
+	var req PlayerTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+
+	var username, status string
+	err := config.GetDB().QueryRow("SELECT username, status FROM players WHERE id = ?", req.PlayerID).Scan(&username, &status)
+	if err == sql.ErrNoRows {
+		ErrorResponse(c, http.StatusNotFound, "玩家不存在", "PLAYER_NOT_FOUND")
+		return
+	} else if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "查詢玩家失敗", "DATABASE_ERROR")
+		return
+	}
+	if status != "active" {
+		ErrorResponse(c, http.StatusForbidden, services.ErrPlayerInactive.Error(), "PLAYER_INACTIVE")
+		return
+	}
+
+	token, err := tc.authService.GeneratePlayerToken(req.PlayerID, username, playerTokenTTL)
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, "生成 Token 失敗", "TOKEN_ERROR")
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"token":      token,
+		"expires_at": time.Now().Add(playerTokenTTL),
+	}, "玩家 Token 核發成功")
+}
+
+// GetTable 取得牌桌目前狀態（觀察者視角，不含任何暗牌）
+func (tc *TableController) GetTable(c *gin.Context) {
+	roomID, err := strconv.ParseInt(c.Param("room_id"), 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "房間ID格式錯誤", "INVALID_ROOM_ID")
+		return
+	}
+	snapshot := tc.hub.Snapshot(roomID)
+	if snapshot == nil {
+		ErrorResponse(c, http.StatusNotFound, "牌桌尚未開啟", "TABLE_NOT_OPEN")
+		return
+	}
+	SuccessResponse(c, snapshot, "牌桌狀態獲取成功")
+}
+
+func (tc *TableController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "ROOM_NOT_FOUND")
+	case errors.Is(err, services.ErrRoomInactive):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "ROOM_INACTIVE")
+	case errors.Is(err, services.ErrRoomNotPoker), errors.Is(err, services.ErrInvalidBlinds):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "ROOM_NOT_SUPPORTED")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "開啟牌桌失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
+
+// checkOrigin 依 ALLOWED_ORIGINS 檢查 WebSocket 來源
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || config.AppConfig == nil {
+		return true
+	}
+	for _, allowed := range config.AppConfig.Security.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
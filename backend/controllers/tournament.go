@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/engine/tournament"
+	"nexus-gaming-backend/services"
+	"nexus-gaming-backend/table"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// tournamentCheckInterval 檢查到期開賽的間隔
+const tournamentCheckInterval = 30 * time.Second
+
+// TournamentController 錦標賽控制器
+type TournamentController struct {
+	tournamentService *services.TournamentService
+	manager           *table.TournamentManager
+	authService       *services.AuthService
+	upgrader          websocket.Upgrader
+}
+
+// NewTournamentController 建立新的錦標賽控制器，並啟動開賽排程（同時恢復重啟前進行中的錦標賽）
+func NewTournamentController() *TournamentController {
+	turnTimeout := 20 * time.Second
+	if config.AppConfig != nil && config.AppConfig.Game.TurnTimeout > 0 {
+		turnTimeout = config.AppConfig.Game.TurnTimeout
+	}
+	service := services.NewTournamentService()
+	manager := table.NewTournamentManager(service, turnTimeout)
+	go manager.Run(tournamentCheckInterval)
+
+	return &TournamentController{
+		tournamentService: service,
+		manager:           manager,
+		authService:       services.NewAuthService(),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin,
+		},
+	}
+}
+
+// CreateTournamentRequest 建立錦標賽請求
+type CreateTournamentRequest struct {
+	Name            string                     `json:"name" binding:"required"`
+	RoomID          int64                      `json:"room_id" binding:"required"`
+	BuyIn           float64                    `json:"buy_in" binding:"required,gt=0"`
+	Fee             float64                    `json:"fee" binding:"gte=0"`
+	StartingStack   float64                    `json:"starting_stack" binding:"required,gt=0"`
+	SeatsPerTable   int                        `json:"seats_per_table"`
+	MinEntrants     int                        `json:"min_entrants"`
+	MaxEntrants     int                        `json:"max_entrants"`
+	BlindSchedule   tournament.Schedule        `json:"blind_schedule" binding:"required"`
+	PayoutStructure tournament.PayoutStructure `json:"payout_structure"`
+	LateRegLevels   int                        `json:"late_reg_levels"`
+	StartsAt        time.Time                  `json:"starts_at" binding:"required"`
+}
+
+// TournamentPlayerRequest 代玩家報名請求
+type TournamentPlayerRequest struct {
+	PlayerID int64 `json:"player_id" binding:"required"`
+}
+
+// CreateTournament 建立錦標賽
+func (tc *TournamentController) CreateTournament(c *gin.Context) {
+	var req CreateTournamentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	t, err := tc.tournamentService.Create(services.TournamentDefinition(req), c.GetInt("user_id"))
+	if err != nil {
+		tc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, t, "錦標賽建立成功")
+}
+
+// GetTournaments 列出錦標賽（?status= 篩選狀態）
+func (tc *TournamentController) GetTournaments(c *gin.Context) {
+	list, err := tc.tournamentService.List(c.Query("status"))
+	if err != nil {
+		tc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, list, "錦標賽列表獲取成功")
+}
+
+// GetTournament 取得錦標賽、參賽紀錄與即時狀態
+func (tc *TournamentController) GetTournament(c *gin.Context) {
+	id, ok := tc.tournamentID(c)
+	if !ok {
+		return
+	}
+	t, err := tc.tournamentService.Get(id)
+	if err != nil {
+		tc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{
+		"tournament": t,
+		"live":       tc.manager.State(id),
+	}, "錦標賽獲取成功")
+}
+
+// StartTournament 立即開賽（不等預定時間）
+func (tc *TournamentController) StartTournament(c *gin.Context) {
+	tc.control(c, tc.manager.Start, "錦標賽已開賽")
+}
+
+// PauseTournament 暫停錦標賽
+func (tc *TournamentController) PauseTournament(c *gin.Context) {
+	tc.control(c, tc.manager.Pause, "錦標賽已暫停")
+}
+
+// ResumeTournament 恢復錦標賽
+func (tc *TournamentController) ResumeTournament(c *gin.Context) {
+	tc.control(c, tc.manager.Resume, "錦標賽已恢復")
+}
+
+// CancelTournament 取消錦標賽並退還報名費與服務費
+func (tc *TournamentController) CancelTournament(c *gin.Context) {
+	tc.control(c, tc.manager.Cancel, "錦標賽已取消，報名費已退還")
+}
+
+// RegisterPlayer 代玩家報名（營運端）
+func (tc *TournamentController) RegisterPlayer(c *gin.Context) {
+	id, ok := tc.tournamentID(c)
+	if !ok {
+		return
+	}
+	var req TournamentPlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	tc.register(c, id, req.PlayerID)
+}
+
+// UnregisterPlayer 代玩家取消報名（營運端）
+func (tc *TournamentController) UnregisterPlayer(c *gin.Context) {
+	id, ok := tc.tournamentID(c)
+	if !ok {
+		return
+	}
+	playerID, err := strconv.ParseInt(c.Param("player_id"), 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "玩家ID格式錯誤", "INVALID_PLAYER_ID")
+		return
+	}
+	tc.unregister(c, id, playerID)
+}
+
+// PlayerRegister 玩家自行報名
+func (tc *TournamentController) PlayerRegister(c *gin.Context) {
+	if id, ok := tc.tournamentID(c); ok {
+		tc.register(c, id, c.GetInt64("player_id"))
+	}
+}
+
+// PlayerUnregister 玩家自行取消報名
+func (tc *TournamentController) PlayerUnregister(c *gin.Context) {
+	if id, ok := tc.tournamentID(c); ok {
+		tc.unregister(c, id, c.GetInt64("player_id"))
+	}
+}
+
+// Connect 建立錦標賽 WebSocket 連線；玩家進入自己的牌桌，換桌時連線自動跟隨
+func (tc *TournamentController) Connect(c *gin.Context) {
+	id, ok := tc.tournamentID(c)
+	if !ok {
+		return
+	}
+	token := c.Query("token")
+	if token == "" {
+		token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		ErrorResponse(c, http.StatusUnauthorized, "缺少玩家 Token", "MISSING_TOKEN")
+		return
+	}
+	claims, err := tc.authService.ValidatePlayerToken(token)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, "玩家 Token 驗證失敗", "INVALID_TOKEN")
+		return
+	}
+	if _, err := tc.manager.Locate(id, claims.PlayerID); err != nil {
+		tc.handleError(c, err)
+		return
+	}
+
+	conn, err := tc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	if err := tc.manager.Serve(id, conn, claims.PlayerID, claims.Username); err != nil {
+		conn.Close()
+	}
+}
+
+func (tc *TournamentController) register(c *gin.Context, id, playerID int64) {
+	entry, late, err := tc.tournamentService.Register(id, playerID)
+	if err != nil {
+		tc.handleError(c, err)
+		return
+	}
+	if late {
+		tc.manager.Register(id, *entry)
+	}
+	SuccessResponse(c, gin.H{"entry": entry, "late_registration": late}, "報名成功")
+}
+
+func (tc *TournamentController) unregister(c *gin.Context, id, playerID int64) {
+	if err := tc.tournamentService.Unregister(id, playerID); err != nil {
+		tc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, nil, "已取消報名並退款")
+}
+
+// control 執行需要錦標賽ID的管理操作
+func (tc *TournamentController) control(c *gin.Context, action func(int64) error, message string) {
+	id, ok := tc.tournamentID(c)
+	if !ok {
+		return
+	}
+	if err := action(id); err != nil {
+		tc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"tournament_id": id}, message)
+}
+
+func (tc *TournamentController) tournamentID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "錦標賽ID格式錯誤", "INVALID_TOURNAMENT_ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (tc *TournamentController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTournamentNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "TOURNAMENT_NOT_FOUND")
+	case errors.Is(err, services.ErrPlayerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PLAYER_NOT_FOUND")
+	case errors.Is(err, services.ErrInvalidTournament):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_TOURNAMENT")
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrRoomInactive),
+		errors.Is(err, services.ErrRoomNotPoker), errors.Is(err, services.ErrInvalidBlinds):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "ROOM_NOT_SUPPORTED")
+	case errors.Is(err, services.ErrInsufficientBalance), errors.Is(err, services.ErrWalletNotFound):
+		ErrorResponse(c, http.StatusPaymentRequired, err.Error(), "INSUFFICIENT_BALANCE")
+	case errors.Is(err, services.ErrPlayerInactive):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "PLAYER_INACTIVE")
+	case errors.Is(err, services.ErrRegistrationClosed), errors.Is(err, services.ErrTournamentFull),
+		errors.Is(err, services.ErrAlreadyRegistered), errors.Is(err, services.ErrNotRegistered),
+		errors.Is(err, services.ErrTournamentClosed), errors.Is(err, services.ErrTournamentEntryChanged),
+		errors.Is(err, table.ErrTournamentNotRunning), errors.Is(err, table.ErrTournamentRunning),
+		errors.Is(err, table.ErrTournamentNotOpen), errors.Is(err, table.ErrNotEnoughEntrants),
+		errors.Is(err, table.ErrTournamentPaused), errors.Is(err, table.ErrTournamentNotPaused):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "TOURNAMENT_STATE_CONFLICT")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "錦標賽操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,399 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/payment"
+	"nexus-gaming-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithdrawalController 提領與錢包凍結控制器
+type WithdrawalController struct {
+	withdrawalService *services.WithdrawalService
+	holdService       *services.HoldService
+}
+
+// NewWithdrawalController 建立新的提領控制器
+func NewWithdrawalController() *WithdrawalController {
+	withdrawals := services.NewWithdrawalService()
+	return &WithdrawalController{
+		withdrawalService: withdrawals,
+		holdService:       withdrawals.Holds,
+	}
+}
+
+// WithdrawalListRequest 提領申請列表查詢
+type WithdrawalListRequest struct {
+	PlayerID     int64  `form:"player_id"`
+	Status       string `form:"status" binding:"omitempty,oneof=pending reviewing approved processing completed rejected cancelled"`
+	ReviewStatus string `form:"review_status" binding:"omitempty,oneof=auto_approved manual_review risk_review rejected"`
+	AssignedTo   int    `form:"assigned_to"`
+	Page         int    `form:"page"`
+	Limit        int    `form:"limit"`
+}
+
+// CreateWithdrawalRequest 建立提領申請請求
+type CreateWithdrawalRequest struct {
+	PlayerID         int64           `json:"player_id" binding:"required"`
+	Amount           money.Amount    `json:"amount" binding:"required"`
+	Currency         string          `json:"currency" binding:"omitempty,len=3"` // 省略時為預設幣別
+	WithdrawalMethod string          `json:"withdrawal_method" binding:"required,oneof=bank_transfer e_wallet cryptocurrency check"`
+	PaymentMethodID  *int            `json:"payment_method_id"`                                       // 使用保存的支付方式
+	AccountInfo      json.RawMessage `json:"account_info" binding:"required_without=PaymentMethodID"` // 未指定支付方式時必填
+}
+
+// ReviewWithdrawalRequest 審核提領請求
+type ReviewWithdrawalRequest struct {
+	Notes string `json:"notes"`
+}
+
+// AssignWithdrawalRequest 指派審核員請求（省略時指派給自己）
+type AssignWithdrawalRequest struct {
+	AssigneeID int `json:"assignee_id"`
+}
+
+// WithdrawalNoteRequest 審核備註請求
+type WithdrawalNoteRequest struct {
+	Note string `json:"note" binding:"required,max=2000"`
+}
+
+// PlaceHoldRequest 人工凍結請求
+type PlaceHoldRequest struct {
+	Amount        money.Amount `json:"amount" binding:"required"`
+	Reason        string       `json:"reason" binding:"required,max=50"`
+	ReferenceType string       `json:"reference_type" binding:"required,max=50"`
+	ReferenceID   string       `json:"reference_id" binding:"required,max=100"`
+	TTLSeconds    int          `json:"ttl_seconds" binding:"min=0"` // 0 表示不逾期
+}
+
+// CaptureHoldRequest 凍結扣款請求（金額為 0 或省略時扣除全部凍結金額）
+type CaptureHoldRequest struct {
+	Amount      money.Amount `json:"amount"`
+	Description string       `json:"description"`
+}
+
+// GetWithdrawals 提領申請列表
+func (wc *WithdrawalController) GetWithdrawals(c *gin.Context) {
+	wc.list(c, false, "提領申請列表獲取成功")
+}
+
+// GetReviewQueue 待審核佇列（pending、reviewing，依風險分數由高到低）
+func (wc *WithdrawalController) GetReviewQueue(c *gin.Context) {
+	wc.list(c, true, "待審核佇列獲取成功")
+}
+
+func (wc *WithdrawalController) list(c *gin.Context, queue bool, message string) {
+	var req WithdrawalListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		req.Limit = 20
+	}
+	list, total, err := wc.withdrawalService.List(services.WithdrawalFilter{
+		PlayerID:     req.PlayerID,
+		Status:       req.Status,
+		ReviewStatus: req.ReviewStatus,
+		AssignedTo:   req.AssignedTo,
+		Queue:        queue,
+		Page:         req.Page,
+		Limit:        req.Limit,
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	totalPages := int((total + int64(req.Limit) - 1) / int64(req.Limit))
+	SuccessResponse(c, gin.H{
+		"withdrawals": list,
+		"pagination": gin.H{
+			"page":         req.Page,
+			"limit":        req.Limit,
+			"total":        total,
+			"total_pages":  totalPages,
+			"has_next":     req.Page < totalPages,
+			"has_previous": req.Page > 1,
+		},
+	}, message)
+}
+
+// GetWithdrawal 提領申請詳情（含風險檢查與審核紀錄）
+func (wc *WithdrawalController) GetWithdrawal(c *gin.Context) {
+	w, err := wc.withdrawalService.Get(c.Param("id"))
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	notes, err := wc.withdrawalService.Notes(w.WithdrawalID)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, gin.H{"withdrawal": w, "notes": notes}, "提領申請獲取成功")
+}
+
+// CreateWithdrawal 建立提領申請（凍結提領金額並評估風險；全部規則通過時自動核准並出款）
+func (wc *WithdrawalController) CreateWithdrawal(c *gin.Context) {
+	var req CreateWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if !req.Amount.IsPositive() {
+		ErrorResponse(c, http.StatusBadRequest, services.ErrInvalidAmount.Error(), "INVALID_AMOUNT")
+		return
+	}
+	w, err := wc.withdrawalService.Create(services.WithdrawalRequest{
+		PlayerID:         req.PlayerID,
+		Currency:         strings.ToUpper(req.Currency),
+		Amount:           req.Amount,
+		WithdrawalMethod: req.WithdrawalMethod,
+		PaymentMethodID:  req.PaymentMethodID,
+		AccountInfo:      req.AccountInfo,
+		IPAddress:        c.ClientIP(),
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, w, "提領申請已建立")
+}
+
+// ApproveWithdrawal 核准提領（需雙人覆核者由第二位審核員核准後才出款）
+func (wc *WithdrawalController) ApproveWithdrawal(c *gin.Context) {
+	wc.review(c, wc.withdrawalService.Approve, "提領已核准")
+}
+
+// RejectWithdrawal 駁回提領（釋放凍結）
+func (wc *WithdrawalController) RejectWithdrawal(c *gin.Context) {
+	wc.review(c, wc.withdrawalService.Reject, "提領已駁回")
+}
+
+func (wc *WithdrawalController) review(c *gin.Context, fn func(string, *int, string) (*services.Withdrawal, error), message string) {
+	var req ReviewWithdrawalRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+			return
+		}
+	}
+	reviewerID := c.GetInt("user_id")
+	w, err := fn(c.Param("id"), &reviewerID, req.Notes)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	if w.Status == "reviewing" && w.FirstApproverID != nil {
+		message = "已完成第一位核准，待另一位審核員覆核"
+	}
+	SuccessResponse(c, w, message)
+}
+
+// AssignWithdrawal 指派待審核的提領給審核員
+func (wc *WithdrawalController) AssignWithdrawal(c *gin.Context) {
+	var req AssignWithdrawalRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+			return
+		}
+	}
+	operatorID := c.GetInt("user_id")
+	if req.AssigneeID == 0 {
+		req.AssigneeID = operatorID
+	}
+	w, err := wc.withdrawalService.Assign(c.Param("id"), req.AssigneeID, &operatorID)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, w, "提領已指派")
+}
+
+// AddWithdrawalNote 新增審核備註
+func (wc *WithdrawalController) AddWithdrawalNote(c *gin.Context) {
+	var req WithdrawalNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	userID := c.GetInt("user_id")
+	note, err := wc.withdrawalService.AddNote(c.Param("id"), &userID, req.Note)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, note, "審核備註已新增")
+}
+
+// PayoutWithdrawal 立即對已核准的提領出款，或查詢出款中的提領（排程也會定期處理）
+func (wc *WithdrawalController) PayoutWithdrawal(c *gin.Context) {
+	w, err := wc.withdrawalService.Payout(c.Param("id"))
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, w, "提領出款已處理")
+}
+
+// GetPlayerHolds 玩家的錢包凍結列表（可依 status 篩選）
+func (wc *WithdrawalController) GetPlayerHolds(c *gin.Context) {
+	playerID, ok := parsePlayerID(c)
+	if !ok {
+		return
+	}
+	holds, err := wc.holdService.List(playerID, c.Query("status"))
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, holds, "錢包凍結列表獲取成功")
+}
+
+// PlacePlayerHold 人工凍結玩家可用餘額
+func (wc *WithdrawalController) PlacePlayerHold(c *gin.Context) {
+	playerID, ok := parsePlayerID(c)
+	if !ok {
+		return
+	}
+	var req PlaceHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+		return
+	}
+	if req.ReferenceType == services.HoldRefWithdrawal || req.ReferenceType == services.HoldRefTable {
+		ErrorResponse(c, http.StatusBadRequest, "提領與牌桌凍結由系統建立", "RESERVED_REFERENCE_TYPE")
+		return
+	}
+	operatorID := c.GetInt("user_id")
+	hold, err := wc.holdService.Place(services.HoldRequest{
+		PlayerID:      playerID,
+		Amount:        req.Amount,
+		Reason:        req.Reason,
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   req.ReferenceID,
+		TTL:           time.Duration(req.TTLSeconds) * time.Second,
+		OperatorID:    &operatorID,
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, hold, "已凍結餘額")
+}
+
+// CaptureHold 自凍結扣款（未扣除的部分釋放回可用餘額）
+func (wc *WithdrawalController) CaptureHold(c *gin.Context) {
+	hold, ok := wc.manualHold(c)
+	if !ok {
+		return
+	}
+	var req CaptureHoldRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, "請求參數錯誤: "+err.Error(), "INVALID_REQUEST")
+			return
+		}
+	}
+	if req.Description == "" {
+		req.Description = "凍結扣款（" + hold.Reason + "）"
+	}
+	operatorID := c.GetInt("user_id")
+	txn, err := wc.holdService.Capture(hold.HoldCode, req.Amount, services.WalletEntry{
+		Type:          "adjustment",
+		ReferenceID:   hold.ReferenceID,
+		ReferenceType: hold.ReferenceType,
+		Description:   req.Description,
+		OperatorID:    &operatorID,
+	})
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, txn, "凍結已扣款")
+}
+
+// ReleaseHold 釋放凍結回可用餘額
+func (wc *WithdrawalController) ReleaseHold(c *gin.Context) {
+	hold, ok := wc.manualHold(c)
+	if !ok {
+		return
+	}
+	hold, err := wc.holdService.Release(hold.HoldCode)
+	if err != nil {
+		wc.handleError(c, err)
+		return
+	}
+	SuccessResponse(c, hold, "凍結已釋放")
+}
+
+// manualHold 取得可人工結清的凍結（提領凍結經由審核結清，牌桌凍結由離座結清）
+func (wc *WithdrawalController) manualHold(c *gin.Context) (*services.Hold, bool) {
+	hold, err := wc.holdService.Get(c.Param("code"))
+	if err != nil {
+		wc.handleError(c, err)
+		return nil, false
+	}
+	if hold.ReferenceType == services.HoldRefWithdrawal || hold.ReferenceType == services.HoldRefTable {
+		ErrorResponse(c, http.StatusConflict, "提領凍結請經由審核結清，牌桌凍結於離座時結清", "HOLD_MANAGED")
+		return nil, false
+	}
+	return hold, true
+}
+
+func (wc *WithdrawalController) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWithdrawalNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "WITHDRAWAL_NOT_FOUND")
+	case errors.Is(err, services.ErrHoldNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "HOLD_NOT_FOUND")
+	case errors.Is(err, services.ErrWithdrawalNotPending):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "WITHDRAWAL_NOT_PENDING")
+	case errors.Is(err, services.ErrWithdrawalNotPayable):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "WITHDRAWAL_NOT_PAYABLE")
+	case errors.Is(err, services.ErrWithdrawalAssigned):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "WITHDRAWAL_ASSIGNED")
+	case errors.Is(err, services.ErrSecondApproverSame):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "SECOND_APPROVER_REQUIRED")
+	case errors.Is(err, services.ErrReviewerRequired), errors.Is(err, services.ErrWithdrawalNoteInvalid):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_REQUEST")
+	case errors.Is(err, payment.ErrUnknownProvider), errors.Is(err, payment.ErrPayoutUnsupported),
+		errors.Is(err, services.ErrPayoutNotConfigured):
+		ErrorResponse(c, http.StatusServiceUnavailable, err.Error(), "PAYOUT_UNAVAILABLE")
+	case errors.Is(err, services.ErrGatewayUnavailable):
+		ErrorResponse(c, http.StatusBadGateway, err.Error(), "GATEWAY_UNAVAILABLE")
+	case errors.Is(err, services.ErrHoldNotActive):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "HOLD_NOT_ACTIVE")
+	case errors.Is(err, services.ErrHoldExceeded):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "HOLD_EXCEEDED")
+	case errors.Is(err, services.ErrInsufficientBalance), errors.Is(err, services.ErrWalletNotFound):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "INSUFFICIENT_BALANCE")
+	case errors.Is(err, services.ErrInvalidAmount):
+		ErrorResponse(c, http.StatusBadRequest, err.Error(), "INVALID_AMOUNT")
+	case errors.Is(err, services.ErrPaymentMethodNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PAYMENT_METHOD_NOT_FOUND")
+	case errors.Is(err, services.ErrPaymentMethodBlocked):
+		ErrorResponse(c, http.StatusForbidden, err.Error(), "PAYMENT_METHOD_BLOCKED")
+	case errors.Is(err, services.ErrPaymentMethodInactive):
+		ErrorResponse(c, http.StatusConflict, err.Error(), "PAYMENT_METHOD_INACTIVE")
+	case errors.Is(err, services.ErrVaultUnavailable):
+		ErrorResponse(c, http.StatusServiceUnavailable, err.Error(), "VAULT_UNAVAILABLE")
+	case errors.Is(err, services.ErrPlayerNotFound):
+		ErrorResponse(c, http.StatusNotFound, err.Error(), "PLAYER_NOT_FOUND")
+	case errors.Is(err, services.ErrPlayerInactive):
+		ErrorResponse(c, http.StatusForbidden, "玩家帳號狀態不允許此操作", "PLAYER_INACTIVE")
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, "提領或凍結操作失敗: "+err.Error(), "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,127 @@
+// Package cards 撲克牌編碼與牌型評估
+//
+// 牌張編碼與 rng 套件一致：index = 花色*13 + 點數，
+// 點數 0..12 對應 "23456789TJQKA"，花色 0..3 對應 "cdhs"。
+package cards
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	rankChars = "23456789TJQKA"
+	suitChars = "cdhs"
+)
+
+// DeckSize 一副牌張數
+const DeckSize = 52
+
+// Card 單張牌
+type Card int
+
+// Rank 點數（0 = 2 ... 12 = A）
+func (c Card) Rank() int { return int(c) % 13 }
+
+// Suit 花色（0 = 梅花 ... 3 = 黑桃）
+func (c Card) Suit() int { return int(c) / 13 }
+
+// Valid 是否為合法牌張
+func (c Card) Valid() bool { return c >= 0 && c < DeckSize }
+
+// String 牌張文字（例如 "As"、"Td"）
+func (c Card) String() string {
+	if !c.Valid() {
+		return "??"
+	}
+	return string(rankChars[c.Rank()]) + string(suitChars[c.Suit()])
+}
+
+// MarshalText 以文字形式輸出 JSON
+func (c Card) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Hidden 隱藏的牌張（輸出為 "??"）
+const Hidden Card = -1
+
+// UnmarshalText 解析文字形式的牌張（"??" 解析為 Hidden）
+func (c *Card) UnmarshalText(text []byte) error {
+	if string(text) == "??" {
+		*c = Hidden
+		return nil
+	}
+	card, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*c = card
+	return nil
+}
+
+// New 依點數與花色建立牌張
+func New(rank, suit int) Card {
+	return Card(suit*13 + rank)
+}
+
+// Parse 解析牌張文字
+func Parse(s string) (Card, error) {
+	if len(s) != 2 {
+		return -1, fmt.Errorf("無效的牌張: %q", s)
+	}
+	rank := strings.IndexByte(rankChars, strings.ToUpper(s[:1])[0])
+	suit := strings.IndexByte(suitChars, strings.ToLower(s[1:])[0])
+	if rank < 0 || suit < 0 {
+		return -1, fmt.Errorf("無效的牌張: %q", s)
+	}
+	return New(rank, suit), nil
+}
+
+// ParseList 解析以空白分隔的多張牌
+func ParseList(s string) ([]Card, error) {
+	fields := strings.Fields(s)
+	list := make([]Card, 0, len(fields))
+	for _, f := range fields {
+		c, err := Parse(f)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// FromIndexes 將 rng 洗牌結果轉為牌張
+func FromIndexes(indexes []int) []Card {
+	list := make([]Card, len(indexes))
+	for i, v := range indexes {
+		list[i] = Card(v % DeckSize)
+	}
+	return list
+}
+
+// Mask 牌張集合（bit i 代表牌張 i）
+type Mask uint64
+
+// MaskOf 建立牌張集合
+func MaskOf(list ...Card) Mask {
+	var m Mask
+	for _, c := range list {
+		m |= 1 << uint(c)
+	}
+	return m
+}
+
+// Has 是否包含牌張
+func (m Mask) Has(c Card) bool { return m&(1<<uint(c)) != 0 }
+
+// Remaining 回傳不在集合內的所有牌張
+func (m Mask) Remaining() []Card {
+	list := make([]Card, 0, DeckSize)
+	for c := Card(0); c < DeckSize; c++ {
+		if !m.Has(c) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
@@ -0,0 +1,187 @@
+package cards
+
+import "math/bits"
+
+// Category 牌型類別（數值越大越強）
+type Category int
+
+const (
+	HighCard Category = iota
+	OnePair
+	TwoPair
+	ThreeOfAKind
+	Straight
+	Flush
+	FullHouse
+	FourOfAKind
+	StraightFlush
+)
+
+var categoryNames = [...]string{
+	"high_card", "one_pair", "two_pair", "three_of_a_kind", "straight",
+	"flush", "full_house", "four_of_a_kind", "straight_flush",
+}
+
+// String 牌型代碼
+func (c Category) String() string {
+	if c < 0 || int(c) >= len(categoryNames) {
+		return "unknown"
+	}
+	return categoryNames[c]
+}
+
+// HandValue 牌力值，可直接比較大小（類別 << 20 | 五個踢腳點數各 4 bits）
+type HandValue uint32
+
+// Category 牌型類別
+func (v HandValue) Category() Category { return Category(v >> 20) }
+
+// Evaluate 評估 1 至 7 張牌的最佳牌型
+//
+// 少於 5 張時（例如梭哈未發完的明牌）只計算對子、三條、四條等點數組合，
+// 不計順子與同花。
+func Evaluate(hand []Card) HandValue {
+	var counts [13]int
+	var suits [4]uint16
+	var ranks uint16
+	for _, c := range hand {
+		r := c.Rank()
+		counts[r]++
+		suits[c.Suit()] |= 1 << uint(r)
+		ranks |= 1 << uint(r)
+	}
+
+	if len(hand) >= 5 {
+		for _, sm := range suits {
+			if bits.OnesCount16(sm) < 5 {
+				continue
+			}
+			if top := straightTop(sm); top >= 0 {
+				return value(StraightFlush, top)
+			}
+			return value(Flush, topRanks(sm, 5)...)
+		}
+	}
+
+	// 依張數分組（高點數優先）
+	var quads, trips, pairs, singles []int
+	for r := 12; r >= 0; r-- {
+		switch counts[r] {
+		case 4:
+			quads = append(quads, r)
+		case 3:
+			trips = append(trips, r)
+		case 2:
+			pairs = append(pairs, r)
+		case 1:
+			singles = append(singles, r)
+		}
+	}
+
+	if len(quads) > 0 {
+		return value(FourOfAKind, quads[0], kicker(counts, quads[0]))
+	}
+	if len(trips) > 0 && (len(trips) > 1 || len(pairs) > 0) {
+		pair := -1
+		if len(pairs) > 0 {
+			pair = pairs[0]
+		}
+		if len(trips) > 1 && trips[1] > pair {
+			pair = trips[1]
+		}
+		return value(FullHouse, trips[0], pair)
+	}
+	if len(hand) >= 5 {
+		if top := straightTop(ranks); top >= 0 {
+			return value(Straight, top)
+		}
+	}
+	if len(trips) > 0 {
+		return value(ThreeOfAKind, append([]int{trips[0]}, kickers(counts, 2, trips[0])...)...)
+	}
+	if len(pairs) >= 2 {
+		return value(TwoPair, pairs[0], pairs[1], kicker(counts, pairs[0], pairs[1]))
+	}
+	if len(pairs) == 1 {
+		return value(OnePair, append([]int{pairs[0]}, kickers(counts, 3, pairs[0])...)...)
+	}
+	return value(HighCard, topRanks(ranks, 5)...)
+}
+
+// Compare 比較兩手牌（>0 表示 a 較大）
+func Compare(a, b []Card) int {
+	va, vb := Evaluate(a), Evaluate(b)
+	switch {
+	case va > vb:
+		return 1
+	case va < vb:
+		return -1
+	}
+	return 0
+}
+
+// value 組合牌力值
+func value(cat Category, ranks ...int) HandValue {
+	v := HandValue(cat) << 20
+	for i := 0; i < 5; i++ {
+		r := 0
+		if i < len(ranks) && ranks[i] >= 0 {
+			r = ranks[i] + 1
+		}
+		v |= HandValue(r) << uint(16-4*i)
+	}
+	return v
+}
+
+// straightTop 回傳順子最高點數（A-2-3-4-5 視為 5 高），無順子回傳 -1
+func straightTop(mask uint16) int {
+	for top := 12; top >= 4; top-- {
+		need := uint16(0x1f) << uint(top-4)
+		if mask&need == need {
+			return top
+		}
+	}
+	const wheel = 1<<12 | 0xf
+	if mask&wheel == wheel {
+		return 3
+	}
+	return -1
+}
+
+// topRanks 由高至低取出最多 n 個點數
+func topRanks(mask uint16, n int) []int {
+	list := []int{}
+	for r := 12; r >= 0 && len(list) < n; r-- {
+		if mask&(1<<uint(r)) != 0 {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+// kicker 取出排除指定點數後最高的一張
+func kicker(counts [13]int, exclude ...int) int {
+	k := kickers(counts, 1, exclude...)
+	if len(k) == 0 {
+		return -1
+	}
+	return k[0]
+}
+
+// kickers 取出排除指定點數後最高的 n 張
+func kickers(counts [13]int, n int, exclude ...int) []int {
+	list := []int{}
+outer:
+	for r := 12; r >= 0 && len(list) < n; r-- {
+		if counts[r] == 0 {
+			continue
+		}
+		for _, e := range exclude {
+			if r == e {
+				continue outer
+			}
+		}
+		list = append(list, r)
+	}
+	return list
+}
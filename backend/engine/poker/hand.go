@@ -0,0 +1,720 @@
+package poker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"nexus-gaming-backend/engine/cards"
+)
+
+// lastRound 最後一個下注輪（德州河牌、梭哈第五張）
+const lastRound = 3
+
+// Player 牌局中的玩家狀態
+type Player struct {
+	SeatInfo
+	StartStack float64      `json:"start_stack"`
+	Hole       []cards.Card `json:"hole,omitempty"`
+	Up         []cards.Card `json:"up,omitempty"`
+	Bet        float64      `json:"bet"`       // 本輪已下注
+	Committed  float64      `json:"committed"` // 本手累計投入
+	Folded     bool         `json:"folded"`
+	AllIn      bool         `json:"all_in"`
+	Won        float64      `json:"won"`
+
+	acted bool
+}
+
+// canAct 是否仍可行動
+func (p *Player) canAct() bool {
+	return !p.Folded && !p.AllIn
+}
+
+// Options 輪到行動的座位可用選項
+type Options struct {
+	Seat     int     `json:"seat"`
+	ToCall   float64 `json:"to_call"`
+	MinRaise float64 `json:"min_raise"` // 最小加注需投入的金額（含跟注）
+	Stack    float64 `json:"stack"`
+	CanCheck bool    `json:"can_check"`
+	CanRaise bool    `json:"can_raise"`
+}
+
+// Hand 單手牌局
+type Hand struct {
+	rules      Rules
+	button     int
+	players    []*Player // 依座位排序
+	deck       []cards.Card
+	next       int
+	board      []cards.Card
+	round      int
+	currentBet float64
+	minRaise   float64
+	toAct      int // players 索引，-1 表示無人待行動
+	started    bool
+	over       bool
+	result     *Result
+	events     []Event
+	now        func() time.Time
+}
+
+// NewHand 建立牌局；deck 為已洗好的牌序（通常來自 rng 的可驗證洗牌）
+func NewHand(rules Rules, seats []SeatInfo, button int, deck []cards.Card) (*Hand, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if len(seats) < 2 {
+		return nil, errors.New("至少需要 2 位玩家")
+	}
+	need := 5*len(seats) + 5
+	if len(deck) < need {
+		return nil, fmt.Errorf("牌數不足：需要 %d 張", need)
+	}
+
+	h := &Hand{
+		rules:  rules,
+		button: button,
+		deck:   deck,
+		toAct:  -1,
+		now:    time.Now,
+	}
+	used := map[int]bool{}
+	for _, s := range seats {
+		if s.Seat <= 0 || used[s.Seat] {
+			return nil, fmt.Errorf("座位號無效或重複: %d", s.Seat)
+		}
+		if s.Stack <= 0 {
+			return nil, fmt.Errorf("座位 %d 沒有籌碼", s.Seat)
+		}
+		used[s.Seat] = true
+		h.players = append(h.players, &Player{SeatInfo: s, StartStack: s.Stack})
+	}
+	sort.Slice(h.players, func(i, j int) bool { return h.players[i].Seat < h.players[j].Seat })
+	return h, nil
+}
+
+// SetClock 設定事件時間來源（重播時可固定時間）
+func (h *Hand) SetClock(now func() time.Time) {
+	h.now = now
+}
+
+// Rules 牌局規則
+func (h *Hand) Rules() Rules { return h.rules }
+
+// Button 按鈕位座位
+func (h *Hand) Button() int { return h.button }
+
+// Round 目前下注輪
+func (h *Hand) Round() int { return h.round }
+
+// Board 公共牌
+func (h *Hand) Board() []cards.Card { return append([]cards.Card{}, h.board...) }
+
+// Players 玩家狀態副本
+func (h *Hand) Players() []Player {
+	list := make([]Player, len(h.players))
+	for i, p := range h.players {
+		list[i] = *p
+		list[i].Hole = append([]cards.Card{}, p.Hole...)
+		list[i].Up = append([]cards.Card{}, p.Up...)
+	}
+	return list
+}
+
+// Player 依座位取得玩家狀態副本
+func (h *Hand) Player(seat int) (Player, bool) {
+	for _, p := range h.Players() {
+		if p.Seat == seat {
+			return p, true
+		}
+	}
+	return Player{}, false
+}
+
+// Events 目前為止的所有事件
+func (h *Hand) Events() []Event { return append([]Event{}, h.events...) }
+
+// Over 牌局是否結束
+func (h *Hand) Over() bool { return h.over }
+
+// Result 牌局結果（結束後才有值）
+func (h *Hand) Result() *Result { return h.result }
+
+// Pot 底池總額
+func (h *Hand) Pot() float64 {
+	total := 0.0
+	for _, p := range h.players {
+		total += p.Committed
+	}
+	return round2(total)
+}
+
+// ToAct 待行動的座位（0 表示無）
+func (h *Hand) ToAct() int {
+	if h.toAct < 0 || h.over {
+		return 0
+	}
+	return h.players[h.toAct].Seat
+}
+
+// Options 待行動座位的可用選項
+func (h *Hand) Options() (Options, bool) {
+	if h.toAct < 0 || h.over {
+		return Options{}, false
+	}
+	p := h.players[h.toAct]
+	toCall := h.currentBet - p.Bet
+	if toCall > p.Stack {
+		toCall = p.Stack
+	}
+	minRaise := h.currentBet + h.minRaise - p.Bet
+	return Options{
+		Seat:     p.Seat,
+		ToCall:   round2(toCall),
+		MinRaise: round2(minRaise),
+		Stack:    p.Stack,
+		CanCheck: toCall <= 0,
+		CanRaise: p.Stack > toCall && h.othersCanAct(h.toAct),
+	}, true
+}
+
+// Start 收取盲注／底注、發牌並決定第一位行動者
+func (h *Hand) Start() ([]Event, error) {
+	if h.started {
+		return nil, errors.New("牌局已開始")
+	}
+	h.started = true
+	mark := len(h.events)
+
+	h.emit(Event{Type: EventHandStart, Seat: h.button})
+
+	if h.rules.Ante > 0 {
+		for _, p := range h.players {
+			h.post(p, h.rules.Ante, EventPostAnte)
+		}
+		// 底注直接進入底池，不計入本輪下注
+		for _, p := range h.players {
+			p.Bet = 0
+		}
+	}
+
+	order := h.dealOrder()
+	switch h.rules.Variant {
+	case TexasHoldem:
+		sb, bb := h.blindSeats()
+		h.post(h.players[sb], h.rules.SmallBlind, EventPostBlind)
+		h.post(h.players[bb], h.rules.BigBlind, EventPostBlind)
+		h.currentBet = h.rules.BigBlind
+		h.minRaise = h.rules.BigBlind
+		for _, i := range order {
+			p := h.players[i]
+			p.Hole = h.draw(2)
+			h.emit(Event{Type: EventDealHole, Seat: p.Seat, Cards: p.Hole, Stack: p.Stack})
+		}
+		h.toAct = h.nextCanAct(bb)
+	case FiveCardStud:
+		h.minRaise = h.rules.BigBlind
+		for _, i := range order {
+			p := h.players[i]
+			p.Hole = h.draw(1)
+			h.emit(Event{Type: EventDealHole, Seat: p.Seat, Cards: p.Hole, Stack: p.Stack})
+		}
+		h.dealUpCards(order)
+		h.toAct = h.bestShowing()
+	}
+
+	h.settleIfDone()
+	return h.events[mark:], nil
+}
+
+// Act 套用動作；timeout 表示由系統逾時代為行動
+func (h *Hand) Act(seat int, a Action, timeout bool) ([]Event, error) {
+	if !h.started {
+		return nil, ErrNotStarted
+	}
+	if h.over {
+		return nil, ErrHandOver
+	}
+	if h.toAct < 0 || h.players[h.toAct].Seat != seat {
+		return nil, ErrNotYourTurn
+	}
+	mark := len(h.events)
+	p := h.players[h.toAct]
+	toCall := round2(h.currentBet - p.Bet)
+
+	switch a.Type {
+	case ActionFold:
+		if toCall <= 0 {
+			return nil, fmt.Errorf("%w: 無需跟注時不可蓋牌", ErrInvalidAction)
+		}
+		p.Folded = true
+		a.Amount = 0
+	case ActionCheck:
+		if toCall > 0 {
+			return nil, fmt.Errorf("%w: 需要跟注 %.2f", ErrInvalidAction, toCall)
+		}
+		a.Amount = 0
+	case ActionCall:
+		if toCall <= 0 {
+			return nil, fmt.Errorf("%w: 無需跟注", ErrInvalidAction)
+		}
+		a.Amount = h.commit(p, toCall)
+	case ActionRaise, ActionAllIn:
+		amount := round2(a.Amount)
+		if a.Type == ActionAllIn {
+			amount = p.Stack
+		}
+		if amount <= 0 || amount > p.Stack {
+			return nil, fmt.Errorf("%w: 下注金額無效", ErrInvalidAction)
+		}
+		// 其他玩家皆已全下時無法加注，超出部分視為跟注
+		if !h.othersCanAct(h.toAct) && amount > toCall {
+			amount = toCall
+		}
+		if amount <= toCall {
+			if amount < toCall && amount < p.Stack {
+				return nil, fmt.Errorf("%w: 金額不足以跟注", ErrInvalidAction)
+			}
+			// 全下金額不超過跟注額，視為跟注
+			a.Amount = h.commit(p, amount)
+			break
+		}
+		newBet := round2(p.Bet + amount)
+		raiseBy := round2(newBet - h.currentBet)
+		if raiseBy < h.minRaise && amount < p.Stack {
+			return nil, fmt.Errorf("%w: 最小加注需投入 %.2f", ErrInvalidAction, h.currentBet+h.minRaise-p.Bet)
+		}
+		a.Amount = h.commit(p, amount)
+		if raiseBy >= h.minRaise {
+			h.minRaise = raiseBy
+		}
+		h.currentBet = newBet
+		for _, o := range h.players {
+			if o != p && o.canAct() {
+				o.acted = false
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAction, a.Type)
+	}
+
+	if p.Stack <= 0 && !p.Folded {
+		p.AllIn = true
+		if a.Type != ActionFold {
+			a.Type = ActionAllIn
+		}
+	}
+	p.acted = true
+	act := a
+	h.emit(Event{Type: EventAction, Seat: p.Seat, Action: &act, Timeout: timeout, Amount: a.Amount, Stack: p.Stack})
+
+	h.toAct = h.nextCanAct(h.toAct)
+	h.settleIfDone()
+	return h.events[mark:], nil
+}
+
+// settleIfDone 檢查本輪／整手是否結束並推進牌局
+func (h *Hand) settleIfDone() {
+	for !h.over {
+		if h.activeCount() == 1 {
+			h.finish(false)
+			return
+		}
+		if !h.roundComplete() {
+			return
+		}
+		h.endRound()
+		if h.round == lastRound {
+			h.finish(true)
+			return
+		}
+		h.round++
+		h.dealRound()
+		// 只剩一人（或無人）可以行動時，直接發完剩餘的牌
+		if h.canActCount() <= 1 {
+			h.toAct = -1
+			continue
+		}
+		h.toAct = h.firstToAct()
+	}
+}
+
+// roundComplete 本輪是否所有可行動玩家都已行動且下注相同
+func (h *Hand) roundComplete() bool {
+	for _, p := range h.players {
+		if !p.canAct() {
+			continue
+		}
+		if p.Bet < h.currentBet {
+			return false
+		}
+		if !p.acted && h.canActCount() > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// endRound 收集本輪下注
+func (h *Hand) endRound() {
+	for _, p := range h.players {
+		p.Bet = 0
+		p.acted = false
+	}
+	h.currentBet = 0
+	h.minRaise = h.rules.BigBlind
+	h.emit(Event{Type: EventPotUpdate})
+}
+
+// dealRound 發出下一輪的牌
+func (h *Hand) dealRound() {
+	switch h.rules.Variant {
+	case TexasHoldem:
+		n := 1
+		if h.round == 1 {
+			n = 3
+		}
+		cs := h.draw(n)
+		h.board = append(h.board, cs...)
+		h.emit(Event{Type: EventDealBoard, Cards: cs})
+	case FiveCardStud:
+		h.dealUpCards(h.dealOrder())
+	}
+}
+
+// dealUpCards 梭哈：每位未蓋牌玩家發一張明牌
+func (h *Hand) dealUpCards(order []int) {
+	for _, i := range order {
+		p := h.players[i]
+		if p.Folded {
+			continue
+		}
+		c := h.draw(1)
+		p.Up = append(p.Up, c...)
+		h.emit(Event{Type: EventDealUp, Seat: p.Seat, Cards: c, Stack: p.Stack})
+	}
+}
+
+// firstToAct 每輪第一位行動者
+func (h *Hand) firstToAct() int {
+	if h.rules.Variant == FiveCardStud {
+		return h.bestShowing()
+	}
+	return h.nextCanAct(h.buttonIndex())
+}
+
+// bestShowing 梭哈：明牌最大者先行動（同牌力時以按鈕位左側優先）
+func (h *Hand) bestShowing() int {
+	best, bestValue := -1, cards.HandValue(0)
+	for _, i := range h.dealOrder() {
+		p := h.players[i]
+		if !p.canAct() {
+			continue
+		}
+		if v := cards.Evaluate(p.Up); best < 0 || v > bestValue {
+			best, bestValue = i, v
+		}
+	}
+	return best
+}
+
+// finish 結算牌局
+func (h *Hand) finish(showdown bool) {
+	h.over = true
+	h.toAct = -1
+
+	for _, p := range h.players {
+		p.Bet = 0
+	}
+
+	// 退回無人跟注的下注
+	top, second := -1, 0.0
+	for i, p := range h.players {
+		if top < 0 || p.Committed > h.players[top].Committed {
+			if top >= 0 {
+				second = maxf(second, h.players[top].Committed)
+			}
+			top = i
+		} else {
+			second = maxf(second, p.Committed)
+		}
+	}
+	if excess := round2(h.players[top].Committed - second); excess > 0 {
+		p := h.players[top]
+		p.Committed = round2(p.Committed - excess)
+		p.Stack = round2(p.Stack + excess)
+		h.emit(Event{Type: EventUncalledBet, Seat: p.Seat, Amount: excess, Stack: p.Stack})
+	}
+
+	result := &Result{Payouts: map[int]float64{}, Showdown: showdown}
+	values := map[int]cards.HandValue{}
+	if showdown {
+		result.Hands = map[int]string{}
+		for _, i := range h.dealOrder() {
+			p := h.players[i]
+			if p.Folded {
+				continue
+			}
+			own := append(append(append([]cards.Card{}, p.Hole...), p.Up...), h.board...)
+			v := cards.Evaluate(own)
+			values[p.Seat] = v
+			result.Hands[p.Seat] = v.Category().String()
+			h.emit(Event{Type: EventShowdown, Seat: p.Seat, Cards: append(append([]cards.Card{}, p.Hole...), p.Up...), Hand: v.Category().String(), Stack: p.Stack})
+		}
+	}
+
+	result.Pots = h.buildPots()
+	h.applyRake(result)
+	order := h.dealOrder()
+	for i := range result.Pots {
+		pot := &result.Pots[i]
+		pot.Winners = winners(pot.Eligible, values, showdown)
+		for seat, amount := range split(pot.Amount, pot.Winners, order, h.players) {
+			result.Payouts[seat] = round2(result.Payouts[seat] + amount)
+		}
+	}
+	for _, p := range h.players {
+		if won := result.Payouts[p.Seat]; won > 0 {
+			p.Won = won
+			p.Stack = round2(p.Stack + won)
+		}
+	}
+
+	h.result = result
+	h.emit(Event{Type: EventHandEnd, Result: result})
+}
+
+// buildPots 依投入金額分層建立主池與邊池
+func (h *Hand) buildPots() []Pot {
+	levels := []float64{}
+	seen := map[float64]bool{}
+	for _, p := range h.players {
+		if !p.Folded && p.Committed > 0 && !seen[p.Committed] {
+			seen[p.Committed] = true
+			levels = append(levels, p.Committed)
+		}
+	}
+	sort.Float64s(levels)
+
+	pots := []Pot{}
+	prev := 0.0
+	for li, level := range levels {
+		pot := Pot{}
+		for _, p := range h.players {
+			// 最上層收取剩餘所有投入（含蓋牌玩家超出部分）
+			upto := p.Committed
+			if li < len(levels)-1 && upto > level {
+				upto = level
+			}
+			if upto > prev {
+				pot.Amount += upto - prev
+			}
+			if !p.Folded && p.Committed >= level {
+				pot.Eligible = append(pot.Eligible, p.Seat)
+			}
+		}
+		pot.Amount = round2(pot.Amount)
+		if pot.Amount > 0 {
+			pots = append(pots, pot)
+		}
+		prev = level
+	}
+	return pots
+}
+
+// applyRake 依規則抽水（僅在進入第二輪後，即「no flop no drop」）
+func (h *Hand) applyRake(result *Result) {
+	if h.rules.RakeRate <= 0 || h.round == 0 {
+		return
+	}
+	total := 0.0
+	for _, pot := range result.Pots {
+		total += pot.Amount
+	}
+	rake := round2(total * h.rules.RakeRate)
+	if h.rules.RakeCap > 0 && rake > h.rules.RakeCap {
+		rake = h.rules.RakeCap
+	}
+	result.Rake = rake
+	for i := range result.Pots {
+		if rake <= 0 {
+			break
+		}
+		take := minf(rake, result.Pots[i].Amount)
+		result.Pots[i].Amount = round2(result.Pots[i].Amount - take)
+		result.Pots[i].Rake = take
+		rake = round2(rake - take)
+	}
+}
+
+// winners 底池贏家（未攤牌時為唯一剩餘玩家）
+func winners(eligible []int, values map[int]cards.HandValue, showdown bool) []int {
+	if !showdown {
+		return append([]int{}, eligible...)
+	}
+	best := cards.HandValue(0)
+	list := []int{}
+	for _, seat := range eligible {
+		v := values[seat]
+		switch {
+		case v > best:
+			best, list = v, []int{seat}
+		case v == best:
+			list = append(list, seat)
+		}
+	}
+	return list
+}
+
+// split 平分底池；無法整除的零頭依按鈕位左側順序分配
+func split(amount float64, seats []int, order []int, players []*Player) map[int]float64 {
+	out := map[int]float64{}
+	if len(seats) == 0 {
+		return out
+	}
+	cents := int64(math.Round(amount * 100))
+	share := cents / int64(len(seats))
+	rem := cents % int64(len(seats))
+	in := map[int]bool{}
+	for _, s := range seats {
+		in[s] = true
+		out[s] = float64(share) / 100
+	}
+	for _, i := range order {
+		if rem == 0 {
+			break
+		}
+		if seat := players[i].Seat; in[seat] {
+			out[seat] = round2(out[seat] + 0.01)
+			rem--
+		}
+	}
+	return out
+}
+
+// post 收取強制下注（籌碼不足時全下）
+func (h *Hand) post(p *Player, amount float64, t EventType) {
+	paid := h.commit(p, amount)
+	if p.Stack <= 0 {
+		p.AllIn = true
+	}
+	h.emit(Event{Type: t, Seat: p.Seat, Amount: paid, Stack: p.Stack})
+}
+
+// commit 投入籌碼，回傳實際投入金額
+func (h *Hand) commit(p *Player, amount float64) float64 {
+	if amount > p.Stack {
+		amount = p.Stack
+	}
+	amount = round2(amount)
+	p.Stack = round2(p.Stack - amount)
+	p.Bet = round2(p.Bet + amount)
+	p.Committed = round2(p.Committed + amount)
+	return amount
+}
+
+func (h *Hand) draw(n int) []cards.Card {
+	cs := append([]cards.Card{}, h.deck[h.next:h.next+n]...)
+	h.next += n
+	return cs
+}
+
+func (h *Hand) emit(e Event) {
+	e.Seq = len(h.events) + 1
+	e.At = h.now()
+	e.Round = h.round
+	e.Pot = h.Pot()
+	h.events = append(h.events, e)
+}
+
+// buttonIndex 按鈕位的索引（按鈕座位不在牌局中時取其前一位）
+func (h *Hand) buttonIndex() int {
+	idx := len(h.players) - 1
+	for i, p := range h.players {
+		if p.Seat <= h.button {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// dealOrder 由按鈕位左側開始的索引順序
+func (h *Hand) dealOrder() []int {
+	n := len(h.players)
+	start := h.buttonIndex() + 1
+	order := make([]int, n)
+	for i := range order {
+		order[i] = (start + i) % n
+	}
+	return order
+}
+
+// blindSeats 小盲與大盲的索引（兩人時按鈕位為小盲）
+func (h *Hand) blindSeats() (int, int) {
+	n := len(h.players)
+	b := h.buttonIndex()
+	if n == 2 {
+		return b, (b + 1) % n
+	}
+	return (b + 1) % n, (b + 2) % n
+}
+
+// nextCanAct 由索引 from 之後找下一位可行動的玩家
+func (h *Hand) nextCanAct(from int) int {
+	n := len(h.players)
+	for i := 1; i <= n; i++ {
+		idx := (from + i) % n
+		if p := h.players[idx]; p.canAct() && (!p.acted || p.Bet < h.currentBet) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// othersCanAct 除了 idx 之外是否還有人可以行動
+func (h *Hand) othersCanAct(idx int) bool {
+	for i, p := range h.players {
+		if i != idx && p.canAct() {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hand) activeCount() int {
+	n := 0
+	for _, p := range h.players {
+		if !p.Folded {
+			n++
+		}
+	}
+	return n
+}
+
+func (h *Hand) canActCount() int {
+	n := 0
+	for _, p := range h.players {
+		if p.canAct() {
+			n++
+		}
+	}
+	return n
+}
+
+func maxf(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minf(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
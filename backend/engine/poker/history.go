@@ -0,0 +1,259 @@
+package poker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"nexus-gaming-backend/engine/cards"
+)
+
+// HistoryFormat 牌局歷史格式版本
+const HistoryFormat = "nexus-hh/1"
+
+// History 標準牌局歷史：開局座位、每張牌、每個動作（含時間）、籌碼與底池變化及結果
+//
+// 由 NewHistory 於牌局結束時產生，寫入後不再修改；搭配 Fairness 的種子即可重播。
+type History struct {
+	Format      string          `json:"format"`
+	SessionID   int64           `json:"session_id"`
+	SessionCode string          `json:"session_code"`
+	Table       HistoryTable    `json:"table"`
+	Currency    string          `json:"currency,omitempty"`
+	Rules       Rules           `json:"rules"`
+	Button      int             `json:"button"`
+	Seats       []HistorySeat   `json:"seats"`
+	Board       []cards.Card    `json:"board,omitempty"`
+	Actions     []HistoryAction `json:"actions"`
+	Events      []Event         `json:"events"`
+	Result      *Result         `json:"result"`
+	Fairness    HistoryFairness `json:"fairness"`
+	StartedAt   time.Time       `json:"started_at"`
+	FinishedAt  time.Time       `json:"finished_at"`
+}
+
+// HistoryTable 牌桌資訊
+type HistoryTable struct {
+	RoomID     int64  `json:"room_id"`
+	RoomCode   string `json:"room_code"`
+	Name       string `json:"name"`
+	MaxPlayers int    `json:"max_players"`
+}
+
+// HistorySeat 座位的開局與結束狀態
+type HistorySeat struct {
+	SeatInfo
+	EndStack  float64      `json:"end_stack"`
+	Hole      []cards.Card `json:"hole,omitempty"`
+	Up        []cards.Card `json:"up,omitempty"`
+	Committed float64      `json:"committed"`
+	Won       float64      `json:"won"`
+	Folded    bool         `json:"folded"`
+}
+
+// HistoryAction 依序發生的玩家動作（重播的輸入）
+type HistoryAction struct {
+	Seq     int       `json:"seq"` // 對應的事件序號
+	Round   int       `json:"round"`
+	Seat    int       `json:"seat"`
+	Action  Action    `json:"action"`
+	Timeout bool      `json:"timeout,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// HistoryFairness 產生牌序的種子（揭露後寫入）
+type HistoryFairness struct {
+	Algorithm      string   `json:"algorithm"`
+	ServerSeedHash string   `json:"server_seed_hash"`
+	ServerSeed     string   `json:"server_seed"`
+	ClientSeeds    []string `json:"client_seeds"`
+	Nonce          int64    `json:"nonce"`
+}
+
+// NewHistory 由已結束的牌局產生歷史紀錄（牌桌、幣別與種子由呼叫端補上）
+func NewHistory(h *Hand) (*History, error) {
+	if !h.over {
+		return nil, errors.New("牌局尚未結束")
+	}
+	hist := &History{
+		Format: HistoryFormat,
+		Rules:  h.rules,
+		Button: h.button,
+		Board:  h.Board(),
+		Events: h.Events(),
+		Result: h.result,
+	}
+	for _, p := range h.players {
+		info := p.SeatInfo
+		info.Stack = p.StartStack
+		hist.Seats = append(hist.Seats, HistorySeat{
+			SeatInfo:  info,
+			EndStack:  p.Stack,
+			Hole:      append([]cards.Card{}, p.Hole...),
+			Up:        append([]cards.Card{}, p.Up...),
+			Committed: p.Committed,
+			Won:       p.Won,
+			Folded:    p.Folded,
+		})
+	}
+	hist.Actions = []HistoryAction{}
+	for _, e := range hist.Events {
+		if e.Type == EventAction && e.Action != nil {
+			hist.Actions = append(hist.Actions, HistoryAction{
+				Seq: e.Seq, Round: e.Round, Seat: e.Seat, Action: *e.Action, Timeout: e.Timeout, At: e.At,
+			})
+		}
+	}
+	if n := len(hist.Events); n > 0 {
+		hist.StartedAt = hist.Events[0].At
+		hist.FinishedAt = hist.Events[n-1].At
+	}
+	return hist, nil
+}
+
+// Seat 取得座位紀錄
+func (hist *History) Seat(seat int) (HistorySeat, bool) {
+	for _, s := range hist.Seats {
+		if s.Seat == seat {
+			return s, true
+		}
+	}
+	return HistorySeat{}, false
+}
+
+// SeatOfPlayer 玩家所在座位（0 表示未參與）
+func (hist *History) SeatOfPlayer(playerID int64) int {
+	for _, s := range hist.Seats {
+		if !s.IsBot() && s.PlayerID == playerID {
+			return s.Seat
+		}
+	}
+	return 0
+}
+
+// Shown 攤牌時亮出牌面的座位
+func (hist *History) Shown() map[int]bool {
+	shown := map[int]bool{}
+	for _, e := range hist.Events {
+		if e.Type == EventShowdown {
+			shown[e.Seat] = true
+		}
+	}
+	return shown
+}
+
+// ForViewer 玩家視角的副本：只保留自己與攤牌座位的暗牌（viewer 為 0 時隱藏所有未亮出的暗牌）
+func (hist *History) ForViewer(viewer int) *History {
+	shown := hist.Shown()
+	out := *hist
+	out.Seats = make([]HistorySeat, len(hist.Seats))
+	for i, s := range hist.Seats {
+		if s.Seat != viewer && !shown[s.Seat] {
+			s.Hole = hidden(len(s.Hole))
+		}
+		out.Seats[i] = s
+	}
+	out.Events = make([]Event, len(hist.Events))
+	for i, e := range hist.Events {
+		if e.Seat != viewer {
+			e = e.Redacted()
+		}
+		out.Events[i] = e
+	}
+	return &out
+}
+
+func hidden(n int) []cards.Card {
+	cs := make([]cards.Card, n)
+	for i := range cs {
+		cs[i] = cards.Hidden
+	}
+	return cs
+}
+
+// ReplayReport 重播比對結果
+type ReplayReport struct {
+	Matches        bool            `json:"matches"`
+	Mismatches     []string        `json:"mismatches,omitempty"`
+	EventsCompared int             `json:"events_compared"`
+	ActionsApplied int             `json:"actions_applied"`
+	Result         *Result         `json:"result,omitempty"`
+	EndStacks      map[int]float64 `json:"end_stacks"`
+}
+
+// maxMismatches 回報的差異上限
+const maxMismatches = 20
+
+// Replay 以牌序與紀錄的動作重新執行引擎，逐一比對事件、結果與結束籌碼
+func Replay(hist *History, deck []cards.Card) (*ReplayReport, error) {
+	seats := make([]SeatInfo, len(hist.Seats))
+	for i, s := range hist.Seats {
+		seats[i] = s.SeatInfo
+	}
+	h, err := NewHand(hist.Rules, seats, hist.Button, deck)
+	if err != nil {
+		return nil, err
+	}
+	// 事件時間沿用紀錄，使比對只反映牌局內容
+	next := 0
+	h.SetClock(func() time.Time {
+		var at time.Time
+		if next < len(hist.Events) {
+			at = hist.Events[next].At
+		}
+		next++
+		return at
+	})
+
+	report := &ReplayReport{EndStacks: map[int]float64{}}
+	mismatch := func(format string, args ...interface{}) {
+		if len(report.Mismatches) < maxMismatches {
+			report.Mismatches = append(report.Mismatches, fmt.Sprintf(format, args...))
+		}
+	}
+
+	if _, err := h.Start(); err != nil {
+		return nil, err
+	}
+	for _, a := range hist.Actions {
+		if _, err := h.Act(a.Seat, a.Action, a.Timeout); err != nil {
+			mismatch("事件 #%d 座位 %d 的動作無法重播: %v", a.Seq, a.Seat, err)
+			break
+		}
+		report.ActionsApplied++
+	}
+	if !h.Over() {
+		mismatch("重播後牌局未結束")
+	}
+
+	replayed := h.Events()
+	if len(replayed) != len(hist.Events) {
+		mismatch("事件數量不符：紀錄 %d，重播 %d", len(hist.Events), len(replayed))
+	}
+	for i := 0; i < len(replayed) && i < len(hist.Events); i++ {
+		report.EventsCompared++
+		if !sameJSON(replayed[i], hist.Events[i]) {
+			mismatch("事件 #%d（%s）內容不符", hist.Events[i].Seq, hist.Events[i].Type)
+		}
+	}
+	if !sameJSON(h.Result(), hist.Result) {
+		mismatch("牌局結果不符")
+	}
+	for _, p := range h.Players() {
+		report.EndStacks[p.Seat] = p.Stack
+		if s, ok := hist.Seat(p.Seat); !ok || s.EndStack != p.Stack {
+			mismatch("座位 %d 結束籌碼不符：紀錄 %.2f，重播 %.2f", p.Seat, s.EndStack, p.Stack)
+		}
+	}
+
+	report.Result = h.Result()
+	report.Matches = len(report.Mismatches) == 0
+	return report, nil
+}
+
+func sameJSON(a, b interface{}) bool {
+	x, err1 := json.Marshal(a)
+	y, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(x) == string(y)
+}
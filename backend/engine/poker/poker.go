@@ -0,0 +1,161 @@
+// Package poker 德州撲克與五張梭哈的單手牌局狀態機
+//
+// Hand 不做任何 I/O：輸入為座位、規則、已洗好的牌序與依序發生的動作，
+// 輸出為事件序列。相同的輸入必定產生相同的事件與結果，可供牌局重播驗證。
+package poker
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"nexus-gaming-backend/engine/cards"
+)
+
+// Variant 遊戲變體（對應 games.game_type）
+type Variant string
+
+const (
+	TexasHoldem  Variant = "texas_holdem"
+	FiveCardStud Variant = "stud_poker"
+)
+
+// Rules 牌局規則
+type Rules struct {
+	Variant    Variant `json:"variant"`
+	SmallBlind float64 `json:"small_blind,omitempty"` // 德州小盲
+	BigBlind   float64 `json:"big_blind"`             // 德州大盲；梭哈為下注單位
+	Ante       float64 `json:"ante,omitempty"`        // 底注
+	RakeRate   float64 `json:"rake_rate,omitempty"`   // 抽水比例
+	RakeCap    float64 `json:"rake_cap,omitempty"`    // 抽水上限（0 表示不設限）
+}
+
+// Validate 驗證規則
+func (r Rules) Validate() error {
+	switch r.Variant {
+	case TexasHoldem:
+		if r.SmallBlind <= 0 || r.BigBlind < r.SmallBlind {
+			return errors.New("盲注設定無效")
+		}
+	case FiveCardStud:
+		if r.BigBlind <= 0 {
+			return errors.New("下注單位必須大於 0")
+		}
+	default:
+		return errors.New("不支援的遊戲變體: " + string(r.Variant))
+	}
+	if r.Ante < 0 || r.RakeRate < 0 || r.RakeRate >= 1 || r.RakeCap < 0 {
+		return errors.New("底注或抽水設定無效")
+	}
+	return nil
+}
+
+// ActionType 動作類型（與 ai 套件相同的代碼）
+type ActionType string
+
+const (
+	ActionFold  ActionType = "fold"
+	ActionCheck ActionType = "check"
+	ActionCall  ActionType = "call"
+	ActionRaise ActionType = "raise"
+	ActionAllIn ActionType = "all_in"
+)
+
+// Action 玩家動作；Amount 為本次投入的籌碼
+type Action struct {
+	Type   ActionType `json:"type"`
+	Amount float64    `json:"amount,omitempty"`
+}
+
+// SeatInfo 開局時的座位資訊
+type SeatInfo struct {
+	Seat     int     `json:"seat"`      // 座位號（從 1 開始）
+	PlayerID int64   `json:"player_id"` // 真人玩家 ID（AI 為 0）
+	BotID    string  `json:"bot_id,omitempty"`
+	Name     string  `json:"name"`
+	Stack    float64 `json:"stack"`
+}
+
+// IsBot 是否為 AI 玩家
+func (s SeatInfo) IsBot() bool { return s.BotID != "" }
+
+// EventType 事件類型
+type EventType string
+
+const (
+	EventHandStart   EventType = "hand_start"
+	EventPostAnte    EventType = "post_ante"
+	EventPostBlind   EventType = "post_blind"
+	EventDealHole    EventType = "deal_hole"  // 私密：只有該座位可見牌面
+	EventDealUp      EventType = "deal_up"    // 梭哈明牌
+	EventDealBoard   EventType = "deal_board" // 德州公共牌
+	EventAction      EventType = "action"
+	EventPotUpdate   EventType = "pot_update"
+	EventUncalledBet EventType = "uncalled_bet"
+	EventShowdown    EventType = "showdown"
+	EventHandEnd     EventType = "hand_end"
+)
+
+// Event 牌局事件
+type Event struct {
+	Seq     int          `json:"seq"`
+	Type    EventType    `json:"type"`
+	At      time.Time    `json:"at"`
+	Round   int          `json:"round"`
+	Seat    int          `json:"seat,omitempty"`
+	Cards   []cards.Card `json:"cards,omitempty"`
+	Action  *Action      `json:"action,omitempty"`
+	Timeout bool         `json:"timeout,omitempty"` // 逾時由系統代為行動
+	Amount  float64      `json:"amount,omitempty"`
+	Stack   float64      `json:"stack"` // 該座位事件後的籌碼
+	Pot     float64      `json:"pot"`   // 事件後的底池總額
+	Hand    string       `json:"hand,omitempty"`
+	Result  *Result      `json:"result,omitempty"`
+}
+
+// Private 是否含有僅限單一座位可見的牌面
+func (e Event) Private() bool {
+	return e.Type == EventDealHole
+}
+
+// Redacted 隱藏牌面的副本（保留張數）
+func (e Event) Redacted() Event {
+	if !e.Private() {
+		return e
+	}
+	e.Cards = make([]cards.Card, len(e.Cards))
+	for i := range e.Cards {
+		e.Cards[i] = cards.Hidden
+	}
+	return e
+}
+
+// Pot 底池（主池或邊池）
+type Pot struct {
+	Amount   float64 `json:"amount"`
+	Rake     float64 `json:"rake"`
+	Eligible []int   `json:"eligible"`
+	Winners  []int   `json:"winners"`
+}
+
+// Result 牌局結果
+type Result struct {
+	Pots     []Pot           `json:"pots"`
+	Payouts  map[int]float64 `json:"payouts"` // 座位 → 贏得金額
+	Rake     float64         `json:"rake"`
+	Showdown bool            `json:"showdown"`
+	Hands    map[int]string  `json:"hands,omitempty"` // 攤牌座位 → 牌型
+}
+
+// 錯誤
+var (
+	ErrHandOver      = errors.New("牌局已結束")
+	ErrNotYourTurn   = errors.New("尚未輪到此座位行動")
+	ErrInvalidAction = errors.New("不合法的動作")
+	ErrNotStarted    = errors.New("牌局尚未開始")
+)
+
+// round2 四捨五入至分
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,352 @@
+package poker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nexus-gaming-backend/engine/cards"
+)
+
+// 德州各輪標題（依 round 索引）
+var holdemStreets = []string{"HOLE CARDS", "FLOP", "TURN", "RIVER"}
+
+// 梭哈各輪標題（第二張牌起算）
+var studStreets = []string{"2nd STREET", "3rd STREET", "4th STREET", "5th STREET"}
+
+// Text 以 PokerStars 相容的文字格式輸出牌局歷史（可匯入一般撲克分析工具）
+//
+// viewer 為觀看者的座位：只列出自己與攤牌座位的暗牌；viewer 為 -1 時列出所有暗牌（管理端）。
+func (hist *History) Text(viewer int) string {
+	var b strings.Builder
+	names := map[int]string{}
+	for _, s := range hist.Seats {
+		names[s.Seat] = seatName(s)
+	}
+	shown := hist.Shown()
+	visible := func(seat int) bool { return viewer < 0 || seat == viewer || shown[seat] }
+
+	stud := hist.Rules.Variant == FiveCardStud
+	game := "Hold'em No Limit"
+	stakes := money(hist.Rules.SmallBlind) + "/" + money(hist.Rules.BigBlind)
+	if stud {
+		game = "5 Card Stud No Limit"
+		stakes = "Ante " + money(hist.Rules.Ante) + ", Bring-In " + money(hist.Rules.BigBlind)
+	}
+	if hist.Currency != "" {
+		stakes += " " + hist.Currency
+	}
+	fmt.Fprintf(&b, "PokerStars Hand #%d: %s (%s) - %s UTC\n",
+		hist.SessionID, game, stakes, hist.StartedAt.UTC().Format("2006/01/02 15:04:05"))
+	fmt.Fprintf(&b, "Table '%s' %d-max Seat #%d is the button\n", tableName(hist), hist.Table.MaxPlayers, hist.Button)
+	for _, s := range hist.Seats {
+		fmt.Fprintf(&b, "Seat %d: %s (%s in chips)\n", s.Seat, names[s.Seat], money(s.Stack))
+	}
+
+	// 本輪各座位已下注金額，用來換算 "raises X to Y"
+	bets := map[int]float64{}
+	current := 0.0
+	round := 0
+	printed := map[string]bool{}
+	header := func(title string, extra ...string) {
+		if printed[title] {
+			return
+		}
+		printed[title] = true
+		line := "*** " + title + " ***"
+		if len(extra) > 0 {
+			line += " " + strings.Join(extra, " ")
+		}
+		b.WriteString(line + "\n")
+	}
+	for _, e := range hist.Events {
+		if e.Round > round {
+			bets, current, round = map[int]float64{}, 0, e.Round
+		}
+		switch e.Type {
+		case EventPostAnte:
+			fmt.Fprintf(&b, "%s: posts the ante %s\n", names[e.Seat], money(e.Amount))
+		case EventPostBlind:
+			kind := "small blind"
+			if current > 0 {
+				kind = "big blind"
+			}
+			bets[e.Seat] = e.Amount
+			if e.Amount > current {
+				current = e.Amount
+			}
+			fmt.Fprintf(&b, "%s: posts %s %s\n", names[e.Seat], kind, money(e.Amount))
+		case EventDealHole:
+			if stud {
+				header(studStreets[0])
+			} else {
+				header(holdemStreets[0])
+			}
+			if visible(e.Seat) {
+				s, _ := hist.Seat(e.Seat)
+				if stud {
+					fmt.Fprintf(&b, "Dealt to %s %s %s\n", names[e.Seat], cardList(e.Cards), cardList(firstN(s.Up, 1)))
+				} else {
+					fmt.Fprintf(&b, "Dealt to %s %s\n", names[e.Seat], cardList(e.Cards))
+				}
+			}
+		case EventDealUp:
+			if e.Round == 0 {
+				// 自己的第一張明牌已與暗牌一起列出
+				if !visible(e.Seat) {
+					fmt.Fprintf(&b, "Dealt to %s %s\n", names[e.Seat], cardList(e.Cards))
+				}
+				continue
+			}
+			header(studStreets[e.Round])
+			s, _ := hist.Seat(e.Seat)
+			fmt.Fprintf(&b, "Dealt to %s %s %s\n", names[e.Seat], cardList(firstN(s.Up, e.Round)), cardList(e.Cards))
+		case EventDealBoard:
+			if prior := firstN(hist.Board, boardBefore(e.Round)); len(prior) > 0 {
+				header(holdemStreets[e.Round], cardList(prior), cardList(e.Cards))
+			} else {
+				header(holdemStreets[e.Round], cardList(e.Cards))
+			}
+		case EventAction:
+			fmt.Fprintf(&b, "%s: %s\n", names[e.Seat], actionText(e, bets, &current))
+		case EventUncalledBet:
+			fmt.Fprintf(&b, "Uncalled bet (%s) returned to %s\n", money(e.Amount), names[e.Seat])
+		case EventShowdown:
+			header("SHOW DOWN")
+			fmt.Fprintf(&b, "%s: shows %s (%s)\n", names[e.Seat], cardList(e.Cards), handName(e.Hand))
+		}
+	}
+
+	result := hist.Result
+	if result != nil {
+		for i, pot := range result.Pots {
+			label := "pot"
+			if len(result.Pots) > 1 {
+				label = "main pot"
+				if i > 0 {
+					label = fmt.Sprintf("side pot-%d", i)
+				}
+			}
+			share := splitForText(pot)
+			for _, seat := range pot.Winners {
+				fmt.Fprintf(&b, "%s collected %s from %s\n", names[seat], money(share[seat]), label)
+			}
+		}
+	}
+
+	b.WriteString("*** SUMMARY ***\n")
+	total, rake := 0.0, 0.0
+	if result != nil {
+		for _, pot := range result.Pots {
+			total += pot.Amount + pot.Rake
+		}
+		rake = result.Rake
+	}
+	fmt.Fprintf(&b, "Total pot %s | Rake %s\n", money(total), money(rake))
+	if len(hist.Board) > 0 {
+		fmt.Fprintf(&b, "Board %s\n", cardList(hist.Board))
+	}
+	for _, s := range hist.Seats {
+		fmt.Fprintf(&b, "Seat %d: %s%s %s\n", s.Seat, names[s.Seat], seatRole(hist, s.Seat), seatSummary(hist, s, shown, visible(s.Seat)))
+	}
+	return b.String()
+}
+
+// actionText 動作文字，並更新本輪下注狀態
+func actionText(e Event, bets map[int]float64, current *float64) string {
+	a := e.Action
+	suffix := ""
+	if a.Type == ActionAllIn {
+		suffix = " and is all-in"
+	}
+	switch {
+	case a.Type == ActionFold:
+		return "folds"
+	case a.Type == ActionCheck:
+		return "checks"
+	case a.Amount <= 0:
+		return "checks"
+	}
+
+	before := bets[e.Seat]
+	bets[e.Seat] = round2(before + a.Amount)
+	to := bets[e.Seat]
+	switch {
+	case to <= *current:
+		return "calls " + money(a.Amount) + suffix
+	case *current <= 0:
+		*current = to
+		return "bets " + money(a.Amount) + suffix
+	default:
+		by := round2(to - *current)
+		*current = to
+		return "raises " + money(by) + " to " + money(to) + suffix
+	}
+}
+
+// seatSummary 摘要區的座位結果
+func seatSummary(hist *History, s HistorySeat, shown map[int]bool, visible bool) string {
+	if s.Folded {
+		return "folded " + foldStreet(hist, s.Seat)
+	}
+	hand := ""
+	if hist.Result != nil {
+		hand = hist.Result.Hands[s.Seat]
+	}
+	switch {
+	case shown[s.Seat] && s.Won > 0:
+		return fmt.Sprintf("showed %s and won (%s) with %s", cardList(s.Hole), money(s.Won), handName(hand))
+	case shown[s.Seat]:
+		return fmt.Sprintf("showed %s and lost with %s", cardList(s.Hole), handName(hand))
+	case s.Won > 0:
+		return fmt.Sprintf("collected (%s)", money(s.Won))
+	case visible && len(s.Hole) > 0:
+		return "mucked " + cardList(s.Hole)
+	default:
+		return "mucked"
+	}
+}
+
+// foldStreet 蓋牌時所在的輪次文字
+func foldStreet(hist *History, seat int) string {
+	for _, e := range hist.Events {
+		if e.Type != EventAction || e.Seat != seat || e.Action.Type != ActionFold {
+			continue
+		}
+		if hist.Rules.Variant == FiveCardStud {
+			return "on " + studStreets[e.Round]
+		}
+		switch e.Round {
+		case 0:
+			return "before Flop"
+		case 1:
+			return "on the Flop"
+		case 2:
+			return "on the Turn"
+		default:
+			return "on the River"
+		}
+	}
+	return ""
+}
+
+// seatRole 按鈕位與盲注標示
+func seatRole(hist *History, seat int) string {
+	roles := []string{}
+	if seat == hist.Button {
+		roles = append(roles, "button")
+	}
+	blinds := 0
+	for _, e := range hist.Events {
+		if e.Type != EventPostBlind {
+			continue
+		}
+		blinds++
+		if e.Seat == seat {
+			if blinds == 1 {
+				roles = append(roles, "small blind")
+			} else {
+				roles = append(roles, "big blind")
+			}
+		}
+	}
+	if len(roles) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(roles, ") (") + ")"
+}
+
+// splitForText 各贏家於底池中分得的金額
+func splitForText(pot Pot) map[int]float64 {
+	share := map[int]float64{}
+	if len(pot.Winners) == 0 {
+		return share
+	}
+	each := round2(pot.Amount / float64(len(pot.Winners)))
+	winners := append([]int{}, pot.Winners...)
+	sort.Ints(winners)
+	rest := pot.Amount
+	for i, seat := range winners {
+		if i == len(winners)-1 {
+			share[seat] = round2(rest)
+			break
+		}
+		share[seat] = each
+		rest -= each
+	}
+	return share
+}
+
+// boardBefore 德州：某輪之前已發出的公共牌張數
+func boardBefore(round int) int {
+	switch round {
+	case 1:
+		return 0
+	case 2:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func firstN(list []cards.Card, n int) []cards.Card {
+	if n > len(list) {
+		n = len(list)
+	}
+	return list[:n]
+}
+
+func cardList(list []cards.Card) string {
+	parts := make([]string, len(list))
+	for i, c := range list {
+		parts[i] = c.String()
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// handName 牌型代碼轉為文字（one_pair → a pair）
+func handName(category string) string {
+	switch category {
+	case "high_card":
+		return "high card"
+	case "one_pair":
+		return "a pair"
+	case "two_pair":
+		return "two pair"
+	case "three_of_a_kind":
+		return "three of a kind"
+	case "straight":
+		return "a straight"
+	case "flush":
+		return "a flush"
+	case "full_house":
+		return "a full house"
+	case "four_of_a_kind":
+		return "four of a kind"
+	case "straight_flush":
+		return "a straight flush"
+	}
+	return strings.ReplaceAll(category, "_", " ")
+}
+
+func seatName(s HistorySeat) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if s.IsBot() {
+		return s.BotID
+	}
+	return "Player" + strconv.FormatInt(s.PlayerID, 10)
+}
+
+func tableName(hist *History) string {
+	if hist.Table.Name != "" {
+		return hist.Table.Name
+	}
+	return hist.Table.RoomCode
+}
+
+func money(v float64) string {
+	return strconv.FormatFloat(round2(v), 'f', -1, 64)
+}
@@ -0,0 +1,206 @@
+package slots
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ConfigKey 老虎機設定在 game_configs 中使用的 config_key
+const ConfigKey = "slot_machine"
+
+// SymbolType 符號類型
+type SymbolType string
+
+const (
+	SymbolNormal  SymbolType = "normal"
+	SymbolWild    SymbolType = "wild"
+	SymbolScatter SymbolType = "scatter"
+)
+
+// EvaluationMode 派彩計算方式
+type EvaluationMode string
+
+const (
+	EvaluationPaylines EvaluationMode = "paylines" // 固定賠付線
+	EvaluationWays     EvaluationMode = "ways"     // 任意路（相鄰輪軸）
+)
+
+// Symbol 符號定義
+type Symbol struct {
+	ID   string             `json:"id"`   // 符號代碼（輪帶中使用）
+	Name string             `json:"name"` // 顯示名稱
+	Type SymbolType         `json:"type"` // 符號類型
+	Pays map[string]float64 `json:"pays"` // 連線數 → 賠率倍數（線注或總注，依類型而定）
+}
+
+// FreeSpinConfig 免費旋轉設定
+type FreeSpinConfig struct {
+	TriggerSymbol string         `json:"trigger_symbol"`  // 觸發符號（預設為唯一的 scatter）
+	Awards        map[string]int `json:"awards"`          // 觸發符號數量 → 免費旋轉次數
+	Multiplier    float64        `json:"multiplier"`      // 免費旋轉派彩倍數（預設 1）
+	Retrigger     bool           `json:"retrigger"`       // 免費旋轉中是否可再觸發
+	Reels         [][]string     `json:"reels,omitempty"` // 免費旋轉輪帶（省略則沿用主輪帶）
+}
+
+// Config 老虎機設定（儲存在 game_configs.config_value）
+//
+// 賠率單位：
+//   - 一般/百搭符號的 pays 為「線注」倍數，線注 = 總注 / bet_divisor
+//   - scatter 符號的 pays 為「總注」倍數，不受賠付線限制
+type Config struct {
+	Rows       int             `json:"rows"`                  // 可見列數
+	Reels      [][]string      `json:"reels"`                 // 各輪軸的輪帶
+	Symbols    []Symbol        `json:"symbols"`               // 符號與賠率表
+	Evaluation EvaluationMode  `json:"evaluation"`            // paylines 或 ways
+	Paylines   [][]int         `json:"paylines,omitempty"`    // 每條賠付線在各輪軸的列索引
+	BetDivisor float64         `json:"bet_divisor,omitempty"` // 總注換算線注的除數（paylines 預設為線數，ways 預設為 1）
+	FreeSpins  *FreeSpinConfig `json:"free_spins,omitempty"`  // 免費旋轉設定
+}
+
+// ParseConfig 解析並驗證老虎機設定
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("老虎機設定格式錯誤: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate 驗證設定內容
+func (c *Config) Validate() error {
+	if c.Rows <= 0 {
+		return errors.New("rows 必須大於 0")
+	}
+	if len(c.Reels) < 3 {
+		return errors.New("至少需要 3 個輪軸")
+	}
+	if len(c.Symbols) == 0 {
+		return errors.New("未定義任何符號")
+	}
+
+	symbols := make(map[string]SymbolType, len(c.Symbols))
+	scatters := 0
+	for _, s := range c.Symbols {
+		if s.ID == "" {
+			return errors.New("符號代碼不能為空")
+		}
+		if _, dup := symbols[s.ID]; dup {
+			return fmt.Errorf("符號代碼重複: %s", s.ID)
+		}
+		switch s.Type {
+		case SymbolNormal, SymbolWild:
+		case SymbolScatter:
+			scatters++
+		default:
+			return fmt.Errorf("符號 %s 的類型無效: %s", s.ID, s.Type)
+		}
+		for k, v := range s.Pays {
+			n, err := strconv.Atoi(k)
+			if err != nil || n < 1 || n > len(c.Reels)*c.Rows {
+				return fmt.Errorf("符號 %s 的賠率鍵無效: %s", s.ID, k)
+			}
+			if v < 0 {
+				return fmt.Errorf("符號 %s 的賠率不能為負數", s.ID)
+			}
+		}
+		symbols[s.ID] = s.Type
+	}
+
+	if err := validateReels(c.Reels, c.Rows, symbols); err != nil {
+		return err
+	}
+
+	switch c.Evaluation {
+	case EvaluationPaylines:
+		if len(c.Paylines) == 0 {
+			return errors.New("paylines 模式需要至少一條賠付線")
+		}
+		for i, line := range c.Paylines {
+			if len(line) != len(c.Reels) {
+				return fmt.Errorf("第 %d 條賠付線長度與輪軸數不符", i+1)
+			}
+			for _, row := range line {
+				if row < 0 || row >= c.Rows {
+					return fmt.Errorf("第 %d 條賠付線的列索引超出範圍", i+1)
+				}
+			}
+		}
+	case EvaluationWays:
+	default:
+		return fmt.Errorf("無效的派彩計算方式: %s", c.Evaluation)
+	}
+
+	if c.BetDivisor < 0 {
+		return errors.New("bet_divisor 不能為負數")
+	}
+
+	if fs := c.FreeSpins; fs != nil {
+		if fs.TriggerSymbol == "" {
+			if scatters != 1 {
+				return errors.New("免費旋轉未指定觸發符號，且 scatter 符號不只一個")
+			}
+		} else if symbols[fs.TriggerSymbol] != SymbolScatter {
+			return fmt.Errorf("免費旋轉觸發符號必須為 scatter: %s", fs.TriggerSymbol)
+		}
+		if len(fs.Awards) == 0 {
+			return errors.New("免費旋轉未設定獎勵次數")
+		}
+		for k, v := range fs.Awards {
+			if n, err := strconv.Atoi(k); err != nil || n < 1 || n > len(c.Reels)*c.Rows {
+				return fmt.Errorf("免費旋轉獎勵鍵無效: %s", k)
+			}
+			if v <= 0 {
+				return errors.New("免費旋轉次數必須大於 0")
+			}
+		}
+		if fs.Multiplier < 0 {
+			return errors.New("免費旋轉倍數不能為負數")
+		}
+		if len(fs.Reels) > 0 {
+			if len(fs.Reels) != len(c.Reels) {
+				return errors.New("免費旋轉輪帶數量與主輪帶不符")
+			}
+			if err := validateReels(fs.Reels, c.Rows, symbols); err != nil {
+				return fmt.Errorf("免費旋轉輪帶: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// freeSpinTrigger 免費旋轉觸發符號（未指定時為唯一的 scatter；不修改設定本身）
+func (c *Config) freeSpinTrigger() string {
+	if c.FreeSpins == nil {
+		return ""
+	}
+	if c.FreeSpins.TriggerSymbol != "" {
+		return c.FreeSpins.TriggerSymbol
+	}
+	for _, s := range c.Symbols {
+		if s.Type == SymbolScatter {
+			return s.ID
+		}
+	}
+	return ""
+}
+
+// validateReels 驗證輪帶內容
+func validateReels(reels [][]string, rows int, symbols map[string]SymbolType) error {
+	for i, strip := range reels {
+		if len(strip) < rows {
+			return fmt.Errorf("第 %d 個輪軸長度小於可見列數", i+1)
+		}
+		for _, id := range strip {
+			if _, ok := symbols[id]; !ok {
+				return fmt.Errorf("第 %d 個輪軸包含未定義符號: %s", i+1, id)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package slots
+
+import (
+	"errors"
+	"strconv"
+)
+
+// RandomSource 隨機數來源（*math/rand.Rand 與 rng 套件的串流皆符合此介面）
+type RandomSource interface {
+	Intn(n int) int
+}
+
+// LineWin 單條賠付線（或單一符號的 ways）中獎明細
+type LineWin struct {
+	Line   int     `json:"line"`   // 賠付線索引（ways 模式為 -1）
+	Symbol string  `json:"symbol"` // 中獎符號
+	Count  int     `json:"count"`  // 連線數
+	Ways   int     `json:"ways"`   // 路數（paylines 模式為 1）
+	Win    float64 `json:"win"`    // 派彩金額
+}
+
+// SpinResult 單次旋轉結果
+type SpinResult struct {
+	Stops            []int      `json:"stops"`              // 各輪軸停止位置
+	Window           [][]string `json:"window"`             // 可見視窗（[輪軸][列]）
+	LineWins         []LineWin  `json:"line_wins"`          // 連線中獎
+	ScatterCount     int        `json:"scatter_count"`      // scatter 數量
+	ScatterWin       float64    `json:"scatter_win"`        // scatter 派彩
+	FreeSpinsAwarded int        `json:"free_spins_awarded"` // 觸發的免費旋轉次數
+	TotalWin         float64    `json:"total_win"`          // 總派彩
+	IsFreeSpin       bool       `json:"is_free_spin"`       // 是否為免費旋轉
+}
+
+// Machine 編譯後的老虎機（以索引取代符號代碼以加速計算）
+type Machine struct {
+	rows       int
+	reels      [][]int
+	freeReels  [][]int
+	ids        []string
+	kinds      []SymbolType
+	scatters   []int       // 所有 scatter 符號索引
+	pays       [][]float64 // [符號][連線數] → 倍數
+	paylines   [][]int
+	ways       bool
+	betDivisor float64
+	trigger    int   // 免費旋轉觸發符號（-1 表示無）
+	awards     []int // [觸發數量] → 免費旋轉次數
+	freeMult   float64
+	retrigger  bool
+}
+
+// NewMachine 依設定建立老虎機
+func NewMachine(cfg *Config) (*Machine, error) {
+	if cfg == nil {
+		return nil, errors.New("老虎機設定不能為空")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Machine{
+		rows:     cfg.Rows,
+		ways:     cfg.Evaluation == EvaluationWays,
+		paylines: cfg.Paylines,
+		trigger:  -1,
+	}
+
+	index := make(map[string]int, len(cfg.Symbols))
+	maxCount := len(cfg.Reels) * cfg.Rows
+	for i, s := range cfg.Symbols {
+		index[s.ID] = i
+		if s.Type == SymbolScatter {
+			m.scatters = append(m.scatters, i)
+		}
+		m.ids = append(m.ids, s.ID)
+		m.kinds = append(m.kinds, s.Type)
+		pays := make([]float64, maxCount+1)
+		for k, v := range s.Pays {
+			n, _ := strconv.Atoi(k)
+			pays[n] = v
+		}
+		m.pays = append(m.pays, pays)
+	}
+
+	m.reels = compileReels(cfg.Reels, index)
+	m.freeReels = m.reels
+
+	m.betDivisor = cfg.BetDivisor
+	if m.betDivisor == 0 {
+		m.betDivisor = 1
+		if !m.ways {
+			m.betDivisor = float64(len(cfg.Paylines))
+		}
+	}
+
+	if fs := cfg.FreeSpins; fs != nil {
+		m.trigger = index[cfg.freeSpinTrigger()]
+		m.awards = make([]int, maxCount+1)
+		for k, v := range fs.Awards {
+			n, _ := strconv.Atoi(k)
+			m.awards[n] = v
+		}
+		m.freeMult = fs.Multiplier
+		if m.freeMult == 0 {
+			m.freeMult = 1
+		}
+		m.retrigger = fs.Retrigger
+		if len(fs.Reels) > 0 {
+			m.freeReels = compileReels(fs.Reels, index)
+		}
+	}
+
+	return m, nil
+}
+
+// compileReels 將輪帶符號代碼轉為索引
+func compileReels(reels [][]string, index map[string]int) [][]int {
+	compiled := make([][]int, len(reels))
+	for r, strip := range reels {
+		compiled[r] = make([]int, len(strip))
+		for i, id := range strip {
+			compiled[r][i] = index[id]
+		}
+	}
+	return compiled
+}
+
+// Spin 以主輪帶進行一次旋轉
+func (m *Machine) Spin(rnd RandomSource, bet float64) *SpinResult {
+	return m.spin(rnd, bet, false)
+}
+
+// SpinFree 以免費旋轉輪帶進行一次旋轉（派彩套用免費旋轉倍數）
+func (m *Machine) SpinFree(rnd RandomSource, bet float64) *SpinResult {
+	return m.spin(rnd, bet, true)
+}
+
+func (m *Machine) spin(rnd RandomSource, bet float64, free bool) *SpinResult {
+	reels := m.reels
+	if free {
+		reels = m.freeReels
+	}
+	stops := make([]int, len(reels))
+	for r, strip := range reels {
+		stops[r] = rnd.Intn(len(strip))
+	}
+	return m.Evaluate(stops, bet, free)
+}
+
+// Evaluate 依停止位置計算派彩
+func (m *Machine) Evaluate(stops []int, bet float64, free bool) *SpinResult {
+	reels := m.reels
+	if free {
+		reels = m.freeReels
+	}
+	window := m.window(reels, stops)
+
+	var lineWins []LineWin
+	outcome := m.evaluateWindow(window, func(w LineWin) {
+		lineWins = append(lineWins, w)
+	})
+
+	mult := 1.0
+	if free {
+		mult = m.freeMult
+	}
+
+	result := &SpinResult{
+		Stops:            stops,
+		Window:           make([][]string, len(window)),
+		ScatterCount:     outcome.scatters,
+		ScatterWin:       outcome.scatterWin * bet * mult,
+		FreeSpinsAwarded: outcome.freeSpins,
+		IsFreeSpin:       free,
+	}
+	if free && !m.retrigger {
+		result.FreeSpinsAwarded = 0
+	}
+	for r, col := range window {
+		result.Window[r] = make([]string, len(col))
+		for row, sym := range col {
+			result.Window[r][row] = m.ids[sym]
+		}
+	}
+	for _, w := range lineWins {
+		w.Win *= bet * mult
+		result.LineWins = append(result.LineWins, w)
+	}
+	result.TotalWin = outcome.total() * bet * mult
+
+	return result
+}
+
+// window 取得可見視窗
+func (m *Machine) window(reels [][]int, stops []int) [][]int {
+	window := make([][]int, len(reels))
+	for r, strip := range reels {
+		window[r] = make([]int, m.rows)
+		for row := 0; row < m.rows; row++ {
+			window[r][row] = strip[(stops[r]+row)%len(strip)]
+		}
+	}
+	return window
+}
+
+// windowOutcome 視窗計算結果（以總注為 1 的倍數表示）
+type windowOutcome struct {
+	lineWin    float64
+	scatterWin float64
+	scatters   int
+	freeSpins  int
+}
+
+func (o windowOutcome) total() float64 {
+	return o.lineWin + o.scatterWin
+}
+
+// evaluateWindow 計算視窗派彩；onWin 可為 nil（RTP 計算時略過明細以節省配置）
+func (m *Machine) evaluateWindow(window [][]int, onWin func(LineWin)) windowOutcome {
+	var out windowOutcome
+
+	if m.ways {
+		out.lineWin = m.evaluateWays(window, onWin)
+	} else {
+		out.lineWin = m.evaluatePaylines(window, onWin)
+	}
+
+	// scatter 不受賠付線限制，計算整個視窗
+	for _, sym := range m.scatters {
+		n := 0
+		for _, col := range window {
+			for _, s := range col {
+				if s == sym {
+					n++
+				}
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		out.scatterWin += m.pays[sym][n]
+		if sym == m.trigger {
+			out.scatters = n
+			out.freeSpins = m.awards[n]
+		}
+	}
+
+	return out
+}
+
+// evaluatePaylines 計算固定賠付線派彩
+func (m *Machine) evaluatePaylines(window [][]int, onWin func(LineWin)) float64 {
+	total := 0.0
+	line := make([]int, len(window))
+	for li, rows := range m.paylines {
+		for r := range window {
+			line[r] = window[r][rows[r]]
+		}
+
+		// 從左至右連續的百搭符號可單獨成線
+		wildRun := 0
+		for wildRun < len(line) && m.kinds[line[wildRun]] == SymbolWild {
+			wildRun++
+		}
+		bestSym, bestCount, best := -1, 0, 0.0
+		if wildRun > 0 {
+			bestSym, bestCount, best = line[0], wildRun, m.pays[line[0]][wildRun]
+		}
+
+		// 第一個非百搭符號決定目標符號，百搭可替代
+		if wildRun < len(line) && m.kinds[line[wildRun]] == SymbolNormal {
+			target := line[wildRun]
+			count := wildRun
+			for count < len(line) && (line[count] == target || m.kinds[line[count]] == SymbolWild) {
+				count++
+			}
+			if pay := m.pays[target][count]; pay > best {
+				bestSym, bestCount, best = target, count, pay
+			}
+		}
+
+		if best > 0 {
+			win := best / m.betDivisor
+			total += win
+			if onWin != nil {
+				onWin(LineWin{Line: li, Symbol: m.ids[bestSym], Count: bestCount, Ways: 1, Win: win})
+			}
+		}
+	}
+	return total
+}
+
+// evaluateWays 計算任意路派彩（從最左輪軸起相鄰出現即成立）
+func (m *Machine) evaluateWays(window [][]int, onWin func(LineWin)) float64 {
+	total := 0.0
+	for sym, kind := range m.kinds {
+		if kind != SymbolNormal {
+			continue
+		}
+		ways, count := 1, 0
+		for _, col := range window {
+			hits := 0
+			for _, s := range col {
+				if s == sym || m.kinds[s] == SymbolWild {
+					hits++
+				}
+			}
+			if hits == 0 {
+				break
+			}
+			ways *= hits
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		if pay := m.pays[sym][count]; pay > 0 {
+			win := pay * float64(ways) / m.betDivisor
+			total += win
+			if onWin != nil {
+				onWin(LineWin{Line: -1, Symbol: m.ids[sym], Count: count, Ways: ways, Win: win})
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,359 @@
+package slots
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// RTP 計算方式
+const (
+	MethodExhaustive = "exhaustive"
+	MethodMonteCarlo = "monte_carlo"
+)
+
+// 預設計算參數
+const (
+	DefaultMaxCombinations   int64 = 5_000_000
+	DefaultSimulationSpins   int64 = 10_000_000
+	MaxInlineSimulationSpins int64 = 200_000 // API 請求內同步驗證的模擬次數上限（大量模擬請用 cmd/slotsrtp）
+	maxFreeSpinsPerSession         = 10_000  // 模擬時單次免費旋轉上限，避免無限再觸發
+)
+
+// RTPOptions RTP 計算參數
+type RTPOptions struct {
+	MaxCombinations int64 // 窮舉的組合數上限，超過則改用蒙地卡羅模擬
+	Spins           int64 // 蒙地卡羅模擬次數
+	Seed            int64 // 模擬亂數種子（0 表示固定預設值，確保結果可重現）
+	Workers         int   // 並行數（預設為 CPU 數）
+}
+
+// RTPReport RTP 計算報告
+type RTPReport struct {
+	Method              string     `json:"method"`                        // exhaustive 或 monte_carlo
+	RTP                 float64    `json:"rtp"`                           // 總回報率
+	BaseGameRTP         float64    `json:"base_game_rtp"`                 // 主遊戲回報率
+	FreeSpinRTP         float64    `json:"free_spin_rtp"`                 // 免費旋轉貢獻的回報率
+	HitFrequency        float64    `json:"hit_frequency"`                 // 主遊戲中獎頻率
+	FreeSpinTriggerRate float64    `json:"free_spin_trigger_rate"`        // 免費旋轉觸發率
+	AvgFreeSpins        float64    `json:"avg_free_spins"`                // 每次觸發平均免費旋轉次數（含再觸發）
+	Combinations        int64      `json:"combinations,omitempty"`        // 窮舉組合數
+	Spins               int64      `json:"spins,omitempty"`               // 模擬次數
+	StdError            float64    `json:"std_error,omitempty"`           // 模擬標準誤
+	ConfidenceInterval  [2]float64 `json:"confidence_interval,omitempty"` // 模擬 99.7% 信賴區間
+}
+
+// RTPDeviationError RTP 偏離目標值錯誤
+type RTPDeviationError struct {
+	Target    float64
+	Actual    float64
+	Tolerance float64
+}
+
+func (e *RTPDeviationError) Error() string {
+	return fmt.Sprintf("計算 RTP %.4f%% 與設定值 %.4f%% 差距超過容許範圍 %.4f%%",
+		e.Actual*100, e.Target*100, e.Tolerance*100)
+}
+
+// CheckRTP 檢查 RTP 是否在目標值容許範圍內
+func CheckRTP(report *RTPReport, target, tolerance float64) error {
+	if report == nil {
+		return errors.New("RTP 報告不能為空")
+	}
+	if math.Abs(report.RTP-target) > tolerance {
+		return &RTPDeviationError{Target: target, Actual: report.RTP, Tolerance: tolerance}
+	}
+	return nil
+}
+
+// CalculateRTP 計算老虎機理論回報率：組合數可負擔時窮舉，否則以蒙地卡羅模擬
+func (m *Machine) CalculateRTP(opts RTPOptions) (*RTPReport, error) {
+	if opts.MaxCombinations <= 0 {
+		opts.MaxCombinations = DefaultMaxCombinations
+	}
+	if opts.Spins <= 0 {
+		opts.Spins = DefaultSimulationSpins
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+
+	base := combinations(m.reels)
+	total := base
+	if m.trigger >= 0 && !sameReels(m.reels, m.freeReels) {
+		total += combinations(m.freeReels)
+	}
+	if base > 0 && total <= opts.MaxCombinations {
+		return m.exhaustiveRTP(opts.Workers)
+	}
+	return m.monteCarloRTP(opts)
+}
+
+// reelStats 單組輪帶的窮舉統計（以總注為 1）
+type reelStats struct {
+	combos      int64
+	totalWin    float64
+	hits        int64
+	triggers    int64
+	awardedSpin float64
+}
+
+func (s *reelStats) add(o reelStats) {
+	s.combos += o.combos
+	s.totalWin += o.totalWin
+	s.hits += o.hits
+	s.triggers += o.triggers
+	s.awardedSpin += o.awardedSpin
+}
+
+// exhaustiveRTP 窮舉所有停止位置組合
+func (m *Machine) exhaustiveRTP(workers int) (*RTPReport, error) {
+	baseStats := m.enumerate(m.reels, workers)
+	n := float64(baseStats.combos)
+
+	report := &RTPReport{
+		Method:       MethodExhaustive,
+		BaseGameRTP:  baseStats.totalWin / n,
+		HitFrequency: float64(baseStats.hits) / n,
+		Combinations: baseStats.combos,
+	}
+
+	if m.trigger >= 0 {
+		freeStats := baseStats
+		if !sameReels(m.reels, m.freeReels) {
+			freeStats = m.enumerate(m.freeReels, workers)
+			report.Combinations += freeStats.combos
+		}
+		fn := float64(freeStats.combos)
+		freeEV := freeStats.totalWin / fn * m.freeMult
+
+		// 再觸發：每次免費旋轉期望再獲得 retriggerEV 次，總期望次數為等比級數
+		retriggerEV := 0.0
+		if m.retrigger {
+			retriggerEV = freeStats.awardedSpin / fn
+		}
+		if retriggerEV >= 1 {
+			return nil, errors.New("免費旋轉再觸發期望值大於等於 1，RTP 發散")
+		}
+
+		awardedEV := baseStats.awardedSpin / n
+		report.FreeSpinTriggerRate = float64(baseStats.triggers) / n
+		if baseStats.triggers > 0 {
+			report.AvgFreeSpins = baseStats.awardedSpin / float64(baseStats.triggers) / (1 - retriggerEV)
+		}
+		report.FreeSpinRTP = awardedEV / (1 - retriggerEV) * freeEV
+	}
+
+	report.RTP = report.BaseGameRTP + report.FreeSpinRTP
+	return report, nil
+}
+
+// enumerate 並行窮舉一組輪帶，依第一個輪軸的停止位置分配工作
+func (m *Machine) enumerate(reels [][]int, workers int) reelStats {
+	first := len(reels[0])
+	if workers > first {
+		workers = first
+	}
+
+	jobs := make(chan int, first)
+	for i := 0; i < first; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	var (
+		mu    sync.Mutex
+		total reelStats
+		wg    sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var local reelStats
+			stops := make([]int, len(reels))
+			window := m.newWindow(len(reels))
+			for firstStop := range jobs {
+				for i := range stops {
+					stops[i] = 0
+				}
+				stops[0] = firstStop
+				for {
+					m.fillWindow(reels, stops, window)
+					o := m.evaluateWindow(window, nil)
+					local.combos++
+					if win := o.total(); win > 0 {
+						local.totalWin += win
+						local.hits++
+					}
+					if o.freeSpins > 0 {
+						local.triggers++
+						local.awardedSpin += float64(o.freeSpins)
+					}
+					if !advance(stops, reels) {
+						break
+					}
+				}
+			}
+			mu.Lock()
+			total.add(local)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return total
+}
+
+// advance 推進第 1 個之後的輪軸（里程表方式），全部走完時回傳 false
+func advance(stops []int, reels [][]int) bool {
+	for r := len(stops) - 1; r >= 1; r-- {
+		stops[r]++
+		if stops[r] < len(reels[r]) {
+			return true
+		}
+		stops[r] = 0
+	}
+	return false
+}
+
+// monteCarloRTP 以模擬估算 RTP（包含完整的免費旋轉流程）
+func (m *Machine) monteCarloRTP(opts RTPOptions) (*RTPReport, error) {
+	type partial struct {
+		spins, hits, triggers  int64
+		freeSpins              int64
+		base, free, sum, sumSq float64
+	}
+
+	per := opts.Spins / int64(opts.Workers)
+	if per == 0 {
+		per, opts.Workers = opts.Spins, 1
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = 20241219
+	}
+
+	results := make([]partial, opts.Workers)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed + int64(w)*7919))
+			stops := make([]int, len(m.reels))
+			window := m.newWindow(len(m.reels))
+			p := &results[w]
+			for i := int64(0); i < per; i++ {
+				o := m.randomOutcome(rnd, m.reels, stops, window)
+				spinWin := o.total()
+				p.base += spinWin
+				if spinWin > 0 {
+					p.hits++
+				}
+				if o.freeSpins > 0 {
+					p.triggers++
+					remaining := o.freeSpins
+					played := 0
+					for remaining > 0 && played < maxFreeSpinsPerSession {
+						fo := m.randomOutcome(rnd, m.freeReels, stops, window)
+						win := fo.total() * m.freeMult
+						p.free += win
+						spinWin += win
+						remaining--
+						played++
+						if m.retrigger {
+							remaining += fo.freeSpins
+						}
+					}
+					p.freeSpins += int64(played)
+				}
+				p.sum += spinWin
+				p.sumSq += spinWin * spinWin
+				p.spins++
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var t partial
+	for _, p := range results {
+		t.spins += p.spins
+		t.hits += p.hits
+		t.triggers += p.triggers
+		t.freeSpins += p.freeSpins
+		t.base += p.base
+		t.free += p.free
+		t.sum += p.sum
+		t.sumSq += p.sumSq
+	}
+
+	n := float64(t.spins)
+	mean := t.sum / n
+	variance := t.sumSq/n - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+	stdErr := math.Sqrt(variance / n)
+
+	report := &RTPReport{
+		Method:              MethodMonteCarlo,
+		RTP:                 mean,
+		BaseGameRTP:         t.base / n,
+		FreeSpinRTP:         t.free / n,
+		HitFrequency:        float64(t.hits) / n,
+		FreeSpinTriggerRate: float64(t.triggers) / n,
+		Spins:               t.spins,
+		StdError:            stdErr,
+		ConfidenceInterval:  [2]float64{mean - 3*stdErr, mean + 3*stdErr},
+	}
+	if t.triggers > 0 {
+		report.AvgFreeSpins = float64(t.freeSpins) / float64(t.triggers)
+	}
+	return report, nil
+}
+
+// randomOutcome 隨機停止並計算（重複使用緩衝區）
+func (m *Machine) randomOutcome(rnd RandomSource, reels [][]int, stops []int, window [][]int) windowOutcome {
+	for r, strip := range reels {
+		stops[r] = rnd.Intn(len(strip))
+	}
+	m.fillWindow(reels, stops, window)
+	return m.evaluateWindow(window, nil)
+}
+
+func (m *Machine) newWindow(reels int) [][]int {
+	window := make([][]int, reels)
+	for r := range window {
+		window[r] = make([]int, m.rows)
+	}
+	return window
+}
+
+func (m *Machine) fillWindow(reels [][]int, stops []int, window [][]int) {
+	for r, strip := range reels {
+		for row := 0; row < m.rows; row++ {
+			window[r][row] = strip[(stops[r]+row)%len(strip)]
+		}
+	}
+}
+
+// combinations 計算輪帶組合總數（溢位時回傳 math.MaxInt64）
+func combinations(reels [][]int) int64 {
+	total := int64(1)
+	for _, strip := range reels {
+		n := int64(len(strip))
+		if total > math.MaxInt64/n {
+			return math.MaxInt64
+		}
+		total *= n
+	}
+	return total
+}
+
+// sameReels 判斷兩組輪帶是否為同一份資料
+func sameReels(a, b [][]int) bool {
+	return len(a) > 0 && len(b) > 0 && &a[0] == &b[0]
+}
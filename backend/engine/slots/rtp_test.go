@@ -0,0 +1,125 @@
+package slots
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// tinyConfig 三個輪軸、單列、單一賠付線的機台，每個輪帶為 A 與 other 各一格：
+//   - AAA 機率 1/8，派彩 4 倍總注
+//   - 設定 free 時 other 為 scatter：SSS 機率 1/8，派彩 2 倍並獎勵 1 次免費旋轉
+func tinyConfig(free bool) *Config {
+	other := Symbol{ID: "B", Name: "B", Type: SymbolNormal}
+	if free {
+		other = Symbol{ID: "S", Name: "Scatter", Type: SymbolScatter, Pays: map[string]float64{"3": 2}}
+	}
+	strip := []string{"A", other.ID}
+	cfg := &Config{
+		Rows:       1,
+		Reels:      [][]string{strip, strip, strip},
+		Symbols:    []Symbol{{ID: "A", Name: "A", Type: SymbolNormal, Pays: map[string]float64{"3": 4}}, other},
+		Evaluation: EvaluationPaylines,
+		Paylines:   [][]int{{0, 0, 0}},
+	}
+	if free {
+		cfg.FreeSpins = &FreeSpinConfig{Awards: map[string]int{"3": 1}, Multiplier: 1}
+	}
+	return cfg
+}
+
+func tinyMachine(t *testing.T, free bool) *Machine {
+	t.Helper()
+	cfg := tinyConfig(free)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	m, err := NewMachine(cfg)
+	if err != nil {
+		t.Fatalf("NewMachine: %v", err)
+	}
+	return m
+}
+
+func TestCalculateRTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		free     bool
+		wantRTP  float64
+		wantBase float64
+		wantFree float64
+	}{
+		// 主遊戲 1/8 × 4 = 0.5
+		{"line pays only", false, 0.5, 0.5, 0},
+		// 主遊戲 1/8 × 4 + 1/8 × 2 = 0.75；每局期望免費旋轉 1/8 次，每次期望 0.75 → 0.09375
+		{"with free spins", true, 0.84375, 0.75, 0.09375},
+	}
+	for _, tt := range tests {
+		m := tinyMachine(t, tt.free)
+
+		exact, err := m.CalculateRTP(RTPOptions{Workers: 2})
+		if err != nil {
+			t.Fatalf("%s: exhaustive: %v", tt.name, err)
+		}
+		if exact.Method != MethodExhaustive || exact.Combinations != 8 {
+			t.Errorf("%s: method = %s, combinations = %d; want exhaustive, 8", tt.name, exact.Method, exact.Combinations)
+		}
+		if exact.RTP != tt.wantRTP || exact.BaseGameRTP != tt.wantBase || exact.FreeSpinRTP != tt.wantFree {
+			t.Errorf("%s: exhaustive RTP = %v (base %v, free %v); want %v (base %v, free %v)",
+				tt.name, exact.RTP, exact.BaseGameRTP, exact.FreeSpinRTP, tt.wantRTP, tt.wantBase, tt.wantFree)
+		}
+
+		// 組合數上限低於 8 時改用蒙地卡羅
+		sim, err := m.CalculateRTP(RTPOptions{MaxCombinations: 1, Spins: MaxInlineSimulationSpins, Workers: 2})
+		if err != nil {
+			t.Fatalf("%s: monte carlo: %v", tt.name, err)
+		}
+		if sim.Method != MethodMonteCarlo || sim.Spins != MaxInlineSimulationSpins {
+			t.Errorf("%s: method = %s, spins = %d; want monte_carlo, %d", tt.name, sim.Method, sim.Spins, MaxInlineSimulationSpins)
+		}
+		if sim.StdError <= 0 {
+			t.Errorf("%s: std error = %v, want > 0", tt.name, sim.StdError)
+		}
+		if diff := math.Abs(sim.RTP - tt.wantRTP); diff > 3*sim.StdError {
+			t.Errorf("%s: monte carlo RTP = %v, exact %v, diff %v exceeds 3σ (%v)", tt.name, sim.RTP, tt.wantRTP, diff, 3*sim.StdError)
+		}
+		if err := CheckRTP(sim, tt.wantRTP, 3*sim.StdError); err != nil {
+			t.Errorf("%s: CheckRTP within 3σ: %v", tt.name, err)
+		}
+	}
+}
+
+func TestCheckRTP(t *testing.T) {
+	report := &RTPReport{RTP: 0.95}
+	tests := []struct {
+		name      string
+		target    float64
+		tolerance float64
+		deviates  bool
+	}{
+		{"exact", 0.95, 0, false},
+		{"within tolerance", 0.96, 0.02, false},
+		{"above tolerance", 0.97, 0.01, true},
+		{"below tolerance", 0.93, 0.01, true},
+	}
+	for _, tt := range tests {
+		err := CheckRTP(report, tt.target, tt.tolerance)
+		if !tt.deviates {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		var dev *RTPDeviationError
+		if !errors.As(err, &dev) {
+			t.Fatalf("%s: error = %v, want *RTPDeviationError", tt.name, err)
+		}
+		if dev.Target != tt.target || dev.Actual != report.RTP || dev.Tolerance != tt.tolerance {
+			t.Errorf("%s: deviation = %+v", tt.name, dev)
+		}
+	}
+
+	if err := CheckRTP(nil, 0.95, 0.01); err == nil {
+		t.Error("nil report: expected error")
+	}
+}
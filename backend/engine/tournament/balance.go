@@ -0,0 +1,131 @@
+package tournament
+
+import "sort"
+
+// TableLoad 牌桌目前人數
+type TableLoad struct {
+	Table   int `json:"table"`
+	Players int `json:"players"`
+}
+
+// Move 自某桌移動玩家到另一桌
+type Move struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+// Plan 平衡結果
+type Plan struct {
+	Break int    `json:"break,omitempty"` // 要拆除的牌桌（0 表示不拆桌）
+	Moves []Move `json:"moves,omitempty"`
+}
+
+// Balance 計算閒置牌桌（剛結束一手、可安全移出玩家）的拆桌與平衡動作
+//
+// 剩餘人數可以用更少的牌桌容納時拆除閒置牌桌，玩家依序補到人數最少的牌桌；
+// 否則閒置牌桌比人數最少的牌桌多 2 人以上時，移出玩家直到差距不超過 1。
+// 只會從閒置牌桌移出玩家；其他牌桌的失衡會在它們結束當手時再處理。
+func Balance(tables []TableLoad, seatsPerTable, idle int) Plan {
+	loads := map[int]int{}
+	total := 0
+	found := false
+	for _, t := range tables {
+		loads[t.Table] = t.Players
+		total += t.Players
+		if t.Table == idle {
+			found = true
+		}
+	}
+	if !found || len(tables) < 2 || seatsPerTable <= 0 {
+		return Plan{}
+	}
+
+	plan := Plan{}
+	need := (total + seatsPerTable - 1) / seatsPerTable
+	if need < 1 {
+		need = 1
+	}
+	if len(tables) > need {
+		plan.Break = idle
+		moving := loads[idle]
+		delete(loads, idle)
+		moves := map[int]int{}
+		for ; moving > 0; moving-- {
+			to := smallest(loads, seatsPerTable)
+			if to == 0 {
+				break
+			}
+			loads[to]++
+			moves[to]++
+		}
+		plan.Moves = toMoves(idle, moves)
+		return plan
+	}
+
+	moves := map[int]int{}
+	for {
+		delete(loads, 0)
+		to := smallestExcept(loads, idle)
+		if to == 0 || loads[idle]-loads[to] < 2 {
+			break
+		}
+		loads[idle]--
+		loads[to]++
+		moves[to]++
+	}
+	plan.Moves = toMoves(idle, moves)
+	return plan
+}
+
+// smallest 有空位且人數最少的牌桌（同人數取桌號小者）
+func smallest(loads map[int]int, seats int) int {
+	best := 0
+	for _, table := range sortedTables(loads) {
+		if loads[table] >= seats {
+			continue
+		}
+		if best == 0 || loads[table] < loads[best] {
+			best = table
+		}
+	}
+	return best
+}
+
+func smallestExcept(loads map[int]int, except int) int {
+	best := 0
+	for _, table := range sortedTables(loads) {
+		if table == except {
+			continue
+		}
+		if best == 0 || loads[table] < loads[best] {
+			best = table
+		}
+	}
+	return best
+}
+
+func sortedTables(loads map[int]int) []int {
+	tables := make([]int, 0, len(loads))
+	for t := range loads {
+		tables = append(tables, t)
+	}
+	sort.Ints(tables)
+	return tables
+}
+
+func toMoves(from int, counts map[int]int) []Move {
+	var moves []Move
+	for _, to := range sortedTables(counts) {
+		moves = append(moves, Move{From: from, To: to, Count: counts[to]})
+	}
+	return moves
+}
+
+// TablesNeeded 容納所有玩家所需的牌桌數
+func TablesNeeded(players, seatsPerTable int) int {
+	if players <= 0 || seatsPerTable <= 0 {
+		return 0
+	}
+	return (players + seatsPerTable - 1) / seatsPerTable
+}
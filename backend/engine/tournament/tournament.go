@@ -0,0 +1,151 @@
+// Package tournament 錦標賽規則：盲注級別、獎金結構與牌桌平衡
+//
+// 本套件只做計算，不做任何 I/O；錦標賽的執行由 table 套件的 Director 負責。
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"nexus-gaming-backend/engine/poker"
+)
+
+// Level 盲注級別
+type Level struct {
+	SmallBlind float64 `json:"small_blind"`
+	BigBlind   float64 `json:"big_blind"`
+	Ante       float64 `json:"ante,omitempty"`
+	Minutes    int     `json:"minutes"` // 級別持續時間
+}
+
+// Rules 此級別的牌局規則（錦標賽不抽水；梭哈以大盲作為下注單位）
+func (l Level) Rules(variant poker.Variant) poker.Rules {
+	rules := poker.Rules{Variant: variant, BigBlind: l.BigBlind, Ante: l.Ante}
+	if variant == poker.TexasHoldem {
+		rules.SmallBlind = l.SmallBlind
+	}
+	return rules
+}
+
+// Schedule 盲注級別表（最後一個級別持續到比賽結束）
+type Schedule []Level
+
+// Validate 驗證級別表
+func (s Schedule) Validate() error {
+	if len(s) == 0 {
+		return errors.New("盲注級別表不可為空")
+	}
+	for i, l := range s {
+		if l.SmallBlind <= 0 || l.BigBlind < l.SmallBlind || l.Ante < 0 {
+			return fmt.Errorf("第 %d 級盲注設定無效", i+1)
+		}
+		if l.Minutes <= 0 {
+			return fmt.Errorf("第 %d 級持續時間必須大於 0", i+1)
+		}
+		if i > 0 && l.BigBlind < s[i-1].BigBlind {
+			return fmt.Errorf("第 %d 級大盲不可小於前一級", i+1)
+		}
+	}
+	return nil
+}
+
+// Duration 級別持續時間；最後一級持續到比賽結束，回傳 0
+func (s Schedule) Duration(level int) time.Duration {
+	if level < 0 || level >= len(s)-1 {
+		return 0
+	}
+	return time.Duration(s[level].Minutes) * time.Minute
+}
+
+// PayoutTier 依參賽人數套用的獎金比例
+type PayoutTier struct {
+	MaxEntrants int       `json:"max_entrants"` // 參賽人數上限（含）；0 表示不限
+	Percents    []float64 `json:"percents"`     // 各名次占獎金池的百分比（合計 100）
+}
+
+// PayoutStructure 獎金結構（依 MaxEntrants 由小到大比對）
+type PayoutStructure []PayoutTier
+
+// DefaultPayouts 預設獎金結構
+var DefaultPayouts = PayoutStructure{
+	{MaxEntrants: 3, Percents: []float64{100}},
+	{MaxEntrants: 6, Percents: []float64{65, 35}},
+	{MaxEntrants: 18, Percents: []float64{50, 30, 20}},
+	{MaxEntrants: 45, Percents: []float64{40, 25, 15, 12, 8}},
+	{MaxEntrants: 0, Percents: []float64{30, 20, 14, 10, 8, 6, 5, 4, 3}},
+}
+
+// Validate 驗證獎金結構
+func (p PayoutStructure) Validate() error {
+	if len(p) == 0 {
+		return errors.New("獎金結構不可為空")
+	}
+	for i, tier := range p {
+		if len(tier.Percents) == 0 {
+			return fmt.Errorf("第 %d 組獎金比例不可為空", i+1)
+		}
+		total := 0.0
+		for j, pct := range tier.Percents {
+			if pct <= 0 {
+				return fmt.Errorf("第 %d 組第 %d 名比例必須大於 0", i+1, j+1)
+			}
+			if j > 0 && pct > tier.Percents[j-1] {
+				return fmt.Errorf("第 %d 組名次比例必須遞減", i+1)
+			}
+			total += pct
+		}
+		if math.Abs(total-100) > 0.001 {
+			return fmt.Errorf("第 %d 組獎金比例合計必須為 100", i+1)
+		}
+	}
+	return nil
+}
+
+// Tier 取得參賽人數適用的獎金比例
+func (p PayoutStructure) Tier(entrants int) []float64 {
+	tiers := append(PayoutStructure{}, p...)
+	sort.SliceStable(tiers, func(i, j int) bool {
+		a, b := tiers[i].MaxEntrants, tiers[j].MaxEntrants
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+	for _, tier := range tiers {
+		if tier.MaxEntrants == 0 || entrants <= tier.MaxEntrants {
+			return tier.Percents
+		}
+	}
+	return tiers[len(tiers)-1].Percents
+}
+
+// Prizes 依獎金池與參賽人數計算各名次獎金（四捨五入至分，餘數歸第一名）
+//
+// 得獎名次不會超過參賽人數。
+func (p PayoutStructure) Prizes(pool float64, entrants int) []float64 {
+	percents := p.Tier(entrants)
+	if len(percents) > entrants {
+		percents = percents[:entrants]
+	}
+	if len(percents) == 0 || pool <= 0 {
+		return nil
+	}
+	// 名次少於結構時，依比例重新分配至 100%
+	total := 0.0
+	for _, pct := range percents {
+		total += pct
+	}
+	cents := int64(math.Round(pool * 100))
+	prizes := make([]float64, len(percents))
+	paid := int64(0)
+	for i, pct := range percents {
+		c := int64(math.Floor(float64(cents) * pct / total))
+		prizes[i] = float64(c) / 100
+		paid += c
+	}
+	prizes[0] = float64(int64(math.Round(prizes[0]*100))+cents-paid) / 100
+	return prizes
+}
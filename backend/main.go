@@ -1,20 +1,48 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"nexus-gaming-backend/config"
 	"nexus-gaming-backend/routes"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout 關閉服務時等待進行中請求的上限
+const shutdownTimeout = 15 * time.Second
+
 func main() {
+	// 收到中斷訊號時停止背景作業並關閉服務
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 初始化配置
 	config.InitConfig()
 
 	// 初始化資料庫連接
-	config.ConnectDatabase()
+	if err := config.ConnectDatabase(); err != nil {
+		log.Printf("資料庫連接失敗: %v", err)
+	}
+
+	// 檢查支付設定
+	checkPayoutProvider()
+
+	// 需要資料庫的啟動作業與背景排程（連線失敗時略過，不阻擋服務啟動）
+	jobs := &sync.WaitGroup{}
+	if config.GetDB() != nil {
+		recoverTableStacks()
+		ensureAgentHierarchy()
+		jobs = startBackgroundJobs(ctx)
+	}
 
 	// 初始化 Gin 路由器
 	r := gin.Default()
@@ -24,6 +52,21 @@ func main() {
 	routes.SetupAPIV2Routes(r)
 
 	// 啟動服務器
-	fmt.Println("Nexus Gaming Backend Server starting on :8080")
-	log.Fatal(r.Run(":8080"))
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		fmt.Println("Nexus Gaming Backend Server starting on :8080")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("正在關閉服務...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("關閉服務失敗: %v", err)
+	}
+	jobs.Wait()
 }
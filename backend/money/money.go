@@ -0,0 +1,171 @@
+// Package money 以整數「分」表示的精確金額
+//
+// 錢包、交易與總帳一律使用 Amount，避免 float64 在加減與比較時產生誤差。
+// 資料庫 DECIMAL(15,2) 欄位以字串讀寫，JSON 以十進位數字輸出，輸入可接受數字或字串。
+// 只有在與牌局引擎等以 float64 運算的模組交界處才使用 FromFloat。
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount 金額（單位：分）
+type Amount int64
+
+// Zero 零元
+const Zero Amount = 0
+
+// ErrInvalidAmount 金額格式錯誤
+var ErrInvalidAmount = errors.New("金額格式錯誤（最多兩位小數）")
+
+// FromCents 以分建立金額
+func FromCents(cents int64) Amount {
+	return Amount(cents)
+}
+
+// FromFloat 將浮點數四捨五入至分
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * 100))
+}
+
+// Parse 精確解析十進位字串（例如 "100"、"-12.5"、"0.01"），小數超過兩位視為錯誤
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" && frac == "" || len(frac) > 2 || hasDot && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidAmount
+		}
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/100-1 {
+		return 0, ErrInvalidAmount
+	}
+	cents := int64(0)
+	if frac != "" {
+		frac += strings.Repeat("0", 2-len(frac))
+		cents, _ = strconv.ParseInt(frac, 10, 64)
+	}
+	a := Amount(units*100 + cents)
+	if neg {
+		a = -a
+	}
+	return a, nil
+}
+
+// Cents 以分表示的整數
+func (a Amount) Cents() int64 {
+	return int64(a)
+}
+
+// Float64 轉為浮點數（僅供顯示或交給以浮點數運算的模組）
+func (a Amount) Float64() float64 {
+	return float64(a) / 100
+}
+
+// IsPositive 是否大於 0
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+// IsNegative 是否小於 0
+func (a Amount) IsNegative() bool {
+	return a < 0
+}
+
+// Abs 絕對值
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// String 兩位小數的十進位字串
+func (a Amount) String() string {
+	sign := ""
+	c := int64(a)
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// MarshalJSON 以十進位數字輸出（例如 123.45）
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON 接受數字或字串，不經過浮點數轉換
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Scan 讀取 DECIMAL 欄位
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = Amount(v * 100)
+		return nil
+	case float64:
+		*a = FromFloat(v)
+		return nil
+	default:
+		return fmt.Errorf("無法將 %T 轉為金額", src)
+	}
+}
+
+// scanString 解析資料庫回傳的十進位字串（SUM 等運算可能帶有多於兩位的小數零）
+func (a *Amount) scanString(s string) error {
+	if whole, frac, ok := strings.Cut(s, "."); ok && len(frac) > 2 {
+		if strings.TrimRight(frac[2:], "0") != "" {
+			return fmt.Errorf("金額 %s 超過兩位小數", s)
+		}
+		s = whole + "." + frac[:2]
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value 以十進位字串寫入資料庫
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
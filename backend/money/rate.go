@@ -0,0 +1,142 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// rateDecimals 匯率輸出與儲存的小數位數（對應 DECIMAL(20,10)）
+const rateDecimals = 10
+
+// ErrInvalidRate 匯率格式錯誤
+var ErrInvalidRate = errors.New("匯率必須為大於 0 的十進位數字")
+
+// Rate 匯率（1 單位基準幣可兌換的報價幣數量），以有理數保存避免換算誤差
+type Rate struct {
+	r *big.Rat
+}
+
+// ParseRate 精確解析十進位匯率字串（例如 "0.0312"、"32.15"）
+func ParseRate(s string) (Rate, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || r.Sign() <= 0 {
+		return Rate{}, ErrInvalidRate
+	}
+	return Rate{r: r}, nil
+}
+
+// One 1:1 匯率（相同幣別）
+func One() Rate {
+	return Rate{r: big.NewRat(1, 1)}
+}
+
+// IsZero 是否為未設定的匯率
+func (r Rate) IsZero() bool {
+	return r.r == nil || r.r.Sign() == 0
+}
+
+// Inverse 反向匯率
+func (r Rate) Inverse() Rate {
+	if r.IsZero() {
+		return Rate{}
+	}
+	return Rate{r: new(big.Rat).Inv(r.r)}
+}
+
+// Mul 串接匯率（例如 A→B 乘以 B→C 得到 A→C）
+func (r Rate) Mul(o Rate) Rate {
+	if r.IsZero() || o.IsZero() {
+		return Rate{}
+	}
+	return Rate{r: new(big.Rat).Mul(r.r, o.r)}
+}
+
+// Convert 以匯率換算金額，四捨五入至分（0.5 分遠離零進位）
+func (r Rate) Convert(a Amount) Amount {
+	if r.IsZero() {
+		return 0
+	}
+	num := new(big.Int).Mul(big.NewInt(int64(a)), r.r.Num())
+	den := r.r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Amount(q.Int64())
+}
+
+// String 十位小數的十進位字串（去除尾端的 0）
+func (r Rate) String() string {
+	if r.IsZero() {
+		return "0"
+	}
+	s := r.r.FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Round 四捨五入至儲存精度（十位小數），寫入資料庫前後的值一致
+func (r Rate) Round() Rate {
+	if r.IsZero() {
+		return r
+	}
+	v, _ := ParseRate(r.r.FloatString(rateDecimals))
+	return v
+}
+
+// MarshalJSON 以十進位數字輸出
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON 接受數字或字串
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Scan 讀取 DECIMAL 欄位
+func (r *Rate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return r.scanString(string(v))
+	case string:
+		return r.scanString(v)
+	case float64:
+		return r.scanString(fmt.Sprintf("%.10f", v))
+	default:
+		return fmt.Errorf("無法將 %T 轉為匯率", src)
+	}
+}
+
+func (r *Rate) scanString(s string) error {
+	v, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Value 以十進位字串寫入資料庫
+func (r Rate) Value() (driver.Value, error) {
+	if r.IsZero() {
+		return nil, ErrInvalidRate
+	}
+	return r.r.FloatString(rateDecimals), nil
+}
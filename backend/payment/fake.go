@@ -0,0 +1,248 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nexus-gaming-backend/money"
+)
+
+// FakeProvider 內建模擬閘道的提供商代碼
+const FakeProvider = "fake"
+
+// FakeSignatureHeader 模擬閘道回呼的簽章標頭，格式為 t=<unix 秒>,v1=<hex HMAC-SHA256("<t>.<body>")>
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// fakeTransaction 模擬閘道內部的交易狀態
+type fakeTransaction struct {
+	TransactionStatus
+	expiresAt time.Time
+}
+
+// fakeCallback 模擬閘道的回呼內容
+type fakeCallback struct {
+	EventID              string       `json:"event_id"`
+	GatewayTransactionID string       `json:"gateway_transaction_id"`
+	Reference            string       `json:"reference"`
+	Status               Status       `json:"status"`
+	Amount               money.Amount `json:"amount"`
+	Currency             string       `json:"currency"`
+	Fee                  money.Amount `json:"fee"`
+	OccurredAt           time.Time    `json:"occurred_at"`
+}
+
+// FakeGateway 不連外的模擬閘道：交易保存在記憶體，由 Complete 模擬玩家付款結果並產生已簽章的回呼
+type FakeGateway struct {
+	Secret     []byte
+	Tolerance  time.Duration // 回呼時間戳的容許誤差
+	PaymentURL string        // 付款頁網址前綴
+	Now        func() time.Time
+
+	mu           sync.Mutex
+	transactions map[string]*fakeTransaction
+	payouts      map[string]*TransactionStatus // 出款（以 Reference 去重）
+}
+
+// NewFakeGateway 建立模擬閘道
+func NewFakeGateway(secret string, tolerance time.Duration) *FakeGateway {
+	return &FakeGateway{
+		Secret:       []byte(secret),
+		Tolerance:    tolerance,
+		PaymentURL:   "/fake-pay/",
+		Now:          time.Now,
+		transactions: map[string]*fakeTransaction{},
+		payouts:      map[string]*TransactionStatus{},
+	}
+}
+
+// Name 提供商代碼
+func (g *FakeGateway) Name() string {
+	return FakeProvider
+}
+
+// CreateIntent 建立付款意圖
+func (g *FakeGateway) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("模擬閘道: 金額必須大於 0")
+	}
+	id := "FAKE" + randomHex(8)
+	now := g.Now()
+	g.mu.Lock()
+	g.transactions[id] = &fakeTransaction{
+		TransactionStatus: TransactionStatus{
+			GatewayTransactionID: id,
+			Reference:            req.Reference,
+			Status:               StatusPending,
+			Amount:               req.Amount,
+			Currency:             req.Currency,
+		},
+		expiresAt: req.ExpiresAt,
+	}
+	g.mu.Unlock()
+	return &Intent{
+		GatewayTransactionID: id,
+		Status:               StatusPending,
+		PaymentURL:           g.PaymentURL + id,
+		Raw:                  map[string]interface{}{"provider": FakeProvider, "id": id, "method": req.Method},
+		CreatedAt:            now,
+		ExpiresAt:            req.ExpiresAt,
+	}, nil
+}
+
+// QueryStatus 查詢交易狀態（逾期未付款視為 expired）
+func (g *FakeGateway) QueryStatus(ctx context.Context, gatewayTransactionID string) (*TransactionStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t, ok := g.transactions[gatewayTransactionID]
+	if !ok {
+		return nil, ErrTransactionUnknown
+	}
+	if t.Status == StatusPending && !t.expiresAt.IsZero() && g.Now().After(t.expiresAt) {
+		t.Status = StatusExpired
+	}
+	status := t.TransactionStatus
+	return &status, nil
+}
+
+// Complete 模擬玩家付款結果，回傳應送往回呼端點的標頭與內容
+func (g *FakeGateway) Complete(gatewayTransactionID string, status Status) (http.Header, []byte, error) {
+	g.mu.Lock()
+	t, ok := g.transactions[gatewayTransactionID]
+	if ok {
+		t.Status = status
+	}
+	g.mu.Unlock()
+	if !ok {
+		return nil, nil, ErrTransactionUnknown
+	}
+	now := g.Now()
+	body, err := json.Marshal(fakeCallback{
+		EventID:              "EVT" + randomHex(8),
+		GatewayTransactionID: t.GatewayTransactionID,
+		Reference:            t.Reference,
+		Status:               status,
+		Amount:               t.Amount,
+		Currency:             t.Currency,
+		Fee:                  t.Fee,
+		OccurredAt:           now,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, g.Sign(body, now))
+	return header, body, nil
+}
+
+// CreatePayout 建立出款；模擬閘道的出款立即成功，同一 Reference 回傳既有出款
+func (g *FakeGateway) CreatePayout(ctx context.Context, req PayoutRequest) (*TransactionStatus, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("模擬閘道: 金額必須大於 0")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range g.payouts {
+		if p.Reference == req.Reference {
+			status := *p
+			return &status, nil
+		}
+	}
+	p := &TransactionStatus{
+		GatewayTransactionID: "FAKEPO" + randomHex(8),
+		Reference:            req.Reference,
+		Status:               StatusSucceeded,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+	}
+	g.payouts[p.GatewayTransactionID] = p
+	status := *p
+	return &status, nil
+}
+
+// QueryPayout 查詢出款狀態
+func (g *FakeGateway) QueryPayout(ctx context.Context, gatewayTransactionID string) (*TransactionStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p, ok := g.payouts[gatewayTransactionID]
+	if !ok {
+		return nil, ErrTransactionUnknown
+	}
+	status := *p
+	return &status, nil
+}
+
+// Sign 產生回呼簽章標頭值
+func (g *FakeGateway) Sign(body []byte, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + g.mac(ts, body)
+}
+
+// VerifyCallback 驗證簽章與時間戳後解析回呼
+func (g *FakeGateway) VerifyCallback(header http.Header, body []byte) (*CallbackEvent, error) {
+	var ts, sig string
+	for _, part := range strings.Split(header.Get(FakeSignatureHeader), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	if ts == "" || sig == "" {
+		return nil, ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(g.mac(ts, body))) {
+		return nil, ErrInvalidSignature
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if d := g.Now().Sub(time.Unix(sec, 0)); d > g.Tolerance || d < -g.Tolerance {
+		return nil, ErrStaleCallback
+	}
+
+	var cb fakeCallback
+	if err := json.Unmarshal(body, &cb); err != nil || cb.EventID == "" || cb.GatewayTransactionID == "" {
+		return nil, ErrMalformedCallback
+	}
+	return &CallbackEvent{
+		EventID:    cb.EventID,
+		OccurredAt: cb.OccurredAt,
+		TransactionStatus: TransactionStatus{
+			GatewayTransactionID: cb.GatewayTransactionID,
+			Reference:            cb.Reference,
+			Status:               cb.Status,
+			Amount:               cb.Amount,
+			Currency:             cb.Currency,
+			Fee:                  cb.Fee,
+		},
+		Payload: body,
+	}, nil
+}
+
+func (g *FakeGateway) mac(ts string, body []byte) string {
+	m := hmac.New(sha256.New, g.Secret)
+	m.Write([]byte(ts))
+	m.Write([]byte("."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return strings.ToUpper(hex.EncodeToString(b))
+}
@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var fakeTestNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func newTestFakeGateway() *FakeGateway {
+	g := NewFakeGateway("test-secret", 5*time.Minute)
+	g.Now = func() time.Time { return fakeTestNow }
+	return g
+}
+
+func signedHeader(g *FakeGateway, body []byte, at time.Time) http.Header {
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, g.Sign(body, at))
+	return header
+}
+
+const validCallbackBody = `{"event_id":"EVT1","gateway_transaction_id":"FAKE1","reference":"DEP1","status":"succeeded","amount":"100.00","currency":"TWD"}`
+
+func TestFakeVerifyCallbackAcceptsSignedBody(t *testing.T) {
+	g := newTestFakeGateway()
+	body := []byte(validCallbackBody)
+
+	ev, err := g.VerifyCallback(signedHeader(g, body, fakeTestNow), body)
+	if err != nil {
+		t.Fatalf("VerifyCallback: %v", err)
+	}
+	if ev.EventID != "EVT1" || ev.GatewayTransactionID != "FAKE1" || ev.Status != StatusSucceeded {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestFakeVerifyCallbackRejectsBadSignature(t *testing.T) {
+	g := newTestFakeGateway()
+	body := []byte(validCallbackBody)
+	other := NewFakeGateway("other-secret", 5*time.Minute)
+
+	cases := map[string]http.Header{
+		"missing header": {},
+		"missing v1":     {FakeSignatureHeader: []string{"t=1792411200"}},
+		"wrong secret":   signedHeader(other, body, fakeTestNow),
+		"tampered body":  signedHeader(g, []byte(validCallbackBody+" "), fakeTestNow),
+		"non-numeric ts": {FakeSignatureHeader: []string{"t=abc,v1=" + g.mac("abc", body)}},
+	}
+	for name, header := range cases {
+		if _, err := g.VerifyCallback(header, body); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
+
+func TestFakeVerifyCallbackRejectsStaleTimestamp(t *testing.T) {
+	g := newTestFakeGateway()
+	body := []byte(validCallbackBody)
+
+	for _, at := range []time.Time{fakeTestNow.Add(-6 * time.Minute), fakeTestNow.Add(6 * time.Minute)} {
+		if _, err := g.VerifyCallback(signedHeader(g, body, at), body); !errors.Is(err, ErrStaleCallback) {
+			t.Errorf("signed at %s: got %v, want ErrStaleCallback", at, err)
+		}
+	}
+}
+
+func TestFakeVerifyCallbackRejectsMalformedBody(t *testing.T) {
+	g := newTestFakeGateway()
+
+	cases := map[string]string{
+		"invalid json":           `{"event_id":`,
+		"missing event_id":       `{"gateway_transaction_id":"FAKE1","status":"succeeded"}`,
+		"missing transaction id": `{"event_id":"EVT1","status":"succeeded"}`,
+	}
+	for name, raw := range cases {
+		body := []byte(raw)
+		if _, err := g.VerifyCallback(signedHeader(g, body, fakeTestNow), body); !errors.Is(err, ErrMalformedCallback) {
+			t.Errorf("%s: got %v, want ErrMalformedCallback", name, err)
+		}
+	}
+}
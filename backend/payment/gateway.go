@@ -0,0 +1,128 @@
+// Package payment 支付閘道轉接介面
+//
+// 每個支付提供商實作 PaymentGateway：建立付款意圖、查詢交易狀態、驗證回呼簽章。
+// 支援出款的閘道另外實作 PayoutGateway。
+// 回呼只在簽章與時間戳都通過驗證後才轉為 CallbackEvent；事件去重（防止重送）由呼叫端以事件ID處理。
+// 內建的 FakeGateway 不連外，可在本機與測試環境跑完整的儲值流程。
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"nexus-gaming-backend/money"
+)
+
+// 支付閘道相關錯誤
+var (
+	ErrUnknownProvider    = errors.New("未知的支付提供商")
+	ErrInvalidSignature   = errors.New("回呼簽章驗證失敗")
+	ErrStaleCallback      = errors.New("回呼時間戳超出容許範圍")
+	ErrMalformedCallback  = errors.New("回呼內容格式錯誤")
+	ErrTransactionUnknown = errors.New("閘道查無此交易")
+)
+
+// Status 閘道交易狀態
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 等待付款
+	StatusSucceeded Status = "succeeded" // 已付款
+	StatusFailed    Status = "failed"    // 付款失敗或取消
+	StatusExpired   Status = "expired"   // 逾時未付款
+)
+
+// IntentRequest 建立付款意圖請求
+type IntentRequest struct {
+	Reference string       // 本系統訂單號（deposit_id），回呼時原樣帶回
+	PlayerID  int64        // 付款玩家
+	Amount    money.Amount // 付款金額
+	Currency  string       // 幣別
+	Method    string       // 付款方式（deposits.payment_method）
+	ExpiresAt time.Time    // 付款期限
+}
+
+// Intent 付款意圖
+type Intent struct {
+	GatewayTransactionID string                 `json:"gateway_transaction_id"`
+	Status               Status                 `json:"status"`
+	PaymentURL           string                 `json:"payment_url,omitempty"` // 導向玩家付款的網址
+	Raw                  map[string]interface{} `json:"raw,omitempty"`         // 閘道原始回應（寫入 gateway_response）
+	CreatedAt            time.Time              `json:"created_at"`
+	ExpiresAt            time.Time              `json:"expires_at"`
+}
+
+// TransactionStatus 閘道交易狀態查詢結果
+type TransactionStatus struct {
+	GatewayTransactionID string       `json:"gateway_transaction_id"`
+	Reference            string       `json:"reference"`
+	Status               Status       `json:"status"`
+	Amount               money.Amount `json:"amount"`
+	Currency             string       `json:"currency"`
+	Fee                  money.Amount `json:"fee"`
+}
+
+// CallbackEvent 已驗證的回呼事件
+type CallbackEvent struct {
+	EventID    string    `json:"event_id"` // 閘道事件ID，同一事件重送時相同
+	OccurredAt time.Time `json:"occurred_at"`
+	TransactionStatus
+	Payload []byte `json:"-"` // 原始回呼內容
+}
+
+// PaymentGateway 支付閘道轉接介面
+type PaymentGateway interface {
+	// Name 提供商代碼（對應 deposits.payment_provider 與回呼路徑）
+	Name() string
+	// CreateIntent 建立付款意圖
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// QueryStatus 主動查詢交易狀態（回呼遺失或逾期檢查時使用）
+	QueryStatus(ctx context.Context, gatewayTransactionID string) (*TransactionStatus, error)
+	// VerifyCallback 驗證回呼簽章與時間戳，通過後解析為事件
+	VerifyCallback(header http.Header, body []byte) (*CallbackEvent, error)
+}
+
+// Registry 支付閘道註冊表
+type Registry struct {
+	mu       sync.RWMutex
+	gateways map[string]PaymentGateway
+}
+
+// NewRegistry 建立空的註冊表
+func NewRegistry() *Registry {
+	return &Registry{gateways: map[string]PaymentGateway{}}
+}
+
+// Register 註冊閘道（同名時取代）
+func (r *Registry) Register(g PaymentGateway) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gateways[g.Name()] = g
+}
+
+// Get 依提供商代碼取得閘道
+func (r *Registry) Get(name string) (PaymentGateway, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.gateways[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return g, nil
+}
+
+// Names 已註冊的提供商代碼
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.gateways))
+	for name := range r.gateways {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"nexus-gaming-backend/money"
+)
+
+// ErrPayoutUnsupported 閘道不支援出款
+var ErrPayoutUnsupported = errors.New("此支付提供商不支援出款")
+
+// PayoutRequest 出款請求
+type PayoutRequest struct {
+	Reference   string          // 本系統訂單號（withdrawal_id），閘道以此去重，重送不會重複出款
+	PlayerID    int64           // 收款玩家
+	Amount      money.Amount    // 出款金額
+	Currency    string          // 幣別
+	Method      string          // 出款方式（withdrawals.withdrawal_method）
+	AccountInfo json.RawMessage // 收款帳戶資訊
+}
+
+// PayoutGateway 出款轉接介面（支付閘道可選擇實作）
+type PayoutGateway interface {
+	// Name 提供商代碼
+	Name() string
+	// CreatePayout 建立出款；同一 Reference 重送時回傳既有出款
+	CreatePayout(ctx context.Context, req PayoutRequest) (*TransactionStatus, error)
+	// QueryPayout 查詢出款狀態
+	QueryPayout(ctx context.Context, gatewayTransactionID string) (*TransactionStatus, error)
+}
+
+// Payout 依提供商代碼取得出款閘道
+func (r *Registry) Payout(name string) (PayoutGateway, error) {
+	g, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := g.(PayoutGateway)
+	if !ok {
+		return nil, ErrPayoutUnsupported
+	}
+	return p, nil
+}
@@ -0,0 +1,255 @@
+package rng
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Algorithm 目前使用的可驗證公平演算法識別
+const Algorithm = "hmac-sha256/fisher-yates/v1"
+
+// CommitmentStatus 承諾狀態
+type CommitmentStatus string
+
+const (
+	CommitmentCommitted CommitmentStatus = "committed" // 已公布雜湊，種子保密中
+	CommitmentRevealed  CommitmentStatus = "revealed"  // 回合結束，種子已揭露
+)
+
+// 結果類型
+const (
+	OutcomeDeck  = "deck"  // 單副 52 張牌（德州撲克、梭哈）
+	OutcomeShoe  = "shoe"  // 多副牌靴（百家樂）
+	OutcomeWheel = "wheel" // 轉輪（輪盤）
+	OutcomeReels = "reels" // 老虎機輪軸停止位置
+)
+
+// 客戶端種子限制（加入承諾與公開驗證端點皆適用）
+const (
+	maxClientSeeds      = 16 // 單回合可加入的客戶端種子上限
+	maxClientSeedLength = 64 // 單一客戶端種子長度上限（位元組）
+)
+
+// 客戶端種子相關錯誤
+var (
+	ErrClientSeedLength = fmt.Errorf("客戶端種子長度必須介於 1 到 %d 字元", maxClientSeedLength)
+	ErrTooManySeeds     = fmt.Errorf("客戶端種子數量不能超過 %d 個", maxClientSeeds)
+)
+
+// 結果參數上限（驗證端點公開，參數來自客戶端，須在取樣前限制）
+const (
+	maxWheelPockets = 1000    // 轉輪格數上限
+	maxReels        = 20      // 老虎機輪軸數上限
+	maxReelLength   = 100_000 // 單一輪帶長度上限
+)
+
+// Commitment 單回合的承諾—揭露紀錄（存於 game_sessions.game_data.fairness）
+type Commitment struct {
+	Algorithm      string           `json:"algorithm"`
+	Status         CommitmentStatus `json:"status"`
+	ServerSeedHash string           `json:"server_seed_hash"`      // 回合開始前公布
+	ServerSeed     string           `json:"server_seed,omitempty"` // 回合結束後揭露
+	ClientSeeds    []string         `json:"client_seeds"`          // 依加入順序
+	Nonce          int64            `json:"nonce"`
+	Spec           OutcomeSpec      `json:"spec"` // 結果類型與參數
+	CommittedAt    time.Time        `json:"committed_at"`
+	RevealedAt     *time.Time       `json:"revealed_at,omitempty"`
+	Result         *Outcome         `json:"result,omitempty"` // 揭露時記錄的回合結果
+}
+
+// NewCommitment 產生新的伺服器種子並建立承諾
+func NewCommitment(nonce int64, spec OutcomeSpec) (*Commitment, error) {
+	seed, err := NewServerSeed()
+	if err != nil {
+		return nil, err
+	}
+	return &Commitment{
+		Algorithm:      Algorithm,
+		Status:         CommitmentCommitted,
+		ServerSeedHash: HashSeed(seed),
+		ServerSeed:     seed,
+		ClientSeeds:    []string{},
+		Nonce:          nonce,
+		Spec:           spec,
+		CommittedAt:    time.Now(),
+	}, nil
+}
+
+// AddClientSeed 加入客戶端種子（僅限揭露前）
+func (c *Commitment) AddClientSeed(seed string) error {
+	if c.Status != CommitmentCommitted {
+		return errors.New("回合已揭露，無法再加入客戶端種子")
+	}
+	if seed == "" || len(seed) > maxClientSeedLength {
+		return ErrClientSeedLength
+	}
+	if len(c.ClientSeeds) >= maxClientSeeds {
+		return ErrTooManySeeds
+	}
+	c.ClientSeeds = append(c.ClientSeeds, seed)
+	return nil
+}
+
+// checkClientSeeds 檢查客戶端種子的數量與長度
+func checkClientSeeds(seeds []string) error {
+	if len(seeds) > maxClientSeeds {
+		return ErrTooManySeeds
+	}
+	for _, s := range seeds {
+		if s == "" || len(s) > maxClientSeedLength {
+			return ErrClientSeedLength
+		}
+	}
+	return nil
+}
+
+// Reveal 揭露伺服器種子並記錄本回合結果
+func (c *Commitment) Reveal() error {
+	if c.Status == CommitmentRevealed {
+		return nil
+	}
+	result, err := ComputeOutcome(c.Stream(), c.Spec)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	c.Status = CommitmentRevealed
+	c.RevealedAt = &now
+	c.Result = result
+	return nil
+}
+
+// Public 回傳可公開的副本（揭露前隱藏伺服器種子）
+func (c *Commitment) Public() Commitment {
+	pub := *c
+	pub.ClientSeeds = append([]string{}, c.ClientSeeds...)
+	if pub.Status != CommitmentRevealed {
+		pub.ServerSeed = ""
+		pub.Result = nil
+	}
+	return pub
+}
+
+// Stream 取得本回合的確定性隨機串流
+func (c *Commitment) Stream() *Stream {
+	return NewStream(c.ServerSeed, CombineClientSeeds(c.ClientSeeds), c.Nonce)
+}
+
+// CombineClientSeeds 合併多個客戶端種子（長度前綴後取 SHA-256，避免串接歧義）
+func CombineClientSeeds(seeds []string) string {
+	h := sha256.New()
+	var length [4]byte
+	for _, s := range seeds {
+		binary.BigEndian.PutUint32(length[:], uint32(len(s)))
+		h.Write(length[:])
+		h.Write([]byte(s))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// OutcomeSpec 結果重算參數
+type OutcomeSpec struct {
+	Type        string `json:"type" binding:"required,oneof=deck shoe wheel reels"`
+	Decks       int    `json:"decks,omitempty"`        // shoe：牌副數（預設 8）
+	Pockets     int    `json:"pockets,omitempty"`      // wheel：格數（預設 37，歐式輪盤）
+	Spins       int    `json:"spins,omitempty"`        // wheel：連續轉動次數（預設 1）
+	ReelLengths []int  `json:"reel_lengths,omitempty"` // reels：各輪帶長度
+}
+
+// Outcome 重算的回合結果
+type Outcome struct {
+	Type   string   `json:"type"`
+	Values []int    `json:"values"`           // 牌張編碼、轉輪格位或輪軸停止位置
+	Labels []string `json:"labels,omitempty"` // 牌張文字（牌類結果）
+}
+
+// ComputeOutcome 依串流與參數產生結果（發牌／轉輪時與驗證時使用同一函式）
+func ComputeOutcome(src Source, spec OutcomeSpec) (*Outcome, error) {
+	out := &Outcome{Type: spec.Type}
+	switch spec.Type {
+	case OutcomeDeck:
+		out.Values = ShuffleDeck(src)
+	case OutcomeShoe:
+		decks := spec.Decks
+		if decks == 0 {
+			decks = 8
+		}
+		if decks < 1 || decks > 16 {
+			return nil, errors.New("牌副數必須介於 1 到 16")
+		}
+		out.Values = ShuffleShoe(decks, src)
+	case OutcomeWheel:
+		pockets, spins := spec.Pockets, spec.Spins
+		if pockets == 0 {
+			pockets = 37
+		}
+		if spins == 0 {
+			spins = 1
+		}
+		if pockets < 2 || pockets > maxWheelPockets || spins < 1 || spins > 1000 {
+			return nil, fmt.Errorf("轉輪參數無效（格數 2 到 %d、轉動次數 1 到 1000）", maxWheelPockets)
+		}
+		for i := 0; i < spins; i++ {
+			out.Values = append(out.Values, src.Intn(pockets))
+		}
+		return out, nil
+	case OutcomeReels:
+		if len(spec.ReelLengths) == 0 {
+			return nil, errors.New("reels 結果需要提供輪帶長度")
+		}
+		if len(spec.ReelLengths) > maxReels {
+			return nil, fmt.Errorf("輪軸數不能超過 %d", maxReels)
+		}
+		for _, n := range spec.ReelLengths {
+			if n <= 0 || n > maxReelLength {
+				return nil, fmt.Errorf("輪帶長度必須介於 1 到 %d", maxReelLength)
+			}
+		}
+		for _, n := range spec.ReelLengths {
+			out.Values = append(out.Values, src.Intn(n))
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("不支援的結果類型: %s", spec.Type)
+	}
+
+	out.Labels = make([]string, len(out.Values))
+	for i, v := range out.Values {
+		out.Labels[i] = CardLabel(v)
+	}
+	return out, nil
+}
+
+// VerifyResult 驗證結果
+type VerifyResult struct {
+	HashMatches    bool     `json:"hash_matches"`     // 揭露的種子雜湊是否與事前公布值相符
+	ServerSeedHash string   `json:"server_seed_hash"` // 重算的雜湊
+	CombinedClient string   `json:"combined_client_seed"`
+	Outcome        *Outcome `json:"outcome,omitempty"`
+}
+
+// Verify 以揭露的種子重算回合結果（客戶端種子的數量與長度限制與 AddClientSeed 相同）
+func Verify(serverSeed, committedHash string, clientSeeds []string, nonce int64, spec OutcomeSpec) (*VerifyResult, error) {
+	if serverSeed == "" {
+		return nil, errors.New("伺服器種子尚未揭露")
+	}
+	if err := checkClientSeeds(clientSeeds); err != nil {
+		return nil, err
+	}
+	result := &VerifyResult{
+		ServerSeedHash: HashSeed(serverSeed),
+		CombinedClient: CombineClientSeeds(clientSeeds),
+	}
+	result.HashMatches = result.ServerSeedHash == committedHash
+
+	outcome, err := ComputeOutcome(NewStream(serverSeed, result.CombinedClient, nonce), spec)
+	if err != nil {
+		return nil, err
+	}
+	result.Outcome = outcome
+	return result, nil
+}
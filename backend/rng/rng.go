@@ -0,0 +1,162 @@
+// Package rng 提供牌局與轉輪結果使用的隨機數來源
+//
+// 兩種來源：
+//   - SecureSource：直接使用作業系統 CSPRNG（crypto/rand），不可重現
+//   - Stream：以伺服器種子、客戶端種子與 nonce 透過 HMAC-SHA256 衍生的確定性串流，
+//     伺服器種子本身由 CSPRNG 產生，揭露後任何人都能重算結果（可驗證公平）
+package rng
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Source 隨機整數來源（與 *math/rand.Rand 相容）
+type Source interface {
+	Intn(n int) int
+}
+
+// SecureSource 以 crypto/rand 為基礎的隨機來源
+type SecureSource struct{}
+
+// Intn 回傳 [0, n) 的均勻隨機整數
+func (SecureSource) Intn(n int) int {
+	if n <= 0 {
+		panic("rng: Intn 參數必須大於 0")
+	}
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		// 系統亂數來源失效時無法保證公平性，直接中止
+		panic(fmt.Sprintf("rng: 讀取系統亂數失敗: %v", err))
+	}
+	return int(v.Int64())
+}
+
+// Stream 以 HMAC-SHA256 衍生的確定性隨機串流
+//
+// 第 k 個區塊 = HMAC-SHA256(key=伺服器種子, msg="客戶端種子:nonce:k")，
+// 依序取出 32 位元整數，Intn 使用拒絕取樣避免模數偏差。
+type Stream struct {
+	key        []byte
+	clientSeed string
+	nonce      int64
+	counter    uint64
+	block      []byte
+	pos        int
+}
+
+// NewStream 建立確定性串流
+func NewStream(serverSeed, clientSeed string, nonce int64) *Stream {
+	return &Stream{
+		key:        []byte(serverSeed),
+		clientSeed: clientSeed,
+		nonce:      nonce,
+	}
+}
+
+// Uint32 取出下一個 32 位元整數
+func (s *Stream) Uint32() uint32 {
+	if s.block == nil || s.pos+4 > len(s.block) {
+		mac := hmac.New(sha256.New, s.key)
+		mac.Write([]byte(s.clientSeed + ":" + strconv.FormatInt(s.nonce, 10) + ":" + strconv.FormatUint(s.counter, 10)))
+		s.block = mac.Sum(nil)
+		s.pos = 0
+		s.counter++
+	}
+	v := binary.BigEndian.Uint32(s.block[s.pos:])
+	s.pos += 4
+	return v
+}
+
+// MaxStreamIntn Stream.Intn 可接受的最大 n（每次取樣 32 位元，超過時拒絕取樣的上限為 0 而永不結束）
+const MaxStreamIntn = 1 << 32
+
+// ErrIntnRange Intn 參數超出範圍
+var ErrIntnRange = errors.New("rng: Intn 參數必須介於 1 到 2^32")
+
+// TryIntn 回傳 [0, n) 的均勻整數；n 超出 [1, MaxStreamIntn] 時回傳 ErrIntnRange
+func (s *Stream) TryIntn(n int) (int, error) {
+	if n <= 0 || uint64(n) > MaxStreamIntn {
+		return 0, ErrIntnRange
+	}
+	bound := uint64(n)
+	limit := (uint64(1) << 32) - (uint64(1)<<32)%bound
+	for {
+		v := uint64(s.Uint32())
+		if v < limit {
+			return int(v % bound), nil
+		}
+	}
+}
+
+// Intn 回傳 [0, n) 的均勻整數（實作 Source；參數超出範圍時與 math/rand 相同以 panic 中止，
+// 需要錯誤回傳時請使用 TryIntn）
+func (s *Stream) Intn(n int) int {
+	v, err := s.TryIntn(n)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Shuffle 以 Fisher-Yates 產生 0..n-1 的隨機排列
+func Shuffle(n int, src Source) []int {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j := src.Intn(i + 1)
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm
+}
+
+// 牌張編碼：index = suit*13 + rank，rank 0..12 對應 2..A，suit 0..3 對應 c d h s
+const (
+	DeckSize  = 52
+	rankChars = "23456789TJQKA"
+	suitChars = "cdhs"
+)
+
+// ShuffleDeck 洗一副 52 張牌，回傳牌張編碼
+func ShuffleDeck(src Source) []int {
+	return Shuffle(DeckSize, src)
+}
+
+// ShuffleShoe 洗多副牌組成的牌靴（如百家樂 8 副），回傳牌張編碼（0..51，可重複）
+func ShuffleShoe(decks int, src Source) []int {
+	perm := Shuffle(decks*DeckSize, src)
+	for i, v := range perm {
+		perm[i] = v % DeckSize
+	}
+	return perm
+}
+
+// CardLabel 牌張編碼轉為文字（如 "As"、"Td"）
+func CardLabel(index int) string {
+	index %= DeckSize
+	return string(rankChars[index%13]) + string(suitChars[index/13])
+}
+
+// NewServerSeed 以 CSPRNG 產生 32 位元組伺服器種子（十六進位字串）
+func NewServerSeed() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("產生伺服器種子失敗: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashSeed 計算種子的 SHA-256 雜湊（對外公布的承諾值）
+func HashSeed(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,270 @@
+package rng
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamDeterministic(t *testing.T) {
+	// 第一個區塊 = HMAC-SHA256(key=server, msg="client:7:0")
+	mac := hmac.New(sha256.New, []byte("server"))
+	mac.Write([]byte("client:7:0"))
+	block := mac.Sum(nil)
+
+	s := NewStream("server", "client", 7)
+	for i := 0; i < 8; i++ {
+		want := binary.BigEndian.Uint32(block[i*4:])
+		if got := s.Uint32(); got != want {
+			t.Fatalf("Uint32 #%d = %d, want %d", i, got, want)
+		}
+	}
+
+	tests := []struct {
+		name               string
+		server, client     string
+		nonce              int64
+		wantSameAsBaseline bool
+	}{
+		{"same inputs", "server", "client", 7, true},
+		{"different server seed", "server2", "client", 7, false},
+		{"different client seed", "server", "client2", 7, false},
+		{"different nonce", "server", "client", 8, false},
+	}
+	baseline := drawUint32(NewStream("server", "client", 7), 20)
+	for _, tt := range tests {
+		got := drawUint32(NewStream(tt.server, tt.client, tt.nonce), 20)
+		if same := reflect.DeepEqual(got, baseline); same != tt.wantSameAsBaseline {
+			t.Errorf("%s: same as baseline = %v, want %v", tt.name, same, tt.wantSameAsBaseline)
+		}
+	}
+}
+
+func drawUint32(s *Stream, n int) []uint32 {
+	out := make([]uint32, n)
+	for i := range out {
+		out[i] = s.Uint32()
+	}
+	return out
+}
+
+func TestStreamTryIntnBounds(t *testing.T) {
+	tests := []struct {
+		n       int
+		wantErr bool
+	}{
+		{-1, true},
+		{0, true},
+		{1, false},
+		{2, false},
+		{37, false},
+		{52, false},
+		{1<<31 + 1, false},
+		{MaxStreamIntn, false},
+		{MaxStreamIntn + 1, true},
+	}
+	for _, tt := range tests {
+		s := NewStream("seed", "client", 1)
+		for i := 0; i < 200; i++ {
+			v, err := s.TryIntn(tt.n)
+			if tt.wantErr {
+				if !errors.Is(err, ErrIntnRange) {
+					t.Fatalf("TryIntn(%d) error = %v, want ErrIntnRange", tt.n, err)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("TryIntn(%d): %v", tt.n, err)
+			}
+			if v < 0 || v >= tt.n {
+				t.Fatalf("TryIntn(%d) = %d, out of range", tt.n, v)
+			}
+		}
+	}
+}
+
+func TestStreamTryIntnEdgeValues(t *testing.T) {
+	// n = 1 只能回傳 0，仍會消耗串流
+	s := NewStream("seed", "client", 1)
+	for i := 0; i < 10; i++ {
+		if v, err := s.TryIntn(1); err != nil || v != 0 {
+			t.Fatalf("TryIntn(1) = %d, %v; want 0, nil", v, err)
+		}
+	}
+
+	// n = 2^32 時拒絕取樣上限為 2^32，不會拒絕任何值，結果等於原始 32 位元整數
+	a, b := NewStream("seed", "client", 2), NewStream("seed", "client", 2)
+	for i := 0; i < 10; i++ {
+		v, err := a.TryIntn(MaxStreamIntn)
+		if err != nil {
+			t.Fatalf("TryIntn(2^32): %v", err)
+		}
+		if want := int(b.Uint32()); v != want {
+			t.Fatalf("TryIntn(2^32) = %d, want %d", v, want)
+		}
+	}
+}
+
+func TestStreamIntnPanicsOutOfRange(t *testing.T) {
+	defer func() {
+		if r := recover(); r != ErrIntnRange {
+			t.Fatalf("recover() = %v, want ErrIntnRange", r)
+		}
+	}()
+	NewStream("seed", "client", 1).Intn(MaxStreamIntn + 1)
+}
+
+func TestShuffleReproducible(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		build func(Source) []int
+	}{
+		{"shuffle 10", 10, func(src Source) []int { return Shuffle(10, src) }},
+		{"deck", DeckSize, ShuffleDeck},
+		{"shoe", 8 * DeckSize, func(src Source) []int { return ShuffleShoe(8, src) }},
+	}
+	for _, tt := range tests {
+		first := tt.build(NewStream("server", "client", 3))
+		second := tt.build(NewStream("server", "client", 3))
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("%s: same seeds produced different orders", tt.name)
+		}
+		if other := tt.build(NewStream("server", "client", 4)); reflect.DeepEqual(first, other) {
+			t.Errorf("%s: different nonce produced the same order", tt.name)
+		}
+		if len(first) != tt.n {
+			t.Errorf("%s: len = %d, want %d", tt.name, len(first), tt.n)
+		}
+	}
+
+	perm := Shuffle(100, NewStream("server", "client", 5))
+	seen := make([]bool, len(perm))
+	for _, v := range perm {
+		if v < 0 || v >= len(perm) || seen[v] {
+			t.Fatalf("Shuffle is not a permutation: %v", perm)
+		}
+		seen[v] = true
+	}
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	specs := []OutcomeSpec{
+		{Type: OutcomeDeck},
+		{Type: OutcomeShoe, Decks: 2},
+		{Type: OutcomeWheel, Pockets: 37, Spins: 3},
+		{Type: OutcomeReels, ReelLengths: []int{30, 32, 34}},
+	}
+	for _, spec := range specs {
+		c, err := NewCommitment(42, spec)
+		if err != nil {
+			t.Fatalf("NewCommitment: %v", err)
+		}
+		for _, seed := range []string{"alice", "bob"} {
+			if err := c.AddClientSeed(seed); err != nil {
+				t.Fatalf("AddClientSeed: %v", err)
+			}
+		}
+		if err := c.Reveal(); err != nil {
+			t.Fatalf("Reveal: %v", err)
+		}
+
+		res, err := Verify(c.ServerSeed, c.ServerSeedHash, c.ClientSeeds, c.Nonce, spec)
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", spec.Type, err)
+		}
+		if !res.HashMatches {
+			t.Errorf("%s: hash should match", spec.Type)
+		}
+		if !reflect.DeepEqual(res.Outcome, c.Result) {
+			t.Errorf("%s: outcome = %v, want %v", spec.Type, res.Outcome, c.Result)
+		}
+
+		// 伺服器種子與事前公布的雜湊不符
+		res, err = Verify(c.ServerSeed, HashSeed("other"), c.ClientSeeds, c.Nonce, spec)
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", spec.Type, err)
+		}
+		if res.HashMatches {
+			t.Errorf("%s: hash mismatch not detected", spec.Type)
+		}
+
+		// 客戶端種子順序或 nonce 不同時結果不同
+		for name, alt := range map[string]func() (*VerifyResult, error){
+			"client seed order": func() (*VerifyResult, error) {
+				return Verify(c.ServerSeed, c.ServerSeedHash, []string{"bob", "alice"}, c.Nonce, spec)
+			},
+			"nonce": func() (*VerifyResult, error) {
+				return Verify(c.ServerSeed, c.ServerSeedHash, c.ClientSeeds, c.Nonce+1, spec)
+			},
+		} {
+			res, err := alt()
+			if err != nil {
+				t.Fatalf("%s/%s: Verify: %v", spec.Type, name, err)
+			}
+			if reflect.DeepEqual(res.Outcome, c.Result) {
+				t.Errorf("%s/%s: outcome mismatch not detected", spec.Type, name)
+			}
+		}
+	}
+}
+
+func TestVerifyRejectsInvalidInput(t *testing.T) {
+	tooMany := make([]string, maxClientSeeds+1)
+	for i := range tooMany {
+		tooMany[i] = "s"
+	}
+	tests := []struct {
+		name        string
+		serverSeed  string
+		clientSeeds []string
+		spec        OutcomeSpec
+		wantErr     error
+	}{
+		{"unrevealed seed", "", nil, OutcomeSpec{Type: OutcomeDeck}, nil},
+		{"too many client seeds", "seed", tooMany, OutcomeSpec{Type: OutcomeDeck}, ErrTooManySeeds},
+		{"client seed too long", "seed", []string{strings.Repeat("x", maxClientSeedLength+1)}, OutcomeSpec{Type: OutcomeDeck}, ErrClientSeedLength},
+		{"empty client seed", "seed", []string{""}, OutcomeSpec{Type: OutcomeDeck}, ErrClientSeedLength},
+		{"too many pockets", "seed", nil, OutcomeSpec{Type: OutcomeWheel, Pockets: maxWheelPockets + 1}, nil},
+		{"too many reels", "seed", nil, OutcomeSpec{Type: OutcomeReels, ReelLengths: make([]int, maxReels+1)}, nil},
+		{"reel too long", "seed", nil, OutcomeSpec{Type: OutcomeReels, ReelLengths: []int{maxReelLength + 1}}, nil},
+	}
+	for _, tt := range tests {
+		_, err := Verify(tt.serverSeed, HashSeed(tt.serverSeed), tt.clientSeeds, 1, tt.spec)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		} else if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	maxSeeds := make([]string, maxClientSeeds)
+	for i := range maxSeeds {
+		maxSeeds[i] = strings.Repeat("x", maxClientSeedLength)
+	}
+	if _, err := Verify("seed", HashSeed("seed"), maxSeeds, 1, OutcomeSpec{Type: OutcomeDeck}); err != nil {
+		t.Errorf("seeds at the limits should be accepted: %v", err)
+	}
+}
+
+func TestAddClientSeedLimits(t *testing.T) {
+	c, err := NewCommitment(1, OutcomeSpec{Type: OutcomeDeck})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddClientSeed(strings.Repeat("x", maxClientSeedLength+1)); !errors.Is(err, ErrClientSeedLength) {
+		t.Errorf("long seed: error = %v, want ErrClientSeedLength", err)
+	}
+	for i := 0; i < maxClientSeeds; i++ {
+		if err := c.AddClientSeed("s"); err != nil {
+			t.Fatalf("AddClientSeed #%d: %v", i, err)
+		}
+	}
+	if err := c.AddClientSeed("s"); !errors.Is(err, ErrTooManySeeds) {
+		t.Errorf("extra seed: error = %v, want ErrTooManySeeds", err)
+	}
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"nexus-gaming-backend/config"
 	"nexus-gaming-backend/controllers"
 	"nexus-gaming-backend/middleware"
 
@@ -30,6 +31,60 @@ func SetupRoutes(r *gin.Engine) {
 			auth.GET("/profile", authController.GetProfile)
 		}
 
+		// 可驗證公平（公開，不需要驗證）
+		fairness := v1.Group("/fairness")
+		fairnessController := controllers.NewFairnessController()
+		{
+			fairness.GET("/sessions/:code", fairnessController.GetSessionFairness)
+			fairness.GET("/sessions/:code/verify", fairnessController.VerifySession)
+			fairness.POST("/verify", fairnessController.Verify)
+		}
+
+		// 即時牌桌 WebSocket（以玩家 Token 驗證）
+		tableController := controllers.NewTableController()
+		v1.GET("/tables/:room_id/ws", tableController.Connect)
+		v1.GET("/tables/:room_id/practice/ws", tableController.ConnectPractice)
+		playerPractice := v1.Group("/player/practice")
+		playerPractice.Use(controllers.NewAuthController().PlayerAuthMiddleware())
+		{
+			playerPractice.GET("/balance", tableController.GetPracticeBalance)
+		}
+
+		// 玩家端牌局歷史（以玩家 Token 驗證，只能查看自己參與的牌局）
+		handHistoryController := controllers.NewHandHistoryController()
+		playerHands := v1.Group("/player/hands")
+		playerHands.Use(controllers.NewAuthController().PlayerAuthMiddleware())
+		{
+			playerHands.GET("/:code", handHistoryController.GetPlayerHand)
+			playerHands.GET("/:code/export", handHistoryController.ExportPlayerHand)
+		}
+
+		// 支付閘道回呼（公開，以各閘道的簽章驗證來源）
+		depositController := controllers.NewDepositController()
+		v1.POST("/payments/:provider/webhook", depositController.HandleWebhook)
+
+		// 錦標賽：玩家 WebSocket 與自行報名（以玩家 Token 驗證）
+		tournamentController := controllers.NewTournamentController()
+		v1.GET("/tournaments/:id/ws", tournamentController.Connect)
+		playerTournaments := v1.Group("/player/tournaments")
+		playerTournaments.Use(controllers.NewAuthController().PlayerAuthMiddleware())
+		{
+			playerTournaments.POST("/:id/register", tournamentController.PlayerRegister)
+			playerTournaments.DELETE("/:id/register", tournamentController.PlayerUnregister)
+		}
+
+		// 玩家端支付方式（以玩家 Token 驗證，只能管理自己的支付方式）
+		paymentMethodController := controllers.NewPaymentMethodController()
+		playerPaymentMethods := v1.Group("/player/payment-methods")
+		playerPaymentMethods.Use(controllers.NewAuthController().PlayerAuthMiddleware())
+		{
+			playerPaymentMethods.GET("", paymentMethodController.GetPaymentMethods)
+			playerPaymentMethods.POST("", paymentMethodController.CreatePaymentMethod)
+			playerPaymentMethods.PUT("/:method_id", paymentMethodController.UpdatePaymentMethod)
+			playerPaymentMethods.DELETE("/:method_id", paymentMethodController.DeletePaymentMethod)
+			playerPaymentMethods.PUT("/:method_id/default", paymentMethodController.SetDefaultPaymentMethod)
+		}
+
 		// 暫時開放的路由（用於開發測試）
 		// TODO: 之後移回需要身份驗證的群組
 		players := v1.Group("/players")
@@ -42,6 +97,7 @@ func SetupRoutes(r *gin.Engine) {
 		authenticated := v1.Group("/")
 		authMiddleware := controllers.NewAuthController().AuthMiddleware()
 		authenticated.Use(authMiddleware)
+		adminMiddleware := controllers.NewAuthController().AdminPermissionMiddleware()
 		{
 			// 使用者管理路由
 			users := authenticated.Group("/users")
@@ -57,6 +113,7 @@ func SetupRoutes(r *gin.Engine) {
 			// 玩家管理路由
 			playersAuth := authenticated.Group("/players")
 			playerController2 := controllers.NewPlayerController()
+			withdrawalController := controllers.NewWithdrawalController()
 			{
 				// players.GET("/", playerController.GetPlayers) // 已移到上方開放路由
 				playersAuth.GET("/:id", playerController2.GetPlayer)
@@ -73,6 +130,20 @@ func SetupRoutes(r *gin.Engine) {
 				playersAuth.POST("/:id/deposit", playerController2.DepositPlayerBalance)
 				playersAuth.POST("/:id/withdraw", playerController2.WithdrawPlayerBalance)
 				playersAuth.GET("/:id/transactions", playerController2.GetPlayerTransactions)
+				playersAuth.POST("/:id/fx-conversions", playerController2.ConvertPlayerCurrency)
+
+				// 玩家錢包凍結
+				playersAuth.GET("/:id/holds", withdrawalController.GetPlayerHolds)
+				playersAuth.POST("/:id/holds", withdrawalController.PlacePlayerHold)
+
+				// 支付方式（帳戶資訊加密保存並遮罩輸出、驗證與詐欺封鎖）
+				playersAuth.GET("/:id/payment-methods", paymentMethodController.GetPaymentMethods)
+				playersAuth.POST("/:id/payment-methods", paymentMethodController.CreatePaymentMethod)
+				playersAuth.PUT("/:id/payment-methods/:method_id", paymentMethodController.UpdatePaymentMethod)
+				playersAuth.DELETE("/:id/payment-methods/:method_id", paymentMethodController.DeletePaymentMethod)
+				playersAuth.PUT("/:id/payment-methods/:method_id/default", paymentMethodController.SetDefaultPaymentMethod)
+				playersAuth.PUT("/:id/payment-methods/:method_id/verify", paymentMethodController.VerifyPaymentMethod)
+				playersAuth.POST("/:id/payment-methods/:method_id/report-fraud", paymentMethodController.ReportPaymentMethodFraud)
 
 				// 玩家限制管理
 				playersAuth.POST("/:id/restrictions", playerController2.SetPlayerRestriction)
@@ -116,76 +187,219 @@ func SetupRoutes(r *gin.Engine) {
 				games.GET("/:id/stats", controllers.GetGameStats)
 			}
 
+			// 即時牌桌管理
+			tables := authenticated.Group("/tables")
+			{
+				tables.POST("/player-token", tableController.IssuePlayerToken)
+				tables.GET("/:room_id", tableController.GetTable)
+			}
+
+			// 牌局歷史（完整內容、重播驗證與文字匯出）
+			hands := authenticated.Group("/hands")
+			{
+				hands.GET("/:code", handHistoryController.GetHand)
+				hands.GET("/:code/replay", handHistoryController.ReplayHand)
+				hands.GET("/:code/export", handHistoryController.ExportHand)
+			}
+
+			// 錦標賽管理（建立、開賽、暫停、恢復、取消退款與代報名）
+			tournaments := authenticated.Group("/tournaments")
+			{
+				tournaments.GET("/", tournamentController.GetTournaments)
+				tournaments.GET("/:id", tournamentController.GetTournament)
+				tournaments.POST("/", tournamentController.CreateTournament)
+				tournaments.POST("/:id/start", tournamentController.StartTournament)
+				tournaments.POST("/:id/pause", tournamentController.PauseTournament)
+				tournaments.POST("/:id/resume", tournamentController.ResumeTournament)
+				tournaments.POST("/:id/cancel", tournamentController.CancelTournament)
+				tournaments.POST("/:id/entries", tournamentController.RegisterPlayer)
+				tournaments.DELETE("/:id/entries/:player_id", tournamentController.UnregisterPlayer)
+			}
+
 			// 財務管理路由
 			financial := authenticated.Group("/financial")
+			ledgerController := controllers.NewLedgerController()
+			fxController := controllers.NewFXController()
+			reconciliationController := controllers.NewReconciliationController()
 			{
 				// 交易記錄
 				financial.GET("/transactions", controllers.GetTransactions)
 				financial.GET("/transactions/:id", controllers.GetTransaction)
 
+				// 複式記帳總帳（傳票、沖銷與完整性檢查）
+				financial.GET("/transactions/:id/journal", ledgerController.GetTransactionJournal)
+				financial.POST("/transactions/:id/reverse", adminMiddleware, ledgerController.ReverseTransaction) // 沖銷需要管理員權限
+				financial.GET("/ledger/integrity", ledgerController.VerifyLedger)
+
+				// 幣別與匯率（靜態匯率表與管理員覆寫）
+				financial.GET("/currencies", fxController.GetCurrencies)
+				financial.GET("/fx-rates", fxController.GetRates)
+				financial.POST("/fx-rates/overrides", adminMiddleware, fxController.SetRateOverride) // 覆寫匯率需要管理員權限
+
 				// 儲值管理
-				financial.GET("/deposits", controllers.GetDeposits)
-				financial.POST("/deposits", controllers.CreateDeposit)
-				financial.PUT("/deposits/:id/confirm", controllers.ConfirmDeposit)
-
-				// 提領管理
-				financial.GET("/withdrawals", controllers.GetWithdrawals)
-				financial.POST("/withdrawals", controllers.CreateWithdrawal)
-				financial.PUT("/withdrawals/:id/approve", controllers.ApproveWithdrawal)
-
-				// 對帳報表
-				financial.GET("/reconciliation/daily", controllers.GetDailyReconciliation)
-				financial.GET("/reconciliation/monthly", controllers.GetMonthlyReconciliation)
+				financial.GET("/deposits", depositController.GetDeposits)
+				financial.POST("/deposits", depositController.CreateDeposit)
+				financial.PUT("/deposits/:id/confirm", depositController.ConfirmDeposit)
+				if config.AppConfig != nil && config.AppConfig.Payment.FakeGatewayEnabled {
+					// 模擬閘道付款（僅本機與測試環境）
+					financial.POST("/deposits/fake/:gateway_tx_id/complete", depositController.CompleteFakePayment)
+				}
+
+				// 提領管理（建立時凍結並評分、審核佇列與雙人覆核、核准後經閘道出款、駁回時釋放）
+				financial.GET("/withdrawals", withdrawalController.GetWithdrawals)
+				financial.GET("/withdrawals/review-queue", withdrawalController.GetReviewQueue)
+				financial.GET("/withdrawals/:id", withdrawalController.GetWithdrawal)
+				financial.POST("/withdrawals", withdrawalController.CreateWithdrawal)
+				financial.PUT("/withdrawals/:id/assign", withdrawalController.AssignWithdrawal)
+				financial.POST("/withdrawals/:id/notes", withdrawalController.AddWithdrawalNote)
+				financial.PUT("/withdrawals/:id/approve", withdrawalController.ApproveWithdrawal)
+				financial.PUT("/withdrawals/:id/reject", withdrawalController.RejectWithdrawal)
+				financial.POST("/withdrawals/:id/payout", withdrawalController.PayoutWithdrawal)
+
+				// 錢包凍結（人工凍結的扣款與釋放）
+				financial.POST("/wallet-holds/:code/capture", withdrawalController.CaptureHold)
+				financial.POST("/wallet-holds/:code/release", withdrawalController.ReleaseHold)
+
+				// 詐欺封鎖帳號（依帳號指紋封鎖所有玩家的相同帳號）
+				financial.GET("/blocked-accounts", paymentMethodController.GetBlockedAccounts)
+				financial.DELETE("/blocked-accounts/:fingerprint", paymentMethodController.UnblockAccount)
+
+				// 對帳報表（已結帳日期為不可修改的快照，結帳後禁止回溯寫入交易）
+				financial.GET("/reconciliation/daily", reconciliationController.GetDailyReconciliation)
+				financial.GET("/reconciliation/monthly", reconciliationController.GetMonthlyReconciliation)
+				financial.POST("/reconciliation/daily/close", adminMiddleware, reconciliationController.CloseDay) // 結帳需要管理員權限
+			}
+
+			// 紅利管理（活動自動發放、流水貢獻比例、人工發放與沒收）
+			bonuses := authenticated.Group("/bonuses")
+			bonusController := controllers.NewBonusController()
+			{
+				bonuses.GET("/", bonusController.GetBonuses)
+				bonuses.POST("/", bonusController.GrantBonus)
+				bonuses.GET("/campaigns", bonusController.GetCampaigns)
+				bonuses.POST("/campaigns", bonusController.CreateCampaign)
+				bonuses.PUT("/campaigns/:id", bonusController.UpdateCampaign)
+				bonuses.GET("/contributions", bonusController.GetContributions)
+				bonuses.PUT("/contributions/:game_type", bonusController.SetContribution)
+				bonuses.GET("/:id", bonusController.GetBonus)
+				bonuses.POST("/:id/forfeit", bonusController.ForfeitBonus)
 			}
 
-			// 代理商管理路由
+			// 代理商管理路由（建立時一併建立登入帳號；終止時需指定經銷商與玩家的移轉方式）
 			agents := authenticated.Group("/agents")
+			agentController := controllers.NewAgentController()
+			commissionController := controllers.NewCommissionController()
+			settlementController := controllers.NewSettlementController()
+			agentWalletController := controllers.NewAgentWalletController()
 			{
-				agents.GET("/", controllers.GetAgents)
-				agents.GET("/:id", controllers.GetAgent)
-				agents.POST("/", controllers.CreateAgent)
-				agents.PUT("/:id", controllers.UpdateAgent)
-				agents.DELETE("/:id", controllers.DeleteAgent)
-				agents.PUT("/:id/status", controllers.UpdateAgentStatus)
+				agents.GET("/", agentController.GetAgents)
+				agents.GET("/:id", agentController.GetAgent)
+				agents.POST("/", agentController.CreateAgent)
+				agents.PUT("/:id", agentController.UpdateAgent)
+				agents.DELETE("/:id", agentController.DeleteAgent)
+				agents.PUT("/:id/status", agentController.UpdateAgentStatus)
+
+				// 層級結構
+				agents.GET("/tree", agentController.GetAgentTree)
+				agents.POST("/hierarchy/rebuild", agentController.RebuildHierarchy)
+				agents.GET("/:id/ancestors", agentController.GetAgentAncestors)
+				agents.GET("/:id/descendants", agentController.GetAgentDescendants)
+				agents.GET("/:id/subtree-stats", agentController.GetAgentSubtreeStats)
+				agents.PUT("/:id/parent", agentController.MoveAgent)
 
 				// 經銷商管理
-				agents.GET("/:id/dealers", controllers.GetAgentDealers)
-				agents.POST("/:id/dealers", controllers.CreateDealer)
+				agents.GET("/:id/dealers", agentController.GetAgentDealers)
+				agents.POST("/:id/dealers", agentController.CreateDealer)
 
 				// 分潤管理
-				agents.GET("/:id/commission", controllers.GetAgentCommission)
-				agents.PUT("/:id/commission", controllers.UpdateAgentCommission)
-				agents.GET("/:id/settlements", controllers.GetAgentSettlements)
+				agents.GET("/:id/commission", commissionController.GetAgentCommission)
+				agents.PUT("/:id/commission", commissionController.UpdateAgentCommission)
+				agents.DELETE("/:id/commission/:config_id", commissionController.DisableAgentCommission)
+				agents.GET("/:id/commissions", commissionController.GetAgentCommissions)
+				agents.GET("/:id/settlements", settlementController.GetAgentSettlements)
+				agents.GET("/:id/player-transfers", agentController.GetAgentPlayerTransfers)
+
+				// 籌碼錢包與信用額度（下級的額度不可超過上級，使用率跨越門檻時產生警示）
+				agents.GET("/credit-alerts", agentWalletController.GetCreditAlerts)
+				agents.POST("/credit-alerts/:id/acknowledge", agentWalletController.AcknowledgeCreditAlert)
+				agents.GET("/:id/wallet", agentWalletController.GetAgentWallet)
+				agents.PUT("/:id/wallet/credit-limit", adminMiddleware, agentWalletController.SetAgentCreditLimit) // 調整信用額度需要管理員權限
+				agents.POST("/:id/wallet/chips", agentWalletController.PurchaseAgentChips)
+				agents.POST("/:id/wallet/repayments", agentWalletController.RepayAgentCredit)
+				agents.POST("/:id/wallet/player-transfers", agentWalletController.TransferAgentPlayerChips)
+				agents.GET("/:id/wallet/transactions", agentWalletController.GetAgentWalletTransactions)
 			}
 
 			// 經銷商管理路由
 			dealers := authenticated.Group("/dealers")
 			{
-				dealers.GET("/", controllers.GetDealers)
-				dealers.GET("/:id", controllers.GetDealer)
-				dealers.PUT("/:id", controllers.UpdateDealer)
-				dealers.DELETE("/:id", controllers.DeleteDealer)
-				dealers.PUT("/:id/status", controllers.UpdateDealerStatus)
+				dealers.GET("/", agentController.GetDealers)
+				dealers.GET("/:id", agentController.GetDealer)
+				dealers.PUT("/:id", agentController.UpdateDealer)
+				dealers.DELETE("/:id", agentController.DeleteDealer)
+				dealers.PUT("/:id/status", agentController.UpdateDealerStatus)
 
 				// 經銷商分潤
-				dealers.GET("/:id/commission", controllers.GetDealerCommission)
-				dealers.GET("/:id/settlements", controllers.GetDealerSettlements)
+				dealers.GET("/:id/commission", commissionController.GetDealerCommission)
+				dealers.PUT("/:id/commission", commissionController.UpdateDealerCommission)
+				dealers.DELETE("/:id/commission/:config_id", commissionController.DisableDealerCommission)
+				dealers.GET("/:id/commissions", commissionController.GetDealerCommissions)
+				dealers.GET("/:id/settlements", settlementController.GetDealerSettlements)
+				dealers.GET("/:id/player-transfers", agentController.GetDealerPlayerTransfers)
+
+				// 經銷商籌碼錢包與信用額度
+				dealers.GET("/:id/wallet", agentWalletController.GetDealerWallet)
+				dealers.PUT("/:id/wallet/credit-limit", adminMiddleware, agentWalletController.SetDealerCreditLimit) // 調整信用額度需要管理員權限
+				dealers.POST("/:id/wallet/chips", agentWalletController.PurchaseDealerChips)
+				dealers.POST("/:id/wallet/repayments", agentWalletController.RepayDealerCredit)
+				dealers.POST("/:id/wallet/player-transfers", agentWalletController.TransferDealerPlayerChips)
+				dealers.GET("/:id/wallet/transactions", agentWalletController.GetDealerWalletTransactions)
 
 				// 經銷商玩家
-				dealers.GET("/:id/players", controllers.GetDealerPlayers)
+				dealers.GET("/:id/players", agentController.GetDealerPlayers)
+			}
+
+			// 玩家移轉路由（佣金依交易當時的歸屬計算，移轉前的交易歸原歸屬）
+			{
+				playersAuth.POST("/transfers", agentController.TransferPlayers)
+				playersAuth.GET("/transfers", agentController.GetPlayerTransfers)
+				playersAuth.GET("/:id/transfers", agentController.GetPlayerTransferHistory)
+			}
+
+			// 分潤計算路由（計算已結束期間的佣金；同期間未確認的結果整批取代）
+			commissions := authenticated.Group("/commissions")
+			{
+				commissions.POST("/calculate", commissionController.CalculateCommissions)
+				commissions.GET("/runs", commissionController.GetCommissionRuns)
+				commissions.GET("/runs/:id", commissionController.GetCommissionRun)
+			}
+
+			// 結算路由（依結算週期定期產生；調整、審核、支付，爭議重算後產生新版本）
+			settlements := authenticated.Group("/settlements")
+			{
+				settlements.POST("/close", settlementController.CloseSettlements)
+				settlements.GET("/:type/:id", settlementController.GetSettlement)
+				settlements.GET("/:type/:id/versions", settlementController.GetSettlementVersions)
+				settlements.POST("/:type/:id/adjustments", settlementController.AdjustSettlement)
+				settlements.POST("/:type/:id/approve", settlementController.ApproveSettlement)
+				settlements.POST("/:type/:id/pay", settlementController.PaySettlement)
+				settlements.POST("/:type/:id/dispute", settlementController.DisputeSettlement)
+				settlements.POST("/:type/:id/recalculate", settlementController.RecalculateSettlement)
 			}
 
 			// 報表管理路由
 			reports := authenticated.Group("/reports")
+			reportController := controllers.NewReportController()
 			{
 				// 營運報表
 				reports.GET("/dashboard", controllers.GetDashboardData)
-				reports.GET("/revenue", controllers.GetRevenueReport)
+				reports.GET("/revenue", reportController.GetRevenueReport)
 				reports.GET("/player-analysis", controllers.GetPlayerAnalysisReport)
 				reports.GET("/game-performance", controllers.GetGamePerformanceReport)
 
 				// 代理商報表
-				reports.GET("/agent-performance", controllers.GetAgentPerformanceReport)
+				reports.GET("/agent-performance", reportController.GetAgentPerformanceReport)
+				reports.POST("/agent-performance/rollup", reportController.RollupAgentPerformance)
 				reports.GET("/commission-summary", controllers.GetCommissionSummaryReport)
 
 				// 自訂報表
@@ -195,7 +409,6 @@ func SetupRoutes(r *gin.Engine) {
 
 			// 系統管理路由
 			admin := authenticated.Group("/admin")
-			adminMiddleware := controllers.NewAuthController().AdminPermissionMiddleware()
 			admin.Use(adminMiddleware) // 需要管理員權限
 			{
 				// 角色權限管理
@@ -215,6 +428,10 @@ func SetupRoutes(r *gin.Engine) {
 				// 系統設置
 				admin.GET("/settings", controllers.GetSystemSettings)
 				admin.PUT("/settings", controllers.UpdateSystemSettings)
+
+				// 敏感資料加密金鑰（各主金鑰的資料筆數與輪替）
+				admin.GET("/vault/keys", paymentMethodController.GetVaultKeys)
+				admin.POST("/vault/rotate", paymentMethodController.RotateVaultKeys)
 			}
 		}
 	}
@@ -0,0 +1,954 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/models"
+	"nexus-gaming-backend/money"
+)
+
+// 代理商與經銷商相關錯誤
+var (
+	ErrAgentNotFound           = errors.New("代理商不存在")
+	ErrDealerNotFound          = errors.New("經銷商不存在")
+	ErrAgentCodeExists         = errors.New("代理商編號已存在")
+	ErrDealerCodeExists        = errors.New("經銷商編號已存在")
+	ErrLoginExists             = errors.New("登入帳號或電子郵件已被使用")
+	ErrStatusTransition        = errors.New("不允許的狀態變更")
+	ErrContractDates           = errors.New("合約結束日期不可早於開始日期")
+	ErrOutsideContract         = errors.New("不在代理商合約期間內")
+	ErrAgentNotOperable        = errors.New("代理商未啟用或不在合約期間內")
+	ErrDealerNotOperable       = errors.New("經銷商未啟用")
+	ErrDealerLimit             = errors.New("超過代理商的經銷商數量上限")
+	ErrPlayerLimit             = errors.New("超過經銷商的玩家數量上限")
+	ErrCompanyAgent            = errors.New("總公司不可變更狀態或刪除")
+	ErrAgentHasSubAgents       = errors.New("尚有未終止的下級代理商，請先移動或終止")
+	ErrReassignmentRequired    = errors.New("終止前需指定經銷商與玩家的移轉方式")
+	ErrInvalidReassignment     = errors.New("移轉方式或移轉對象無效")
+	ErrAgentInUse              = errors.New("代理商已有下級代理商、經銷商、玩家、佣金、結算或錢包異動記錄，請改為終止")
+	ErrDealerInUse             = errors.New("經銷商已有玩家、佣金、結算或錢包異動記錄，請改為終止")
+	ErrParentAgentNotOperable  = errors.New("上級代理商未啟用或不在合約期間內")
+	ErrCompanyAgentUnavailable = errors.New("找不到總公司代理商")
+)
+
+// 代理商與經銷商狀態
+const (
+	AgentStatusActive     = "active"
+	AgentStatusInactive   = "inactive"
+	AgentStatusSuspended  = "suspended"
+	AgentStatusTerminated = "terminated"
+)
+
+// agentStatusTransitions 允許的狀態變更（代理商與經銷商相同；終止後不可再變更）
+var agentStatusTransitions = map[string][]string{
+	AgentStatusActive:    {AgentStatusInactive, AgentStatusSuspended, AgentStatusTerminated},
+	AgentStatusInactive:  {AgentStatusActive, AgentStatusSuspended, AgentStatusTerminated},
+	AgentStatusSuspended: {AgentStatusActive, AgentStatusInactive, AgentStatusTerminated},
+}
+
+// 移轉方式
+const (
+	ReassignMove      = "reassign"  // 移至指定的代理商或經銷商
+	ReassignTerminate = "terminate" // 經銷商一併終止（僅用於代理商終止時的經銷商）
+	ReassignRelease   = "release"   // 玩家改為直屬總公司
+)
+
+// Agent 代理商
+type Agent struct {
+	ID                 int               `json:"id"`
+	AgentCode          string            `json:"agent_code"`
+	AgentName          string            `json:"agent_name"`
+	ContactPerson      string            `json:"contact_person"`
+	Email              string            `json:"email"`
+	Phone              string            `json:"phone,omitempty"`
+	Address            string            `json:"address,omitempty"`
+	BusinessLicense    string            `json:"business_license,omitempty"`
+	TaxID              string            `json:"tax_id,omitempty"`
+	BankAccount        map[string]string `json:"bank_account,omitempty"` // 遮罩後的銀行帳戶資訊
+	ContractStartDate  *string           `json:"contract_start_date,omitempty"`
+	ContractEndDate    *string           `json:"contract_end_date,omitempty"`
+	InContract         bool              `json:"in_contract"`
+	Status             string            `json:"status"`
+	UserID             int               `json:"user_id"`
+	Username           string            `json:"username"`
+	ParentAgentID      *int              `json:"parent_agent_id,omitempty"`
+	Level              int               `json:"level"`
+	CommissionRate     float64           `json:"commission_rate"`
+	MaxDealers         int               `json:"max_dealers"`
+	CurrentDealers     int               `json:"current_dealers"` // 未終止的經銷商數量
+	TotalPlayers       int64             `json:"total_players"`
+	TotalRevenue       money.Amount      `json:"total_revenue"`
+	TotalCommission    money.Amount      `json:"total_commission"`
+	LastSettlementDate *string           `json:"last_settlement_date,omitempty"`
+	Notes              string            `json:"notes,omitempty"`
+	CreatedBy          *int              `json:"created_by,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+
+	contractStart, contractEnd *time.Time
+}
+
+// AgentInput 建立或更新代理商（登入帳號與密碼僅於建立時使用，更新時銀行帳戶為 nil 表示不變更）
+type AgentInput struct {
+	AgentCode         string
+	AgentName         string
+	ContactPerson     string
+	Email             string
+	Phone             string
+	Address           string
+	BusinessLicense   string
+	TaxID             string
+	BankAccount       map[string]string
+	ContractStartDate *time.Time
+	ContractEndDate   *time.Time
+	ParentAgentID     *int // 省略時為總公司
+	CommissionRate    float64
+	MaxDealers        int
+	Notes             string
+	Username          string
+	Password          string
+}
+
+// AgentFilter 代理商查詢條件
+type AgentFilter struct {
+	Status        string
+	ParentAgentID int
+	Keyword       string
+	Page          int
+	Limit         int
+}
+
+// PlayerReassignment 玩家的移轉方式
+type PlayerReassignment struct {
+	Action         string // reassign 或 release
+	TargetDealerID *int   // 移至經銷商（所屬代理商一併變更）
+	TargetAgentID  *int   // 移至代理商直屬
+}
+
+// AgentTermination 終止代理商時的經銷商與玩家移轉方式
+type AgentTermination struct {
+	DealerAction        string // reassign 或 terminate
+	DealerTargetAgentID *int
+	Players             PlayerReassignment // 代理商直屬的玩家（經銷商一併終止時也包含其玩家）
+}
+
+// ReassignmentResult 移轉結果
+type ReassignmentResult struct {
+	DealersMoved      int64 `json:"dealers_moved"`
+	DealersTerminated int64 `json:"dealers_terminated"`
+	PlayersMoved      int64 `json:"players_moved"`
+	PlayersReleased   int64 `json:"players_released"`
+}
+
+// AgentStatusLog 狀態異動記錄
+type AgentStatusLog struct {
+	ID         int64           `json:"id"`
+	FromStatus string          `json:"from_status"`
+	ToStatus   string          `json:"to_status"`
+	Reason     string          `json:"reason,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	OperatorID *int            `json:"operator_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AgentService 代理商與經銷商管理
+//
+// 建立代理商或經銷商時一併建立對應角色的後台登入帳號，狀態變更同步至登入帳號。
+// 代理商只有在啟用且位於合約期間內時才可新增經銷商或承接玩家；終止代理商或經銷商時必須指定
+// 其經銷商與玩家的移轉方式，所有移轉與狀態變更在同一交易內完成並留下記錄。
+type AgentService struct {
+	DB        *sql.DB
+	Hierarchy *HierarchyService
+}
+
+// NewAgentService 建立新的代理商服務
+func NewAgentService() *AgentService {
+	return &AgentService{DB: config.GetDB(), Hierarchy: NewHierarchyService()}
+}
+
+// List 依條件列出代理商，回傳資料與總筆數
+func (s *AgentService) List(f AgentFilter) ([]Agent, int64, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if f.Status != "" {
+		where += " AND a.status = ?"
+		args = append(args, f.Status)
+	}
+	if f.ParentAgentID > 0 {
+		where += " AND a.parent_agent_id = ?"
+		args = append(args, f.ParentAgentID)
+	}
+	if f.Keyword != "" {
+		where += " AND (a.agent_code LIKE ? OR a.agent_name LIKE ? OR a.contact_person LIKE ?)"
+		kw := "%" + f.Keyword + "%"
+		args = append(args, kw, kw, kw)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM agents a"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(agentSelect+where+" ORDER BY a.level, a.id LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []Agent{}
+	for rows.Next() {
+		a, err := scanAgent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *a)
+	}
+	return list, total, rows.Err()
+}
+
+// Get 取得代理商
+func (s *AgentService) Get(id int) (*Agent, error) {
+	return loadAgent(s.DB, "a.id = ?", id)
+}
+
+// StatusLogs 取得代理商或經銷商的狀態異動記錄
+func (s *AgentService) StatusLogs(targetType string, id int) ([]AgentStatusLog, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, from_status, to_status, reason, details, operator_id, created_at
+		FROM agent_status_logs WHERE target_type = ? AND target_id = ? ORDER BY id DESC
+	`, targetType, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []AgentStatusLog{}
+	for rows.Next() {
+		var (
+			l          AgentStatusLog
+			reason     sql.NullString
+			details    []byte
+			operatorID sql.NullInt64
+		)
+		if err := rows.Scan(&l.ID, &l.FromStatus, &l.ToStatus, &reason, &details, &operatorID, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		l.Reason, l.OperatorID = reason.String, nullInt(operatorID)
+		if len(details) > 0 {
+			l.Details = json.RawMessage(details)
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// Create 建立代理商與其登入帳號（上級代理商需啟用且位於合約期間內）
+func (s *AgentService) Create(in AgentInput, createdBy *int) (*Agent, error) {
+	if err := validateContract(in.ContractStartDate, in.ContractEndDate); err != nil {
+		return nil, err
+	}
+	bank, err := sealBankAccount(columnAgentBankAccount, in.BankAccount)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	err = withAgentTx(s.DB, func(tx *sql.Tx) error {
+		var (
+			parent *Agent
+			err    error
+		)
+		if in.ParentAgentID != nil {
+			parent, err = loadAgent(tx, "a.id = ? FOR UPDATE OF a", *in.ParentAgentID)
+		} else {
+			parent, err = loadAgent(tx, "a.parent_agent_id IS NULL AND a.level = 0 ORDER BY a.id LIMIT 1 FOR UPDATE OF a")
+			if err == ErrAgentNotFound {
+				err = ErrCompanyAgentUnavailable
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := parent.operable(); err != nil {
+			return ErrParentAgentNotOperable
+		}
+		if err := s.Hierarchy.CheckDepth(parent.Level); err != nil {
+			return err
+		}
+		userID, err := createLoginTx(tx, in.Username, in.Email, in.Password, "agent")
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(`
+			INSERT INTO agents
+				(agent_code, agent_name, contact_person, email, phone, address, business_license, tax_id, bank_account,
+				 contract_start_date, contract_end_date, status, user_id, parent_agent_id, level, commission_rate,
+				 max_dealers, notes, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, in.AgentCode, in.AgentName, in.ContactPerson, in.Email, nullString(in.Phone), nullString(in.Address),
+			nullString(in.BusinessLicense), nullString(in.TaxID), bank, in.ContractStartDate, in.ContractEndDate,
+			initialAgentStatus(in.ContractStartDate, in.ContractEndDate), userID, parent.ID, parent.Level+1,
+			in.CommissionRate, in.MaxDealers, nullString(in.Notes), createdBy)
+		if isDuplicateKey(err) {
+			return ErrAgentCodeExists
+		} else if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if err := s.Hierarchy.InsertAgentTx(tx, int(id), &parent.ID); err != nil {
+			return err
+		}
+		return syncLoginStatusTx(tx, userID, initialAgentStatus(in.ContractStartDate, in.ContractEndDate))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(int(id))
+}
+
+// Update 更新代理商資料（編號、上級代理商與登入帳號不可在此變更）
+func (s *AgentService) Update(id int, in AgentInput) (*Agent, error) {
+	if err := validateContract(in.ContractStartDate, in.ContractEndDate); err != nil {
+		return nil, err
+	}
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		a, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", id)
+		if err != nil {
+			return err
+		}
+		if a.Status == AgentStatusTerminated {
+			return ErrStatusTransition
+		}
+		if in.MaxDealers > 0 {
+			active, err := countActiveDealers(tx, id)
+			if err != nil {
+				return err
+			}
+			if active > in.MaxDealers {
+				return ErrDealerLimit
+			}
+		}
+		if a.Status == AgentStatusActive && !inContract(in.ContractStartDate, in.ContractEndDate, time.Now()) {
+			return ErrOutsideContract
+		}
+		query := `
+			UPDATE agents SET agent_name = ?, contact_person = ?, email = ?, phone = ?, address = ?,
+				business_license = ?, tax_id = ?, contract_start_date = ?, contract_end_date = ?,
+				commission_rate = ?, max_dealers = ?, notes = ?`
+		args := []interface{}{in.AgentName, in.ContactPerson, in.Email, nullString(in.Phone), nullString(in.Address),
+			nullString(in.BusinessLicense), nullString(in.TaxID), in.ContractStartDate, in.ContractEndDate,
+			in.CommissionRate, in.MaxDealers, nullString(in.Notes)}
+		if in.BankAccount != nil {
+			bank, err := sealBankAccount(columnAgentBankAccount, in.BankAccount)
+			if err != nil {
+				return err
+			}
+			query += ", bank_account = ?"
+			args = append(args, bank)
+		}
+		_, err = tx.Exec(query+" WHERE id = ?", append(args, id)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// UpdateStatus 變更代理商狀態（終止請使用 Terminate）；啟用時需位於合約期間內且上級代理商可營運
+func (s *AgentService) UpdateStatus(id int, status, reason string, operatorID *int) (*Agent, error) {
+	if status == AgentStatusTerminated {
+		return nil, ErrReassignmentRequired
+	}
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		a, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", id)
+		if err != nil {
+			return err
+		}
+		if a.ParentAgentID == nil {
+			return ErrCompanyAgent
+		}
+		if !canTransition(a.Status, status) {
+			return ErrStatusTransition
+		}
+		if status == AgentStatusActive {
+			if !a.InContract {
+				return ErrOutsideContract
+			}
+			parent, err := loadAgent(tx, "a.id = ?", *a.ParentAgentID)
+			if err != nil {
+				return err
+			}
+			if err := parent.operable(); err != nil {
+				return ErrParentAgentNotOperable
+			}
+		}
+		return s.setStatusTx(tx, a, status, reason, nil, operatorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// Terminate 終止代理商：依指定方式移轉或終止其經銷商，並移轉其玩家（有經銷商或玩家時必須指定移轉方式）
+func (s *AgentService) Terminate(id int, plan AgentTermination, reason string, operatorID *int) (*Agent, *ReassignmentResult, error) {
+	result := &ReassignmentResult{}
+	move := newPlayerMove(TransferAgentTermination, reason, operatorID)
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		a, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", id)
+		if err != nil {
+			return err
+		}
+		if a.ParentAgentID == nil {
+			return ErrCompanyAgent
+		}
+		if !canTransition(a.Status, AgentStatusTerminated) {
+			return ErrStatusTransition
+		}
+		var children int
+		if err := tx.QueryRow(`
+			SELECT COUNT(*) FROM agents WHERE parent_agent_id = ? AND status <> 'terminated'
+		`, id).Scan(&children); err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrAgentHasSubAgents
+		}
+
+		dealers, err := countActiveDealers(tx, id)
+		if err != nil {
+			return err
+		}
+		if dealers > 0 {
+			switch plan.DealerAction {
+			case ReassignMove:
+				if plan.DealerTargetAgentID == nil || *plan.DealerTargetAgentID == id {
+					return ErrInvalidReassignment
+				}
+				if result.DealersMoved, err = s.moveDealersTx(tx, id, *plan.DealerTargetAgentID, move); err != nil {
+					return err
+				}
+			case ReassignTerminate:
+				if result.DealersTerminated, err = s.terminateDealersTx(tx, id, reason, operatorID, move); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%w: 代理商尚有 %d 個經銷商", ErrReassignmentRequired, dealers)
+			}
+		}
+
+		players, err := countPlayers(tx, "agent_id = ?", id)
+		if err != nil {
+			return err
+		}
+		if players > 0 {
+			if plan.Players.Action == "" {
+				return fmt.Errorf("%w: 代理商尚有 %d 位玩家", ErrReassignmentRequired, players)
+			}
+			if plan.Players.TargetAgentID != nil && *plan.Players.TargetAgentID == id {
+				return ErrInvalidReassignment
+			}
+			if err := s.movePlayersTx(tx, "agent_id = ?", []interface{}{id}, players, plan.Players, move, result); err != nil {
+				return err
+			}
+		}
+
+		details, _ := json.Marshal(result)
+		return s.setStatusTx(tx, a, AgentStatusTerminated, reason, details, operatorID)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := s.Get(id)
+	return a, result, err
+}
+
+// MoveAgent 將代理商連同其下級代理商與經銷商移至新的上級代理商（新上級不可為已終止或位於自己的子樹內）
+func (s *AgentService) MoveAgent(id, parentID int) (*Agent, error) {
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		a, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", id)
+		if err != nil {
+			return err
+		}
+		if a.ParentAgentID == nil {
+			return ErrHierarchyRootMove
+		}
+		if a.Status == AgentStatusTerminated {
+			return ErrStatusTransition
+		}
+		if *a.ParentAgentID == parentID {
+			return nil
+		}
+		parent, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", parentID)
+		if err != nil {
+			return err
+		}
+		if parent.Status == AgentStatusTerminated {
+			return ErrParentAgentNotOperable
+		}
+		return s.Hierarchy.MoveAgentTx(tx, id, parentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// Delete 刪除代理商（僅限沒有下級代理商、經銷商、玩家、佣金與結算記錄者，其餘請改為終止），登入帳號改為停用
+func (s *AgentService) Delete(id int) error {
+	return withAgentTx(s.DB, func(tx *sql.Tx) error {
+		a, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", id)
+		if err != nil {
+			return err
+		}
+		if a.ParentAgentID == nil {
+			return ErrCompanyAgent
+		}
+		var used int
+		if err := tx.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM agents WHERE parent_agent_id = ?)
+			     + (SELECT COUNT(*) FROM dealers WHERE agent_id = ?)
+			     + (SELECT COUNT(*) FROM players WHERE agent_id = ?)
+			     + (SELECT COUNT(*) FROM agent_settlements WHERE agent_id = ?)
+			     + (SELECT COUNT(*) FROM commissions WHERE agent_id = ?)
+			     + (SELECT COUNT(*) FROM agent_wallet_transactions WHERE owner_type = 'agent' AND owner_id = ?)
+		`, id, id, id, id, id, id).Scan(&used); err != nil {
+			return err
+		}
+		if used > 0 {
+			return ErrAgentInUse
+		}
+		if _, err := tx.Exec("DELETE FROM agent_status_logs WHERE target_type = 'agent' AND target_id = ?", id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM commission_configs WHERE target_type = 'agent' AND target_id = ?", id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM agent_wallets WHERE owner_type = 'agent' AND owner_id = ?", id); err != nil {
+			return err
+		}
+		if err := s.Hierarchy.DeleteNodeTx(tx, NodeAgent, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM agents WHERE id = ?", id); err != nil {
+			return err
+		}
+		return syncLoginStatusTx(tx, a.UserID, AgentStatusInactive)
+	})
+}
+
+// ExpireContracts 將合約已到期的啟用中代理商改為停用，回傳處理筆數
+func (s *AgentService) ExpireContracts() (int, error) {
+	rows, err := s.DB.Query(`
+		SELECT id FROM agents
+		WHERE status = 'active' AND parent_agent_id IS NOT NULL AND contract_end_date < CURDATE()
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, id := range ids {
+		err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+			a, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", id)
+			if err != nil {
+				return err
+			}
+			if a.Status != AgentStatusActive || a.InContract {
+				return nil
+			}
+			n++
+			return s.setStatusTx(tx, a, AgentStatusInactive, "合約到期", nil, nil)
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Run 定期停用合約到期的代理商，直到 ctx 結束（於背景 goroutine 執行）
+func (s *AgentService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.ExpireContracts(); err != nil {
+			log.Printf("代理商合約到期處理失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已停用 %d 個合約到期的代理商", n)
+		}
+	}
+}
+
+// setStatusTx 變更代理商狀態、同步登入帳號並記錄
+func (s *AgentService) setStatusTx(tx *sql.Tx, a *Agent, status, reason string, details []byte, operatorID *int) error {
+	if _, err := tx.Exec("UPDATE agents SET status = ? WHERE id = ?", status, a.ID); err != nil {
+		return err
+	}
+	if err := syncLoginStatusTx(tx, a.UserID, status); err != nil {
+		return err
+	}
+	return logStatusTx(tx, "agent", a.ID, a.Status, status, reason, details, operatorID)
+}
+
+// moveDealersTx 將代理商未終止的經銷商與其玩家移至其他代理商（玩家的歸屬變更記錄為移轉）
+func (s *AgentService) moveDealersTx(tx *sql.Tx, fromID, toID int, move playerMove) (int64, error) {
+	target, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", toID)
+	if err != nil {
+		return 0, err
+	}
+	if err := target.operable(); err != nil {
+		return 0, err
+	}
+	moving, err := countActiveDealers(tx, fromID)
+	if err != nil {
+		return 0, err
+	}
+	if target.MaxDealers > 0 && target.CurrentDealers+moving > target.MaxDealers {
+		return 0, ErrDealerLimit
+	}
+	rows, err := tx.Query("SELECT id FROM dealers WHERE agent_id = ? AND status <> 'terminated'", fromID)
+	if err != nil {
+		return 0, err
+	}
+	var dealerIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		dealerIDs = append(dealerIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	move.source = TransferDealerMove
+	for i := range dealerIDs {
+		if _, err := transferPlayersTx(tx, "dealer_id = ?", []interface{}{dealerIDs[i]}, move.to(&toID, &dealerIDs[i])); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.Exec("UPDATE dealers SET agent_id = ? WHERE agent_id = ? AND status <> 'terminated'", toID, fromID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Hierarchy.RelinkDealersTx(tx, toID); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// terminateDealersTx 終止代理商所有未終止的經銷商（其玩家留在代理商下，由呼叫端一併移轉）
+func (s *AgentService) terminateDealersTx(tx *sql.Tx, agentID int, reason string, operatorID *int, move playerMove) (int64, error) {
+	rows, err := tx.Query(dealerSelect+" WHERE d.agent_id = ? AND d.status <> 'terminated' FOR UPDATE OF d", agentID)
+	if err != nil {
+		return 0, err
+	}
+	var dealers []*Dealer
+	for rows.Next() {
+		d, err := scanDealer(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		dealers = append(dealers, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, d := range dealers {
+		if _, err := transferPlayersTx(tx, "dealer_id = ?", []interface{}{d.ID}, move.to(&agentID, nil)); err != nil {
+			return 0, err
+		}
+		if err := setDealerStatusTx(tx, d, AgentStatusTerminated, reason, nil, operatorID); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(dealers)), nil
+}
+
+// movePlayersTx 依移轉方式移轉符合條件且未刪除的玩家（移至經銷商時檢查玩家數量上限），並記錄移轉
+func (s *AgentService) movePlayersTx(tx *sql.Tx, where string, args []interface{}, count int,
+	plan PlayerReassignment, move playerMove, result *ReassignmentResult) error {
+	where += " AND status <> 'deleted'"
+	switch {
+	case plan.Action == ReassignRelease:
+		n, err := transferPlayersTx(tx, where, args, move.to(nil, nil))
+		if err != nil {
+			return err
+		}
+		result.PlayersReleased = n
+		return nil
+	case plan.Action == ReassignMove && plan.TargetDealerID != nil:
+		d, err := loadDealer(tx, "d.id = ? FOR UPDATE", *plan.TargetDealerID)
+		if err != nil {
+			return err
+		}
+		if err := d.operable(); err != nil {
+			return err
+		}
+		agent, err := loadAgent(tx, "a.id = ?", d.AgentID)
+		if err != nil {
+			return err
+		}
+		if err := agent.operable(); err != nil {
+			return err
+		}
+		current, err := countPlayers(tx, "dealer_id = ?", d.ID)
+		if err != nil {
+			return err
+		}
+		if d.MaxPlayers > 0 && current+count > d.MaxPlayers {
+			return ErrPlayerLimit
+		}
+		n, err := transferPlayersTx(tx, where, args, move.to(&d.AgentID, &d.ID))
+		if err != nil {
+			return err
+		}
+		result.PlayersMoved = n
+		return nil
+	case plan.Action == ReassignMove && plan.TargetAgentID != nil:
+		agent, err := loadAgent(tx, "a.id = ?", *plan.TargetAgentID)
+		if err != nil {
+			return err
+		}
+		if err := agent.operable(); err != nil {
+			return err
+		}
+		n, err := transferPlayersTx(tx, where, args, move.to(&agent.ID, nil))
+		if err != nil {
+			return err
+		}
+		result.PlayersMoved = n
+		return nil
+	default:
+		return ErrInvalidReassignment
+	}
+}
+
+// operable 代理商是否可新增經銷商或承接玩家
+func (a *Agent) operable() error {
+	if a.Status != AgentStatusActive || !a.InContract {
+		return ErrAgentNotOperable
+	}
+	return nil
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range agentStatusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func validateContract(start, end *time.Time) error {
+	if start != nil && end != nil && end.Before(*start) {
+		return ErrContractDates
+	}
+	return nil
+}
+
+// inContract 指定時間是否位於合約期間內（未設定的日期視為不限）
+func inContract(start, end *time.Time, now time.Time) bool {
+	today := now.Format("2006-01-02")
+	if start != nil && start.Format("2006-01-02") > today {
+		return false
+	}
+	if end != nil && end.Format("2006-01-02") < today {
+		return false
+	}
+	return true
+}
+
+// initialAgentStatus 新代理商的狀態（合約尚未開始或已結束時為停用）
+func initialAgentStatus(start, end *time.Time) string {
+	if inContract(start, end, time.Now()) {
+		return AgentStatusActive
+	}
+	return AgentStatusInactive
+}
+
+// createLoginTx 建立指定角色的後台登入帳號
+func createLoginTx(tx *sql.Tx, username, email, password, role string) (int, error) {
+	var roleID int
+	if err := tx.QueryRow("SELECT id FROM roles WHERE name = ?", role).Scan(&roleID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("找不到角色 %s", role)
+		}
+		return 0, err
+	}
+	user := &models.User{}
+	if err := user.HashPassword(password); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec("INSERT INTO users (username, email, password_hash, role_id) VALUES (?, ?, ?, ?)",
+		username, email, user.Password, roleID)
+	if isDuplicateKey(err) {
+		return 0, ErrLoginExists
+	} else if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// syncLoginStatusTx 依代理商或經銷商狀態同步登入帳號狀態（終止視為停用）
+func syncLoginStatusTx(tx *sql.Tx, userID int, status string) error {
+	if status == AgentStatusTerminated {
+		status = AgentStatusInactive
+	}
+	_, err := tx.Exec("UPDATE users SET status = ? WHERE id = ?", status, userID)
+	return err
+}
+
+func logStatusTx(tx *sql.Tx, targetType string, id int, from, to, reason string, details []byte, operatorID *int) error {
+	var detailsArg interface{}
+	if len(details) > 0 {
+		detailsArg = string(details)
+	}
+	_, err := tx.Exec(`
+		INSERT INTO agent_status_logs (target_type, target_id, from_status, to_status, reason, details, operator_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, targetType, id, from, to, nullString(reason), detailsArg, operatorID)
+	return err
+}
+
+func countActiveDealers(q queryer, agentID int) (int, error) {
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM dealers WHERE agent_id = ? AND status <> 'terminated'", agentID).Scan(&n)
+	return n, err
+}
+
+func countPlayers(q queryer, where string, args ...interface{}) (int, error) {
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM players WHERE status <> 'deleted' AND "+where, args...).Scan(&n)
+	return n, err
+}
+
+// sealBankAccount 加密銀行帳戶資訊（空值保持 NULL）
+func sealBankAccount(col vaultColumn, account map[string]string) (interface{}, error) {
+	if len(account) == 0 {
+		return nil, nil
+	}
+	raw, _ := json.Marshal(account)
+	return sealJSON(col, raw)
+}
+
+// openBankAccount 解密並遮罩銀行帳戶資訊
+func openBankAccount(col vaultColumn, data []byte) (map[string]string, error) {
+	raw, err := openJSON(col, data)
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, nil
+	}
+	account := map[string]string{}
+	for k, v := range fields {
+		if str, ok := v.(string); ok {
+			account[k] = str
+		}
+	}
+	return maskAccount(account), nil
+}
+
+func withAgentTx(db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// formatDate 將日期欄位格式化為 YYYY-MM-DD
+func formatDate(t sql.NullTime) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.Time.Format("2006-01-02")
+	return &s
+}
+
+const agentSelect = `
+	SELECT a.id, a.agent_code, a.agent_name, a.contact_person, a.email, COALESCE(a.phone, ''),
+	       COALESCE(a.address, ''), COALESCE(a.business_license, ''), COALESCE(a.tax_id, ''), a.bank_account,
+	       a.contract_start_date, a.contract_end_date, a.status, a.user_id, COALESCE(u.username, ''),
+	       a.parent_agent_id, a.level, a.commission_rate, a.max_dealers,
+	       (SELECT COUNT(*) FROM dealers d WHERE d.agent_id = a.id AND d.status <> 'terminated'),
+	       (SELECT COUNT(*) FROM players p WHERE p.agent_id = a.id AND p.status <> 'deleted'),
+	       a.total_revenue, a.total_commission, a.last_settlement_date, COALESCE(a.notes, ''), a.created_by,
+	       a.created_at, a.updated_at
+	FROM agents a
+	LEFT JOIN users u ON u.id = a.user_id`
+
+func loadAgent(q queryer, where string, args ...interface{}) (*Agent, error) {
+	a, err := scanAgent(q.QueryRow(agentSelect+" WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrAgentNotFound
+	}
+	return a, err
+}
+
+func scanAgent(row rowScanner) (*Agent, error) {
+	a := &Agent{}
+	var (
+		bank                       []byte
+		start, end, lastSettlement sql.NullTime
+		parentID, createdBy        sql.NullInt64
+	)
+	if err := row.Scan(&a.ID, &a.AgentCode, &a.AgentName, &a.ContactPerson, &a.Email, &a.Phone,
+		&a.Address, &a.BusinessLicense, &a.TaxID, &bank,
+		&start, &end, &a.Status, &a.UserID, &a.Username,
+		&parentID, &a.Level, &a.CommissionRate, &a.MaxDealers,
+		&a.CurrentDealers, &a.TotalPlayers,
+		&a.TotalRevenue, &a.TotalCommission, &lastSettlement, &a.Notes, &createdBy,
+		&a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	account, err := openBankAccount(columnAgentBankAccount, bank)
+	if err != nil {
+		return nil, err
+	}
+	a.BankAccount = account
+	a.contractStart, a.contractEnd = nullTime(start), nullTime(end)
+	a.ContractStartDate, a.ContractEndDate, a.LastSettlementDate = formatDate(start), formatDate(end), formatDate(lastSettlement)
+	a.InContract = inContract(a.contractStart, a.contractEnd, time.Now())
+	a.ParentAgentID, a.CreatedBy = nullInt(parentID), nullInt(createdBy)
+	return a, nil
+}
@@ -0,0 +1,906 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 代理商錢包相關錯誤
+var (
+	ErrAgentWalletOwner         = errors.New("錢包擁有者類型必須為 agent 或 dealer")
+	ErrCompanyWallet            = errors.New("總公司不使用籌碼錢包與信用額度")
+	ErrChipPayment              = errors.New("購買籌碼的付款方式必須為 prepaid 或 credit")
+	ErrInsufficientChips        = errors.New("籌碼不足")
+	ErrCreditLimitExceeded      = errors.New("超過信用額度")
+	ErrInvalidCreditLimit       = errors.New("信用額度不可為負數")
+	ErrCreditLimitAboveParent   = errors.New("信用額度不可超過上級代理商的信用額度")
+	ErrCreditLimitBelowChildren = errors.New("信用額度不可低於下級代理商或經銷商的信用額度")
+	ErrRepaymentExceedsCredit   = errors.New("還款金額超過已使用的信用額度")
+	ErrTransferDirection        = errors.New("移轉方向必須為 deposit 或 withdrawal")
+	ErrPlayerNotOwned           = errors.New("玩家不屬於此代理商或經銷商")
+	ErrPlayerNotTransferable    = errors.New("玩家帳號狀態不允許此操作")
+	ErrCreditAlertNotFound      = errors.New("信用額度警示不存在")
+)
+
+// 購買籌碼的付款方式
+const (
+	ChipPrepaid = "prepaid" // 預付：以現金購買
+	ChipCredit  = "credit"  // 賒帳：動用信用額度
+)
+
+// 代理商錢包異動類型
+const (
+	AgentTxChipPurchase      = "chip_purchase"
+	AgentTxCreditDraw        = "credit_draw"
+	AgentTxCreditRepayment   = "credit_repayment"
+	AgentTxPlayerDeposit     = "player_deposit"
+	AgentTxPlayerWithdrawal  = "player_withdrawal"
+	AgentTxSettlementNetting = "settlement_netting"
+)
+
+// 信用額度使用率警示等級
+const (
+	CreditAlertNone     = "none"
+	CreditAlertWarning  = "warning"
+	CreditAlertCritical = "critical"
+)
+
+// AgentWallet 代理商或經銷商的籌碼錢包（單一幣別）
+//
+// Balance 為持有的籌碼；CreditUsed 為自身賒帳購買且尚未償還的金額。
+// 代理商的 Exposure 含所有下級代理商與經銷商的 CreditUsed，信用額度與使用率以 Exposure 計算。
+type AgentWallet struct {
+	OwnerType       string       `json:"owner_type"`
+	OwnerID         int          `json:"owner_id"`
+	Currency        string       `json:"currency"`
+	Balance         money.Amount `json:"balance"`
+	CreditLimit     money.Amount `json:"credit_limit"`
+	CreditUsed      money.Amount `json:"credit_used"`
+	Exposure        money.Amount `json:"exposure"`
+	AvailableCredit money.Amount `json:"available_credit"` // 自身額度的剩餘（上級代理商的額度另行檢查）
+	Utilisation     float64      `json:"utilisation"`
+	AlertLevel      string       `json:"alert_level"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+
+	id int64
+}
+
+// AgentWalletTransaction 代理商錢包異動記錄
+type AgentWalletTransaction struct {
+	ID                  int64        `json:"id"`
+	TransactionID       string       `json:"transaction_id"`
+	OwnerType           string       `json:"owner_type"`
+	OwnerID             int          `json:"owner_id"`
+	Currency            string       `json:"currency"`
+	Type                string       `json:"type"`
+	Amount              money.Amount `json:"amount"`
+	BalanceBefore       money.Amount `json:"balance_before"`
+	BalanceAfter        money.Amount `json:"balance_after"`
+	CreditUsedBefore    money.Amount `json:"credit_used_before"`
+	CreditUsedAfter     money.Amount `json:"credit_used_after"`
+	PlayerID            *int64       `json:"player_id,omitempty"`
+	PlayerTransactionID string       `json:"player_transaction_id,omitempty"`
+	SettlementID        string       `json:"settlement_id,omitempty"`
+	JournalCode         string       `json:"journal_code"`
+	Description         string       `json:"description,omitempty"`
+	OperatorID          *int         `json:"operator_id,omitempty"`
+	CreatedAt           time.Time    `json:"created_at"`
+}
+
+// AgentWalletFilter 代理商錢包異動查詢條件
+type AgentWalletFilter struct {
+	OwnerType string
+	OwnerID   int
+	Currency  string
+	Type      string
+	Page      int
+	Limit     int
+}
+
+// CreditAlert 信用額度使用率警示
+type CreditAlert struct {
+	ID             int64        `json:"id"`
+	OwnerType      string       `json:"owner_type"`
+	OwnerID        int          `json:"owner_id"`
+	Currency       string       `json:"currency"`
+	AlertLevel     string       `json:"alert_level"`
+	Utilisation    float64      `json:"utilisation"`
+	Exposure       money.Amount `json:"exposure"`
+	CreditLimit    money.Amount `json:"credit_limit"`
+	AcknowledgedBy *int         `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time   `json:"acknowledged_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// CreditAlertFilter 信用額度警示查詢條件
+type CreditAlertFilter struct {
+	OwnerType    string
+	OwnerID      int
+	Level        string
+	Acknowledged *bool
+	Page         int
+	Limit        int
+}
+
+// ChipPurchase 向總公司購買籌碼
+type ChipPurchase struct {
+	Currency    string
+	Amount      money.Amount
+	Payment     string // prepaid 或 credit
+	Description string
+	OperatorID  *int
+}
+
+// AgentPlayerTransfer 代理商或經銷商與玩家之間的籌碼移轉（deposit 為轉給玩家，withdrawal 為自玩家收回）
+type AgentPlayerTransfer struct {
+	PlayerID    int64
+	Currency    string
+	Amount      money.Amount
+	Direction   string
+	Description string
+	OperatorID  *int
+}
+
+// AgentPlayerTransferResult 籌碼移轉結果（兩邊的異動共用同一張傳票）
+type AgentPlayerTransferResult struct {
+	AgentTransaction  *AgentWalletTransaction `json:"agent_transaction"`
+	PlayerTransaction *WalletTransaction      `json:"player_transaction"`
+}
+
+// AgentWalletService 代理商與經銷商的籌碼錢包與信用額度
+//
+// 代理商與經銷商向總公司以現金（預付）或信用額度（賒帳）購買籌碼，再直接為所屬玩家儲值或收回籌碼；
+// 所有異動與玩家錢包一樣在同一交易內過帳總帳。信用額度由上而下限制：
+// 下級的額度不可超過上級，賒帳時自身與每一層上級代理商（不含總公司）的 Exposure 都不可超過其額度。
+// 結算支付時佣金先抵扣已使用的額度，使用率向上跨越門檻時產生警示。
+type AgentWalletService struct {
+	DB            *sql.DB
+	Ledger        *LedgerService
+	Wallet        *WalletService
+	WarningRatio  float64
+	CriticalRatio float64
+}
+
+// NewAgentWalletService 建立新的代理商錢包服務
+func NewAgentWalletService() *AgentWalletService {
+	wallet := NewWalletService()
+	s := &AgentWalletService{
+		DB:            config.GetDB(),
+		Ledger:        wallet.Ledger,
+		Wallet:        wallet,
+		WarningRatio:  0.8,
+		CriticalRatio: 0.95,
+	}
+	if config.AppConfig != nil {
+		if r := config.AppConfig.Agent.CreditWarningRatio; r > 0 {
+			s.WarningRatio = r
+		}
+		if r := config.AppConfig.Agent.CreditCriticalRatio; r > 0 {
+			s.CriticalRatio = r
+		}
+	}
+	return s
+}
+
+// walletOwner 錢包擁有者
+type walletOwner struct {
+	Type string
+	ID   int
+}
+
+// Wallets 代理商或經銷商各幣別的錢包
+func (s *AgentWalletService) Wallets(ownerType string, id int) ([]AgentWallet, error) {
+	o, err := s.owner(s.DB, ownerType, id, false)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query(agentWalletSelect+" WHERE owner_type = ? AND owner_id = ? ORDER BY currency", o.Type, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	list := []AgentWallet{}
+	for rows.Next() {
+		w, err := scanAgentWallet(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, *w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if err := fillExposure(s.DB, &list[i], false); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// SetCreditLimit 設定信用額度（不可超過上級代理商的額度，也不可低於直屬下級的額度）
+//
+// 額度可調降至已使用的額度以下：之後不能再賒帳，使用率超過門檻時產生警示。
+func (s *AgentWalletService) SetCreditLimit(ownerType string, id int, currency string, limit money.Amount) (*AgentWallet, error) {
+	if limit.IsNegative() {
+		return nil, ErrInvalidCreditLimit
+	}
+	currency = s.Wallet.CurrencyOf(currency)
+	var result *AgentWallet
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		o, err := s.owner(tx, ownerType, id, false)
+		if err != nil {
+			return err
+		}
+		ancestors, w, err := s.lockCreditChainTx(tx, o, currency)
+		if err != nil {
+			return err
+		}
+		if n := len(ancestors); n > 0 && limit > ancestors[n-1].CreditLimit {
+			return ErrCreditLimitAboveParent
+		}
+		if o.Type == NodeAgent {
+			var children money.Amount
+			if err := tx.QueryRow(`
+				SELECT COALESCE(MAX(w.credit_limit), 0) FROM agent_wallets w
+				WHERE w.currency = ? AND (
+					(w.owner_type = 'agent' AND w.owner_id IN (SELECT id FROM agents WHERE parent_agent_id = ?))
+					OR (w.owner_type = 'dealer' AND w.owner_id IN (SELECT id FROM dealers WHERE agent_id = ?)))
+				FOR SHARE
+			`, currency, o.ID, o.ID).Scan(&children); err != nil {
+				return err
+			}
+			if limit < children {
+				return ErrCreditLimitBelowChildren
+			}
+		}
+		if _, err := tx.Exec("UPDATE agent_wallets SET credit_limit = ? WHERE id = ?", limit, w.id); err != nil {
+			return err
+		}
+		w.CreditLimit = limit
+		if err := s.refreshAlertsTx(tx, append(ancestors, w)); err != nil {
+			return err
+		}
+		result = w
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PurchaseChipsTx 在呼叫端交易內向總公司購買籌碼（賒帳時檢查自身與所有上級代理商的信用額度）
+func (s *AgentWalletService) PurchaseChipsTx(tx *sql.Tx, ownerType string, id int, p ChipPurchase) (*AgentWalletTransaction, error) {
+	if !p.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	if p.Payment != ChipPrepaid && p.Payment != ChipCredit {
+		return nil, ErrChipPayment
+	}
+	o, err := s.owner(tx, ownerType, id, true)
+	if err != nil {
+		return nil, err
+	}
+	currency := s.Wallet.CurrencyOf(p.Currency)
+
+	var (
+		ancestors []*AgentWallet
+		w         *AgentWallet
+	)
+	if p.Payment == ChipCredit {
+		if ancestors, w, err = s.lockCreditChainTx(tx, o, currency); err != nil {
+			return nil, err
+		}
+		for _, n := range append(ancestors, w) {
+			if err := fillExposure(tx, n, true); err != nil {
+				return nil, err
+			}
+			if n.Exposure+p.Amount > n.CreditLimit {
+				if n != w {
+					return nil, fmt.Errorf("%w（上級代理商 %d 剩餘額度 %s）", ErrCreditLimitExceeded, n.OwnerID, n.AvailableCredit)
+				}
+				return nil, fmt.Errorf("%w（剩餘額度 %s）", ErrCreditLimitExceeded, n.AvailableCredit)
+			}
+		}
+	} else if w, err = lockAgentWalletTx(tx, o, currency); err != nil {
+		return nil, err
+	}
+
+	t := &AgentWalletTransaction{
+		OwnerType:        o.Type,
+		OwnerID:          o.ID,
+		Currency:         currency,
+		Type:             AgentTxChipPurchase,
+		Amount:           p.Amount,
+		BalanceBefore:    w.Balance,
+		BalanceAfter:     w.Balance + p.Amount,
+		CreditUsedBefore: w.CreditUsed,
+		CreditUsedAfter:  w.CreditUsed,
+		Description:      p.Description,
+		OperatorID:       p.OperatorID,
+	}
+	counter := HouseAccount(HouseCash, currency)
+	if p.Payment == ChipCredit {
+		t.Type = AgentTxCreditDraw
+		t.CreditUsedAfter += p.Amount
+		counter = HouseAccount(HouseCredit, currency)
+	}
+	if err := s.applyTx(tx, w, t, []LedgerLine{
+		{Account: ownerAccount(o, currency), Amount: p.Amount},
+		{Account: counter, Amount: -p.Amount},
+	}); err != nil {
+		return nil, err
+	}
+	if p.Payment == ChipCredit {
+		if err := s.refreshAlertsTx(tx, append(ancestors, w)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// RepayCreditTx 在呼叫端交易內以現金償還已使用的信用額度（不影響持有的籌碼）
+func (s *AgentWalletService) RepayCreditTx(tx *sql.Tx, ownerType string, id int, currency string, amount money.Amount, description string, operatorID *int) (*AgentWalletTransaction, error) {
+	if !amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	o, err := s.owner(tx, ownerType, id, false)
+	if err != nil {
+		return nil, err
+	}
+	currency = s.Wallet.CurrencyOf(currency)
+	ancestors, w, err := s.lockCreditChainTx(tx, o, currency)
+	if err != nil {
+		return nil, err
+	}
+	if amount > w.CreditUsed {
+		return nil, ErrRepaymentExceedsCredit
+	}
+	t := &AgentWalletTransaction{
+		OwnerType:        o.Type,
+		OwnerID:          o.ID,
+		Currency:         currency,
+		Type:             AgentTxCreditRepayment,
+		Amount:           amount,
+		BalanceBefore:    w.Balance,
+		BalanceAfter:     w.Balance,
+		CreditUsedBefore: w.CreditUsed,
+		CreditUsedAfter:  w.CreditUsed - amount,
+		Description:      description,
+		OperatorID:       operatorID,
+	}
+	if err := s.applyTx(tx, w, t, []LedgerLine{
+		{Account: HouseAccount(HouseCredit, currency), Amount: amount},
+		{Account: HouseAccount(HouseCash, currency), Amount: -amount},
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.refreshAlertsTx(tx, append(ancestors, w)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// TransferPlayerTx 在呼叫端交易內將籌碼轉給所屬玩家或自玩家收回
+//
+// 代理商只能移轉直屬玩家（不含經銷商的玩家）。玩家端寫入 deposit 或 withdrawal 交易，
+// 傳票的對方帳戶為代理商或經銷商帳戶，兩邊的餘額異動由同一張傳票記錄。
+func (s *AgentWalletService) TransferPlayerTx(tx *sql.Tx, ownerType string, id int, p AgentPlayerTransfer) (*AgentPlayerTransferResult, error) {
+	if !p.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	if p.Direction != "deposit" && p.Direction != "withdrawal" {
+		return nil, ErrTransferDirection
+	}
+	o, err := s.owner(tx, ownerType, id, true)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		status            string
+		agentID, dealerID sql.NullInt64
+	)
+	err = tx.QueryRow("SELECT status, agent_id, dealer_id FROM players WHERE id = ?", p.PlayerID).Scan(&status, &agentID, &dealerID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransferPlayerNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	owned := dealerID.Valid && int(dealerID.Int64) == o.ID
+	if o.Type == NodeAgent {
+		owned = !dealerID.Valid && agentID.Valid && int(agentID.Int64) == o.ID
+	}
+	if !owned {
+		return nil, ErrPlayerNotOwned
+	}
+	if status == "deleted" || (p.Direction == "withdrawal" && status == "suspended") {
+		return nil, ErrPlayerNotTransferable
+	}
+
+	currency := s.Wallet.CurrencyOf(p.Currency)
+	w, err := lockAgentWalletTx(tx, o, currency)
+	if err != nil {
+		return nil, err
+	}
+	t := &AgentWalletTransaction{
+		TransactionID:    newAgentWalletTxID(),
+		OwnerType:        o.Type,
+		OwnerID:          o.ID,
+		Currency:         currency,
+		Amount:           p.Amount,
+		BalanceBefore:    w.Balance,
+		CreditUsedBefore: w.CreditUsed,
+		CreditUsedAfter:  w.CreditUsed,
+		PlayerID:         &p.PlayerID,
+		Description:      p.Description,
+		OperatorID:       p.OperatorID,
+	}
+	account := ownerAccount(o, currency)
+	entry := WalletEntry{
+		PlayerID:      p.PlayerID,
+		Currency:      currency,
+		Type:          p.Direction,
+		Amount:        p.Amount,
+		ReferenceID:   t.TransactionID,
+		ReferenceType: o.Type + "_transfer",
+		Description:   p.Description,
+		OperatorID:    p.OperatorID,
+		Counterparty:  &account,
+	}
+	var player *WalletTransaction
+	if p.Direction == "deposit" {
+		if w.Balance < p.Amount {
+			return nil, ErrInsufficientChips
+		}
+		t.Type, t.BalanceAfter = AgentTxPlayerDeposit, w.Balance-p.Amount
+		player, err = s.Wallet.CreditTx(tx, entry)
+	} else {
+		t.Type, t.BalanceAfter = AgentTxPlayerWithdrawal, w.Balance+p.Amount
+		player, err = s.Wallet.DebitTx(tx, entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.PlayerTransactionID, t.JournalCode = player.TransactionID, player.JournalCode
+	if err := s.applyTx(tx, w, t, nil); err != nil {
+		return nil, err
+	}
+	return &AgentPlayerTransferResult{AgentTransaction: t, PlayerTransaction: player}, nil
+}
+
+// NetSettlementTx 在結算支付的交易內以佣金抵扣對象在結算幣別已使用的信用額度，回傳抵扣金額
+func (s *AgentWalletService) NetSettlementTx(tx *sql.Tx, st *Settlement, payout money.Amount) (money.Amount, error) {
+	if !payout.IsPositive() {
+		return 0, nil
+	}
+	var used money.Amount
+	err := tx.QueryRow(`
+		SELECT credit_used FROM agent_wallets WHERE owner_type = ? AND owner_id = ? AND currency = ?
+	`, st.TargetType, st.TargetID, st.Currency).Scan(&used)
+	if err == sql.ErrNoRows || err == nil && !used.IsPositive() {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	o := &walletOwner{Type: st.TargetType, ID: st.TargetID}
+	ancestors, w, err := s.lockCreditChainTx(tx, o, st.Currency)
+	if err != nil {
+		return 0, err
+	}
+	netted := payout
+	if w.CreditUsed < netted {
+		netted = w.CreditUsed
+	}
+	if !netted.IsPositive() {
+		return 0, nil
+	}
+	t := &AgentWalletTransaction{
+		OwnerType:        o.Type,
+		OwnerID:          o.ID,
+		Currency:         st.Currency,
+		Type:             AgentTxSettlementNetting,
+		Amount:           netted,
+		BalanceBefore:    w.Balance,
+		BalanceAfter:     w.Balance,
+		CreditUsedBefore: w.CreditUsed,
+		CreditUsedAfter:  w.CreditUsed - netted,
+		SettlementID:     st.SettlementID,
+		Description:      "結算 " + st.SettlementID + " 佣金抵扣信用額度",
+	}
+	if err := s.applyTx(tx, w, t, []LedgerLine{
+		{Account: HouseAccount(HouseCredit, st.Currency), Amount: netted},
+		{Account: HouseAccount(HouseCommission, st.Currency), Amount: -netted},
+	}); err != nil {
+		return 0, err
+	}
+	if err := s.refreshAlertsTx(tx, append(ancestors, w)); err != nil {
+		return 0, err
+	}
+	return netted, nil
+}
+
+// Transactions 依條件列出錢包異動，回傳資料與總筆數
+func (s *AgentWalletService) Transactions(f AgentWalletFilter) ([]AgentWalletTransaction, int64, error) {
+	if _, err := s.owner(s.DB, f.OwnerType, f.OwnerID, false); err != nil {
+		return nil, 0, err
+	}
+	where := " WHERE owner_type = ? AND owner_id = ?"
+	args := []interface{}{f.OwnerType, f.OwnerID}
+	if f.Currency != "" {
+		where += " AND currency = ?"
+		args = append(args, f.Currency)
+	}
+	if f.Type != "" {
+		where += " AND transaction_type = ?"
+		args = append(args, f.Type)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM agent_wallet_transactions"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(`
+		SELECT id, transaction_id, owner_type, owner_id, currency, transaction_type, amount, balance_before, balance_after,
+		       credit_used_before, credit_used_after, player_id, COALESCE(player_transaction_id, ''),
+		       COALESCE(settlement_id, ''), journal_code, COALESCE(description, ''), operator_id, created_at
+		FROM agent_wallet_transactions`+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []AgentWalletTransaction{}
+	for rows.Next() {
+		var (
+			t          AgentWalletTransaction
+			playerID   sql.NullInt64
+			operatorID sql.NullInt64
+		)
+		if err := rows.Scan(&t.ID, &t.TransactionID, &t.OwnerType, &t.OwnerID, &t.Currency, &t.Type, &t.Amount,
+			&t.BalanceBefore, &t.BalanceAfter, &t.CreditUsedBefore, &t.CreditUsedAfter, &playerID,
+			&t.PlayerTransactionID, &t.SettlementID, &t.JournalCode, &t.Description, &operatorID, &t.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if playerID.Valid {
+			t.PlayerID = &playerID.Int64
+		}
+		t.OperatorID = nullInt(operatorID)
+		list = append(list, t)
+	}
+	return list, total, rows.Err()
+}
+
+// Alerts 依條件列出信用額度警示（由新到舊），回傳資料與總筆數
+func (s *AgentWalletService) Alerts(f CreditAlertFilter) ([]CreditAlert, int64, error) {
+	where := " WHERE 1 = 1"
+	args := []interface{}{}
+	if f.OwnerType != "" {
+		where += " AND owner_type = ?"
+		args = append(args, f.OwnerType)
+	}
+	if f.OwnerID > 0 {
+		where += " AND owner_id = ?"
+		args = append(args, f.OwnerID)
+	}
+	if f.Level != "" {
+		where += " AND alert_level = ?"
+		args = append(args, f.Level)
+	}
+	if f.Acknowledged != nil {
+		if *f.Acknowledged {
+			where += " AND acknowledged_at IS NOT NULL"
+		} else {
+			where += " AND acknowledged_at IS NULL"
+		}
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM agent_credit_alerts"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(creditAlertSelect+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []CreditAlert{}
+	for rows.Next() {
+		a, err := scanCreditAlert(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *a)
+	}
+	return list, total, rows.Err()
+}
+
+// AcknowledgeAlert 確認信用額度警示（已確認的警示不再變更）
+func (s *AgentWalletService) AcknowledgeAlert(id int64, operatorID int) (*CreditAlert, error) {
+	if _, err := s.DB.Exec(`
+		UPDATE agent_credit_alerts SET acknowledged_by = ?, acknowledged_at = NOW()
+		WHERE id = ? AND acknowledged_at IS NULL
+	`, operatorID, id); err != nil {
+		return nil, err
+	}
+	a, err := scanCreditAlert(s.DB.QueryRow(creditAlertSelect+" WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCreditAlertNotFound
+	}
+	return a, err
+}
+
+// owner 檢查錢包擁有者存在且不是總公司；operable 為 true 時另需為可營運狀態（購買籌碼與移轉玩家）
+func (s *AgentWalletService) owner(q queryer, ownerType string, id int, operable bool) (*walletOwner, error) {
+	switch ownerType {
+	case NodeAgent:
+		a, err := loadAgent(q, "a.id = ?", id)
+		if err != nil {
+			return nil, err
+		}
+		if a.ParentAgentID == nil {
+			return nil, ErrCompanyWallet
+		}
+		if operable {
+			if err := a.operable(); err != nil {
+				return nil, err
+			}
+		}
+	case NodeDealer:
+		d, err := loadDealer(q, "d.id = ?", id)
+		if err != nil {
+			return nil, err
+		}
+		if operable {
+			if err := d.operable(); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, ErrAgentWalletOwner
+	}
+	return &walletOwner{Type: ownerType, ID: id}, nil
+}
+
+// lockCreditChainTx 由上而下鎖定所有上級代理商（不含總公司）與自身的錢包，回傳上級錢包（最後一個為直屬上級）與自身錢包
+//
+// 所有會變動額度或已使用額度的操作都以相同順序鎖定，同一條上級鏈上的操作依序執行而不會互相死結。
+func (s *AgentWalletService) lockCreditChainTx(tx *sql.Tx, o *walletOwner, currency string) ([]*AgentWallet, *AgentWallet, error) {
+	rows, err := tx.Query(`
+		SELECT h.ancestor_id FROM agent_hierarchy h
+		JOIN agents a ON a.id = h.ancestor_id
+		WHERE h.descendant_type = ? AND h.descendant_id = ? AND h.level_difference > 0 AND a.parent_agent_id IS NOT NULL
+		ORDER BY h.level_difference DESC
+	`, o.Type, o.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	ancestors := make([]*AgentWallet, 0, len(ids))
+	for _, id := range ids {
+		w, err := lockAgentWalletTx(tx, &walletOwner{Type: NodeAgent, ID: id}, currency)
+		if err != nil {
+			return nil, nil, err
+		}
+		ancestors = append(ancestors, w)
+	}
+	w, err := lockAgentWalletTx(tx, o, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ancestors, w, nil
+}
+
+// fillExposure 計算錢包的 Exposure、剩餘額度與使用率（代理商含所有下級；locking 為 true 時以共享鎖讀取最新值）
+func fillExposure(q queryer, w *AgentWallet, locking bool) error {
+	w.Exposure = w.CreditUsed
+	if w.OwnerType == NodeAgent {
+		query := `
+			SELECT COALESCE(SUM(w.credit_used), 0) FROM agent_wallets w
+			JOIN agent_hierarchy h ON h.descendant_type = w.owner_type AND h.descendant_id = w.owner_id
+			WHERE h.ancestor_id = ? AND w.currency = ?`
+		if locking {
+			query += " FOR SHARE"
+		}
+		if err := q.QueryRow(query, w.OwnerID, w.Currency).Scan(&w.Exposure); err != nil {
+			return err
+		}
+	}
+	w.AvailableCredit = w.CreditLimit - w.Exposure
+	if w.AvailableCredit.IsNegative() {
+		w.AvailableCredit = 0
+	}
+	w.Utilisation = utilisation(w.Exposure, w.CreditLimit)
+	return nil
+}
+
+// refreshAlertsTx 重新計算已鎖定錢包的使用率；警示等級升高時產生警示，降低時只更新等級
+func (s *AgentWalletService) refreshAlertsTx(tx *sql.Tx, wallets []*AgentWallet) error {
+	for _, w := range wallets {
+		if err := fillExposure(tx, w, true); err != nil {
+			return err
+		}
+		level := s.alertLevel(w)
+		if level == w.AlertLevel {
+			continue
+		}
+		if alertRank(level) > alertRank(w.AlertLevel) {
+			if _, err := tx.Exec(`
+				INSERT INTO agent_credit_alerts (owner_type, owner_id, currency, alert_level, utilisation, exposure, credit_limit)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, w.OwnerType, w.OwnerID, w.Currency, level, math.Min(w.Utilisation, 999), w.Exposure, w.CreditLimit); err != nil {
+				return err
+			}
+			log.Printf("%s %d 信用額度使用率 %.2f%%（%s）", w.OwnerType, w.OwnerID, w.Utilisation*100, level)
+		}
+		if _, err := tx.Exec("UPDATE agent_wallets SET alert_level = ? WHERE id = ?", level, w.id); err != nil {
+			return err
+		}
+		w.AlertLevel = level
+	}
+	return nil
+}
+
+// alertLevel 依使用率判斷警示等級（沒有額度卻有使用額度時為嚴重）
+func (s *AgentWalletService) alertLevel(w *AgentWallet) string {
+	switch {
+	case w.Exposure.IsPositive() && w.Utilisation >= s.CriticalRatio:
+		return CreditAlertCritical
+	case w.Exposure.IsPositive() && w.Utilisation >= s.WarningRatio:
+		return CreditAlertWarning
+	default:
+		return CreditAlertNone
+	}
+}
+
+// applyTx 更新已鎖定錢包的籌碼與已使用額度，lines 不為 nil 時過帳傳票，並寫入異動記錄
+func (s *AgentWalletService) applyTx(tx *sql.Tx, w *AgentWallet, t *AgentWalletTransaction, lines []LedgerLine) error {
+	if t.TransactionID == "" {
+		t.TransactionID = newAgentWalletTxID()
+	}
+	if lines != nil {
+		j := &Journal{
+			Type:          t.Type,
+			Currency:      t.Currency,
+			TransactionID: t.TransactionID,
+			Description:   t.Description,
+			OperatorID:    t.OperatorID,
+			Lines:         lines,
+		}
+		if err := s.Ledger.PostTx(tx, j); err != nil {
+			return err
+		}
+		t.JournalCode = j.JournalCode
+	}
+	if _, err := tx.Exec(`
+		UPDATE agent_wallets SET balance = ?, credit_used = ? WHERE id = ?
+	`, t.BalanceAfter, t.CreditUsedAfter, w.id); err != nil {
+		return err
+	}
+	w.Balance, w.CreditUsed = t.BalanceAfter, t.CreditUsedAfter
+	if r := []rune(t.Description); len(r) > 255 {
+		t.Description = string(r[:255])
+	}
+	res, err := tx.Exec(`
+		INSERT INTO agent_wallet_transactions
+			(transaction_id, owner_type, owner_id, currency, transaction_type, amount, balance_before, balance_after,
+			 credit_used_before, credit_used_after, player_id, player_transaction_id, settlement_id, journal_code,
+			 description, operator_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.TransactionID, t.OwnerType, t.OwnerID, t.Currency, t.Type, t.Amount, t.BalanceBefore, t.BalanceAfter,
+		t.CreditUsedBefore, t.CreditUsedAfter, t.PlayerID, nullString(t.PlayerTransactionID), nullString(t.SettlementID),
+		t.JournalCode, nullString(t.Description), t.OperatorID)
+	if err != nil {
+		return fmt.Errorf("寫入代理商錢包異動失敗: %v", err)
+	}
+	if t.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	t.CreatedAt = time.Now()
+	return nil
+}
+
+// lockAgentWalletTx 鎖定錢包列（不存在時建立額度為 0 的錢包）
+func lockAgentWalletTx(tx *sql.Tx, o *walletOwner, currency string) (*AgentWallet, error) {
+	if _, err := tx.Exec(`
+		INSERT IGNORE INTO agent_wallets (owner_type, owner_id, currency) VALUES (?, ?, ?)
+	`, o.Type, o.ID, currency); err != nil {
+		return nil, fmt.Errorf("建立代理商錢包失敗: %v", err)
+	}
+	return scanAgentWallet(tx.QueryRow(agentWalletSelect+`
+		WHERE owner_type = ? AND owner_id = ? AND currency = ? FOR UPDATE
+	`, o.Type, o.ID, currency))
+}
+
+// ownerAccount 代理商或經銷商的總帳帳戶
+func ownerAccount(o *walletOwner, currency string) LedgerAccount {
+	if o.Type == NodeDealer {
+		return DealerAccount(int64(o.ID), currency)
+	}
+	return AgentAccount(int64(o.ID), currency)
+}
+
+// utilisation 使用率（四位小數；沒有額度卻有使用額度時為 1）
+func utilisation(exposure, limit money.Amount) float64 {
+	if !limit.IsPositive() {
+		if exposure.IsPositive() {
+			return 1
+		}
+		return 0
+	}
+	return math.Round(exposure.Float64()/limit.Float64()*10000) / 10000
+}
+
+func alertRank(level string) int {
+	switch level {
+	case CreditAlertCritical:
+		return 2
+	case CreditAlertWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// newAgentWalletTxID 產生代理商錢包異動流水號
+func newAgentWalletTxID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "AW" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
+
+const agentWalletSelect = `
+	SELECT id, owner_type, owner_id, currency, balance, credit_limit, credit_used, alert_level, updated_at
+	FROM agent_wallets`
+
+func scanAgentWallet(row rowScanner) (*AgentWallet, error) {
+	w := &AgentWallet{}
+	if err := row.Scan(&w.id, &w.OwnerType, &w.OwnerID, &w.Currency, &w.Balance, &w.CreditLimit, &w.CreditUsed,
+		&w.AlertLevel, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Exposure = w.CreditUsed
+	return w, nil
+}
+
+const creditAlertSelect = `
+	SELECT id, owner_type, owner_id, currency, alert_level, utilisation, exposure, credit_limit,
+	       acknowledged_by, acknowledged_at, created_at
+	FROM agent_credit_alerts`
+
+func scanCreditAlert(row rowScanner) (*CreditAlert, error) {
+	var (
+		a              CreditAlert
+		acknowledgedBy sql.NullInt64
+		acknowledgedAt sql.NullTime
+	)
+	if err := row.Scan(&a.ID, &a.OwnerType, &a.OwnerID, &a.Currency, &a.AlertLevel, &a.Utilisation, &a.Exposure,
+		&a.CreditLimit, &acknowledgedBy, &acknowledgedAt, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	a.AcknowledgedBy, a.AcknowledgedAt = nullInt(acknowledgedBy), nullTime(acknowledgedAt)
+	return &a, nil
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"nexus-gaming-backend/ai"
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/rng"
+)
+
+// AI 玩家相關錯誤
+var (
+	ErrAIDisabled      = errors.New("此房間未啟用 AI 玩家")
+	ErrAIUnsupported   = errors.New("此遊戲類型不支援 AI 玩家")
+	ErrSessionFinished = errors.New("場次已結束")
+)
+
+// aiStrategyKey game_configs 中的 AI 策略設定鍵
+const aiStrategyKey = "ai_strategy"
+
+// aiBuyInBigBlinds AI 玩家入座籌碼（以最低下注的倍數計）
+const aiBuyInBigBlinds = 100
+
+// botNames AI 玩家顯示名稱
+var botNames = []string{"阿豪", "小美", "老K", "阿傑", "小雨", "大衛", "阿明", "小芳", "阿龍"}
+
+// BotSeat AI 玩家座位資訊（存於 game_sessions.ai_players）
+type BotSeat struct {
+	BotID      string        `json:"bot_id"`
+	Seat       int           `json:"seat"`
+	Name       string        `json:"name"`
+	Difficulty ai.Difficulty `json:"difficulty"`
+	Strategy   string        `json:"strategy"`
+	Seed       int64         `json:"seed"`
+	Chips      float64       `json:"chips"`
+}
+
+// BotResult AI 玩家單場結果
+type BotResult struct {
+	BotID        string        `json:"bot_id"`
+	Seat         int           `json:"seat"`
+	Difficulty   ai.Difficulty `json:"difficulty"`
+	Strategy     string        `json:"strategy"`
+	InitialChips float64       `json:"initial_chips"`
+	FinalChips   float64       `json:"final_chips"`
+	TotalBet     float64       `json:"total_bet"`
+	TotalWin     float64       `json:"total_win"`
+	HandCategory string        `json:"hand_category,omitempty"`
+	Folded       bool          `json:"folded"`
+}
+
+// AIService AI 玩家服務：補位、建立 Bot 與記錄結果
+type AIService struct {
+	DB *sql.DB
+}
+
+// NewAIService 建立新的 AI 玩家服務
+func NewAIService() *AIService {
+	return &AIService{
+		DB: config.GetDB(),
+	}
+}
+
+// LoadParams 讀取遊戲的 ai_strategy 設定（未設定時使用預設值）
+func (s *AIService) LoadParams(gameID int) (ai.Params, error) {
+	var raw []byte
+	err := s.DB.QueryRow(`
+		SELECT config_value FROM game_configs
+		WHERE game_id = ? AND config_key = ? AND is_active = TRUE
+	`, gameID, aiStrategyKey).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return ai.DefaultParams, nil
+	} else if err != nil {
+		return ai.DefaultParams, err
+	}
+	return ai.ParseParams(raw)
+}
+
+// FillSeats 以 AI 玩家補滿場次的空位，回傳目前所有 AI 座位
+//
+// 真人玩家的座位來自 game_participations；已入座的 AI 玩家保留不變。
+func (s *AIService) FillSeats(sessionID int64) ([]BotSeat, error) {
+	if config.AppConfig != nil && !config.AppConfig.Game.AIEnabled {
+		return nil, ErrAIDisabled
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		maxPlayers       int
+		minBet           float64
+		status, gameType string
+		aiEnabled        bool
+		difficulty       string
+		aiPlayers        sql.NullString
+	)
+	err = tx.QueryRow(`
+		SELECT gs.max_players, gs.min_bet, gs.status, g.game_type,
+		       gr.ai_enabled, gr.ai_difficulty, gs.ai_players
+		FROM game_sessions gs
+		JOIN game_rooms gr ON gs.room_id = gr.id
+		JOIN games g ON gs.game_id = g.id
+		WHERE gs.id = ?
+		FOR UPDATE
+	`, sessionID).Scan(&maxPlayers, &minBet, &status, &gameType, &aiEnabled, &difficulty, &aiPlayers)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !aiEnabled {
+		return nil, ErrAIDisabled
+	}
+	if gameType != ai.GameTexasHoldem && gameType != ai.GameStudPoker {
+		return nil, ErrAIUnsupported
+	}
+	if status == "finished" || status == "cancelled" {
+		return nil, ErrSessionFinished
+	}
+
+	seats, err := parseBotSeats(aiPlayers)
+	if err != nil {
+		return nil, err
+	}
+
+	occupied := make(map[int]bool, maxPlayers)
+	for _, b := range seats {
+		occupied[b.Seat] = true
+	}
+	rows, err := tx.Query(`
+		SELECT seat_number FROM game_participations
+		WHERE session_id = ? AND status = 'playing' AND seat_number IS NOT NULL
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var seat int
+		if err := rows.Scan(&seat); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		occupied[seat] = true
+	}
+	rows.Close()
+
+	for seat := 1; seat <= maxPlayers; seat++ {
+		if occupied[seat] {
+			continue
+		}
+		seats = append(seats, s.newBotSeat(sessionID, seat, ai.Difficulty(difficulty), minBet))
+	}
+
+	if err := saveBotSeats(tx, sessionID, seats); err != nil {
+		return nil, err
+	}
+	return seats, tx.Commit()
+}
+
+// VacateSeat 讓出 AI 座位（真人玩家入座時呼叫）
+func (s *AIService) VacateSeat(sessionID int64, seat int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var aiPlayers sql.NullString
+	err = tx.QueryRow("SELECT ai_players FROM game_sessions WHERE id = ? FOR UPDATE", sessionID).Scan(&aiPlayers)
+	if err == sql.ErrNoRows {
+		return ErrSessionNotFound
+	} else if err != nil {
+		return err
+	}
+	seats, err := parseBotSeats(aiPlayers)
+	if err != nil {
+		return err
+	}
+
+	kept := seats[:0]
+	for _, b := range seats {
+		if b.Seat != seat {
+			kept = append(kept, b)
+		}
+	}
+	if err := saveBotSeats(tx, sessionID, kept); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Bots 依座位資訊建立 Bot 實例（以座位號為鍵）
+func (s *AIService) Bots(seats []BotSeat, params ai.Params) (map[int]ai.Bot, error) {
+	bots := make(map[int]ai.Bot, len(seats))
+	for _, b := range seats {
+		bot, err := ai.New(b.Difficulty, params, b.Seed)
+		if err != nil {
+			return nil, err
+		}
+		bots[b.Seat] = bot
+	}
+	return bots, nil
+}
+
+// RecordResults 記錄 AI 玩家的場次結果（獨立於 game_participations，不進入玩家分析）
+func (s *AIService) RecordResults(sessionID int64, results []BotResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO ai_game_results
+			(session_id, bot_id, seat_number, difficulty, strategy, initial_chips,
+			 final_chips, total_bet, total_win, hand_category, folded)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+		ON DUPLICATE KEY UPDATE
+			final_chips = VALUES(final_chips),
+			total_bet = VALUES(total_bet),
+			total_win = VALUES(total_win),
+			hand_category = VALUES(hand_category),
+			folded = VALUES(folded)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range results {
+		if _, err := stmt.Exec(sessionID, r.BotID, r.Seat, r.Difficulty, r.Strategy, r.InitialChips,
+			r.FinalChips, r.TotalBet, r.TotalWin, r.HandCategory, r.Folded); err != nil {
+			return fmt.Errorf("記錄 AI 玩家結果失敗: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// newBotSeat 建立新的 AI 座位
+func (s *AIService) newBotSeat(sessionID int64, seat int, difficulty ai.Difficulty, minBet float64) BotSeat {
+	strategy := ""
+	if bot, err := ai.New(difficulty, ai.DefaultParams, 0); err == nil {
+		strategy = bot.Strategy()
+	}
+	return BotSeat{
+		BotID:      fmt.Sprintf("bot-%d-%d", sessionID, seat),
+		Seat:       seat,
+		Name:       botNames[(int(sessionID)+seat)%len(botNames)],
+		Difficulty: difficulty,
+		Strategy:   strategy,
+		Seed:       int64(rng.SecureSource{}.Intn(math.MaxInt32)),
+		Chips:      minBet * aiBuyInBigBlinds,
+	}
+}
+
+func parseBotSeats(raw sql.NullString) ([]BotSeat, error) {
+	seats := []BotSeat{}
+	if !raw.Valid || raw.String == "" || raw.String == "null" {
+		return seats, nil
+	}
+	if err := json.Unmarshal([]byte(raw.String), &seats); err != nil {
+		return nil, fmt.Errorf("ai_players 資料格式錯誤: %v", err)
+	}
+	return seats, nil
+}
+
+func saveBotSeats(tx *sql.Tx, sessionID int64, seats []BotSeat) error {
+	data, err := json.Marshal(seats)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE game_sessions SET ai_players = ? WHERE id = ?", string(data), sessionID)
+	return err
+}
@@ -34,6 +34,20 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+// Token audience：後台與玩家 Token 使用同一把簽章金鑰，驗證時各自要求對應的 audience，
+// 玩家 Token 無法通過後台驗證，後台 Token 也無法當作玩家 Token 使用
+const (
+	backofficeAudience = "backoffice"
+	playerAudience     = "player"
+)
+
+// PlayerClaims 玩家 JWT 聲明（牌桌連線使用）
+type PlayerClaims struct {
+	PlayerID int64  `json:"player_id"`
+	Username string `json:"username"`
+	jwt.RegisteredClaims
+}
+
 // GenerateToken 生成 JWT Token
 func (s *AuthService) GenerateToken(user *models.User) (string, error) {
 	if user == nil {
@@ -60,6 +74,7 @@ func (s *AuthService) GenerateToken(user *models.User) (string, error) {
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "nexus-gaming",
 			Subject:   fmt.Sprintf("user-%d", user.ID),
+			Audience:  jwt.ClaimStrings{backofficeAudience},
 		},
 	}
 
@@ -88,7 +103,7 @@ func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 			return nil, fmt.Errorf("無效的簽名方法: %v", token.Header["alg"])
 		}
 		return []byte(s.JWTSecret), nil
-	})
+	}, jwt.WithAudience(backofficeAudience))
 
 	if err != nil {
 		return nil, fmt.Errorf("Token 解析失敗: %v", err)
@@ -303,3 +318,53 @@ func (s *AuthService) updateLastLogin(userID int) error {
 	_, err := s.DB.Exec(query, time.Now(), userID)
 	return err
 }
+
+// GeneratePlayerToken 生成玩家 Token（由營運端核發給遊戲客戶端）
+func (s *AuthService) GeneratePlayerToken(playerID int64, username string, ttl time.Duration) (string, error) {
+	if playerID <= 0 {
+		return "", errors.New("玩家ID無效")
+	}
+	now := time.Now()
+	claims := &PlayerClaims{
+		PlayerID: playerID,
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "nexus-gaming",
+			Subject:   fmt.Sprintf("player-%d", playerID),
+			Audience:  jwt.ClaimStrings{playerAudience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(s.JWTSecret))
+	if err != nil {
+		return "", fmt.Errorf("無法生成 Token: %v", err)
+	}
+	return tokenString, nil
+}
+
+// ValidatePlayerToken 驗證玩家 Token
+func (s *AuthService) ValidatePlayerToken(tokenString string) (*PlayerClaims, error) {
+	if tokenString == "" {
+		return nil, errors.New("Token 不能為空")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &PlayerClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("無效的簽名方法: %v", token.Header["alg"])
+		}
+		return []byte(s.JWTSecret), nil
+	}, jwt.WithAudience(playerAudience))
+	if err != nil {
+		return nil, fmt.Errorf("Token 解析失敗: %v", err)
+	}
+
+	claims, ok := token.Claims.(*PlayerClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("Token 無效")
+	}
+	return claims, nil
+}
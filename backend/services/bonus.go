@@ -0,0 +1,1249 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 紅利相關錯誤
+var (
+	ErrBonusNotFound    = errors.New("紅利不存在")
+	ErrBonusNotActive   = errors.New("紅利已完成、沒收或逾期")
+	ErrCampaignNotFound = errors.New("紅利活動不存在")
+	ErrCampaignInvalid  = errors.New("紅利活動需設定固定金額或儲值百分比")
+	ErrCampaignTier     = errors.New("會員等級活動需設定等級門檻")
+	ErrCampaignCode     = errors.New("活動代碼已存在")
+	ErrBonusDuplicate   = errors.New("此活動已對該玩家以相同來源發放過紅利")
+)
+
+// 紅利活動發放條件
+const (
+	BonusTriggerFirstDeposit = "first_deposit" // 玩家首次儲值完成
+	BonusTriggerReferral     = "referral"      // 被推薦玩家首次儲值完成時發給推薦人
+	BonusTriggerLoyaltyTier  = "loyalty_tier"  // 會員等級達到門檻
+	BonusTriggerManual       = "manual"        // 僅人工發放
+)
+
+// 紅利動用順序
+const (
+	BonusFirst = "bonus_first" // 先動用紅利再動用現金
+	CashFirst  = "cash_first"  // 現金不足時才動用紅利
+)
+
+// 紅利異動類型
+const (
+	BonusEventGrant   = "grant"
+	BonusEventStake   = "stake"
+	BonusEventReturn  = "return"
+	BonusEventWager   = "wager"
+	BonusEventRelease = "release"
+	BonusEventForfeit = "forfeit"
+	BonusEventExpire  = "expire"
+)
+
+// 紅利傳票類型（紅利餘額不在錢包內，異動只過帳總帳，不寫入 transactions）
+const (
+	journalBonusGrant   = "bonus_grant"
+	journalBonusStake   = "bonus_stake"
+	journalBonusReturn  = "bonus_return"
+	journalBonusForfeit = "bonus_forfeit"
+)
+
+// bonusSweepBatch 每次排程最多處理的逾期紅利或會員等級發放筆數
+const bonusSweepBatch = 200
+
+// BonusCampaign 紅利活動
+type BonusCampaign struct {
+	ID                  int           `json:"id"`
+	CampaignCode        string        `json:"campaign_code"`
+	Name                string        `json:"name"`
+	Description         string        `json:"description,omitempty"`
+	TriggerType         string        `json:"trigger_type"`
+	BonusType           string        `json:"bonus_type"`
+	Currency            string        `json:"currency"`
+	FixedAmount         money.Amount  `json:"fixed_amount"`
+	Percent             float64       `json:"percent"`
+	MaxAmount           *money.Amount `json:"max_amount,omitempty"`
+	MinDeposit          money.Amount  `json:"min_deposit"`
+	LoyaltyTier         *int          `json:"loyalty_tier,omitempty"`
+	WageringRequirement float64       `json:"wagering_requirement"`
+	ValidDays           int           `json:"valid_days"`
+	ConsumePolicy       string        `json:"consume_policy"`
+	StartsAt            *time.Time    `json:"starts_at,omitempty"`
+	EndsAt              *time.Time    `json:"ends_at,omitempty"`
+	Status              string        `json:"status"`
+	CreatedBy           *int          `json:"created_by,omitempty"`
+	CreatedAt           time.Time     `json:"created_at"`
+}
+
+// amountFor 依儲值金額計算發放金額（固定金額加儲值百分比，不超過上限）
+func (c *BonusCampaign) amountFor(base money.Amount) money.Amount {
+	amount := c.FixedAmount + money.FromFloat(base.Float64()*c.Percent/100)
+	if c.MaxAmount != nil && amount > *c.MaxAmount {
+		amount = *c.MaxAmount
+	}
+	return amount
+}
+
+// Bonus 玩家紅利
+type Bonus struct {
+	BonusID             string       `json:"bonus_id"`
+	PlayerID            int64        `json:"player_id"`
+	CampaignID          *int         `json:"campaign_id,omitempty"`
+	BonusType           string       `json:"bonus_type"`
+	Amount              money.Amount `json:"amount"`
+	Currency            string       `json:"currency"`
+	Balance             money.Amount `json:"balance"`
+	ConsumePolicy       string       `json:"consume_policy"`
+	WageringRequirement float64      `json:"wagering_requirement"`
+	WageredAmount       money.Amount `json:"wagered_amount"`
+	RemainingWagering   money.Amount `json:"remaining_wagering"`
+	ReleasedAmount      money.Amount `json:"released_amount"`
+	ForfeitedAmount     money.Amount `json:"forfeited_amount"`
+	Status              string       `json:"status"`
+	SourceReference     string       `json:"source_reference,omitempty"`
+	Description         string       `json:"description,omitempty"`
+	ValidFrom           time.Time    `json:"valid_from"`
+	ValidTo             *time.Time   `json:"valid_to,omitempty"`
+	CompletedAt         *time.Time   `json:"completed_at,omitempty"`
+	ForfeitedAt         *time.Time   `json:"forfeited_at,omitempty"`
+	GrantedBy           *int         `json:"granted_by,omitempty"`
+	CreatedAt           time.Time    `json:"created_at"`
+}
+
+// BonusEvent 紅利異動記錄
+type BonusEvent struct {
+	EventType     string       `json:"event_type"`
+	Amount        money.Amount `json:"amount"`
+	Wagered       money.Amount `json:"wagered"`
+	GameType      string       `json:"game_type,omitempty"`
+	ReferenceType string       `json:"reference_type,omitempty"`
+	ReferenceID   string       `json:"reference_id,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// GameContribution 遊戲類型的流水貢獻比例
+type GameContribution struct {
+	GameType        string  `json:"game_type"`
+	ContributionPct float64 `json:"contribution_pct"`
+}
+
+// BonusGrant 紅利發放請求
+type BonusGrant struct {
+	PlayerID            int64
+	CampaignID          *int
+	BonusType           string
+	Amount              money.Amount
+	Currency            string // 空字串表示預設幣別
+	WageringRequirement float64
+	ValidDays           int    // 0 表示不逾期
+	ConsumePolicy       string // 空字串表示預設動用順序
+	SourceReference     string // 同一活動、玩家與來源只發放一次
+	Description         string
+	GrantedBy           *int
+}
+
+// Wager 計入流水的下注
+type Wager struct {
+	PlayerID      int64
+	Currency      string
+	GameType      string
+	Amount        money.Amount
+	ReferenceType string
+	ReferenceID   string
+}
+
+// BonusStake 牌桌帶入時動用的紅利
+type BonusStake struct {
+	ID       int64
+	BonusID  string
+	Currency string
+	Amount   money.Amount
+}
+
+// BonusStakes 同一次帶入動用的全部紅利
+type BonusStakes []BonusStake
+
+// Total 動用的紅利合計
+func (st BonusStakes) Total() money.Amount {
+	var total money.Amount
+	for _, s := range st {
+		total += s.Amount
+	}
+	return total
+}
+
+// Split 依帶入時現金與各筆紅利的比例分配剩餘籌碼，回傳現金部分與各筆紅利應返還的金額
+//
+// 以分為單位無條件捨去，餘數歸現金；沒有現金帶入時餘數歸最後一筆紅利。
+func (st BonusStakes) Split(final, cash money.Amount) (money.Amount, []money.Amount) {
+	shares := make([]money.Amount, len(st))
+	total := cash + st.Total()
+	if !total.IsPositive() {
+		return final, shares
+	}
+	rest := final
+	for i, s := range st {
+		shares[i] = money.FromCents(final.Cents() * s.Amount.Cents() / total.Cents())
+		rest -= shares[i]
+	}
+	if !cash.IsPositive() && len(st) > 0 {
+		shares[len(st)-1] += rest
+		rest = 0
+	}
+	return rest, shares
+}
+
+// BonusFilter 紅利查詢條件
+type BonusFilter struct {
+	PlayerID int64
+	Status   string
+	Page     int
+	Limit    int
+}
+
+// BonusService 紅利引擎
+//
+// 紅利餘額與現金錢包分開記錄於各筆紅利，總帳以玩家紅利帳戶（bonus:<玩家>:<幣別>）對應；
+// 牌桌帶入依動用順序扣用紅利，離座時依比例返還，真錢牌局的下注額依遊戲貢獻比例計入流水，
+// 流水完成後剩餘紅利轉入現金錢包；逾期、人工取消或流水未完成即提領時沒收。
+// 鎖定順序一律為錢包列、紅利列。
+type BonusService struct {
+	DB                  *sql.DB
+	Wallet              *WalletService
+	Ledger              *LedgerService
+	ConsumePolicy       string
+	ForfeitOnWithdrawal bool
+}
+
+// NewBonusService 建立新的紅利服務
+func NewBonusService() *BonusService {
+	wallet := NewWalletService()
+	policy, forfeit := CashFirst, true
+	if config.AppConfig != nil {
+		if config.AppConfig.Bonus.ConsumePolicy == BonusFirst {
+			policy = BonusFirst
+		}
+		forfeit = config.AppConfig.Bonus.ForfeitOnWithdrawal
+	}
+	return &BonusService{
+		DB:                  wallet.DB,
+		Wallet:              wallet,
+		Ledger:              wallet.Ledger,
+		ConsumePolicy:       policy,
+		ForfeitOnWithdrawal: forfeit,
+	}
+}
+
+// Campaigns 列出紅利活動（status 為空時列出全部）
+func (s *BonusService) Campaigns(status string) ([]BonusCampaign, error) {
+	query := "SELECT " + campaignColumns + " FROM bonus_campaigns"
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	rows, err := s.DB.Query(query+" ORDER BY id DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []BonusCampaign{}
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, rows.Err()
+}
+
+// GetCampaign 取得紅利活動
+func (s *BonusService) GetCampaign(id int) (*BonusCampaign, error) {
+	c, err := scanCampaign(s.DB.QueryRow("SELECT "+campaignColumns+" FROM bonus_campaigns WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCampaignNotFound
+	}
+	return c, err
+}
+
+// SaveCampaign 建立（ID 為 0）或更新紅利活動；已發放的紅利不受活動修改影響
+func (s *BonusService) SaveCampaign(c *BonusCampaign) (*BonusCampaign, error) {
+	if !c.FixedAmount.IsPositive() && c.Percent <= 0 {
+		return nil, ErrCampaignInvalid
+	}
+	if c.TriggerType == BonusTriggerLoyaltyTier && c.LoyaltyTier == nil {
+		return nil, ErrCampaignTier
+	}
+	c.Currency = s.Wallet.CurrencyOf(c.Currency)
+	if c.ConsumePolicy == "" {
+		c.ConsumePolicy = s.ConsumePolicy
+	}
+	if c.Status == "" {
+		c.Status = "active"
+	}
+	args := []interface{}{c.Name, nullString(c.Description), c.TriggerType, c.BonusType, c.Currency,
+		c.FixedAmount, c.Percent, c.MaxAmount, c.MinDeposit, c.LoyaltyTier, c.WageringRequirement,
+		c.ValidDays, c.ConsumePolicy, c.StartsAt, c.EndsAt, c.Status}
+	if c.ID == 0 {
+		var exists int
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM bonus_campaigns WHERE campaign_code = ?", c.CampaignCode).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists > 0 {
+			return nil, ErrCampaignCode
+		}
+		res, err := s.DB.Exec(`
+			INSERT INTO bonus_campaigns
+				(name, description, trigger_type, bonus_type, currency, fixed_amount, percent, max_amount,
+				 min_deposit, loyalty_tier, wagering_requirement, valid_days, consume_policy, starts_at, ends_at,
+				 status, campaign_code, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, append(args, c.CampaignCode, c.CreatedBy)...)
+		if err != nil {
+			return nil, err
+		}
+		id, _ := res.LastInsertId()
+		return s.GetCampaign(int(id))
+	}
+	if _, err := s.DB.Exec(`
+		UPDATE bonus_campaigns
+		SET name = ?, description = ?, trigger_type = ?, bonus_type = ?, currency = ?, fixed_amount = ?,
+		    percent = ?, max_amount = ?, min_deposit = ?, loyalty_tier = ?, wagering_requirement = ?,
+		    valid_days = ?, consume_policy = ?, starts_at = ?, ends_at = ?, status = ?
+		WHERE id = ?
+	`, append(args, c.ID)...); err != nil {
+		return nil, err
+	}
+	return s.GetCampaign(c.ID)
+}
+
+// Contributions 列出各遊戲類型的流水貢獻比例
+func (s *BonusService) Contributions() ([]GameContribution, error) {
+	rows, err := s.DB.Query("SELECT game_type, contribution_pct FROM bonus_game_contributions ORDER BY game_type")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []GameContribution{}
+	for rows.Next() {
+		var g GameContribution
+		if err := rows.Scan(&g.GameType, &g.ContributionPct); err != nil {
+			return nil, err
+		}
+		list = append(list, g)
+	}
+	return list, rows.Err()
+}
+
+// SetContribution 設定遊戲類型的流水貢獻比例（0 表示不計入流水）
+func (s *BonusService) SetContribution(gameType string, pct float64, operatorID *int) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO bonus_game_contributions (game_type, contribution_pct, updated_by) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE contribution_pct = VALUES(contribution_pct), updated_by = VALUES(updated_by)
+	`, gameType, pct, operatorID)
+	return err
+}
+
+// Grant 人工發放紅利（同一活動、玩家與來源已發放過時回傳 ErrBonusDuplicate）
+func (s *BonusService) Grant(g BonusGrant) (*Bonus, error) {
+	var b *Bonus
+	err := s.withTx(func(tx *sql.Tx) error {
+		var status string
+		err := tx.QueryRow("SELECT status FROM players WHERE id = ?", g.PlayerID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrPlayerNotFound
+		} else if err != nil {
+			return err
+		}
+		if status == "deleted" || status == "suspended" {
+			return ErrPlayerInactive
+		}
+		if b, err = s.GrantTx(tx, g); err == nil && b == nil {
+			err = ErrBonusDuplicate
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(b.BonusID)
+}
+
+// GrantTx 在呼叫端交易內發放紅利：自紅利發放帳戶轉入玩家紅利帳戶；無流水要求者直接轉為現金
+//
+// 同一活動、玩家與來源已發放過時回傳 nil。
+func (s *BonusService) GrantTx(tx *sql.Tx, g BonusGrant) (*Bonus, error) {
+	if !g.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	b := &Bonus{
+		BonusID:             newBonusID(),
+		PlayerID:            g.PlayerID,
+		CampaignID:          g.CampaignID,
+		BonusType:           g.BonusType,
+		Amount:              g.Amount,
+		Currency:            s.Wallet.CurrencyOf(g.Currency),
+		Balance:             g.Amount,
+		ConsumePolicy:       g.ConsumePolicy,
+		WageringRequirement: g.WageringRequirement,
+		Status:              "active",
+		SourceReference:     g.SourceReference,
+		Description:         g.Description,
+		ValidFrom:           time.Now(),
+		GrantedBy:           g.GrantedBy,
+	}
+	if b.ConsumePolicy == "" {
+		b.ConsumePolicy = s.ConsumePolicy
+	}
+	if g.ValidDays > 0 {
+		validTo := b.ValidFrom.AddDate(0, 0, g.ValidDays)
+		b.ValidTo = &validTo
+	}
+	res, err := tx.Exec(`
+		INSERT IGNORE INTO bonuses
+			(bonus_id, player_id, campaign_id, bonus_type, amount, currency, balance, consume_policy,
+			 wagering_requirement, status, source_reference, valid_from, valid_to, description, granted_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 'active', ?, ?, ?, ?, ?)
+	`, b.BonusID, b.PlayerID, b.CampaignID, b.BonusType, b.Amount, b.Currency, b.Balance, b.ConsumePolicy,
+		b.WageringRequirement, nullString(b.SourceReference), b.ValidFrom, b.ValidTo, nullString(b.Description), b.GrantedBy)
+	if err != nil {
+		return nil, fmt.Errorf("寫入紅利失敗: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+
+	if err := s.postTx(tx, journalBonusGrant, b.Currency, "發放紅利 "+b.BonusID, g.GrantedBy,
+		BonusAccount(b.Currency), PlayerBonusAccount(b.PlayerID, b.Currency), b.Amount); err != nil {
+		return nil, err
+	}
+	if err := addBonusEventTx(tx, b.BonusID, BonusEventGrant, b.Amount, 0, "", "bonus_grant", b.SourceReference); err != nil {
+		return nil, err
+	}
+	if b.WageringRequirement <= 0 {
+		if err := s.completeTx(tx, b, "bonus_grant", b.SourceReference); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// OnDepositTx 儲值入帳時在同一交易發放首儲紅利，並依推薦關係發放推薦人紅利
+//
+// 只有玩家的第一筆完成儲值會觸發；首儲紅利金額記錄於儲值訂單。
+func (s *BonusService) OnDepositTx(tx *sql.Tx, playerID int64, depositID, currency string, amount money.Amount) error {
+	var previous int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM deposits WHERE player_id = ? AND status = 'completed' AND deposit_id <> ?
+	`, playerID, depositID).Scan(&previous); err != nil {
+		return err
+	}
+	if previous > 0 {
+		return nil
+	}
+
+	campaigns, err := s.activeCampaigns(tx, BonusTriggerFirstDeposit, currency)
+	if err != nil {
+		return err
+	}
+	for i := range campaigns {
+		c := &campaigns[i]
+		if amount < c.MinDeposit {
+			continue
+		}
+		b, err := s.grantCampaignTx(tx, c, playerID, c.amountFor(amount), depositID, "首儲紅利（"+c.Name+"）")
+		if err != nil {
+			return err
+		}
+		if b != nil {
+			if _, err := tx.Exec(`
+				UPDATE deposits SET bonus_amount = bonus_amount + ?, bonus_type = ? WHERE deposit_id = ?
+			`, b.Amount, b.BonusType, depositID); err != nil {
+				return err
+			}
+		}
+	}
+
+	var referrerID sql.NullInt64
+	if err := tx.QueryRow("SELECT referrer_id FROM players WHERE id = ?", playerID).Scan(&referrerID); err != nil {
+		return err
+	}
+	if !referrerID.Valid {
+		return nil
+	}
+	campaigns, err = s.activeCampaigns(tx, BonusTriggerReferral, currency)
+	if err != nil {
+		return err
+	}
+	for i := range campaigns {
+		c := &campaigns[i]
+		if amount < c.MinDeposit {
+			continue
+		}
+		source := "referral:" + strconv.FormatInt(playerID, 10)
+		if _, err := s.grantCampaignTx(tx, c, referrerID.Int64, c.amountFor(amount), source,
+			fmt.Sprintf("推薦紅利（%s，被推薦玩家 %d 首儲）", c.Name, playerID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GrantLoyaltyTiers 對會員等級達到門檻且尚未領取的玩家發放會員等級紅利，回傳發放筆數
+func (s *BonusService) GrantLoyaltyTiers() (int, error) {
+	campaigns, err := s.activeCampaigns(s.DB, BonusTriggerLoyaltyTier, "")
+	if err != nil {
+		return 0, err
+	}
+	granted := 0
+	for i := range campaigns {
+		c := &campaigns[i]
+		if c.LoyaltyTier == nil {
+			continue
+		}
+		source := "vip:" + strconv.Itoa(*c.LoyaltyTier)
+		rows, err := s.DB.Query(`
+			SELECT p.id FROM players p
+			WHERE p.vip_level >= ? AND p.status = 'active'
+			  AND NOT EXISTS (
+				SELECT 1 FROM bonuses b WHERE b.campaign_id = ? AND b.player_id = p.id AND b.source_reference = ?
+			  )
+			ORDER BY p.id LIMIT ?
+		`, *c.LoyaltyTier, c.ID, source, bonusSweepBatch)
+		if err != nil {
+			return granted, err
+		}
+		var players []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return granted, err
+			}
+			players = append(players, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return granted, err
+		}
+
+		for _, playerID := range players {
+			err := s.withTx(func(tx *sql.Tx) error {
+				b, err := s.grantCampaignTx(tx, c, playerID, c.FixedAmount, source,
+					fmt.Sprintf("會員等級紅利（%s，VIP %d）", c.Name, *c.LoyaltyTier))
+				if b != nil {
+					granted++
+				}
+				return err
+			})
+			if err != nil {
+				return granted, err
+			}
+		}
+	}
+	return granted, nil
+}
+
+// grantCampaignTx 依活動設定發放紅利（金額為 0 時不發放）
+func (s *BonusService) grantCampaignTx(tx *sql.Tx, c *BonusCampaign, playerID int64, amount money.Amount, source, description string) (*Bonus, error) {
+	if !amount.IsPositive() {
+		return nil, nil
+	}
+	campaignID := c.ID
+	return s.GrantTx(tx, BonusGrant{
+		PlayerID:            playerID,
+		CampaignID:          &campaignID,
+		BonusType:           c.BonusType,
+		Amount:              amount,
+		Currency:            c.Currency,
+		WageringRequirement: c.WageringRequirement,
+		ValidDays:           c.ValidDays,
+		ConsumePolicy:       c.ConsumePolicy,
+		SourceReference:     source,
+		Description:         description,
+	})
+}
+
+// activeCampaigns 取得進行中的活動（currency 為空時不限幣別）
+func (s *BonusService) activeCampaigns(q queryer, trigger, currency string) ([]BonusCampaign, error) {
+	query := "SELECT " + campaignColumns + ` FROM bonus_campaigns
+		WHERE trigger_type = ? AND status = 'active'
+		  AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW())`
+	args := []interface{}{trigger}
+	if currency != "" {
+		query += " AND currency = ?"
+		args = append(args, currency)
+	}
+	rows, err := q.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []BonusCampaign{}
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, rows.Err()
+}
+
+// StakeTx 在呼叫端交易內依動用順序扣用紅利，回傳動用的紅利金額（其餘由呼叫端自現金支付）
+//
+// 順序為 bonus_first 的紅利、現金可用餘額、cash_first 的紅利；同順序內先到期者優先。
+// 紅利與現金合計仍不足時回傳 ErrInsufficientBalance。
+func (s *BonusService) StakeTx(tx *sql.Tx, playerID int64, currency string, amount money.Amount, referenceType, referenceID string) (money.Amount, error) {
+	currency = s.Wallet.CurrencyOf(currency)
+	if ok, err := s.hasActive(tx, playerID, currency, "balance > 0"); err != nil || !ok {
+		return 0, err
+	}
+	available, err := s.lockWalletTx(tx, playerID, currency)
+	if err != nil {
+		return 0, err
+	}
+	bonuses, err := s.lockActiveTx(tx, playerID, currency, "balance > 0")
+	if err != nil {
+		return 0, err
+	}
+
+	need := amount
+	stakes := BonusStakes{}
+	take := func(policy string) {
+		for _, b := range bonuses {
+			if b.ConsumePolicy != policy || !need.IsPositive() {
+				continue
+			}
+			t := minAmount(b.Balance, need)
+			stakes = append(stakes, BonusStake{BonusID: b.BonusID, Currency: currency, Amount: t})
+			need -= t
+		}
+	}
+	take(BonusFirst)
+	need -= minAmount(available, need)
+	take(CashFirst)
+	if need.IsPositive() {
+		return 0, ErrInsufficientBalance
+	}
+
+	for _, st := range stakes {
+		if _, err := tx.Exec("UPDATE bonuses SET balance = balance - ? WHERE bonus_id = ?", st.Amount, st.BonusID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO bonus_stakes (bonus_id, player_id, currency, amount, reference_type, reference_id)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, st.BonusID, playerID, currency, st.Amount, referenceType, referenceID); err != nil {
+			return 0, err
+		}
+		if err := addBonusEventTx(tx, st.BonusID, BonusEventStake, -st.Amount, 0, "", referenceType, referenceID); err != nil {
+			return 0, err
+		}
+	}
+	total := stakes.Total()
+	if total.IsPositive() {
+		if err := s.postTx(tx, journalBonusStake, currency, "紅利帶入 "+referenceType+" "+referenceID, nil,
+			PlayerBonusAccount(playerID, currency), HouseAccount(HouseGame, currency), total); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// OpenStakesTx 鎖定並取得參考對應、尚未結清的紅利動用
+func (s *BonusService) OpenStakesTx(tx *sql.Tx, playerID int64, referenceType, referenceID string) (BonusStakes, error) {
+	rows, err := tx.Query(`
+		SELECT id, bonus_id, currency, amount FROM bonus_stakes
+		WHERE player_id = ? AND reference_type = ? AND reference_id = ? AND status = 'open'
+		ORDER BY id
+		FOR UPDATE
+	`, playerID, referenceType, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stakes := BonusStakes{}
+	for rows.Next() {
+		var st BonusStake
+		if err := rows.Scan(&st.ID, &st.BonusID, &st.Currency, &st.Amount); err != nil {
+			return nil, err
+		}
+		stakes = append(stakes, st)
+	}
+	return stakes, rows.Err()
+}
+
+// SettleStakesTx 在呼叫端交易內結清紅利動用，shares 為各筆應返還的金額（由 BonusStakes.Split 計算）
+//
+// 紅利仍有效時返還紅利餘額；已完成流水者直接轉為現金；已沒收或逾期者一併沒收。
+func (s *BonusService) SettleStakesTx(tx *sql.Tx, playerID int64, stakes BonusStakes, shares []money.Amount, referenceType, referenceID string) error {
+	if len(stakes) == 0 {
+		return nil
+	}
+	currency := stakes[0].Currency
+	if _, err := s.lockWalletTx(tx, playerID, currency); err != nil {
+		return err
+	}
+	var returned money.Amount
+	for _, share := range shares {
+		returned += share
+	}
+	if returned.IsPositive() {
+		if err := s.postTx(tx, journalBonusReturn, currency, "紅利結清 "+referenceType+" "+referenceID, nil,
+			HouseAccount(HouseGame, currency), PlayerBonusAccount(playerID, currency), returned); err != nil {
+			return err
+		}
+	}
+
+	for i, st := range stakes {
+		share := shares[i]
+		if _, err := tx.Exec(`
+			UPDATE bonus_stakes SET status = 'settled', returned_amount = ?, settled_at = NOW() WHERE id = ?
+		`, share, st.ID); err != nil {
+			return err
+		}
+		if !share.IsPositive() {
+			continue
+		}
+		b, err := s.lock(tx, st.BonusID)
+		if err != nil {
+			return err
+		}
+		switch b.Status {
+		case "active":
+			if _, err := tx.Exec("UPDATE bonuses SET balance = balance + ? WHERE bonus_id = ?", share, b.BonusID); err != nil {
+				return err
+			}
+			err = addBonusEventTx(tx, b.BonusID, BonusEventReturn, share, 0, "", referenceType, referenceID)
+		case "completed":
+			if _, err := tx.Exec("UPDATE bonuses SET released_amount = released_amount + ? WHERE bonus_id = ?", share, b.BonusID); err != nil {
+				return err
+			}
+			err = s.releaseTx(tx, b, share, referenceType, referenceID)
+		default:
+			if _, err := tx.Exec("UPDATE bonuses SET forfeited_amount = forfeited_amount + ? WHERE bonus_id = ?", share, b.BonusID); err != nil {
+				return err
+			}
+			err = s.forfeitFundsTx(tx, b, share, BonusEventForfeit, referenceType, referenceID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReturnOpenStakes 全額返還某參考類型尚未結清的紅利動用（牌桌只存在於記憶體，重新啟動時與帶入凍結一併返還）
+func (s *BonusService) ReturnOpenStakes(referenceType string) (int, error) {
+	rows, err := s.DB.Query(`
+		SELECT DISTINCT player_id, reference_id FROM bonus_stakes WHERE reference_type = ? AND status = 'open'
+	`, referenceType)
+	if err != nil {
+		return 0, err
+	}
+	type ref struct {
+		playerID int64
+		id       string
+	}
+	var refs []ref
+	for rows.Next() {
+		var r ref
+		if err := rows.Scan(&r.playerID, &r.id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		refs = append(refs, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, r := range refs {
+		err := s.withTx(func(tx *sql.Tx) error {
+			stakes, err := s.OpenStakesTx(tx, r.playerID, referenceType, r.id)
+			if err != nil {
+				return err
+			}
+			shares := make([]money.Amount, len(stakes))
+			for i, st := range stakes {
+				shares[i] = st.Amount
+			}
+			n += len(stakes)
+			return s.SettleStakesTx(tx, r.playerID, stakes, shares, referenceType, r.id)
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// RecordWagerTx 在呼叫端交易內依遊戲貢獻比例將下注額計入有效紅利的流水（先到期者優先），流水完成的紅利轉為現金
+func (s *BonusService) RecordWagerTx(tx *sql.Tx, w Wager) error {
+	if !w.Amount.IsPositive() {
+		return nil
+	}
+	currency := s.Wallet.CurrencyOf(w.Currency)
+	if ok, err := s.hasActive(tx, w.PlayerID, currency, "1=1"); err != nil || !ok {
+		return err
+	}
+	var pct float64
+	err := tx.QueryRow("SELECT contribution_pct FROM bonus_game_contributions WHERE game_type = ?", w.GameType).Scan(&pct)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	remaining := money.FromFloat(w.Amount.Float64() * pct / 100)
+	if !remaining.IsPositive() {
+		return nil
+	}
+
+	if _, err := s.lockWalletTx(tx, w.PlayerID, currency); err != nil {
+		return err
+	}
+	bonuses, err := s.lockActiveTx(tx, w.PlayerID, currency, "1=1")
+	if err != nil {
+		return err
+	}
+	for _, b := range bonuses {
+		if !remaining.IsPositive() {
+			break
+		}
+		take := minAmount(remaining, b.RemainingWagering)
+		if take.IsPositive() {
+			if _, err := tx.Exec("UPDATE bonuses SET wagered_amount = wagered_amount + ? WHERE bonus_id = ?", take, b.BonusID); err != nil {
+				return err
+			}
+			if err := addBonusEventTx(tx, b.BonusID, BonusEventWager, 0, take, w.GameType, w.ReferenceType, w.ReferenceID); err != nil {
+				return err
+			}
+			remaining -= take
+		}
+		if b.RemainingWagering-take <= 0 {
+			if err := s.completeTx(tx, b, w.ReferenceType, w.ReferenceID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ForfeitForWithdrawalTx 在呼叫端交易內沒收流水未完成的有效紅利（提領核准時呼叫），回傳沒收的紅利餘額
+//
+// 已動用於牌桌的部分於離座結清時一併沒收。未啟用提領沒收時不做任何事。
+func (s *BonusService) ForfeitForWithdrawalTx(tx *sql.Tx, playerID int64, currency, withdrawalID string) (money.Amount, error) {
+	if !s.ForfeitOnWithdrawal {
+		return 0, nil
+	}
+	currency = s.Wallet.CurrencyOf(currency)
+	if ok, err := s.hasActive(tx, playerID, currency, "remaining_wagering > 0"); err != nil || !ok {
+		return 0, err
+	}
+	if _, err := s.lockWalletTx(tx, playerID, currency); err != nil {
+		return 0, err
+	}
+	bonuses, err := s.lockActiveTx(tx, playerID, currency, "remaining_wagering > 0")
+	if err != nil {
+		return 0, err
+	}
+	var total money.Amount
+	for _, b := range bonuses {
+		if err := s.closeTx(tx, b, "forfeited", BonusEventForfeit, "withdrawal", withdrawalID); err != nil {
+			return 0, err
+		}
+		total += b.Balance
+	}
+	return total, nil
+}
+
+// Forfeit 人工沒收有效紅利
+func (s *BonusService) Forfeit(bonusID string, operatorID *int) (*Bonus, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		b, err := s.load(tx, "bonus_id = ?", bonusID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.lockWalletTx(tx, b.PlayerID, b.Currency); err != nil {
+			return err
+		}
+		if b, err = s.lock(tx, bonusID); err != nil {
+			return err
+		}
+		if b.Status != "active" {
+			return ErrBonusNotActive
+		}
+		ref := ""
+		if operatorID != nil {
+			ref = strconv.Itoa(*operatorID)
+		}
+		return s.closeTx(tx, b, "forfeited", BonusEventForfeit, "operator", ref)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(bonusID)
+}
+
+// ExpireDue 將已過有效期限的紅利標記為逾期並沒收餘額，回傳處理筆數
+func (s *BonusService) ExpireDue() (int, error) {
+	rows, err := s.DB.Query(`
+		SELECT bonus_id, player_id, currency FROM bonuses
+		WHERE status = 'active' AND valid_to IS NOT NULL AND valid_to <= NOW()
+		ORDER BY valid_to LIMIT ?
+	`, bonusSweepBatch)
+	if err != nil {
+		return 0, err
+	}
+	var due []Bonus
+	for rows.Next() {
+		var b Bonus
+		if err := rows.Scan(&b.BonusID, &b.PlayerID, &b.Currency); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, d := range due {
+		err := s.withTx(func(tx *sql.Tx) error {
+			if _, err := s.lockWalletTx(tx, d.PlayerID, d.Currency); err != nil {
+				return err
+			}
+			b, err := s.lock(tx, d.BonusID)
+			if err != nil {
+				return err
+			}
+			if b.Status != "active" {
+				return nil
+			}
+			n++
+			return s.closeTx(tx, b, "expired", BonusEventExpire, "", "")
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Run 定期處理逾期紅利與會員等級紅利，直到 ctx 結束（於背景 goroutine 執行）
+func (s *BonusService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.ExpireDue(); err != nil {
+			log.Printf("紅利逾期處理失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已將 %d 筆紅利標記為逾期", n)
+		}
+		if n, err := s.GrantLoyaltyTiers(); err != nil {
+			log.Printf("會員等級紅利發放失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已發放 %d 筆會員等級紅利", n)
+		}
+	}
+}
+
+// Get 取得紅利
+func (s *BonusService) Get(bonusID string) (*Bonus, error) {
+	return s.load(s.DB, "bonus_id = ?", bonusID)
+}
+
+// List 依條件列出紅利，回傳資料與總筆數
+func (s *BonusService) List(f BonusFilter) ([]Bonus, int64, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if f.PlayerID > 0 {
+		where += " AND player_id = ?"
+		args = append(args, f.PlayerID)
+	}
+	if f.Status != "" {
+		where += " AND status = ?"
+		args = append(args, f.Status)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM bonuses"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query("SELECT "+bonusColumns+" FROM bonuses"+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []Bonus{}
+	for rows.Next() {
+		b, err := scanBonus(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *b)
+	}
+	return list, total, rows.Err()
+}
+
+// Events 取得紅利異動記錄
+func (s *BonusService) Events(bonusID string) ([]BonusEvent, error) {
+	rows, err := s.DB.Query(`
+		SELECT event_type, amount, wagered, game_type, reference_type, reference_id, created_at
+		FROM bonus_events WHERE bonus_id = ? ORDER BY id
+	`, bonusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []BonusEvent{}
+	for rows.Next() {
+		var (
+			e                        BonusEvent
+			gameType, refType, refID sql.NullString
+		)
+		if err := rows.Scan(&e.EventType, &e.Amount, &e.Wagered, &gameType, &refType, &refID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.GameType, e.ReferenceType, e.ReferenceID = gameType.String, refType.String, refID.String
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// completeTx 流水完成：標記完成並將剩餘紅利餘額轉為現金
+func (s *BonusService) completeTx(tx *sql.Tx, b *Bonus, referenceType, referenceID string) error {
+	if _, err := tx.Exec(`
+		UPDATE bonuses
+		SET status = 'completed', completed_at = NOW(), released_amount = released_amount + balance, balance = 0
+		WHERE bonus_id = ?
+	`, b.BonusID); err != nil {
+		return err
+	}
+	if !b.Balance.IsPositive() {
+		return nil
+	}
+	return s.releaseTx(tx, b, b.Balance, referenceType, referenceID)
+}
+
+// releaseTx 將紅利資金自玩家紅利帳戶轉入現金錢包（交易類型 bonus）
+func (s *BonusService) releaseTx(tx *sql.Tx, b *Bonus, amount money.Amount, referenceType, referenceID string) error {
+	counter := PlayerBonusAccount(b.PlayerID, b.Currency)
+	if _, err := s.Wallet.CreditTx(tx, WalletEntry{
+		PlayerID:      b.PlayerID,
+		Currency:      b.Currency,
+		Type:          "bonus",
+		Amount:        amount,
+		ReferenceID:   b.BonusID,
+		ReferenceType: "bonus",
+		Description:   "紅利完成流水轉為現金",
+		Counterparty:  &counter,
+	}); err != nil {
+		return err
+	}
+	return addBonusEventTx(tx, b.BonusID, BonusEventRelease, -amount, 0, "", referenceType, referenceID)
+}
+
+// closeTx 沒收或逾期：標記狀態並將紅利餘額轉回紅利發放帳戶
+func (s *BonusService) closeTx(tx *sql.Tx, b *Bonus, status, event, referenceType, referenceID string) error {
+	if _, err := tx.Exec(`
+		UPDATE bonuses
+		SET status = ?, forfeited_at = NOW(), forfeited_amount = forfeited_amount + balance, balance = 0
+		WHERE bonus_id = ?
+	`, status, b.BonusID); err != nil {
+		return err
+	}
+	return s.forfeitFundsTx(tx, b, b.Balance, event, referenceType, referenceID)
+}
+
+// forfeitFundsTx 將沒收的紅利資金自玩家紅利帳戶轉回紅利發放帳戶
+func (s *BonusService) forfeitFundsTx(tx *sql.Tx, b *Bonus, amount money.Amount, event, referenceType, referenceID string) error {
+	if amount.IsPositive() {
+		if err := s.postTx(tx, journalBonusForfeit, b.Currency, "沒收紅利 "+b.BonusID, nil,
+			PlayerBonusAccount(b.PlayerID, b.Currency), BonusAccount(b.Currency), amount); err != nil {
+			return err
+		}
+	}
+	return addBonusEventTx(tx, b.BonusID, event, -amount, 0, "", referenceType, referenceID)
+}
+
+// postTx 過帳紅利傳票（自 from 轉出 amount 至 to）
+func (s *BonusService) postTx(tx *sql.Tx, journalType, currency, description string, operatorID *int, from, to LedgerAccount, amount money.Amount) error {
+	return s.Ledger.PostTx(tx, &Journal{
+		Type:        journalType,
+		Currency:    currency,
+		Description: description,
+		OperatorID:  operatorID,
+		Lines: []LedgerLine{
+			{Account: from, Amount: -amount},
+			{Account: to, Amount: amount},
+		},
+	})
+}
+
+// hasActive 不加鎖檢查玩家是否有符合條件的有效紅利（沒有紅利的玩家不需鎖定錢包）
+func (s *BonusService) hasActive(tx *sql.Tx, playerID int64, currency, cond string) (bool, error) {
+	var n int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM bonuses
+		WHERE player_id = ? AND currency = ? AND status = 'active' AND (valid_to IS NULL OR valid_to > NOW()) AND `+cond,
+		playerID, currency).Scan(&n)
+	return n > 0, err
+}
+
+// lockWalletTx 鎖定錢包列並回傳可用餘額（錢包不存在時為 0）
+func (s *BonusService) lockWalletTx(tx *sql.Tx, playerID int64, currency string) (money.Amount, error) {
+	var available money.Amount
+	err := tx.QueryRow(`
+		SELECT balance - frozen_balance FROM player_wallets
+		WHERE player_id = ? AND currency = ?
+		FOR UPDATE
+	`, playerID, currency).Scan(&available)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return available, err
+}
+
+// lockActiveTx 鎖定玩家符合條件的有效紅利（先到期者在前）
+func (s *BonusService) lockActiveTx(tx *sql.Tx, playerID int64, currency, cond string) ([]*Bonus, error) {
+	rows, err := tx.Query("SELECT "+bonusColumns+` FROM bonuses
+		WHERE player_id = ? AND currency = ? AND status = 'active' AND (valid_to IS NULL OR valid_to > NOW()) AND `+cond+`
+		ORDER BY valid_to IS NULL, valid_to, id
+		FOR UPDATE`, playerID, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*Bonus
+	for rows.Next() {
+		b, err := scanBonus(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+func (s *BonusService) lock(tx *sql.Tx, bonusID string) (*Bonus, error) {
+	return s.load(tx, "bonus_id = ? FOR UPDATE", bonusID)
+}
+
+func (s *BonusService) load(q queryer, where string, args ...interface{}) (*Bonus, error) {
+	b, err := scanBonus(q.QueryRow("SELECT "+bonusColumns+" FROM bonuses WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrBonusNotFound
+	}
+	return b, err
+}
+
+func (s *BonusService) withTx(fn func(*sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addBonusEventTx 寫入紅利異動記錄
+func addBonusEventTx(tx *sql.Tx, bonusID, event string, amount, wagered money.Amount, gameType, referenceType, referenceID string) error {
+	_, err := tx.Exec(`
+		INSERT INTO bonus_events (bonus_id, event_type, amount, wagered, game_type, reference_type, reference_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, bonusID, event, amount, wagered, nullString(gameType), nullString(referenceType), nullString(referenceID))
+	return err
+}
+
+func minAmount(a, b money.Amount) money.Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+const campaignColumns = `id, campaign_code, name, description, trigger_type, bonus_type, currency, fixed_amount,
+	percent, max_amount, min_deposit, loyalty_tier, wagering_requirement, valid_days, consume_policy,
+	starts_at, ends_at, status, created_by, created_at`
+
+func scanCampaign(row rowScanner) (*BonusCampaign, error) {
+	c := &BonusCampaign{}
+	var (
+		description      sql.NullString
+		maxAmount        sql.NullString
+		loyaltyTier      sql.NullInt64
+		createdBy        sql.NullInt64
+		startsAt, endsAt sql.NullTime
+	)
+	if err := row.Scan(&c.ID, &c.CampaignCode, &c.Name, &description, &c.TriggerType, &c.BonusType, &c.Currency,
+		&c.FixedAmount, &c.Percent, &maxAmount, &c.MinDeposit, &loyaltyTier, &c.WageringRequirement, &c.ValidDays,
+		&c.ConsumePolicy, &startsAt, &endsAt, &c.Status, &createdBy, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	c.Description = description.String
+	if maxAmount.Valid {
+		m, err := money.Parse(maxAmount.String)
+		if err != nil {
+			return nil, err
+		}
+		c.MaxAmount = &m
+	}
+	c.LoyaltyTier, c.CreatedBy = nullInt(loyaltyTier), nullInt(createdBy)
+	c.StartsAt, c.EndsAt = nullTime(startsAt), nullTime(endsAt)
+	return c, nil
+}
+
+const bonusColumns = `bonus_id, player_id, campaign_id, bonus_type, amount, currency, balance, consume_policy,
+	wagering_requirement, wagered_amount, remaining_wagering, released_amount, forfeited_amount, status,
+	source_reference, description, valid_from, valid_to, completed_at, forfeited_at, granted_by, created_at`
+
+func scanBonus(row rowScanner) (*Bonus, error) {
+	b := &Bonus{}
+	var (
+		campaignID, grantedBy             sql.NullInt64
+		source, description               sql.NullString
+		validTo, completedAt, forfeitedAt sql.NullTime
+	)
+	if err := row.Scan(&b.BonusID, &b.PlayerID, &campaignID, &b.BonusType, &b.Amount, &b.Currency, &b.Balance,
+		&b.ConsumePolicy, &b.WageringRequirement, &b.WageredAmount, &b.RemainingWagering, &b.ReleasedAmount,
+		&b.ForfeitedAmount, &b.Status, &source, &description, &b.ValidFrom, &validTo, &completedAt, &forfeitedAt,
+		&grantedBy, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	b.CampaignID, b.GrantedBy = nullInt(campaignID), nullInt(grantedBy)
+	b.SourceReference, b.Description = source.String, description.String
+	b.ValidTo, b.CompletedAt, b.ForfeitedAt = nullTime(validTo), nullTime(completedAt), nullTime(forfeitedAt)
+	return b, nil
+}
+
+// newBonusID 產生紅利編號
+func newBonusID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return "BN" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
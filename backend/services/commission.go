@@ -0,0 +1,1244 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 分潤相關錯誤
+var (
+	ErrCommissionConfigNotFound = errors.New("分潤配置不存在")
+	ErrCommissionRunNotFound    = errors.New("分潤計算批次不存在")
+	ErrCommissionPeriod         = errors.New("計算期間不正確或尚未結束")
+	ErrCommissionOverlap        = errors.New("計算期間與已計算的期間重疊")
+	ErrCommissionLaterPeriod    = errors.New("已有之後期間的計算結果，負營收結轉會不一致")
+	ErrCommissionLocked         = errors.New("此期間的佣金已納入結算，無法重新計算")
+	ErrInvalidCommissionConfig  = errors.New("分潤配置不正確")
+	ErrCommissionTargetInvalid  = errors.New("總公司或已終止的對象不可設定分潤")
+)
+
+// 分潤配置類型（commission_configs.commission_type）
+const (
+	CommissionRevenueShare = "revenue_share"
+	CommissionCPA          = "cpa"
+	CommissionHybrid       = "hybrid"
+)
+
+// 佣金記錄類型（commissions.commission_type）
+const (
+	commissionTypeLoss    = "loss_commission"    // 營收分成（下注為正、派彩與退款為負）
+	commissionTypeDeposit = "deposit_commission" // CPA（來源為新玩家的首次儲值）
+)
+
+// gameTypeAll 未另外配置的遊戲類型
+const gameTypeAll = "all"
+
+// commissionInsertBatch 佣金記錄每次批次寫入的筆數
+const commissionInsertBatch = 200
+
+// CommissionTier 階梯（達到最低營收與最低玩家數時改用此比率，取符合條件中門檻最高者）
+type CommissionTier struct {
+	MinRevenue money.Amount `json:"min_revenue"`
+	MinPlayers int          `json:"min_players"`
+	Rate       float64      `json:"rate"`
+	CPAAmount  money.Amount `json:"cpa_amount,omitempty"` // 大於 0 時取代配置的 CPA 金額
+}
+
+// CommissionConfig 分潤配置
+type CommissionConfig struct {
+	ID                int              `json:"id"`
+	ConfigName        string           `json:"config_name"`
+	TargetType        string           `json:"target_type"`
+	TargetID          int              `json:"target_id"`
+	GameType          string           `json:"game_type"`
+	CommissionType    string           `json:"commission_type"`
+	RevenueShareRate  float64          `json:"revenue_share_rate"`
+	CPAAmount         money.Amount     `json:"cpa_amount"`
+	MinPlayers        int              `json:"min_players"`
+	MinRevenue        money.Amount     `json:"min_revenue"`
+	Tiers             []CommissionTier `json:"tiers"`
+	NegativeCarryover bool             `json:"negative_carryover"`
+	SettlementPeriod  string           `json:"settlement_period"`
+	IsActive          bool             `json:"is_active"`
+	EffectiveFrom     string           `json:"effective_from"`
+	EffectiveTo       *string          `json:"effective_to,omitempty"`
+	CreatedBy         *int             `json:"created_by,omitempty"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+}
+
+// CommissionConfigInput 新增分潤配置（同一對象與遊戲類型的現行配置於新配置生效前一日失效）
+type CommissionConfigInput struct {
+	ConfigName        string
+	GameType          string
+	CommissionType    string
+	RevenueShareRate  float64
+	CPAAmount         money.Amount
+	MinPlayers        int
+	MinRevenue        money.Amount
+	Tiers             []CommissionTier
+	NegativeCarryover bool
+	SettlementPeriod  string
+	EffectiveFrom     time.Time
+	EffectiveTo       *time.Time
+}
+
+// CommissionPeriod 計算期間（含首尾兩日）
+type CommissionPeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (p CommissionPeriod) startDate() string { return p.Start.Format("2006-01-02") }
+
+func (p CommissionPeriod) endDate() string { return p.End.Format("2006-01-02") }
+
+// CommissionTarget 計算對象（代理商或經銷商）
+type CommissionTarget struct {
+	Type string `json:"target_type"`
+	ID   int    `json:"target_id"`
+}
+
+// CommissionOverride 上級代理商的差額佣金
+type CommissionOverride struct {
+	AgentID int          `json:"agent_id"`
+	Level   int          `json:"level"`
+	Rate    float64      `json:"rate"` // 上級比率減去下級比率
+	Amount  money.Amount `json:"amount"`
+}
+
+// CommissionRunDetails 計算明細（存於 commission_runs.details）
+type CommissionRunDetails struct {
+	Tier      *int                 `json:"tier,omitempty"` // 適用的階梯索引
+	CPAAmount money.Amount         `json:"cpa_amount,omitempty"`
+	Overrides []CommissionOverride `json:"overrides"`
+}
+
+// CommissionRun 分潤計算批次（對象、遊戲類型、幣別與期間）
+type CommissionRun struct {
+	ID                    int64                `json:"id"`
+	RunID                 string               `json:"run_id"`
+	TargetType            string               `json:"target_type"`
+	TargetID              int                  `json:"target_id"`
+	AgentID               int                  `json:"agent_id"`
+	ConfigID              *int                 `json:"config_id,omitempty"`
+	GameType              string               `json:"game_type"`
+	Currency              string               `json:"currency"`
+	PeriodStart           string               `json:"period_start"`
+	PeriodEnd             string               `json:"period_end"`
+	CommissionType        string               `json:"commission_type"`
+	TotalBets             money.Amount         `json:"total_bets"`
+	TotalWins             money.Amount         `json:"total_wins"`
+	NetRevenue            money.Amount         `json:"net_revenue"`
+	CarryIn               money.Amount         `json:"carry_in"`
+	CommissionableRevenue money.Amount         `json:"commissionable_revenue"`
+	CarryOut              money.Amount         `json:"carry_out"`
+	ActivePlayers         int                  `json:"active_players"`
+	NewPlayers            int                  `json:"new_players"`
+	Qualified             bool                 `json:"qualified"`
+	Rate                  float64              `json:"rate"`
+	RevenueCommission     money.Amount         `json:"revenue_commission"`
+	CPACommission         money.Amount         `json:"cpa_commission"`
+	OverrideCommission    money.Amount         `json:"override_commission"`
+	Details               CommissionRunDetails `json:"details"`
+	CreatedBy             *int                 `json:"created_by,omitempty"`
+	CreatedAt             time.Time            `json:"created_at"`
+}
+
+// CommissionSkip 未計算的對象與原因
+type CommissionSkip struct {
+	CommissionTarget
+	Reason string `json:"reason"`
+}
+
+// CommissionResult 一次計算的結果
+type CommissionResult struct {
+	PeriodStart string           `json:"period_start"`
+	PeriodEnd   string           `json:"period_end"`
+	Runs        []CommissionRun  `json:"runs"`
+	Skipped     []CommissionSkip `json:"skipped"`
+}
+
+// Commission 佣金記錄（每筆對應一筆來源交易）
+type Commission struct {
+	ID                  int64        `json:"id"`
+	CommissionID        string       `json:"commission_id"`
+	RunID               *int64       `json:"run_id,omitempty"`
+	AgentID             int          `json:"agent_id"`
+	DealerID            *int         `json:"dealer_id,omitempty"`
+	PlayerID            int64        `json:"player_id"`
+	SourceTransactionID int64        `json:"source_transaction_id"`
+	TransactionCode     string       `json:"transaction_code"` // 來源交易流水號
+	CommissionType      string       `json:"commission_type"`
+	GameType            string       `json:"game_type,omitempty"`
+	OverrideLevel       int          `json:"override_level"`
+	Rate                float64      `json:"rate"`
+	BaseAmount          money.Amount `json:"base_amount"`
+	CommissionAmount    money.Amount `json:"commission_amount"`
+	Currency            string       `json:"currency"`
+	Status              string       `json:"status"`
+	SettlementID        string       `json:"settlement_id,omitempty"`
+	PeriodStart         string       `json:"period_start"`
+	PeriodEnd           string       `json:"period_end"`
+	PaidAt              *time.Time   `json:"paid_at,omitempty"`
+	CreatedAt           time.Time    `json:"created_at"`
+}
+
+// CommissionFilter 佣金記錄與計算批次查詢條件（列佣金記錄時 TargetType 與 TargetID 為受益者，列批次時為計算對象）
+type CommissionFilter struct {
+	TargetType   string
+	TargetID     int
+	RunID        int64
+	SettlementID string
+	Status       string
+	PeriodStart  *time.Time
+	PeriodEnd    *time.Time
+	Page         int
+	Limit        int
+}
+
+// CommissionTotal 受益者依幣別與狀態的佣金合計
+type CommissionTotal struct {
+	Currency string       `json:"currency"`
+	Status   string       `json:"status"`
+	Direct   money.Amount `json:"direct"`   // 直接佣金
+	Override money.Amount `json:"override"` // 下級貢獻的差額佣金
+	Count    int64        `json:"count"`
+}
+
+// CommissionService 分潤計算引擎
+//
+// 依期間內有效的分潤配置（遊戲類型專屬配置優先，其餘歸入 all 配置，皆無時使用代理商或經銷商的基礎佣金比率）
+// 計算直屬玩家的營收分成與 CPA（交易依發生當時的玩家歸屬計入），套用階梯與負營收結轉，
+// 並依層級結構由直屬上級往上計算差額佣金。
+// 每個對象、遊戲類型、幣別與期間產生一筆計算批次，佣金記錄逐筆對應來源交易；重新計算時整批取代，
+// 已納入結算的佣金不可重新計算。
+type CommissionService struct {
+	DB             *sql.DB
+	OverrideLevels int
+}
+
+// NewCommissionService 建立新的分潤計算服務
+func NewCommissionService() *CommissionService {
+	s := &CommissionService{DB: config.GetDB()}
+	if config.AppConfig != nil {
+		s.OverrideLevels = config.AppConfig.Agent.OverrideLevels
+	}
+	return s
+}
+
+// Configs 對象的所有分潤配置（含已失效者，依遊戲類型與生效日期排序）
+func (s *CommissionService) Configs(targetType string, targetID int) ([]CommissionConfig, error) {
+	if err := commissionTargetExists(s.DB, targetType, targetID); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query(commissionConfigSelect+`
+		WHERE target_type = ? AND target_id = ?
+		ORDER BY game_type, effective_from DESC, id DESC
+	`, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []CommissionConfig{}
+	for rows.Next() {
+		c, err := scanCommissionConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, rows.Err()
+}
+
+// SaveConfig 新增對象的分潤配置；同一遊戲類型原本的配置於新配置生效前一日失效，生效日期較晚者停用
+func (s *CommissionService) SaveConfig(targetType string, targetID int, in CommissionConfigInput, createdBy *int) (*CommissionConfig, error) {
+	if err := validateCommissionConfig(&in); err != nil {
+		return nil, err
+	}
+	tiers, err := json.Marshal(in.Tiers)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	err = withAgentTx(s.DB, func(tx *sql.Tx) error {
+		t, err := lockCommissionTarget(tx, targetType, targetID)
+		if err != nil {
+			return err
+		}
+		if t.company || t.status == AgentStatusTerminated {
+			return ErrCommissionTargetInvalid
+		}
+		from := in.EffectiveFrom.Format("2006-01-02")
+		if _, err := tx.Exec(`
+			UPDATE commission_configs SET is_active = FALSE
+			WHERE target_type = ? AND target_id = ? AND game_type = ? AND is_active AND effective_from >= ?
+		`, targetType, targetID, in.GameType, from); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			UPDATE commission_configs SET effective_to = DATE_SUB(?, INTERVAL 1 DAY)
+			WHERE target_type = ? AND target_id = ? AND game_type = ? AND is_active
+			  AND (effective_to IS NULL OR effective_to >= ?)
+		`, from, targetType, targetID, in.GameType, from); err != nil {
+			return err
+		}
+		var to interface{}
+		if in.EffectiveTo != nil {
+			to = in.EffectiveTo.Format("2006-01-02")
+		}
+		res, err := tx.Exec(`
+			INSERT INTO commission_configs
+				(config_name, target_type, target_id, game_type, commission_type, revenue_share_rate, cpa_amount,
+				 min_players, min_revenue, tier_config, negative_carryover, settlement_period, is_active,
+				 effective_from, effective_to, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, TRUE, ?, ?, ?)
+		`, in.ConfigName, targetType, targetID, in.GameType, in.CommissionType, in.RevenueShareRate, in.CPAAmount,
+			in.MinPlayers, in.MinRevenue, tiers, in.NegativeCarryover, in.SettlementPeriod, from, to, createdBy)
+		if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scanCommissionConfig(s.DB.QueryRow(commissionConfigSelect+" WHERE id = ?", id))
+}
+
+// DisableConfig 停用分潤配置（已計算的期間不受影響）
+func (s *CommissionService) DisableConfig(targetType string, targetID, id int) error {
+	res, err := s.DB.Exec(`
+		UPDATE commission_configs SET is_active = FALSE WHERE id = ? AND target_type = ? AND target_id = ?
+	`, id, targetType, targetID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommissionConfigNotFound
+	}
+	return nil
+}
+
+// Calculate 計算期間內的佣金；target 為 nil 時計算期間內有交易的所有代理商與經銷商
+//
+// 每個對象各自在一個交易內完成，已納入結算或期間衝突而無法重新計算的對象列於 Skipped。
+func (s *CommissionService) Calculate(p CommissionPeriod, target *CommissionTarget, createdBy *int) (*CommissionResult, error) {
+	p.Start, p.End = startOfDay(p.Start), startOfDay(p.End)
+	if p.End.Before(p.Start) || !p.End.Before(startOfDay(time.Now())) {
+		return nil, ErrCommissionPeriod
+	}
+	var targets []CommissionTarget
+	if target != nil {
+		targets = []CommissionTarget{*target}
+	} else {
+		var err error
+		if targets, err = s.activeTargets(p); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &CommissionResult{
+		PeriodStart: p.startDate(),
+		PeriodEnd:   p.endDate(),
+		Runs:        []CommissionRun{},
+		Skipped:     []CommissionSkip{},
+	}
+	for _, t := range targets {
+		var runs []CommissionRun
+		err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+			var err error
+			runs, err = s.calculateTx(tx, t, p, createdBy)
+			return err
+		})
+		switch {
+		case err == nil:
+			result.Runs = append(result.Runs, runs...)
+		case errors.Is(err, ErrCommissionLocked), errors.Is(err, ErrCommissionOverlap),
+			errors.Is(err, ErrCommissionLaterPeriod):
+			if target != nil {
+				return nil, err
+			}
+			result.Skipped = append(result.Skipped, CommissionSkip{CommissionTarget: t, Reason: err.Error()})
+		default:
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// activeTargets 期間內直屬玩家有遊戲或儲值交易、或已有計算結果的代理商與經銷商
+func (s *CommissionService) activeTargets(p CommissionPeriod) ([]CommissionTarget, error) {
+	rows, err := s.DB.Query(`
+		SELECT IF(pa.dealer_id IS NULL, 'agent', 'dealer'), COALESCE(pa.dealer_id, pa.agent_id)
+		FROM transactions t
+		JOIN player_attributions pa ON pa.player_id = t.player_id AND pa.effective_from <= t.created_at
+		     AND (pa.effective_to IS NULL OR pa.effective_to > t.created_at)
+		WHERE t.status = 'completed' AND t.transaction_type IN ('bet', 'win', 'refund', 'deposit')
+		  AND t.created_at >= ? AND t.created_at < ? AND pa.agent_id IS NOT NULL
+		UNION
+		SELECT target_type, target_id FROM commission_runs WHERE period_start = ? AND period_end = ?
+	`, p.Start, p.End.AddDate(0, 0, 1), p.startDate(), p.endDate())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []CommissionTarget
+	for rows.Next() {
+		var t CommissionTarget
+		if err := rows.Scan(&t.Type, &t.ID); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Type != list[j].Type {
+			return list[i].Type < list[j].Type
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, rows.Err()
+}
+
+// commissionTarget 計算時鎖定的對象
+type commissionTarget struct {
+	CommissionTarget
+	agentID int
+	rate    float64
+	status  string
+	company bool
+}
+
+// commissionSource 計佣來源交易
+type commissionSource struct {
+	id       int64
+	playerID int64
+	kind     string
+	amount   money.Amount // 營收分成時下注為正、派彩與退款為負
+	currency string
+	gameType string
+}
+
+// commissionGroup 同一配置與幣別的來源交易
+type commissionGroup struct {
+	gameType string
+	currency string
+	cfg      *CommissionConfig
+	revenue  []commissionSource
+	deposits []commissionSource
+}
+
+// commissionRow 待寫入的佣金記錄
+type commissionRow struct {
+	agentID  int
+	dealerID *int
+	source   commissionSource
+	kind     string
+	level    int
+	rate     float64
+	base     money.Amount
+	amount   money.Amount
+}
+
+// calculateTx 計算單一對象在期間內的佣金（取代同期間原有的計算結果）
+func (s *CommissionService) calculateTx(tx *sql.Tx, target CommissionTarget, p CommissionPeriod, createdBy *int) ([]CommissionRun, error) {
+	t, err := lockCommissionTarget(tx, target.Type, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if t.company {
+		return nil, nil
+	}
+	if err := s.clearRunsTx(tx, t, p); err != nil {
+		return nil, err
+	}
+
+	configs, err := effectiveConfigs(tx, t, p)
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	scope := "pa.agent_id = ? AND pa.dealer_id IS NULL"
+	if t.Type == NodeDealer {
+		scope = "pa.dealer_id = ?"
+	}
+	from, to := p.Start, p.End.AddDate(0, 0, 1)
+
+	groups := map[string]*commissionGroup{}
+	group := func(gameType, currency string) *commissionGroup {
+		key := gameType + "|" + currency
+		g := groups[key]
+		if g == nil {
+			g = &commissionGroup{gameType: gameType, currency: currency, cfg: configs[gameType]}
+			groups[key] = g
+		}
+		return g
+	}
+
+	rows, err := tx.Query(`
+		SELECT t.id, t.player_id, t.transaction_type, t.amount, t.currency, COALESCE(g.game_type, '')
+		FROM transactions t
+		JOIN player_attributions pa ON pa.player_id = t.player_id AND pa.effective_from <= t.created_at
+		     AND (pa.effective_to IS NULL OR pa.effective_to > t.created_at)
+		LEFT JOIN game_rooms r ON t.reference_type IN ('table_buy_in', 'table_cash_out') AND r.room_code = t.reference_id
+		LEFT JOIN tournaments tn ON t.reference_type IN ('tournament_buy_in', 'tournament_refund', 'tournament_prize')
+		     AND tn.tournament_code = t.reference_id
+		LEFT JOIN game_rooms tr ON tr.id = tn.room_id
+		LEFT JOIN games g ON g.id = COALESCE(r.game_id, tr.game_id)
+		WHERE `+scope+` AND t.status = 'completed' AND t.created_at >= ? AND t.created_at < ?
+		  AND (t.transaction_type IN ('bet', 'win') OR t.reference_type = 'tournament_refund')
+		ORDER BY t.id
+	`, target.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var src commissionSource
+		if err := rows.Scan(&src.id, &src.playerID, &src.kind, &src.amount, &src.currency, &src.gameType); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if src.kind != "bet" {
+			src.amount = -src.amount
+		}
+		key := src.gameType
+		if configs[key] == nil {
+			key = gameTypeAll
+		}
+		if configs[key] == nil || !configs[key].hasRevenueShare() {
+			continue
+		}
+		g := group(key, src.currency)
+		g.revenue = append(g.revenue, src)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// CPA：直屬玩家的首次儲值落在期間內，一位玩家一筆
+	if cfg := configs[gameTypeAll]; cfg != nil && cfg.hasCPA() {
+		rows, err := tx.Query(`
+			SELECT t.id, t.player_id, t.transaction_type, t.amount, t.currency
+			FROM transactions t
+			JOIN player_attributions pa ON pa.player_id = t.player_id AND pa.effective_from <= t.created_at
+			     AND (pa.effective_to IS NULL OR pa.effective_to > t.created_at)
+			WHERE `+scope+` AND t.transaction_type = 'deposit' AND t.status = 'completed'
+			  AND t.created_at >= ? AND t.created_at < ?
+			  AND NOT EXISTS (
+				SELECT 1 FROM transactions e
+				WHERE e.player_id = t.player_id AND e.transaction_type = 'deposit' AND e.status = 'completed' AND e.id < t.id
+			  )
+			ORDER BY t.id
+		`, target.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var src commissionSource
+			if err := rows.Scan(&src.id, &src.playerID, &src.kind, &src.amount, &src.currency); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			g := group(gameTypeAll, src.currency)
+			g.deposits = append(g.deposits, src)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ancestors, err := s.overrideChain(tx, t, p)
+	if err != nil {
+		return nil, err
+	}
+	runs := []CommissionRun{}
+	for _, k := range keys {
+		run, err := s.runGroupTx(tx, t, p, groups[k], ancestors, createdBy)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+// runGroupTx 計算並寫入一個配置與幣別的批次與佣金記錄
+func (s *CommissionService) runGroupTx(tx *sql.Tx, t *commissionTarget, p CommissionPeriod, g *commissionGroup,
+	ancestors []overrideAncestor, createdBy *int) (*CommissionRun, error) {
+	cfg := g.cfg
+	run := &CommissionRun{
+		RunID:          newCommissionRunID(),
+		TargetType:     t.Type,
+		TargetID:       t.ID,
+		AgentID:        t.agentID,
+		GameType:       g.gameType,
+		Currency:       g.currency,
+		PeriodStart:    p.startDate(),
+		PeriodEnd:      p.endDate(),
+		CommissionType: cfg.CommissionType,
+		NewPlayers:     len(g.deposits),
+		CreatedBy:      createdBy,
+		Details:        CommissionRunDetails{Overrides: []CommissionOverride{}},
+	}
+	if cfg.ID > 0 {
+		id := cfg.ID
+		run.ConfigID = &id
+	}
+	players := map[int64]bool{}
+	for _, src := range g.revenue {
+		if src.kind == "bet" {
+			run.TotalBets += src.amount
+			players[src.playerID] = true
+		} else {
+			run.TotalWins -= src.amount
+		}
+	}
+	run.ActivePlayers = len(players)
+	run.NetRevenue = run.TotalBets - run.TotalWins
+
+	if cfg.NegativeCarryover {
+		err := tx.QueryRow(`
+			SELECT carry_out FROM commission_runs
+			WHERE target_type = ? AND target_id = ? AND game_type = ? AND currency = ? AND period_end < ?
+			ORDER BY period_end DESC LIMIT 1
+		`, t.Type, t.ID, g.gameType, g.currency, p.startDate()).Scan(&run.CarryIn)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	run.CommissionableRevenue = run.NetRevenue + run.CarryIn
+	if run.CommissionableRevenue.IsNegative() && cfg.NegativeCarryover {
+		run.CarryOut = run.CommissionableRevenue
+	}
+	run.Qualified = run.ActivePlayers >= cfg.MinPlayers &&
+		(!cfg.MinRevenue.IsPositive() || run.NetRevenue >= cfg.MinRevenue)
+	rate, cpa, tier := cfg.tier(run.CommissionableRevenue, run.ActivePlayers)
+	run.Rate, run.Details.Tier = rate, tier
+
+	var dealerID *int
+	if t.Type == NodeDealer {
+		id := t.ID
+		dealerID = &id
+	}
+	var rows []commissionRow
+	if cfg.hasRevenueShare() && run.CommissionableRevenue.IsPositive() {
+		if run.Qualified && rate > 0 {
+			run.RevenueCommission = percentOf(run.CommissionableRevenue, rate)
+			rows = append(rows, allocateCommission(g.revenue, run.NetRevenue, run.RevenueCommission, commissionRow{
+				agentID: t.agentID, dealerID: dealerID, kind: commissionTypeLoss, rate: rate,
+			})...)
+		}
+		// 差額佣金：上級比率高於目前已分配的最高比率時，取其差額
+		floor := rate
+		for i, a := range ancestors {
+			r := a.rate(g.gameType)
+			if r <= floor {
+				continue
+			}
+			diff := r - floor
+			amount := percentOf(run.CommissionableRevenue, diff)
+			floor = r
+			if !amount.IsPositive() {
+				continue
+			}
+			run.OverrideCommission += amount
+			run.Details.Overrides = append(run.Details.Overrides, CommissionOverride{
+				AgentID: a.id, Level: i + 1, Rate: diff, Amount: amount,
+			})
+			rows = append(rows, allocateCommission(g.revenue, run.NetRevenue, amount, commissionRow{
+				agentID: a.id, kind: commissionTypeLoss, level: i + 1, rate: diff,
+			})...)
+		}
+	}
+	if cfg.hasCPA() && run.Qualified && cpa.IsPositive() {
+		run.Details.CPAAmount = cpa
+		for _, src := range g.deposits {
+			run.CPACommission += cpa
+			rows = append(rows, commissionRow{
+				agentID: t.agentID, dealerID: dealerID, source: src, kind: commissionTypeDeposit,
+				base: src.amount, amount: cpa,
+			})
+		}
+	}
+
+	details, err := json.Marshal(run.Details)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`
+		INSERT INTO commission_runs
+			(run_id, target_type, target_id, agent_id, config_id, game_type, currency, period_start, period_end,
+			 commission_type, total_bets, total_wins, net_revenue, carry_in, commissionable_revenue, carry_out,
+			 active_players, new_players, qualified, rate, revenue_commission, cpa_commission, override_commission,
+			 details, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.RunID, run.TargetType, run.TargetID, run.AgentID, run.ConfigID, run.GameType, run.Currency,
+		run.PeriodStart, run.PeriodEnd, run.CommissionType, run.TotalBets, run.TotalWins, run.NetRevenue,
+		run.CarryIn, run.CommissionableRevenue, run.CarryOut, run.ActivePlayers, run.NewPlayers, run.Qualified,
+		run.Rate, run.RevenueCommission, run.CPACommission, run.OverrideCommission, details, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if run.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := insertCommissionsTx(tx, run, rows); err != nil {
+		return nil, err
+	}
+	run.CreatedAt = time.Now()
+	return run, nil
+}
+
+// clearRunsTx 檢查期間是否可計算，並刪除同期間原有的批次（佣金記錄一併刪除）
+func (s *CommissionService) clearRunsTx(tx *sql.Tx, t *commissionTarget, p CommissionPeriod) error {
+	start, end := p.startDate(), p.endDate()
+	var same, overlap, later, locked int
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(period_start = ? AND period_end = ?), 0),
+		       COALESCE(SUM(period_start <= ? AND period_end >= ? AND NOT (period_start = ? AND period_end = ?)), 0),
+		       COALESCE(SUM(period_start > ?), 0)
+		FROM commission_runs WHERE target_type = ? AND target_id = ?
+	`, start, end, end, start, start, end, end, t.Type, t.ID).Scan(&same, &overlap, &later); err != nil {
+		return err
+	}
+	if overlap > 0 {
+		return ErrCommissionOverlap
+	}
+	if later > 0 {
+		return ErrCommissionLaterPeriod
+	}
+	if same == 0 {
+		return nil
+	}
+	// 對象已鎖定，同一對象的計算與結算不會同時進行
+	if err := tx.QueryRow(`
+		SELECT COUNT(c.id) FROM commission_runs r
+		LEFT JOIN commissions c ON c.run_id = r.id AND (c.status <> 'pending' OR c.settlement_id IS NOT NULL)
+		WHERE r.target_type = ? AND r.target_id = ? AND r.period_start = ? AND r.period_end = ?
+	`, t.Type, t.ID, start, end).Scan(&locked); err != nil {
+		return err
+	}
+	if locked > 0 {
+		return ErrCommissionLocked
+	}
+	_, err := tx.Exec(`
+		DELETE FROM commission_runs WHERE target_type = ? AND target_id = ? AND period_start = ? AND period_end = ?
+	`, t.Type, t.ID, start, end)
+	return err
+}
+
+// overrideAncestor 可抽取差額佣金的上級代理商與其各遊戲類型的營收分成比率
+type overrideAncestor struct {
+	id    int
+	rates map[string]float64
+}
+
+// overrideChain 由直屬上級往上（不含總公司與已終止者）的上級代理商，經銷商的第一層為所屬代理商
+func (s *CommissionService) overrideChain(tx *sql.Tx, t *commissionTarget, p CommissionPeriod) ([]overrideAncestor, error) {
+	limit := ""
+	if s.OverrideLevels > 0 {
+		limit = fmt.Sprintf(" LIMIT %d", s.OverrideLevels)
+	}
+	rows, err := tx.Query(`
+		SELECT a.id FROM agent_hierarchy h
+		JOIN agents a ON a.id = h.ancestor_id
+		WHERE h.descendant_id = ? AND h.descendant_type = ? AND h.level_difference > 0
+		  AND a.parent_agent_id IS NOT NULL AND a.status <> 'terminated'
+		ORDER BY h.level_difference`+limit, t.ID, t.Type)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var chain []overrideAncestor
+	for _, id := range ids {
+		a := &commissionTarget{CommissionTarget: CommissionTarget{Type: NodeAgent, ID: id}}
+		if err := tx.QueryRow("SELECT commission_rate FROM agents WHERE id = ?", id).Scan(&a.rate); err != nil {
+			return nil, err
+		}
+		configs, err := effectiveConfigs(tx, a, p)
+		if err != nil {
+			return nil, err
+		}
+		rates := map[string]float64{}
+		for gameType, cfg := range configs {
+			if cfg.hasRevenueShare() {
+				rates[gameType] = cfg.RevenueShareRate
+			} else {
+				rates[gameType] = 0
+			}
+		}
+		chain = append(chain, overrideAncestor{id: id, rates: rates})
+	}
+	return chain, nil
+}
+
+// rate 遊戲類型的營收分成比率（未另外配置的遊戲類型使用 all 的比率）
+func (a overrideAncestor) rate(gameType string) float64 {
+	if r, ok := a.rates[gameType]; ok {
+		return r
+	}
+	return a.rates[gameTypeAll]
+}
+
+// Runs 列出計算批次（依期間與對象篩選），回傳資料與總筆數
+func (s *CommissionService) Runs(f CommissionFilter) ([]CommissionRun, int64, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if f.TargetType != "" {
+		where += " AND r.target_type = ?"
+		args = append(args, f.TargetType)
+	}
+	if f.TargetID > 0 {
+		where += " AND r.target_id = ?"
+		args = append(args, f.TargetID)
+	}
+	if f.PeriodStart != nil {
+		where += " AND r.period_start >= ?"
+		args = append(args, *f.PeriodStart)
+	}
+	if f.PeriodEnd != nil {
+		where += " AND r.period_end <= ?"
+		args = append(args, *f.PeriodEnd)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM commission_runs r"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(commissionRunSelect+where+" ORDER BY r.period_start DESC, r.id DESC LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []CommissionRun{}
+	for rows.Next() {
+		r, err := scanCommissionRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *r)
+	}
+	return list, total, rows.Err()
+}
+
+// Run 取得計算批次
+func (s *CommissionService) Run(id int64) (*CommissionRun, error) {
+	r, err := scanCommissionRun(s.DB.QueryRow(commissionRunSelect+" WHERE r.id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCommissionRunNotFound
+	}
+	return r, err
+}
+
+// Commissions 列出佣金記錄（依受益者、批次、狀態與期間篩選），回傳資料與總筆數
+func (s *CommissionService) Commissions(f CommissionFilter) ([]Commission, int64, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if f.TargetType != "" {
+		if err := commissionTargetExists(s.DB, f.TargetType, f.TargetID); err != nil {
+			return nil, 0, err
+		}
+	}
+	switch f.TargetType {
+	case NodeAgent:
+		where += " AND c.agent_id = ? AND c.dealer_id IS NULL"
+		args = append(args, f.TargetID)
+	case NodeDealer:
+		where += " AND c.dealer_id = ?"
+		args = append(args, f.TargetID)
+	}
+	if f.RunID > 0 {
+		where += " AND c.run_id = ?"
+		args = append(args, f.RunID)
+	}
+	if f.SettlementID != "" {
+		where += " AND c.settlement_id = ?"
+		args = append(args, f.SettlementID)
+	}
+	if f.Status != "" {
+		where += " AND c.status = ?"
+		args = append(args, f.Status)
+	}
+	if f.PeriodStart != nil {
+		where += " AND c.period_start >= ?"
+		args = append(args, *f.PeriodStart)
+	}
+	if f.PeriodEnd != nil {
+		where += " AND c.period_end <= ?"
+		args = append(args, *f.PeriodEnd)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM commissions c"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(`
+		SELECT c.id, c.commission_id, c.run_id, c.agent_id, c.dealer_id, c.player_id, c.source_transaction_id,
+		       t.transaction_id, c.commission_type, COALESCE(c.game_type, ''), c.override_level, c.rate,
+		       c.base_amount, c.commission_amount, c.currency, c.status, COALESCE(c.settlement_id, ''),
+		       c.period_start, c.period_end,
+		       c.paid_at, c.created_at
+		FROM commissions c
+		JOIN transactions t ON t.id = c.source_transaction_id`+where+`
+		ORDER BY c.id DESC LIMIT ? OFFSET ?
+	`, append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []Commission{}
+	for rows.Next() {
+		var (
+			c                Commission
+			runID, dealerID  sql.NullInt64
+			periodStart, end time.Time
+			paidAt           sql.NullTime
+		)
+		if err := rows.Scan(&c.ID, &c.CommissionID, &runID, &c.AgentID, &dealerID, &c.PlayerID,
+			&c.SourceTransactionID, &c.TransactionCode, &c.CommissionType, &c.GameType, &c.OverrideLevel, &c.Rate,
+			&c.BaseAmount, &c.CommissionAmount, &c.Currency, &c.Status, &c.SettlementID, &periodStart, &end,
+			&paidAt, &c.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if runID.Valid {
+			c.RunID = &runID.Int64
+		}
+		c.DealerID = nullInt(dealerID)
+		c.PeriodStart, c.PeriodEnd = periodStart.Format("2006-01-02"), end.Format("2006-01-02")
+		c.PaidAt = nullTime(paidAt)
+		list = append(list, c)
+	}
+	return list, total, rows.Err()
+}
+
+// Totals 受益者依幣別與狀態的佣金合計
+func (s *CommissionService) Totals(targetType string, targetID int) ([]CommissionTotal, error) {
+	where := "agent_id = ? AND dealer_id IS NULL"
+	if targetType == NodeDealer {
+		where = "dealer_id = ?"
+	}
+	rows, err := s.DB.Query(`
+		SELECT currency, status,
+		       COALESCE(SUM(CASE WHEN override_level = 0 THEN commission_amount END), 0),
+		       COALESCE(SUM(CASE WHEN override_level > 0 THEN commission_amount END), 0),
+		       COUNT(*)
+		FROM commissions WHERE `+where+`
+		GROUP BY currency, status ORDER BY currency, status
+	`, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []CommissionTotal{}
+	for rows.Next() {
+		var t CommissionTotal
+		if err := rows.Scan(&t.Currency, &t.Status, &t.Direct, &t.Override, &t.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// commissionTargetExists 檢查代理商或經銷商是否存在
+func commissionTargetExists(q queryer, targetType string, id int) error {
+	table, notFound := "agents", ErrAgentNotFound
+	if targetType == NodeDealer {
+		table, notFound = "dealers", ErrDealerNotFound
+	}
+	var n int
+	if err := q.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ?", id).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// lockCommissionTarget 鎖定代理商或經銷商並取得其所屬代理商與基礎佣金比率
+func lockCommissionTarget(tx *sql.Tx, targetType string, id int) (*commissionTarget, error) {
+	t := &commissionTarget{CommissionTarget: CommissionTarget{Type: targetType, ID: id}}
+	switch targetType {
+	case NodeAgent:
+		var parentID sql.NullInt64
+		err := tx.QueryRow(`
+			SELECT id, commission_rate, status, parent_agent_id FROM agents WHERE id = ? FOR UPDATE
+		`, id).Scan(&t.agentID, &t.rate, &t.status, &parentID)
+		if err == sql.ErrNoRows {
+			return nil, ErrAgentNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		t.company = !parentID.Valid
+	case NodeDealer:
+		err := tx.QueryRow(`
+			SELECT agent_id, commission_rate, status FROM dealers WHERE id = ? FOR UPDATE
+		`, id).Scan(&t.agentID, &t.rate, &t.status)
+		if err == sql.ErrNoRows {
+			return nil, ErrDealerNotFound
+		} else if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidCommissionConfig
+	}
+	return t, nil
+}
+
+// effectiveConfigs 對象在期間內有效的配置（每個遊戲類型取生效日期最晚者），
+// 沒有 all 配置時以基礎佣金比率作為 all 的營收分成
+func effectiveConfigs(q queryer, t *commissionTarget, p CommissionPeriod) (map[string]*CommissionConfig, error) {
+	rows, err := q.Query(commissionConfigSelect+`
+		WHERE target_type = ? AND target_id = ? AND is_active AND effective_from <= ?
+		  AND (effective_to IS NULL OR effective_to >= ?)
+		ORDER BY effective_from DESC, id DESC
+	`, t.Type, t.ID, p.endDate(), p.startDate())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	configs := map[string]*CommissionConfig{}
+	for rows.Next() {
+		c, err := scanCommissionConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		if configs[c.GameType] == nil {
+			configs[c.GameType] = c
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if configs[gameTypeAll] == nil && t.rate > 0 {
+		configs[gameTypeAll] = &CommissionConfig{
+			GameType:          gameTypeAll,
+			CommissionType:    CommissionRevenueShare,
+			RevenueShareRate:  t.rate,
+			NegativeCarryover: true,
+			Tiers:             []CommissionTier{},
+		}
+	}
+	return configs, nil
+}
+
+func (c *CommissionConfig) hasRevenueShare() bool {
+	return c.CommissionType == CommissionRevenueShare || c.CommissionType == CommissionHybrid
+}
+
+func (c *CommissionConfig) hasCPA() bool {
+	return c.CommissionType == CommissionCPA || c.CommissionType == CommissionHybrid
+}
+
+// tier 依可計佣營收與玩家數取得營收分成比率與 CPA 金額，回傳適用的階梯索引（未達任何階梯為 nil）
+func (c *CommissionConfig) tier(revenue money.Amount, players int) (float64, money.Amount, *int) {
+	rate, cpa := c.RevenueShareRate, c.CPAAmount
+	var index *int
+	for i, t := range c.Tiers {
+		if revenue < t.MinRevenue || players < t.MinPlayers {
+			continue
+		}
+		if index == nil || t.MinRevenue >= c.Tiers[*index].MinRevenue {
+			i := i
+			index = &i
+		}
+	}
+	if index != nil {
+		rate = c.Tiers[*index].Rate
+		if c.Tiers[*index].CPAAmount.IsPositive() {
+			cpa = c.Tiers[*index].CPAAmount
+		}
+	}
+	return rate, cpa, index
+}
+
+// validateCommissionConfig 檢查配置並補上預設值
+func validateCommissionConfig(in *CommissionConfigInput) error {
+	if in.GameType == "" {
+		in.GameType = gameTypeAll
+	}
+	if in.SettlementPeriod == "" {
+		in.SettlementPeriod = "monthly"
+	}
+	if in.Tiers == nil {
+		in.Tiers = []CommissionTier{}
+	}
+	if in.RevenueShareRate < 0 || in.RevenueShareRate > 1 || in.CPAAmount.IsNegative() ||
+		in.MinPlayers < 0 || in.MinRevenue.IsNegative() {
+		return ErrInvalidCommissionConfig
+	}
+	if in.EffectiveTo != nil && in.EffectiveTo.Before(in.EffectiveFrom) {
+		return ErrInvalidCommissionConfig
+	}
+	for _, t := range in.Tiers {
+		if t.Rate < 0 || t.Rate > 1 || t.MinRevenue.IsNegative() || t.MinPlayers < 0 || t.CPAAmount.IsNegative() {
+			return ErrInvalidCommissionConfig
+		}
+	}
+	return nil
+}
+
+// percentOf 金額乘以比率，四捨五入至分
+func percentOf(a money.Amount, rate float64) money.Amount {
+	return money.FromFloat(a.Float64() * rate)
+}
+
+// allocateCommission 將佣金依各來源交易佔淨營收的比例分配，捨入差額計入最後一筆
+func allocateCommission(sources []commissionSource, net, total money.Amount, tmpl commissionRow) []commissionRow {
+	if len(sources) == 0 || net == 0 {
+		return nil
+	}
+	ratio := total.Float64() / net.Float64()
+	rows := make([]commissionRow, 0, len(sources))
+	var sum money.Amount
+	for _, src := range sources {
+		r := tmpl
+		r.source, r.base = src, src.amount
+		r.amount = money.FromFloat(src.amount.Float64() * ratio)
+		sum += r.amount
+		rows = append(rows, r)
+	}
+	rows[len(rows)-1].amount += total - sum
+	return rows
+}
+
+// insertCommissionsTx 批次寫入佣金記錄
+func insertCommissionsTx(tx *sql.Tx, run *CommissionRun, rows []commissionRow) error {
+	for start := 0; start < len(rows); start += commissionInsertBatch {
+		end := start + commissionInsertBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*15)
+		for _, r := range rows[start:end] {
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			var gameType interface{}
+			if r.kind == commissionTypeLoss {
+				gameType = nullString(r.source.gameType)
+			}
+			args = append(args, newCommissionID(), run.ID, r.agentID, r.dealerID, r.source.playerID, r.source.id,
+				r.kind, gameType, r.level, r.rate, r.base, r.amount, run.Currency, run.PeriodStart, run.PeriodEnd)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO commissions
+				(commission_id, run_id, agent_id, dealer_id, player_id, source_transaction_id, commission_type,
+				 game_type, override_level, rate, base_amount, commission_amount, currency, period_start, period_end)
+			VALUES `+strings.Join(values, ", "), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newCommissionRunID 產生分潤計算批次編號
+func newCommissionRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return "CR" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
+
+// newCommissionID 產生佣金編號（同一批次可能寫入大量記錄，使用較長的隨機碼）
+func newCommissionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "CM" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
+
+const commissionConfigSelect = `
+	SELECT id, config_name, target_type, target_id, game_type, commission_type, revenue_share_rate, cpa_amount,
+	       min_players, min_revenue, tier_config, negative_carryover, settlement_period, is_active,
+	       effective_from, effective_to, created_by, created_at, updated_at
+	FROM commission_configs`
+
+func scanCommissionConfig(row rowScanner) (*CommissionConfig, error) {
+	c := &CommissionConfig{}
+	var (
+		tiers     []byte
+		from      time.Time
+		to        sql.NullTime
+		createdBy sql.NullInt64
+	)
+	err := row.Scan(&c.ID, &c.ConfigName, &c.TargetType, &c.TargetID, &c.GameType, &c.CommissionType,
+		&c.RevenueShareRate, &c.CPAAmount, &c.MinPlayers, &c.MinRevenue, &tiers, &c.NegativeCarryover,
+		&c.SettlementPeriod, &c.IsActive, &from, &to, &createdBy, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCommissionConfigNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	c.Tiers = []CommissionTier{}
+	if len(tiers) > 0 {
+		if err := json.Unmarshal(tiers, &c.Tiers); err != nil {
+			return nil, fmt.Errorf("分潤配置 %d 的階梯設定格式錯誤: %v", c.ID, err)
+		}
+	}
+	c.EffectiveFrom = from.Format("2006-01-02")
+	c.EffectiveTo = formatDate(to)
+	c.CreatedBy = nullInt(createdBy)
+	return c, nil
+}
+
+const commissionRunSelect = `
+	SELECT r.id, r.run_id, r.target_type, r.target_id, r.agent_id, r.config_id, r.game_type, r.currency,
+	       r.period_start, r.period_end, r.commission_type, r.total_bets, r.total_wins, r.net_revenue, r.carry_in,
+	       r.commissionable_revenue, r.carry_out, r.active_players, r.new_players, r.qualified, r.rate,
+	       r.revenue_commission, r.cpa_commission, r.override_commission, r.details, r.created_by, r.created_at
+	FROM commission_runs r`
+
+func scanCommissionRun(row rowScanner) (*CommissionRun, error) {
+	r := &CommissionRun{}
+	var (
+		configID, createdBy sql.NullInt64
+		start, end          time.Time
+		details             []byte
+	)
+	if err := row.Scan(&r.ID, &r.RunID, &r.TargetType, &r.TargetID, &r.AgentID, &configID, &r.GameType, &r.Currency,
+		&start, &end, &r.CommissionType, &r.TotalBets, &r.TotalWins, &r.NetRevenue, &r.CarryIn,
+		&r.CommissionableRevenue, &r.CarryOut, &r.ActivePlayers, &r.NewPlayers, &r.Qualified, &r.Rate,
+		&r.RevenueCommission, &r.CPACommission, &r.OverrideCommission, &details, &createdBy, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	r.ConfigID, r.CreatedBy = nullInt(configID), nullInt(createdBy)
+	r.PeriodStart, r.PeriodEnd = start.Format("2006-01-02"), end.Format("2006-01-02")
+	r.Details.Overrides = []CommissionOverride{}
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, &r.Details); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"nexus-gaming-backend/money"
+)
+
+// Dealer 經銷商
+type Dealer struct {
+	ID                 int               `json:"id"`
+	DealerCode         string            `json:"dealer_code"`
+	DealerName         string            `json:"dealer_name"`
+	ContactPerson      string            `json:"contact_person"`
+	Email              string            `json:"email"`
+	Phone              string            `json:"phone,omitempty"`
+	Address            string            `json:"address,omitempty"`
+	IDNumber           string            `json:"id_number,omitempty"`    // 遮罩後的身分證號碼
+	BankAccount        map[string]string `json:"bank_account,omitempty"` // 遮罩後的銀行帳戶資訊
+	AgentID            int               `json:"agent_id"`
+	AgentName          string            `json:"agent_name"`
+	UserID             int               `json:"user_id"`
+	Username           string            `json:"username"`
+	Status             string            `json:"status"`
+	CommissionRate     float64           `json:"commission_rate"`
+	MaxPlayers         int               `json:"max_players"`
+	CurrentPlayers     int               `json:"current_players"` // 未刪除的玩家數量
+	TotalRevenue       money.Amount      `json:"total_revenue"`
+	TotalCommission    money.Amount      `json:"total_commission"`
+	LastSettlementDate *string           `json:"last_settlement_date,omitempty"`
+	Territory          string            `json:"territory,omitempty"`
+	Notes              string            `json:"notes,omitempty"`
+	CreatedBy          *int              `json:"created_by,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+// DealerInput 建立或更新經銷商（登入帳號與密碼僅於建立時使用，更新時銀行帳戶為 nil 表示不變更）
+type DealerInput struct {
+	DealerCode     string
+	DealerName     string
+	ContactPerson  string
+	Email          string
+	Phone          string
+	Address        string
+	IDNumber       string
+	BankAccount    map[string]string
+	CommissionRate float64
+	MaxPlayers     int
+	Territory      string
+	Notes          string
+	Username       string
+	Password       string
+}
+
+// DealerFilter 經銷商查詢條件
+type DealerFilter struct {
+	AgentID int
+	Status  string
+	Keyword string
+	Page    int
+	Limit   int
+}
+
+// DealerPlayer 經銷商的玩家
+type DealerPlayer struct {
+	ID            int64        `json:"id"`
+	PlayerID      string       `json:"player_id"`
+	Username      string       `json:"username"`
+	Nickname      string       `json:"nickname,omitempty"`
+	Status        string       `json:"status"`
+	VIPLevel      int          `json:"vip_level"`
+	TotalDeposit  money.Amount `json:"total_deposit"`
+	TotalWithdraw money.Amount `json:"total_withdraw"`
+	LastLoginAt   *time.Time   `json:"last_login_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// Dealers 依條件列出經銷商，回傳資料與總筆數
+func (s *AgentService) Dealers(f DealerFilter) ([]Dealer, int64, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if f.AgentID > 0 {
+		where += " AND d.agent_id = ?"
+		args = append(args, f.AgentID)
+	}
+	if f.Status != "" {
+		where += " AND d.status = ?"
+		args = append(args, f.Status)
+	}
+	if f.Keyword != "" {
+		where += " AND (d.dealer_code LIKE ? OR d.dealer_name LIKE ? OR d.contact_person LIKE ?)"
+		kw := "%" + f.Keyword + "%"
+		args = append(args, kw, kw, kw)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM dealers d"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(dealerSelect+where+" ORDER BY d.id DESC LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []Dealer{}
+	for rows.Next() {
+		d, err := scanDealer(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *d)
+	}
+	return list, total, rows.Err()
+}
+
+// GetDealer 取得經銷商
+func (s *AgentService) GetDealer(id int) (*Dealer, error) {
+	return loadDealer(s.DB, "d.id = ?", id)
+}
+
+// CreateDealer 在代理商下建立經銷商與其登入帳號（代理商需可營運且未達經銷商數量上限）
+func (s *AgentService) CreateDealer(agentID int, in DealerInput, createdBy *int) (*Dealer, error) {
+	bank, err := sealBankAccount(columnDealerBankAccount, in.BankAccount)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	err = withAgentTx(s.DB, func(tx *sql.Tx) error {
+		agent, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", agentID)
+		if err != nil {
+			return err
+		}
+		if err := agent.operable(); err != nil {
+			return err
+		}
+		if agent.MaxDealers > 0 && agent.CurrentDealers >= agent.MaxDealers {
+			return ErrDealerLimit
+		}
+		userID, err := createLoginTx(tx, in.Username, in.Email, in.Password, "dealer")
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(`
+			INSERT INTO dealers
+				(dealer_code, dealer_name, contact_person, email, phone, address, id_number, bank_account,
+				 agent_id, user_id, commission_rate, max_players, territory, notes, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, in.DealerCode, in.DealerName, in.ContactPerson, in.Email, nullString(in.Phone), nullString(in.Address),
+			nullString(in.IDNumber), bank, agentID, userID, in.CommissionRate, in.MaxPlayers,
+			nullString(in.Territory), nullString(in.Notes), createdBy)
+		if isDuplicateKey(err) {
+			return ErrDealerCodeExists
+		} else if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		return s.Hierarchy.InsertDealerTx(tx, int(id), agentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDealer(int(id))
+}
+
+// UpdateDealer 更新經銷商資料（編號、所屬代理商與登入帳號不可在此變更）
+func (s *AgentService) UpdateDealer(id int, in DealerInput) (*Dealer, error) {
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		d, err := loadDealer(tx, "d.id = ? FOR UPDATE OF d", id)
+		if err != nil {
+			return err
+		}
+		if d.Status == AgentStatusTerminated {
+			return ErrStatusTransition
+		}
+		if in.MaxPlayers > 0 && d.CurrentPlayers > in.MaxPlayers {
+			return ErrPlayerLimit
+		}
+		query := `
+			UPDATE dealers SET dealer_name = ?, contact_person = ?, email = ?, phone = ?, address = ?,
+				commission_rate = ?, max_players = ?, territory = ?, notes = ?`
+		args := []interface{}{in.DealerName, in.ContactPerson, in.Email, nullString(in.Phone), nullString(in.Address),
+			in.CommissionRate, in.MaxPlayers, nullString(in.Territory), nullString(in.Notes)}
+		if in.IDNumber != "" {
+			query += ", id_number = ?"
+			args = append(args, in.IDNumber)
+		}
+		if in.BankAccount != nil {
+			bank, err := sealBankAccount(columnDealerBankAccount, in.BankAccount)
+			if err != nil {
+				return err
+			}
+			query += ", bank_account = ?"
+			args = append(args, bank)
+		}
+		_, err = tx.Exec(query+" WHERE id = ?", append(args, id)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDealer(id)
+}
+
+// UpdateDealerStatus 變更經銷商狀態（終止請使用 TerminateDealer）；啟用時所屬代理商需可營運
+func (s *AgentService) UpdateDealerStatus(id int, status, reason string, operatorID *int) (*Dealer, error) {
+	if status == AgentStatusTerminated {
+		return nil, ErrReassignmentRequired
+	}
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		d, err := loadDealer(tx, "d.id = ? FOR UPDATE OF d", id)
+		if err != nil {
+			return err
+		}
+		if !canTransition(d.Status, status) {
+			return ErrStatusTransition
+		}
+		if status == AgentStatusActive {
+			agent, err := loadAgent(tx, "a.id = ?", d.AgentID)
+			if err != nil {
+				return err
+			}
+			if err := agent.operable(); err != nil {
+				return err
+			}
+		}
+		return setDealerStatusTx(tx, d, status, reason, nil, operatorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDealer(id)
+}
+
+// TerminateDealer 終止經銷商並依指定方式移轉其玩家（有玩家時必須指定移轉方式）
+func (s *AgentService) TerminateDealer(id int, plan PlayerReassignment, reason string, operatorID *int) (*Dealer, *ReassignmentResult, error) {
+	result := &ReassignmentResult{}
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		d, err := loadDealer(tx, "d.id = ? FOR UPDATE OF d", id)
+		if err != nil {
+			return err
+		}
+		if !canTransition(d.Status, AgentStatusTerminated) {
+			return ErrStatusTransition
+		}
+		if d.CurrentPlayers > 0 {
+			if plan.Action == "" {
+				return ErrReassignmentRequired
+			}
+			if plan.TargetDealerID != nil && *plan.TargetDealerID == id {
+				return ErrInvalidReassignment
+			}
+			move := newPlayerMove(TransferDealerTermination, reason, operatorID)
+			if err := s.movePlayersTx(tx, "dealer_id = ?", []interface{}{id}, d.CurrentPlayers, plan, move, result); err != nil {
+				return err
+			}
+		}
+		details, _ := json.Marshal(result)
+		return setDealerStatusTx(tx, d, AgentStatusTerminated, reason, details, operatorID)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := s.GetDealer(id)
+	return d, result, err
+}
+
+// DeleteDealer 刪除經銷商（僅限沒有玩家、佣金與結算記錄者，其餘請改為終止），登入帳號改為停用
+func (s *AgentService) DeleteDealer(id int) error {
+	return withAgentTx(s.DB, func(tx *sql.Tx) error {
+		d, err := loadDealer(tx, "d.id = ? FOR UPDATE OF d", id)
+		if err != nil {
+			return err
+		}
+		var used int
+		if err := tx.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM players WHERE dealer_id = ?)
+			     + (SELECT COUNT(*) FROM dealer_settlements WHERE dealer_id = ?)
+			     + (SELECT COUNT(*) FROM commissions WHERE dealer_id = ?)
+			     + (SELECT COUNT(*) FROM agent_wallet_transactions WHERE owner_type = 'dealer' AND owner_id = ?)
+		`, id, id, id, id).Scan(&used); err != nil {
+			return err
+		}
+		if used > 0 {
+			return ErrDealerInUse
+		}
+		if _, err := tx.Exec("DELETE FROM agent_status_logs WHERE target_type = 'dealer' AND target_id = ?", id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM commission_configs WHERE target_type = 'dealer' AND target_id = ?", id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM agent_wallets WHERE owner_type = 'dealer' AND owner_id = ?", id); err != nil {
+			return err
+		}
+		if err := s.Hierarchy.DeleteNodeTx(tx, NodeDealer, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM dealers WHERE id = ?", id); err != nil {
+			return err
+		}
+		return syncLoginStatusTx(tx, d.UserID, AgentStatusInactive)
+	})
+}
+
+// DealerPlayers 列出經銷商的玩家，回傳資料與總筆數
+func (s *AgentService) DealerPlayers(dealerID int, status string, page, limit int) ([]DealerPlayer, int64, error) {
+	if _, err := s.GetDealer(dealerID); err != nil {
+		return nil, 0, err
+	}
+	where := " WHERE dealer_id = ?"
+	args := []interface{}{dealerID}
+	if status != "" {
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM players"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(`
+		SELECT id, player_id, username, COALESCE(nickname, ''), status, vip_level, total_deposit, total_withdraw,
+		       last_login_at, created_at
+		FROM players`+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []DealerPlayer{}
+	for rows.Next() {
+		var (
+			p         DealerPlayer
+			lastLogin sql.NullTime
+		)
+		if err := rows.Scan(&p.ID, &p.PlayerID, &p.Username, &p.Nickname, &p.Status, &p.VIPLevel,
+			&p.TotalDeposit, &p.TotalWithdraw, &lastLogin, &p.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		p.LastLoginAt = nullTime(lastLogin)
+		list = append(list, p)
+	}
+	return list, total, rows.Err()
+}
+
+// operable 經銷商是否可承接玩家
+func (d *Dealer) operable() error {
+	if d.Status != AgentStatusActive {
+		return ErrDealerNotOperable
+	}
+	return nil
+}
+
+// setDealerStatusTx 變更經銷商狀態、同步登入帳號並記錄
+func setDealerStatusTx(tx *sql.Tx, d *Dealer, status, reason string, details []byte, operatorID *int) error {
+	if _, err := tx.Exec("UPDATE dealers SET status = ? WHERE id = ?", status, d.ID); err != nil {
+		return err
+	}
+	if err := syncLoginStatusTx(tx, d.UserID, status); err != nil {
+		return err
+	}
+	return logStatusTx(tx, "dealer", d.ID, d.Status, status, reason, details, operatorID)
+}
+
+const dealerSelect = `
+	SELECT d.id, d.dealer_code, d.dealer_name, d.contact_person, d.email, COALESCE(d.phone, ''),
+	       COALESCE(d.address, ''), COALESCE(d.id_number, ''), d.bank_account, d.agent_id, a.agent_name,
+	       d.user_id, COALESCE(u.username, ''), d.status, d.commission_rate, d.max_players,
+	       (SELECT COUNT(*) FROM players p WHERE p.dealer_id = d.id AND p.status <> 'deleted'),
+	       d.total_revenue, d.total_commission, d.last_settlement_date, COALESCE(d.territory, ''),
+	       COALESCE(d.notes, ''), d.created_by, d.created_at, d.updated_at
+	FROM dealers d
+	JOIN agents a ON a.id = d.agent_id
+	LEFT JOIN users u ON u.id = d.user_id`
+
+func loadDealer(q queryer, where string, args ...interface{}) (*Dealer, error) {
+	d, err := scanDealer(q.QueryRow(dealerSelect+" WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrDealerNotFound
+	}
+	return d, err
+}
+
+func scanDealer(row rowScanner) (*Dealer, error) {
+	d := &Dealer{}
+	var (
+		bank           []byte
+		lastSettlement sql.NullTime
+		createdBy      sql.NullInt64
+	)
+	if err := row.Scan(&d.ID, &d.DealerCode, &d.DealerName, &d.ContactPerson, &d.Email, &d.Phone,
+		&d.Address, &d.IDNumber, &bank, &d.AgentID, &d.AgentName,
+		&d.UserID, &d.Username, &d.Status, &d.CommissionRate, &d.MaxPlayers,
+		&d.CurrentPlayers,
+		&d.TotalRevenue, &d.TotalCommission, &lastSettlement, &d.Territory,
+		&d.Notes, &createdBy, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	account, err := openBankAccount(columnDealerBankAccount, bank)
+	if err != nil {
+		return nil, err
+	}
+	d.BankAccount = account
+	if d.IDNumber != "" {
+		d.IDNumber = maskNumber(d.IDNumber)
+	}
+	d.LastSettlementDate, d.CreatedBy = formatDate(lastSettlement), nullInt(createdBy)
+	return d, nil
+}
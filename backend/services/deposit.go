@@ -0,0 +1,499 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/payment"
+)
+
+// 儲值相關錯誤
+var (
+	ErrDepositNotFound    = errors.New("儲值訂單不存在")
+	ErrGatewayUnavailable = errors.New("支付閘道暫時無法使用")
+)
+
+// 回呼與狀態查詢的處理結果
+const (
+	DepositResultCredited       = "credited"            // 已入帳
+	DepositResultFailed         = "failed"              // 付款失敗
+	DepositResultExpired        = "expired"             // 逾期未付款
+	DepositResultPending        = "pending"             // 尚未付款，不異動
+	DepositResultIgnored        = "ignored"             // 訂單已結束，不再異動
+	DepositResultDuplicate      = "duplicate"           // 重送的回呼事件
+	DepositResultUnknown        = "unknown_transaction" // 查無對應訂單
+	DepositResultAmountMismatch = "amount_mismatch"     // 金額或幣別與訂單不符，轉人工處理
+)
+
+// 閘道呼叫逾時
+const gatewayTimeout = 10 * time.Second
+
+// depositExpiryBatch 每次逾期檢查最多處理的訂單數
+const depositExpiryBatch = 200
+
+// Deposit 儲值訂單
+type Deposit struct {
+	DepositID            string       `json:"deposit_id"`
+	PlayerID             int64        `json:"player_id"`
+	Amount               money.Amount `json:"amount"`
+	Currency             string       `json:"currency"`
+	PaymentMethod        string       `json:"payment_method"`
+	PaymentProvider      string       `json:"payment_provider"`
+	Status               string       `json:"status"`
+	GatewayTransactionID string       `json:"gateway_transaction_id,omitempty"`
+	ProcessorFee         money.Amount `json:"processor_fee"`
+	PaymentURL           string       `json:"payment_url,omitempty"`
+	TransactionID        string       `json:"transaction_id,omitempty"` // 入帳交易流水號
+	Notes                string       `json:"notes,omitempty"`
+	ProcessedAt          *time.Time   `json:"processed_at,omitempty"`
+	ExpiredAt            *time.Time   `json:"expired_at,omitempty"`
+	CreatedAt            time.Time    `json:"created_at"`
+}
+
+// DepositRequest 建立儲值訂單
+type DepositRequest struct {
+	PlayerID        int64
+	Amount          money.Amount
+	Currency        string // 空字串表示預設幣別
+	PaymentMethod   string
+	PaymentProvider string
+	IPAddress       string
+	UserAgent       string
+}
+
+// DepositFilter 儲值訂單查詢條件
+type DepositFilter struct {
+	PlayerID int64
+	Status   string
+	Provider string
+	Page     int
+	Limit    int
+}
+
+var (
+	paymentGateways     *payment.Registry
+	paymentGatewaysOnce sync.Once
+)
+
+// PaymentGateways 共用的支付閘道註冊表（模擬閘道的交易保存在記憶體，建立與回呼必須使用同一個實例）
+//
+// 模擬閘道未設定簽章金鑰時不註冊，避免以公開的預設金鑰偽造回呼入帳。
+func PaymentGateways() *payment.Registry {
+	paymentGatewaysOnce.Do(func() {
+		paymentGateways = payment.NewRegistry()
+		if cfg := config.AppConfig; cfg != nil && cfg.Payment.FakeGatewayEnabled {
+			if cfg.Payment.FakeGatewaySecret == "" {
+				log.Printf("已啟用模擬閘道但未設定 PAYMENT_FAKE_GATEWAY_SECRET，不註冊模擬閘道")
+				return
+			}
+			paymentGateways.Register(payment.NewFakeGateway(cfg.Payment.FakeGatewaySecret, cfg.Payment.WebhookTolerance))
+		}
+	})
+	return paymentGateways
+}
+
+// DepositService 儲值服務
+//
+// 建立訂單後向閘道建立付款意圖；入帳只依閘道回呼或主動查詢的結果進行，
+// 訂單列以 FOR UPDATE 鎖定，同一訂單只會入帳一次。
+type DepositService struct {
+	DB          *sql.DB
+	Wallet      *WalletService
+	FX          *FXService
+	Gateways    *payment.Registry
+	Bonus       *BonusService
+	ExpireAfter time.Duration
+}
+
+// NewDepositService 建立新的儲值服務
+func NewDepositService() *DepositService {
+	fx := NewFXService()
+	expireAfter := 30 * time.Minute
+	if config.AppConfig != nil && config.AppConfig.Payment.DepositExpireAfter > 0 {
+		expireAfter = config.AppConfig.Payment.DepositExpireAfter
+	}
+	return &DepositService{
+		DB:          fx.DB,
+		Wallet:      fx.Wallet,
+		FX:          fx,
+		Gateways:    PaymentGateways(),
+		Bonus:       NewBonusService(),
+		ExpireAfter: expireAfter,
+	}
+}
+
+// Create 建立儲值訂單並向閘道建立付款意圖
+func (s *DepositService) Create(req DepositRequest) (*Deposit, error) {
+	if !req.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	gw, err := s.Gateways.Get(req.PaymentProvider)
+	if err != nil {
+		return nil, err
+	}
+	currency := s.Wallet.CurrencyOf(req.Currency)
+	if err := s.FX.CheckCurrency(currency); err != nil {
+		return nil, err
+	}
+	var status string
+	err = s.DB.QueryRow("SELECT status FROM players WHERE id = ?", req.PlayerID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, ErrPlayerNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if status == "deleted" {
+		return nil, ErrPlayerInactive
+	}
+
+	expiredAt := time.Now().Add(s.ExpireAfter)
+	d := &Deposit{
+		DepositID:       newDepositID(),
+		PlayerID:        req.PlayerID,
+		Amount:          req.Amount,
+		Currency:        currency,
+		PaymentMethod:   req.PaymentMethod,
+		PaymentProvider: gw.Name(),
+		Status:          "pending",
+		ExpiredAt:       &expiredAt,
+		CreatedAt:       time.Now(),
+	}
+	if _, err := s.DB.Exec(`
+		INSERT INTO deposits
+			(deposit_id, player_id, amount, currency, payment_method, payment_provider, status, expired_at, ip_address, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, 'pending', ?, ?, ?)
+	`, d.DepositID, d.PlayerID, d.Amount, d.Currency, d.PaymentMethod, d.PaymentProvider, expiredAt,
+		nullString(req.IPAddress), nullString(req.UserAgent)); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+	defer cancel()
+	intent, err := gw.CreateIntent(ctx, payment.IntentRequest{
+		Reference: d.DepositID,
+		PlayerID:  d.PlayerID,
+		Amount:    d.Amount,
+		Currency:  d.Currency,
+		Method:    d.PaymentMethod,
+		ExpiresAt: expiredAt,
+	})
+	if err != nil {
+		s.DB.Exec("UPDATE deposits SET status = 'failed', notes = ? WHERE deposit_id = ?", "建立付款意圖失敗: "+err.Error(), d.DepositID)
+		return nil, fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
+	}
+	raw := map[string]interface{}{"intent": intent.Raw, "payment_url": intent.PaymentURL}
+	response, _ := json.Marshal(raw)
+	if _, err := s.DB.Exec(`
+		UPDATE deposits SET status = 'processing', gateway_transaction_id = ?, gateway_response = ?
+		WHERE deposit_id = ? AND status = 'pending'
+	`, intent.GatewayTransactionID, string(response), d.DepositID); err != nil {
+		return nil, err
+	}
+	d.Status, d.GatewayTransactionID, d.PaymentURL = "processing", intent.GatewayTransactionID, intent.PaymentURL
+	return d, nil
+}
+
+// HandleWebhook 處理閘道回呼：驗證簽章、以事件ID去重，再依回報狀態更新訂單並入帳
+//
+// 事件列與訂單異動在同一交易：處理失敗時一併回滾，閘道重送時會重新處理。
+func (s *DepositService) HandleWebhook(provider string, header http.Header, body []byte) (string, error) {
+	gw, err := s.Gateways.Get(provider)
+	if err != nil {
+		return "", err
+	}
+	ev, err := gw.VerifyCallback(header, body)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`
+		INSERT IGNORE INTO payment_webhook_events
+			(provider, event_id, gateway_transaction_id, reference_id, event_status, payload)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, provider, ev.EventID, ev.GatewayTransactionID, nullString(ev.Reference), string(ev.Status), string(ev.Payload))
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return DepositResultDuplicate, nil
+	}
+
+	result := DepositResultUnknown
+	d, err := s.lock(tx, "d.payment_provider = ? AND d.gateway_transaction_id = ?", provider, ev.GatewayTransactionID)
+	if err == nil {
+		result, err = s.apply(tx, d, &ev.TransactionStatus, "回呼")
+	} else if errors.Is(err, ErrDepositNotFound) {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(`
+		UPDATE payment_webhook_events SET result = ? WHERE provider = ? AND event_id = ?
+	`, result, provider, ev.EventID); err != nil {
+		return "", err
+	}
+	return result, tx.Commit()
+}
+
+// Confirm 主動向閘道查詢訂單狀態並更新（回呼遺失時由營運端觸發）
+func (s *DepositService) Confirm(depositID string) (*Deposit, string, error) {
+	d, err := s.Get(depositID)
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := s.refresh(d, false)
+	if err != nil {
+		return nil, "", err
+	}
+	d, err = s.Get(depositID)
+	return d, result, err
+}
+
+// ExpireDue 處理已過付款期限的訂單：先向閘道查詢，已付款者入帳，其餘標記為 expired
+func (s *DepositService) ExpireDue() (int, error) {
+	rows, err := s.DB.Query(`
+		SELECT deposit_id FROM deposits
+		WHERE status IN ('pending', 'processing') AND expired_at IS NOT NULL AND expired_at <= NOW()
+		ORDER BY expired_at
+		LIMIT ?
+	`, depositExpiryBatch)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, id := range ids {
+		d, err := s.Get(id)
+		if err != nil {
+			return processed, err
+		}
+		if _, err := s.refresh(d, true); err != nil {
+			log.Printf("儲值訂單 %s 逾期處理失敗: %v", id, err)
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// Run 定期處理逾期的儲值訂單，直到 ctx 結束（阻塞執行，應以 goroutine 啟動）
+func (s *DepositService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.ExpireDue(); err != nil {
+			log.Printf("處理逾期儲值訂單失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已處理 %d 筆逾期儲值訂單", n)
+		}
+	}
+}
+
+// refresh 查詢閘道狀態並套用；expire 為 true 時，閘道仍未付款或查無交易的訂單標記為 expired
+func (s *DepositService) refresh(d *Deposit, expire bool) (string, error) {
+	status := &payment.TransactionStatus{GatewayTransactionID: d.GatewayTransactionID, Status: payment.StatusPending}
+	if gw, err := s.Gateways.Get(d.PaymentProvider); err == nil && d.GatewayTransactionID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+		st, err := gw.QueryStatus(ctx, d.GatewayTransactionID)
+		cancel()
+		if err == nil {
+			status = st
+		} else if !errors.Is(err, payment.ErrTransactionUnknown) || !expire {
+			return "", fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
+		}
+	} else if !expire {
+		return "", payment.ErrUnknownProvider
+	}
+	if expire && status.Status == payment.StatusPending {
+		status.Status = payment.StatusExpired
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	locked, err := s.lock(tx, "d.deposit_id = ?", d.DepositID)
+	if err != nil {
+		return "", err
+	}
+	source := "狀態查詢"
+	if expire {
+		source = "逾期檢查"
+	}
+	result, err := s.apply(tx, locked, status, source)
+	if err != nil {
+		return "", err
+	}
+	return result, tx.Commit()
+}
+
+// apply 依閘道回報的狀態更新已鎖定的訂單；付款成功時入帳至訂單幣別錢包
+//
+// 已完成、失敗或取消的訂單不再異動；逾期的訂單若之後回報付款成功仍會入帳（款項已實際收到）。
+func (s *DepositService) apply(tx *sql.Tx, d *Deposit, st *payment.TransactionStatus, source string) (string, error) {
+	switch d.Status {
+	case "completed", "failed", "cancelled":
+		return DepositResultIgnored, nil
+	case "expired":
+		if st.Status != payment.StatusSucceeded {
+			return DepositResultIgnored, nil
+		}
+	}
+
+	switch st.Status {
+	case payment.StatusSucceeded:
+		if st.Amount != d.Amount || (st.Currency != "" && !strings.EqualFold(st.Currency, d.Currency)) {
+			note := fmt.Sprintf("%s金額不符：閘道回報 %s %s，訂單為 %s %s，需人工處理",
+				source, st.Amount, st.Currency, d.Amount, d.Currency)
+			_, err := tx.Exec("UPDATE deposits SET status = 'failed', notes = ? WHERE deposit_id = ?", note, d.DepositID)
+			return DepositResultAmountMismatch, err
+		}
+		t, err := s.Wallet.CreditTx(tx, WalletEntry{
+			PlayerID:      d.PlayerID,
+			Currency:      d.Currency,
+			Type:          "deposit",
+			Amount:        d.Amount,
+			ReferenceID:   d.DepositID,
+			ReferenceType: "deposit",
+			Description:   fmt.Sprintf("儲值入帳（%s %s）", d.PaymentProvider, d.GatewayTransactionID),
+		})
+		if err != nil {
+			return "", err
+		}
+		_, err = tx.Exec(`
+			UPDATE deposits SET status = 'completed', processor_fee = ?, processed_at = NOW(), notes = ?
+			WHERE deposit_id = ?
+		`, st.Fee, "入帳交易 "+t.TransactionID+"（"+source+"）", d.DepositID)
+		if err != nil {
+			return "", err
+		}
+		return DepositResultCredited, s.Bonus.OnDepositTx(tx, d.PlayerID, d.DepositID, d.Currency, d.Amount)
+	case payment.StatusFailed:
+		_, err := tx.Exec("UPDATE deposits SET status = 'failed', notes = ? WHERE deposit_id = ?", source+"回報付款失敗", d.DepositID)
+		return DepositResultFailed, err
+	case payment.StatusExpired:
+		_, err := tx.Exec("UPDATE deposits SET status = 'expired', notes = ? WHERE deposit_id = ?", source+"：逾期未付款", d.DepositID)
+		return DepositResultExpired, err
+	default:
+		return DepositResultPending, nil
+	}
+}
+
+// Get 取得儲值訂單
+func (s *DepositService) Get(depositID string) (*Deposit, error) {
+	return s.load(s.DB, "d.deposit_id = ?", depositID)
+}
+
+// List 依條件列出儲值訂單，回傳資料與總筆數
+func (s *DepositService) List(f DepositFilter) ([]Deposit, int64, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if f.PlayerID > 0 {
+		where += " AND d.player_id = ?"
+		args = append(args, f.PlayerID)
+	}
+	if f.Status != "" {
+		where += " AND d.status = ?"
+		args = append(args, f.Status)
+	}
+	if f.Provider != "" {
+		where += " AND d.payment_provider = ?"
+		args = append(args, f.Provider)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM deposits d"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(depositSelect+where+" ORDER BY d.id DESC LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []Deposit{}
+	for rows.Next() {
+		d, err := scanDeposit(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *d)
+	}
+	return list, total, rows.Err()
+}
+
+func (s *DepositService) lock(tx *sql.Tx, where string, args ...interface{}) (*Deposit, error) {
+	return s.load(tx, where+" FOR UPDATE OF d", args...)
+}
+
+func (s *DepositService) load(q queryer, where string, args ...interface{}) (*Deposit, error) {
+	d, err := scanDeposit(q.QueryRow(depositSelect+" WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrDepositNotFound
+	}
+	return d, err
+}
+
+const depositSelect = `
+	SELECT d.deposit_id, d.player_id, d.amount, d.currency, d.payment_method, COALESCE(d.payment_provider, ''),
+	       d.status, COALESCE(d.gateway_transaction_id, ''), d.processor_fee,
+	       COALESCE(JSON_UNQUOTE(JSON_EXTRACT(d.gateway_response, '$.payment_url')), ''),
+	       COALESCE(t.transaction_id, ''), COALESCE(d.notes, ''), d.processed_at, d.expired_at, d.created_at
+	FROM deposits d
+	LEFT JOIN transactions t ON t.reference_type = 'deposit' AND t.reference_id = d.deposit_id`
+
+func scanDeposit(row rowScanner) (*Deposit, error) {
+	d := &Deposit{}
+	var processedAt, expiredAt sql.NullTime
+	if err := row.Scan(&d.DepositID, &d.PlayerID, &d.Amount, &d.Currency, &d.PaymentMethod, &d.PaymentProvider,
+		&d.Status, &d.GatewayTransactionID, &d.ProcessorFee, &d.PaymentURL, &d.TransactionID, &d.Notes,
+		&processedAt, &expiredAt, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	if processedAt.Valid {
+		d.ProcessedAt = &processedAt.Time
+	}
+	if expiredAt.Valid {
+		d.ExpiredAt = &expiredAt.Time
+	}
+	return d, nil
+}
+
+// newDepositID 產生儲值訂單號
+func newDepositID() string {
+	return "DP" + strings.TrimPrefix(NewTransactionID(), "TX")
+}
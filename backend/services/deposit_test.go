@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/payment"
+)
+
+// 儲值回呼流程測試同樣需要 TEST_MYSQL_DSN（見 wallet_test.go）
+
+type depositTestEnv struct {
+	*walletTestEnv
+	deposits *DepositService
+	gateway  *payment.FakeGateway
+}
+
+func newDepositTestEnv(t *testing.T) *depositTestEnv {
+	t.Helper()
+	env := newWalletTestEnv(t)
+	gateway := payment.NewFakeGateway("test-secret", 5*time.Minute)
+	gateways := payment.NewRegistry()
+	gateways.Register(gateway)
+	return &depositTestEnv{
+		walletTestEnv: env,
+		gateway:       gateway,
+		deposits: &DepositService{
+			DB:          env.db,
+			Wallet:      env.wallet,
+			FX:          &FXService{DB: env.db, Wallet: env.wallet},
+			Gateways:    gateways,
+			Bonus:       &BonusService{DB: env.db, Wallet: env.wallet, Ledger: env.wallet.Ledger, ConsumePolicy: CashFirst},
+			ExpireAfter: 30 * time.Minute,
+		},
+	}
+}
+
+func (env *depositTestEnv) create(t *testing.T, playerID int64, amount money.Amount) *Deposit {
+	t.Helper()
+	d, err := env.deposits.Create(DepositRequest{
+		PlayerID:        playerID,
+		Amount:          amount,
+		Currency:        testCurrency,
+		PaymentMethod:   "credit_card",
+		PaymentProvider: payment.FakeProvider,
+	})
+	if err != nil {
+		t.Fatalf("建立儲值訂單失敗: %v", err)
+	}
+	return d
+}
+
+// depositCredits 訂單實際入帳的交易筆數
+func (env *depositTestEnv) depositCredits(t *testing.T, depositID string) int {
+	t.Helper()
+	var n int
+	if err := env.db.QueryRow(`
+		SELECT COUNT(*) FROM transactions WHERE transaction_type = 'deposit' AND reference_id = ?
+	`, depositID).Scan(&n); err != nil {
+		t.Fatalf("查詢入帳交易失敗: %v", err)
+	}
+	return n
+}
+
+func (env *depositTestEnv) status(t *testing.T, depositID string) string {
+	t.Helper()
+	d, err := env.deposits.Get(depositID)
+	if err != nil {
+		t.Fatalf("查詢儲值訂單失敗: %v", err)
+	}
+	return d.Status
+}
+
+// 付款成功的回呼只入帳一次；同一事件重送回傳 duplicate 且不重複入帳
+func TestDepositWebhookCreditsOnceAndDeduplicates(t *testing.T) {
+	env := newDepositTestEnv(t)
+	playerID := env.newPlayer(t)
+	d := env.create(t, playerID, money.FromCents(10000))
+
+	header, body, err := env.gateway.Complete(d.GatewayTransactionID, payment.StatusSucceeded)
+	if err != nil {
+		t.Fatalf("模擬付款失敗: %v", err)
+	}
+	result, err := env.deposits.HandleWebhook(payment.FakeProvider, header, body)
+	if err != nil || result != DepositResultCredited {
+		t.Fatalf("首次回呼 = %q, %v，預期 %q", result, err, DepositResultCredited)
+	}
+	result, err = env.deposits.HandleWebhook(payment.FakeProvider, header, body)
+	if err != nil || result != DepositResultDuplicate {
+		t.Fatalf("重送回呼 = %q, %v，預期 %q", result, err, DepositResultDuplicate)
+	}
+
+	if n := env.depositCredits(t, d.DepositID); n != 1 {
+		t.Errorf("入帳交易 %d 筆，預期 1 筆", n)
+	}
+	if s := env.status(t, d.DepositID); s != "completed" {
+		t.Errorf("訂單狀態 = %s，預期 completed", s)
+	}
+	if balance, _ := env.balances(t, playerID); balance < d.Amount {
+		t.Errorf("餘額 = %s，應至少為儲值金額 %s", balance, d.Amount)
+	}
+}
+
+// 閘道回報金額與訂單不符時不入帳，訂單標記為失敗待人工處理
+func TestDepositWebhookAmountMismatchFailsDeposit(t *testing.T) {
+	env := newDepositTestEnv(t)
+	playerID := env.newPlayer(t)
+	d := env.create(t, playerID, money.FromCents(10000))
+
+	now := time.Now()
+	body, err := json.Marshal(map[string]interface{}{
+		"event_id":               "EVT-MISMATCH-" + d.DepositID,
+		"gateway_transaction_id": d.GatewayTransactionID,
+		"reference":              d.DepositID,
+		"status":                 payment.StatusSucceeded,
+		"amount":                 money.FromCents(9000),
+		"currency":               d.Currency,
+		"occurred_at":            now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	header.Set(payment.FakeSignatureHeader, env.gateway.Sign(body, now))
+
+	result, err := env.deposits.HandleWebhook(payment.FakeProvider, header, body)
+	if err != nil || result != DepositResultAmountMismatch {
+		t.Fatalf("回呼 = %q, %v，預期 %q", result, err, DepositResultAmountMismatch)
+	}
+	if s := env.status(t, d.DepositID); s != "failed" {
+		t.Errorf("訂單狀態 = %s，預期 failed", s)
+	}
+	if n := env.depositCredits(t, d.DepositID); n != 0 {
+		t.Errorf("金額不符仍入帳 %d 筆", n)
+	}
+}
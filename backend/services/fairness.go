@@ -0,0 +1,168 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/rng"
+)
+
+// 可驗證公平相關錯誤
+var (
+	ErrSessionNotFound     = errors.New("遊戲場次不存在")
+	ErrFairnessNotFound    = errors.New("此場次沒有公平性承諾紀錄")
+	ErrFairnessCommitted   = errors.New("此場次已建立公平性承諾")
+	ErrFairnessNotRevealed = errors.New("伺服器種子尚未揭露")
+)
+
+// FairnessService 可驗證公平服務（承諾存於 game_sessions.game_data 的 fairness 欄位）
+type FairnessService struct {
+	DB *sql.DB
+}
+
+// NewFairnessService 建立新的可驗證公平服務
+func NewFairnessService() *FairnessService {
+	return &FairnessService{
+		DB: config.GetDB(),
+	}
+}
+
+// Commit 於回合開始前產生伺服器種子並公布雜湊（以場次 ID 作為 nonce）
+func (s *FairnessService) Commit(sessionID int64, spec rng.OutcomeSpec) (*rng.Commitment, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing, err := s.load(tx, "id = ?", sessionID, true)
+	if err != nil && !errors.Is(err, ErrFairnessNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrFairnessCommitted
+	}
+
+	commitment, err := rng.NewCommitment(sessionID, spec)
+	if err != nil {
+		return nil, fmt.Errorf("產生伺服器種子失敗: %v", err)
+	}
+	if err := s.save(tx, sessionID, commitment); err != nil {
+		return nil, err
+	}
+	return commitment, tx.Commit()
+}
+
+// AddClientSeed 加入玩家提供的客戶端種子（僅限揭露前）
+func (s *FairnessService) AddClientSeed(sessionID int64, seed string) (*rng.Commitment, error) {
+	return s.update(sessionID, func(c *rng.Commitment) error {
+		return c.AddClientSeed(seed)
+	})
+}
+
+// Reveal 回合結束後揭露伺服器種子
+func (s *FairnessService) Reveal(sessionID int64) (*rng.Commitment, error) {
+	return s.update(sessionID, func(c *rng.Commitment) error {
+		return c.Reveal()
+	})
+}
+
+// Get 依場次 ID 取得承諾（包含伺服器種子，僅供伺服器內部發牌使用）
+func (s *FairnessService) Get(sessionID int64) (*rng.Commitment, error) {
+	return s.load(s.DB, "id = ?", sessionID, false)
+}
+
+// GetByCode 依場次代碼取得承諾
+func (s *FairnessService) GetByCode(sessionCode string) (*rng.Commitment, error) {
+	return s.load(s.DB, "session_code = ?", sessionCode, false)
+}
+
+// VerifySession 以場次儲存的種子重算結果，並與揭露時記錄的結果比對
+func (s *FairnessService) VerifySession(sessionCode string) (*rng.VerifyResult, bool, error) {
+	c, err := s.GetByCode(sessionCode)
+	if err != nil {
+		return nil, false, err
+	}
+	if c.Status != rng.CommitmentRevealed {
+		return nil, false, ErrFairnessNotRevealed
+	}
+	result, err := rng.Verify(c.ServerSeed, c.ServerSeedHash, c.ClientSeeds, c.Nonce, c.Spec)
+	if err != nil {
+		return nil, false, err
+	}
+	matches := false
+	if c.Result != nil {
+		a, _ := json.Marshal(c.Result.Values)
+		b, _ := json.Marshal(result.Outcome.Values)
+		matches = string(a) == string(b)
+	}
+	return result, matches, nil
+}
+
+// update 在交易內鎖定場次並修改承諾
+func (s *FairnessService) update(sessionID int64, fn func(*rng.Commitment) error) (*rng.Commitment, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c, err := s.load(tx, "id = ?", sessionID, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(c); err != nil {
+		return nil, err
+	}
+	if err := s.save(tx, sessionID, c); err != nil {
+		return nil, err
+	}
+	return c, tx.Commit()
+}
+
+// queryer *sql.DB 與 *sql.Tx 共用的查詢介面
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// load 讀取場次的 fairness 欄位
+func (s *FairnessService) load(q queryer, where string, arg interface{}, forUpdate bool) (*rng.Commitment, error) {
+	query := "SELECT JSON_EXTRACT(game_data, '$.fairness') FROM game_sessions WHERE " + where
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	var raw sql.NullString
+	err := q.QueryRow(query, arg).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !raw.Valid || raw.String == "null" {
+		return nil, ErrFairnessNotFound
+	}
+
+	var c rng.Commitment
+	if err := json.Unmarshal([]byte(raw.String), &c); err != nil {
+		return nil, fmt.Errorf("公平性承諾資料格式錯誤: %v", err)
+	}
+	return &c, nil
+}
+
+// save 寫回 fairness 欄位（保留 game_data 其他內容）
+func (s *FairnessService) save(tx *sql.Tx, sessionID int64, c *rng.Commitment) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE game_sessions
+		SET game_data = JSON_SET(COALESCE(game_data, JSON_OBJECT()), '$.fairness', CAST(? AS JSON))
+		WHERE id = ?
+	`, string(data), sessionID)
+	return err
+}
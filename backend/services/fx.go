@@ -0,0 +1,369 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 匯率與幣別兌換相關錯誤
+var (
+	ErrRateNotFound        = errors.New("查無匯率")
+	ErrCurrencyUnsupported = errors.New("不支援的幣別")
+	ErrSameCurrency        = errors.New("轉出與轉入幣別相同")
+	ErrConversionTooSmall  = errors.New("兌換後金額不足 0.01")
+)
+
+// 匯率來源
+const (
+	RateSourceStatic   = "static"
+	RateSourceOverride = "override"
+)
+
+// FXRate 匯率（1 單位 Base 可兌換的 Quote 數量）
+type FXRate struct {
+	Base        string     `json:"base"`
+	Quote       string     `json:"quote"`
+	Rate        money.Rate `json:"rate"`
+	Source      string     `json:"source"` // static、override，或以 inverse:／cross: 標示推導方式
+	EffectiveAt time.Time  `json:"effective_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// RateSource 匯率來源；查無匯率時回傳 ErrRateNotFound
+type RateSource interface {
+	Rate(base, quote string, at time.Time) (*FXRate, error)
+}
+
+// TableRateSource 以 fx_rates 表中指定來源的匯率查詢（取 at 當下已生效且未失效的最新一筆，正反方向皆可）
+type TableRateSource struct {
+	DB     *sql.DB
+	Source string
+}
+
+// Rate 查詢 at 當下的匯率
+func (s *TableRateSource) Rate(base, quote string, at time.Time) (*FXRate, error) {
+	r := &FXRate{Base: base, Quote: quote, Source: s.Source}
+	var (
+		rowBase   string
+		expiresAt sql.NullTime
+	)
+	err := s.DB.QueryRow(`
+		SELECT base_currency, rate, effective_at, expires_at FROM fx_rates
+		WHERE ((base_currency = ? AND quote_currency = ?) OR (base_currency = ? AND quote_currency = ?))
+		AND source = ? AND effective_at <= ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY effective_at DESC, id DESC
+		LIMIT 1
+	`, base, quote, quote, base, s.Source, at, at).Scan(&rowBase, &r.Rate, &r.EffectiveAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrRateNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if rowBase != base {
+		r.Rate = r.Rate.Inverse().Round()
+		r.Source = "inverse:" + s.Source
+	}
+	if expiresAt.Valid {
+		r.ExpiresAt = &expiresAt.Time
+	}
+	return r, nil
+}
+
+// ChainRateSource 依序查詢多個來源，採用第一個有匯率的來源
+type ChainRateSource []RateSource
+
+// Rate 查詢 at 當下的匯率
+func (c ChainRateSource) Rate(base, quote string, at time.Time) (*FXRate, error) {
+	for _, src := range c {
+		r, err := src.Rate(base, quote, at)
+		if err == nil {
+			return r, nil
+		} else if !errors.Is(err, ErrRateNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrRateNotFound
+}
+
+// FXConversion 幣別兌換紀錄
+type FXConversion struct {
+	ConversionCode      string       `json:"conversion_code"`
+	PlayerID            int64        `json:"player_id"`
+	FromCurrency        string       `json:"from_currency"`
+	FromAmount          money.Amount `json:"from_amount"`
+	ToCurrency          string       `json:"to_currency"`
+	ToAmount            money.Amount `json:"to_amount"`
+	Rate                money.Rate   `json:"rate"`
+	RateSource          string       `json:"rate_source"`
+	DebitTransactionID  string       `json:"debit_transaction_id"`
+	CreditTransactionID string       `json:"credit_transaction_id"`
+	CreatedAt           time.Time    `json:"created_at"`
+}
+
+// FXConversionRequest 幣別兌換請求
+type FXConversionRequest struct {
+	PlayerID     int64
+	FromCurrency string
+	ToCurrency   string
+	Amount       money.Amount // 轉出金額
+	OperatorID   *int
+}
+
+// RateOverride 管理員覆寫匯率
+type RateOverride struct {
+	Base        string
+	Quote       string
+	Rate        money.Rate
+	EffectiveAt time.Time  // 零值表示立即生效
+	ExpiresAt   *time.Time // nil 表示直到下一筆覆寫
+	Note        string
+	OperatorID  *int
+}
+
+// Currency 幣別
+type Currency struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol,omitempty"`
+	IsActive bool   `json:"is_active"`
+}
+
+// FXService 匯率與幣別兌換服務
+//
+// 匯率依 Source 查詢（預設為管理員覆寫優先、其次靜態匯率表），查無兩幣別間的匯率時
+// 改用經由預設幣別的交叉匯率。兌換在同一交易內扣除轉出錢包、存入轉入錢包，
+// 兩筆交易各自以該幣別傳票對沖平台匯兌帳戶（house:fx:幣別），平台的外匯部位即為匯兌帳戶餘額。
+type FXService struct {
+	DB     *sql.DB
+	Wallet *WalletService
+	Source RateSource
+}
+
+// NewFXService 建立新的匯率服務
+func NewFXService() *FXService {
+	db := config.GetDB()
+	return &FXService{
+		DB:     db,
+		Wallet: NewWalletService(),
+		Source: ChainRateSource{
+			&TableRateSource{DB: db, Source: RateSourceOverride},
+			&TableRateSource{DB: db, Source: RateSourceStatic},
+		},
+	}
+}
+
+// Rate 取得 at 當下 base → quote 的匯率
+func (s *FXService) Rate(base, quote string, at time.Time) (*FXRate, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	if base == quote {
+		return &FXRate{Base: base, Quote: quote, Rate: money.One(), Source: "identity", EffectiveAt: at}, nil
+	}
+	r, err := s.Source.Rate(base, quote, at)
+	if !errors.Is(err, ErrRateNotFound) {
+		return r, err
+	}
+	pivot := s.Wallet.Currency
+	if base == pivot || quote == pivot {
+		return nil, err
+	}
+	leg1, err := s.Source.Rate(base, pivot, at)
+	if err != nil {
+		return nil, err
+	}
+	leg2, err := s.Source.Rate(pivot, quote, at)
+	if err != nil {
+		return nil, err
+	}
+	effective := leg1.EffectiveAt
+	if leg2.EffectiveAt.After(effective) {
+		effective = leg2.EffectiveAt
+	}
+	return &FXRate{
+		Base:        base,
+		Quote:       quote,
+		Rate:        leg1.Rate.Mul(leg2.Rate).Round(),
+		Source:      "cross:" + leg1.Source + "/" + leg2.Source,
+		EffectiveAt: effective,
+	}, nil
+}
+
+// ConvertAmount 以 at 當下的匯率換算金額（報表合併使用，不異動錢包）
+func (s *FXService) ConvertAmount(amount money.Amount, from, to string, at time.Time) (money.Amount, *FXRate, error) {
+	r, err := s.Rate(from, to, at)
+	if err != nil {
+		return 0, nil, err
+	}
+	return r.Rate.Convert(amount), r, nil
+}
+
+// Currencies 列出幣別
+func (s *FXService) Currencies() ([]Currency, error) {
+	rows, err := s.DB.Query("SELECT code, name, COALESCE(symbol, ''), is_active FROM currencies ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Currency{}
+	for rows.Next() {
+		var c Currency
+		if err := rows.Scan(&c.Code, &c.Name, &c.Symbol, &c.IsActive); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// CheckCurrency 確認幣別存在且開放使用
+func (s *FXService) CheckCurrency(code string) error {
+	var active bool
+	err := s.DB.QueryRow("SELECT is_active FROM currencies WHERE code = ?", code).Scan(&active)
+	if err == sql.ErrNoRows || err == nil && !active {
+		return fmt.Errorf("%w: %s", ErrCurrencyUnsupported, code)
+	}
+	return err
+}
+
+// SetOverride 新增管理員覆寫匯率（不修改既有匯率，歷史匯率保留供報表換算）
+func (s *FXService) SetOverride(o RateOverride) (*FXRate, error) {
+	o.Base, o.Quote = strings.ToUpper(o.Base), strings.ToUpper(o.Quote)
+	if o.Base == o.Quote {
+		return nil, ErrSameCurrency
+	}
+	for _, code := range []string{o.Base, o.Quote} {
+		if err := s.CheckCurrency(code); err != nil {
+			return nil, err
+		}
+	}
+	if o.Rate.IsZero() {
+		return nil, money.ErrInvalidRate
+	}
+	if o.EffectiveAt.IsZero() {
+		o.EffectiveAt = time.Now()
+	}
+	if _, err := s.DB.Exec(`
+		INSERT INTO fx_rates (base_currency, quote_currency, rate, source, effective_at, expires_at, note, created_by)
+		VALUES (?, ?, ?, 'override', ?, ?, ?, ?)
+	`, o.Base, o.Quote, o.Rate, o.EffectiveAt, o.ExpiresAt, nullString(o.Note), o.OperatorID); err != nil {
+		return nil, err
+	}
+	return &FXRate{
+		Base: o.Base, Quote: o.Quote, Rate: o.Rate.Round(), Source: RateSourceOverride,
+		EffectiveAt: o.EffectiveAt, ExpiresAt: o.ExpiresAt,
+	}, nil
+}
+
+// Rates 列出 at 當下所有幣別對預設幣別的匯率
+func (s *FXService) Rates(at time.Time) ([]FXRate, error) {
+	currencies, err := s.Currencies()
+	if err != nil {
+		return nil, err
+	}
+	rates := []FXRate{}
+	for _, c := range currencies {
+		if !c.IsActive || c.Code == s.Wallet.Currency {
+			continue
+		}
+		r, err := s.Rate(c.Code, s.Wallet.Currency, at)
+		if errors.Is(err, ErrRateNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		rates = append(rates, *r)
+	}
+	return rates, nil
+}
+
+// ConvertTx 在呼叫端交易內兌換玩家錢包幣別：扣除轉出錢包、以當下匯率存入轉入錢包並寫入兌換紀錄
+func (s *FXService) ConvertTx(tx *sql.Tx, req FXConversionRequest) (*FXConversion, error) {
+	from, to := strings.ToUpper(req.FromCurrency), strings.ToUpper(req.ToCurrency)
+	if from == to {
+		return nil, ErrSameCurrency
+	}
+	if !req.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	for _, code := range []string{from, to} {
+		if err := s.CheckCurrency(code); err != nil {
+			return nil, err
+		}
+	}
+	rate, err := s.Rate(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	c := &FXConversion{
+		ConversionCode: newConversionCode(),
+		PlayerID:       req.PlayerID,
+		FromCurrency:   from,
+		FromAmount:     req.Amount,
+		ToCurrency:     to,
+		ToAmount:       rate.Rate.Convert(req.Amount),
+		Rate:           rate.Rate,
+		RateSource:     rate.Source,
+		CreatedAt:      time.Now(),
+	}
+	if !c.ToAmount.IsPositive() {
+		return nil, ErrConversionTooSmall
+	}
+
+	// 兩個錢包依幣別順序一次鎖定，避免反向兌換互相等待
+	if _, err := tx.Exec(`
+		SELECT id FROM player_wallets WHERE player_id = ? AND currency IN (?, ?)
+		ORDER BY currency FOR UPDATE
+	`, req.PlayerID, from, to); err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("幣別兌換 %s %s → %s %s（匯率 %s）", c.FromAmount, from, c.ToAmount, to, c.Rate)
+	debit, err := s.Wallet.DebitTx(tx, WalletEntry{
+		PlayerID:      req.PlayerID,
+		Currency:      from,
+		Type:          "fx_conversion",
+		Amount:        c.FromAmount,
+		ReferenceID:   c.ConversionCode,
+		ReferenceType: "fx_conversion",
+		Description:   description,
+		OperatorID:    req.OperatorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	credit, err := s.Wallet.CreditTx(tx, WalletEntry{
+		PlayerID:      req.PlayerID,
+		Currency:      to,
+		Type:          "fx_conversion",
+		Amount:        c.ToAmount,
+		ReferenceID:   c.ConversionCode,
+		ReferenceType: "fx_conversion",
+		Description:   description,
+		OperatorID:    req.OperatorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.DebitTransactionID, c.CreditTransactionID = debit.TransactionID, credit.TransactionID
+
+	if _, err := tx.Exec(`
+		INSERT INTO fx_conversions
+			(conversion_code, player_id, from_currency, from_amount, to_currency, to_amount, rate, rate_source,
+			 debit_transaction_id, credit_transaction_id, operator_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.ConversionCode, c.PlayerID, from, c.FromAmount, to, c.ToAmount, c.Rate, c.RateSource,
+		c.DebitTransactionID, c.CreditTransactionID, req.OperatorID); err != nil {
+		return nil, fmt.Errorf("寫入兌換紀錄失敗: %v", err)
+	}
+	return c, nil
+}
+
+// newConversionCode 產生兌換編號
+func newConversionCode() string {
+	return "FX" + strings.TrimPrefix(NewTransactionID(), "TX")
+}
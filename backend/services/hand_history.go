@@ -0,0 +1,153 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/engine/cards"
+	"nexus-gaming-backend/engine/poker"
+	"nexus-gaming-backend/rng"
+)
+
+// 牌局歷史相關錯誤
+var (
+	ErrHandHistoryNotFound = errors.New("此場次沒有牌局歷史")
+	ErrHandHistoryExists   = errors.New("此場次已寫入牌局歷史")
+	ErrNotHandParticipant  = errors.New("玩家未參與此牌局")
+)
+
+// StoredHandHistory 已寫入的牌局歷史
+type StoredHandHistory struct {
+	History     *poker.History `json:"history"`
+	ContentHash string         `json:"content_hash"`
+	HashMatches bool           `json:"hash_matches"` // 讀出內容的雜湊是否與寫入時相符
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// HandReplay 重播結果
+type HandReplay struct {
+	SessionCode  string              `json:"session_code"`
+	HashMatches  bool                `json:"hash_matches"`  // 歷史內容未被竄改
+	SeedVerified bool                `json:"seed_verified"` // 揭露的伺服器種子與事前公布的雜湊相符
+	Report       *poker.ReplayReport `json:"report"`
+	Verified     bool                `json:"verified"`
+}
+
+// HandHistoryService 牌局歷史服務（寫入一次，之後只讀）
+type HandHistoryService struct {
+	DB *sql.DB
+}
+
+// NewHandHistoryService 建立新的牌局歷史服務
+func NewHandHistoryService() *HandHistoryService {
+	return &HandHistoryService{
+		DB: config.GetDB(),
+	}
+}
+
+// Record 於交易內寫入牌局歷史，並在 game_data 記錄歷史的格式與雜湊
+func (s *HandHistoryService) Record(tx *sql.Tx, hist *poker.History) (string, error) {
+	data, err := json.Marshal(hist)
+	if err != nil {
+		return "", err
+	}
+	hash := contentHash(data)
+
+	_, err = tx.Exec(`
+		INSERT INTO hand_histories
+			(session_id, session_code, room_id, game_type, format, history, content_hash, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, hist.SessionID, hist.SessionCode, hist.Table.RoomID, string(hist.Rules.Variant), hist.Format,
+		string(data), hash, hist.StartedAt, hist.FinishedAt)
+	if isDuplicateKey(err) {
+		return "", ErrHandHistoryExists
+	} else if err != nil {
+		return "", fmt.Errorf("寫入牌局歷史失敗: %v", err)
+	}
+
+	ref, _ := json.Marshal(map[string]string{"format": hist.Format, "content_hash": hash})
+	if _, err := tx.Exec(`
+		UPDATE game_sessions
+		SET game_data = JSON_SET(COALESCE(game_data, JSON_OBJECT()), '$.hand_history', CAST(? AS JSON))
+		WHERE id = ?
+	`, string(ref), hist.SessionID); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Get 依場次代碼讀取牌局歷史並驗證內容雜湊
+func (s *HandHistoryService) Get(sessionCode string) (*StoredHandHistory, error) {
+	var (
+		data   string
+		stored StoredHandHistory
+	)
+	err := s.DB.QueryRow(`
+		SELECT history, content_hash, created_at FROM hand_histories WHERE session_code = ?
+	`, sessionCode).Scan(&data, &stored.ContentHash, &stored.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrHandHistoryNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var hist poker.History
+	if err := json.Unmarshal([]byte(data), &hist); err != nil {
+		return nil, fmt.Errorf("牌局歷史格式錯誤: %v", err)
+	}
+	stored.History = &hist
+	stored.HashMatches = contentHash([]byte(data)) == stored.ContentHash
+	return &stored, nil
+}
+
+// GetForPlayer 玩家視角的牌局歷史（只含自己與攤牌座位的暗牌）
+func (s *HandHistoryService) GetForPlayer(sessionCode string, playerID int64) (*StoredHandHistory, int, error) {
+	stored, err := s.Get(sessionCode)
+	if err != nil {
+		return nil, 0, err
+	}
+	seat := stored.History.SeatOfPlayer(playerID)
+	if seat == 0 {
+		return nil, 0, ErrNotHandParticipant
+	}
+	stored.History = stored.History.ForViewer(seat)
+	return stored, seat, nil
+}
+
+// Replay 以歷史中的種子重建牌序，重新執行引擎並比對紀錄
+func (s *HandHistoryService) Replay(sessionCode string) (*HandReplay, error) {
+	stored, err := s.Get(sessionCode)
+	if err != nil {
+		return nil, err
+	}
+	hist := stored.History
+	f := hist.Fairness
+	verify, err := rng.Verify(f.ServerSeed, f.ServerSeedHash, f.ClientSeeds, f.Nonce, rng.OutcomeSpec{Type: rng.OutcomeDeck})
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := poker.Replay(hist, cards.FromIndexes(verify.Outcome.Values))
+	if err != nil {
+		return nil, err
+	}
+	replay := &HandReplay{
+		SessionCode:  sessionCode,
+		HashMatches:  stored.HashMatches,
+		SeedVerified: verify.HashMatches,
+		Report:       report,
+	}
+	replay.Verified = replay.HashMatches && replay.SeedVerified && report.Matches
+	return replay, nil
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
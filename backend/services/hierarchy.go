@@ -0,0 +1,485 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 層級結構相關錯誤
+var (
+	ErrHierarchyCycle    = errors.New("不可將代理商移至自己或其下級代理商之下")
+	ErrHierarchyTooDeep  = errors.New("超過代理商層級上限")
+	ErrHierarchyRootMove = errors.New("總公司不可移動")
+)
+
+// 層級節點類型
+const (
+	NodeAgent  = "agent"
+	NodeDealer = "dealer"
+)
+
+// defaultMaxAgentDepth 未設定時的代理商層級上限（總公司為第 0 層）
+const defaultMaxAgentDepth = 5
+
+// HierarchyNode 層級中的代理商或經銷商
+type HierarchyNode struct {
+	ID              int              `json:"id"`
+	Type            string           `json:"type"`
+	Code            string           `json:"code"`
+	Name            string           `json:"name"`
+	Status          string           `json:"status"`
+	Level           int              `json:"level"`
+	ParentID        *int             `json:"parent_id,omitempty"` // 上級代理商
+	LevelDifference int              `json:"level_difference"`    // 與查詢節點的層級差距
+	PlayerCount     int64            `json:"player_count"`        // 直屬玩家數（代理商不含其經銷商的玩家）
+	Children        []*HierarchyNode `json:"children,omitempty"`
+}
+
+// SubtreeStats 代理商及其所有下級的彙總
+type SubtreeStats struct {
+	AgentID       int          `json:"agent_id"`
+	Agents        int64        `json:"agents"` // 下級代理商數（不含自己）
+	Dealers       int64        `json:"dealers"`
+	MaxDepth      int          `json:"max_depth"` // 最深的下級代理商與自己的層級差距
+	Players       int64        `json:"players"`
+	ActivePlayers int64        `json:"active_players"`
+	TotalDeposit  money.Amount `json:"total_deposit"`
+	TotalWithdraw money.Amount `json:"total_withdraw"`
+	TotalBet      money.Amount `json:"total_bet"`
+	TotalWin      money.Amount `json:"total_win"`
+}
+
+// HierarchyService 代理商層級結構（agent_hierarchy 閉包表）
+//
+// 閉包表保存每個代理商到自己（差距 0）與所有上級代理商的路徑，經銷商則保存到所屬代理商及其所有上級的路徑。
+// 建立、移動與刪除代理商或經銷商時在呼叫端的交易內同步維護，移動時拒絕形成循環或超過層級上限。
+type HierarchyService struct {
+	DB       *sql.DB
+	MaxDepth int
+}
+
+// NewHierarchyService 建立新的層級結構服務
+func NewHierarchyService() *HierarchyService {
+	depth := defaultMaxAgentDepth
+	if config.AppConfig != nil && config.AppConfig.Agent.MaxDepth > 0 {
+		depth = config.AppConfig.Agent.MaxDepth
+	}
+	return &HierarchyService{DB: config.GetDB(), MaxDepth: depth}
+}
+
+// CheckDepth 檢查在指定層級的上級代理商下新增代理商是否超過層級上限
+func (s *HierarchyService) CheckDepth(parentLevel int) error {
+	if parentLevel+1 > s.MaxDepth {
+		return ErrHierarchyTooDeep
+	}
+	return nil
+}
+
+// InsertAgentTx 新增代理商的路徑（自己與上級代理商的所有祖先）
+func (s *HierarchyService) InsertAgentTx(tx *sql.Tx, agentID int, parentID *int) error {
+	if _, err := tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, descendant_type, level_difference, path_length)
+		VALUES (?, ?, 'agent', 0, 0)
+	`, agentID, agentID); err != nil {
+		return err
+	}
+	if parentID == nil {
+		return nil
+	}
+	_, err := tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, descendant_type, level_difference, path_length)
+		SELECT ancestor_id, ?, 'agent', level_difference + 1, path_length + 1
+		FROM agent_hierarchy WHERE descendant_id = ? AND descendant_type = 'agent'
+	`, agentID, *parentID)
+	return err
+}
+
+// InsertDealerTx 新增經銷商的路徑（所屬代理商與其所有祖先）
+func (s *HierarchyService) InsertDealerTx(tx *sql.Tx, dealerID, agentID int) error {
+	_, err := tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, descendant_type, level_difference, path_length)
+		SELECT ancestor_id, ?, 'dealer', level_difference + 1, path_length + 1
+		FROM agent_hierarchy WHERE descendant_id = ? AND descendant_type = 'agent'
+	`, dealerID, agentID)
+	return err
+}
+
+// RelinkDealersTx 依經銷商目前的所屬代理商重建其路徑（經銷商改隸其他代理商後呼叫）
+func (s *HierarchyService) RelinkDealersTx(tx *sql.Tx, agentID int) error {
+	if _, err := tx.Exec(`
+		DELETE h FROM agent_hierarchy h
+		JOIN dealers d ON d.id = h.descendant_id AND h.descendant_type = 'dealer'
+		WHERE d.agent_id = ?
+	`, agentID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, descendant_type, level_difference, path_length)
+		SELECT h.ancestor_id, d.id, 'dealer', h.level_difference + 1, h.path_length + 1
+		FROM dealers d
+		JOIN agent_hierarchy h ON h.descendant_id = d.agent_id AND h.descendant_type = 'agent'
+		WHERE d.agent_id = ?
+	`, agentID)
+	return err
+}
+
+// MoveAgentTx 將代理商（含整個下級子樹）移至新的上級代理商，並更新子樹的層級
+func (s *HierarchyService) MoveAgentTx(tx *sql.Tx, agentID, parentID int) error {
+	if agentID == parentID {
+		return ErrHierarchyCycle
+	}
+	var inSubtree int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM agent_hierarchy
+		WHERE ancestor_id = ? AND descendant_id = ? AND descendant_type = 'agent'
+	`, agentID, parentID).Scan(&inSubtree); err != nil {
+		return err
+	}
+	if inSubtree > 0 {
+		return ErrHierarchyCycle
+	}
+	var parentLevel, subtreeDepth int
+	if err := tx.QueryRow("SELECT level FROM agents WHERE id = ?", parentID).Scan(&parentLevel); err == sql.ErrNoRows {
+		return ErrAgentNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := tx.QueryRow(`
+		SELECT COALESCE(MAX(level_difference), 0) FROM agent_hierarchy
+		WHERE ancestor_id = ? AND descendant_type = 'agent'
+	`, agentID).Scan(&subtreeDepth); err != nil {
+		return err
+	}
+	if err := s.CheckDepth(parentLevel + subtreeDepth); err != nil {
+		return err
+	}
+
+	// 切斷子樹與原祖先的路徑，再接到新上級的所有祖先
+	if _, err := tx.Exec(`
+		DELETE h FROM agent_hierarchy h
+		JOIN agent_hierarchy sub ON sub.descendant_id = h.descendant_id AND sub.descendant_type = h.descendant_type
+		     AND sub.ancestor_id = ?
+		JOIN agent_hierarchy anc ON anc.ancestor_id = h.ancestor_id AND anc.descendant_id = ?
+		     AND anc.descendant_type = 'agent' AND anc.ancestor_id <> ?
+	`, agentID, agentID, agentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, descendant_type, level_difference, path_length)
+		SELECT p.ancestor_id, sub.descendant_id, sub.descendant_type,
+		       p.level_difference + sub.level_difference + 1, p.path_length + sub.path_length + 1
+		FROM agent_hierarchy p
+		JOIN agent_hierarchy sub ON sub.ancestor_id = ?
+		WHERE p.descendant_id = ? AND p.descendant_type = 'agent'
+	`, agentID, parentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE agents SET parent_agent_id = ? WHERE id = ?", parentID, agentID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		UPDATE agents a
+		JOIN agent_hierarchy h ON h.descendant_id = a.id AND h.descendant_type = 'agent' AND h.ancestor_id = ?
+		SET a.level = ? + h.level_difference
+	`, agentID, parentLevel+1)
+	return err
+}
+
+// DeleteNodeTx 刪除代理商或經銷商的路徑（代理商需已無下級）
+func (s *HierarchyService) DeleteNodeTx(tx *sql.Tx, nodeType string, id int) error {
+	_, err := tx.Exec("DELETE FROM agent_hierarchy WHERE descendant_id = ? AND descendant_type = ?", id, nodeType)
+	return err
+}
+
+// Rebuild 依 agents.parent_agent_id 與 dealers.agent_id 重建整個閉包表，回傳代理商數
+func (s *HierarchyService) Rebuild() (int, error) {
+	n := 0
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id, parent_agent_id FROM agents ORDER BY id FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		children := map[int][]int{}
+		var roots []int
+		for rows.Next() {
+			var (
+				id     int
+				parent sql.NullInt64
+			)
+			if err := rows.Scan(&id, &parent); err != nil {
+				rows.Close()
+				return err
+			}
+			if parent.Valid {
+				children[int(parent.Int64)] = append(children[int(parent.Int64)], id)
+			} else {
+				roots = append(roots, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM agent_hierarchy"); err != nil {
+			return err
+		}
+
+		// 由根節點逐層建立，確保上級的路徑先存在；無法由根節點到達的代理商（循環資料）不會建立路徑
+		queue := append([]int(nil), roots...)
+		levels := map[int]int{}
+		for _, id := range roots {
+			if err := s.InsertAgentTx(tx, id, nil); err != nil {
+				return err
+			}
+			n++
+		}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, child := range children[id] {
+				parent := id
+				if err := s.InsertAgentTx(tx, child, &parent); err != nil {
+					return err
+				}
+				levels[child] = levels[id] + 1
+				if _, err := tx.Exec("UPDATE agents SET level = ? WHERE id = ?", levels[child], child); err != nil {
+					return err
+				}
+				n++
+				queue = append(queue, child)
+			}
+		}
+		_, err = tx.Exec(`
+			INSERT INTO agent_hierarchy (ancestor_id, descendant_id, descendant_type, level_difference, path_length)
+			SELECT h.ancestor_id, d.id, 'dealer', h.level_difference + 1, h.path_length + 1
+			FROM dealers d
+			JOIN agent_hierarchy h ON h.descendant_id = d.agent_id AND h.descendant_type = 'agent'
+		`)
+		return err
+	})
+	return n, err
+}
+
+// EnsureBuilt 閉包表缺少代理商自身路徑時重建（首次啟用或由舊資料升級）
+func (s *HierarchyService) EnsureBuilt() {
+	var missing int
+	if err := s.DB.QueryRow(`
+		SELECT COUNT(*) FROM agents a
+		WHERE NOT EXISTS (
+			SELECT 1 FROM agent_hierarchy h
+			WHERE h.ancestor_id = a.id AND h.descendant_id = a.id AND h.descendant_type = 'agent'
+		)
+	`).Scan(&missing); err != nil {
+		log.Printf("檢查代理商層級結構失敗: %v", err)
+		return
+	}
+	if missing == 0 {
+		return
+	}
+	if n, err := s.Rebuild(); err != nil {
+		log.Printf("重建代理商層級結構失敗: %v", err)
+	} else {
+		log.Printf("已重建代理商層級結構（%d 個代理商）", n)
+	}
+}
+
+// Ancestors 代理商的所有上級代理商（由總公司往下排序）
+func (s *HierarchyService) Ancestors(agentID int) ([]HierarchyNode, error) {
+	if _, err := loadAgent(s.DB, "a.id = ?", agentID); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query(`
+		SELECT a.id, a.agent_code, a.agent_name, a.status, a.level, a.parent_agent_id, h.level_difference
+		FROM agent_hierarchy h
+		JOIN agents a ON a.id = h.ancestor_id
+		WHERE h.descendant_id = ? AND h.descendant_type = 'agent' AND h.level_difference > 0
+		ORDER BY h.level_difference DESC
+	`, agentID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAgentNodes(rows)
+}
+
+// Descendants 代理商的下級代理商與經銷商（nodeType 為空表示兩者；depth 為 0 表示不限層級）
+func (s *HierarchyService) Descendants(agentID int, nodeType string, depth int) ([]HierarchyNode, error) {
+	if _, err := loadAgent(s.DB, "a.id = ?", agentID); err != nil {
+		return nil, err
+	}
+	list := []HierarchyNode{}
+	if nodeType == "" || nodeType == NodeAgent {
+		rows, err := s.DB.Query(`
+			SELECT a.id, a.agent_code, a.agent_name, a.status, a.level, a.parent_agent_id, h.level_difference
+			FROM agent_hierarchy h
+			JOIN agents a ON a.id = h.descendant_id
+			WHERE h.ancestor_id = ? AND h.descendant_type = 'agent' AND h.level_difference > 0
+			  AND (? = 0 OR h.level_difference <= ?)
+			ORDER BY h.level_difference, a.id
+		`, agentID, depth, depth)
+		if err != nil {
+			return nil, err
+		}
+		agents, err := scanAgentNodes(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, agents...)
+	}
+	if nodeType == "" || nodeType == NodeDealer {
+		rows, err := s.DB.Query(`
+			SELECT d.id, d.dealer_code, d.dealer_name, d.status, a.level + 1, d.agent_id, h.level_difference
+			FROM agent_hierarchy h
+			JOIN dealers d ON d.id = h.descendant_id
+			JOIN agents a ON a.id = d.agent_id
+			WHERE h.ancestor_id = ? AND h.descendant_type = 'dealer'
+			  AND (? = 0 OR h.level_difference <= ?)
+			ORDER BY h.level_difference, d.id
+		`, agentID, depth, depth)
+		if err != nil {
+			return nil, err
+		}
+		dealers, err := scanNodes(rows, NodeDealer)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, dealers...)
+	}
+	return list, nil
+}
+
+// Stats 代理商及其所有下級代理商與經銷商的彙總
+func (s *HierarchyService) Stats(agentID int) (*SubtreeStats, error) {
+	if _, err := loadAgent(s.DB, "a.id = ?", agentID); err != nil {
+		return nil, err
+	}
+	st := &SubtreeStats{AgentID: agentID}
+	if err := s.DB.QueryRow(`
+		SELECT
+			COALESCE(SUM(descendant_type = 'agent' AND level_difference > 0), 0),
+			COALESCE(SUM(descendant_type = 'dealer'), 0),
+			COALESCE(MAX(CASE WHEN descendant_type = 'agent' THEN level_difference END), 0)
+		FROM agent_hierarchy WHERE ancestor_id = ?
+	`, agentID).Scan(&st.Agents, &st.Dealers, &st.MaxDepth); err != nil {
+		return nil, err
+	}
+	if err := s.DB.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(p.status = 'active'), 0),
+		       COALESCE(SUM(p.total_deposit), 0), COALESCE(SUM(p.total_withdraw), 0),
+		       COALESCE(SUM(p.total_bet), 0), COALESCE(SUM(p.total_win), 0)
+		FROM players p
+		JOIN agent_hierarchy h ON h.descendant_id = p.agent_id AND h.descendant_type = 'agent'
+		WHERE h.ancestor_id = ? AND p.status <> 'deleted'
+	`, agentID).Scan(&st.Players, &st.ActivePlayers, &st.TotalDeposit, &st.TotalWithdraw,
+		&st.TotalBet, &st.TotalWin); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Tree 以代理商為根的樹狀結構（rootID 為 0 時以總公司為根），供管理介面呈現
+func (s *HierarchyService) Tree(rootID int, includeDealers bool) (*HierarchyNode, error) {
+	if rootID == 0 {
+		root, err := loadAgent(s.DB, "a.parent_agent_id IS NULL AND a.level = 0 ORDER BY a.id LIMIT 1")
+		if err == ErrAgentNotFound {
+			return nil, ErrCompanyAgentUnavailable
+		} else if err != nil {
+			return nil, err
+		}
+		rootID = root.ID
+	}
+	root, err := loadAgent(s.DB, "a.id = ?", rootID)
+	if err != nil {
+		return nil, err
+	}
+	nodeType := NodeAgent
+	if includeDealers {
+		nodeType = ""
+	}
+	nodes, err := s.Descendants(rootID, nodeType, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := s.directPlayerCounts(rootID)
+	if err != nil {
+		return nil, err
+	}
+	top := &HierarchyNode{
+		ID: root.ID, Type: NodeAgent, Code: root.AgentCode, Name: root.AgentName, Status: root.Status,
+		Level: root.Level, ParentID: root.ParentAgentID, PlayerCount: players[nodeKey{NodeAgent, root.ID}],
+	}
+	agents := map[int]*HierarchyNode{root.ID: top}
+	for i := range nodes {
+		n := &nodes[i]
+		n.PlayerCount = players[nodeKey{n.Type, n.ID}]
+		if n.Type == NodeAgent {
+			agents[n.ID] = n
+		}
+	}
+	// 節點依層級差距排序，上級一定先出現
+	for i := range nodes {
+		n := &nodes[i]
+		if n.ParentID == nil {
+			continue
+		}
+		if parent, ok := agents[*n.ParentID]; ok {
+			parent.Children = append(parent.Children, n)
+		}
+	}
+	return top, nil
+}
+
+type nodeKey struct {
+	Type string
+	ID   int
+}
+
+// directPlayerCounts 子樹內各代理商（不含其經銷商）與各經銷商的直屬玩家數
+func (s *HierarchyService) directPlayerCounts(rootID int) (map[nodeKey]int64, error) {
+	counts := map[nodeKey]int64{}
+	rows, err := s.DB.Query(`
+		SELECT IF(p.dealer_id IS NULL, 'agent', 'dealer'), COALESCE(p.dealer_id, p.agent_id), COUNT(*)
+		FROM players p
+		JOIN agent_hierarchy h ON h.descendant_id = p.agent_id AND h.descendant_type = 'agent'
+		WHERE h.ancestor_id = ? AND p.status <> 'deleted'
+		GROUP BY 1, 2
+	`, rootID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			k nodeKey
+			n int64
+		)
+		if err := rows.Scan(&k.Type, &k.ID, &n); err != nil {
+			return nil, err
+		}
+		counts[k] = n
+	}
+	return counts, rows.Err()
+}
+
+func scanAgentNodes(rows *sql.Rows) ([]HierarchyNode, error) {
+	return scanNodes(rows, NodeAgent)
+}
+
+func scanNodes(rows *sql.Rows, nodeType string) ([]HierarchyNode, error) {
+	defer rows.Close()
+	list := []HierarchyNode{}
+	for rows.Next() {
+		n := HierarchyNode{Type: nodeType}
+		var parentID sql.NullInt64
+		if err := rows.Scan(&n.ID, &n.Code, &n.Name, &n.Status, &n.Level, &parentID, &n.LevelDifference); err != nil {
+			return nil, err
+		}
+		n.ParentID = nullInt(parentID)
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 錢包凍結相關錯誤
+var (
+	ErrHoldNotFound  = errors.New("凍結紀錄不存在")
+	ErrHoldNotActive = errors.New("凍結已結清或已逾期")
+	ErrHoldExceeded  = errors.New("扣款金額超過凍結金額")
+)
+
+// 凍結狀態
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// 凍結原因與參考類型
+const (
+	HoldReasonWithdrawal = "withdrawal"
+	HoldReasonTableBuyIn = "table_buy_in"
+	HoldRefWithdrawal    = "withdrawal"
+	HoldRefTable         = "table"
+)
+
+// holdExpiryBatch 每次逾期檢查最多釋放的凍結筆數
+const holdExpiryBatch = 200
+
+// Hold 錢包凍結
+type Hold struct {
+	HoldCode       string       `json:"hold_code"`
+	PlayerID       int64        `json:"player_id"`
+	Currency       string       `json:"currency"`
+	Amount         money.Amount `json:"amount"`
+	CapturedAmount money.Amount `json:"captured_amount"`
+	Reason         string       `json:"reason"`
+	ReferenceType  string       `json:"reference_type"`
+	ReferenceID    string       `json:"reference_id"`
+	Status         string       `json:"status"`
+	ExpiresAt      *time.Time   `json:"expires_at,omitempty"`
+	TransactionID  string       `json:"transaction_id,omitempty"`
+	SettledAt      *time.Time   `json:"settled_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// HoldRequest 建立凍結請求
+type HoldRequest struct {
+	PlayerID      int64
+	Currency      string // 空字串表示預設幣別
+	Amount        money.Amount
+	Reason        string
+	ReferenceType string
+	ReferenceID   string
+	TTL           time.Duration // 0 表示不逾期
+	OperatorID    *int
+}
+
+// HoldService 錢包凍結服務
+//
+// 凍結只在 player_wallets 的可用與凍結餘額間移動，總餘額不變，因此不寫入交易或總帳；
+// 扣款時先解除凍結再以一般扣款寫入交易與傳票。鎖定順序一律為凍結列、錢包列。
+type HoldService struct {
+	DB     *sql.DB
+	Wallet *WalletService
+}
+
+// NewHoldService 建立新的錢包凍結服務
+func NewHoldService() *HoldService {
+	return &HoldService{
+		DB:     config.GetDB(),
+		Wallet: NewWalletService(),
+	}
+}
+
+// Place 凍結可用餘額
+func (s *HoldService) Place(req HoldRequest) (*Hold, error) {
+	var hold *Hold
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		hold, err = s.PlaceTx(tx, req)
+		return err
+	})
+	return hold, err
+}
+
+// PlaceTx 在呼叫端交易內凍結可用餘額
+func (s *HoldService) PlaceTx(tx *sql.Tx, req HoldRequest) (*Hold, error) {
+	if !req.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	currency := s.Wallet.CurrencyOf(req.Currency)
+	var balance money.Amount
+	err := tx.QueryRow(`
+		SELECT balance FROM player_wallets
+		WHERE player_id = ? AND currency = ?
+		FOR UPDATE
+	`, req.PlayerID, currency).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return nil, ErrWalletNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if balance < req.Amount {
+		return nil, ErrInsufficientBalance
+	}
+	if _, err := tx.Exec(`
+		UPDATE player_wallets SET balance = balance - ?, frozen_balance = frozen_balance + ?
+		WHERE player_id = ? AND currency = ?
+	`, req.Amount, req.Amount, req.PlayerID, currency); err != nil {
+		return nil, err
+	}
+
+	h := &Hold{
+		HoldCode:      newHoldCode(),
+		PlayerID:      req.PlayerID,
+		Currency:      currency,
+		Amount:        req.Amount,
+		Reason:        req.Reason,
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   req.ReferenceID,
+		Status:        HoldActive,
+		CreatedAt:     time.Now(),
+	}
+	if req.TTL > 0 {
+		expires := h.CreatedAt.Add(req.TTL)
+		h.ExpiresAt = &expires
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO wallet_holds
+			(hold_code, player_id, currency, amount, reason, reference_type, reference_id, expires_at, operator_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, h.HoldCode, h.PlayerID, h.Currency, h.Amount, h.Reason, h.ReferenceType, h.ReferenceID,
+		h.ExpiresAt, req.OperatorID); err != nil {
+		return nil, fmt.Errorf("寫入凍結紀錄失敗: %v", err)
+	}
+	return h, nil
+}
+
+// Capture 自凍結扣款（amount 為 0 時扣除全部凍結金額，其餘釋放）
+func (s *HoldService) Capture(code string, amount money.Amount, entry WalletEntry) (*WalletTransaction, error) {
+	var t *WalletTransaction
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		t, err = s.CaptureTx(tx, code, amount, entry)
+		return err
+	})
+	return t, err
+}
+
+// CaptureTx 在呼叫端交易內自凍結扣款；entry 的玩家與金額由凍結決定
+func (s *HoldService) CaptureTx(tx *sql.Tx, code string, amount money.Amount, entry WalletEntry) (*WalletTransaction, error) {
+	h, err := s.lock(tx, code)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		amount = h.Amount
+	}
+	if amount.IsNegative() {
+		return nil, ErrInvalidAmount
+	}
+	if amount > h.Amount {
+		return nil, ErrHoldExceeded
+	}
+	return s.settle(tx, h, -amount, entry)
+}
+
+// Release 釋放凍結回可用餘額
+func (s *HoldService) Release(code string) (*Hold, error) {
+	var hold *Hold
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		hold, err = s.ReleaseTx(tx, code)
+		return err
+	})
+	return hold, err
+}
+
+// ReleaseTx 在呼叫端交易內釋放凍結
+func (s *HoldService) ReleaseTx(tx *sql.Tx, code string) (*Hold, error) {
+	h, err := s.lock(tx, code)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.settle(tx, h, 0, WalletEntry{}); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SettleTx 以最終金額結清凍結：少於凍結金額時扣除差額（entry 記為扣款），多於時釋放全部並入帳差額（entry 記為入帳）
+//
+// entries 依序為扣款與入帳使用的交易內容（類型、參考與說明）。
+func (s *HoldService) SettleTx(tx *sql.Tx, code string, final money.Amount, debit, credit WalletEntry) (*WalletTransaction, error) {
+	h, err := s.lock(tx, code)
+	if err != nil {
+		return nil, err
+	}
+	if final.IsNegative() {
+		return nil, ErrInvalidAmount
+	}
+	delta := final - h.Amount
+	entry := debit
+	if delta > 0 {
+		entry = credit
+	}
+	return s.settle(tx, h, delta, entry)
+}
+
+// KeepTx 在呼叫端交易內取消凍結的逾期時間（已核准待出款的提領不可因逾期釋放）
+func (s *HoldService) KeepTx(tx *sql.Tx, code string) (*Hold, error) {
+	h, err := s.lock(tx, code)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE wallet_holds SET expires_at = NULL WHERE hold_code = ?", code); err != nil {
+		return nil, err
+	}
+	h.ExpiresAt = nil
+	return h, nil
+}
+
+// ActiveByReference 取得參考對應的有效凍結（同一參考有多筆時取最新一筆）
+func (s *HoldService) ActiveByReference(playerID int64, referenceType, referenceID string) (*Hold, error) {
+	return s.load(s.DB, `
+		player_id = ? AND reference_type = ? AND reference_id = ? AND status = 'active'
+		ORDER BY id DESC LIMIT 1`, playerID, referenceType, referenceID)
+}
+
+// Get 取得凍結
+func (s *HoldService) Get(code string) (*Hold, error) {
+	return s.load(s.DB, "hold_code = ?", code)
+}
+
+// List 列出玩家的凍結（status 為空時列出全部）
+func (s *HoldService) List(playerID int64, status string) ([]Hold, error) {
+	query := "SELECT " + holdColumns + " FROM wallet_holds WHERE player_id = ?"
+	args := []interface{}{playerID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	rows, err := s.DB.Query(query+" ORDER BY id DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	holds := []Hold{}
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *h)
+	}
+	return holds, rows.Err()
+}
+
+// ReleaseByReferenceType 全額釋放某參考類型的全部有效凍結，並逐筆記錄供人工覆核
+//
+// 牌桌重新啟動時先依籌碼快照結清（TableStore.RecoverStacks），此處只處理沒有快照的遺留凍結。
+func (s *HoldService) ReleaseByReferenceType(referenceType string) (int, error) {
+	codes, err := s.codes("reference_type = ? AND status = 'active'", referenceType)
+	if err != nil {
+		return 0, err
+	}
+	released := 0
+	for _, code := range codes {
+		h, err := s.Release(code)
+		if errors.Is(err, ErrHoldNotActive) {
+			continue
+		} else if err != nil {
+			return released, err
+		}
+		log.Printf("全額釋放遺留凍結 %s：玩家 %d 參考 %s/%s 金額 %s %s（無結清依據，請人工覆核）",
+			h.HoldCode, h.PlayerID, h.ReferenceType, h.ReferenceID, h.Amount, h.Currency)
+		released++
+	}
+	return released, nil
+}
+
+// ExpireDue 釋放已逾期的凍結；提領的凍結逾期時一併取消尚未審核的提領申請
+func (s *HoldService) ExpireDue() (int, error) {
+	codes, err := s.codes("status = 'active' AND expires_at IS NOT NULL AND expires_at <= NOW() LIMIT ?", holdExpiryBatch)
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, code := range codes {
+		err := s.withTx(func(tx *sql.Tx) error {
+			h, err := s.lock(tx, code)
+			if err != nil {
+				return err
+			}
+			if h.ExpiresAt == nil || h.ExpiresAt.After(time.Now()) {
+				return nil
+			}
+			h.Status = HoldExpired
+			if _, err := s.settle(tx, h, 0, WalletEntry{}); err != nil {
+				return err
+			}
+			if h.ReferenceType == HoldRefWithdrawal {
+				_, err = tx.Exec(`
+					UPDATE withdrawals SET status = 'cancelled', review_notes = '凍結逾期，提領申請自動取消'
+					WHERE withdrawal_id = ? AND status IN ('pending', 'reviewing')
+				`, h.ReferenceID)
+			}
+			return err
+		})
+		if errors.Is(err, ErrHoldNotActive) {
+			continue
+		} else if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// Run 定期釋放逾期的凍結，直到 ctx 結束（阻塞執行，應以 goroutine 啟動）
+func (s *HoldService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.ExpireDue(); err != nil {
+			log.Printf("釋放逾期凍結失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已釋放 %d 筆逾期凍結", n)
+		}
+	}
+}
+
+// settle 解除凍結並依 delta 異動可用餘額：負數扣款、正數入帳、0 只釋放
+//
+// 狀態為 expired 時保留，其餘依是否扣款標記為 captured 或 released。
+func (s *HoldService) settle(tx *sql.Tx, h *Hold, delta money.Amount, entry WalletEntry) (*WalletTransaction, error) {
+	if _, err := tx.Exec(`
+		UPDATE player_wallets SET balance = balance + ?, frozen_balance = frozen_balance - ?
+		WHERE player_id = ? AND currency = ?
+	`, h.Amount, h.Amount, h.PlayerID, h.Currency); err != nil {
+		return nil, err
+	}
+
+	var t *WalletTransaction
+	if delta != 0 {
+		entry.PlayerID, entry.Currency = h.PlayerID, h.Currency
+		entry.Amount = delta.Abs()
+		var err error
+		if delta.IsNegative() {
+			t, err = s.Wallet.DebitTx(tx, entry)
+		} else {
+			t, err = s.Wallet.CreditTx(tx, entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.TransactionID = t.TransactionID
+	}
+	if delta.IsNegative() {
+		h.Status, h.CapturedAmount = HoldCaptured, -delta
+	} else if h.Status != HoldExpired {
+		h.Status = HoldReleased
+	}
+	now := time.Now()
+	h.SettledAt = &now
+	if _, err := tx.Exec(`
+		UPDATE wallet_holds
+		SET status = ?, captured_amount = ?, transaction_id = ?, settled_at = ?
+		WHERE hold_code = ?
+	`, h.Status, h.CapturedAmount, nullString(h.TransactionID), now, h.HoldCode); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// lock 鎖定有效的凍結
+func (s *HoldService) lock(tx *sql.Tx, code string) (*Hold, error) {
+	h, err := s.load(tx, "hold_code = ? FOR UPDATE", code)
+	if err != nil {
+		return nil, err
+	}
+	if h.Status != HoldActive {
+		return nil, ErrHoldNotActive
+	}
+	return h, nil
+}
+
+func (s *HoldService) load(q queryer, where string, args ...interface{}) (*Hold, error) {
+	h, err := scanHold(q.QueryRow("SELECT "+holdColumns+" FROM wallet_holds WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrHoldNotFound
+	}
+	return h, err
+}
+
+func (s *HoldService) codes(where string, args ...interface{}) ([]string, error) {
+	rows, err := s.DB.Query("SELECT hold_code FROM wallet_holds WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (s *HoldService) withTx(fn func(*sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const holdColumns = `hold_code, player_id, currency, amount, captured_amount, reason, reference_type, reference_id,
+	status, expires_at, transaction_id, settled_at, created_at`
+
+func scanHold(row rowScanner) (*Hold, error) {
+	h := &Hold{}
+	var (
+		expiresAt, settledAt sql.NullTime
+		transactionID        sql.NullString
+	)
+	if err := row.Scan(&h.HoldCode, &h.PlayerID, &h.Currency, &h.Amount, &h.CapturedAmount, &h.Reason,
+		&h.ReferenceType, &h.ReferenceID, &h.Status, &expiresAt, &transactionID, &settledAt, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		h.ExpiresAt = &expiresAt.Time
+	}
+	if settledAt.Valid {
+		h.SettledAt = &settledAt.Time
+	}
+	h.TransactionID = transactionID.String
+	return h, nil
+}
+
+// newHoldCode 產生凍結編號
+func newHoldCode() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "HD" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
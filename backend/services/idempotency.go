@@ -0,0 +1,100 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"nexus-gaming-backend/config"
+)
+
+// 冪等鍵相關錯誤
+var (
+	ErrIdempotencyKeyInvalid = errors.New("Idempotency-Key 長度必須介於 1 到 128 字元")
+	ErrIdempotencyKeyReused  = errors.New("Idempotency-Key 已用於不同內容的請求")
+)
+
+// maxIdempotencyKeyLength 冪等鍵最大長度
+const maxIdempotencyKeyLength = 128
+
+// IdempotencyService 冪等請求服務
+//
+// 冪等鍵列與業務異動寫在同一個交易：執行失敗時一併回滾，重試會重新執行；
+// 並行的相同請求會在插入冪等鍵時等待前一個交易結束，之後直接重播其回應。
+type IdempotencyService struct {
+	DB *sql.DB
+}
+
+// NewIdempotencyService 建立新的冪等請求服務
+func NewIdempotencyService() *IdempotencyService {
+	return &IdempotencyService{DB: config.GetDB()}
+}
+
+// Run 在交易內執行 fn 並儲存其回應；key 已成功執行過時不再執行，回傳儲存的回應與 replayed = true
+//
+// key 為空字串時不做去重，僅在交易內執行 fn。
+func (s *IdempotencyService) Run(scope, key string, request interface{}, fn func(tx *sql.Tx) (interface{}, error)) (json.RawMessage, bool, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, false, ErrIdempotencyKeyInvalid
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, false, err
+	}
+	sum := sha256.Sum256(payload)
+	hash := hex.EncodeToString(sum[:])
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var keyID int64
+	if key != "" {
+		res, err := tx.Exec(`
+			INSERT IGNORE INTO idempotency_keys (scope, idempotency_key, request_hash) VALUES (?, ?, ?)
+		`, scope, key, hash)
+		if err != nil {
+			return nil, false, fmt.Errorf("寫入冪等鍵失敗: %v", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			var storedHash string
+			var body sql.NullString
+			if err := tx.QueryRow(`
+				SELECT request_hash, response_body FROM idempotency_keys
+				WHERE scope = ? AND idempotency_key = ?
+			`, scope, key).Scan(&storedHash, &body); err != nil {
+				return nil, false, err
+			}
+			if storedHash != hash {
+				return nil, false, ErrIdempotencyKeyReused
+			}
+			return json.RawMessage(body.String), true, nil
+		}
+		if keyID, err = res.LastInsertId(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	result, err := fn(tx)
+	if err != nil {
+		return nil, false, err
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return nil, false, err
+	}
+	if keyID != 0 {
+		if _, err := tx.Exec("UPDATE idempotency_keys SET response_body = ? WHERE id = ?", string(body), keyID); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return body, false, nil
+}
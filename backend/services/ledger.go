@@ -0,0 +1,495 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 總帳相關錯誤
+var (
+	ErrUnbalancedJournal = errors.New("傳票借貸不平衡")
+	ErrJournalNotFound   = errors.New("傳票不存在")
+	ErrAlreadyReversed   = errors.New("傳票已沖銷")
+	ErrReverseReversal   = errors.New("沖銷傳票不可再沖銷")
+)
+
+// 總帳帳戶類型
+const (
+	AccountPlayer = "player"
+	AccountHouse  = "house"
+	AccountBonus  = "bonus"
+	AccountAgent  = "agent"
+	AccountDealer = "dealer"
+)
+
+// 平台帳戶用途
+const (
+	HouseCash       = "cash"       // 外部資金（儲值、提領）
+	HouseGame       = "game"       // 遊戲損益（下注、派彩、退款）
+	HouseCommission = "commission" // 佣金支出
+	HouseAdjustment = "adjustment" // 人工調整與沖銷
+	HouseFX         = "fx"         // 幣別兌換（各幣別一個帳戶，餘額為平台的外匯部位）
+	HouseCredit     = "credit"     // 代理商與經銷商的信用額度（餘額為尚未償還的賒帳籌碼）
+)
+
+// JournalReversal 沖銷傳票類型
+const JournalReversal = "reversal"
+
+// LedgerAccount 總帳帳戶
+type LedgerAccount struct {
+	Type     string `json:"type"`
+	OwnerID  int64  `json:"owner_id,omitempty"`
+	Purpose  string `json:"purpose,omitempty"`
+	Currency string `json:"currency"`
+}
+
+// PlayerAccount 玩家錢包對應的帳戶
+func PlayerAccount(playerID int64, currency string) LedgerAccount {
+	return LedgerAccount{Type: AccountPlayer, OwnerID: playerID, Currency: currency}
+}
+
+// HouseAccount 平台帳戶
+func HouseAccount(purpose, currency string) LedgerAccount {
+	return LedgerAccount{Type: AccountHouse, Purpose: purpose, Currency: currency}
+}
+
+// BonusAccount 紅利發放帳戶
+func BonusAccount(currency string) LedgerAccount {
+	return LedgerAccount{Type: AccountBonus, Currency: currency}
+}
+
+// PlayerBonusAccount 玩家的紅利餘額帳戶（已發放但尚未轉為現金的紅利）
+func PlayerBonusAccount(playerID int64, currency string) LedgerAccount {
+	return LedgerAccount{Type: AccountBonus, OwnerID: playerID, Currency: currency}
+}
+
+// AgentAccount 代理商帳戶
+func AgentAccount(agentID int64, currency string) LedgerAccount {
+	return LedgerAccount{Type: AccountAgent, OwnerID: agentID, Currency: currency}
+}
+
+// DealerAccount 經銷商帳戶
+func DealerAccount(dealerID int64, currency string) LedgerAccount {
+	return LedgerAccount{Type: AccountDealer, OwnerID: dealerID, Currency: currency}
+}
+
+// Code 帳戶代碼（例如 player:12:TWD、house:cash:TWD、bonus:TWD、bonus:12:TWD）
+func (a LedgerAccount) Code() string {
+	switch {
+	case a.Type == AccountHouse:
+		return a.Type + ":" + a.Purpose + ":" + a.Currency
+	case a.owned():
+		return a.Type + ":" + strconv.FormatInt(a.OwnerID, 10) + ":" + a.Currency
+	default:
+		return a.Type + ":" + a.Currency
+	}
+}
+
+// LedgerLine 傳票分錄（金額為正表示增加帳戶餘額）
+type LedgerLine struct {
+	Account LedgerAccount `json:"account"`
+	Amount  money.Amount  `json:"amount"`
+}
+
+// Journal 傳票
+type Journal struct {
+	ID            int64        `json:"id"`
+	JournalCode   string       `json:"journal_code"`
+	Type          string       `json:"type"`
+	TransactionID string       `json:"transaction_id,omitempty"`
+	Currency      string       `json:"currency"`
+	ReversalOf    int64        `json:"reversal_of,omitempty"`
+	ReversedBy    string       `json:"reversed_by,omitempty"` // 沖銷此傳票的傳票編號
+	Description   string       `json:"description,omitempty"`
+	OperatorID    *int         `json:"operator_id,omitempty"`
+	Lines         []LedgerLine `json:"lines"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// UnbalancedJournal 借貸不平衡的傳票
+type UnbalancedJournal struct {
+	JournalCode string       `json:"journal_code"`
+	Difference  money.Amount `json:"difference"`
+}
+
+// WalletMismatch 錢包餘額與總帳不符
+type WalletMismatch struct {
+	PlayerID      int64        `json:"player_id"`
+	Currency      string       `json:"currency"`
+	WalletBalance money.Amount `json:"wallet_balance"` // 可用加凍結餘額
+	LedgerBalance money.Amount `json:"ledger_balance"`
+	Difference    money.Amount `json:"difference"`
+}
+
+// AgentWalletMismatch 代理商或經銷商的籌碼餘額與總帳不符
+type AgentWalletMismatch struct {
+	OwnerType     string       `json:"owner_type"`
+	OwnerID       int          `json:"owner_id"`
+	Currency      string       `json:"currency"`
+	WalletBalance money.Amount `json:"wallet_balance"`
+	LedgerBalance money.Amount `json:"ledger_balance"`
+	Difference    money.Amount `json:"difference"`
+}
+
+// FrozenMismatch 凍結餘額與有效凍結合計不一致的錢包
+type FrozenMismatch struct {
+	PlayerID      int64        `json:"player_id"`
+	Currency      string       `json:"currency"`
+	FrozenBalance money.Amount `json:"frozen_balance"`
+	ActiveHolds   money.Amount `json:"active_holds"`
+	Difference    money.Amount `json:"difference"`
+}
+
+// LedgerIntegrityReport 總帳完整性檢查結果
+type LedgerIntegrityReport struct {
+	CheckedAt             time.Time             `json:"checked_at"`
+	Journals              int64                 `json:"journals"`
+	Entries               int64                 `json:"entries"`
+	UnbalancedJournals    []UnbalancedJournal   `json:"unbalanced_journals"`
+	WalletMismatches      []WalletMismatch      `json:"wallet_mismatches"`
+	AgentWalletMismatches []AgentWalletMismatch `json:"agent_wallet_mismatches"`
+	FrozenMismatches      []FrozenMismatch      `json:"frozen_mismatches"`
+	UnpostedTransactions  int64                 `json:"unposted_transactions"` // 已完成但沒有傳票的交易（總帳上線前的歷史資料）
+	Balanced              bool                  `json:"balanced"`
+}
+
+// LedgerService 複式記帳總帳服務
+//
+// 傳票必須在呼叫端的交易內過帳（PostTx），與錢包異動一起提交或回滾；
+// 分錄過帳後不可修改，更正只能以沖銷傳票進行。
+type LedgerService struct {
+	DB       *sql.DB
+	accounts sync.Map // 帳戶代碼 → 帳戶ID
+}
+
+// NewLedgerService 建立新的總帳服務
+func NewLedgerService() *LedgerService {
+	return &LedgerService{DB: config.GetDB()}
+}
+
+// PostTx 在呼叫端交易內過帳（驗證借貸平衡後寫入傳票與分錄）
+func (s *LedgerService) PostTx(tx *sql.Tx, j *Journal) error {
+	if len(j.Lines) < 2 {
+		return ErrUnbalancedJournal
+	}
+	var sum money.Amount
+	for _, l := range j.Lines {
+		if l.Amount == 0 || l.Account.Currency != j.Currency {
+			return ErrUnbalancedJournal
+		}
+		sum += l.Amount
+	}
+	if sum != 0 {
+		return ErrUnbalancedJournal
+	}
+
+	if j.JournalCode == "" {
+		j.JournalCode = newJournalCode()
+	}
+	var reversalOf interface{}
+	if j.ReversalOf != 0 {
+		reversalOf = j.ReversalOf
+	}
+	res, err := tx.Exec(`
+		INSERT INTO ledger_journals
+			(journal_code, journal_type, transaction_id, currency, reversal_of, description, operator_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, j.JournalCode, j.Type, nullString(j.TransactionID), j.Currency, reversalOf, nullString(j.Description), j.OperatorID)
+	if err != nil {
+		return fmt.Errorf("寫入傳票失敗: %w", err)
+	}
+	if j.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	for _, l := range j.Lines {
+		accountID, err := s.accountID(l.Account)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO ledger_entries (journal_id, account_id, amount) VALUES (?, ?, ?)
+		`, j.ID, accountID, l.Amount); err != nil {
+			return fmt.Errorf("寫入分錄失敗: %w", err)
+		}
+	}
+	return nil
+}
+
+// Post 以獨立交易過帳（不涉及玩家錢包的傳票，例如平台與代理帳戶間的移轉）
+func (s *LedgerService) Post(j *Journal) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.PostTx(tx, j); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByTransaction 取得交易流水號對應的傳票
+func (s *LedgerService) GetByTransaction(transactionID string) (*Journal, error) {
+	return s.load(s.DB, "j.transaction_id = ?", transactionID)
+}
+
+// lockByTransaction 在交易內鎖定交易流水號對應的傳票（沖銷前使用）
+func (s *LedgerService) lockByTransaction(tx *sql.Tx, transactionID string) (*Journal, error) {
+	return s.load(tx, "j.transaction_id = ? FOR UPDATE", transactionID)
+}
+
+func (s *LedgerService) load(q queryer, where string, args ...interface{}) (*Journal, error) {
+	j := &Journal{}
+	var (
+		transactionID, description sql.NullString
+		reversalOf                 sql.NullInt64
+		operatorID                 sql.NullInt64
+	)
+	err := q.QueryRow(`
+		SELECT j.id, j.journal_code, j.journal_type, j.transaction_id, j.currency, j.reversal_of,
+		       j.description, j.operator_id, j.created_at
+		FROM ledger_journals j
+		WHERE `+where, args...).Scan(&j.ID, &j.JournalCode, &j.Type, &transactionID, &j.Currency, &reversalOf,
+		&description, &operatorID, &j.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrJournalNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	j.TransactionID, j.Description, j.ReversalOf = transactionID.String, description.String, reversalOf.Int64
+	if operatorID.Valid {
+		id := int(operatorID.Int64)
+		j.OperatorID = &id
+	}
+
+	var reversedBy sql.NullString
+	err = q.QueryRow("SELECT journal_code FROM ledger_journals WHERE reversal_of = ?", j.ID).Scan(&reversedBy)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	j.ReversedBy = reversedBy.String
+
+	rows, err := q.Query(`
+		SELECT a.account_type, COALESCE(a.owner_id, 0), a.purpose, a.currency, e.amount
+		FROM ledger_entries e
+		JOIN ledger_accounts a ON e.account_id = a.id
+		WHERE e.journal_id = ?
+		ORDER BY e.id
+	`, j.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l LedgerLine
+		if err := rows.Scan(&l.Account.Type, &l.Account.OwnerID, &l.Account.Purpose, &l.Account.Currency, &l.Amount); err != nil {
+			return nil, err
+		}
+		j.Lines = append(j.Lines, l)
+	}
+	return j, rows.Err()
+}
+
+// Verify 檢查總帳完整性：每張傳票借貸平衡，且每個錢包的總餘額等於其總帳帳戶的分錄合計
+func (s *LedgerService) Verify() (*LedgerIntegrityReport, error) {
+	report := &LedgerIntegrityReport{
+		CheckedAt:             time.Now(),
+		UnbalancedJournals:    []UnbalancedJournal{},
+		WalletMismatches:      []WalletMismatch{},
+		AgentWalletMismatches: []AgentWalletMismatch{},
+		FrozenMismatches:      []FrozenMismatch{},
+	}
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM ledger_journals").Scan(&report.Journals); err != nil {
+		return nil, err
+	}
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM ledger_entries").Scan(&report.Entries); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT j.journal_code, SUM(e.amount)
+		FROM ledger_journals j
+		JOIN ledger_entries e ON e.journal_id = j.id
+		GROUP BY j.id, j.journal_code
+		HAVING SUM(e.amount) <> 0
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var u UnbalancedJournal
+		if err := rows.Scan(&u.JournalCode, &u.Difference); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.UnbalancedJournals = append(report.UnbalancedJournals, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Query(`
+		SELECT w.player_id, w.currency, w.balance + w.frozen_balance, COALESCE(l.total, 0)
+		FROM player_wallets w
+		LEFT JOIN (
+			SELECT a.owner_id, a.currency, SUM(e.amount) AS total
+			FROM ledger_accounts a
+			JOIN ledger_entries e ON e.account_id = a.id
+			WHERE a.account_type = 'player'
+			GROUP BY a.owner_id, a.currency
+		) l ON l.owner_id = w.player_id AND l.currency = w.currency
+		WHERE w.balance + w.frozen_balance <> COALESCE(l.total, 0)
+		ORDER BY w.player_id, w.currency
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var m WalletMismatch
+		if err := rows.Scan(&m.PlayerID, &m.Currency, &m.WalletBalance, &m.LedgerBalance); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Difference = m.WalletBalance - m.LedgerBalance
+		report.WalletMismatches = append(report.WalletMismatches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Query(`
+		SELECT w.owner_type, w.owner_id, w.currency, w.balance, COALESCE(l.total, 0)
+		FROM agent_wallets w
+		LEFT JOIN (
+			SELECT a.account_type, a.owner_id, a.currency, SUM(e.amount) AS total
+			FROM ledger_accounts a
+			JOIN ledger_entries e ON e.account_id = a.id
+			WHERE a.account_type IN ('agent', 'dealer')
+			GROUP BY a.account_type, a.owner_id, a.currency
+		) l ON l.account_type = w.owner_type AND l.owner_id = w.owner_id AND l.currency = w.currency
+		WHERE w.balance <> COALESCE(l.total, 0)
+		ORDER BY w.owner_type, w.owner_id, w.currency
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var m AgentWalletMismatch
+		if err := rows.Scan(&m.OwnerType, &m.OwnerID, &m.Currency, &m.WalletBalance, &m.LedgerBalance); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Difference = m.WalletBalance - m.LedgerBalance
+		report.AgentWalletMismatches = append(report.AgentWalletMismatches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Query(`
+		SELECT w.player_id, w.currency, w.frozen_balance, COALESCE(h.total, 0)
+		FROM player_wallets w
+		LEFT JOIN (
+			SELECT player_id, currency, SUM(amount) AS total
+			FROM wallet_holds
+			WHERE status = 'active'
+			GROUP BY player_id, currency
+		) h ON h.player_id = w.player_id AND h.currency = w.currency
+		WHERE w.frozen_balance <> COALESCE(h.total, 0)
+		ORDER BY w.player_id, w.currency
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var m FrozenMismatch
+		if err := rows.Scan(&m.PlayerID, &m.Currency, &m.FrozenBalance, &m.ActiveHolds); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Difference = m.FrozenBalance - m.ActiveHolds
+		report.FrozenMismatches = append(report.FrozenMismatches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.QueryRow(`
+		SELECT COUNT(*) FROM transactions t
+		WHERE t.status = 'completed'
+		AND NOT EXISTS (SELECT 1 FROM ledger_journals j WHERE j.transaction_id = t.transaction_id)
+	`).Scan(&report.UnpostedTransactions); err != nil {
+		return nil, err
+	}
+
+	report.Balanced = len(report.UnbalancedJournals) == 0 && len(report.WalletMismatches) == 0 &&
+		len(report.AgentWalletMismatches) == 0 && len(report.FrozenMismatches) == 0
+	return report, nil
+}
+
+// accountID 取得帳戶ID，不存在時建立
+//
+// 帳戶在呼叫端交易之外建立並快取：帳戶只是識別資料，不持有餘額，
+// 過帳時不需鎖定平台帳戶，避免所有資金異動在同一列上排隊。
+func (s *LedgerService) accountID(a LedgerAccount) (int64, error) {
+	code := a.Code()
+	if id, ok := s.accounts.Load(code); ok {
+		return id.(int64), nil
+	}
+	var ownerID interface{}
+	if a.owned() {
+		ownerID = a.OwnerID
+	}
+	if _, err := s.DB.Exec(`
+		INSERT IGNORE INTO ledger_accounts (account_code, account_type, owner_id, purpose, currency)
+		VALUES (?, ?, ?, ?, ?)
+	`, code, a.Type, ownerID, a.Purpose, a.Currency); err != nil {
+		return 0, fmt.Errorf("建立總帳帳戶失敗: %v", err)
+	}
+	var id int64
+	if err := s.DB.QueryRow("SELECT id FROM ledger_accounts WHERE account_code = ?", code).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.accounts.Store(code, id)
+	return id, nil
+}
+
+// owned 帳戶是否屬於特定玩家、代理商或經銷商
+func (a LedgerAccount) owned() bool {
+	return a.Type == AccountPlayer || a.Type == AccountAgent || a.Type == AccountDealer || a.OwnerID != 0
+}
+
+// reversal 建立沖銷傳票（分錄金額全部反向）
+func (j *Journal) reversal(description string, operatorID *int) *Journal {
+	r := &Journal{
+		Type:        JournalReversal,
+		Currency:    j.Currency,
+		ReversalOf:  j.ID,
+		Description: description,
+		OperatorID:  operatorID,
+	}
+	for _, l := range j.Lines {
+		r.Lines = append(r.Lines, LedgerLine{Account: l.Account, Amount: -l.Amount})
+	}
+	return r
+}
+
+// newJournalCode 產生傳票編號
+func newJournalCode() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "JN" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDatabaseUnavailable 資料庫尚未連線（啟動時連線失敗）
+var ErrDatabaseUnavailable = errors.New("資料庫未連線")
+
+// MySQL 錯誤碼
+const (
+	mysqlErrDuplicateEntry = 1062 // 唯一鍵衝突
+	mysqlErrDeadlock       = 1213 // 偵測到死結，交易已被回滾
+)
+
+// maxDeadlockRetries 死結時整筆交易的最多嘗試次數
+const maxDeadlockRetries = 3
+
+// isDuplicateKey 是否為唯一鍵衝突
+func isDuplicateKey(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == mysqlErrDuplicateEntry
+}
+
+// isDeadlock 是否為死結（交易已被 MySQL 回滾，可整筆重試）
+func isDeadlock(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == mysqlErrDeadlock
+}
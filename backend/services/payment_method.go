@@ -0,0 +1,497 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+)
+
+// 支付方式相關錯誤
+var (
+	ErrPaymentMethodNotFound = errors.New("支付方式不存在")
+	ErrPaymentMethodBlocked  = errors.New("此支付方式已因詐欺通報遭封鎖")
+	ErrPaymentMethodInactive = errors.New("支付方式已停用")
+	ErrAccountNumberRequired = errors.New("帳戶資訊需包含卡號、帳號、IBAN 或錢包地址")
+	ErrSensitiveField        = errors.New("不可保存卡片安全碼或密碼")
+	ErrFingerprintNotBlocked = errors.New("此帳號未被封鎖")
+)
+
+// accountNumberFields 帳號欄位（依序取第一個有值者作為遮罩與指紋的依據）
+var accountNumberFields = []string{"card_number", "account_number", "iban", "wallet_address", "account_id"}
+
+// plainAccountFields 輸出時不遮罩的欄位
+var plainAccountFields = map[string]bool{
+	"bank_name": true, "bank_code": true, "branch_name": true, "brand": true, "network": true, "currency": true,
+}
+
+// forbiddenAccountFields 不可保存的欄位
+var forbiddenAccountFields = map[string]bool{"cvv": true, "cvc": true, "cvv2": true, "pin": true, "password": true}
+
+// PaymentMethod 玩家保存的支付方式（帳戶資訊一律遮罩輸出）
+type PaymentMethod struct {
+	ID            int               `json:"id"`
+	PlayerID      int64             `json:"player_id"`
+	MethodType    string            `json:"method_type"`
+	ProviderName  string            `json:"provider_name"`
+	AccountInfo   map[string]string `json:"account_info"`
+	MaskedNumber  string            `json:"masked_number"`
+	DisplayName   string            `json:"display_name,omitempty"`
+	IsVerified    bool              `json:"is_verified"`
+	VerifiedAt    *time.Time        `json:"verified_at,omitempty"`
+	IsDefault     bool              `json:"is_default"`
+	Status        string            `json:"status"`
+	BlockedReason string            `json:"blocked_reason,omitempty"`
+	LastUsedAt    *time.Time        `json:"last_used_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+
+	fingerprint string
+	account     map[string]string
+}
+
+// PaymentMethodRequest 新增支付方式請求
+type PaymentMethodRequest struct {
+	PlayerID     int64
+	MethodType   string
+	ProviderName string
+	AccountInfo  map[string]string
+	DisplayName  string
+	Default      bool
+}
+
+// BlockedPaymentAccount 因詐欺通報封鎖的帳號
+type BlockedPaymentAccount struct {
+	Fingerprint      string    `json:"fingerprint"`
+	MethodType       string    `json:"method_type"`
+	MaskedNumber     string    `json:"masked_number"`
+	Reason           string    `json:"reason"`
+	ReportedPlayerID *int64    `json:"reported_player_id,omitempty"`
+	BlockedBy        *int      `json:"blocked_by,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// PaymentMethodService 玩家支付方式保管
+//
+// 帳戶資訊以信封加密保存，另存遮罩後的帳號與帳號指紋；通報詐欺時以指紋封鎖所有玩家的相同帳號，
+// 封鎖的帳號不可再新增，也不可用於提領。
+type PaymentMethodService struct {
+	DB *sql.DB
+}
+
+// NewPaymentMethodService 建立新的支付方式服務
+func NewPaymentMethodService() *PaymentMethodService {
+	return &PaymentMethodService{DB: config.GetDB()}
+}
+
+// List 列出玩家的支付方式（預設在前）
+func (s *PaymentMethodService) List(playerID int64) ([]PaymentMethod, error) {
+	rows, err := s.DB.Query(paymentMethodSelect+" WHERE player_id = ? ORDER BY is_default DESC, id DESC", playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []PaymentMethod{}
+	for rows.Next() {
+		m, err := scanPaymentMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *m)
+	}
+	return list, rows.Err()
+}
+
+// Get 取得玩家的支付方式
+func (s *PaymentMethodService) Get(playerID int64, id int) (*PaymentMethod, error) {
+	return s.load(s.DB, "id = ? AND player_id = ?", id, playerID)
+}
+
+// Create 新增支付方式（玩家第一個有效的支付方式自動設為預設）
+func (s *PaymentMethodService) Create(req PaymentMethodRequest) (*PaymentMethod, error) {
+	account := map[string]string{}
+	for k, v := range req.AccountInfo {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if forbiddenAccountFields[k] {
+			return nil, ErrSensitiveField
+		}
+		if v = strings.TrimSpace(v); v != "" {
+			account[k] = v
+		}
+	}
+	number := accountNumber(account)
+	if number == "" {
+		return nil, ErrAccountNumberRequired
+	}
+	v, err := DataVault()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := v.Fingerprint(number)
+	raw, _ := json.Marshal(account)
+	sealed, err := sealJSON(columnPaymentMethodAccount, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = s.withTx(func(tx *sql.Tx) error {
+		var status string
+		err := tx.QueryRow("SELECT status FROM players WHERE id = ? FOR UPDATE", req.PlayerID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrPlayerNotFound
+		} else if err != nil {
+			return err
+		}
+		if status == "deleted" || status == "suspended" {
+			return ErrPlayerInactive
+		}
+		if blocked, err := isBlockedFingerprint(tx, fingerprint); err != nil {
+			return err
+		} else if blocked {
+			return ErrPaymentMethodBlocked
+		}
+		var defaults int
+		if err := tx.QueryRow(`
+			SELECT COUNT(*) FROM payment_methods WHERE player_id = ? AND is_default = TRUE AND status = 'active'
+		`, req.PlayerID).Scan(&defaults); err != nil {
+			return err
+		}
+		isDefault := req.Default || defaults == 0
+		if isDefault {
+			if _, err := tx.Exec("UPDATE payment_methods SET is_default = FALSE WHERE player_id = ?", req.PlayerID); err != nil {
+				return err
+			}
+		}
+		res, err := tx.Exec(`
+			INSERT INTO payment_methods
+				(player_id, method_type, provider_name, account_info, masked_number, fingerprint, display_name, is_default)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, req.PlayerID, req.MethodType, req.ProviderName, sealed, maskNumber(number), fingerprint,
+			nullString(req.DisplayName), isDefault)
+		if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(req.PlayerID, int(id))
+}
+
+// Update 更新顯示名稱與提供商（帳戶資訊不可修改，需新增支付方式）
+func (s *PaymentMethodService) Update(playerID int64, id int, providerName, displayName string) (*PaymentMethod, error) {
+	m, err := s.Get(playerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if providerName == "" {
+		providerName = m.ProviderName
+	}
+	if _, err := s.DB.Exec(`
+		UPDATE payment_methods SET provider_name = ?, display_name = ? WHERE id = ? AND player_id = ?
+	`, providerName, nullString(displayName), id, playerID); err != nil {
+		return nil, err
+	}
+	return s.Get(playerID, id)
+}
+
+// Delete 刪除支付方式（封鎖的支付方式保留供稽核）；刪除預設時改以最近新增的有效支付方式為預設
+func (s *PaymentMethodService) Delete(playerID int64, id int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		m, err := s.load(tx, "id = ? AND player_id = ? FOR UPDATE", id, playerID)
+		if err != nil {
+			return err
+		}
+		if m.Status == "blocked" {
+			return ErrPaymentMethodBlocked
+		}
+		if _, err := tx.Exec("DELETE FROM payment_methods WHERE id = ?", id); err != nil {
+			return err
+		}
+		if !m.IsDefault {
+			return nil
+		}
+		_, err = tx.Exec(`
+			UPDATE payment_methods SET is_default = TRUE
+			WHERE player_id = ? AND status = 'active'
+			ORDER BY id DESC LIMIT 1
+		`, playerID)
+		return err
+	})
+}
+
+// SetDefault 設為玩家的預設支付方式
+func (s *PaymentMethodService) SetDefault(playerID int64, id int) (*PaymentMethod, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		m, err := s.load(tx, "id = ? AND player_id = ? FOR UPDATE", id, playerID)
+		if err != nil {
+			return err
+		}
+		if err := m.usable(); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE payment_methods SET is_default = (id = ?) WHERE player_id = ?", id, playerID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(playerID, id)
+}
+
+// Verify 標記支付方式已驗證（例如人工核對帳戶持有人或小額驗證入帳完成）
+func (s *PaymentMethodService) Verify(playerID int64, id int, operatorID *int) (*PaymentMethod, error) {
+	m, err := s.Get(playerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.usable(); err != nil {
+		return nil, err
+	}
+	if _, err := s.DB.Exec(`
+		UPDATE payment_methods SET is_verified = TRUE, verified_at = NOW(), verified_by = ? WHERE id = ?
+	`, operatorID, id); err != nil {
+		return nil, err
+	}
+	return s.Get(playerID, id)
+}
+
+// ReportFraud 通報詐欺：封鎖此帳號並停用所有玩家的相同帳號，回傳受影響的支付方式筆數
+func (s *PaymentMethodService) ReportFraud(playerID int64, id int, reason string, operatorID *int) (int64, error) {
+	var affected int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		m, err := s.load(tx, "id = ? AND player_id = ? FOR UPDATE", id, playerID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO blocked_payment_fingerprints
+				(fingerprint, method_type, masked_number, reason, reported_player_id, reported_method_id, blocked_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE reason = VALUES(reason)
+		`, m.fingerprint, m.MethodType, m.MaskedNumber, reason, playerID, id, operatorID); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`
+			UPDATE payment_methods SET status = 'blocked', is_default = FALSE, blocked_reason = ?
+			WHERE fingerprint = ? AND status <> 'blocked'
+		`, reason, m.fingerprint)
+		if err != nil {
+			return err
+		}
+		affected, _ = res.RowsAffected()
+		return nil
+	})
+	return affected, err
+}
+
+// Blocked 列出封鎖的帳號
+func (s *PaymentMethodService) Blocked() ([]BlockedPaymentAccount, error) {
+	rows, err := s.DB.Query(`
+		SELECT fingerprint, method_type, masked_number, reason, reported_player_id, blocked_by, created_at
+		FROM blocked_payment_fingerprints ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []BlockedPaymentAccount{}
+	for rows.Next() {
+		var (
+			b         BlockedPaymentAccount
+			playerID  sql.NullInt64
+			blockedBy sql.NullInt64
+		)
+		if err := rows.Scan(&b.Fingerprint, &b.MethodType, &b.MaskedNumber, &b.Reason, &playerID, &blockedBy, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		if playerID.Valid {
+			b.ReportedPlayerID = &playerID.Int64
+		}
+		b.BlockedBy = nullInt(blockedBy)
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// Unblock 解除封鎖，因此封鎖而停用的支付方式恢復為有效
+func (s *PaymentMethodService) Unblock(fingerprint string) (int64, error) {
+	var restored int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM blocked_payment_fingerprints WHERE fingerprint = ?", fingerprint)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrFingerprintNotBlocked
+		}
+		res, err = tx.Exec(`
+			UPDATE payment_methods SET status = 'active', blocked_reason = NULL
+			WHERE fingerprint = ? AND status = 'blocked'
+		`, fingerprint)
+		if err != nil {
+			return err
+		}
+		restored, _ = res.RowsAffected()
+		return nil
+	})
+	return restored, err
+}
+
+// AccountTx 在呼叫端交易內取得可用於提領的支付方式完整帳戶資訊，並更新最後使用時間
+func (s *PaymentMethodService) AccountTx(tx *sql.Tx, playerID int64, id int) (*PaymentMethod, json.RawMessage, error) {
+	m, err := s.load(tx, "id = ? AND player_id = ? FOR UPDATE", id, playerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.usable(); err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.Exec("UPDATE payment_methods SET last_used_at = NOW() WHERE id = ?", id); err != nil {
+		return nil, nil, err
+	}
+	raw, _ := json.Marshal(m.account)
+	return m, raw, nil
+}
+
+// CheckAccountTx 檢查提領帳戶資訊中的帳號是否已被封鎖
+func (s *PaymentMethodService) CheckAccountTx(tx *sql.Tx, info json.RawMessage) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(info, &fields); err != nil {
+		return nil
+	}
+	account := map[string]string{}
+	for k, v := range fields {
+		if str, ok := v.(string); ok {
+			account[strings.ToLower(k)] = str
+		}
+	}
+	number := accountNumber(account)
+	if number == "" {
+		return nil
+	}
+	v, err := DataVault()
+	if err != nil {
+		return err
+	}
+	blocked, err := isBlockedFingerprint(tx, v.Fingerprint(number))
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrPaymentMethodBlocked
+	}
+	return nil
+}
+
+// usable 支付方式是否可使用
+func (m *PaymentMethod) usable() error {
+	switch m.Status {
+	case "blocked":
+		return ErrPaymentMethodBlocked
+	case "active":
+		return nil
+	default:
+		return ErrPaymentMethodInactive
+	}
+}
+
+func (s *PaymentMethodService) load(q queryer, where string, args ...interface{}) (*PaymentMethod, error) {
+	m, err := scanPaymentMethod(q.QueryRow(paymentMethodSelect+" WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentMethodNotFound
+	}
+	return m, err
+}
+
+func (s *PaymentMethodService) withTx(fn func(*sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isBlockedFingerprint 帳號指紋是否已被封鎖
+func isBlockedFingerprint(q queryer, fingerprint string) (bool, error) {
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM blocked_payment_fingerprints WHERE fingerprint = ?", fingerprint).Scan(&n)
+	return n > 0, err
+}
+
+// accountNumber 取得正規化的帳號（去除空白與連字號並轉為大寫）
+func accountNumber(account map[string]string) string {
+	for _, field := range accountNumberFields {
+		if v := account[field]; v != "" {
+			return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(v))
+		}
+	}
+	return ""
+}
+
+// maskNumber 只保留末四碼
+func maskNumber(v string) string {
+	r := []rune(v)
+	if len(r) <= 4 {
+		return strings.Repeat("*", len(r))
+	}
+	return strings.Repeat("*", len(r)-4) + string(r[len(r)-4:])
+}
+
+// maskAccount 遮罩帳戶資訊（銀行名稱等非敏感欄位除外）
+func maskAccount(account map[string]string) map[string]string {
+	masked := make(map[string]string, len(account))
+	for k, v := range account {
+		if plainAccountFields[k] {
+			masked[k] = v
+		} else {
+			masked[k] = maskNumber(v)
+		}
+	}
+	return masked
+}
+
+const paymentMethodSelect = `
+	SELECT id, player_id, method_type, provider_name, account_info, COALESCE(masked_number, ''),
+	       COALESCE(fingerprint, ''), COALESCE(display_name, ''), is_verified, verified_at, is_default, status,
+	       COALESCE(blocked_reason, ''), last_used_at, created_at
+	FROM payment_methods`
+
+func scanPaymentMethod(row rowScanner) (*PaymentMethod, error) {
+	m := &PaymentMethod{}
+	var (
+		accountInfo          []byte
+		verifiedAt, lastUsed sql.NullTime
+	)
+	if err := row.Scan(&m.ID, &m.PlayerID, &m.MethodType, &m.ProviderName, &accountInfo, &m.MaskedNumber,
+		&m.fingerprint, &m.DisplayName, &m.IsVerified, &verifiedAt, &m.IsDefault, &m.Status,
+		&m.BlockedReason, &lastUsed, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	raw, err := openJSON(columnPaymentMethodAccount, accountInfo)
+	if err != nil {
+		return nil, err
+	}
+	m.account = map[string]string{}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err == nil {
+		for k, v := range fields {
+			if str, ok := v.(string); ok {
+				m.account[k] = str
+			}
+		}
+	}
+	m.AccountInfo = maskAccount(m.account)
+	m.VerifiedAt, m.LastUsedAt = nullTime(verifiedAt), nullTime(lastUsed)
+	return m, nil
+}
@@ -0,0 +1,841 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 業績統計相關錯誤
+var (
+	ErrPerformanceRange  = errors.New("統計日期區間無效")
+	ErrPerformanceRankBy = errors.New("不支援的排名指標")
+)
+
+// PerformanceStats 單日業績（金額為統計幣別）
+//
+// GGR = 下注 - 派彩 - 退款，NGR = GGR - 紅利；活躍玩家為當日有下注或參與牌局的玩家，
+// 留存率為前一日活躍玩家中當日仍活躍的比例（百分比）。
+type PerformanceStats struct {
+	TotalPlayers     int          `json:"total_players"`
+	ActivePlayers    int          `json:"active_players"`
+	NewPlayers       int          `json:"new_players"`
+	BettingPlayers   int          `json:"betting_players"`
+	TotalDeposits    money.Amount `json:"total_deposits"`
+	TotalWithdrawals money.Amount `json:"total_withdrawals"`
+	TotalBets        money.Amount `json:"total_bets"`
+	TotalWins        money.Amount `json:"total_wins"`
+	TotalRefunds     money.Amount `json:"total_refunds"`
+	TotalBonuses     money.Amount `json:"total_bonuses"`
+	GrossRevenue     money.Amount `json:"gross_revenue"`
+	NetRevenue       money.Amount `json:"net_revenue"`
+	CommissionEarned money.Amount `json:"commission_earned"`
+	DealerCount      int          `json:"dealer_count"`
+	AvgBetPerPlayer  money.Amount `json:"avg_bet_per_player"`
+	RetentionRate    float64      `json:"player_retention_rate"`
+
+	prevActive, retained int
+}
+
+// add 依交易類型累加金額
+func (p *PerformanceStats) add(transactionType string, amount money.Amount) {
+	switch transactionType {
+	case "deposit":
+		p.TotalDeposits += amount
+	case "withdrawal":
+		p.TotalWithdrawals += amount
+	case "bet":
+		p.TotalBets += amount
+	case "win":
+		p.TotalWins += amount
+	case "refund":
+		p.TotalRefunds += amount
+	case "bonus":
+		p.TotalBonuses += amount
+	}
+}
+
+// finish 計算衍生指標
+func (p *PerformanceStats) finish() {
+	p.GrossRevenue = p.TotalBets - p.TotalWins - p.TotalRefunds
+	p.NetRevenue = p.GrossRevenue - p.TotalBonuses
+	p.AvgBetPerPlayer = 0
+	if p.BettingPlayers > 0 {
+		p.AvgBetPerPlayer = p.TotalBets / money.Amount(p.BettingPlayers)
+	}
+	p.RetentionRate = 0
+	if p.prevActive > 0 {
+		p.RetentionRate = math.Round(float64(p.retained)/float64(p.prevActive)*10000) / 100
+	}
+}
+
+// PerformanceDay 單日業績
+type PerformanceDay struct {
+	Date string `json:"date"`
+	PerformanceStats
+}
+
+// PerformanceTotals 期間業績（人數以日計加總；總玩家數與經銷商數為期末值）
+type PerformanceTotals struct {
+	TotalPlayers      int          `json:"total_players"`
+	ActivePlayerDays  int          `json:"active_player_days"`
+	AvgDailyActive    float64      `json:"avg_daily_active"`
+	NewPlayers        int          `json:"new_players"`
+	BettingPlayerDays int          `json:"betting_player_days"`
+	TotalDeposits     money.Amount `json:"total_deposits"`
+	TotalWithdrawals  money.Amount `json:"total_withdrawals"`
+	TotalBets         money.Amount `json:"total_bets"`
+	TotalWins         money.Amount `json:"total_wins"`
+	TotalRefunds      money.Amount `json:"total_refunds"`
+	TotalBonuses      money.Amount `json:"total_bonuses"`
+	GrossRevenue      money.Amount `json:"gross_revenue"`
+	NetRevenue        money.Amount `json:"net_revenue"`
+	CommissionEarned  money.Amount `json:"commission_earned"`
+	DealerCount       int          `json:"dealer_count"`
+	AvgBetPerPlayer   money.Amount `json:"avg_bet_per_player"`
+	RetentionRate     float64      `json:"player_retention_rate"` // 期間內每日留存率的平均
+	Days              int          `json:"days"`                  // 已有統計的天數
+}
+
+// PerformanceEntry 代理商或經銷商的期間業績、前期比較與排名
+type PerformanceEntry struct {
+	TargetType string              `json:"target_type"`
+	ID         int                 `json:"id"`
+	Code       string              `json:"code"`
+	Name       string              `json:"name"`
+	Rank       int                 `json:"rank"`
+	Current    PerformanceTotals   `json:"current"`
+	Previous   PerformanceTotals   `json:"previous"`
+	Changes    map[string]*float64 `json:"changes"` // 對前期的變化百分比（前期為 0 時為 null）
+}
+
+// PerformanceComparison 整體期間業績與前期比較
+type PerformanceComparison struct {
+	Current  PerformanceTotals   `json:"current"`
+	Previous PerformanceTotals   `json:"previous"`
+	Changes  map[string]*float64 `json:"changes"`
+}
+
+// PerformanceReport 代理商業績報表
+//
+// 未指定代理商時，Totals 與 Daily 為所有代理商的合計；指定代理商時為該代理商（含其經銷商的玩家），
+// 並列出其經銷商排名。
+type PerformanceReport struct {
+	StartDate     string                `json:"start_date"`
+	EndDate       string                `json:"end_date"`
+	PreviousStart string                `json:"previous_start"`
+	PreviousEnd   string                `json:"previous_end"`
+	Currency      string                `json:"currency"`
+	RankBy        string                `json:"rank_by"`
+	Totals        PerformanceComparison `json:"totals"`
+	Daily         []PerformanceDay      `json:"daily"`
+	Rankings      []PerformanceEntry    `json:"rankings"` // 代理商排名（前 Limit 名）
+	Agent         *PerformanceEntry     `json:"agent,omitempty"`
+	Dealers       []PerformanceEntry    `json:"dealers,omitempty"`
+	MissingDays   []string              `json:"missing_days"` // 尚未產生統計的日期
+}
+
+// PerformanceReportFilter 業績報表條件
+type PerformanceReportFilter struct {
+	Start   time.Time
+	End     time.Time
+	AgentID *int
+	RankBy  string
+	Limit   int
+}
+
+// performanceRankBy 可排名的指標
+var performanceRankBy = map[string]func(PerformanceTotals) float64{
+	"net_revenue":       func(t PerformanceTotals) float64 { return t.NetRevenue.Float64() },
+	"gross_revenue":     func(t PerformanceTotals) float64 { return t.GrossRevenue.Float64() },
+	"total_bets":        func(t PerformanceTotals) float64 { return t.TotalBets.Float64() },
+	"total_deposits":    func(t PerformanceTotals) float64 { return t.TotalDeposits.Float64() },
+	"commission_earned": func(t PerformanceTotals) float64 { return t.CommissionEarned.Float64() },
+	"new_players":       func(t PerformanceTotals) float64 { return float64(t.NewPlayers) },
+	"active_players":    func(t PerformanceTotals) float64 { return t.AvgDailyActive },
+	"retention_rate":    func(t PerformanceTotals) float64 { return t.RetentionRate },
+}
+
+// performanceChanges 列入前期比較的指標
+var performanceChanges = []string{
+	"net_revenue", "gross_revenue", "total_bets", "total_deposits", "commission_earned", "new_players", "active_players",
+}
+
+// PerformanceService 代理商與經銷商每日業績統計
+//
+// 每日統計由交易、玩家與遊戲參與記錄整批重建（先刪除再寫入），可重複執行；
+// 玩家依目前的所屬代理商與經銷商歸屬，各幣別金額以當日結束時的匯率換算為統計幣別（預設幣別）。
+type PerformanceService struct {
+	DB *sql.DB
+	FX *FXService
+}
+
+// NewPerformanceService 建立新的業績統計服務
+func NewPerformanceService() *PerformanceService {
+	return &PerformanceService{DB: config.GetDB(), FX: NewFXService()}
+}
+
+// Rollup 重建 [start, end] 期間（含首尾日）的每日統計；指定代理商時只重建該代理商與其經銷商
+func (s *PerformanceService) Rollup(start, end time.Time, agentID *int) (int, error) {
+	start, end = startOfDay(start), startOfDay(end)
+	if end.Before(start) || end.After(startOfDay(time.Now())) {
+		return 0, ErrPerformanceRange
+	}
+	if agentID != nil {
+		if err := commissionTargetExists(s.DB, NodeAgent, *agentID); err != nil {
+			return 0, err
+		}
+	}
+	prev, err := s.activePlayers(start.AddDate(0, 0, -1), agentID)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if prev, err = s.rollupDay(day, agentID, prev); err != nil {
+			return n, fmt.Errorf("%s 業績統計失敗: %w", day.Format("2006-01-02"), err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// CatchUp 重建最後統計日（不晚於前一日）至今日的統計；尚無統計時由第一個代理商建立日起回補
+func (s *PerformanceService) CatchUp() (int, error) {
+	today := startOfDay(time.Now())
+	var last, first sql.NullTime
+	if err := s.DB.QueryRow("SELECT MAX(stat_date) FROM agent_performance_stats").Scan(&last); err != nil {
+		return 0, err
+	}
+	start := today.AddDate(0, 0, -1)
+	if last.Valid {
+		if d := startOfDay(last.Time); d.Before(start) {
+			start = d
+		}
+	} else {
+		if err := s.DB.QueryRow("SELECT MIN(created_at) FROM agents").Scan(&first); err != nil {
+			return 0, err
+		}
+		if first.Valid && startOfDay(first.Time).Before(start) {
+			start = startOfDay(first.Time)
+		}
+	}
+	return s.Rollup(start, today, nil)
+}
+
+// Run 啟動時回補並定期重建每日統計，直到 ctx 結束（阻塞執行，應以 goroutine 啟動）
+func (s *PerformanceService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.CatchUp(); err != nil {
+			log.Printf("代理商業績統計失敗: %v", err)
+		} else if n > 2 {
+			log.Printf("已回補 %d 日的代理商業績統計", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// performanceOwner 玩家目前的所屬代理商與經銷商（無經銷商時為 0）
+type performanceOwner struct {
+	agentID  int
+	dealerID int
+}
+
+// activePlayers 某日活躍的玩家（有下注或參與牌局，練習場不計），值為是否有下注
+func (s *PerformanceService) activePlayers(day time.Time, agentID *int) (map[int64]activePlayer, error) {
+	scope, args := performanceScope("p.agent_id", agentID)
+	next := day.AddDate(0, 0, 1)
+	rows, err := s.DB.Query(`
+		SELECT p.id, p.agent_id, COALESCE(p.dealer_id, 0), MAX(a.bet)
+		FROM (
+			SELECT player_id, 1 AS bet FROM transactions
+			WHERE transaction_type = 'bet' AND status = 'completed' AND created_at >= ? AND created_at < ?
+			UNION ALL
+			SELECT gp.player_id, 0 FROM game_participations gp
+			JOIN game_sessions gs ON gs.id = gp.session_id
+			WHERE gp.join_time >= ? AND gp.join_time < ? AND gs.session_type <> 'practice'
+		) a
+		JOIN players p ON p.id = a.player_id
+		WHERE p.agent_id IS NOT NULL`+scope+`
+		GROUP BY p.id, p.agent_id, p.dealer_id
+	`, append([]interface{}{day, next, day, next}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	active := map[int64]activePlayer{}
+	for rows.Next() {
+		var (
+			id int64
+			a  activePlayer
+		)
+		if err := rows.Scan(&id, &a.agentID, &a.dealerID, &a.bet); err != nil {
+			return nil, err
+		}
+		active[id] = a
+	}
+	return active, rows.Err()
+}
+
+// activePlayer 活躍玩家的歸屬與是否有下注
+type activePlayer struct {
+	performanceOwner
+	bet bool
+}
+
+// rollupDay 重建單日統計，回傳當日活躍玩家供下一日計算留存
+func (s *PerformanceService) rollupDay(day time.Time, agentID *int, prev map[int64]activePlayer) (map[int64]activePlayer, error) {
+	next := day.AddDate(0, 0, 1)
+	currency := s.FX.Wallet.Currency
+	agents := map[int]*PerformanceStats{}
+	dealers := map[int]*PerformanceStats{}
+	dealerAgents := map[int]int{}
+	stats := func(o performanceOwner) []*PerformanceStats {
+		var list []*PerformanceStats
+		if p := agents[o.agentID]; p != nil {
+			list = append(list, p)
+		}
+		if p := dealers[o.dealerID]; p != nil && o.dealerID > 0 {
+			list = append(list, p)
+		}
+		return list
+	}
+
+	// 當日已存在的代理商與經銷商
+	scope, args := performanceScope("id", agentID)
+	rows, err := s.DB.Query("SELECT id FROM agents WHERE created_at < ?"+scope, append([]interface{}{next}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		agents[id] = &PerformanceStats{}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	scope, args = performanceScope("agent_id", agentID)
+	rows, err = s.DB.Query("SELECT id, agent_id FROM dealers WHERE created_at < ?"+scope, append([]interface{}{next}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id, agent int
+		if err := rows.Scan(&id, &agent); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dealers[id], dealerAgents[id] = &PerformanceStats{}, agent
+		if p := agents[agent]; p != nil {
+			p.DealerCount++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 玩家數與新玩家
+	scope, args = performanceScope("agent_id", agentID)
+	rows, err = s.DB.Query(`
+		SELECT agent_id, COALESCE(dealer_id, 0), COUNT(*), COALESCE(SUM(created_at >= ?), 0)
+		FROM players
+		WHERE agent_id IS NOT NULL AND status <> 'deleted' AND created_at < ?`+scope+`
+		GROUP BY agent_id, dealer_id
+	`, append([]interface{}{day, next}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			o            performanceOwner
+			total, fresh int
+		)
+		if err := rows.Scan(&o.agentID, &o.dealerID, &total, &fresh); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for _, p := range stats(o) {
+			p.TotalPlayers += total
+			p.NewPlayers += fresh
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 交易金額（換算為統計幣別）
+	rates := map[string]*money.Rate{}
+	convert := func(amount money.Amount, from string) (money.Amount, error) {
+		r, ok := rates[from]
+		if !ok {
+			rate, err := s.FX.Rate(from, currency, next.Add(-time.Second))
+			if err != nil {
+				return 0, err
+			}
+			r = &rate.Rate
+			rates[from] = r
+		}
+		return r.Convert(amount), nil
+	}
+	scope, args = performanceScope("p.agent_id", agentID)
+	rows, err = s.DB.Query(`
+		SELECT p.agent_id, COALESCE(p.dealer_id, 0), t.currency, t.transaction_type, SUM(t.amount)
+		FROM transactions t
+		JOIN players p ON p.id = t.player_id
+		WHERE t.status = 'completed' AND t.created_at >= ? AND t.created_at < ?
+		  AND t.transaction_type IN ('deposit', 'withdrawal', 'bet', 'win', 'refund', 'bonus')
+		  AND p.agent_id IS NOT NULL`+scope+`
+		GROUP BY p.agent_id, p.dealer_id, t.currency, t.transaction_type
+	`, append([]interface{}{day, next}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			o                     performanceOwner
+			from, transactionType string
+			amount                money.Amount
+		)
+		if err := rows.Scan(&o.agentID, &o.dealerID, &from, &transactionType, &amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		converted, err := convert(amount, from)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for _, p := range stats(o) {
+			p.add(transactionType, converted)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 當日交易產生的佣金（代理商只計自己受益的佣金）
+	scope, args = performanceScope("c.agent_id", agentID)
+	rows, err = s.DB.Query(`
+		SELECT c.agent_id, COALESCE(c.dealer_id, 0), c.currency, SUM(c.commission_amount)
+		FROM commissions c
+		JOIN transactions t ON t.id = c.source_transaction_id
+		WHERE c.status <> 'cancelled' AND t.created_at >= ? AND t.created_at < ?`+scope+`
+		GROUP BY c.agent_id, c.dealer_id, c.currency
+	`, append([]interface{}{day, next}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			o      performanceOwner
+			from   string
+			amount money.Amount
+		)
+		if err := rows.Scan(&o.agentID, &o.dealerID, &from, &amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		converted, err := convert(amount, from)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if o.dealerID > 0 {
+			if p := dealers[o.dealerID]; p != nil {
+				p.CommissionEarned += converted
+			}
+		} else if p := agents[o.agentID]; p != nil {
+			p.CommissionEarned += converted
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 活躍玩家與留存
+	active, err := s.activePlayers(day, agentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range active {
+		for _, p := range stats(a.performanceOwner) {
+			p.ActivePlayers++
+			if a.bet {
+				p.BettingPlayers++
+			}
+		}
+	}
+	for id, a := range prev {
+		_, retained := active[id]
+		for _, p := range stats(a.performanceOwner) {
+			p.prevActive++
+			if retained {
+				p.retained++
+			}
+		}
+	}
+
+	err = withAgentTx(s.DB, func(tx *sql.Tx) error {
+		date := day.Format("2006-01-02")
+		scope, args := performanceScope("agent_id", agentID)
+		if _, err := tx.Exec("DELETE FROM agent_performance_stats WHERE stat_date = ?"+scope,
+			append([]interface{}{date}, args...)...); err != nil {
+			return err
+		}
+		dealerScope := ""
+		if agentID != nil {
+			// 經銷商可能已轉移至其他代理商，一併刪除目前所屬經銷商的舊統計
+			dealerScope = " AND (agent_id = ? OR dealer_id IN (SELECT id FROM dealers WHERE agent_id = ?))"
+			args = []interface{}{*agentID, *agentID}
+		}
+		if _, err := tx.Exec("DELETE FROM dealer_performance_stats WHERE stat_date = ?"+dealerScope,
+			append([]interface{}{date}, args...)...); err != nil {
+			return err
+		}
+
+		var values []string
+		var params []interface{}
+		for id, p := range agents {
+			p.finish()
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			params = append(params, id, date, currency, p.TotalPlayers, p.ActivePlayers, p.NewPlayers, p.BettingPlayers,
+				p.TotalDeposits, p.TotalWithdrawals, p.TotalBets, p.TotalWins, p.TotalRefunds, p.TotalBonuses,
+				p.GrossRevenue, p.NetRevenue, p.CommissionEarned, p.DealerCount, p.AvgBetPerPlayer, p.RetentionRate)
+		}
+		if len(values) > 0 {
+			if _, err := tx.Exec(`
+				INSERT INTO agent_performance_stats
+					(agent_id, stat_date, currency, total_players, active_players, new_players, betting_players,
+					 total_deposits, total_withdrawals, total_bets, total_wins, total_refunds, total_bonuses,
+					 gross_revenue, net_revenue, commission_earned, dealer_count, avg_bet_per_player, player_retention_rate)
+				VALUES `+strings.Join(values, ", "), params...); err != nil {
+				return err
+			}
+		}
+
+		values, params = nil, nil
+		for id, p := range dealers {
+			p.finish()
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			params = append(params, id, dealerAgents[id], date, currency, p.TotalPlayers, p.ActivePlayers, p.NewPlayers,
+				p.BettingPlayers, p.TotalDeposits, p.TotalWithdrawals, p.TotalBets, p.TotalWins, p.TotalRefunds,
+				p.TotalBonuses, p.GrossRevenue, p.NetRevenue, p.CommissionEarned, p.AvgBetPerPlayer, p.RetentionRate)
+		}
+		if len(values) > 0 {
+			if _, err := tx.Exec(`
+				INSERT INTO dealer_performance_stats
+					(dealer_id, agent_id, stat_date, currency, total_players, active_players, new_players, betting_players,
+					 total_deposits, total_withdrawals, total_bets, total_wins, total_refunds, total_bonuses,
+					 gross_revenue, net_revenue, commission_earned, avg_bet_per_player, player_retention_rate)
+				VALUES `+strings.Join(values, ", "), params...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+// Report 產生 [start, end] 期間的業績報表，並與前一個等長期間比較
+func (s *PerformanceService) Report(f PerformanceReportFilter) (*PerformanceReport, error) {
+	start, end := startOfDay(f.Start), startOfDay(f.End)
+	if end.Before(start) {
+		return nil, ErrPerformanceRange
+	}
+	if f.RankBy == "" {
+		f.RankBy = "net_revenue"
+	}
+	value, ok := performanceRankBy[f.RankBy]
+	if !ok {
+		return nil, ErrPerformanceRankBy
+	}
+	days := int(end.Sub(start).Hours()/24+0.5) + 1
+	prevEnd := start.AddDate(0, 0, -1)
+	prevStart := prevEnd.AddDate(0, 0, -(days - 1))
+	report := &PerformanceReport{
+		StartDate:     start.Format("2006-01-02"),
+		EndDate:       end.Format("2006-01-02"),
+		PreviousStart: prevStart.Format("2006-01-02"),
+		PreviousEnd:   prevEnd.Format("2006-01-02"),
+		Currency:      s.FX.Wallet.Currency,
+		RankBy:        f.RankBy,
+		Daily:         []PerformanceDay{},
+		MissingDays:   []string{},
+	}
+
+	current, err := s.periodTotals(NodeAgent, start, end, nil)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.periodTotals(NodeAgent, prevStart, prevEnd, nil)
+	if err != nil {
+		return nil, err
+	}
+	agents := rankPerformance(NodeAgent, current, previous, value)
+	report.Rankings = agents
+	if f.Limit > 0 && len(report.Rankings) > f.Limit {
+		report.Rankings = report.Rankings[:f.Limit]
+	}
+
+	if f.AgentID != nil {
+		if err := commissionTargetExists(s.DB, NodeAgent, *f.AgentID); err != nil {
+			return nil, err
+		}
+		for i := range agents {
+			if agents[i].ID == *f.AgentID {
+				report.Agent = &agents[i]
+			}
+		}
+		if report.Agent == nil {
+			report.Agent = &PerformanceEntry{TargetType: NodeAgent, ID: *f.AgentID, Changes: map[string]*float64{}}
+		}
+		report.Totals = PerformanceComparison{
+			Current: report.Agent.Current, Previous: report.Agent.Previous, Changes: report.Agent.Changes,
+		}
+		dealersCurrent, err := s.periodTotals(NodeDealer, start, end, f.AgentID)
+		if err != nil {
+			return nil, err
+		}
+		dealersPrevious, err := s.periodTotals(NodeDealer, prevStart, prevEnd, f.AgentID)
+		if err != nil {
+			return nil, err
+		}
+		report.Dealers = rankPerformance(NodeDealer, dealersCurrent, dealersPrevious, value)
+	} else {
+		var cur, prev PerformanceTotals
+		for _, e := range agents {
+			sumPerformance(&cur, e.Current)
+			sumPerformance(&prev, e.Previous)
+		}
+		report.Totals = PerformanceComparison{Current: cur, Previous: prev, Changes: performanceDelta(cur, prev)}
+	}
+
+	if report.Daily, err = s.daily(start, end, f.AgentID); err != nil {
+		return nil, err
+	}
+	have := map[string]bool{}
+	for _, d := range report.Daily {
+		have[d.Date] = true
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if date := day.Format("2006-01-02"); !have[date] {
+			report.MissingDays = append(report.MissingDays, date)
+		}
+	}
+	return report, nil
+}
+
+// performanceEntry 期間彙總的中間結果
+type performanceEntry struct {
+	code, name string
+	totals     PerformanceTotals
+}
+
+// periodTotals 各代理商（或指定代理商的各經銷商）在期間內的彙總
+func (s *PerformanceService) periodTotals(targetType string, start, end time.Time, agentID *int) (map[int]*performanceEntry, error) {
+	query := `
+		SELECT s.agent_id, a.agent_code, a.agent_name,`
+	from := `
+		FROM agent_performance_stats s JOIN agents a ON a.id = s.agent_id
+		WHERE s.stat_date >= ? AND s.stat_date <= ?
+		GROUP BY s.agent_id, a.agent_code, a.agent_name`
+	last := end.Format("2006-01-02")
+	dealerCount := "MAX(CASE WHEN s.stat_date = ? THEN s.dealer_count END)"
+	args := []interface{}{last, last, start.Format("2006-01-02"), last}
+	if targetType == NodeDealer {
+		query = `
+		SELECT s.dealer_id, d.dealer_code, d.dealer_name,`
+		from = `
+		FROM dealer_performance_stats s JOIN dealers d ON d.id = s.dealer_id
+		WHERE s.stat_date >= ? AND s.stat_date <= ? AND d.agent_id = ?
+		GROUP BY s.dealer_id, d.dealer_code, d.dealer_name`
+		dealerCount = "0"
+		args = []interface{}{last, start.Format("2006-01-02"), last, *agentID}
+	}
+	rows, err := s.DB.Query(query+`
+		       COALESCE(MAX(CASE WHEN s.stat_date = ? THEN s.total_players END), 0),
+		       COALESCE(`+dealerCount+`, 0),
+		       SUM(s.active_players), SUM(s.new_players), SUM(s.betting_players),
+		       SUM(s.total_deposits), SUM(s.total_withdrawals), SUM(s.total_bets), SUM(s.total_wins),
+		       SUM(s.total_refunds), SUM(s.total_bonuses), SUM(s.gross_revenue), SUM(s.net_revenue),
+		       SUM(s.commission_earned), AVG(s.player_retention_rate), COUNT(*)`+from, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := map[int]*performanceEntry{}
+	for rows.Next() {
+		var (
+			id        int
+			e         performanceEntry
+			retention float64
+		)
+		t := &e.totals
+		if err := rows.Scan(&id, &e.code, &e.name, &t.TotalPlayers, &t.DealerCount,
+			&t.ActivePlayerDays, &t.NewPlayers, &t.BettingPlayerDays,
+			&t.TotalDeposits, &t.TotalWithdrawals, &t.TotalBets, &t.TotalWins,
+			&t.TotalRefunds, &t.TotalBonuses, &t.GrossRevenue, &t.NetRevenue,
+			&t.CommissionEarned, &retention, &t.Days); err != nil {
+			return nil, err
+		}
+		t.RetentionRate = math.Round(retention*100) / 100
+		finishTotals(t)
+		result[id] = &e
+	}
+	return result, rows.Err()
+}
+
+// daily 每日業績（指定代理商時為該代理商，否則為所有代理商合計）
+func (s *PerformanceService) daily(start, end time.Time, agentID *int) ([]PerformanceDay, error) {
+	scope, args := performanceScope("agent_id", agentID)
+	rows, err := s.DB.Query(`
+		SELECT stat_date, SUM(total_players), SUM(active_players), SUM(new_players), SUM(betting_players),
+		       SUM(total_deposits), SUM(total_withdrawals), SUM(total_bets), SUM(total_wins), SUM(total_refunds),
+		       SUM(total_bonuses), SUM(gross_revenue), SUM(net_revenue), SUM(commission_earned), SUM(dealer_count),
+		       AVG(player_retention_rate)
+		FROM agent_performance_stats
+		WHERE stat_date >= ? AND stat_date <= ?`+scope+`
+		GROUP BY stat_date ORDER BY stat_date
+	`, append([]interface{}{start.Format("2006-01-02"), end.Format("2006-01-02")}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []PerformanceDay{}
+	for rows.Next() {
+		var (
+			d         PerformanceDay
+			date      time.Time
+			retention float64
+		)
+		p := &d.PerformanceStats
+		if err := rows.Scan(&date, &p.TotalPlayers, &p.ActivePlayers, &p.NewPlayers, &p.BettingPlayers,
+			&p.TotalDeposits, &p.TotalWithdrawals, &p.TotalBets, &p.TotalWins, &p.TotalRefunds,
+			&p.TotalBonuses, &p.GrossRevenue, &p.NetRevenue, &p.CommissionEarned, &p.DealerCount,
+			&retention); err != nil {
+			return nil, err
+		}
+		d.Date = date.Format("2006-01-02")
+		if p.BettingPlayers > 0 {
+			p.AvgBetPerPlayer = p.TotalBets / money.Amount(p.BettingPlayers)
+		}
+		p.RetentionRate = math.Round(retention*100) / 100
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// rankPerformance 依指標排名（同值同名次），並計算對前期的變化
+func rankPerformance(targetType string, current, previous map[int]*performanceEntry, value func(PerformanceTotals) float64) []PerformanceEntry {
+	list := []PerformanceEntry{}
+	for id, e := range current {
+		entry := PerformanceEntry{TargetType: targetType, ID: id, Code: e.code, Name: e.name, Current: e.totals}
+		if p := previous[id]; p != nil {
+			entry.Previous = p.totals
+		}
+		entry.Changes = performanceDelta(entry.Current, entry.Previous)
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		vi, vj := value(list[i].Current), value(list[j].Current)
+		if vi != vj {
+			return vi > vj
+		}
+		return list[i].ID < list[j].ID
+	})
+	for i := range list {
+		list[i].Rank = i + 1
+		if i > 0 && value(list[i].Current) == value(list[i-1].Current) {
+			list[i].Rank = list[i-1].Rank
+		}
+	}
+	return list
+}
+
+// performanceDelta 對前期的變化百分比
+func performanceDelta(cur, prev PerformanceTotals) map[string]*float64 {
+	changes := map[string]*float64{}
+	for _, key := range performanceChanges {
+		c, p := performanceRankBy[key](cur), performanceRankBy[key](prev)
+		if p == 0 {
+			changes[key] = nil
+			continue
+		}
+		v := math.Round((c-p)/math.Abs(p)*10000) / 100
+		changes[key] = &v
+	}
+	return changes
+}
+
+// sumPerformance 累加期間業績（留存率以活躍人日加權）
+func sumPerformance(dst *PerformanceTotals, src PerformanceTotals) {
+	retention := dst.RetentionRate*float64(dst.ActivePlayerDays) + src.RetentionRate*float64(src.ActivePlayerDays)
+	dst.TotalPlayers += src.TotalPlayers
+	dst.ActivePlayerDays += src.ActivePlayerDays
+	dst.NewPlayers += src.NewPlayers
+	dst.BettingPlayerDays += src.BettingPlayerDays
+	dst.TotalDeposits += src.TotalDeposits
+	dst.TotalWithdrawals += src.TotalWithdrawals
+	dst.TotalBets += src.TotalBets
+	dst.TotalWins += src.TotalWins
+	dst.TotalRefunds += src.TotalRefunds
+	dst.TotalBonuses += src.TotalBonuses
+	dst.GrossRevenue += src.GrossRevenue
+	dst.NetRevenue += src.NetRevenue
+	dst.CommissionEarned += src.CommissionEarned
+	dst.DealerCount += src.DealerCount
+	if src.Days > dst.Days {
+		dst.Days = src.Days
+	}
+	dst.RetentionRate = 0
+	if dst.ActivePlayerDays > 0 {
+		dst.RetentionRate = math.Round(retention/float64(dst.ActivePlayerDays)*100) / 100
+	}
+	finishTotals(dst)
+}
+
+// finishTotals 計算期間的平均值
+func finishTotals(t *PerformanceTotals) {
+	t.AvgDailyActive, t.AvgBetPerPlayer = 0, 0
+	if t.Days > 0 {
+		t.AvgDailyActive = math.Round(float64(t.ActivePlayerDays)/float64(t.Days)*100) / 100
+	}
+	if t.BettingPlayerDays > 0 {
+		t.AvgBetPerPlayer = t.TotalBets / money.Amount(t.BettingPlayerDays)
+	}
+}
+
+// performanceScope 指定代理商時的查詢條件
+func performanceScope(column string, agentID *int) (string, []interface{}) {
+	if agentID == nil {
+		return "", nil
+	}
+	return " AND " + column + " = ?", []interface{}{*agentID}
+}
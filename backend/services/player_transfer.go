@@ -0,0 +1,356 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 玩家移轉相關錯誤
+var (
+	ErrTransferPlayers        = errors.New("請指定 1 至 500 位玩家")
+	ErrTransferTarget         = errors.New("請指定移轉目標代理商或經銷商其中之一")
+	ErrTransferPlayerNotFound = errors.New("玩家不存在或已刪除")
+	ErrTransferEffective      = errors.New("生效時間不可晚於現在，也不可早於玩家目前歸屬的生效時間")
+	ErrTransferLocked         = errors.New("生效時間之後已有確認或納入結算的佣金，無法回溯移轉")
+)
+
+// maxTransferPlayers 單次移轉的玩家數上限
+const maxTransferPlayers = 500
+
+// 移轉來源
+const (
+	TransferManual            = "manual"
+	TransferAgentTermination  = "agent_termination"
+	TransferDealerTermination = "dealer_termination"
+	TransferDealerMove        = "dealer_move"
+)
+
+// PlayerTransferRequest 玩家移轉請求（目標為經銷商時所屬代理商一併變更；未指定生效時間為立即生效）
+type PlayerTransferRequest struct {
+	PlayerIDs   []int64
+	AgentID     *int
+	DealerID    *int
+	EffectiveAt *time.Time
+	Reason      string
+}
+
+// PlayerTransferResult 玩家移轉結果
+type PlayerTransferResult struct {
+	BatchID     string    `json:"batch_id"`
+	EffectiveAt time.Time `json:"effective_at"`
+	Moved       int64     `json:"moved"`
+	Unchanged   []int64   `json:"unchanged"` // 已屬於目標而未移轉的玩家
+}
+
+// PlayerTransfer 玩家移轉記錄
+type PlayerTransfer struct {
+	ID           int64     `json:"id"`
+	BatchID      string    `json:"batch_id"`
+	PlayerID     int64     `json:"player_id"`
+	Username     string    `json:"username"`
+	FromAgentID  *int      `json:"from_agent_id"`
+	FromDealerID *int      `json:"from_dealer_id"`
+	ToAgentID    *int      `json:"to_agent_id"`
+	ToDealerID   *int      `json:"to_dealer_id"`
+	EffectiveAt  time.Time `json:"effective_at"`
+	Source       string    `json:"source"`
+	Reason       string    `json:"reason"`
+	CreatedBy    *int      `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PlayerTransferFilter 移轉記錄查詢條件（代理商、經銷商為移出或移入任一方）
+type PlayerTransferFilter struct {
+	PlayerID int64
+	AgentID  int
+	DealerID int
+	BatchID  string
+	Page     int
+	Limit    int
+}
+
+// playerMove 一批玩家移轉的目標與記錄資訊
+type playerMove struct {
+	agentID     *int
+	dealerID    *int
+	effectiveAt time.Time
+	batchID     string
+	source      string
+	reason      string
+	operatorID  *int
+}
+
+// newPlayerMove 建立立即生效的移轉批次
+func newPlayerMove(source, reason string, operatorID *int) playerMove {
+	return playerMove{
+		effectiveAt: time.Now().Truncate(time.Second),
+		batchID:     newTransferBatchID(),
+		source:      source,
+		reason:      reason,
+		operatorID:  operatorID,
+	}
+}
+
+// to 指定移轉目標
+func (m playerMove) to(agentID, dealerID *int) playerMove {
+	m.agentID, m.dealerID = agentID, dealerID
+	return m
+}
+
+// TransferPlayers 將玩家移至代理商直屬或經銷商，生效時間前的佣金歸原歸屬、之後的歸新歸屬；
+// 回溯生效時，生效時間之後不可已有確認或納入結算的佣金
+func (s *AgentService) TransferPlayers(req PlayerTransferRequest, operatorID *int) (*PlayerTransferResult, error) {
+	ids := uniquePlayerIDs(req.PlayerIDs)
+	if len(ids) == 0 || len(ids) > maxTransferPlayers {
+		return nil, ErrTransferPlayers
+	}
+	if (req.AgentID == nil) == (req.DealerID == nil) {
+		return nil, ErrTransferTarget
+	}
+	m := newPlayerMove(TransferManual, req.Reason, operatorID)
+	if req.EffectiveAt != nil {
+		if req.EffectiveAt.After(time.Now()) {
+			return nil, ErrTransferEffective
+		}
+		m.effectiveAt = req.EffectiveAt.Truncate(time.Second)
+	}
+
+	result := &PlayerTransferResult{BatchID: m.batchID, EffectiveAt: m.effectiveAt, Unchanged: []int64{}}
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		var maxPlayers, current int
+		if req.DealerID != nil {
+			d, err := loadDealer(tx, "d.id = ? FOR UPDATE OF d", *req.DealerID)
+			if err != nil {
+				return err
+			}
+			if err := d.operable(); err != nil {
+				return err
+			}
+			agent, err := loadAgent(tx, "a.id = ?", d.AgentID)
+			if err != nil {
+				return err
+			}
+			if err := agent.operable(); err != nil {
+				return err
+			}
+			agentID := d.AgentID
+			m = m.to(&agentID, req.DealerID)
+			maxPlayers = d.MaxPlayers
+			if current, err = countPlayers(tx, "dealer_id = ?", d.ID); err != nil {
+				return err
+			}
+		} else {
+			agent, err := loadAgent(tx, "a.id = ? FOR UPDATE OF a", *req.AgentID)
+			if err != nil {
+				return err
+			}
+			if err := agent.operable(); err != nil {
+				return err
+			}
+			m = m.to(req.AgentID, nil)
+		}
+
+		in, args := int64Placeholders(ids)
+		rows, err := tx.Query("SELECT id, agent_id, dealer_id FROM players WHERE id IN ("+in+") AND status <> 'deleted' FOR UPDATE", args...)
+		if err != nil {
+			return err
+		}
+		found := map[int64]bool{}
+		var moving []interface{}
+		for rows.Next() {
+			var (
+				id            int64
+				agent, dealer sql.NullInt64
+			)
+			if err := rows.Scan(&id, &agent, &dealer); err != nil {
+				rows.Close()
+				return err
+			}
+			found[id] = true
+			if sameOwner(agent, m.agentID) && sameOwner(dealer, m.dealerID) {
+				result.Unchanged = append(result.Unchanged, id)
+				continue
+			}
+			moving = append(moving, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		var missing []string
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, fmt.Sprint(id))
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%w: %s", ErrTransferPlayerNotFound, strings.Join(missing, ", "))
+		}
+		if len(moving) == 0 {
+			return nil
+		}
+		if maxPlayers > 0 && current+len(moving) > maxPlayers {
+			return ErrPlayerLimit
+		}
+
+		in = strings.TrimSuffix(strings.Repeat("?, ", len(moving)), ", ")
+		if req.EffectiveAt != nil {
+			var n int
+			if err := tx.QueryRow(`
+				SELECT COUNT(*) FROM player_attributions
+				WHERE player_id IN (`+in+`) AND effective_to IS NULL AND effective_from > ?
+			`, append(moving, m.effectiveAt)...).Scan(&n); err != nil {
+				return err
+			}
+			if n > 0 {
+				return ErrTransferEffective
+			}
+			if err := tx.QueryRow(`
+				SELECT COUNT(*) FROM commissions c
+				JOIN transactions t ON t.id = c.source_transaction_id
+				WHERE c.player_id IN (`+in+`) AND t.created_at >= ?
+				  AND (c.status <> 'pending' OR c.settlement_id IS NOT NULL)
+			`, append(moving, m.effectiveAt)...).Scan(&n); err != nil {
+				return err
+			}
+			if n > 0 {
+				return ErrTransferLocked
+			}
+		}
+		result.Moved, err = transferPlayersTx(tx, "id IN ("+in+")", moving, m)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PlayerTransfers 依條件列出移轉記錄（由新到舊），回傳資料與總筆數
+func (s *AgentService) PlayerTransfers(f PlayerTransferFilter) ([]PlayerTransfer, int64, error) {
+	where := " WHERE 1=1"
+	var args []interface{}
+	if f.PlayerID > 0 {
+		where += " AND pt.player_id = ?"
+		args = append(args, f.PlayerID)
+	}
+	if f.AgentID > 0 {
+		where += " AND (pt.from_agent_id = ? OR pt.to_agent_id = ?)"
+		args = append(args, f.AgentID, f.AgentID)
+	}
+	if f.DealerID > 0 {
+		where += " AND (pt.from_dealer_id = ? OR pt.to_dealer_id = ?)"
+		args = append(args, f.DealerID, f.DealerID)
+	}
+	if f.BatchID != "" {
+		where += " AND pt.batch_id = ?"
+		args = append(args, f.BatchID)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM player_transfers pt"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(`
+		SELECT pt.id, pt.batch_id, pt.player_id, p.username, pt.from_agent_id, pt.from_dealer_id, pt.to_agent_id,
+		       pt.to_dealer_id, pt.effective_at, pt.source, pt.reason, pt.created_by, pt.created_at
+		FROM player_transfers pt
+		JOIN players p ON p.id = pt.player_id`+where+`
+		ORDER BY pt.id DESC LIMIT ? OFFSET ?
+	`, append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []PlayerTransfer{}
+	for rows.Next() {
+		var (
+			t                                        PlayerTransfer
+			fromAgent, fromDealer, toAgent, toDealer sql.NullInt64
+			createdBy                                sql.NullInt64
+		)
+		if err := rows.Scan(&t.ID, &t.BatchID, &t.PlayerID, &t.Username, &fromAgent, &fromDealer, &toAgent,
+			&toDealer, &t.EffectiveAt, &t.Source, &t.Reason, &createdBy, &t.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		t.FromAgentID, t.FromDealerID = nullInt(fromAgent), nullInt(fromDealer)
+		t.ToAgentID, t.ToDealerID = nullInt(toAgent), nullInt(toDealer)
+		t.CreatedBy = nullInt(createdBy)
+		list = append(list, t)
+	}
+	return list, total, rows.Err()
+}
+
+// transferPlayersTx 將符合條件（players 表的欄位）且歸屬不同的玩家移至新歸屬：
+// 記錄移轉、結束目前歸屬並建立新歸屬，回傳移轉的玩家數
+func transferPlayersTx(tx *sql.Tx, where string, args []interface{}, m playerMove) (int64, error) {
+	where += " AND NOT (agent_id <=> ? AND dealer_id <=> ?)"
+	args = append(append([]interface{}{}, args...), m.agentID, m.dealerID)
+	if _, err := tx.Exec(`
+		INSERT INTO player_transfers
+			(batch_id, player_id, from_agent_id, from_dealer_id, to_agent_id, to_dealer_id, effective_at, source, reason, created_by)
+		SELECT ?, id, agent_id, dealer_id, ?, ?, ?, ?, ?, ?
+		FROM players WHERE `+where,
+		append([]interface{}{m.batchID, m.agentID, m.dealerID, m.effectiveAt, m.source, m.reason, m.operatorID}, args...)...); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE player_attributions SET effective_to = ?
+		WHERE effective_to IS NULL AND player_id IN (SELECT id FROM players WHERE `+where+`)
+	`, append([]interface{}{m.effectiveAt}, args...)...); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO player_attributions (player_id, agent_id, dealer_id, effective_from, batch_id)
+		SELECT id, ?, ?, ?, ? FROM players WHERE `+where,
+		append([]interface{}{m.agentID, m.dealerID, m.effectiveAt, m.batchID}, args...)...); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec("UPDATE players SET agent_id = ?, dealer_id = ? WHERE "+where,
+		append([]interface{}{m.agentID, m.dealerID}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// sameOwner 玩家目前的歸屬欄位是否與目標相同
+func sameOwner(current sql.NullInt64, target *int) bool {
+	if target == nil {
+		return !current.Valid
+	}
+	return current.Valid && current.Int64 == int64(*target)
+}
+
+// uniquePlayerIDs 去除重複與無效的玩家ID（保留順序）
+func uniquePlayerIDs(ids []int64) []int64 {
+	seen := map[int64]bool{}
+	var list []int64
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			list = append(list, id)
+		}
+	}
+	return list
+}
+
+// int64Placeholders IN 條件的佔位符與參數
+func int64Placeholders(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// newTransferBatchID 產生移轉批次編號
+func newTransferBatchID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return "PT" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
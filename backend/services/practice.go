@@ -0,0 +1,205 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/table"
+)
+
+// PracticeCurrency 練習場遊戲幣的幣別代碼（僅用於牌局歷史顯示）
+const PracticeCurrency = "PLAY"
+
+// 練習場遊戲幣預設額度
+const (
+	defaultPracticeBalance     = 10000
+	defaultPracticeReplenishAt = 100
+)
+
+// PracticeWallet 練習遊戲幣錢包
+type PracticeWallet struct {
+	PlayerID          int64      `json:"player_id"`
+	Currency          string     `json:"currency"`
+	Balance           float64    `json:"balance"`
+	ReplenishCount    int        `json:"replenish_count"`
+	LastReplenishedAt *time.Time `json:"last_replenished_at,omitempty"`
+	Replenished       bool       `json:"replenished"` // 本次操作是否觸發自動補充
+}
+
+// PracticeService 練習遊戲幣服務（獨立於 player_wallets 與 transactions）
+//
+// 餘額低於補充門檻、或不足以支付帶入籌碼時，自動補足至起始額度（帶入金額較大時補足至帶入金額）。
+type PracticeService struct {
+	DB              *sql.DB
+	StartingBalance float64 // 起始與補充額度
+	ReplenishAt     float64 // 自動補充門檻
+}
+
+// NewPracticeService 建立新的練習遊戲幣服務
+func NewPracticeService() *PracticeService {
+	s := &PracticeService{
+		DB:              config.GetDB(),
+		StartingBalance: defaultPracticeBalance,
+		ReplenishAt:     defaultPracticeReplenishAt,
+	}
+	if config.AppConfig != nil {
+		if config.AppConfig.Game.PracticeBalance > 0 {
+			s.StartingBalance = config.AppConfig.Game.PracticeBalance
+		}
+		if config.AppConfig.Game.PracticeReplenishAt >= 0 {
+			s.ReplenishAt = config.AppConfig.Game.PracticeReplenishAt
+		}
+	}
+	return s
+}
+
+// Get 取得玩家的練習遊戲幣錢包（不存在時建立；低於門檻時自動補充）
+func (s *PracticeService) Get(playerID int64) (*PracticeWallet, error) {
+	return s.withTx(playerID, func(tx *sql.Tx, w *PracticeWallet) error {
+		return s.replenish(tx, w, 0)
+	})
+}
+
+// Debit 扣除遊戲幣（不足時先自動補充）
+func (s *PracticeService) Debit(playerID int64, amount float64) (*PracticeWallet, error) {
+	amount = math.Round(amount*100) / 100
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return s.withTx(playerID, func(tx *sql.Tx, w *PracticeWallet) error {
+		if err := s.replenish(tx, w, amount); err != nil {
+			return err
+		}
+		w.Balance = math.Round((w.Balance-amount)*100) / 100
+		_, err := tx.Exec("UPDATE practice_wallets SET balance = ? WHERE player_id = ?", w.Balance, playerID)
+		return err
+	})
+}
+
+// Credit 存入遊戲幣
+func (s *PracticeService) Credit(playerID int64, amount float64) (*PracticeWallet, error) {
+	amount = math.Round(amount*100) / 100
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return s.withTx(playerID, func(tx *sql.Tx, w *PracticeWallet) error {
+		w.Balance = math.Round((w.Balance+amount)*100) / 100
+		_, err := tx.Exec("UPDATE practice_wallets SET balance = ? WHERE player_id = ?", w.Balance, playerID)
+		return err
+	})
+}
+
+// replenish 餘額低於門檻或不足 need 時補足（補充後餘額至少為 need）
+func (s *PracticeService) replenish(tx *sql.Tx, w *PracticeWallet, need float64) error {
+	if w.Balance >= s.ReplenishAt && w.Balance >= need {
+		return nil
+	}
+	target := math.Max(s.StartingBalance, need)
+	if w.Balance >= target {
+		return nil
+	}
+	now := time.Now()
+	if _, err := tx.Exec(`
+		UPDATE practice_wallets
+		SET balance = ?, replenish_count = replenish_count + 1, last_replenished_at = ?
+		WHERE player_id = ?
+	`, target, now, w.PlayerID); err != nil {
+		return fmt.Errorf("補充遊戲幣失敗: %v", err)
+	}
+	w.Balance = target
+	w.ReplenishCount++
+	w.LastReplenishedAt = &now
+	w.Replenished = true
+	return nil
+}
+
+// withTx 鎖定（必要時建立）玩家的練習錢包後執行 fn
+func (s *PracticeService) withTx(playerID int64, fn func(*sql.Tx, *PracticeWallet) error) (*PracticeWallet, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 首次使用時以起始額度建立錢包
+	if _, err := tx.Exec(`
+		INSERT IGNORE INTO practice_wallets (player_id, balance, last_replenished_at) VALUES (?, ?, NOW())
+	`, playerID, s.StartingBalance); err != nil {
+		return nil, fmt.Errorf("建立練習錢包失敗: %v", err)
+	}
+	w := &PracticeWallet{PlayerID: playerID, Currency: PracticeCurrency}
+	var last sql.NullTime
+	if err := tx.QueryRow(`
+		SELECT balance, replenish_count, last_replenished_at FROM practice_wallets
+		WHERE player_id = ?
+		FOR UPDATE
+	`, playerID).Scan(&w.Balance, &w.ReplenishCount, &last); err != nil {
+		return nil, err
+	}
+	if last.Valid {
+		w.LastReplenishedAt = &last.Time
+	}
+
+	if err := fn(tx, w); err != nil {
+		return nil, err
+	}
+	return w, tx.Commit()
+}
+
+// PracticeStore 練習場牌桌的資料存取（實作 table.Store）
+//
+// 房間設定、場次、公平性與牌局歷史沿用 TableStore；入座與離座只異動練習遊戲幣，
+// 不寫入 player_wallets、transactions，也不更新房間人數。
+type PracticeStore struct {
+	*TableStore
+	Practice *PracticeService
+}
+
+// NewPracticeStore 建立新的練習場牌桌資料存取
+func NewPracticeStore() *PracticeStore {
+	return &PracticeStore{
+		TableStore: NewTableStore(),
+		Practice:   NewPracticeService(),
+	}
+}
+
+// LoadRoom 讀取房間設定並標記為練習場（不抽水）
+func (s *PracticeStore) LoadRoom(roomID int64) (*table.RoomInfo, error) {
+	room, err := s.TableStore.LoadRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+	room.Practice = true
+	room.Currency = PracticeCurrency
+	room.Rules.RakeRate = 0
+	return room, nil
+}
+
+// BuyIn 入座時扣除遊戲幣
+func (s *PracticeStore) BuyIn(room *table.RoomInfo, playerID int64, amount float64) error {
+	if err := s.checkPlayer(playerID); err != nil {
+		return err
+	}
+	_, err := s.Practice.Debit(playerID, amount)
+	return err
+}
+
+// CashOut 離座時將剩餘籌碼轉回遊戲幣
+func (s *PracticeStore) CashOut(room *table.RoomInfo, playerID int64, amount float64) error {
+	if amount <= 0 {
+		return nil
+	}
+	_, err := s.Practice.Credit(playerID, amount)
+	return err
+}
+
+// FinishHand 寫入牌局結果；牌局歷史以遊戲幣幣別記錄
+func (s *PracticeStore) FinishHand(record *table.HandRecord) (string, error) {
+	if record.History != nil {
+		record.History.Currency = PracticeCurrency
+	}
+	return s.TableStore.FinishHand(record)
+}
@@ -0,0 +1,625 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 對帳相關錯誤
+var (
+	ErrPeriodNotEnded = errors.New("期間尚未結束，無法結帳")
+)
+
+// 對帳結果（financial_reports.reconciliation_status）
+const (
+	ReconciliationBalanced   = "balanced"
+	ReconciliationMismatched = "mismatched"
+)
+
+// 差異檢查項目
+const (
+	CheckBalanceMovement   = "balance_movement"   // 交易的餘額變動與金額不符
+	CheckMissingJournal    = "missing_journal"    // 交易沒有對應傳票
+	CheckJournalAmount     = "journal_amount"     // 傳票的玩家分錄與餘額變動不符
+	CheckGatewayDeposit    = "gateway_deposit"    // 閘道儲值訂單與入帳交易不符
+	CheckGatewayWithdrawal = "gateway_withdrawal" // 閘道出款與提領交易不符
+)
+
+// defaultMaxMismatches 每份報表保存的差異明細上限
+const defaultMaxMismatches = 500
+
+// ReconciliationMismatch 對帳差異（可追查至交易或訂單）
+type ReconciliationMismatch struct {
+	Check         string       `json:"check"`
+	TransactionID string       `json:"transaction_id,omitempty"`
+	ReferenceID   string       `json:"reference_id,omitempty"`
+	Expected      money.Amount `json:"expected"`
+	Actual        money.Amount `json:"actual"`
+	Detail        string       `json:"detail"`
+}
+
+// WalletMovement 錢包異動比對：交易的餘額變動合計與總帳玩家帳戶分錄合計
+type WalletMovement struct {
+	TransactionNet money.Amount `json:"transaction_net"`
+	LedgerNet      money.Amount `json:"ledger_net"`
+	Difference     money.Amount `json:"difference"`
+}
+
+// GatewaySummary 支付閘道紀錄彙總
+type GatewaySummary struct {
+	DepositsCompleted    int          `json:"deposits_completed"`
+	DepositsAmount       money.Amount `json:"deposits_amount"`
+	WithdrawalsCompleted int          `json:"withdrawals_completed"`
+	WithdrawalsAmount    money.Amount `json:"withdrawals_amount"`
+}
+
+// ReconciliationDetails 報表快照的對帳明細（存於 financial_reports.details）
+type ReconciliationDetails struct {
+	Wallet     WalletMovement           `json:"wallet"`
+	Gateway    GatewaySummary           `json:"gateway"`
+	Mismatches []ReconciliationMismatch `json:"mismatches"`
+	Truncated  bool                     `json:"truncated"` // 差異超過保存上限
+	Days       int                      `json:"days,omitempty"`
+}
+
+// ReconciliationReport 對帳報表（日報或月報，單一幣別）
+type ReconciliationReport struct {
+	ReportDate    string        `json:"report_date"`
+	ReportType    string        `json:"report_type"`
+	Currency      string        `json:"currency"`
+	Status        string        `json:"status"`
+	Totals        RevenueTotals `json:"totals"`
+	ActivePlayers int           `json:"active_players"`
+	NewPlayers    int           `json:"new_players"` // 只計入預設幣別的報表
+	MismatchCount int           `json:"mismatch_count"`
+	ReconciliationDetails
+	Snapshot  bool       `json:"snapshot"` // 是否為已寫入的快照（未結束的期間為試算）
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ReconciliationService 對帳服務
+//
+// 已結束的日期結帳時寫入各幣別的日報快照並記錄結帳日期，之後資料庫拒絕該日的回溯交易；
+// 快照寫入後不可修改，重新查詢直接回傳快照。月報由當月各日快照彙總。
+type ReconciliationService struct {
+	DB            *sql.DB
+	Currency      string
+	MaxMismatches int
+}
+
+// NewReconciliationService 建立新的對帳服務
+func NewReconciliationService() *ReconciliationService {
+	s := &ReconciliationService{DB: config.GetDB(), Currency: "TWD", MaxMismatches: defaultMaxMismatches}
+	if config.AppConfig != nil {
+		s.Currency = config.AppConfig.Game.DefaultCurrency
+		if config.AppConfig.Reconciliation.MaxMismatches > 0 {
+			s.MaxMismatches = config.AppConfig.Reconciliation.MaxMismatches
+		}
+	}
+	return s
+}
+
+// Daily 取得某日各幣別的日報：已結帳回傳快照，未結帳為即時試算（不寫入）
+func (s *ReconciliationService) Daily(day time.Time) ([]ReconciliationReport, error) {
+	day = startOfDay(day)
+	reports, err := s.snapshots("daily", day)
+	if err != nil || len(reports) > 0 {
+		return reports, err
+	}
+	return s.compute(day)
+}
+
+// CloseDay 結帳：計算各幣別日報並在同一交易寫入快照與結帳日期（已結帳時回傳既有快照）
+func (s *ReconciliationService) CloseDay(day time.Time, operatorID *int) ([]ReconciliationReport, error) {
+	day = startOfDay(day)
+	if day.AddDate(0, 0, 1).After(time.Now()) {
+		return nil, ErrPeriodNotEnded
+	}
+	reports, err := s.compute(day)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT IGNORE INTO reconciliation_closed_days (close_date, closed_by) VALUES (?, ?)",
+		day.Format("2006-01-02"), operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return s.snapshots("daily", day)
+	}
+	for i := range reports {
+		if err := s.insertSnapshot(tx, &reports[i], operatorID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.snapshots("daily", day)
+}
+
+// Monthly 取得某月各幣別的月報：由當月各日報彙總；當月已結束且每日皆已結帳時寫入月報快照，否則為試算
+func (s *ReconciliationService) Monthly(month time.Time, operatorID *int) ([]ReconciliationReport, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 1, 0)
+	reports, err := s.snapshots("monthly", start)
+	if err != nil || len(reports) > 0 {
+		return reports, err
+	}
+
+	byCurrency := map[string]*ReconciliationReport{}
+	allClosed := !end.After(time.Now())
+	for day := start; day.Before(end) && !day.After(time.Now()); day = day.AddDate(0, 0, 1) {
+		daily, err := s.Daily(day)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range daily {
+			allClosed = allClosed && d.Snapshot
+			m := byCurrency[d.Currency]
+			if m == nil {
+				m = &ReconciliationReport{
+					ReportDate: start.Format("2006-01-02"),
+					ReportType: "monthly",
+					Currency:   d.Currency,
+					Status:     ReconciliationBalanced,
+					ReconciliationDetails: ReconciliationDetails{
+						Mismatches: []ReconciliationMismatch{},
+					},
+				}
+				byCurrency[d.Currency] = m
+			}
+			m.merge(&d, s.MaxMismatches)
+		}
+	}
+	// 活躍玩家以整月不重複計
+	for currency, m := range byCurrency {
+		if err := s.DB.QueryRow(`
+			SELECT COUNT(DISTINCT player_id) FROM transactions
+			WHERE currency = ? AND transaction_type = 'bet' AND status = 'completed' AND created_at >= ? AND created_at < ?
+		`, currency, start, end).Scan(&m.ActivePlayers); err != nil {
+			return nil, err
+		}
+	}
+	reports = sortedReports(byCurrency)
+	if !allClosed {
+		return reports, nil
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for i := range reports {
+		if err := s.insertSnapshot(tx, &reports[i], operatorID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.snapshots("monthly", start)
+}
+
+// CloseDueDays 結帳最後結帳日之後至昨日的所有日期（尚無結帳紀錄時只結帳昨日）
+func (s *ReconciliationService) CloseDueDays() (int, error) {
+	yesterday := startOfDay(time.Now()).AddDate(0, 0, -1)
+	var last sql.NullTime
+	if err := s.DB.QueryRow("SELECT MAX(close_date) FROM reconciliation_closed_days").Scan(&last); err != nil {
+		return 0, err
+	}
+	day := yesterday
+	if last.Valid {
+		day = startOfDay(last.Time).AddDate(0, 0, 1)
+	}
+	closed := 0
+	for ; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		reports, err := s.CloseDay(day, nil)
+		if err != nil {
+			return closed, fmt.Errorf("%s 結帳失敗: %v", day.Format("2006-01-02"), err)
+		}
+		for _, r := range reports {
+			if r.Status == ReconciliationMismatched {
+				log.Printf("%s %s 對帳有 %d 筆差異", r.ReportDate, r.Currency, r.MismatchCount)
+			}
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// Run 定期結帳已結束的日期，直到 ctx 結束（阻塞執行，應以 goroutine 啟動）
+func (s *ReconciliationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.CloseDueDays(); err != nil {
+			log.Printf("自動結帳失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已結帳 %d 日", n)
+		}
+	}
+}
+
+// compute 計算某日各幣別的對帳結果（不寫入）
+func (s *ReconciliationService) compute(day time.Time) ([]ReconciliationReport, error) {
+	start, end := day, day.AddDate(0, 0, 1)
+	byCurrency := map[string]*ReconciliationReport{}
+	report := func(currency string) *ReconciliationReport {
+		r := byCurrency[currency]
+		if r == nil {
+			r = &ReconciliationReport{
+				ReportDate: day.Format("2006-01-02"),
+				ReportType: "daily",
+				Currency:   currency,
+				Status:     ReconciliationBalanced,
+				ReconciliationDetails: ReconciliationDetails{
+					Mismatches: []ReconciliationMismatch{},
+				},
+			}
+			byCurrency[currency] = r
+		}
+		return r
+	}
+	report(s.Currency)
+
+	// 交易彙總與錢包餘額變動
+	rows, err := s.DB.Query(`
+		SELECT currency, transaction_type, SUM(amount), SUM(balance_after - balance_before),
+		       COUNT(DISTINCT CASE WHEN transaction_type = 'bet' THEN player_id END)
+		FROM transactions
+		WHERE status = 'completed' AND created_at >= ? AND created_at < ?
+		GROUP BY currency, transaction_type
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			currency, transactionType string
+			amount, net               money.Amount
+			bettors                   int
+		)
+		if err := rows.Scan(&currency, &transactionType, &amount, &net, &bettors); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r := report(currency)
+		r.Totals.add(transactionType, amount)
+		r.Wallet.TransactionNet += net
+		if transactionType == "bet" {
+			r.ActivePlayers = bettors
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 總帳玩家帳戶分錄
+	if err := s.scanPairs(`
+		SELECT a.currency, SUM(e.amount)
+		FROM ledger_entries e
+		JOIN ledger_journals j ON j.id = e.journal_id
+		JOIN ledger_accounts a ON a.id = e.account_id
+		WHERE a.account_type = 'player' AND j.created_at >= ? AND j.created_at < ?
+		GROUP BY a.currency
+	`, func(currency string, amount money.Amount, _ int) {
+		report(currency).Wallet.LedgerNet = amount
+	}, start, end); err != nil {
+		return nil, err
+	}
+
+	// 閘道紀錄
+	if err := s.scanPairs(`
+		SELECT currency, SUM(amount), COUNT(*) FROM deposits
+		WHERE status = 'completed' AND processed_at >= ? AND processed_at < ?
+		GROUP BY currency
+	`, func(currency string, amount money.Amount, n int) {
+		g := &report(currency).Gateway
+		g.DepositsAmount, g.DepositsCompleted = amount, n
+	}, start, end); err != nil {
+		return nil, err
+	}
+	if err := s.scanPairs(`
+		SELECT currency, SUM(amount), COUNT(*) FROM withdrawals
+		WHERE status = 'completed' AND processed_at >= ? AND processed_at < ?
+		GROUP BY currency
+	`, func(currency string, amount money.Amount, n int) {
+		g := &report(currency).Gateway
+		g.WithdrawalsAmount, g.WithdrawalsCompleted = amount, n
+	}, start, end); err != nil {
+		return nil, err
+	}
+
+	for _, q := range mismatchQueries {
+		rows, err := s.DB.Query(q.query, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			m := ReconciliationMismatch{Check: q.check}
+			var currency string
+			if err := rows.Scan(&currency, &m.TransactionID, &m.ReferenceID, &m.Expected, &m.Actual); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			m.Detail = q.detail
+			report(currency).addMismatch(m, s.MaxMismatches)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var newPlayers int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM players WHERE created_at >= ? AND created_at < ?", start, end).Scan(&newPlayers); err != nil {
+		return nil, err
+	}
+	report(s.Currency).NewPlayers = newPlayers
+
+	for _, r := range byCurrency {
+		r.Wallet.Difference = r.Wallet.TransactionNet - r.Wallet.LedgerNet
+		if r.Wallet.Difference != 0 && r.MismatchCount == 0 {
+			// 差額無法歸屬到個別交易（例如傳票直接過帳），仍標記為不平衡
+			r.addMismatch(ReconciliationMismatch{
+				Check:    CheckJournalAmount,
+				Expected: r.Wallet.TransactionNet,
+				Actual:   r.Wallet.LedgerNet,
+				Detail:   "交易餘額變動合計與總帳玩家帳戶分錄合計不符",
+			}, s.MaxMismatches)
+		}
+	}
+	return sortedReports(byCurrency), nil
+}
+
+// mismatchQueries 差異追查查詢：回傳幣別、交易流水號、參考編號、預期與實際金額（參數為期間起訖）
+var mismatchQueries = []struct {
+	check  string
+	detail string
+	query  string
+}{
+	{
+		check:  CheckBalanceMovement,
+		detail: "交易的餘額變動與交易金額不符",
+		query: `
+			SELECT t.currency, t.transaction_id, COALESCE(t.reference_id, ''), t.amount, ABS(t.balance_after - t.balance_before)
+			FROM transactions t
+			WHERE t.status = 'completed' AND t.created_at >= ? AND t.created_at < ?
+			  AND ABS(t.balance_after - t.balance_before) <> t.amount`,
+	},
+	{
+		check:  CheckMissingJournal,
+		detail: "交易沒有對應的總帳傳票",
+		query: `
+			SELECT t.currency, t.transaction_id, COALESCE(t.reference_id, ''), t.balance_after - t.balance_before, 0
+			FROM transactions t
+			LEFT JOIN ledger_journals j ON j.transaction_id = t.transaction_id
+			WHERE t.status = 'completed' AND t.created_at >= ? AND t.created_at < ? AND j.id IS NULL`,
+	},
+	{
+		check:  CheckJournalAmount,
+		detail: "傳票的玩家分錄與交易的餘額變動不符",
+		query: `
+			SELECT t.currency, t.transaction_id, COALESCE(t.reference_id, ''), t.balance_after - t.balance_before,
+			       COALESCE(SUM(CASE WHEN a.account_type = 'player' THEN e.amount END), 0)
+			FROM transactions t
+			JOIN ledger_journals j ON j.transaction_id = t.transaction_id
+			JOIN ledger_entries e ON e.journal_id = j.id
+			JOIN ledger_accounts a ON a.id = e.account_id
+			WHERE t.status = 'completed' AND t.created_at >= ? AND t.created_at < ?
+			GROUP BY t.id, t.currency, t.transaction_id, t.reference_id, t.balance_after, t.balance_before
+			HAVING COALESCE(SUM(CASE WHEN a.account_type = 'player' THEN e.amount END), 0) <> t.balance_after - t.balance_before`,
+	},
+	{
+		check:  CheckGatewayDeposit,
+		detail: "已完成的儲值訂單沒有對應入帳，或入帳金額不符",
+		query: `
+			SELECT d.currency, COALESCE(t.transaction_id, ''), d.deposit_id, d.amount, COALESCE(t.amount, 0)
+			FROM deposits d
+			LEFT JOIN transactions t ON t.reference_type = 'deposit' AND t.reference_id = d.deposit_id
+			     AND t.transaction_type = 'deposit' AND t.status = 'completed'
+			WHERE d.status = 'completed' AND d.processed_at >= ? AND d.processed_at < ?
+			  AND (t.id IS NULL OR t.amount <> d.amount OR t.currency <> d.currency)`,
+	},
+	{
+		check:  CheckGatewayDeposit,
+		detail: "儲值入帳交易沒有對應已完成的儲值訂單",
+		query: `
+			SELECT t.currency, t.transaction_id, COALESCE(t.reference_id, ''), 0, t.amount
+			FROM transactions t
+			LEFT JOIN deposits d ON d.deposit_id = t.reference_id AND d.status = 'completed'
+			WHERE t.transaction_type = 'deposit' AND t.reference_type = 'deposit' AND t.status = 'completed'
+			  AND t.created_at >= ? AND t.created_at < ? AND d.id IS NULL`,
+	},
+	{
+		check:  CheckGatewayWithdrawal,
+		detail: "已完成出款的提領沒有對應扣款，或扣款金額不符",
+		query: `
+			SELECT w.currency, COALESCE(t.transaction_id, ''), w.withdrawal_id, w.amount, COALESCE(t.amount, 0)
+			FROM withdrawals w
+			LEFT JOIN transactions t ON t.reference_type = 'withdrawal' AND t.reference_id = w.withdrawal_id
+			     AND t.transaction_type = 'withdrawal' AND t.status = 'completed'
+			WHERE w.status = 'completed' AND w.processed_at >= ? AND w.processed_at < ?
+			  AND (t.id IS NULL OR t.amount <> w.amount OR t.currency <> w.currency)`,
+	},
+	{
+		check:  CheckGatewayWithdrawal,
+		detail: "提領扣款交易沒有對應已完成出款的提領",
+		query: `
+			SELECT t.currency, t.transaction_id, COALESCE(t.reference_id, ''), 0, t.amount
+			FROM transactions t
+			LEFT JOIN withdrawals w ON w.withdrawal_id = t.reference_id AND w.status = 'completed'
+			WHERE t.transaction_type = 'withdrawal' AND t.reference_type = 'withdrawal' AND t.status = 'completed'
+			  AND t.created_at >= ? AND t.created_at < ? AND w.id IS NULL`,
+	},
+}
+
+// scanPairs 執行以幣別分組的彙總查詢（第三欄為筆數時一併回傳）
+func (s *ReconciliationService) scanPairs(query string, fn func(string, money.Amount, int), args ...interface{}) error {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			currency string
+			amount   money.Amount
+			n        int
+		)
+		dest := []interface{}{&currency, &amount}
+		if len(cols) > 2 {
+			dest = append(dest, &n)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		fn(currency, amount, n)
+	}
+	return rows.Err()
+}
+
+// addMismatch 記錄差異（明細超過上限時只計數）
+func (r *ReconciliationReport) addMismatch(m ReconciliationMismatch, limit int) {
+	r.Status = ReconciliationMismatched
+	r.MismatchCount++
+	if len(r.Mismatches) < limit {
+		r.Mismatches = append(r.Mismatches, m)
+	} else {
+		r.Truncated = true
+	}
+}
+
+// merge 將日報累加至月報
+func (r *ReconciliationReport) merge(d *ReconciliationReport, limit int) {
+	r.Totals.Deposits += d.Totals.Deposits
+	r.Totals.Withdrawals += d.Totals.Withdrawals
+	r.Totals.Bets += d.Totals.Bets
+	r.Totals.Wins += d.Totals.Wins
+	r.Totals.Refunds += d.Totals.Refunds
+	r.Totals.Bonuses += d.Totals.Bonuses
+	r.Totals.Commissions += d.Totals.Commissions
+	r.Totals.Adjustments += d.Totals.Adjustments
+	r.Totals.GGR += d.Totals.GGR
+	r.NewPlayers += d.NewPlayers
+	r.Wallet.TransactionNet += d.Wallet.TransactionNet
+	r.Wallet.LedgerNet += d.Wallet.LedgerNet
+	r.Wallet.Difference += d.Wallet.Difference
+	r.Gateway.DepositsCompleted += d.Gateway.DepositsCompleted
+	r.Gateway.DepositsAmount += d.Gateway.DepositsAmount
+	r.Gateway.WithdrawalsCompleted += d.Gateway.WithdrawalsCompleted
+	r.Gateway.WithdrawalsAmount += d.Gateway.WithdrawalsAmount
+	r.Days++
+	r.MismatchCount += d.MismatchCount
+	if d.Status == ReconciliationMismatched {
+		r.Status = ReconciliationMismatched
+	}
+	for _, m := range d.Mismatches {
+		if len(r.Mismatches) >= limit {
+			r.Truncated = true
+			break
+		}
+		r.Mismatches = append(r.Mismatches, m)
+	}
+	r.Truncated = r.Truncated || d.Truncated
+}
+
+// insertSnapshot 寫入報表快照（同期間同幣別已有快照時保留既有快照）
+func (s *ReconciliationService) insertSnapshot(tx *sql.Tx, r *ReconciliationReport, operatorID *int) error {
+	details, err := json.Marshal(r.ReconciliationDetails)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT IGNORE INTO financial_reports
+			(report_date, report_type, total_deposits, total_withdrawals, total_bets, total_wins, total_refunds,
+			 house_profit, bonus_paid, commission_earned, total_adjustments, active_players, new_players, currency,
+			 reconciliation_status, mismatch_count, details, generated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.ReportDate, r.ReportType, r.Totals.Deposits, r.Totals.Withdrawals, r.Totals.Bets, r.Totals.Wins, r.Totals.Refunds,
+		r.Totals.GGR, r.Totals.Bonuses, r.Totals.Commissions, r.Totals.Adjustments, r.ActivePlayers, r.NewPlayers, r.Currency,
+		r.Status, r.MismatchCount, string(details), operatorID)
+	return err
+}
+
+// snapshots 讀取已寫入的報表快照
+func (s *ReconciliationService) snapshots(reportType string, date time.Time) ([]ReconciliationReport, error) {
+	rows, err := s.DB.Query(`
+		SELECT report_date, report_type, currency, reconciliation_status, total_deposits, total_withdrawals,
+		       total_bets, total_wins, total_refunds, house_profit, bonus_paid, commission_earned, total_adjustments,
+		       active_players, new_players, mismatch_count, details, created_at
+		FROM financial_reports
+		WHERE report_type = ? AND report_date = ?
+		ORDER BY currency
+	`, reportType, date.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reports := []ReconciliationReport{}
+	for rows.Next() {
+		var (
+			r         ReconciliationReport
+			reportDay time.Time
+			details   []byte
+			createdAt time.Time
+		)
+		if err := rows.Scan(&reportDay, &r.ReportType, &r.Currency, &r.Status, &r.Totals.Deposits, &r.Totals.Withdrawals,
+			&r.Totals.Bets, &r.Totals.Wins, &r.Totals.Refunds, &r.Totals.GGR, &r.Totals.Bonuses, &r.Totals.Commissions,
+			&r.Totals.Adjustments, &r.ActivePlayers, &r.NewPlayers, &r.MismatchCount, &details, &createdAt); err != nil {
+			return nil, err
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &r.ReconciliationDetails); err != nil {
+				return nil, err
+			}
+		}
+		if r.Mismatches == nil {
+			r.Mismatches = []ReconciliationMismatch{}
+		}
+		r.ReportDate = reportDay.Format("2006-01-02")
+		r.Snapshot, r.CreatedAt = true, &createdAt
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+func sortedReports(byCurrency map[string]*ReconciliationReport) []ReconciliationReport {
+	reports := make([]ReconciliationReport, 0, len(byCurrency))
+	for _, r := range byCurrency {
+		reports = append(reports, *r)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Currency < reports[j].Currency })
+	return reports
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
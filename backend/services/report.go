@@ -0,0 +1,184 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// RevenueTotals 交易彙總（GGR = 下注 - 派彩 - 退款）
+type RevenueTotals struct {
+	Deposits    money.Amount `json:"deposits"`
+	Withdrawals money.Amount `json:"withdrawals"`
+	Bets        money.Amount `json:"bets"`
+	Wins        money.Amount `json:"wins"`
+	Refunds     money.Amount `json:"refunds"`
+	Bonuses     money.Amount `json:"bonuses"`
+	Commissions money.Amount `json:"commissions"`
+	Adjustments money.Amount `json:"adjustments"`
+	GGR         money.Amount `json:"ggr"`
+}
+
+// add 依交易類型累加金額（幣別兌換為錢包間移轉，不計入營收）
+func (t *RevenueTotals) add(transactionType string, amount money.Amount) {
+	switch transactionType {
+	case "deposit":
+		t.Deposits += amount
+	case "withdrawal":
+		t.Withdrawals += amount
+	case "bet":
+		t.Bets += amount
+	case "win":
+		t.Wins += amount
+	case "refund":
+		t.Refunds += amount
+	case "bonus":
+		t.Bonuses += amount
+	case "commission":
+		t.Commissions += amount
+	case "adjustment":
+		t.Adjustments += amount
+	}
+	t.GGR = t.Bets - t.Wins - t.Refunds
+}
+
+// CurrencyRevenue 單一幣別的原幣彙總
+type CurrencyRevenue struct {
+	Currency string `json:"currency"`
+	RevenueTotals
+}
+
+// DailyRevenue 單日合併為報表幣別的彙總與當日採用的匯率
+type DailyRevenue struct {
+	Date  string            `json:"date"`
+	Rates map[string]string `json:"rates"` // 幣別 → 對報表幣別的匯率
+	RevenueTotals
+}
+
+// RevenueReport 營收報表（各幣別原幣彙總，以及依每日歷史匯率合併的報表幣別彙總）
+type RevenueReport struct {
+	StartDate         string            `json:"start_date"`
+	EndDate           string            `json:"end_date"`
+	ReportingCurrency string            `json:"reporting_currency"`
+	ByCurrency        []CurrencyRevenue `json:"by_currency"`
+	Daily             []DailyRevenue    `json:"daily"`
+	Consolidated      RevenueTotals     `json:"consolidated"`
+	MissingRates      []string          `json:"missing_rates"` // 查無匯率而未合併的「日期 幣別」
+}
+
+// ReportService 財務報表服務
+type ReportService struct {
+	DB *sql.DB
+	FX *FXService
+}
+
+// NewReportService 建立新的報表服務
+func NewReportService() *ReportService {
+	return &ReportService{DB: config.GetDB(), FX: NewFXService()}
+}
+
+// Revenue 產生 [start, end] 期間（含首尾日）的營收報表；各日金額以當日結束時的匯率換算為報表幣別
+func (s *ReportService) Revenue(start, end time.Time, reportingCurrency string) (*RevenueReport, error) {
+	if reportingCurrency == "" {
+		reportingCurrency = s.FX.Wallet.Currency
+	}
+	if err := s.FX.CheckCurrency(reportingCurrency); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query(`
+		SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS day, currency, transaction_type, SUM(amount)
+		FROM transactions
+		WHERE status = 'completed' AND created_at >= ? AND created_at < ?
+		GROUP BY day, currency, transaction_type
+		ORDER BY day, currency
+	`, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &RevenueReport{
+		StartDate:         start.Format("2006-01-02"),
+		EndDate:           end.Format("2006-01-02"),
+		ReportingCurrency: reportingCurrency,
+		ByCurrency:        []CurrencyRevenue{},
+		Daily:             []DailyRevenue{},
+		MissingRates:      []string{},
+	}
+	byCurrency := map[string]*CurrencyRevenue{}
+	daily := map[string]*DailyRevenue{}
+	rates := map[string]*money.Rate{} // 「日期 幣別」→ 匯率（nil 表示查無）
+	for rows.Next() {
+		var (
+			day, currency, transactionType string
+			amount                         money.Amount
+		)
+		if err := rows.Scan(&day, &currency, &transactionType, &amount); err != nil {
+			return nil, err
+		}
+		cr := byCurrency[currency]
+		if cr == nil {
+			cr = &CurrencyRevenue{Currency: currency}
+			byCurrency[currency] = cr
+		}
+		cr.add(transactionType, amount)
+
+		d := daily[day]
+		if d == nil {
+			d = &DailyRevenue{Date: day, Rates: map[string]string{}}
+			daily[day] = d
+		}
+		key := day + " " + currency
+		rate, ok := rates[key]
+		if !ok {
+			rate, err = s.dayRate(day, currency, reportingCurrency)
+			if err != nil {
+				return nil, err
+			}
+			rates[key] = rate
+			if rate == nil {
+				report.MissingRates = append(report.MissingRates, key)
+			} else {
+				d.Rates[currency] = rate.String()
+			}
+		}
+		if rate != nil {
+			converted := rate.Convert(amount)
+			d.add(transactionType, converted)
+			report.Consolidated.add(transactionType, converted)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, cr := range byCurrency {
+		report.ByCurrency = append(report.ByCurrency, *cr)
+	}
+	sort.Slice(report.ByCurrency, func(i, j int) bool { return report.ByCurrency[i].Currency < report.ByCurrency[j].Currency })
+	for _, d := range daily {
+		report.Daily = append(report.Daily, *d)
+	}
+	sort.Slice(report.Daily, func(i, j int) bool { return report.Daily[i].Date < report.Daily[j].Date })
+	return report, nil
+}
+
+// dayRate 取得某日結束時 currency → reporting 的匯率，查無時回傳 nil
+func (s *ReportService) dayRate(day, currency, reporting string) (*money.Rate, error) {
+	date, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("日期格式錯誤: %v", err)
+	}
+	r, err := s.FX.Rate(currency, reporting, date.AddDate(0, 0, 1).Add(-time.Second))
+	if errors.Is(err, ErrRateNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &r.Rate, nil
+}
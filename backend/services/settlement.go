@@ -0,0 +1,845 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 結算相關錯誤
+var (
+	ErrSettlementNotFound = errors.New("結算不存在")
+	ErrSettlementStatus   = errors.New("結算目前的狀態不允許此操作")
+	ErrInvalidAdjustment  = errors.New("調整金額不可為 0 且需說明原因")
+)
+
+// 結算狀態
+const (
+	SettlementPending    = "pending"
+	SettlementCalculated = "calculated"
+	SettlementApproved   = "approved"
+	SettlementPaid       = "paid"
+	SettlementDisputed   = "disputed"
+)
+
+// 結算週期（commission_configs.settlement_period）
+const (
+	SettlementDaily   = "daily"
+	SettlementWeekly  = "weekly"
+	SettlementMonthly = "monthly"
+)
+
+// settlementCatchUp 每次檢查時每個對象最多補結的期間數
+const settlementCatchUp = 62
+
+// SettlementRunSummary 結算涵蓋的分潤計算批次
+type SettlementRunSummary struct {
+	RunID             string       `json:"run_id"`
+	GameType          string       `json:"game_type"`
+	NetRevenue        money.Amount `json:"net_revenue"`
+	CarryIn           money.Amount `json:"carry_in"`
+	CarryOut          money.Amount `json:"carry_out"`
+	Qualified         bool         `json:"qualified"`
+	Rate              float64      `json:"rate"`
+	RevenueCommission money.Amount `json:"revenue_commission"`
+	CPACommission     money.Amount `json:"cpa_commission"`
+}
+
+// SettlementAdjustment 結算調整
+type SettlementAdjustment struct {
+	ID        int64        `json:"id"`
+	Amount    money.Amount `json:"amount"`
+	Reason    string       `json:"reason"`
+	CreatedBy int          `json:"created_by"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// SettlementDetails 計算明細（存於 calculation_details）
+type SettlementDetails struct {
+	Runs                    []SettlementRunSummary `json:"runs"`
+	DirectCommission        money.Amount           `json:"direct_commission"`   // 自己批次的營收分成
+	OverrideCommission      money.Amount           `json:"override_commission"` // 下級貢獻的差額佣金
+	CPACommission           money.Amount           `json:"cpa_commission"`
+	CommissionCount         int64                  `json:"commission_count"`
+	Adjustments             []SettlementAdjustment `json:"adjustments"`
+	CommissionsRecalculated *bool                  `json:"commissions_recalculated,omitempty"` // 爭議重算時是否重新計算佣金
+}
+
+// Settlement 代理商或經銷商的期間結算（單一幣別）
+//
+// TotalRevenue 為期間淨營收（下注減派彩），NetRevenue 為加上負營收結轉後的可計佣營收；
+// TotalPayout = CommissionAmount + BonusAmount + AdjustmentAmount；
+// 支付時先抵扣對象已使用的信用額度（CreditNetted），實際支付現金為 PaidAmount - CreditNetted。
+type Settlement struct {
+	ID                 int64             `json:"id"`
+	SettlementID       string            `json:"settlement_id"`
+	TargetType         string            `json:"target_type"`
+	TargetID           int               `json:"target_id"`
+	AgentID            int               `json:"agent_id"`
+	PeriodStart        string            `json:"period_start"`
+	PeriodEnd          string            `json:"period_end"`
+	Currency           string            `json:"currency"`
+	TotalRevenue       money.Amount      `json:"total_revenue"`
+	TotalBets          money.Amount      `json:"total_bets"`
+	TotalWins          money.Amount      `json:"total_wins"`
+	NetRevenue         money.Amount      `json:"net_revenue"`
+	CommissionRate     float64           `json:"commission_rate"`
+	CommissionAmount   money.Amount      `json:"commission_amount"`
+	BonusAmount        money.Amount      `json:"bonus_amount"`
+	AdjustmentAmount   money.Amount      `json:"adjustment_amount"`
+	TotalPayout        money.Amount      `json:"total_payout"`
+	PaidAmount         money.Amount      `json:"paid_amount"`
+	CreditNetted       money.Amount      `json:"credit_netted"`
+	PlayerCount        int               `json:"player_count"`
+	ActivePlayerCount  int               `json:"active_player_count"`
+	NewPlayerCount     int               `json:"new_player_count"`
+	Status             string            `json:"status"`
+	Version            int               `json:"version"`
+	CalculationDetails SettlementDetails `json:"calculation_details"`
+	PaymentMethod      string            `json:"payment_method,omitempty"`
+	PaymentReference   string            `json:"payment_reference,omitempty"`
+	PaidAt             *time.Time        `json:"paid_at,omitempty"`
+	ApprovedBy         *int              `json:"approved_by,omitempty"`
+	ApprovedAt         *time.Time        `json:"approved_at,omitempty"`
+	DisputeReason      string            `json:"dispute_reason,omitempty"`
+	DisputedBy         *int              `json:"disputed_by,omitempty"`
+	DisputedAt         *time.Time        `json:"disputed_at,omitempty"`
+	Notes              string            `json:"notes,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+// SettlementVersion 結算版本快照
+type SettlementVersion struct {
+	Version   int             `json:"version"`
+	Status    string          `json:"status"`
+	Reason    string          `json:"reason"`
+	Snapshot  json.RawMessage `json:"snapshot"`
+	CreatedBy *int            `json:"created_by,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// SettlementFilter 結算查詢條件
+type SettlementFilter struct {
+	TargetType  string
+	TargetID    int
+	Status      string
+	PeriodStart *time.Time
+	PeriodEnd   *time.Time
+	Page        int
+	Limit       int
+}
+
+// SettlementPayment 支付資訊
+type SettlementPayment struct {
+	Method    string
+	Reference string
+}
+
+// SettlementService 代理商與經銷商結算
+//
+// 排程依各對象 all 分潤配置的結算週期（未配置為每月），在期間結束後計算該期間的佣金，
+// 將受益者尚未結算的佣金（含下級貢獻的差額佣金）依幣別彙總成結算，之後經調整、審核、支付。
+// 爭議會重新開啟結算，重算後成為新版本；每次計算、調整與爭議都保存完整快照。
+type SettlementService struct {
+	DB          *sql.DB
+	Commissions *CommissionService
+	Credit      *AgentWalletService
+}
+
+// NewSettlementService 建立新的結算服務
+func NewSettlementService() *SettlementService {
+	return &SettlementService{DB: config.GetDB(), Commissions: NewCommissionService(), Credit: NewAgentWalletService()}
+}
+
+// List 依條件列出結算，回傳資料與總筆數
+func (s *SettlementService) List(f SettlementFilter) ([]Settlement, int64, error) {
+	table, column := settlementTable(f.TargetType)
+	if err := commissionTargetExists(s.DB, f.TargetType, f.TargetID); err != nil {
+		return nil, 0, err
+	}
+	where := " WHERE s." + column + " = ?"
+	args := []interface{}{f.TargetID}
+	if f.Status != "" {
+		where += " AND s.status = ?"
+		args = append(args, f.Status)
+	}
+	if f.PeriodStart != nil {
+		where += " AND s.settlement_period_start >= ?"
+		args = append(args, f.PeriodStart.Format("2006-01-02"))
+	}
+	if f.PeriodEnd != nil {
+		where += " AND s.settlement_period_end <= ?"
+		args = append(args, f.PeriodEnd.Format("2006-01-02"))
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM "+table+" s"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.DB.Query(settlementSelect(f.TargetType)+where+
+		" ORDER BY s.settlement_period_start DESC, s.currency LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []Settlement{}
+	for rows.Next() {
+		st, err := scanSettlement(rows, f.TargetType)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *st)
+	}
+	return list, total, rows.Err()
+}
+
+// Get 取得結算
+func (s *SettlementService) Get(targetType string, id int64) (*Settlement, error) {
+	return loadSettlement(s.DB, targetType, "s.id = ?", id)
+}
+
+// Versions 結算的所有版本（由新到舊）
+func (s *SettlementService) Versions(targetType string, id int64) ([]SettlementVersion, error) {
+	st, err := s.Get(targetType, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query(`
+		SELECT version, status, reason, snapshot, created_by, created_at
+		FROM settlement_versions WHERE settlement_type = ? AND settlement_id = ?
+		ORDER BY version DESC
+	`, targetType, st.SettlementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []SettlementVersion{}
+	for rows.Next() {
+		var (
+			v         SettlementVersion
+			snapshot  []byte
+			createdBy sql.NullInt64
+		)
+		if err := rows.Scan(&v.Version, &v.Status, &v.Reason, &snapshot, &createdBy, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		v.Snapshot, v.CreatedBy = json.RawMessage(snapshot), nullInt(createdBy)
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+// CloseDuePeriods 為所有代理商與經銷商結算已結束的期間，回傳產生的結算筆數
+func (s *SettlementService) CloseDuePeriods() (int, error) {
+	rows, err := s.DB.Query(`
+		SELECT 'agent', id, last_settlement_date, created_at FROM agents WHERE parent_agent_id IS NOT NULL
+		UNION ALL
+		SELECT 'dealer', id, last_settlement_date, created_at FROM dealers
+	`)
+	if err != nil {
+		return 0, err
+	}
+	type due struct {
+		target  CommissionTarget
+		last    sql.NullTime
+		created time.Time
+	}
+	var list []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.target.Type, &d.target.ID, &d.last, &d.created); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		list = append(list, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, d := range list {
+		start := startOfDay(d.created)
+		if d.last.Valid {
+			start = startOfDay(d.last.Time).AddDate(0, 0, 1)
+		}
+		k, err := s.closeTarget(d.target, start)
+		n += k
+		if err != nil {
+			// 單一對象失敗（例如期間與已計算的佣金衝突）不影響其他對象
+			log.Printf("%s %d 結算失敗: %v", d.target.Type, d.target.ID, err)
+		}
+	}
+	return n, nil
+}
+
+// Run 定期結算已結束的期間，直到 ctx 結束（於背景 goroutine 執行）
+func (s *SettlementService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.CloseDuePeriods(); err != nil {
+			log.Printf("代理商結算失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已產生 %d 筆代理商與經銷商結算", n)
+		}
+	}
+}
+
+// closeTarget 由 start 起依序結算對象已結束的期間
+func (s *SettlementService) closeTarget(t CommissionTarget, start time.Time) (int, error) {
+	today := startOfDay(time.Now())
+	n := 0
+	for i := 0; i < settlementCatchUp; i++ {
+		kind, err := s.periodKind(t, start)
+		if err != nil {
+			return n, err
+		}
+		p := CommissionPeriod{Start: start, End: settlementPeriodEnd(kind, start)}
+		if !p.End.Before(today) {
+			break
+		}
+		if _, err := s.Commissions.Calculate(p, &t, nil); err != nil {
+			return n, err
+		}
+		err = withAgentTx(s.DB, func(tx *sql.Tx) error {
+			k, err := s.createSettlementsTx(tx, t, p)
+			n += k
+			return err
+		})
+		if err != nil {
+			return n, err
+		}
+		start = p.End.AddDate(0, 0, 1)
+	}
+	return n, nil
+}
+
+// periodKind 對象在指定日期適用的結算週期（all 分潤配置，未配置為每月）
+func (s *SettlementService) periodKind(t CommissionTarget, day time.Time) (string, error) {
+	var kind string
+	err := s.DB.QueryRow(`
+		SELECT settlement_period FROM commission_configs
+		WHERE target_type = ? AND target_id = ? AND game_type = 'all' AND is_active
+		  AND effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)
+		ORDER BY effective_from DESC, id DESC LIMIT 1
+	`, t.Type, t.ID, day.Format("2006-01-02"), day.Format("2006-01-02")).Scan(&kind)
+	if err == sql.ErrNoRows {
+		return SettlementMonthly, nil
+	}
+	return kind, err
+}
+
+// createSettlementsTx 將期間內的佣金依幣別彙總成結算，並推進對象的最後結算日期
+func (s *SettlementService) createSettlementsTx(tx *sql.Tx, t CommissionTarget, p CommissionPeriod) (int, error) {
+	target, err := lockCommissionTarget(tx, t.Type, t.ID)
+	if err != nil {
+		return 0, err
+	}
+	table := targetTable(t.Type)
+	var last sql.NullTime
+	if err := tx.QueryRow("SELECT last_settlement_date FROM "+table+" WHERE id = ?", t.ID).Scan(&last); err != nil {
+		return 0, err
+	}
+	if last.Valid && !startOfDay(last.Time).Before(p.End) {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT DISTINCT currency FROM commissions
+		WHERE `+beneficiaryWhere(t.Type)+` AND status = 'pending' AND settlement_id IS NULL AND period_end <= ?
+		UNION
+		SELECT DISTINCT currency FROM commission_runs
+		WHERE target_type = ? AND target_id = ? AND period_start >= ? AND period_end <= ?
+	`, t.ID, p.endDate(), t.Type, t.ID, p.startDate(), p.endDate())
+	if err != nil {
+		return 0, err
+	}
+	var currencies []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		currencies = append(currencies, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	stTable, column := settlementTable(t.Type)
+	for _, currency := range currencies {
+		st := &Settlement{
+			SettlementID: newSettlementID(t.Type),
+			TargetType:   t.Type,
+			TargetID:     t.ID,
+			AgentID:      target.agentID,
+			PeriodStart:  p.startDate(),
+			PeriodEnd:    p.endDate(),
+			Currency:     currency,
+			Status:       SettlementCalculated,
+			Version:      1,
+		}
+		cols, vals := column+", ", "?, "
+		args := []interface{}{st.SettlementID, t.ID}
+		if t.Type == NodeDealer {
+			cols, vals = "dealer_id, agent_id, ", "?, ?, "
+			args = append(args, target.agentID)
+		}
+		res, err := tx.Exec(`
+			INSERT INTO `+stTable+`
+				(settlement_id, `+cols+`settlement_period_start, settlement_period_end, currency, total_revenue,
+				 total_bets, total_wins, net_revenue, commission_rate, commission_amount, total_payout,
+				 player_count, active_player_count, new_player_count, status, version)
+			VALUES (?, `+vals+`?, ?, ?, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 'calculated', 1)
+		`, append(args, st.PeriodStart, st.PeriodEnd, currency)...)
+		if err != nil {
+			return 0, err
+		}
+		if st.ID, err = res.LastInsertId(); err != nil {
+			return 0, err
+		}
+		if err := linkCommissionsTx(tx, st); err != nil {
+			return 0, err
+		}
+		if err := buildSettlementTx(tx, st); err != nil {
+			return 0, err
+		}
+		if err := saveSettlementVersionTx(tx, st, "期間結算", nil); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.Exec("UPDATE "+table+" SET last_settlement_date = ? WHERE id = ?", p.endDate(), t.ID); err != nil {
+		return 0, err
+	}
+	return len(currencies), nil
+}
+
+// Adjust 新增結算調整（僅限已計算或爭議中的結算）
+func (s *SettlementService) Adjust(targetType string, id int64, amount money.Amount, reason string, operatorID int) (*Settlement, error) {
+	if amount == 0 || reason == "" {
+		return nil, ErrInvalidAdjustment
+	}
+	return s.update(targetType, id, func(tx *sql.Tx, st *Settlement) error {
+		if st.Status != SettlementCalculated && st.Status != SettlementDisputed {
+			return ErrSettlementStatus
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO settlement_adjustments (settlement_type, settlement_id, amount, reason, created_by)
+			VALUES (?, ?, ?, ?, ?)
+		`, targetType, st.SettlementID, amount, reason, operatorID); err != nil {
+			return err
+		}
+		st.Version++
+		if err := buildSettlementTx(tx, st); err != nil {
+			return err
+		}
+		return saveSettlementVersionTx(tx, st, "調整："+reason, &operatorID)
+	})
+}
+
+// Approve 審核結算，佣金記錄改為已確認
+func (s *SettlementService) Approve(targetType string, id int64, reviewerID int) (*Settlement, error) {
+	return s.update(targetType, id, func(tx *sql.Tx, st *Settlement) error {
+		if st.Status != SettlementCalculated {
+			return ErrSettlementStatus
+		}
+		table, _ := settlementTable(targetType)
+		if _, err := tx.Exec(`
+			UPDATE `+table+` SET status = 'approved', approved_by = ?, approved_at = NOW() WHERE id = ?
+		`, reviewerID, st.ID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			UPDATE commissions SET status = 'confirmed' WHERE settlement_id = ? AND status = 'pending'
+		`, st.SettlementID)
+		return err
+	})
+}
+
+// MarkPaid 標記結算已支付（爭議重算後只支付與已支付金額的差額），並累計對象的營收與佣金
+//
+// 應付金額先抵扣對象在結算幣別已使用的信用額度，只有剩餘部分以現金支付。
+func (s *SettlementService) MarkPaid(targetType string, id int64, payment SettlementPayment) (*Settlement, error) {
+	return s.update(targetType, id, func(tx *sql.Tx, st *Settlement) error {
+		if st.Status != SettlementApproved {
+			return ErrSettlementStatus
+		}
+		payout := st.TotalPayout - st.PaidAmount
+		revenue := st.TotalRevenue
+		if st.PaidAt != nil {
+			revenue = 0
+		}
+		netted, err := s.Credit.NetSettlementTx(tx, st, payout)
+		if err != nil {
+			return err
+		}
+		table, _ := settlementTable(targetType)
+		if _, err := tx.Exec(`
+			UPDATE `+table+`
+			SET status = 'paid', paid_amount = total_payout, credit_netted = credit_netted + ?,
+			    payment_method = ?, payment_reference = ?, paid_at = NOW()
+			WHERE id = ?
+		`, netted, payment.Method, payment.Reference, st.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			UPDATE commissions SET status = 'paid', paid_at = NOW() WHERE settlement_id = ? AND status = 'confirmed'
+		`, st.SettlementID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE `+targetTable(targetType)+` SET total_commission = total_commission + ?, total_revenue = total_revenue + ?
+			WHERE id = ?
+		`, payout, revenue, st.TargetID)
+		return err
+	})
+}
+
+// Dispute 對結算提出爭議，重新開啟待重算（已確認的佣金退回未確認，已支付者保留）
+func (s *SettlementService) Dispute(targetType string, id int64, reason string, operatorID int) (*Settlement, error) {
+	return s.update(targetType, id, func(tx *sql.Tx, st *Settlement) error {
+		switch st.Status {
+		case SettlementCalculated, SettlementApproved, SettlementPaid:
+		default:
+			return ErrSettlementStatus
+		}
+		table, _ := settlementTable(targetType)
+		if _, err := tx.Exec(`
+			UPDATE `+table+`
+			SET status = 'disputed', dispute_reason = ?, disputed_by = ?, disputed_at = NOW(),
+			    approved_by = NULL, approved_at = NULL
+			WHERE id = ?
+		`, reason, operatorID, st.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			UPDATE commissions SET status = 'pending' WHERE settlement_id = ? AND status = 'confirmed'
+		`, st.SettlementID); err != nil {
+			return err
+		}
+		st.Status, st.DisputeReason, st.DisputedBy = SettlementDisputed, reason, &operatorID
+		st.ApprovedBy, st.ApprovedAt = nil, nil
+		st.Version++
+		if _, err := tx.Exec("UPDATE "+table+" SET version = ? WHERE id = ?", st.Version, st.ID); err != nil {
+			return err
+		}
+		return saveSettlementVersionTx(tx, st, "爭議："+reason, &operatorID)
+	})
+}
+
+// Recalculate 重算爭議中的結算：重新計算對象在期間內的佣金（佣金已支付或已有後續期間時只重新彙總），
+// 重新納入未結算的佣金並成為新版本
+func (s *SettlementService) Recalculate(targetType string, id int64, operatorID int) (*Settlement, error) {
+	st, err := s.update(targetType, id, func(tx *sql.Tx, st *Settlement) error {
+		if st.Status != SettlementDisputed {
+			return ErrSettlementStatus
+		}
+		_, err := tx.Exec(`
+			UPDATE commissions SET settlement_id = NULL WHERE settlement_id = ? AND status = 'pending'
+		`, st.SettlementID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	start, _ := time.ParseInLocation("2006-01-02", st.PeriodStart, time.Local)
+	end, _ := time.ParseInLocation("2006-01-02", st.PeriodEnd, time.Local)
+	recalculated := true
+	_, err = s.Commissions.Calculate(CommissionPeriod{Start: start, End: end},
+		&CommissionTarget{Type: targetType, ID: st.TargetID}, &operatorID)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrCommissionLocked), errors.Is(err, ErrCommissionLaterPeriod),
+		errors.Is(err, ErrCommissionOverlap):
+		recalculated = false
+	default:
+		return nil, err
+	}
+
+	return s.update(targetType, id, func(tx *sql.Tx, st *Settlement) error {
+		if st.Status != SettlementDisputed {
+			return ErrSettlementStatus
+		}
+		if err := linkCommissionsTx(tx, st); err != nil {
+			return err
+		}
+		st.Status = SettlementCalculated
+		st.Version++
+		st.CalculationDetails.CommissionsRecalculated = &recalculated
+		if err := buildSettlementTx(tx, st); err != nil {
+			return err
+		}
+		return saveSettlementVersionTx(tx, st, "爭議重算", &operatorID)
+	})
+}
+
+// update 鎖定結算並執行變更，回傳變更後的結算
+func (s *SettlementService) update(targetType string, id int64, fn func(*sql.Tx, *Settlement) error) (*Settlement, error) {
+	err := withAgentTx(s.DB, func(tx *sql.Tx) error {
+		st, err := loadSettlement(tx, targetType, "s.id = ? FOR UPDATE", id)
+		if err != nil {
+			return err
+		}
+		return fn(tx, st)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(targetType, id)
+}
+
+// linkCommissionsTx 將受益者在期間結束前尚未結算的佣金納入結算
+func linkCommissionsTx(tx *sql.Tx, st *Settlement) error {
+	_, err := tx.Exec(`
+		UPDATE commissions SET settlement_id = ?
+		WHERE `+beneficiaryWhere(st.TargetType)+` AND currency = ? AND status = 'pending'
+		  AND settlement_id IS NULL AND period_end <= ?
+	`, st.SettlementID, st.TargetID, st.Currency, st.PeriodEnd)
+	return err
+}
+
+// buildSettlementTx 依納入的佣金、期間的計算批次與調整重新彙總結算並寫入
+func buildSettlementTx(tx *sql.Tx, st *Settlement) error {
+	recalculated := st.CalculationDetails.CommissionsRecalculated
+	d := SettlementDetails{
+		Runs:                    []SettlementRunSummary{},
+		Adjustments:             []SettlementAdjustment{},
+		CommissionsRecalculated: recalculated,
+	}
+	st.TotalBets, st.TotalWins, st.TotalRevenue, st.NetRevenue = 0, 0, 0, 0
+	rows, err := tx.Query(`
+		SELECT run_id, game_type, total_bets, total_wins, net_revenue, carry_in, commissionable_revenue, carry_out,
+		       qualified, rate, revenue_commission, cpa_commission
+		FROM commission_runs
+		WHERE target_type = ? AND target_id = ? AND currency = ? AND period_start >= ? AND period_end <= ?
+		ORDER BY game_type
+	`, st.TargetType, st.TargetID, st.Currency, st.PeriodStart, st.PeriodEnd)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			r                   SettlementRunSummary
+			bets, wins, payable money.Amount
+		)
+		if err := rows.Scan(&r.RunID, &r.GameType, &bets, &wins, &r.NetRevenue, &r.CarryIn, &payable, &r.CarryOut,
+			&r.Qualified, &r.Rate, &r.RevenueCommission, &r.CPACommission); err != nil {
+			rows.Close()
+			return err
+		}
+		st.TotalBets += bets
+		st.TotalWins += wins
+		st.TotalRevenue += r.NetRevenue
+		st.NetRevenue += payable
+		d.Runs = append(d.Runs, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN override_level = 0 AND commission_type = 'loss_commission' THEN commission_amount END), 0),
+		       COALESCE(SUM(CASE WHEN override_level > 0 THEN commission_amount END), 0),
+		       COALESCE(SUM(CASE WHEN commission_type = 'deposit_commission' THEN commission_amount END), 0),
+		       COUNT(*)
+		FROM commissions WHERE settlement_id = ?
+	`, st.SettlementID).Scan(&d.DirectCommission, &d.OverrideCommission, &d.CPACommission, &d.CommissionCount); err != nil {
+		return err
+	}
+	st.CommissionAmount = d.DirectCommission + d.OverrideCommission + d.CPACommission
+	st.CommissionRate = 0
+	if st.NetRevenue.IsPositive() {
+		st.CommissionRate = math.Min(math.Round(d.DirectCommission.Float64()/st.NetRevenue.Float64()*10000)/10000, 1)
+	}
+
+	rows, err = tx.Query(`
+		SELECT id, amount, reason, created_by, created_at FROM settlement_adjustments
+		WHERE settlement_type = ? AND settlement_id = ? ORDER BY id
+	`, st.TargetType, st.SettlementID)
+	if err != nil {
+		return err
+	}
+	st.AdjustmentAmount = 0
+	for rows.Next() {
+		var a SettlementAdjustment
+		if err := rows.Scan(&a.ID, &a.Amount, &a.Reason, &a.CreatedBy, &a.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		st.AdjustmentAmount += a.Amount
+		d.Adjustments = append(d.Adjustments, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	st.TotalPayout = st.CommissionAmount + st.BonusAmount + st.AdjustmentAmount
+
+	scope := "p.agent_id = ? AND p.dealer_id IS NULL"
+	if st.TargetType == NodeDealer {
+		scope = "p.dealer_id = ?"
+	}
+	from, _ := time.ParseInLocation("2006-01-02", st.PeriodStart, time.Local)
+	to, _ := time.ParseInLocation("2006-01-02", st.PeriodEnd, time.Local)
+	to = to.AddDate(0, 0, 1)
+	if err := tx.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM players p WHERE `+scope+` AND p.status <> 'deleted'),
+		       (SELECT COUNT(DISTINCT t.player_id) FROM transactions t JOIN players p ON p.id = t.player_id
+		        WHERE `+scope+` AND t.transaction_type = 'bet' AND t.status = 'completed' AND t.currency = ?
+		          AND t.created_at >= ? AND t.created_at < ?),
+		       (SELECT COUNT(*) FROM players p WHERE `+scope+` AND p.created_at >= ? AND p.created_at < ?)
+	`, st.TargetID, st.TargetID, st.Currency, from, to, st.TargetID, from, to).Scan(
+		&st.PlayerCount, &st.ActivePlayerCount, &st.NewPlayerCount); err != nil {
+		return err
+	}
+
+	st.CalculationDetails = d
+	details, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	table, _ := settlementTable(st.TargetType)
+	_, err = tx.Exec(`
+		UPDATE `+table+`
+		SET total_revenue = ?, total_bets = ?, total_wins = ?, net_revenue = ?, commission_rate = ?,
+		    commission_amount = ?, adjustment_amount = ?, total_payout = ?, player_count = ?,
+		    active_player_count = ?, new_player_count = ?, status = ?, version = ?, calculation_details = ?
+		WHERE id = ?
+	`, st.TotalRevenue, st.TotalBets, st.TotalWins, st.NetRevenue, st.CommissionRate, st.CommissionAmount,
+		st.AdjustmentAmount, st.TotalPayout, st.PlayerCount, st.ActivePlayerCount, st.NewPlayerCount, st.Status,
+		st.Version, details, st.ID)
+	return err
+}
+
+// saveSettlementVersionTx 保存結算目前內容為新版本
+func saveSettlementVersionTx(tx *sql.Tx, st *Settlement, reason string, operatorID *int) error {
+	snapshot, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if r := []rune(reason); len(r) > 255 {
+		reason = string(r[:255])
+	}
+	_, err = tx.Exec(`
+		INSERT INTO settlement_versions (settlement_type, settlement_id, version, status, reason, snapshot, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, st.TargetType, st.SettlementID, st.Version, st.Status, reason, snapshot, operatorID)
+	return err
+}
+
+// settlementPeriodEnd 由期間開始日推算期間結束日（週結以週日為最後一天）
+func settlementPeriodEnd(kind string, start time.Time) time.Time {
+	switch kind {
+	case SettlementDaily:
+		return start
+	case SettlementWeekly:
+		weekday := (int(start.Weekday()) + 6) % 7 // 週一為 0
+		return start.AddDate(0, 0, 6-weekday)
+	default:
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	}
+}
+
+// settlementTable 結算表與對象欄位
+func settlementTable(targetType string) (string, string) {
+	if targetType == NodeDealer {
+		return "dealer_settlements", "dealer_id"
+	}
+	return "agent_settlements", "agent_id"
+}
+
+// targetTable 代理商或經銷商表
+func targetTable(targetType string) string {
+	if targetType == NodeDealer {
+		return "dealers"
+	}
+	return "agents"
+}
+
+// beneficiaryWhere 佣金記錄的受益者條件（參數為對象ID）
+func beneficiaryWhere(targetType string) string {
+	if targetType == NodeDealer {
+		return "dealer_id = ?"
+	}
+	return "agent_id = ? AND dealer_id IS NULL"
+}
+
+// newSettlementID 產生結算編號（代理商為 AS、經銷商為 DS 開頭）
+func newSettlementID(targetType string) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	prefix := "AS"
+	if targetType == NodeDealer {
+		prefix = "DS"
+	}
+	return prefix + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
+
+func settlementSelect(targetType string) string {
+	table, column := settlementTable(targetType)
+	return `
+	SELECT s.id, s.settlement_id, s.` + column + `, s.agent_id, s.settlement_period_start, s.settlement_period_end,
+	       s.currency, s.total_revenue, s.total_bets, s.total_wins, s.net_revenue, s.commission_rate,
+	       s.commission_amount, s.bonus_amount, s.adjustment_amount, s.total_payout, s.paid_amount, s.credit_netted,
+	       s.player_count, s.active_player_count, s.new_player_count, s.status, s.version, s.calculation_details,
+	       COALESCE(s.payment_method, ''), COALESCE(s.payment_reference, ''), s.paid_at, s.approved_by, s.approved_at,
+	       COALESCE(s.dispute_reason, ''), s.disputed_by, s.disputed_at, COALESCE(s.notes, ''), s.created_at, s.updated_at
+	FROM ` + table + ` s`
+}
+
+func loadSettlement(q queryer, targetType, where string, args ...interface{}) (*Settlement, error) {
+	st, err := scanSettlement(q.QueryRow(settlementSelect(targetType)+" WHERE "+where, args...), targetType)
+	if err == sql.ErrNoRows {
+		return nil, ErrSettlementNotFound
+	}
+	return st, err
+}
+
+func scanSettlement(row rowScanner, targetType string) (*Settlement, error) {
+	st := &Settlement{TargetType: targetType}
+	var (
+		start, end                   time.Time
+		details                      []byte
+		paidAt, approvedAt, disputed sql.NullTime
+		approvedBy, disputedBy       sql.NullInt64
+	)
+	if err := row.Scan(&st.ID, &st.SettlementID, &st.TargetID, &st.AgentID, &start, &end,
+		&st.Currency, &st.TotalRevenue, &st.TotalBets, &st.TotalWins, &st.NetRevenue, &st.CommissionRate,
+		&st.CommissionAmount, &st.BonusAmount, &st.AdjustmentAmount, &st.TotalPayout, &st.PaidAmount, &st.CreditNetted,
+		&st.PlayerCount, &st.ActivePlayerCount, &st.NewPlayerCount, &st.Status, &st.Version, &details,
+		&st.PaymentMethod, &st.PaymentReference, &paidAt, &approvedBy, &approvedAt,
+		&st.DisputeReason, &disputedBy, &disputed, &st.Notes, &st.CreatedAt, &st.UpdatedAt); err != nil {
+		return nil, err
+	}
+	st.PeriodStart, st.PeriodEnd = start.Format("2006-01-02"), end.Format("2006-01-02")
+	st.PaidAt, st.ApprovedAt, st.DisputedAt = nullTime(paidAt), nullTime(approvedAt), nullTime(disputed)
+	st.ApprovedBy, st.DisputedBy = nullInt(approvedBy), nullInt(disputedBy)
+	st.CalculationDetails = SettlementDetails{Runs: []SettlementRunSummary{}, Adjustments: []SettlementAdjustment{}}
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, &st.CalculationDetails); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
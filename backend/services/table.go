@@ -0,0 +1,695 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/ai"
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/engine/cards"
+	"nexus-gaming-backend/engine/poker"
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/rng"
+	"nexus-gaming-backend/table"
+)
+
+// 牌桌相關錯誤
+var (
+	ErrRoomNotFound   = errors.New("房間不存在")
+	ErrRoomInactive   = errors.New("房間未開放")
+	ErrRoomNotPoker   = errors.New("此房間的遊戲不支援即時牌桌")
+	ErrInvalidBlinds  = errors.New("房間盲注設定無效")
+	ErrPlayerNotFound = errors.New("玩家不存在")
+	ErrPlayerInactive = errors.New("玩家帳號無法入座")
+)
+
+// 牌桌帶入籌碼範圍（以大盲倍數計）
+const (
+	tableMinBuyInBlinds = 20
+	tableMaxBuyInBlinds = 200
+)
+
+// TableStore 即時牌桌的資料存取（實作 table.Store）
+type TableStore struct {
+	DB       *sql.DB
+	Wallet   *WalletService
+	Holds    *HoldService
+	Bonus    *BonusService
+	Fairness *FairnessService
+	AI       *AIService
+	History  *HandHistoryService
+}
+
+// NewTableStore 建立新的牌桌資料存取
+func NewTableStore() *TableStore {
+	bonus := NewBonusService()
+	wallet := bonus.Wallet
+	return &TableStore{
+		DB:       config.GetDB(),
+		Wallet:   wallet,
+		Holds:    &HoldService{DB: wallet.DB, Wallet: wallet},
+		Bonus:    bonus,
+		Fairness: NewFairnessService(),
+		AI:       NewAIService(),
+		History:  NewHandHistoryService(),
+	}
+}
+
+// LoadRoom 讀取房間設定並組合牌局規則
+func (s *TableStore) LoadRoom(roomID int64) (*table.RoomInfo, error) {
+	room := &table.RoomInfo{RoomID: roomID}
+	var (
+		status     string
+		difficulty string
+		houseEdge  float64
+	)
+	err := s.DB.QueryRow(`
+		SELECT gr.room_code, gr.name, gr.game_id, g.game_type, gr.max_players,
+		       COALESCE(gr.min_bet, g.min_bet), COALESCE(gr.max_bet, g.max_bet), COALESCE(gr.currency, ''),
+		       gr.status, gr.ai_enabled, gr.ai_difficulty, g.house_edge
+		FROM game_rooms gr
+		JOIN games g ON gr.game_id = g.id
+		WHERE gr.id = ?
+	`, roomID).Scan(&room.RoomCode, &room.Name, &room.GameID, &room.GameType, &room.MaxPlayers,
+		&room.MinBet, &room.MaxBet, &room.Currency, &status, &room.AIEnabled, &difficulty, &houseEdge)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoomNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if status != "active" && status != "full" {
+		return nil, ErrRoomInactive
+	}
+	if room.GameType != ai.GameTexasHoldem && room.GameType != ai.GameStudPoker {
+		return nil, ErrRoomNotPoker
+	}
+	room.Currency = s.Wallet.CurrencyOf(room.Currency)
+	room.AIDifficulty = ai.Difficulty(difficulty)
+	if config.AppConfig != nil && !config.AppConfig.Game.AIEnabled {
+		room.AIEnabled = false
+	}
+
+	rules := poker.Rules{Variant: poker.Variant(room.GameType), RakeRate: houseEdge}
+	switch rules.Variant {
+	case poker.TexasHoldem:
+		var blinds struct {
+			SmallBlind float64 `json:"small_blind"`
+			BigBlind   float64 `json:"big_blind"`
+		}
+		if ok, err := s.gameConfig(room.GameID, "blind_structure", &blinds); err != nil {
+			return nil, err
+		} else if ok && blinds.BigBlind > 0 {
+			rules.SmallBlind, rules.BigBlind = blinds.SmallBlind, blinds.BigBlind
+		} else {
+			rules.SmallBlind, rules.BigBlind = room.MinBet/2, room.MinBet
+		}
+	case poker.FiveCardStud:
+		var ante struct {
+			Value float64 `json:"value"`
+		}
+		if _, err := s.gameConfig(room.GameID, "ante_amount", &ante); err != nil {
+			return nil, err
+		}
+		rules.BigBlind, rules.Ante = room.MinBet, ante.Value
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, ErrInvalidBlinds
+	}
+	room.Rules = rules
+	room.MinBuyIn = rules.BigBlind * tableMinBuyInBlinds
+	room.MaxBuyIn = rules.BigBlind * tableMaxBuyInBlinds
+
+	if room.AIEnabled {
+		params, err := s.AI.LoadParams(room.GameID)
+		if err != nil {
+			return nil, err
+		}
+		room.AIParams = params
+	}
+	return room, nil
+}
+
+// BuyIn 入座時依紅利動用順序扣用紅利，其餘自房間幣別錢包的可用餘額凍結（離座時依剩餘籌碼結清）
+func (s *TableStore) BuyIn(room *table.RoomInfo, playerID int64, amount float64) error {
+	if err := s.checkPlayer(playerID); err != nil {
+		return err
+	}
+
+	total := money.FromFloat(amount)
+	err := s.Holds.withTx(func(tx *sql.Tx) error {
+		bonus, err := s.Bonus.StakeTx(tx, playerID, room.Currency, total, HoldRefTable, room.RoomCode)
+		if err != nil {
+			return err
+		}
+		if cash := total - bonus; cash.IsPositive() {
+			if _, err := s.Holds.PlaceTx(tx, HoldRequest{
+				PlayerID:      playerID,
+				Currency:      room.Currency,
+				Amount:        cash,
+				Reason:        HoldReasonTableBuyIn,
+				ReferenceType: HoldRefTable,
+				ReferenceID:   room.RoomCode,
+			}); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(`
+			INSERT INTO table_stack_snapshots (room_id, room_code, player_id, currency, stack)
+			VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE stack = stack + VALUES(stack)
+		`, room.RoomID, room.RoomCode, playerID, room.Currency, total)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec("UPDATE game_rooms SET current_players = current_players + 1 WHERE id = ?", room.RoomID)
+	return err
+}
+
+// checkPlayer 確認玩家存在且可入座
+func (s *TableStore) checkPlayer(playerID int64) error {
+	var status string
+	err := s.DB.QueryRow("SELECT status FROM players WHERE id = ?", playerID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrPlayerNotFound
+	} else if err != nil {
+		return err
+	}
+	if status != "active" {
+		return ErrPlayerInactive
+	}
+	return nil
+}
+
+// CashOut 離座時依帶入時現金與紅利的比例分配剩餘籌碼：現金部分結清帶入凍結（輸的部分扣款、贏的部分入帳），
+// 紅利部分返還紅利餘額
+func (s *TableStore) CashOut(room *table.RoomInfo, playerID int64, amount float64) error {
+	final := money.FromFloat(amount)
+	debit := WalletEntry{
+		Type:          "bet",
+		ReferenceID:   room.RoomCode,
+		ReferenceType: "table_buy_in",
+		Description:   fmt.Sprintf("牌桌輸分結清（%s）", room.Name),
+	}
+	credit := WalletEntry{
+		Type:          "win",
+		ReferenceID:   room.RoomCode,
+		ReferenceType: "table_cash_out",
+		Description:   fmt.Sprintf("牌桌結清籌碼（%s）", room.Name),
+	}
+
+	hold, err := s.Holds.ActiveByReference(playerID, HoldRefTable, room.RoomCode)
+	if err != nil && !errors.Is(err, ErrHoldNotFound) {
+		return err
+	}
+	err = s.Holds.withTx(func(tx *sql.Tx) error {
+		stakes, err := s.Bonus.OpenStakesTx(tx, playerID, HoldRefTable, room.RoomCode)
+		if err != nil {
+			return err
+		}
+		if hold == nil && len(stakes) == 0 {
+			return ErrHoldNotFound
+		}
+		var cash money.Amount
+		if hold != nil {
+			cash = hold.Amount
+		}
+		cashFinal, shares := stakes.Split(final, cash)
+		if hold != nil {
+			if _, err := s.Holds.SettleTx(tx, hold.HoldCode, cashFinal, debit, credit); err != nil {
+				return err
+			}
+		}
+		if err := s.Bonus.SettleStakesTx(tx, playerID, stakes, shares, HoldRefTable, room.RoomCode); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM table_stack_snapshots WHERE room_code = ? AND player_id = ?", room.RoomCode, playerID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec("UPDATE game_rooms SET current_players = GREATEST(current_players - 1, 0) WHERE id = ?", room.RoomID)
+	return err
+}
+
+// PrepareHand 建立場次、寫入真人座位、承諾伺服器種子，並視需要以 AI 補位
+func (s *TableStore) PrepareHand(plan *table.HandPlan) (*table.PreparedHand, error) {
+	room := plan.Room
+	bots := make([]BotSeat, 0, len(plan.Bots))
+	for _, b := range plan.Bots {
+		bots = append(bots, BotSeat(b))
+	}
+	botData, err := json.Marshal(bots)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 錦標賽場次於 game_data 記錄所屬錦標賽、桌號與盲注級別
+	sessionType, gameData := "normal", "{}"
+	switch {
+	case room.Tournament != nil:
+		data, err := json.Marshal(map[string]*table.TableTag{"tournament": room.Tournament})
+		if err != nil {
+			return nil, err
+		}
+		sessionType, gameData = "tournament", string(data)
+	case room.Practice:
+		sessionType = "practice"
+	}
+
+	code := newSessionCode(room.RoomCode)
+	res, err := tx.Exec(`
+		INSERT INTO game_sessions
+			(session_code, room_id, game_id, session_type, status, max_players, current_players,
+			 min_bet, max_bet, game_data, ai_players)
+		VALUES (?, ?, ?, ?, 'waiting', ?, ?, ?, ?, ?, ?)
+	`, code, room.RoomID, room.GameID, sessionType, room.MaxPlayers, len(plan.Humans),
+		room.MinBet, room.MaxBet, gameData, string(botData))
+	if err != nil {
+		return nil, fmt.Errorf("建立場次失敗: %v", err)
+	}
+	sessionID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range plan.Humans {
+		if _, err := tx.Exec(`
+			INSERT INTO game_participations (session_id, player_id, seat_number, initial_chips, final_chips)
+			VALUES (?, ?, ?, ?, ?)
+		`, sessionID, h.PlayerID, h.Seat, h.Stack, h.Stack); err != nil {
+			return nil, fmt.Errorf("寫入參與記錄失敗: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	commitment, err := s.Fairness.Commit(sessionID, rng.OutcomeSpec{Type: rng.OutcomeDeck})
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.FillBots {
+		filled, err := s.AI.FillSeats(sessionID)
+		switch {
+		case errors.Is(err, ErrAIDisabled), errors.Is(err, ErrAIUnsupported):
+		case err != nil:
+			return nil, err
+		default:
+			bots, err = s.dropReserved(sessionID, filled, plan.Reserved)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	prepared := &table.PreparedHand{
+		SessionID:   sessionID,
+		SessionCode: code,
+		SeedHash:    commitment.ServerSeedHash,
+	}
+	for _, b := range bots {
+		prepared.Bots = append(prepared.Bots, table.BotInfo(b))
+	}
+	return prepared, nil
+}
+
+// dropReserved 移除補到保留座位上的 AI 並更新 ai_players
+func (s *TableStore) dropReserved(sessionID int64, bots []BotSeat, reserved []int) ([]BotSeat, error) {
+	if len(reserved) == 0 {
+		return bots, nil
+	}
+	skip := make(map[int]bool, len(reserved))
+	for _, seat := range reserved {
+		skip[seat] = true
+	}
+	kept := make([]BotSeat, 0, len(bots))
+	for _, b := range bots {
+		if !skip[b.Seat] {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == len(bots) {
+		return bots, nil
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := saveBotSeats(tx, sessionID, kept); err != nil {
+		return nil, err
+	}
+	return kept, tx.Commit()
+}
+
+// Deck 加入客戶端種子後以承諾的種子產生本手牌序，並將場次標記為進行中
+func (s *TableStore) Deck(sessionID int64, clientSeeds []string) ([]cards.Card, error) {
+	for _, seed := range clientSeeds {
+		if _, err := s.Fairness.AddClientSeed(sessionID, seed); err != nil {
+			return nil, err
+		}
+	}
+	c, err := s.Fairness.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	outcome, err := rng.ComputeOutcome(c.Stream(), c.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.DB.Exec(`
+		UPDATE game_sessions SET status = 'playing', started_at = NOW() WHERE id = ?
+	`, sessionID); err != nil {
+		return nil, err
+	}
+	return cards.FromIndexes(outcome.Values), nil
+}
+
+// handSummary 寫入 game_sessions.game_data.result 的牌局摘要
+type handSummary struct {
+	Rules  poker.Rules        `json:"rules"`
+	Button int                `json:"button"`
+	Board  []cards.Card       `json:"board,omitempty"`
+	Seats  []table.SeatResult `json:"seats"`
+	Result *poker.Result      `json:"result"`
+}
+
+// FinishHand 揭露伺服器種子，於同一交易寫入真人結果、場次摘要、不可變的牌局歷史與紅利流水，再記錄 AI 結果
+func (s *TableStore) FinishHand(record *table.HandRecord) (string, error) {
+	summary, err := json.Marshal(handSummary{
+		Rules:  record.Rules,
+		Button: record.Button,
+		Board:  record.Board,
+		Seats:  record.Seats,
+		Result: record.Result,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	commitment, err := s.Fairness.Reveal(record.SessionID)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var botResults []BotResult
+	for _, r := range record.Seats {
+		if r.BotID != "" {
+			botResults = append(botResults, BotResult{
+				BotID:        r.BotID,
+				Seat:         r.Seat,
+				Difficulty:   r.Difficulty,
+				Strategy:     r.Strategy,
+				InitialChips: r.StartStack,
+				FinalChips:   r.EndStack,
+				TotalBet:     r.Committed,
+				TotalWin:     r.Won,
+				HandCategory: r.Hand,
+				Folded:       r.Folded,
+			})
+			continue
+		}
+		if _, err := tx.Exec(`
+			UPDATE game_participations
+			SET final_chips = ?, total_bet = ?, total_win = ?, status = 'finished', leave_time = NOW()
+			WHERE session_id = ? AND player_id = ?
+		`, r.EndStack, r.Committed, r.Won, record.SessionID, r.PlayerID); err != nil {
+			return "", fmt.Errorf("更新參與記錄失敗: %v", err)
+		}
+		if err := recordPlayerSession(tx, record, r); err != nil {
+			return "", err
+		}
+	}
+	for _, playerID := range record.Left {
+		if _, err := tx.Exec(`
+			UPDATE game_participations SET status = 'left', leave_time = NOW()
+			WHERE session_id = ? AND player_id = ?
+		`, record.SessionID, playerID); err != nil {
+			return "", err
+		}
+	}
+
+	rake := 0.0
+	if record.Result != nil {
+		rake = record.Result.Rake
+	}
+	if _, err := tx.Exec(`
+		UPDATE game_sessions
+		SET status = 'finished', finished_at = NOW(), total_pot = ?, house_commission = ?,
+		    game_data = JSON_SET(COALESCE(game_data, JSON_OBJECT()), '$.result', CAST(? AS JSON))
+		WHERE id = ?
+	`, record.Pot, rake, string(summary), record.SessionID); err != nil {
+		return "", fmt.Errorf("更新場次失敗: %v", err)
+	}
+
+	if hist := record.History; hist != nil {
+		if hist.Currency == "" {
+			hist.Currency = s.Wallet.Currency
+		}
+		hist.Fairness = poker.HistoryFairness{
+			Algorithm:      commitment.Algorithm,
+			ServerSeedHash: commitment.ServerSeedHash,
+			ServerSeed:     commitment.ServerSeed,
+			ClientSeeds:    commitment.ClientSeeds,
+			Nonce:          commitment.Nonce,
+		}
+		if _, err := s.History.Record(tx, hist); err != nil {
+			return "", err
+		}
+	}
+	if err := s.recordWagers(tx, record); err != nil {
+		return "", err
+	}
+	if err := s.saveStacks(tx, record); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	if err := s.AI.RecordResults(record.SessionID, botResults); err != nil {
+		return "", err
+	}
+	return commitment.ServerSeed, nil
+}
+
+// recordWagers 真錢場次中真人玩家的下注額計入紅利流水（練習場與錦標賽場次不計）
+func (s *TableStore) recordWagers(tx *sql.Tx, record *table.HandRecord) error {
+	var gameType, sessionType, sessionCode string
+	if err := tx.QueryRow(`
+		SELECT g.game_type, gs.session_type, gs.session_code
+		FROM game_sessions gs
+		JOIN games g ON gs.game_id = g.id
+		WHERE gs.id = ?
+	`, record.SessionID).Scan(&gameType, &sessionType, &sessionCode); err != nil {
+		return err
+	}
+	if sessionType != "normal" {
+		return nil
+	}
+	currency := s.Wallet.Currency
+	if record.History != nil && record.History.Currency != "" {
+		currency = record.History.Currency
+	}
+	for _, r := range record.Seats {
+		if r.BotID != "" || r.Committed <= 0 {
+			continue
+		}
+		if err := s.Bonus.RecordWagerTx(tx, Wager{
+			PlayerID:      r.PlayerID,
+			Currency:      currency,
+			GameType:      gameType,
+			Amount:        money.FromFloat(r.Committed),
+			ReferenceType: "game_session",
+			ReferenceID:   sessionCode,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveStacks 真錢場次結束時更新真人玩家的籌碼快照（練習場與錦標賽場次沒有帶入凍結，不記錄）
+func (s *TableStore) saveStacks(tx *sql.Tx, record *table.HandRecord) error {
+	var sessionType, roomCode string
+	if err := tx.QueryRow(`
+		SELECT gs.session_type, gr.room_code
+		FROM game_sessions gs
+		JOIN game_rooms gr ON gs.room_id = gr.id
+		WHERE gs.id = ?
+	`, record.SessionID).Scan(&sessionType, &roomCode); err != nil {
+		return err
+	}
+	if sessionType != "normal" {
+		return nil
+	}
+	for _, r := range record.Seats {
+		if r.BotID != "" {
+			continue
+		}
+		if _, err := tx.Exec(`
+			UPDATE table_stack_snapshots SET stack = ?, session_id = ?
+			WHERE room_code = ? AND player_id = ?
+		`, money.FromFloat(r.EndStack), record.SessionID, roomCode, r.PlayerID); err != nil {
+			return fmt.Errorf("更新籌碼快照失敗: %v", err)
+		}
+	}
+	return nil
+}
+
+// RecoverStacks 重新啟動時結清遺留的牌桌帶入：有籌碼快照的依最後一手結束時的籌碼結清
+// （進行中未完成的一手視為作廢，投入的籌碼返還）；沒有快照的凍結與紅利帶入全額返還並逐筆記錄供人工覆核。
+func (s *TableStore) RecoverStacks() (settled, released int, err error) {
+	type snapshot struct {
+		room   table.RoomInfo
+		player int64
+		stack  money.Amount
+		hand   sql.NullInt64
+	}
+	if s.DB == nil {
+		return 0, 0, ErrDatabaseUnavailable
+	}
+	rows, err := s.DB.Query(`
+		SELECT ts.room_id, ts.room_code, gr.name, ts.currency, ts.player_id, ts.stack, ts.session_id
+		FROM table_stack_snapshots ts
+		JOIN game_rooms gr ON ts.room_id = gr.id
+		ORDER BY ts.id
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	var snapshots []snapshot
+	for rows.Next() {
+		var sn snapshot
+		if err := rows.Scan(&sn.room.RoomID, &sn.room.RoomCode, &sn.room.Name, &sn.room.Currency,
+			&sn.player, &sn.stack, &sn.hand); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		snapshots = append(snapshots, sn)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, sn := range snapshots {
+		err := s.CashOut(&sn.room, sn.player, sn.stack.Float64())
+		if errors.Is(err, ErrHoldNotFound) {
+			_, err = s.DB.Exec("DELETE FROM table_stack_snapshots WHERE room_code = ? AND player_id = ?", sn.room.RoomCode, sn.player)
+		}
+		if err != nil {
+			return settled, released, fmt.Errorf("依籌碼快照結清玩家 %d（房間 %s）失敗: %v", sn.player, sn.room.RoomCode, err)
+		}
+		log.Printf("重新啟動結清牌桌帶入：玩家 %d 房間 %s 籌碼 %s %s（最後場次 %d）",
+			sn.player, sn.room.RoomCode, sn.stack, sn.room.Currency, sn.hand.Int64)
+		settled++
+	}
+
+	if released, err = s.Holds.ReleaseByReferenceType(HoldRefTable); err != nil {
+		return settled, released, err
+	}
+	n, err := s.Bonus.ReturnOpenStakes(HoldRefTable)
+	if n > 0 {
+		log.Printf("重新啟動返還 %d 筆沒有籌碼快照的牌桌紅利帶入（全額返還，請人工覆核）", n)
+	}
+	return settled, released + n, err
+}
+
+// recordPlayerSession 將真人玩家本手結果寫入玩家遊戲會話分析表（練習場以 is_practice 標記）
+func recordPlayerSession(tx *sql.Tx, record *table.HandRecord, r table.SeatResult) error {
+	bets := 0
+	if record.History != nil {
+		for _, a := range record.History.Actions {
+			if a.Seat == r.Seat && a.Action.Type != poker.ActionFold && a.Action.Type != poker.ActionCheck {
+				bets++
+			}
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO player_game_sessions
+			(player_id, game_id, game_type, session_id, start_time, end_time, session_duration,
+			 total_bets, total_bet_amount, total_win_amount, max_bet_amount, min_bet_amount,
+			 is_completed, is_practice)
+		SELECT ?, gs.game_id, g.game_type, gs.session_code, COALESCE(gs.started_at, gs.created_at), NOW(),
+		       TIMESTAMPDIFF(SECOND, COALESCE(gs.started_at, gs.created_at), NOW()),
+		       ?, ?, ?, ?, ?, TRUE, gs.session_type = 'practice'
+		FROM game_sessions gs
+		JOIN games g ON gs.game_id = g.id
+		WHERE gs.id = ?
+	`, r.PlayerID, bets, r.Committed, r.Won, r.Committed, r.Committed, record.SessionID); err != nil {
+		return fmt.Errorf("寫入玩家遊戲會話失敗: %v", err)
+	}
+	return nil
+}
+
+// AbortHand 取消未開始的場次
+func (s *TableStore) AbortHand(sessionID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE game_sessions SET status = 'cancelled', finished_at = NOW()
+		WHERE id = ? AND status IN ('waiting', 'playing')
+	`, sessionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE game_participations SET status = 'left', leave_time = NOW()
+		WHERE session_id = ? AND status = 'playing'
+	`, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// gameConfig 讀取啟用中的遊戲設定；未設定時回傳 false
+func (s *TableStore) gameConfig(gameID int, key string, dest interface{}) (bool, error) {
+	var raw []byte
+	err := s.DB.QueryRow(`
+		SELECT config_value FROM game_configs
+		WHERE game_id = ? AND config_key = ? AND is_active = TRUE
+	`, gameID, key).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return false, fmt.Errorf("遊戲設定 %s 格式錯誤: %v", key, err)
+	}
+	return true, nil
+}
+
+// newSessionCode 產生場次代碼（房間代碼 + 時間 + 亂數）
+func newSessionCode(roomCode string) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return strings.ToUpper(roomCode) + "-" + time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,758 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/engine/tournament"
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/table"
+)
+
+// 錦標賽相關錯誤
+var (
+	ErrTournamentNotFound     = errors.New("錦標賽不存在")
+	ErrTournamentClosed       = errors.New("錦標賽已結束或已取消")
+	ErrRegistrationClosed     = errors.New("錦標賽已停止報名")
+	ErrTournamentFull         = errors.New("錦標賽報名人數已滿")
+	ErrAlreadyRegistered      = errors.New("玩家已報名此錦標賽")
+	ErrNotRegistered          = errors.New("玩家未報名此錦標賽")
+	ErrInvalidTournament      = errors.New("錦標賽設定無效")
+	ErrTournamentEntryChanged = errors.New("報名名單已變更，請重新開賽")
+)
+
+// 錦標賽設定預設值與範圍
+const (
+	defaultSeatsPerTable = 9
+	minSeatsPerTable     = 2
+	maxSeatsPerTable     = 10
+	defaultMaxEntrants   = 180
+)
+
+// TournamentDefinition 建立錦標賽的設定
+type TournamentDefinition struct {
+	Name            string                     `json:"name"`
+	RoomID          int64                      `json:"room_id"`
+	BuyIn           float64                    `json:"buy_in"`
+	Fee             float64                    `json:"fee"`
+	StartingStack   float64                    `json:"starting_stack"`
+	SeatsPerTable   int                        `json:"seats_per_table"`
+	MinEntrants     int                        `json:"min_entrants"`
+	MaxEntrants     int                        `json:"max_entrants"`
+	BlindSchedule   tournament.Schedule        `json:"blind_schedule"`
+	PayoutStructure tournament.PayoutStructure `json:"payout_structure"`
+	LateRegLevels   int                        `json:"late_reg_levels"`
+	StartsAt        time.Time                  `json:"starts_at"`
+}
+
+// Tournament 錦標賽
+type Tournament struct {
+	ID             int64  `json:"id"`
+	TournamentCode string `json:"tournament_code"`
+	GameType       string `json:"game_type"`
+	TournamentDefinition
+	CurrentLevel int                     `json:"current_level"`
+	Status       string                  `json:"status"`
+	Entrants     int                     `json:"entrants"`
+	PrizePool    float64                 `json:"prize_pool"`
+	StartedAt    *time.Time              `json:"started_at,omitempty"`
+	FinishedAt   *time.Time              `json:"finished_at,omitempty"`
+	CreatedAt    time.Time               `json:"created_at"`
+	Entries      []TournamentEntryRecord `json:"entries,omitempty"`
+}
+
+// TournamentEntryRecord 參賽紀錄
+type TournamentEntryRecord struct {
+	PlayerID       int64     `json:"player_id"`
+	Username       string    `json:"username"`
+	Status         string    `json:"status"`
+	Chips          float64   `json:"chips"`
+	TableNo        *int      `json:"table_no,omitempty"`
+	Seat           *int      `json:"seat,omitempty"`
+	FinishPosition *int      `json:"finish_position,omitempty"`
+	Prize          float64   `json:"prize"`
+	RegisteredAt   time.Time `json:"registered_at"`
+}
+
+// TournamentService 錦標賽服務（定義、報名與 table.TournamentStore 實作）
+type TournamentService struct {
+	*TableStore
+}
+
+// NewTournamentService 建立新的錦標賽服務
+func NewTournamentService() *TournamentService {
+	return &TournamentService{TableStore: NewTableStore()}
+}
+
+// Create 建立錦標賽（開放報名）
+func (s *TournamentService) Create(def TournamentDefinition, createdBy int) (*Tournament, error) {
+	if def.SeatsPerTable == 0 {
+		def.SeatsPerTable = defaultSeatsPerTable
+	}
+	if def.MinEntrants == 0 {
+		def.MinEntrants = 2
+	}
+	if def.MaxEntrants == 0 {
+		def.MaxEntrants = defaultMaxEntrants
+	}
+	if len(def.PayoutStructure) == 0 {
+		def.PayoutStructure = tournament.DefaultPayouts
+	}
+	if err := validateDefinition(def); err != nil {
+		return nil, err
+	}
+	if _, err := s.LoadRoom(def.RoomID); err != nil {
+		return nil, err
+	}
+
+	schedule, _ := json.Marshal(def.BlindSchedule)
+	payouts, _ := json.Marshal(def.PayoutStructure)
+	res, err := s.DB.Exec(`
+		INSERT INTO tournaments
+			(tournament_code, name, room_id, buy_in, fee, starting_stack, seats_per_table, min_entrants,
+			 max_entrants, blind_schedule, payout_structure, late_reg_levels, starts_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, newTournamentCode(), def.Name, def.RoomID, def.BuyIn, def.Fee, def.StartingStack, def.SeatsPerTable,
+		def.MinEntrants, def.MaxEntrants, string(schedule), string(payouts), def.LateRegLevels, def.StartsAt, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("建立錦標賽失敗: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// validateDefinition 驗證錦標賽設定
+func validateDefinition(def TournamentDefinition) error {
+	invalid := func(msg string) error { return fmt.Errorf("%w: %s", ErrInvalidTournament, msg) }
+	switch {
+	case strings.TrimSpace(def.Name) == "":
+		return invalid("名稱不可為空")
+	case def.BuyIn <= 0 || def.Fee < 0:
+		return invalid("報名費必須大於 0，服務費不可為負數")
+	case def.StartingStack <= 0:
+		return invalid("起始籌碼必須大於 0")
+	case def.SeatsPerTable < minSeatsPerTable || def.SeatsPerTable > maxSeatsPerTable:
+		return invalid(fmt.Sprintf("每桌座位數必須介於 %d 到 %d", minSeatsPerTable, maxSeatsPerTable))
+	case def.MinEntrants < 2 || def.MaxEntrants < def.MinEntrants:
+		return invalid("參賽人數範圍無效")
+	case def.LateRegLevels < 0 || def.LateRegLevels > len(def.BlindSchedule):
+		return invalid("延遲報名級別數無效")
+	case def.StartsAt.IsZero():
+		return invalid("必須指定開賽時間")
+	}
+	if err := def.BlindSchedule.Validate(); err != nil {
+		return invalid(err.Error())
+	}
+	if err := def.PayoutStructure.Validate(); err != nil {
+		return invalid(err.Error())
+	}
+	return nil
+}
+
+// tournamentColumns 查詢錦標賽的欄位（搭配 scanTournament）
+const tournamentColumns = `
+	t.id, t.tournament_code, t.name, t.room_id, g.game_type, t.buy_in, t.fee, t.starting_stack,
+	t.seats_per_table, t.min_entrants, t.max_entrants, t.blind_schedule, t.payout_structure,
+	t.late_reg_levels, t.current_level, t.status, t.entrants, t.prize_pool, t.starts_at,
+	t.started_at, t.finished_at, t.created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTournament(row rowScanner) (*Tournament, error) {
+	var (
+		t                 Tournament
+		schedule, payouts []byte
+		started, finished sql.NullTime
+	)
+	err := row.Scan(&t.ID, &t.TournamentCode, &t.Name, &t.RoomID, &t.GameType, &t.BuyIn, &t.Fee, &t.StartingStack,
+		&t.SeatsPerTable, &t.MinEntrants, &t.MaxEntrants, &schedule, &payouts,
+		&t.LateRegLevels, &t.CurrentLevel, &t.Status, &t.Entrants, &t.PrizePool, &t.StartsAt,
+		&started, &finished, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(schedule, &t.BlindSchedule); err != nil {
+		return nil, fmt.Errorf("盲注級別表格式錯誤: %v", err)
+	}
+	if err := json.Unmarshal(payouts, &t.PayoutStructure); err != nil {
+		return nil, fmt.Errorf("獎金結構格式錯誤: %v", err)
+	}
+	if started.Valid {
+		t.StartedAt = &started.Time
+	}
+	if finished.Valid {
+		t.FinishedAt = &finished.Time
+	}
+	return &t, nil
+}
+
+// List 列出錦標賽（可依狀態篩選）
+func (s *TournamentService) List(status string) ([]Tournament, error) {
+	query := "SELECT " + tournamentColumns + " FROM tournaments t JOIN game_rooms gr ON t.room_id = gr.id JOIN games g ON gr.game_id = g.id"
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE t.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY t.starts_at DESC"
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Tournament{}
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *t)
+	}
+	return list, rows.Err()
+}
+
+// Get 取得錦標賽與參賽紀錄（名次優先，其次籌碼）
+func (s *TournamentService) Get(id int64) (*Tournament, error) {
+	t, err := scanTournament(s.DB.QueryRow("SELECT "+tournamentColumns+`
+		FROM tournaments t JOIN game_rooms gr ON t.room_id = gr.id JOIN games g ON gr.game_id = g.id
+		WHERE t.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTournamentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT te.player_id, p.username, te.status, te.chips, te.table_no, te.seat_number,
+		       te.finish_position, te.prize, te.registered_at
+		FROM tournament_entries te
+		JOIN players p ON te.player_id = p.id
+		WHERE te.tournament_id = ?
+		ORDER BY te.finish_position IS NULL DESC, te.chips DESC, te.finish_position ASC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			e                    TournamentEntryRecord
+			tableNo, seat, place sql.NullInt64
+		)
+		if err := rows.Scan(&e.PlayerID, &e.Username, &e.Status, &e.Chips, &tableNo, &seat,
+			&place, &e.Prize, &e.RegisteredAt); err != nil {
+			return nil, err
+		}
+		e.TableNo, e.Seat, e.FinishPosition = nullInt(tableNo), nullInt(seat), nullInt(place)
+		t.Entries = append(t.Entries, e)
+	}
+	return t, rows.Err()
+}
+
+// Register 報名錦標賽並自錢包扣除報名費與服務費；開賽後於延遲報名級別內仍可報名
+//
+// 回傳新參賽者（供進行中的錦標賽安排座位）以及是否為延遲報名。
+func (s *TournamentService) Register(id, playerID int64) (*table.TournamentEntry, bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var (
+		code, name, status          string
+		buyIn, fee, stack           float64
+		maxEntrants, entrants       int
+		currentLevel, lateRegLevels int
+	)
+	err = tx.QueryRow(`
+		SELECT tournament_code, name, status, buy_in, fee, starting_stack, max_entrants, entrants,
+		       current_level, late_reg_levels
+		FROM tournaments WHERE id = ? FOR UPDATE
+	`, id).Scan(&code, &name, &status, &buyIn, &fee, &stack, &maxEntrants, &entrants, &currentLevel, &lateRegLevels)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrTournamentNotFound
+	} else if err != nil {
+		return nil, false, err
+	}
+	late := false
+	switch status {
+	case "registering":
+	case "running", "paused":
+		if currentLevel >= lateRegLevels {
+			return nil, false, ErrRegistrationClosed
+		}
+		late = true
+	default:
+		return nil, false, ErrRegistrationClosed
+	}
+	if entrants >= maxEntrants {
+		return nil, false, ErrTournamentFull
+	}
+
+	var username, playerStatus string
+	err = tx.QueryRow("SELECT username, status FROM players WHERE id = ?", playerID).Scan(&username, &playerStatus)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrPlayerNotFound
+	} else if err != nil {
+		return nil, false, err
+	}
+	if playerStatus != "active" {
+		return nil, false, ErrPlayerInactive
+	}
+
+	var entryStatus string
+	err = tx.QueryRow(`
+		SELECT status FROM tournament_entries WHERE tournament_id = ? AND player_id = ? FOR UPDATE
+	`, id, playerID).Scan(&entryStatus)
+	if err == nil && entryStatus != "unregistered" {
+		return nil, false, ErrAlreadyRegistered
+	} else if err != nil && err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	txn, err := s.Wallet.DebitTx(tx, WalletEntry{
+		PlayerID:      playerID,
+		Type:          "bet",
+		Amount:        money.FromFloat(buyIn) + money.FromFloat(fee),
+		ReferenceID:   code,
+		ReferenceType: "tournament_buy_in",
+		Description:   fmt.Sprintf("錦標賽報名（%s）", name),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	entryState := "registered"
+	if late {
+		entryState = "playing"
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO tournament_entries (tournament_id, player_id, status, chips, buy_in_transaction_id)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE status = VALUES(status), chips = VALUES(chips),
+			buy_in_transaction_id = VALUES(buy_in_transaction_id), registered_at = NOW(),
+			table_no = NULL, seat_number = NULL, finish_position = NULL, prize = 0
+	`, id, playerID, entryState, stack, txn.TransactionID); err != nil {
+		return nil, false, fmt.Errorf("寫入報名紀錄失敗: %v", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE tournaments SET entrants = entrants + 1, prize_pool = prize_pool + ? WHERE id = ?
+	`, buyIn, id); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return &table.TournamentEntry{PlayerID: playerID, Name: username, Stack: stack}, late, nil
+}
+
+// Unregister 開賽前取消報名並退還報名費與服務費
+func (s *TournamentService) Unregister(id, playerID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		code, name, status string
+		buyIn, fee         float64
+	)
+	err = tx.QueryRow(`
+		SELECT tournament_code, name, status, buy_in, fee FROM tournaments WHERE id = ? FOR UPDATE
+	`, id).Scan(&code, &name, &status, &buyIn, &fee)
+	if err == sql.ErrNoRows {
+		return ErrTournamentNotFound
+	} else if err != nil {
+		return err
+	}
+	if status != "registering" {
+		return ErrRegistrationClosed
+	}
+	res, err := tx.Exec(`
+		UPDATE tournament_entries SET status = 'unregistered'
+		WHERE tournament_id = ? AND player_id = ? AND status = 'registered'
+	`, id, playerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotRegistered
+	}
+	if _, err := s.Wallet.CreditTx(tx, WalletEntry{
+		PlayerID:      playerID,
+		Type:          "refund",
+		Amount:        money.FromFloat(buyIn) + money.FromFloat(fee),
+		ReferenceID:   code,
+		ReferenceType: "tournament_refund",
+		Description:   fmt.Sprintf("錦標賽取消報名退款（%s）", name),
+	}); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE tournaments SET entrants = entrants - 1, prize_pool = prize_pool - ? WHERE id = ?
+	`, buyIn, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ---- table.TournamentStore ----
+
+// LoadTournament 讀取錦標賽設定（房間規則由 LoadRoom 驗證）與尚未淘汰的參賽玩家
+func (s *TournamentService) LoadTournament(id int64) (*table.TournamentInfo, []table.TournamentEntry, error) {
+	t, err := scanTournament(s.DB.QueryRow("SELECT "+tournamentColumns+`
+		FROM tournaments t JOIN game_rooms gr ON t.room_id = gr.id JOIN games g ON gr.game_id = g.id
+		WHERE t.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrTournamentNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+	var elapsed int
+	if err := s.DB.QueryRow("SELECT level_elapsed FROM tournaments WHERE id = ?", id).Scan(&elapsed); err != nil {
+		return nil, nil, err
+	}
+	room, err := s.LoadRoom(t.RoomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	level := t.CurrentLevel
+	if level >= len(t.BlindSchedule) {
+		level = len(t.BlindSchedule) - 1
+	}
+	info := &table.TournamentInfo{
+		ID:            t.ID,
+		Code:          t.TournamentCode,
+		Name:          t.Name,
+		Room:          room,
+		Schedule:      t.BlindSchedule,
+		SeatsPerTable: t.SeatsPerTable,
+		MinEntrants:   t.MinEntrants,
+		Status:        t.Status,
+		Level:         level,
+		LevelElapsed:  time.Duration(elapsed) * time.Second,
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT te.player_id, p.username, te.chips, COALESCE(te.table_no, 0), COALESCE(te.seat_number, 0)
+		FROM tournament_entries te
+		JOIN players p ON te.player_id = p.id
+		WHERE te.tournament_id = ? AND te.status IN ('registered', 'playing')
+		ORDER BY te.id
+	`, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	entries := []table.TournamentEntry{}
+	for rows.Next() {
+		var e table.TournamentEntry
+		if err := rows.Scan(&e.PlayerID, &e.Name, &e.Stack, &e.TableNo, &e.Seat); err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, e)
+	}
+	return info, entries, rows.Err()
+}
+
+// StartTournament 將錦標賽標記為進行中並寫入初始座位（報名名單於讀取後變更時回傳錯誤）
+func (s *TournamentService) StartTournament(id int64, entries []table.TournamentEntry) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow("SELECT status FROM tournaments WHERE id = ? FOR UPDATE", id).Scan(&status); err != nil {
+		return err
+	}
+	if status != "registering" {
+		return table.ErrTournamentNotOpen
+	}
+	var registered int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM tournament_entries WHERE tournament_id = ? AND status = 'registered'
+	`, id).Scan(&registered); err != nil {
+		return err
+	}
+	if registered != len(entries) {
+		return ErrTournamentEntryChanged
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE tournaments SET status = 'running', started_at = NOW(), current_level = 0, level_elapsed = 0
+		WHERE id = ?
+	`, id); err != nil {
+		return err
+	}
+	if err := saveEntries(tx, id, entries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PendingTournaments 已到開賽時間的錦標賽與進行中的錦標賽
+func (s *TournamentService) PendingTournaments() ([]int64, []int64, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, status FROM tournaments
+		WHERE (status = 'registering' AND starts_at <= NOW()) OR status IN ('running', 'paused')
+		ORDER BY starts_at
+	`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var due, active []int64
+	for rows.Next() {
+		var (
+			id     int64
+			status string
+		)
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, nil, err
+		}
+		if status == "registering" {
+			due = append(due, id)
+		} else {
+			active = append(active, id)
+		}
+	}
+	return due, active, rows.Err()
+}
+
+// SaveLevel 保存目前級別、級別已進行秒數與暫停狀態
+func (s *TournamentService) SaveLevel(id int64, level int, elapsed time.Duration, paused bool) error {
+	status := "running"
+	if paused {
+		status = "paused"
+	}
+	_, err := s.DB.Exec(`
+		UPDATE tournaments SET current_level = ?, level_elapsed = ?, status = ?
+		WHERE id = ? AND status IN ('running', 'paused')
+	`, level, int(elapsed/time.Second), status, id)
+	return err
+}
+
+// SaveStacks 保存在場玩家的籌碼與座位
+func (s *TournamentService) SaveStacks(id int64, entries []table.TournamentEntry) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := saveEntries(tx, id, entries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func saveEntries(tx *sql.Tx, id int64, entries []table.TournamentEntry) error {
+	for _, e := range entries {
+		if _, err := tx.Exec(`
+			UPDATE tournament_entries SET status = 'playing', chips = ?, table_no = ?, seat_number = ?
+			WHERE tournament_id = ? AND player_id = ? AND status IN ('registered', 'playing')
+		`, e.Stack, e.TableNo, e.Seat, id, e.PlayerID); err != nil {
+			return fmt.Errorf("保存參賽紀錄失敗: %v", err)
+		}
+	}
+	return nil
+}
+
+// Eliminate 記錄淘汰名次
+func (s *TournamentService) Eliminate(id int64, placements []table.Placement) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, p := range placements {
+		if _, err := tx.Exec(`
+			UPDATE tournament_entries
+			SET status = 'busted', chips = 0, finish_position = ?, busted_at = NOW(), table_no = NULL, seat_number = NULL
+			WHERE tournament_id = ? AND player_id = ? AND status = 'playing'
+		`, p.Position, id, p.PlayerID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CompleteTournament 記錄冠軍並依獎金結構以 win 交易派發獎金
+func (s *TournamentService) CompleteTournament(id int64, winner int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		code, name, status string
+		payoutData         []byte
+		pool               float64
+		entrants           int
+	)
+	err = tx.QueryRow(`
+		SELECT tournament_code, name, status, payout_structure, prize_pool, entrants
+		FROM tournaments WHERE id = ? FOR UPDATE
+	`, id).Scan(&code, &name, &status, &payoutData, &pool, &entrants)
+	if err == sql.ErrNoRows {
+		return ErrTournamentNotFound
+	} else if err != nil {
+		return err
+	}
+	if status != "running" && status != "paused" {
+		return ErrTournamentClosed
+	}
+	var payouts tournament.PayoutStructure
+	if err := json.Unmarshal(payoutData, &payouts); err != nil {
+		return fmt.Errorf("獎金結構格式錯誤: %v", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE tournament_entries
+		SET status = 'finished', finish_position = 1, table_no = NULL, seat_number = NULL
+		WHERE tournament_id = ? AND player_id = ?
+	`, id, winner); err != nil {
+		return err
+	}
+
+	for i, prize := range payouts.Prizes(pool, entrants) {
+		var playerID int64
+		err := tx.QueryRow(`
+			SELECT player_id FROM tournament_entries WHERE tournament_id = ? AND finish_position = ?
+		`, id, i+1).Scan(&playerID)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return err
+		}
+		txn, err := s.Wallet.CreditTx(tx, WalletEntry{
+			PlayerID:      playerID,
+			Type:          "win",
+			Amount:        money.FromFloat(prize),
+			ReferenceID:   code,
+			ReferenceType: "tournament_prize",
+			Description:   fmt.Sprintf("錦標賽獎金第 %d 名（%s）", i+1, name),
+		})
+		if err != nil {
+			return fmt.Errorf("派發第 %d 名獎金失敗: %v", i+1, err)
+		}
+		if _, err := tx.Exec(`
+			UPDATE tournament_entries SET prize = ?, prize_transaction_id = ?
+			WHERE tournament_id = ? AND player_id = ?
+		`, prize, txn.TransactionID, id, playerID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE tournaments SET status = 'finished', finished_at = NOW() WHERE id = ?
+	`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CancelTournament 取消錦標賽，退還所有已付款參賽者（含已淘汰者）的報名費與服務費
+func (s *TournamentService) CancelTournament(id int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		code, name, status string
+		buyIn, fee         float64
+	)
+	err = tx.QueryRow(`
+		SELECT tournament_code, name, status, buy_in, fee FROM tournaments WHERE id = ? FOR UPDATE
+	`, id).Scan(&code, &name, &status, &buyIn, &fee)
+	if err == sql.ErrNoRows {
+		return ErrTournamentNotFound
+	} else if err != nil {
+		return err
+	}
+	if status == "finished" || status == "cancelled" {
+		return ErrTournamentClosed
+	}
+
+	rows, err := tx.Query(`
+		SELECT player_id FROM tournament_entries
+		WHERE tournament_id = ? AND status IN ('registered', 'playing', 'busted')
+	`, id)
+	if err != nil {
+		return err
+	}
+	var players []int64
+	for rows.Next() {
+		var playerID int64
+		if err := rows.Scan(&playerID); err != nil {
+			rows.Close()
+			return err
+		}
+		players = append(players, playerID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	refund := money.FromFloat(buyIn) + money.FromFloat(fee)
+	for _, playerID := range players {
+		if _, err := s.Wallet.CreditTx(tx, WalletEntry{
+			PlayerID:      playerID,
+			Type:          "refund",
+			Amount:        refund,
+			ReferenceID:   code,
+			ReferenceType: "tournament_refund",
+			Description:   fmt.Sprintf("錦標賽取消退款（%s）", name),
+		}); err != nil {
+			return fmt.Errorf("退款給玩家 %d 失敗: %v", playerID, err)
+		}
+		if _, err := tx.Exec(`
+			UPDATE tournament_entries SET status = 'refunded', chips = 0, table_no = NULL, seat_number = NULL
+			WHERE tournament_id = ? AND player_id = ?
+		`, id, playerID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE tournaments SET status = 'cancelled', finished_at = NOW() WHERE id = ?
+	`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+// newTournamentCode 產生錦標賽代碼
+func newTournamentCode() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return "MTT" + time.Now().Format("20060102") + strings.ToUpper(hex.EncodeToString(b))
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/vault"
+)
+
+// ErrVaultUnavailable 未設定主金鑰時無法寫入或讀取加密欄位
+var ErrVaultUnavailable = errors.New("敏感資料加密未設定，請設定 VAULT_MASTER_KEYS、VAULT_ACTIVE_KEY 與 VAULT_FINGERPRINT_KEY")
+
+var (
+	dataVault     *vault.Vault
+	dataVaultErr  error
+	dataVaultOnce sync.Once
+)
+
+// DataVault 共用的敏感資料加密器（主金鑰來自設定）
+func DataVault() (*vault.Vault, error) {
+	dataVaultOnce.Do(func() {
+		if config.AppConfig == nil || config.AppConfig.Vault.MasterKeys == "" {
+			dataVaultErr = ErrVaultUnavailable
+			return
+		}
+		cfg := config.AppConfig.Vault
+		keys, err := vault.ParseKeys(cfg.MasterKeys)
+		if err != nil {
+			dataVaultErr = fmt.Errorf("%w: %v", ErrVaultUnavailable, err)
+			return
+		}
+		if dataVault, err = vault.New(keys, cfg.ActiveKey, []byte(cfg.FingerprintKey)); err != nil {
+			dataVaultErr = fmt.Errorf("%w: %v", ErrVaultUnavailable, err)
+		}
+	})
+	return dataVault, dataVaultErr
+}
+
+// vaultColumn 以信封加密保存的欄位（欄位名稱即加密的附加資料）
+type vaultColumn struct {
+	Table  string
+	Column string
+}
+
+func (c vaultColumn) context() string {
+	return c.Table + "." + c.Column
+}
+
+// 加密欄位
+var (
+	columnPaymentMethodAccount = vaultColumn{Table: "payment_methods", Column: "account_info"}
+	columnWithdrawalAccount    = vaultColumn{Table: "withdrawals", Column: "account_info"}
+	columnAgentBankAccount     = vaultColumn{Table: "agents", Column: "bank_account"}
+	columnDealerBankAccount    = vaultColumn{Table: "dealers", Column: "bank_account"}
+)
+
+var vaultColumns = []vaultColumn{
+	columnPaymentMethodAccount,
+	columnWithdrawalAccount,
+	columnAgentBankAccount,
+	columnDealerBankAccount,
+}
+
+// sealJSON 加密 JSON 欄位值（空值保持 NULL）
+func sealJSON(col vaultColumn, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	v, err := DataVault()
+	if err != nil {
+		return nil, err
+	}
+	out, err := v.Encrypt(raw, col.context())
+	if err != nil {
+		return nil, err
+	}
+	return string(out), nil
+}
+
+// openJSON 解密 JSON 欄位值（尚未加密的舊資料原樣回傳）
+func openJSON(col vaultColumn, data []byte) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if !vault.IsEncrypted(data) {
+		return json.RawMessage(data), nil
+	}
+	v, err := DataVault()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := v.Decrypt(data, col.context())
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(plain), nil
+}
+
+// VaultKeyUsage 加密欄位各主金鑰的資料筆數（key_id 為空表示尚未加密）
+type VaultKeyUsage struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	KeyID  string `json:"key_id"`
+	Rows   int64  `json:"rows"`
+}
+
+// VaultRotation 金鑰輪替結果
+type VaultRotation struct {
+	ActiveKey string         `json:"active_key"`
+	Rewrapped map[string]int `json:"rewrapped"` // 欄位 → 重新包裝或補加密的筆數
+}
+
+// VaultService 加密欄位的金鑰輪替
+//
+// 輪替只以目前的主金鑰重新包裝各筆資料的資料金鑰；尚未加密的舊資料一併加密。
+// 以原值作為更新條件，與同時進行的寫入衝突時略過該筆，下次輪替再處理。
+type VaultService struct {
+	DB    *sql.DB
+	Batch int
+}
+
+// NewVaultService 建立新的金鑰輪替服務
+func NewVaultService() *VaultService {
+	batch := 200
+	if config.AppConfig != nil && config.AppConfig.Vault.RotateBatch > 0 {
+		batch = config.AppConfig.Vault.RotateBatch
+	}
+	return &VaultService{DB: config.GetDB(), Batch: batch}
+}
+
+// Usage 各加密欄位依主金鑰統計的資料筆數
+func (s *VaultService) Usage() ([]VaultKeyUsage, error) {
+	list := []VaultKeyUsage{}
+	for _, col := range vaultColumns {
+		rows, err := s.DB.Query(fmt.Sprintf(`
+			SELECT COALESCE(JSON_UNQUOTE(JSON_EXTRACT(%[1]s, '$.kid')), ''), COUNT(*)
+			FROM %[2]s WHERE %[1]s IS NOT NULL
+			GROUP BY 1 ORDER BY 1`, col.Column, col.Table))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			u := VaultKeyUsage{Table: col.Table, Column: col.Column}
+			if err := rows.Scan(&u.KeyID, &u.Rows); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			list = append(list, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Rotate 將所有加密欄位重新包裝為目前的主金鑰
+func (s *VaultService) Rotate() (*VaultRotation, error) {
+	v, err := DataVault()
+	if err != nil {
+		return nil, err
+	}
+	result := &VaultRotation{ActiveKey: v.ActiveKeyID(), Rewrapped: map[string]int{}}
+	for _, col := range vaultColumns {
+		n, err := s.rotateColumn(v, col)
+		result.Rewrapped[col.context()] = n
+		if err != nil {
+			return result, fmt.Errorf("輪替 %s 失敗: %v", col.context(), err)
+		}
+	}
+	return result, nil
+}
+
+// rotateColumn 分批重新包裝欄位中不是目前主金鑰的資料
+func (s *VaultService) rotateColumn(v *vault.Vault, col vaultColumn) (int, error) {
+	total := 0
+	lastID := int64(0)
+	for {
+		rows, err := s.DB.Query(fmt.Sprintf(`
+			SELECT id, %[1]s FROM %[2]s
+			WHERE id > ? AND %[1]s IS NOT NULL
+			  AND COALESCE(JSON_UNQUOTE(JSON_EXTRACT(%[1]s, '$.kid')), '') <> ?
+			ORDER BY id LIMIT ?`, col.Column, col.Table), lastID, v.ActiveKeyID(), s.Batch)
+		if err != nil {
+			return total, err
+		}
+		type row struct {
+			id   int64
+			data []byte
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.data); err != nil {
+				rows.Close()
+				return total, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		for _, r := range batch {
+			lastID = r.id
+			out, changed, err := v.Rewrap(r.data, col.context())
+			if err != nil {
+				return total, fmt.Errorf("第 %d 筆: %v", r.id, err)
+			}
+			if !changed {
+				continue
+			}
+			res, err := s.DB.Exec(fmt.Sprintf("UPDATE %[2]s SET %[1]s = ? WHERE id = ? AND %[1]s = CAST(? AS JSON)",
+				col.Column, col.Table), string(out), r.id, string(r.data))
+			if err != nil {
+				return total, err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				total++
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"nexus-gaming-backend/vault"
+)
+
+// useTestVault 以固定主金鑰取代全域加密金鑰組，測試結束後還原為未初始化
+func useTestVault(t *testing.T, active string, ids ...string) *vault.Vault {
+	t.Helper()
+	keys := map[string][]byte{}
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte(id[len(id)-1:]), 32)
+	}
+	v, err := vault.New(keys, active, []byte("test-fingerprint"))
+	if err != nil {
+		t.Fatalf("建立測試金鑰組失敗: %v", err)
+	}
+	dataVaultOnce.Do(func() {})
+	dataVault, dataVaultErr = v, nil
+	t.Cleanup(func() {
+		dataVaultOnce = sync.Once{}
+		dataVault, dataVaultErr = nil, nil
+	})
+	return v
+}
+
+func TestSealOpenJSON(t *testing.T) {
+	useTestVault(t, "test-k1", "test-k1")
+	raw := json.RawMessage(`{"account_number":"1234567890"}`)
+
+	sealed, err := sealJSON(columnPaymentMethodAccount, raw)
+	if err != nil {
+		t.Fatalf("sealJSON: %v", err)
+	}
+	data := []byte(sealed.(string))
+	if !vault.IsEncrypted(data) || bytes.Contains(data, []byte("1234567890")) {
+		t.Fatalf("sealJSON 未加密: %s", data)
+	}
+	got, err := openJSON(columnPaymentMethodAccount, data)
+	if err != nil || !bytes.Equal(got, raw) {
+		t.Fatalf("openJSON = %s, %v，預期 %s", got, err, raw)
+	}
+
+	// 密文綁定欄位，不能搬到其他加密欄位使用
+	if _, err := openJSON(columnWithdrawalAccount, data); !errors.Is(err, vault.ErrCorrupted) {
+		t.Errorf("其他欄位解密 error = %v，預期 ErrCorrupted", err)
+	}
+
+	if sealed, err := sealJSON(columnPaymentMethodAccount, nil); err != nil || sealed != nil {
+		t.Errorf("sealJSON(nil) = %v, %v，預期 NULL", sealed, err)
+	}
+	legacy := []byte(`{"account_number":"legacy"}`)
+	if got, err := openJSON(columnPaymentMethodAccount, legacy); err != nil || !bytes.Equal(got, legacy) {
+		t.Errorf("openJSON(舊資料) = %s, %v", got, err)
+	}
+}
+
+// 輪替主金鑰後重新包裝既有資料，移除舊主金鑰仍可讀取（需要 TEST_MYSQL_DSN，見 wallet_test.go）
+func TestVaultRotateThenRereadOldRecords(t *testing.T) {
+	env := newWalletTestEnv(t)
+	playerID := env.newPlayer(t)
+	raw := json.RawMessage(`{"account_number":"4111111111111111"}`)
+
+	useTestVault(t, "test-k1", "test-k1")
+	sealed, err := sealJSON(columnPaymentMethodAccount, raw)
+	if err != nil {
+		t.Fatalf("sealJSON: %v", err)
+	}
+	res, err := env.db.Exec(`
+		INSERT INTO payment_methods (player_id, method_type, provider_name, account_info)
+		VALUES (?, 'credit_card', 'test', ?)
+	`, playerID, sealed)
+	if err != nil {
+		t.Fatalf("建立支付方式失敗: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	stored := func() []byte {
+		t.Helper()
+		var data []byte
+		if err := env.db.QueryRow("SELECT account_info FROM payment_methods WHERE id = ?", id).Scan(&data); err != nil {
+			t.Fatalf("查詢支付方式失敗: %v", err)
+		}
+		return data
+	}
+
+	useTestVault(t, "test-k2", "test-k1", "test-k2")
+	svc := &VaultService{DB: env.db, Batch: 2}
+	rotation, err := svc.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rotation.ActiveKey != "test-k2" || rotation.Rewrapped[columnPaymentMethodAccount.context()] < 1 {
+		t.Errorf("輪替結果 = %+v，預期至少重新包裝 1 筆支付方式", rotation)
+	}
+	if kid := vault.KeyID(stored()); kid != "test-k2" {
+		t.Fatalf("輪替後主金鑰 = %q，預期 test-k2", kid)
+	}
+
+	// 再次輪替不會變更已是目前主金鑰的資料
+	before := stored()
+	if _, err := svc.Rotate(); err != nil {
+		t.Fatalf("再次輪替失敗: %v", err)
+	}
+	if after := stored(); !bytes.Equal(before, after) {
+		t.Error("再次輪替變更了已是目前主金鑰的資料")
+	}
+
+	useTestVault(t, "test-k2", "test-k2")
+	got, err := openJSON(columnPaymentMethodAccount, stored())
+	if err != nil {
+		t.Fatalf("移除舊主金鑰後讀取失敗: %v", err)
+	}
+	var want, have map[string]interface{}
+	json.Unmarshal(raw, &want)
+	json.Unmarshal(got, &have)
+	if have["account_number"] != want["account_number"] {
+		t.Errorf("讀取結果 = %s，預期 %s", got, raw)
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 錢包相關錯誤
+var (
+	ErrWalletNotFound      = errors.New("玩家錢包不存在")
+	ErrInsufficientBalance = errors.New("餘額不足")
+	ErrInvalidAmount       = errors.New("金額必須大於 0")
+)
+
+// WalletEntry 錢包異動請求
+type WalletEntry struct {
+	PlayerID      int64
+	Currency      string       // 錢包幣別；空字串表示預設幣別
+	Type          string       // transactions.transaction_type
+	Amount        money.Amount // 正數；方向由 Debit／Credit 決定
+	ReferenceID   string
+	ReferenceType string
+	Description   string
+	OperatorID    *int
+	Counterparty  *LedgerAccount // 總帳對方帳戶；未指定時依交易類型對應平台或紅利帳戶
+}
+
+// WalletTransaction 錢包異動結果
+type WalletTransaction struct {
+	TransactionID string       `json:"transaction_id"`
+	PlayerID      int64        `json:"player_id"`
+	Type          string       `json:"type"`
+	Currency      string       `json:"currency"`
+	Amount        money.Amount `json:"amount"`
+	BalanceBefore money.Amount `json:"balance_before"`
+	BalanceAfter  money.Amount `json:"balance_after"`
+	JournalCode   string       `json:"journal_code"`
+}
+
+// WalletBalance 錢包餘額（總餘額 = 可用 + 凍結）
+type WalletBalance struct {
+	Currency  string       `json:"currency"`
+	Available money.Amount `json:"available"`
+	Frozen    money.Amount `json:"frozen"`
+	Total     money.Amount `json:"total"`
+}
+
+// WalletService 玩家錢包服務（所有異動皆鎖定錢包列、寫入 transactions，並於同一交易過帳總帳傳票）
+//
+// 每位玩家每個幣別一個錢包；Currency 為未指定幣別時使用的預設幣別。
+type WalletService struct {
+	DB       *sql.DB
+	Currency string
+	Ledger   *LedgerService
+}
+
+// NewWalletService 建立新的錢包服務
+func NewWalletService() *WalletService {
+	currency := "TWD"
+	if config.AppConfig != nil && config.AppConfig.Game.DefaultCurrency != "" {
+		currency = config.AppConfig.Game.DefaultCurrency
+	}
+	return &WalletService{
+		DB:       config.GetDB(),
+		Currency: currency,
+		Ledger:   NewLedgerService(),
+	}
+}
+
+// Balances 取得玩家各幣別的錢包餘額
+func (s *WalletService) Balances(playerID int64) ([]WalletBalance, error) {
+	rows, err := s.DB.Query(`
+		SELECT currency, balance, frozen_balance FROM player_wallets
+		WHERE player_id = ?
+		ORDER BY currency
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := []WalletBalance{}
+	for rows.Next() {
+		var b WalletBalance
+		if err := rows.Scan(&b.Currency, &b.Available, &b.Frozen); err != nil {
+			return nil, err
+		}
+		b.Total = b.Available + b.Frozen
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+// Debit 扣款
+func (s *WalletService) Debit(e WalletEntry) (*WalletTransaction, error) {
+	return s.withTx(func(tx *sql.Tx) (*WalletTransaction, error) {
+		return s.DebitTx(tx, e)
+	})
+}
+
+// Credit 入帳
+func (s *WalletService) Credit(e WalletEntry) (*WalletTransaction, error) {
+	return s.withTx(func(tx *sql.Tx) (*WalletTransaction, error) {
+		return s.CreditTx(tx, e)
+	})
+}
+
+// DebitTx 在呼叫端交易內扣款
+func (s *WalletService) DebitTx(tx *sql.Tx, e WalletEntry) (*WalletTransaction, error) {
+	return s.apply(tx, e, false, nil)
+}
+
+// CreditTx 在呼叫端交易內入帳（錢包不存在時自動建立）
+func (s *WalletService) CreditTx(tx *sql.Tx, e WalletEntry) (*WalletTransaction, error) {
+	return s.apply(tx, e, true, nil)
+}
+
+// Reverse 沖銷一筆錢包交易：以 adjustment 交易反向異動錢包，並過帳分錄全部反向的沖銷傳票
+func (s *WalletService) Reverse(transactionID, reason string, operatorID *int) (*WalletTransaction, error) {
+	return s.withTx(func(tx *sql.Tx) (*WalletTransaction, error) {
+		j, err := s.Ledger.lockByTransaction(tx, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if j.Type == JournalReversal {
+			return nil, ErrReverseReversal
+		}
+		if j.ReversedBy != "" {
+			return nil, ErrAlreadyReversed
+		}
+		var player *LedgerLine
+		for i := range j.Lines {
+			if j.Lines[i].Account.Type == AccountPlayer {
+				player = &j.Lines[i]
+				break
+			}
+		}
+		if player == nil {
+			return nil, ErrJournalNotFound
+		}
+
+		description := fmt.Sprintf("沖銷交易 %s", transactionID)
+		if reason != "" {
+			description += "：" + reason
+		}
+		return s.apply(tx, WalletEntry{
+			PlayerID:      player.Account.OwnerID,
+			Currency:      j.Currency,
+			Type:          "adjustment",
+			Amount:        player.Amount.Abs(),
+			ReferenceID:   transactionID,
+			ReferenceType: JournalReversal,
+			Description:   description,
+			OperatorID:    operatorID,
+		}, player.Amount.IsNegative(), j.reversal(description, operatorID))
+	})
+}
+
+// apply 鎖定錢包列並異動餘額、寫入交易記錄與總帳傳票；journal 為 nil 時依交易類型產生玩家與對方帳戶兩筆分錄
+//
+// 錢包列以 SELECT ... FOR UPDATE 鎖定至交易結束，同一錢包的並行異動依序執行；
+// 餘額檢查以整數分比較，扣款只能動用可用餘額（凍結餘額不計入）。
+func (s *WalletService) apply(tx *sql.Tx, e WalletEntry, credit bool, journal *Journal) (*WalletTransaction, error) {
+	currency := s.CurrencyOf(e.Currency)
+	amount := e.Amount
+	if !amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	delta := amount
+	if !credit {
+		delta = -amount
+	}
+
+	// 入帳時先以 upsert 建立或排他鎖定錢包列：若先 SELECT ... FOR UPDATE 不存在的列再 INSERT，
+	// 兩筆並行的首次入帳會各自持有間隙鎖而互相等待插入（死結）；upsert 則讓第二筆等待第一筆提交後鎖定同一列
+	if credit {
+		if _, err := tx.Exec(`
+			INSERT INTO player_wallets (player_id, currency, balance) VALUES (?, ?, 0)
+			ON DUPLICATE KEY UPDATE balance = balance
+		`, e.PlayerID, currency); err != nil {
+			return nil, fmt.Errorf("建立錢包失敗: %w", err)
+		}
+	}
+
+	var balance money.Amount
+	err := tx.QueryRow(`
+		SELECT balance FROM player_wallets
+		WHERE player_id = ? AND currency = ?
+		FOR UPDATE
+	`, e.PlayerID, currency).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return nil, ErrWalletNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	after := balance + delta
+	if after.IsNegative() {
+		return nil, ErrInsufficientBalance
+	}
+	if _, err := tx.Exec(`
+		UPDATE player_wallets SET balance = ?
+		WHERE player_id = ? AND currency = ?
+	`, after, e.PlayerID, currency); err != nil {
+		return nil, err
+	}
+
+	t := &WalletTransaction{
+		TransactionID: NewTransactionID(),
+		PlayerID:      e.PlayerID,
+		Type:          e.Type,
+		Currency:      currency,
+		Amount:        amount,
+		BalanceBefore: balance,
+		BalanceAfter:  after,
+	}
+	_, err = tx.Exec(`
+		INSERT INTO transactions
+			(transaction_id, player_id, transaction_type, amount, currency, balance_before, balance_after,
+			 status, reference_id, reference_type, description, processed_at, operator_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 'completed', ?, ?, ?, NOW(), ?)
+	`, t.TransactionID, e.PlayerID, e.Type, amount, currency, balance, after,
+		nullString(e.ReferenceID), nullString(e.ReferenceType), nullString(e.Description), e.OperatorID)
+	if err != nil {
+		return nil, fmt.Errorf("寫入交易記錄失敗: %w", err)
+	}
+
+	if journal == nil {
+		counter := counterparty(e.Type, currency)
+		if e.Counterparty != nil {
+			counter = *e.Counterparty
+		}
+		journal = &Journal{
+			Type:        e.Type,
+			Currency:    currency,
+			Description: e.Description,
+			OperatorID:  e.OperatorID,
+			Lines: []LedgerLine{
+				{Account: PlayerAccount(e.PlayerID, currency), Amount: delta},
+				{Account: counter, Amount: -delta},
+			},
+		}
+	}
+	journal.TransactionID = t.TransactionID
+	if err := s.Ledger.PostTx(tx, journal); err != nil {
+		return nil, err
+	}
+	t.JournalCode = journal.JournalCode
+	return t, nil
+}
+
+// CurrencyOf 未指定幣別時回傳預設幣別
+func (s *WalletService) CurrencyOf(currency string) string {
+	if currency == "" {
+		return s.Currency
+	}
+	return currency
+}
+
+// counterparty 交易類型對應的總帳對方帳戶
+func counterparty(transactionType, currency string) LedgerAccount {
+	switch transactionType {
+	case "deposit", "withdrawal":
+		return HouseAccount(HouseCash, currency)
+	case "bonus":
+		return BonusAccount(currency)
+	case "commission":
+		return HouseAccount(HouseCommission, currency)
+	case "adjustment":
+		return HouseAccount(HouseAdjustment, currency)
+	case "fx_conversion":
+		return HouseAccount(HouseFX, currency)
+	default: // bet、win、refund
+		return HouseAccount(HouseGame, currency)
+	}
+}
+
+// withTx 在新交易內執行 fn；遇到死結時整筆交易重試（MySQL 已回滾死結的交易，重試不會重複入帳）
+func (s *WalletService) withTx(fn func(*sql.Tx) (*WalletTransaction, error)) (*WalletTransaction, error) {
+	for attempt := 1; ; attempt++ {
+		t, err := s.runTx(fn)
+		if err == nil || !isDeadlock(err) || attempt >= maxDeadlockRetries {
+			return t, err
+		}
+	}
+}
+
+func (s *WalletService) runTx(fn func(*sql.Tx) (*WalletTransaction, error)) (*WalletTransaction, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := fn(tx)
+	if err != nil {
+		return nil, err
+	}
+	return t, tx.Commit()
+}
+
+// NewTransactionID 產生交易流水號
+func NewTransactionID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "TX" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nexus-gaming-backend/money"
+)
+
+// 錢包並行測試需要已執行 database/init 的 MySQL，以 TEST_MYSQL_DSN 指定
+// （例如 root:password@tcp(127.0.0.1:3306)/nexus_gaming?parseTime=true）；未設定時略過。
+
+const testCurrency = "TWD"
+
+type walletTestEnv struct {
+	db     *sql.DB
+	wallet *WalletService
+	holds  *HoldService
+	idem   *IdempotencyService
+}
+
+func newWalletTestEnv(t *testing.T) *walletTestEnv {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("未設定 TEST_MYSQL_DSN，略過需要 MySQL 的錢包測試")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("開啟資料庫失敗: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatalf("連線資料庫失敗: %v", err)
+	}
+	db.SetMaxOpenConns(64)
+	t.Cleanup(func() { db.Close() })
+
+	wallet := &WalletService{DB: db, Currency: testCurrency, Ledger: &LedgerService{DB: db}}
+	return &walletTestEnv{
+		db:     db,
+		wallet: wallet,
+		holds:  &HoldService{DB: db, Wallet: wallet},
+		idem:   &IdempotencyService{DB: db},
+	}
+}
+
+// newPlayer 建立測試玩家（尚無錢包）
+func (env *walletTestEnv) newPlayer(t *testing.T) int64 {
+	t.Helper()
+	code := fmt.Sprintf("T%d", time.Now().UnixNano())
+	res, err := env.db.Exec("INSERT INTO players (player_id, username) VALUES (?, ?)", code, "wallet_test_"+code)
+	if err != nil {
+		t.Fatalf("建立測試玩家失敗: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func (env *walletTestEnv) balances(t *testing.T, playerID int64) (balance, frozen money.Amount) {
+	t.Helper()
+	if err := env.db.QueryRow(`
+		SELECT balance, frozen_balance FROM player_wallets WHERE player_id = ? AND currency = ?
+	`, playerID, testCurrency).Scan(&balance, &frozen); err != nil {
+		t.Fatalf("查詢錢包失敗: %v", err)
+	}
+	return balance, frozen
+}
+
+// assertHistory 檢查交易記錄筆數，且任何一筆異動後的餘額都不為負數
+func (env *walletTestEnv) assertHistory(t *testing.T, playerID int64, wantRows int) {
+	t.Helper()
+	var rows int
+	var minAfter sql.NullInt64
+	if err := env.db.QueryRow(`
+		SELECT COUNT(*), MIN(ROUND(balance_after * 100)) FROM transactions WHERE player_id = ?
+	`, playerID).Scan(&rows, &minAfter); err != nil {
+		t.Fatalf("查詢交易記錄失敗: %v", err)
+	}
+	if rows != wantRows {
+		t.Errorf("交易記錄筆數 = %d，預期 %d", rows, wantRows)
+	}
+	if minAfter.Valid && minAfter.Int64 < 0 {
+		t.Errorf("出現負餘額: %s", money.FromCents(minAfter.Int64))
+	}
+}
+
+func credit(playerID int64, amount money.Amount, ref string) WalletEntry {
+	return WalletEntry{PlayerID: playerID, Currency: testCurrency, Type: "deposit", Amount: amount, ReferenceID: ref}
+}
+
+func debit(playerID int64, amount money.Amount, ref string) WalletEntry {
+	return WalletEntry{PlayerID: playerID, Currency: testCurrency, Type: "withdrawal", Amount: amount, ReferenceID: ref}
+}
+
+// 多筆並行的首次入帳（錢包尚不存在）都必須成功，不得死結或唯一鍵衝突
+func TestWalletConcurrentFirstCredit(t *testing.T) {
+	env := newWalletTestEnv(t)
+	playerID := env.newPlayer(t)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := env.wallet.Credit(credit(playerID, money.FromCents(100), fmt.Sprintf("first-%d", i))); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("首次入帳失敗: %v", err)
+	}
+
+	balance, frozen := env.balances(t, playerID)
+	if want := money.FromCents(100 * workers); balance != want || frozen != 0 {
+		t.Errorf("餘額 = %s／凍結 %s，預期 %s／0", balance, frozen, want)
+	}
+	env.assertHistory(t, playerID, workers)
+}
+
+// 同一錢包並行扣款、入帳與凍結：最終餘額等於成功異動的合計，且餘額從未為負
+func TestWalletConcurrentDebitCreditHold(t *testing.T) {
+	env := newWalletTestEnv(t)
+	playerID := env.newPlayer(t)
+
+	initial := money.FromCents(10000)
+	if _, err := env.wallet.Credit(credit(playerID, initial, "seed")); err != nil {
+		t.Fatalf("初始入帳失敗: %v", err)
+	}
+
+	const (
+		debitAmount  = money.Amount(300)
+		creditAmount = money.Amount(100)
+		holdAmount   = money.Amount(200)
+		workers      = 90
+	)
+	var (
+		wg                             sync.WaitGroup
+		debits, credits, captures, txs int64
+		unexpected                     = make(chan error, workers)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch i % 3 {
+			case 0:
+				_, err := env.wallet.Debit(debit(playerID, debitAmount, fmt.Sprintf("d-%d", i)))
+				if err == nil {
+					atomic.AddInt64(&debits, 1)
+					atomic.AddInt64(&txs, 1)
+				} else if !errors.Is(err, ErrInsufficientBalance) {
+					unexpected <- err
+				}
+			case 1:
+				if _, err := env.wallet.Credit(credit(playerID, creditAmount, fmt.Sprintf("c-%d", i))); err != nil {
+					unexpected <- err
+					return
+				}
+				atomic.AddInt64(&credits, 1)
+				atomic.AddInt64(&txs, 1)
+			default:
+				h, err := env.holds.Place(HoldRequest{
+					PlayerID: playerID, Currency: testCurrency, Amount: holdAmount,
+					Reason: HoldReasonWithdrawal, ReferenceType: HoldRefWithdrawal, ReferenceID: fmt.Sprintf("h-%d", i),
+				})
+				if errors.Is(err, ErrInsufficientBalance) {
+					return
+				} else if err != nil {
+					unexpected <- err
+					return
+				}
+				// 半數扣除凍結、半數釋放
+				if i%2 == 0 {
+					if _, err := env.holds.Capture(h.HoldCode, 0, debit(playerID, 0, h.HoldCode)); err != nil {
+						unexpected <- err
+						return
+					}
+					atomic.AddInt64(&captures, 1)
+					atomic.AddInt64(&txs, 1)
+				} else if _, err := env.holds.Release(h.HoldCode); err != nil {
+					unexpected <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(unexpected)
+	for err := range unexpected {
+		t.Errorf("非預期錯誤: %v", err)
+	}
+
+	want := initial + money.Amount(credits)*creditAmount - money.Amount(debits)*debitAmount - money.Amount(captures)*holdAmount
+	balance, frozen := env.balances(t, playerID)
+	if balance != want || frozen != 0 {
+		t.Errorf("餘額 = %s／凍結 %s，預期 %s／0（扣款 %d、入帳 %d、凍結扣款 %d）",
+			balance, frozen, want, debits, credits, captures)
+	}
+	if balance.IsNegative() {
+		t.Errorf("最終餘額為負數: %s", balance)
+	}
+	env.assertHistory(t, playerID, int(txs)+1)
+}
+
+// 相同冪等鍵的並行重試只入帳一次，其餘重播第一次的回應
+func TestWalletIdempotentRetryAppliesOnce(t *testing.T) {
+	env := newWalletTestEnv(t)
+	playerID := env.newPlayer(t)
+
+	key := fmt.Sprintf("retry-%d", time.Now().UnixNano())
+	req := map[string]interface{}{"player_id": playerID, "amount": "5.00"}
+	const workers = 10
+
+	var (
+		wg       sync.WaitGroup
+		executed int64
+		mu       sync.Mutex
+		bodies   []json.RawMessage
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, replayed, err := env.idem.Run("wallet_test", key, req, func(tx *sql.Tx) (interface{}, error) {
+				return env.wallet.CreditTx(tx, credit(playerID, money.FromCents(500), key))
+			})
+			if err != nil {
+				t.Errorf("冪等入帳失敗: %v", err)
+				return
+			}
+			if !replayed {
+				atomic.AddInt64(&executed, 1)
+			}
+			mu.Lock()
+			bodies = append(bodies, body)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if executed != 1 {
+		t.Errorf("實際執行 %d 次，預期 1 次", executed)
+	}
+	for _, b := range bodies {
+		if string(b) != string(bodies[0]) {
+			t.Errorf("重播回應與首次不同: %s vs %s", b, bodies[0])
+		}
+	}
+	balance, _ := env.balances(t, playerID)
+	if want := money.FromCents(500); balance != want {
+		t.Errorf("餘額 = %s，預期 %s", balance, want)
+	}
+	env.assertHistory(t, playerID, 1)
+}
@@ -0,0 +1,787 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+	"nexus-gaming-backend/payment"
+)
+
+// 提領相關錯誤
+var (
+	ErrWithdrawalNotFound    = errors.New("提領申請不存在")
+	ErrWithdrawalNotPending  = errors.New("提領申請已審核或已取消")
+	ErrWithdrawalAssigned    = errors.New("提領申請已指派給其他審核員")
+	ErrSecondApproverSame    = errors.New("雙人覆核需由另一位審核員核准")
+	ErrWithdrawalNotPayable  = errors.New("提領申請不在可出款狀態")
+	ErrReviewerRequired      = errors.New("需指定審核員")
+	ErrWithdrawalNoteInvalid = errors.New("備註內容不可為空")
+	ErrPayoutNotConfigured   = errors.New("未設定出款提供商，已核准的提領需人工出款")
+)
+
+// 審核紀錄動作（withdrawal_review_notes.action）
+const (
+	ReviewActionNote          = "note"
+	ReviewActionAssign        = "assign"
+	ReviewActionFirstApproval = "first_approval"
+	ReviewActionApprove       = "approve"
+	ReviewActionReject        = "reject"
+	ReviewActionPayout        = "payout"
+	ReviewActionPayoutFailed  = "payout_failed"
+)
+
+// defaultWithdrawalHoldTTL 未設定時提領凍結的有效期限
+const defaultWithdrawalHoldTTL = 72 * time.Hour
+
+// payoutBatch 每次出款排程最多處理的提領數
+const payoutBatch = 100
+
+// Withdrawal 提領申請
+type Withdrawal struct {
+	WithdrawalID           string          `json:"withdrawal_id"`
+	PlayerID               int64           `json:"player_id"`
+	Amount                 money.Amount    `json:"amount"`
+	Currency               string          `json:"currency"`
+	WithdrawalMethod       string          `json:"withdrawal_method"`
+	PaymentMethodID        *int            `json:"payment_method_id,omitempty"`
+	AccountInfo            json.RawMessage `json:"account_info"`
+	Status                 string          `json:"status"`
+	ReviewStatus           string          `json:"review_status,omitempty"`
+	RiskScore              float64         `json:"risk_score"`
+	RiskChecks             []RiskCheck     `json:"risk_checks,omitempty"`
+	AssignedTo             *int            `json:"assigned_to,omitempty"`
+	AssignedAt             *time.Time      `json:"assigned_at,omitempty"`
+	RequiresSecondApproval bool            `json:"requires_second_approval"`
+	FirstApproverID        *int            `json:"first_approver_id,omitempty"`
+	FirstApprovedAt        *time.Time      `json:"first_approved_at,omitempty"`
+	ReviewerID             *int            `json:"reviewer_id,omitempty"`
+	ReviewedAt             *time.Time      `json:"reviewed_at,omitempty"`
+	ReviewNotes            string          `json:"review_notes,omitempty"`
+	PayoutProvider         string          `json:"payout_provider,omitempty"`
+	GatewayTransactionID   string          `json:"gateway_transaction_id,omitempty"`
+	ProcessedAt            *time.Time      `json:"processed_at,omitempty"`
+	HoldCode               string          `json:"hold_code,omitempty"`
+	TransactionID          string          `json:"transaction_id,omitempty"`
+	CreatedAt              time.Time       `json:"created_at"`
+}
+
+// WithdrawalRequest 建立提領申請
+type WithdrawalRequest struct {
+	PlayerID         int64
+	Currency         string // 空字串表示預設幣別
+	Amount           money.Amount
+	WithdrawalMethod string
+	PaymentMethodID  *int // 使用保存的支付方式時，帳戶資訊取自支付方式
+	AccountInfo      json.RawMessage
+	IPAddress        string
+}
+
+// WithdrawalFilter 提領申請查詢條件
+type WithdrawalFilter struct {
+	PlayerID     int64
+	Status       string
+	ReviewStatus string
+	AssignedTo   int
+	Queue        bool // 只列出待審核（pending、reviewing），依風險分數由高到低
+	Page         int
+	Limit        int
+}
+
+// WithdrawalReviewNote 提領審核紀錄
+type WithdrawalReviewNote struct {
+	ID           int64     `json:"id"`
+	WithdrawalID string    `json:"withdrawal_id"`
+	UserID       *int      `json:"user_id,omitempty"`
+	Action       string    `json:"action"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// WithdrawalService 提領服務
+//
+// 建立申請時凍結提領金額並依規則評分：全部規則通過者自動核准，其餘進入人工審核佇列。
+// 核准後凍結不再逾期，經支付閘道出款，出款成功才自凍結扣款；出款失敗釋放凍結。
+// 駁回時釋放凍結；未核准的凍結逾期由 HoldService 釋放並取消申請。
+type WithdrawalService struct {
+	DB             *sql.DB
+	Holds          *HoldService
+	Bonus          *BonusService
+	PaymentMethods *PaymentMethodService
+	FX             *FXService
+	Gateways       *payment.Registry
+	PayoutProvider string
+	Rules          WithdrawalRules
+	HoldTTL        time.Duration
+}
+
+// NewWithdrawalService 建立新的提領服務
+func NewWithdrawalService() *WithdrawalService {
+	holds := NewHoldService()
+	ttl := defaultWithdrawalHoldTTL
+	var provider string
+	if config.AppConfig != nil {
+		if config.AppConfig.Wallet.WithdrawalHoldTTL > 0 {
+			ttl = config.AppConfig.Wallet.WithdrawalHoldTTL
+		}
+		provider = config.AppConfig.Payment.PayoutProvider
+	}
+	return &WithdrawalService{
+		DB:             holds.DB,
+		Holds:          holds,
+		Bonus:          NewBonusService(),
+		PaymentMethods: NewPaymentMethodService(),
+		FX:             NewFXService(),
+		Gateways:       PaymentGateways(),
+		PayoutProvider: provider,
+		Rules:          DefaultWithdrawalRules(),
+		HoldTTL:        ttl,
+	}
+}
+
+// Create 建立提領申請並凍結提領金額；自動核准者隨即出款（出款失敗時保留為已核准，由排程重試）
+func (s *WithdrawalService) Create(req WithdrawalRequest) (*Withdrawal, error) {
+	var w *Withdrawal
+	err := s.Holds.withTx(func(tx *sql.Tx) error {
+		var err error
+		w, err = s.CreateTx(tx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if w.Status == "approved" && s.PayoutProvider != "" {
+		if _, err := s.Payout(w.WithdrawalID); err != nil {
+			log.Printf("提領 %s 自動出款失敗，待排程重試: %v", w.WithdrawalID, err)
+		}
+	}
+	return s.Get(w.WithdrawalID)
+}
+
+// CreateTx 在呼叫端交易內建立提領申請、凍結提領金額並評估風險；自動核准時沒收流水未完成的紅利
+// （停權或已刪除的玩家不可提領，帳號遭詐欺封鎖者不可提領；帳戶資訊加密保存）
+func (s *WithdrawalService) CreateTx(tx *sql.Tx, req WithdrawalRequest) (*Withdrawal, error) {
+	var status string
+	err := tx.QueryRow("SELECT status FROM players WHERE id = ?", req.PlayerID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, ErrPlayerNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if status == "deleted" || status == "suspended" {
+		return nil, ErrPlayerInactive
+	}
+
+	w := &Withdrawal{
+		WithdrawalID:     newWithdrawalID(),
+		PlayerID:         req.PlayerID,
+		Amount:           req.Amount,
+		Currency:         s.Holds.Wallet.CurrencyOf(req.Currency),
+		WithdrawalMethod: req.WithdrawalMethod,
+		PaymentMethodID:  req.PaymentMethodID,
+		AccountInfo:      req.AccountInfo,
+		Status:           "pending",
+		CreatedAt:        time.Now(),
+	}
+	if req.PaymentMethodID != nil {
+		if _, w.AccountInfo, err = s.PaymentMethods.AccountTx(tx, req.PlayerID, *req.PaymentMethodID); err != nil {
+			return nil, err
+		}
+	} else if err := s.PaymentMethods.CheckAccountTx(tx, w.AccountInfo); err != nil {
+		return nil, err
+	}
+	sealedAccount, err := sealJSON(columnWithdrawalAccount, w.AccountInfo)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := s.Holds.PlaceTx(tx, HoldRequest{
+		PlayerID:      req.PlayerID,
+		Currency:      w.Currency,
+		Amount:        req.Amount,
+		Reason:        HoldReasonWithdrawal,
+		ReferenceType: HoldRefWithdrawal,
+		ReferenceID:   w.WithdrawalID,
+		TTL:           s.HoldTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.HoldCode = hold.HoldCode
+
+	assessment, err := s.assessTx(tx, w.PlayerID, w.Currency, w.Amount)
+	if err != nil {
+		return nil, err
+	}
+	w.ReviewStatus, w.RiskScore, w.RiskChecks = assessment.Decision, assessment.Score, assessment.Checks
+	w.RequiresSecondApproval = assessment.RequiresSecondApproval
+	var forfeitNote string
+	if assessment.Decision == ReviewAutoApproved {
+		w.Status = "approved"
+		if _, err := s.Holds.KeepTx(tx, hold.HoldCode); err != nil {
+			return nil, err
+		}
+		if forfeitNote, err = s.forfeitBonusTx(tx, w); err != nil {
+			return nil, err
+		}
+	}
+	checks, _ := json.Marshal(w.RiskChecks)
+	if _, err := tx.Exec(`
+		INSERT INTO withdrawals
+			(withdrawal_id, player_id, amount, currency, withdrawal_method, payment_method_id, account_info, status,
+			 review_status, requires_second_approval, risk_score, risk_checks, reviewed_at, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, IF(? = 'approved', NOW(), NULL), ?)
+	`, w.WithdrawalID, w.PlayerID, w.Amount, w.Currency, w.WithdrawalMethod, w.PaymentMethodID, sealedAccount, w.Status,
+		w.ReviewStatus, w.RequiresSecondApproval, w.RiskScore, string(checks), w.Status, nullString(req.IPAddress)); err != nil {
+		return nil, err
+	}
+	note := fmt.Sprintf("風險分數 %.0f，轉入%s", w.RiskScore, reviewStatusLabel(w.ReviewStatus))
+	action := ReviewActionNote
+	if w.Status == "approved" {
+		note, action = "全部規則通過，自動核准", ReviewActionApprove
+	}
+	if err := addReviewNoteTx(tx, w.WithdrawalID, nil, action, joinNotes(note, forfeitNote)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// forfeitBonusTx 提領核准時沒收流水未完成的紅利，回傳附加於審核紀錄的說明
+//
+// 沒收延後到核准才執行：評估風險時 bonus_wagering 規則仍看得到未完成的流水，
+// 駁回、取消或凍結逾期的提領也不會讓玩家失去紅利。
+func (s *WithdrawalService) forfeitBonusTx(tx *sql.Tx, w *Withdrawal) (string, error) {
+	forfeited, err := s.Bonus.ForfeitForWithdrawalTx(tx, w.PlayerID, w.Currency, w.WithdrawalID)
+	if err != nil || !forfeited.IsPositive() {
+		return "", err
+	}
+	return fmt.Sprintf("流水未完成，已沒收紅利 %s", forfeited), nil
+}
+
+// joinNotes 以全形分號串接非空白的審核說明
+func joinNotes(notes ...string) string {
+	parts := make([]string, 0, len(notes))
+	for _, n := range notes {
+		if n != "" {
+			parts = append(parts, n)
+		}
+	}
+	return strings.Join(parts, "；")
+}
+
+// Assign 將待審核的提領指派給審核員
+func (s *WithdrawalService) Assign(withdrawalID string, assigneeID int, operatorID *int) (*Withdrawal, error) {
+	if assigneeID <= 0 {
+		return nil, ErrReviewerRequired
+	}
+	err := s.Holds.withTx(func(tx *sql.Tx) error {
+		if _, err := s.lockPending(tx, withdrawalID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			UPDATE withdrawals SET status = 'reviewing', assigned_to = ?, assigned_at = NOW()
+			WHERE withdrawal_id = ?
+		`, assigneeID, withdrawalID); err != nil {
+			return err
+		}
+		return addReviewNoteTx(tx, withdrawalID, operatorID, ReviewActionAssign, fmt.Sprintf("指派給審核員 #%d", assigneeID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(withdrawalID)
+}
+
+// AddNote 新增審核備註
+func (s *WithdrawalService) AddNote(withdrawalID string, userID *int, note string) (*WithdrawalReviewNote, error) {
+	if note == "" {
+		return nil, ErrWithdrawalNoteInvalid
+	}
+	if _, err := s.Get(withdrawalID); err != nil {
+		return nil, err
+	}
+	res, err := s.DB.Exec(`
+		INSERT INTO withdrawal_review_notes (withdrawal_id, user_id, action, note) VALUES (?, ?, ?, ?)
+	`, withdrawalID, userID, ReviewActionNote, note)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return &WithdrawalReviewNote{
+		ID:           id,
+		WithdrawalID: withdrawalID,
+		UserID:       userID,
+		Action:       ReviewActionNote,
+		Note:         note,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// Notes 提領的審核紀錄（依時間排序）
+func (s *WithdrawalService) Notes(withdrawalID string) ([]WithdrawalReviewNote, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, withdrawal_id, user_id, action, COALESCE(note, ''), created_at
+		FROM withdrawal_review_notes WHERE withdrawal_id = ? ORDER BY id
+	`, withdrawalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []WithdrawalReviewNote{}
+	for rows.Next() {
+		var (
+			n      WithdrawalReviewNote
+			userID sql.NullInt64
+		)
+		if err := rows.Scan(&n.ID, &n.WithdrawalID, &userID, &n.Action, &n.Note, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			n.UserID = &id
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// Approve 核准提領：需雙人覆核者第一位核准後等待另一位審核員；最終核准時沒收流水未完成的紅利，
+// 凍結不再逾期並隨即出款
+//
+// 已指派的申請第一位核准者須為被指派的審核員。
+func (s *WithdrawalService) Approve(withdrawalID string, reviewerID *int, notes string) (*Withdrawal, error) {
+	if reviewerID == nil {
+		return nil, ErrReviewerRequired
+	}
+	approved := false
+	err := s.reviewTx(withdrawalID, func(tx *sql.Tx, w *Withdrawal, hold *Hold) error {
+		if w.FirstApproverID == nil && w.AssignedTo != nil && *w.AssignedTo != *reviewerID {
+			return ErrWithdrawalAssigned
+		}
+		if w.RequiresSecondApproval && w.FirstApproverID == nil {
+			if _, err := tx.Exec(`
+				UPDATE withdrawals SET status = 'reviewing', first_approver_id = ?, first_approved_at = NOW()
+				WHERE withdrawal_id = ?
+			`, *reviewerID, w.WithdrawalID); err != nil {
+				return err
+			}
+			return addReviewNoteTx(tx, w.WithdrawalID, reviewerID, ReviewActionFirstApproval, notes)
+		}
+		if w.FirstApproverID != nil && *w.FirstApproverID == *reviewerID {
+			return ErrSecondApproverSame
+		}
+		if _, err := s.Holds.KeepTx(tx, hold.HoldCode); err != nil {
+			return err
+		}
+		forfeitNote, err := s.forfeitBonusTx(tx, w)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			UPDATE withdrawals SET status = 'approved', reviewer_id = ?, reviewed_at = NOW(), review_notes = ?
+			WHERE withdrawal_id = ?
+		`, *reviewerID, nullString(notes), w.WithdrawalID); err != nil {
+			return err
+		}
+		approved = true
+		return addReviewNoteTx(tx, w.WithdrawalID, reviewerID, ReviewActionApprove, joinNotes(notes, forfeitNote))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if approved && s.PayoutProvider != "" {
+		if _, err := s.Payout(withdrawalID); err != nil {
+			log.Printf("提領 %s 出款失敗，待排程重試: %v", withdrawalID, err)
+		}
+	}
+	return s.Get(withdrawalID)
+}
+
+// Reject 駁回提領：釋放凍結回可用餘額
+func (s *WithdrawalService) Reject(withdrawalID string, reviewerID *int, notes string) (*Withdrawal, error) {
+	err := s.reviewTx(withdrawalID, func(tx *sql.Tx, w *Withdrawal, hold *Hold) error {
+		if _, err := s.Holds.ReleaseTx(tx, hold.HoldCode); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			UPDATE withdrawals SET status = 'rejected', review_status = 'rejected', reviewer_id = ?, reviewed_at = NOW(), review_notes = ?
+			WHERE withdrawal_id = ?
+		`, reviewerID, nullString(notes), w.WithdrawalID); err != nil {
+			return err
+		}
+		return addReviewNoteTx(tx, w.WithdrawalID, reviewerID, ReviewActionReject, notes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(withdrawalID)
+}
+
+// reviewTx 鎖定凍結與待審核的提領後執行審核；鎖定順序與逾期釋放相同（凍結列、錢包列、提領申請）
+func (s *WithdrawalService) reviewTx(withdrawalID string, fn func(*sql.Tx, *Withdrawal, *Hold) error) error {
+	w, err := s.Get(withdrawalID)
+	if err != nil {
+		return err
+	}
+	hold, err := s.Holds.ActiveByReference(w.PlayerID, HoldRefWithdrawal, w.WithdrawalID)
+	if errors.Is(err, ErrHoldNotFound) {
+		return ErrWithdrawalNotPending
+	} else if err != nil {
+		return err
+	}
+	err = s.Holds.withTx(func(tx *sql.Tx) error {
+		if _, err := s.Holds.lock(tx, hold.HoldCode); err != nil {
+			return err
+		}
+		w, err := s.lockPending(tx, withdrawalID)
+		if err != nil {
+			return err
+		}
+		return fn(tx, w, hold)
+	})
+	if errors.Is(err, ErrHoldNotActive) {
+		return ErrWithdrawalNotPending
+	}
+	return err
+}
+
+// Payout 對已核准的提領出款，或查詢出款中的提領；出款成功時自凍結扣款，失敗時釋放凍結
+//
+// 閘道以提領訂單號去重，中斷後重送不會重複出款。
+func (s *WithdrawalService) Payout(withdrawalID string) (*Withdrawal, error) {
+	w, err := s.Get(withdrawalID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Status != "approved" && w.Status != "processing" {
+		return nil, ErrWithdrawalNotPayable
+	}
+	provider := w.PayoutProvider
+	if provider == "" {
+		provider = s.PayoutProvider
+	}
+	if provider == "" {
+		return nil, ErrPayoutNotConfigured
+	}
+	gw, err := s.Gateways.Payout(provider)
+	if err != nil {
+		return nil, err
+	}
+	if w.Status == "approved" {
+		res, err := s.DB.Exec(`
+			UPDATE withdrawals SET status = 'processing', payout_provider = ?
+			WHERE withdrawal_id = ? AND status = 'approved'
+		`, provider, withdrawalID)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, ErrWithdrawalNotPayable
+		}
+		w.PayoutProvider = provider
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+	defer cancel()
+	var st *payment.TransactionStatus
+	if w.GatewayTransactionID != "" {
+		st, err = gw.QueryPayout(ctx, w.GatewayTransactionID)
+	} else {
+		st, err = gw.CreatePayout(ctx, payment.PayoutRequest{
+			Reference:   w.WithdrawalID,
+			PlayerID:    w.PlayerID,
+			Amount:      w.Amount,
+			Currency:    w.Currency,
+			Method:      w.WithdrawalMethod,
+			AccountInfo: w.AccountInfo,
+		})
+	}
+	if err != nil {
+		s.DB.Exec(`
+			INSERT INTO withdrawal_review_notes (withdrawal_id, action, note) VALUES (?, ?, ?)
+		`, withdrawalID, ReviewActionPayoutFailed, "出款請求失敗: "+err.Error())
+		return nil, fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
+	}
+	if err := s.settlePayout(w, st); err != nil {
+		return nil, err
+	}
+	return s.Get(withdrawalID)
+}
+
+// settlePayout 依閘道回報的出款狀態結清提領
+func (s *WithdrawalService) settlePayout(w *Withdrawal, st *payment.TransactionStatus) error {
+	response, _ := json.Marshal(st)
+	if st.Status == payment.StatusPending {
+		_, err := s.DB.Exec(`
+			UPDATE withdrawals SET gateway_transaction_id = ?, gateway_response = ?
+			WHERE withdrawal_id = ? AND status = 'processing'
+		`, st.GatewayTransactionID, string(response), w.WithdrawalID)
+		return err
+	}
+	hold, err := s.Holds.ActiveByReference(w.PlayerID, HoldRefWithdrawal, w.WithdrawalID)
+	if err != nil {
+		return err
+	}
+	return s.Holds.withTx(func(tx *sql.Tx) error {
+		var (
+			status, action, note string
+			fee                  money.Amount
+		)
+		if st.Status == payment.StatusSucceeded {
+			if _, err := s.Holds.CaptureTx(tx, hold.HoldCode, 0, WalletEntry{
+				Type:          "withdrawal",
+				ReferenceID:   w.WithdrawalID,
+				ReferenceType: "withdrawal",
+				Description:   "提領出款",
+				OperatorID:    w.ReviewerID,
+			}); err != nil {
+				return err
+			}
+			status, action, fee = "completed", ReviewActionPayout, st.Fee
+			note = fmt.Sprintf("出款完成（%s %s）", w.PayoutProvider, st.GatewayTransactionID)
+		} else {
+			if _, err := s.Holds.ReleaseTx(tx, hold.HoldCode); err != nil {
+				return err
+			}
+			status, action = "rejected", ReviewActionPayoutFailed
+			note = fmt.Sprintf("閘道回報出款%s，已釋放凍結", st.Status)
+		}
+		res, err := tx.Exec(`
+			UPDATE withdrawals
+			SET status = ?, gateway_transaction_id = ?, gateway_response = ?, processor_fee = ?, processed_at = NOW()
+			WHERE withdrawal_id = ? AND status = 'processing'
+		`, status, st.GatewayTransactionID, string(response), fee, w.WithdrawalID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrWithdrawalNotPayable
+		}
+		return addReviewNoteTx(tx, w.WithdrawalID, nil, action, note)
+	})
+}
+
+// ProcessPayouts 對已核准或出款中的提領出款與查詢狀態（未設定出款提供商時只查詢出款中的提領）
+func (s *WithdrawalService) ProcessPayouts() (int, error) {
+	statuses := "'approved', 'processing'"
+	if s.PayoutProvider == "" {
+		statuses = "'processing'"
+	}
+	rows, err := s.DB.Query(`
+		SELECT withdrawal_id FROM withdrawals WHERE status IN (`+statuses+`) ORDER BY id LIMIT ?
+	`, payoutBatch)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	settled := 0
+	for _, id := range ids {
+		w, err := s.Payout(id)
+		if err != nil {
+			log.Printf("提領 %s 出款處理失敗: %v", id, err)
+			continue
+		}
+		if w.Status != "processing" {
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+// RunPayouts 定期處理出款，直到 ctx 結束（阻塞執行，應以 goroutine 啟動）
+func (s *WithdrawalService) RunPayouts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.ProcessPayouts(); err != nil {
+			log.Printf("處理提領出款失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("已結清 %d 筆提領出款", n)
+		}
+	}
+}
+
+// CheckPayoutProvider 檢查設定的出款提供商已註冊且支援出款（啟動時呼叫；未設定時不檢查）
+func (s *WithdrawalService) CheckPayoutProvider() error {
+	if s.PayoutProvider == "" {
+		return nil
+	}
+	if _, err := s.Gateways.Payout(s.PayoutProvider); err != nil {
+		return fmt.Errorf("出款提供商 %s: %w", s.PayoutProvider, err)
+	}
+	return nil
+}
+
+// lockPending 鎖定待審核的提領申請
+func (s *WithdrawalService) lockPending(tx *sql.Tx, withdrawalID string) (*Withdrawal, error) {
+	w, err := scanWithdrawal(tx.QueryRow(withdrawalSelect+" WHERE w.withdrawal_id = ? FOR UPDATE OF w", withdrawalID))
+	if err == sql.ErrNoRows {
+		return nil, ErrWithdrawalNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if w.Status != "pending" && w.Status != "reviewing" {
+		return nil, ErrWithdrawalNotPending
+	}
+	return w, nil
+}
+
+// Get 取得提領申請（含凍結編號）
+func (s *WithdrawalService) Get(withdrawalID string) (*Withdrawal, error) {
+	w, err := scanWithdrawal(s.DB.QueryRow(withdrawalSelect+" WHERE w.withdrawal_id = ?", withdrawalID))
+	if err == sql.ErrNoRows {
+		return nil, ErrWithdrawalNotFound
+	}
+	return w, err
+}
+
+// List 依條件列出提領申請，回傳資料與總筆數
+func (s *WithdrawalService) List(f WithdrawalFilter) ([]Withdrawal, int64, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if f.PlayerID > 0 {
+		where += " AND w.player_id = ?"
+		args = append(args, f.PlayerID)
+	}
+	if f.Queue {
+		where += " AND w.status IN ('pending', 'reviewing')"
+	} else if f.Status != "" {
+		where += " AND w.status = ?"
+		args = append(args, f.Status)
+	}
+	if f.ReviewStatus != "" {
+		where += " AND w.review_status = ?"
+		args = append(args, f.ReviewStatus)
+	}
+	if f.AssignedTo > 0 {
+		where += " AND w.assigned_to = ?"
+		args = append(args, f.AssignedTo)
+	}
+	var total int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM withdrawals w"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := " ORDER BY w.id DESC"
+	if f.Queue {
+		order = " ORDER BY w.risk_score DESC, w.id"
+	}
+	rows, err := s.DB.Query(withdrawalSelect+where+order+" LIMIT ? OFFSET ?",
+		append(args, f.Limit, (f.Page-1)*f.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []Withdrawal{}
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *w)
+	}
+	return list, total, rows.Err()
+}
+
+// addReviewNoteTx 寫入審核紀錄
+func addReviewNoteTx(tx *sql.Tx, withdrawalID string, userID *int, action, note string) error {
+	_, err := tx.Exec(`
+		INSERT INTO withdrawal_review_notes (withdrawal_id, user_id, action, note) VALUES (?, ?, ?, ?)
+	`, withdrawalID, userID, action, nullString(note))
+	return err
+}
+
+// reviewStatusLabel 審核狀態說明
+func reviewStatusLabel(status string) string {
+	switch status {
+	case ReviewRisk:
+		return "風險審核"
+	case ReviewManual:
+		return "人工審核"
+	default:
+		return status
+	}
+}
+
+// withdrawalSelect 提領申請查詢（最新一筆凍結與其結清交易）
+const withdrawalSelect = `
+	SELECT w.withdrawal_id, w.player_id, w.amount, w.currency, w.withdrawal_method, w.payment_method_id,
+	       w.account_info, w.status,
+	       COALESCE(w.review_status, ''), w.risk_score, w.risk_checks, w.assigned_to, w.assigned_at,
+	       w.requires_second_approval, w.first_approver_id, w.first_approved_at,
+	       w.reviewer_id, w.reviewed_at, w.review_notes, COALESCE(w.payout_provider, ''),
+	       COALESCE(w.gateway_transaction_id, ''), w.processed_at, h.hold_code, h.transaction_id, w.created_at
+	FROM withdrawals w
+	LEFT JOIN wallet_holds h ON h.id = (
+		SELECT MAX(id) FROM wallet_holds
+		WHERE reference_type = 'withdrawal' AND reference_id = w.withdrawal_id
+	)`
+
+func scanWithdrawal(row rowScanner) (*Withdrawal, error) {
+	w := &Withdrawal{}
+	var (
+		accountInfo, riskChecks                                  []byte
+		paymentMethodID, assignedTo, firstApproverID, reviewerID sql.NullInt64
+		assignedAt, firstApprovedAt, reviewedAt, processedAt     sql.NullTime
+		notes, holdCode, transactionID                           sql.NullString
+	)
+	if err := row.Scan(&w.WithdrawalID, &w.PlayerID, &w.Amount, &w.Currency, &w.WithdrawalMethod, &paymentMethodID,
+		&accountInfo, &w.Status, &w.ReviewStatus, &w.RiskScore, &riskChecks, &assignedTo, &assignedAt,
+		&w.RequiresSecondApproval, &firstApproverID, &firstApprovedAt,
+		&reviewerID, &reviewedAt, &notes, &w.PayoutProvider,
+		&w.GatewayTransactionID, &processedAt, &holdCode, &transactionID, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	account, err := openJSON(columnWithdrawalAccount, accountInfo)
+	if err != nil {
+		return nil, err
+	}
+	w.AccountInfo, w.PaymentMethodID = account, nullInt(paymentMethodID)
+	if len(riskChecks) > 0 {
+		json.Unmarshal(riskChecks, &w.RiskChecks)
+	}
+	w.AssignedTo, w.FirstApproverID, w.ReviewerID = nullInt(assignedTo), nullInt(firstApproverID), nullInt(reviewerID)
+	w.AssignedAt, w.FirstApprovedAt = nullTime(assignedAt), nullTime(firstApprovedAt)
+	w.ReviewedAt, w.ProcessedAt = nullTime(reviewedAt), nullTime(processedAt)
+	w.ReviewNotes, w.HoldCode, w.TransactionID = notes.String, holdCode.String, transactionID.String
+	return w, nil
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+// newWithdrawalID 產生提領訂單號
+func newWithdrawalID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return "WD" + time.Now().Format("20060102150405") + hex.EncodeToString(b)
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/money"
+)
+
+// 提領審核狀態（withdrawals.review_status）
+const (
+	ReviewAutoApproved = "auto_approved"
+	ReviewManual       = "manual_review"
+	ReviewRisk         = "risk_review"
+	ReviewRejected     = "rejected"
+)
+
+// maxRiskScore 風險分數上限（withdrawals.risk_score 為 DECIMAL(5,2)）
+const maxRiskScore = 100
+
+// verificationRank 玩家驗證等級的高低順序
+var verificationRank = map[string]int{"none": 0, "email": 1, "phone": 2, "identity": 3}
+
+// WithdrawalRules 提領自動核准規則（金額以預設幣別計）
+type WithdrawalRules struct {
+	AutoApproveMax      money.Amount
+	SecondApprovalAbove money.Amount
+	MinAccountAge       time.Duration
+	MinVerification     string
+	CycleWindow         time.Duration
+	CycleMinTurnover    float64
+	RiskReviewScore     float64
+}
+
+// DefaultWithdrawalRules 由配置載入審核規則
+func DefaultWithdrawalRules() WithdrawalRules {
+	r := WithdrawalRules{
+		AutoApproveMax:      money.FromFloat(10000),
+		SecondApprovalAbove: money.FromFloat(50000),
+		MinAccountAge:       72 * time.Hour,
+		MinVerification:     "phone",
+		CycleWindow:         24 * time.Hour,
+		CycleMinTurnover:    1,
+		RiskReviewScore:     60,
+	}
+	if config.AppConfig != nil {
+		c := config.AppConfig.Review
+		r = WithdrawalRules{
+			AutoApproveMax:      money.FromFloat(c.AutoApproveMax),
+			SecondApprovalAbove: money.FromFloat(c.SecondApprovalAbove),
+			MinAccountAge:       c.MinAccountAge,
+			MinVerification:     c.MinVerification,
+			CycleWindow:         c.CycleWindow,
+			CycleMinTurnover:    c.CycleMinTurnover,
+			RiskReviewScore:     c.RiskReviewScore,
+		}
+	}
+	return r
+}
+
+// RiskCheck 單一規則的檢查結果
+type RiskCheck struct {
+	Rule   string  `json:"rule"`
+	Passed bool    `json:"passed"`
+	Score  float64 `json:"score"` // 未通過時計入的風險分數
+	Detail string  `json:"detail"`
+}
+
+// RiskAssessment 提領風險評估：未通過任一規則即轉人工審核，風險分數達門檻轉風險審核
+type RiskAssessment struct {
+	Decision               string      `json:"decision"`
+	Score                  float64     `json:"score"`
+	RequiresSecondApproval bool        `json:"requires_second_approval"`
+	Checks                 []RiskCheck `json:"checks"`
+}
+
+// withdrawalFacts 評估規則所需的玩家資料
+type withdrawalFacts struct {
+	Amount         money.Amount // 換算為預設幣別的提領金額
+	AmountKnown    bool         // 是否查得匯率
+	AccountAge     time.Duration
+	Verification   string
+	UnmetWagering  money.Amount // 進行中獎金尚未完成的流水
+	RecentDeposits money.Amount // 期間內儲值
+	RecentBets     money.Amount // 期間內下注
+}
+
+// withdrawalRule 審核規則：Check 回傳是否通過與說明，未通過時計入 Weight 分
+type withdrawalRule struct {
+	Name   string
+	Weight float64
+	Check  func(r WithdrawalRules, f *withdrawalFacts) (bool, string)
+}
+
+// withdrawalRuleSet 依序評估的審核規則
+var withdrawalRuleSet = []withdrawalRule{
+	{
+		Name:   "amount_threshold",
+		Weight: 15,
+		Check: func(r WithdrawalRules, f *withdrawalFacts) (bool, string) {
+			if !f.AmountKnown {
+				return false, "查無匯率，無法換算提領金額"
+			}
+			return f.Amount <= r.AutoApproveMax, fmt.Sprintf("提領 %s，自動核准上限 %s", f.Amount, r.AutoApproveMax)
+		},
+	},
+	{
+		Name:   "account_age",
+		Weight: 20,
+		Check: func(r WithdrawalRules, f *withdrawalFacts) (bool, string) {
+			return f.AccountAge >= r.MinAccountAge, fmt.Sprintf("帳號年資 %s，需至少 %s",
+				f.AccountAge.Truncate(time.Minute), r.MinAccountAge)
+		},
+	},
+	{
+		Name:   "verification_level",
+		Weight: 20,
+		Check: func(r WithdrawalRules, f *withdrawalFacts) (bool, string) {
+			return verificationRank[f.Verification] >= verificationRank[r.MinVerification],
+				fmt.Sprintf("驗證等級 %s，需至少 %s", f.Verification, r.MinVerification)
+		},
+	},
+	{
+		Name:   "bonus_wagering",
+		Weight: 40,
+		Check: func(r WithdrawalRules, f *withdrawalFacts) (bool, string) {
+			return !f.UnmetWagering.IsPositive(), fmt.Sprintf("獎金流水尚差 %s", f.UnmetWagering)
+		},
+	},
+	{
+		Name:   "deposit_cycling",
+		Weight: 35,
+		Check: func(r WithdrawalRules, f *withdrawalFacts) (bool, string) {
+			required := money.FromFloat(f.RecentDeposits.Float64() * r.CycleMinTurnover)
+			return !f.RecentDeposits.IsPositive() || f.RecentBets >= required,
+				fmt.Sprintf("%s 內儲值 %s、下注 %s，需下注至少 %s", r.CycleWindow, f.RecentDeposits, f.RecentBets, required)
+		},
+	},
+}
+
+// Evaluate 依規則評估提領
+func (r WithdrawalRules) Evaluate(f *withdrawalFacts) *RiskAssessment {
+	a := &RiskAssessment{Decision: ReviewAutoApproved, Checks: make([]RiskCheck, 0, len(withdrawalRuleSet))}
+	for _, rule := range withdrawalRuleSet {
+		passed, detail := rule.Check(r, f)
+		check := RiskCheck{Rule: rule.Name, Passed: passed, Detail: detail}
+		if !passed {
+			check.Score = rule.Weight
+			a.Score += rule.Weight
+			a.Decision = ReviewManual
+		}
+		a.Checks = append(a.Checks, check)
+	}
+	if a.Score > maxRiskScore {
+		a.Score = maxRiskScore
+	}
+	if a.Score >= r.RiskReviewScore {
+		a.Decision = ReviewRisk
+	}
+	a.RequiresSecondApproval = a.Decision == ReviewRisk || !f.AmountKnown || f.Amount > r.SecondApprovalAbove
+	return a
+}
+
+// assessTx 在呼叫端交易內收集玩家資料並評估提領
+func (s *WithdrawalService) assessTx(tx *sql.Tx, playerID int64, currency string, amount money.Amount) (*RiskAssessment, error) {
+	f := &withdrawalFacts{}
+	converted, _, err := s.FX.ConvertAmount(amount, currency, s.Holds.Wallet.Currency, time.Now())
+	if err == nil {
+		f.Amount, f.AmountKnown = converted, true
+	} else if !errors.Is(err, ErrRateNotFound) {
+		return nil, err
+	}
+
+	var createdAt time.Time
+	if err := tx.QueryRow(`
+		SELECT created_at, COALESCE(verification_level, 'none') FROM players WHERE id = ?
+	`, playerID).Scan(&createdAt, &f.Verification); err != nil {
+		return nil, err
+	}
+	f.AccountAge = time.Since(createdAt)
+
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(GREATEST(remaining_wagering, 0)), 0) FROM bonuses
+		WHERE player_id = ? AND currency = ? AND status = 'active'
+	`, playerID, currency).Scan(&f.UnmetWagering); err != nil {
+		return nil, err
+	}
+
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN transaction_type = 'deposit' THEN amount END), 0),
+		       COALESCE(SUM(CASE WHEN transaction_type = 'bet' THEN amount END), 0)
+		FROM transactions
+		WHERE player_id = ? AND currency = ? AND status = 'completed' AND created_at >= ?
+	`, playerID, currency, time.Now().Add(-s.Rules.CycleWindow)).Scan(&f.RecentDeposits, &f.RecentBets); err != nil {
+		return nil, err
+	}
+	return s.Rules.Evaluate(f), nil
+}
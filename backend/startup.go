@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"nexus-gaming-backend/config"
+	"nexus-gaming-backend/services"
+)
+
+// recoverTableStacks 結清重新啟動前遺留的牌桌帶入
+//
+// 牌桌只存在於記憶體，未結清的帶入依最後一手結束時的籌碼快照結清；須在資料庫連線成功後、開始接受連線前執行。
+func recoverTableStacks() {
+	settled, released, err := services.NewTableStore().RecoverStacks()
+	if err != nil {
+		log.Printf("結清遺留的牌桌帶入失敗: %v", err)
+	} else if settled+released > 0 {
+		log.Printf("已依籌碼快照結清 %d 筆、全額返還 %d 筆遺留的牌桌帶入", settled, released)
+	}
+}
+
+// ensureAgentHierarchy 補建缺少的代理商層級結構（首次啟用或由舊資料升級）；須在資料庫連線成功後執行
+func ensureAgentHierarchy() {
+	services.NewHierarchyService().EnsureBuilt()
+}
+
+// checkPayoutProvider 確認設定的出款提供商可用；設定錯誤時拒絕啟動，避免已核准的提領全數出款失敗
+func checkPayoutProvider() {
+	withdrawals := services.NewWithdrawalService()
+	if err := withdrawals.CheckPayoutProvider(); err != nil {
+		log.Fatalf("出款設定錯誤: %v", err)
+	}
+	if withdrawals.PayoutProvider == "" {
+		log.Printf("未設定 PAYOUT_PROVIDER，已核准的提領不會自動出款")
+	}
+}
+
+// jobInterval 排程間隔（未設定時使用預設值）
+func jobInterval(configured, fallback time.Duration) time.Duration {
+	if configured > 0 {
+		return configured
+	}
+	return fallback
+}
+
+// startBackgroundJobs 啟動定期背景作業，ctx 結束時停止；回傳的 WaitGroup 於所有作業結束後完成
+//
+// 須在資料庫連線成功後執行，每項作業只啟動一次。
+func startBackgroundJobs(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	start := func(run func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run()
+		}()
+	}
+	cfg := config.AppConfig
+
+	// 逾期凍結釋放
+	holds := services.NewHoldService()
+	holdInterval := jobInterval(cfg.Wallet.HoldSweepInterval, time.Minute)
+	start(func() { holds.Run(ctx, holdInterval) })
+
+	// 逾期儲值訂單處理
+	deposits := services.NewDepositService()
+	depositInterval := jobInterval(cfg.Payment.DepositSweepInterval, time.Minute)
+	start(func() { deposits.Run(ctx, depositInterval) })
+
+	// 提領出款（與逾期凍結使用相同間隔）
+	withdrawals := services.NewWithdrawalService()
+	start(func() { withdrawals.RunPayouts(ctx, holdInterval) })
+
+	// 已結束日期的自動結帳
+	reconciliation := services.NewReconciliationService()
+	reconciliationInterval := jobInterval(cfg.Reconciliation.Interval, time.Hour)
+	start(func() { reconciliation.Run(ctx, reconciliationInterval) })
+
+	// 逾期紅利與會員等級紅利
+	bonus := services.NewBonusService()
+	bonusInterval := jobInterval(cfg.Bonus.SweepInterval, 5*time.Minute)
+	start(func() { bonus.Run(ctx, bonusInterval) })
+
+	// 合約到期代理商的停用
+	agents := services.NewAgentService()
+	contractInterval := jobInterval(cfg.Agent.ContractSweepInterval, time.Hour)
+	start(func() { agents.Run(ctx, contractInterval) })
+
+	// 代理商與經銷商已結束期間的結算
+	settlements := services.NewSettlementService()
+	settlementInterval := jobInterval(cfg.Agent.SettlementInterval, time.Hour)
+	start(func() { settlements.Run(ctx, settlementInterval) })
+
+	// 代理商與經銷商每日業績統計的回補與定期重建
+	performance := services.NewPerformanceService()
+	performanceInterval := jobInterval(cfg.Agent.PerformanceInterval, 15*time.Minute)
+	start(func() { performance.Run(ctx, performanceInterval) })
+
+	return &wg
+}
@@ -0,0 +1,114 @@
+package table
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket 連線參數
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096
+)
+
+// Client 單一玩家的 WebSocket 連線
+type Client struct {
+	PlayerID int64
+	Name     string
+
+	conn *websocket.Conn
+	send chan []byte
+
+	mu    sync.Mutex
+	table *Table // 錦標賽換桌時會改變
+
+	closeOnce sync.Once
+}
+
+func newClient(t *Table, conn *websocket.Conn, playerID int64, name string) *Client {
+	return &Client{
+		PlayerID: playerID,
+		Name:     name,
+		conn:     conn,
+		table:    t,
+		send:     make(chan []byte, clientSendSize),
+	}
+}
+
+// currentTable 目前所在的牌桌
+func (c *Client) currentTable() *Table {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.table
+}
+
+// moveTo 換桌（由舊牌桌移出後、新牌桌加入前呼叫）
+func (c *Client) moveTo(t *Table) {
+	c.mu.Lock()
+	c.table = t
+	c.mu.Unlock()
+}
+
+// close 關閉送出佇列（只由牌桌 goroutine 呼叫，可重複呼叫）
+func (c *Client) close() {
+	c.closeOnce.Do(func() { close(c.send) })
+}
+
+// readPump 讀取客戶端訊息並轉交牌桌
+func (c *Client) readPump() {
+	defer func() {
+		c.currentTable().post(command{kind: cmdLeave, client: c})
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.currentTable().post(command{kind: cmdMessage, client: c, msg: &ClientMessage{Type: "invalid"}})
+			continue
+		}
+		c.currentTable().post(command{kind: cmdMessage, client: c, msg: &msg})
+	}
+}
+
+// writePump 將牌桌送出的訊息寫入連線，並定期送出 ping
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package table
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Hub 管理所有房間的牌桌；每個牌桌在首次連線時建立並以獨立 goroutine 執行
+type Hub struct {
+	store       Store
+	turnTimeout time.Duration
+
+	mu     sync.Mutex
+	tables map[int64]*Table
+}
+
+// NewHub 建立牌桌管理器
+func NewHub(store Store, turnTimeout time.Duration) *Hub {
+	return &Hub{store: store, turnTimeout: turnTimeout, tables: map[int64]*Table{}}
+}
+
+// Table 取得房間的牌桌，不存在時載入房間設定並啟動
+func (h *Hub) Table(roomID int64) (*Table, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t, ok := h.tables[roomID]; ok {
+		return t, nil
+	}
+	room, err := h.store.LoadRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+	t := newTable(room, h.store, h.turnTimeout)
+	h.tables[roomID] = t
+	go t.run()
+	return t, nil
+}
+
+// Serve 將已升級的連線加入牌桌並阻塞直到連線結束
+func (h *Hub) Serve(roomID int64, conn *websocket.Conn, playerID int64, name string) error {
+	t, err := h.Table(roomID)
+	if err != nil {
+		return err
+	}
+	c := newClient(t, conn, playerID, name)
+	t.post(command{kind: cmdJoin, client: c})
+	go c.writePump()
+	c.readPump()
+	return nil
+}
+
+// Snapshot 取得房間牌桌的觀察者快照（牌桌尚未啟動時回傳 nil）
+func (h *Hub) Snapshot(roomID int64) *Snapshot {
+	h.mu.Lock()
+	t, ok := h.tables[roomID]
+	h.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return t.Snapshot()
+}
@@ -0,0 +1,100 @@
+package table
+
+import (
+	"time"
+
+	"nexus-gaming-backend/engine/cards"
+	"nexus-gaming-backend/engine/poker"
+)
+
+// 客戶端訊息類型
+const (
+	MsgSit        = "sit"         // 入座（seat、buy_in）
+	MsgStand      = "stand"       // 離座
+	MsgSitIn      = "sit_in"      // 逾時暫離後回到牌局
+	MsgAction     = "action"      // 行動（action、amount）
+	MsgClientSeed = "client_seed" // 提供下一手使用的客戶端種子
+	MsgSnapshot   = "snapshot"    // 要求完整牌桌快照
+)
+
+// 伺服器訊息類型
+const (
+	OutSnapshot    = "snapshot"     // 完整牌桌快照（連線及重新連線時送出）
+	OutEvent       = "event"        // 牌局事件
+	OutTurn        = "turn"         // 輪到某座位行動
+	OutSeatUpdate  = "seat_update"  // 座位變更
+	OutHandPending = "hand_pending" // 下一手即將開始（公布種子雜湊）
+	OutReveal      = "reveal"       // 牌局結束，揭露伺服器種子
+	OutError       = "error"
+	OutInfo        = "info"
+)
+
+// ClientMessage 客戶端送出的訊息
+type ClientMessage struct {
+	Type   string  `json:"type"`
+	Seat   int     `json:"seat,omitempty"`
+	BuyIn  float64 `json:"buy_in,omitempty"`
+	Action string  `json:"action,omitempty"`
+	Amount float64 `json:"amount,omitempty"`
+	Seed   string  `json:"seed,omitempty"`
+}
+
+// ServerMessage 伺服器送出的訊息
+type ServerMessage struct {
+	Type     string       `json:"type"`
+	Event    *poker.Event `json:"event,omitempty"`
+	Snapshot *Snapshot    `json:"snapshot,omitempty"`
+	Turn     *TurnInfo    `json:"turn,omitempty"`
+	Seat     *SeatView    `json:"seat,omitempty"`
+	Hand     *HandInfo    `json:"hand,omitempty"`
+	Message  string       `json:"message,omitempty"`
+	Code     string       `json:"code,omitempty"`
+}
+
+// TurnInfo 行動資訊
+type TurnInfo struct {
+	Seat     int            `json:"seat"`
+	Deadline time.Time      `json:"deadline"`
+	Options  *poker.Options `json:"options,omitempty"`
+}
+
+// HandInfo 牌局識別與公平性資訊
+type HandInfo struct {
+	SessionCode string     `json:"session_code"`
+	SeedHash    string     `json:"seed_hash"`
+	ServerSeed  string     `json:"server_seed,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+}
+
+// SeatView 座位的可見資訊
+type SeatView struct {
+	Seat       int          `json:"seat"`
+	Name       string       `json:"name"`
+	IsBot      bool         `json:"is_bot"`
+	Stack      float64      `json:"stack"`
+	Bet        float64      `json:"bet"`
+	Committed  float64      `json:"committed"`
+	InHand     bool         `json:"in_hand"`
+	Folded     bool         `json:"folded"`
+	AllIn      bool         `json:"all_in"`
+	SittingOut bool         `json:"sitting_out"`
+	Connected  bool         `json:"connected"`
+	Hole       []cards.Card `json:"hole,omitempty"` // 僅本人（或攤牌後）可見
+	HoleCount  int          `json:"hole_count,omitempty"`
+	Up         []cards.Card `json:"up,omitempty"`
+	Empty      bool         `json:"empty,omitempty"`
+}
+
+// Snapshot 牌桌完整快照
+type Snapshot struct {
+	Room     *RoomInfo    `json:"room"`
+	YourSeat int          `json:"your_seat"`
+	Hand     *HandInfo    `json:"hand,omitempty"`
+	Button   int          `json:"button"`
+	Round    int          `json:"round"`
+	Board    []cards.Card `json:"board"`
+	Pot      float64      `json:"pot"`
+	Seats    []SeatView   `json:"seats"`
+	Turn     *TurnInfo    `json:"turn,omitempty"`
+	LastSeq  int          `json:"last_seq"` // 已發生的最後事件序號
+}
@@ -0,0 +1,118 @@
+package table
+
+import (
+	"nexus-gaming-backend/ai"
+	"nexus-gaming-backend/engine/cards"
+	"nexus-gaming-backend/engine/poker"
+)
+
+// RoomInfo 牌桌所屬房間的設定
+type RoomInfo struct {
+	RoomID       int64         `json:"room_id"`
+	RoomCode     string        `json:"room_code"`
+	Name         string        `json:"name"`
+	GameID       int           `json:"game_id"`
+	GameType     string        `json:"game_type"`
+	MaxPlayers   int           `json:"max_players"`
+	MinBet       float64       `json:"min_bet"`
+	MaxBet       float64       `json:"max_bet"`
+	Currency     string        `json:"currency"` // 下注幣別（帶入與結清使用同幣別錢包）
+	Rules        poker.Rules   `json:"rules"`
+	MinBuyIn     float64       `json:"min_buy_in"`
+	MaxBuyIn     float64       `json:"max_buy_in"`
+	AIEnabled    bool          `json:"ai_enabled"`
+	AIDifficulty ai.Difficulty `json:"ai_difficulty"`
+	AIParams     ai.Params     `json:"-"`
+	Tournament   *TableTag     `json:"tournament,omitempty"` // 錦標賽牌桌（一般牌桌為 nil）
+	Practice     bool          `json:"practice,omitempty"`   // 練習場：以遊戲幣入座，不抽水
+}
+
+// TableTag 錦標賽牌桌識別；場次以 session_type = 'tournament' 寫入並於 game_data 記錄
+type TableTag struct {
+	TournamentID int64 `json:"tournament_id"`
+	TableNo      int   `json:"table_no"`
+	Level        int   `json:"level"`
+}
+
+// BotInfo AI 座位資訊
+type BotInfo struct {
+	BotID      string        `json:"bot_id"`
+	Seat       int           `json:"seat"`
+	Name       string        `json:"name"`
+	Difficulty ai.Difficulty `json:"difficulty"`
+	Strategy   string        `json:"strategy"`
+	Seed       int64         `json:"seed"`
+	Chips      float64       `json:"chips"`
+}
+
+// HumanSeat 真人玩家座位
+type HumanSeat struct {
+	Seat     int     `json:"seat"`
+	PlayerID int64   `json:"player_id"`
+	Stack    float64 `json:"stack"`
+}
+
+// HandPlan 開局前的座位規劃
+type HandPlan struct {
+	Room     *RoomInfo
+	Humans   []HumanSeat
+	Bots     []BotInfo // 目前已入座的 AI（籌碼為最新值）
+	Reserved []int     // 真人玩家保留但本手不參與的座位（暫離等），不可補位
+	FillBots bool      // 是否以 AI 補滿空位
+}
+
+// PreparedHand 已建立場次並承諾種子的牌局
+type PreparedHand struct {
+	SessionID   int64     `json:"session_id"`
+	SessionCode string    `json:"session_code"`
+	SeedHash    string    `json:"seed_hash"`
+	Bots        []BotInfo `json:"bots"` // 補位後的所有 AI 座位
+}
+
+// SeatResult 單一座位的牌局結果
+type SeatResult struct {
+	Seat       int           `json:"seat"`
+	PlayerID   int64         `json:"player_id,omitempty"`
+	BotID      string        `json:"bot_id,omitempty"`
+	Difficulty ai.Difficulty `json:"difficulty,omitempty"`
+	Strategy   string        `json:"strategy,omitempty"`
+	StartStack float64       `json:"start_stack"`
+	EndStack   float64       `json:"end_stack"`
+	Committed  float64       `json:"committed"`
+	Won        float64       `json:"won"`
+	Folded     bool          `json:"folded"`
+	Hand       string        `json:"hand,omitempty"`
+}
+
+// HandRecord 牌局結束時寫入的紀錄
+type HandRecord struct {
+	SessionID int64          `json:"session_id"`
+	Rules     poker.Rules    `json:"rules"`
+	Button    int            `json:"button"`
+	Board     []cards.Card   `json:"board,omitempty"`
+	Seats     []SeatResult   `json:"seats"`
+	Left      []int64        `json:"left,omitempty"` // 規劃入座但未參與本手的玩家
+	Result    *poker.Result  `json:"result"`
+	History   *poker.History `json:"history"` // 標準牌局歷史（種子由 Store 揭露後補上）
+	Pot       float64        `json:"pot"`
+}
+
+// Store 牌桌的持久化介面（房間設定、錢包、場次與公平性紀錄）
+//
+// 所有方法皆由牌桌 goroutine 同步呼叫。
+type Store interface {
+	// LoadRoom 讀取房間設定
+	LoadRoom(roomID int64) (*RoomInfo, error)
+	// BuyIn 入座時自錢包扣款
+	BuyIn(room *RoomInfo, playerID int64, amount float64) error
+	// CashOut 離座時將剩餘籌碼轉回錢包
+	CashOut(room *RoomInfo, playerID int64, amount float64) error
+	// PrepareHand 建立場次、承諾伺服器種子，並視需要以 AI 補位
+	PrepareHand(plan *HandPlan) (*PreparedHand, error)
+	// Deck 加入客戶端種子後產生本手牌序
+	Deck(sessionID int64, clientSeeds []string) ([]cards.Card, error)
+	// FinishHand 揭露伺服器種子、寫入牌局結果與不可變的牌局歷史，回傳揭露的種子
+	FinishHand(record *HandRecord) (string, error)
+	// AbortHand 取消未開始的場次
+	AbortHand(sessionID int64) error
+}
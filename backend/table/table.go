@@ -0,0 +1,938 @@
+package table
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"nexus-gaming-backend/ai"
+	"nexus-gaming-backend/engine/cards"
+	"nexus-gaming-backend/engine/poker"
+)
+
+// 牌桌時間參數
+const (
+	HandDelay      = 3 * time.Second        // 牌局結束到下一手開始的間隔（供玩家提交客戶端種子）
+	BotMinDelay    = 800 * time.Millisecond // AI 最短行動時間，避免瞬間行動
+	maxTimeouts    = 3                      // 連續逾時次數達到後暫離
+	inboxSize      = 256
+	clientSendSize = 64
+)
+
+type cmdKind int
+
+const (
+	cmdJoin cmdKind = iota
+	cmdLeave
+	cmdMessage
+	cmdTimeout
+	cmdBotAction
+	cmdStartHand
+	cmdSnapshot
+	cmdTourney
+)
+
+// command 送入牌桌 goroutine 的指令；牌桌狀態只在 run 迴圈中讀寫
+type command struct {
+	kind   cmdKind
+	client *Client
+	msg    *ClientMessage
+	turn   int
+	seat   int
+	action ai.Action
+	reply  chan *Snapshot
+	op     *tourneyOp
+}
+
+// seat 座位狀態
+type seat struct {
+	number         int
+	playerID       int64 // AI 為 0
+	name           string
+	stack          float64
+	bot            ai.Bot
+	botInfo        *BotInfo
+	client         *Client
+	sittingOut     bool
+	timeouts       int
+	leaveAfterHand bool
+	clientSeed     string
+}
+
+func (s *seat) isBot() bool { return s.bot != nil }
+
+// pendingSit 等待 AI 於本手結束後讓出的座位
+type pendingSit struct {
+	client *Client
+	buyIn  float64
+}
+
+// Table 單一房間的牌桌；所有狀態由單一 goroutine 持有，不需要鎖
+type Table struct {
+	room        *RoomInfo
+	store       Store
+	turnTimeout time.Duration
+
+	inbox chan command
+	done  chan struct{}
+
+	clients map[*Client]bool
+	seats   map[int]*seat
+	pending map[int]*pendingSit
+
+	prepared   *PreparedHand
+	planned    map[int]int64 // 規劃入座的真人玩家（座位 → 玩家ID）
+	startsAt   time.Time
+	startToken int
+	hand       *poker.Hand
+	handSeats  map[int]bool
+	button     int
+	turnID     int
+	turnSeat   int
+	deadline   time.Time
+	turnTimer  *time.Timer
+	lastReveal *HandInfo
+
+	// 錦標賽牌桌：座位由 Director 安排，每手結束後暫停直到 Director 完成平衡
+	director *Director
+	held     bool
+}
+
+func newTable(room *RoomInfo, store Store, turnTimeout time.Duration) *Table {
+	return &Table{
+		room:        room,
+		store:       store,
+		turnTimeout: turnTimeout,
+		inbox:       make(chan command, inboxSize),
+		done:        make(chan struct{}),
+		clients:     map[*Client]bool{},
+		seats:       map[int]*seat{},
+		pending:     map[int]*pendingSit{},
+	}
+}
+
+// post 將指令送入牌桌（牌桌已停止時丟棄）
+func (t *Table) post(c command) {
+	select {
+	case t.inbox <- c:
+	case <-t.done:
+	}
+}
+
+// Snapshot 取得觀察者視角的快照（可由其他 goroutine 呼叫）
+func (t *Table) Snapshot() *Snapshot {
+	reply := make(chan *Snapshot, 1)
+	t.post(command{kind: cmdSnapshot, reply: reply})
+	select {
+	case s := <-reply:
+		return s
+	case <-t.done:
+		return nil
+	}
+}
+
+// run 牌桌主迴圈
+func (t *Table) run() {
+	for c := range t.inbox {
+		switch c.kind {
+		case cmdJoin:
+			t.handleJoin(c.client)
+		case cmdLeave:
+			t.handleLeave(c.client)
+		case cmdMessage:
+			t.handleMessage(c.client, c.msg)
+		case cmdTimeout:
+			t.handleTimeout(c.turn)
+		case cmdBotAction:
+			t.handleBotAction(c.turn, c.seat, c.action)
+		case cmdStartHand:
+			t.startHand(c.turn)
+		case cmdSnapshot:
+			c.reply <- t.snapshot(0)
+		case cmdTourney:
+			if !t.handleTourney(c.op) {
+				return
+			}
+		}
+	}
+}
+
+// ---- 連線 ----
+
+func (t *Table) handleJoin(c *Client) {
+	// 同一玩家重新連線：關閉舊連線並接手座位
+	for old := range t.clients {
+		if old.PlayerID == c.PlayerID {
+			delete(t.clients, old)
+			old.close()
+		}
+	}
+	t.clients[c] = true
+
+	if s := t.seatOf(c.PlayerID); s != nil {
+		s.client = c
+		t.broadcastSeat(s)
+	}
+	t.sendSnapshot(c)
+}
+
+func (t *Table) handleLeave(c *Client) {
+	if !t.clients[c] {
+		return
+	}
+	delete(t.clients, c)
+	c.close()
+	if s := t.seatOf(c.PlayerID); s != nil && s.client == c {
+		s.client = nil
+		t.broadcastSeat(s)
+	}
+}
+
+// ---- 客戶端訊息 ----
+
+func (t *Table) handleMessage(c *Client, m *ClientMessage) {
+	if !t.clients[c] {
+		return
+	}
+	switch m.Type {
+	case MsgSnapshot:
+		t.sendSnapshot(c)
+	case MsgSit, MsgStand:
+		if t.director != nil {
+			t.sendError(c, "TOURNAMENT_TABLE", "錦標賽座位由系統安排")
+			return
+		}
+		if m.Type == MsgSit {
+			t.handleSit(c, m.Seat, m.BuyIn)
+		} else {
+			t.handleStand(c)
+		}
+	case MsgSitIn:
+		if s := t.seatOf(c.PlayerID); s != nil {
+			s.sittingOut = false
+			s.timeouts = 0
+			t.broadcastSeat(s)
+			t.maybeScheduleHand()
+		}
+	case MsgClientSeed:
+		s := t.seatOf(c.PlayerID)
+		if s == nil {
+			t.sendError(c, "NOT_SEATED", "尚未入座")
+			return
+		}
+		if m.Seed == "" || len(m.Seed) > 64 {
+			t.sendError(c, "INVALID_SEED", "客戶端種子長度必須介於 1 到 64 字元")
+			return
+		}
+		s.clientSeed = m.Seed
+		t.send(c, ServerMessage{Type: OutInfo, Message: "客戶端種子將套用於下一手"})
+	case MsgAction:
+		t.handlePlayerAction(c, ai.Action{Type: ai.ActionType(m.Action), Amount: m.Amount})
+	default:
+		t.sendError(c, "UNKNOWN_MESSAGE", "未知的訊息類型: "+m.Type)
+	}
+}
+
+func (t *Table) handleSit(c *Client, number int, buyIn float64) {
+	if t.seatOf(c.PlayerID) != nil {
+		t.sendError(c, "ALREADY_SEATED", "已經入座")
+		return
+	}
+	if number < 1 || number > t.room.MaxPlayers {
+		t.sendError(c, "INVALID_SEAT", "座位號無效")
+		return
+	}
+	if buyIn < t.room.MinBuyIn || buyIn > t.room.MaxBuyIn {
+		t.sendError(c, "INVALID_BUY_IN", fmt.Sprintf("帶入籌碼必須介於 %.2f 到 %.2f", t.room.MinBuyIn, t.room.MaxBuyIn))
+		return
+	}
+
+	if s, ok := t.seats[number]; ok {
+		if !s.isBot() {
+			t.sendError(c, "SEAT_TAKEN", "座位已有玩家")
+			return
+		}
+		// AI 正在牌局中：本手結束後讓出座位
+		if t.inHand(number) {
+			if _, queued := t.pending[number]; queued {
+				t.sendError(c, "SEAT_TAKEN", "座位已有其他玩家等候")
+				return
+			}
+			t.pending[number] = &pendingSit{client: c, buyIn: buyIn}
+			t.send(c, ServerMessage{Type: OutInfo, Message: "座位將於本手結束後讓出"})
+			return
+		}
+		delete(t.seats, number)
+	}
+	t.seatHuman(c, number, buyIn)
+}
+
+// seatHuman 扣款並讓真人玩家入座
+func (t *Table) seatHuman(c *Client, number int, buyIn float64) {
+	if err := t.store.BuyIn(t.room, c.PlayerID, buyIn); err != nil {
+		t.sendError(c, "BUY_IN_FAILED", "帶入籌碼失敗: "+err.Error())
+		return
+	}
+	s := &seat{number: number, playerID: c.PlayerID, name: c.Name, stack: buyIn}
+	if t.clients[c] {
+		s.client = c
+	}
+	t.seats[number] = s
+	t.broadcastSeat(s)
+	t.maybeScheduleHand()
+}
+
+func (t *Table) handleStand(c *Client) {
+	s := t.seatOf(c.PlayerID)
+	if s == nil {
+		for n, p := range t.pending {
+			if p.client == c {
+				delete(t.pending, n)
+			}
+		}
+		return
+	}
+	if t.inHand(s.number) {
+		// 牌局進行中：輪到時自動過牌／蓋牌，本手結束後離座
+		s.leaveAfterHand = true
+		if t.turnSeat == s.number {
+			t.autoAct(s.number, false)
+		}
+		return
+	}
+	t.unseat(s)
+}
+
+// unseat 離座並結清籌碼（籌碼輸光時同樣結清，讓帶入凍結完成扣款）
+func (t *Table) unseat(s *seat) {
+	if !s.isBot() {
+		if err := t.store.CashOut(t.room, s.playerID, s.stack); err != nil {
+			log.Printf("牌桌 %s 玩家 %d 結清籌碼失敗: %v", t.room.RoomCode, s.playerID, err)
+			if s.client != nil {
+				t.sendError(s.client, "CASH_OUT_FAILED", "結清籌碼失敗，請聯繫客服")
+			}
+			return
+		}
+	}
+	delete(t.seats, s.number)
+	t.broadcast(ServerMessage{Type: OutSeatUpdate, Seat: &SeatView{Seat: s.number, Empty: true}})
+
+	// 沒有真人玩家時 AI 也一併離座
+	if !s.isBot() && t.humanCount() == 0 && t.hand == nil {
+		for _, b := range t.seats {
+			if b.isBot() {
+				t.unseat(b)
+			}
+		}
+	}
+}
+
+// ---- 行動 ----
+
+func (t *Table) handlePlayerAction(c *Client, a ai.Action) {
+	s := t.seatOf(c.PlayerID)
+	if s == nil || t.hand == nil || t.turnSeat != s.number {
+		t.sendError(c, "NOT_YOUR_TURN", "尚未輪到你行動")
+		return
+	}
+	if err := t.apply(s.number, a, false); err != nil {
+		t.sendError(c, "INVALID_ACTION", err.Error())
+		return
+	}
+	s.timeouts = 0
+	t.advance()
+}
+
+func (t *Table) handleTimeout(turn int) {
+	if t.hand == nil || turn != t.turnID {
+		return
+	}
+	seat := t.turnSeat
+	t.autoAct(seat, true)
+	if s := t.seats[seat]; s != nil && !s.isBot() {
+		s.timeouts++
+		if s.timeouts >= maxTimeouts && !s.sittingOut {
+			s.sittingOut = true
+			t.broadcastSeat(s)
+		}
+	}
+}
+
+func (t *Table) handleBotAction(turn, seat int, a ai.Action) {
+	if t.hand == nil || turn != t.turnID || seat != t.turnSeat {
+		return
+	}
+	if err := t.apply(seat, a, false); err != nil {
+		// AI 動作不合法時改為安全動作
+		t.autoAct(seat, false)
+		return
+	}
+	t.advance()
+}
+
+// autoAct 系統代為行動：可過牌則過牌，否則蓋牌
+func (t *Table) autoAct(seat int, timeout bool) {
+	opts, ok := t.hand.Options()
+	if !ok || opts.Seat != seat {
+		return
+	}
+	a := ai.Action{Type: ai.ActionFold}
+	if opts.CanCheck {
+		a.Type = ai.ActionCheck
+	}
+	if err := t.apply(seat, a, timeout); err != nil {
+		log.Printf("牌桌 %s 座位 %d 自動行動失敗: %v", t.room.RoomCode, seat, err)
+		return
+	}
+	t.advance()
+}
+
+// apply 將動作交給引擎並廣播事件
+func (t *Table) apply(seat int, a ai.Action, timeout bool) error {
+	events, err := t.hand.Act(seat, poker.Action{Type: poker.ActionType(a.Type), Amount: a.Amount}, timeout)
+	if err != nil {
+		return err
+	}
+	t.stopTurnTimer()
+	t.turnSeat = 0
+	t.broadcastEvents(events)
+	return nil
+}
+
+// advance 推進到下一位行動者或結算
+func (t *Table) advance() {
+	if t.hand.Over() {
+		t.finishHand()
+		return
+	}
+	seatNo := t.hand.ToAct()
+	opts, _ := t.hand.Options()
+	t.turnID++
+	t.turnSeat = seatNo
+	t.deadline = time.Now().Add(t.turnTimeout)
+	t.broadcast(ServerMessage{Type: OutTurn, Turn: &TurnInfo{Seat: seatNo, Deadline: t.deadline, Options: &opts}})
+
+	turn := t.turnID
+	t.turnTimer = time.AfterFunc(t.turnTimeout, func() {
+		t.post(command{kind: cmdTimeout, turn: turn})
+	})
+
+	s := t.seats[seatNo]
+	switch {
+	case s == nil:
+		t.autoAct(seatNo, false)
+	case s.isBot():
+		view := t.botView(seatNo, opts)
+		bot := s.bot
+		go func() {
+			start := time.Now()
+			a := ai.Act(context.Background(), bot, view, t.turnTimeout)
+			if d := BotMinDelay - time.Since(start); d > 0 {
+				time.Sleep(d)
+			}
+			t.post(command{kind: cmdBotAction, turn: turn, seat: seatNo, action: a})
+		}()
+	case s.leaveAfterHand:
+		t.autoAct(seatNo, false)
+	case t.director != nil && s.sittingOut:
+		// 錦標賽暫離的玩家仍會發牌並繳盲注，輪到時立即代為行動
+		t.autoAct(seatNo, false)
+	}
+}
+
+func (t *Table) stopTurnTimer() {
+	if t.turnTimer != nil {
+		t.turnTimer.Stop()
+		t.turnTimer = nil
+	}
+}
+
+// botView 組合 AI 可見的牌局資訊
+func (t *Table) botView(seatNo int, opts poker.Options) *ai.View {
+	view := &ai.View{
+		GameType: t.room.GameType,
+		Round:    t.hand.Round(),
+		Seat:     seatNo,
+		Board:    t.hand.Board(),
+		Pot:      t.hand.Pot(),
+		ToCall:   opts.ToCall,
+		MinRaise: opts.MinRaise,
+		Stack:    opts.Stack,
+		BigBlind: t.room.Rules.BigBlind,
+	}
+	players := t.hand.Players()
+	order := 0
+	for _, p := range players {
+		if p.Folded {
+			continue
+		}
+		view.Players++
+		if p.Seat == seatNo {
+			view.HoleCards = p.Hole
+			view.UpCards = p.Up
+			continue
+		}
+		view.Opponents = append(view.Opponents, ai.Opponent{
+			Seat: p.Seat, Stack: p.Stack, UpCards: p.Up, Folded: p.Folded, AllIn: p.AllIn,
+		})
+	}
+	// 行動順序：按鈕位之後的座位依序計算
+	for _, p := range players {
+		if p.Folded || p.Seat == seatNo {
+			continue
+		}
+		if relSeat(p.Seat, t.button, t.room.MaxPlayers) < relSeat(seatNo, t.button, t.room.MaxPlayers) {
+			order++
+		}
+	}
+	view.Position = order
+	return view
+}
+
+// relSeat 座位相對於按鈕位的順序（按鈕位左側為 1）
+func relSeat(seat, button, max int) int {
+	return (seat - button - 1 + max) % max
+}
+
+// ---- 牌局流程 ----
+
+// maybeScheduleHand 條件滿足時建立下一手的場次並排程開始
+func (t *Table) maybeScheduleHand() {
+	if t.hand != nil || t.prepared != nil || t.held {
+		return
+	}
+	humans := []HumanSeat{}
+	bots := []BotInfo{}
+	reserved := []int{}
+	for _, s := range t.orderedSeats() {
+		if !t.eligible(s) {
+			if !s.isBot() {
+				reserved = append(reserved, s.number)
+			}
+			continue
+		}
+		if s.isBot() {
+			info := *s.botInfo
+			info.Chips = s.stack
+			bots = append(bots, info)
+			continue
+		}
+		humans = append(humans, HumanSeat{Seat: s.number, PlayerID: s.playerID, Stack: s.stack})
+	}
+	if len(humans) == 0 {
+		return
+	}
+	if len(humans)+len(bots) < 2 && !t.room.AIEnabled {
+		return
+	}
+
+	prepared, err := t.store.PrepareHand(&HandPlan{Room: t.room, Humans: humans, Bots: bots, Reserved: reserved, FillBots: t.room.AIEnabled})
+	if err != nil {
+		log.Printf("牌桌 %s 建立場次失敗: %v", t.room.RoomCode, err)
+		return
+	}
+
+	// 補位的 AI 入座
+	for _, b := range prepared.Bots {
+		if _, taken := t.seats[b.Seat]; taken {
+			continue
+		}
+		bot, err := ai.New(b.Difficulty, t.room.AIParams, b.Seed)
+		if err != nil {
+			log.Printf("牌桌 %s 建立 AI 失敗: %v", t.room.RoomCode, err)
+			continue
+		}
+		info := b
+		s := &seat{number: b.Seat, name: b.Name, stack: b.Chips, bot: bot, botInfo: &info}
+		t.seats[b.Seat] = s
+		t.broadcastSeat(s)
+	}
+
+	eligible := 0
+	for _, s := range t.seats {
+		if t.eligible(s) {
+			eligible++
+		}
+	}
+	if eligible < 2 {
+		if err := t.store.AbortHand(prepared.SessionID); err != nil {
+			log.Printf("牌桌 %s 取消場次失敗: %v", t.room.RoomCode, err)
+		}
+		return
+	}
+
+	t.prepared = prepared
+	t.planned = map[int]int64{}
+	for _, h := range humans {
+		t.planned[h.Seat] = h.PlayerID
+	}
+	t.startsAt = time.Now().Add(HandDelay)
+	t.startToken++
+	token := t.startToken
+	t.broadcast(ServerMessage{Type: OutHandPending, Hand: t.handInfo()})
+	time.AfterFunc(HandDelay, func() {
+		t.post(command{kind: cmdStartHand, turn: token})
+	})
+}
+
+// startHand 依承諾的種子洗牌並開局
+func (t *Table) startHand(token int) {
+	if t.prepared == nil || token != t.startToken || t.hand != nil {
+		return
+	}
+	prepared := t.prepared
+
+	// 只有規劃時在座、仍有籌碼且未暫離的玩家參與
+	infos := []poker.SeatInfo{}
+	seeds := []string{}
+	for _, s := range t.orderedSeats() {
+		if !t.eligible(s) {
+			continue
+		}
+		if !s.isBot() {
+			if t.planned[s.number] != s.playerID {
+				continue
+			}
+			if s.clientSeed != "" {
+				seeds = append(seeds, s.clientSeed)
+			}
+		}
+		info := poker.SeatInfo{Seat: s.number, PlayerID: s.playerID, Name: s.name, Stack: s.stack}
+		if s.isBot() {
+			info.BotID = s.botInfo.BotID
+		}
+		infos = append(infos, info)
+	}
+	if len(infos) < 2 {
+		t.abortPrepared()
+		return
+	}
+
+	deck, err := t.store.Deck(prepared.SessionID, seeds)
+	if err != nil {
+		log.Printf("牌桌 %s 產生牌序失敗: %v", t.room.RoomCode, err)
+		t.abortPrepared()
+		return
+	}
+
+	t.button = t.nextButton(infos)
+	hand, err := poker.NewHand(t.room.Rules, infos, t.button, deck)
+	if err != nil {
+		log.Printf("牌桌 %s 開局失敗: %v", t.room.RoomCode, err)
+		t.abortPrepared()
+		return
+	}
+	t.hand = hand
+	t.handSeats = map[int]bool{}
+	for _, info := range infos {
+		t.handSeats[info.Seat] = true
+	}
+
+	events, _ := hand.Start()
+	t.broadcastEvents(events)
+	t.advance()
+}
+
+// abortPrepared 取消已建立但無法開始的場次
+func (t *Table) abortPrepared() {
+	if t.prepared != nil {
+		if err := t.store.AbortHand(t.prepared.SessionID); err != nil {
+			log.Printf("牌桌 %s 取消場次失敗: %v", t.room.RoomCode, err)
+		}
+	}
+	t.prepared = nil
+	t.planned = nil
+}
+
+// finishHand 寫入結果、更新籌碼並處理離座與補位
+func (t *Table) finishHand() {
+	t.stopTurnTimer()
+	t.turnSeat = 0
+	hand := t.hand
+	prepared := t.prepared
+
+	record := &HandRecord{
+		SessionID: prepared.SessionID,
+		Rules:     hand.Rules(),
+		Button:    hand.Button(),
+		Board:     hand.Board(),
+		Result:    hand.Result(),
+		Pot:       hand.Pot(),
+	}
+	if history, err := poker.NewHistory(hand); err == nil {
+		history.SessionID = prepared.SessionID
+		history.SessionCode = prepared.SessionCode
+		history.Currency = t.room.Currency
+		history.Table = poker.HistoryTable{
+			RoomID: t.room.RoomID, RoomCode: t.room.RoomCode, Name: t.room.Name, MaxPlayers: t.room.MaxPlayers,
+		}
+		record.History = history
+	}
+	for _, p := range hand.Players() {
+		r := SeatResult{
+			Seat: p.Seat, PlayerID: p.PlayerID, BotID: p.BotID,
+			StartStack: p.StartStack, EndStack: p.Stack, Committed: p.Committed, Won: p.Won, Folded: p.Folded,
+		}
+		if s := t.seats[p.Seat]; s != nil && s.isBot() {
+			r.Difficulty = s.botInfo.Difficulty
+			r.Strategy = s.bot.Strategy()
+		}
+		if h, ok := hand.Result().Hands[p.Seat]; ok {
+			r.Hand = h
+		}
+		record.Seats = append(record.Seats, r)
+		if s := t.seats[p.Seat]; s != nil {
+			s.stack = p.Stack
+		}
+	}
+	for seatNo, playerID := range t.planned {
+		if !t.handSeats[seatNo] {
+			record.Left = append(record.Left, playerID)
+		}
+	}
+
+	serverSeed, err := t.store.FinishHand(record)
+	if err != nil {
+		log.Printf("牌桌 %s 寫入牌局結果失敗: %v", t.room.RoomCode, err)
+	}
+	info := t.handInfo()
+	info.ServerSeed = serverSeed
+	info.StartsAt = nil
+	t.lastReveal = info
+	t.broadcast(ServerMessage{Type: OutReveal, Hand: info})
+
+	t.hand = nil
+	t.handSeats = nil
+	t.prepared = nil
+	t.planned = nil
+
+	if t.director != nil {
+		t.reportHand(record)
+		return
+	}
+
+	// 離座、破產與等候座位
+	for _, s := range t.orderedSeats() {
+		switch {
+		case s.leaveAfterHand:
+			t.unseat(s)
+		case s.stack <= 0:
+			t.unseat(s)
+		}
+	}
+	for number, p := range t.pending {
+		delete(t.pending, number)
+		if s, ok := t.seats[number]; ok {
+			if !s.isBot() {
+				t.sendError(p.client, "SEAT_TAKEN", "座位已有玩家")
+				continue
+			}
+			t.unseat(s)
+		}
+		if t.seatOf(p.client.PlayerID) == nil {
+			t.seatHuman(p.client, number, p.buyIn)
+		}
+	}
+	if t.humanCount() == 0 {
+		for _, s := range t.orderedSeats() {
+			if s.isBot() {
+				t.unseat(s)
+			}
+		}
+		return
+	}
+
+	t.maybeScheduleHand()
+}
+
+// nextButton 按鈕位移到上一手按鈕之後的下一位參與者
+func (t *Table) nextButton(infos []poker.SeatInfo) int {
+	for _, info := range infos {
+		if info.Seat > t.button {
+			return info.Seat
+		}
+	}
+	return infos[0].Seat
+}
+
+// ---- 查詢輔助 ----
+
+func (t *Table) eligible(s *seat) bool {
+	if t.director != nil {
+		return s.stack > 0
+	}
+	return s.stack > 0 && !s.sittingOut && !s.leaveAfterHand
+}
+
+func (t *Table) inHand(number int) bool {
+	return t.hand != nil && t.handSeats[number]
+}
+
+func (t *Table) seatOf(playerID int64) *seat {
+	for _, s := range t.seats {
+		if !s.isBot() && s.playerID == playerID {
+			return s
+		}
+	}
+	return nil
+}
+
+func (t *Table) humanCount() int {
+	n := 0
+	for _, s := range t.seats {
+		if !s.isBot() {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *Table) orderedSeats() []*seat {
+	list := make([]*seat, 0, len(t.seats))
+	for _, s := range t.seats {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].number < list[j].number })
+	return list
+}
+
+func (t *Table) handInfo() *HandInfo {
+	if t.prepared == nil {
+		return nil
+	}
+	info := &HandInfo{SessionCode: t.prepared.SessionCode, SeedHash: t.prepared.SeedHash}
+	if t.hand == nil && !t.startsAt.IsZero() {
+		at := t.startsAt
+		info.StartsAt = &at
+	}
+	return info
+}
+
+// ---- 輸出 ----
+
+// seatView 座位的可見資訊；viewer 為觀看者的座位（0 為觀察者）
+func (t *Table) seatView(s *seat, viewer int) SeatView {
+	v := SeatView{
+		Seat: s.number, Name: s.name, IsBot: s.isBot(), Stack: s.stack,
+		SittingOut: s.sittingOut, Connected: s.isBot() || s.client != nil,
+	}
+	if t.hand == nil || !t.handSeats[s.number] {
+		return v
+	}
+	p, ok := t.hand.Player(s.number)
+	if !ok {
+		return v
+	}
+	v.InHand = true
+	v.Stack = p.Stack
+	v.Bet = p.Bet
+	v.Committed = p.Committed
+	v.Folded = p.Folded
+	v.AllIn = p.AllIn
+	v.Up = p.Up
+	v.HoleCount = len(p.Hole)
+	if viewer == s.number {
+		v.Hole = p.Hole
+	}
+	return v
+}
+
+func (t *Table) snapshot(viewer int) *Snapshot {
+	snap := &Snapshot{Room: t.room, YourSeat: viewer, Button: t.button, Board: []cards.Card{}, Hand: t.handInfo()}
+	if snap.Hand == nil {
+		snap.Hand = t.lastReveal
+	}
+	for n := 1; n <= t.room.MaxPlayers; n++ {
+		if s, ok := t.seats[n]; ok {
+			snap.Seats = append(snap.Seats, t.seatView(s, viewer))
+		} else {
+			snap.Seats = append(snap.Seats, SeatView{Seat: n, Empty: true})
+		}
+	}
+	if t.hand != nil {
+		snap.Board = t.hand.Board()
+		snap.Round = t.hand.Round()
+		snap.Pot = t.hand.Pot()
+		if events := t.hand.Events(); len(events) > 0 {
+			snap.LastSeq = events[len(events)-1].Seq
+		}
+		if t.turnSeat != 0 {
+			opts, _ := t.hand.Options()
+			snap.Turn = &TurnInfo{Seat: t.turnSeat, Deadline: t.deadline, Options: &opts}
+		}
+	}
+	return snap
+}
+
+func (t *Table) viewerSeat(c *Client) int {
+	if s := t.seatOf(c.PlayerID); s != nil {
+		return s.number
+	}
+	return 0
+}
+
+func (t *Table) sendSnapshot(c *Client) {
+	t.send(c, ServerMessage{Type: OutSnapshot, Snapshot: t.snapshot(t.viewerSeat(c))})
+}
+
+// broadcastEvents 廣播引擎事件；暗牌只送給該座位
+func (t *Table) broadcastEvents(events []poker.Event) {
+	for _, e := range events {
+		for c := range t.clients {
+			ev := e
+			if e.Private() && t.viewerSeat(c) != e.Seat {
+				ev = e.Redacted()
+			}
+			t.send(c, ServerMessage{Type: OutEvent, Event: &ev})
+		}
+	}
+}
+
+// broadcastSeat 廣播座位變更（依觀看者決定是否包含暗牌）
+func (t *Table) broadcastSeat(s *seat) {
+	for c := range t.clients {
+		v := t.seatView(s, t.viewerSeat(c))
+		t.send(c, ServerMessage{Type: OutSeatUpdate, Seat: &v})
+	}
+}
+
+func (t *Table) broadcast(m ServerMessage) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	for c := range t.clients {
+		t.deliver(c, data)
+	}
+}
+
+func (t *Table) send(c *Client, m ServerMessage) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	t.deliver(c, data)
+}
+
+func (t *Table) sendError(c *Client, code, message string) {
+	t.send(c, ServerMessage{Type: OutError, Code: code, Message: message})
+}
+
+// deliver 非阻塞寫入客戶端佇列；佇列已滿代表客戶端過慢，直接斷線
+func (t *Table) deliver(c *Client, data []byte) {
+	if !t.clients[c] {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		delete(t.clients, c)
+		c.close()
+		if s := t.seatOf(c.PlayerID); s != nil && s.client == c {
+			s.client = nil
+		}
+	}
+}
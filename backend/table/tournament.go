@@ -0,0 +1,851 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"nexus-gaming-backend/engine/tournament"
+
+	"github.com/gorilla/websocket"
+)
+
+// 錦標賽相關錯誤
+var (
+	ErrTournamentNotRunning = errors.New("錦標賽未在進行中")
+	ErrTournamentRunning    = errors.New("錦標賽已在進行中")
+	ErrTournamentNotOpen    = errors.New("錦標賽不在報名階段")
+	ErrNotEnoughEntrants    = errors.New("參賽人數不足")
+	ErrTournamentPaused     = errors.New("錦標賽已暫停")
+	ErrTournamentNotPaused  = errors.New("錦標賽未暫停")
+)
+
+// TournamentInfo 錦標賽設定
+type TournamentInfo struct {
+	ID            int64               `json:"id"`
+	Code          string              `json:"tournament_code"`
+	Name          string              `json:"name"`
+	Room          *RoomInfo           `json:"room"` // 基礎房間（遊戲類型與場次所屬房間）
+	Schedule      tournament.Schedule `json:"blind_schedule"`
+	SeatsPerTable int                 `json:"seats_per_table"`
+	MinEntrants   int                 `json:"min_entrants"`
+	Status        string              `json:"status"`
+	Level         int                 `json:"current_level"`
+	LevelElapsed  time.Duration       `json:"-"` // 目前級別已進行的時間（暫停或重啟時保存）
+}
+
+// TournamentEntry 仍在場的參賽玩家（籌碼與座位）
+type TournamentEntry struct {
+	PlayerID int64   `json:"player_id"`
+	Name     string  `json:"name"`
+	Stack    float64 `json:"stack"`
+	TableNo  int     `json:"table_no"`
+	Seat     int     `json:"seat"`
+}
+
+// Placement 淘汰名次
+type Placement struct {
+	PlayerID int64 `json:"player_id"`
+	Position int   `json:"position"`
+}
+
+// TournamentStore 錦標賽的持久化介面（牌局本身沿用 Store）
+//
+// 除 LoadTournament、StartTournament 與 PendingTournaments 外，皆由 Director goroutine 同步呼叫。
+type TournamentStore interface {
+	Store
+	// LoadTournament 讀取錦標賽設定與尚未淘汰的參賽玩家
+	LoadTournament(id int64) (*TournamentInfo, []TournamentEntry, error)
+	// StartTournament 將錦標賽標記為進行中並寫入初始座位
+	StartTournament(id int64, entries []TournamentEntry) error
+	// PendingTournaments 已到開賽時間的錦標賽，以及進行中（需於重啟後恢復）的錦標賽
+	PendingTournaments() (due []int64, active []int64, err error)
+	// SaveLevel 保存目前級別、級別已進行時間與暫停狀態
+	SaveLevel(id int64, level int, elapsed time.Duration, paused bool) error
+	// SaveStacks 保存在場玩家的籌碼與座位
+	SaveStacks(id int64, entries []TournamentEntry) error
+	// Eliminate 記錄淘汰名次
+	Eliminate(id int64, placements []Placement) error
+	// CompleteTournament 記錄冠軍、依獎金結構派發獎金並結束錦標賽
+	CompleteTournament(id int64, winner int64) error
+	// CancelTournament 取消錦標賽並退還所有參賽者的報名費與服務費
+	CancelTournament(id int64) error
+}
+
+// TournamentState 錦標賽即時狀態
+type TournamentState struct {
+	ID          int64                  `json:"id"`
+	Name        string                 `json:"name"`
+	Paused      bool                   `json:"paused"`
+	Level       int                    `json:"level"`
+	Blinds      tournament.Level       `json:"blinds"`
+	NextLevelAt *time.Time             `json:"next_level_at,omitempty"`
+	Remaining   int                    `json:"remaining"`
+	Tables      []tournament.TableLoad `json:"tables"`
+	Players     []TournamentEntry      `json:"players"` // 依籌碼由多到少
+}
+
+type dirKind int
+
+const (
+	dirHandDone dirKind = iota
+	dirLevelUp
+	dirPause
+	dirResume
+	dirCancel
+	dirRegister
+	dirLocate
+	dirState
+)
+
+// directorCmd 送入 Director goroutine 的指令
+type directorCmd struct {
+	kind     dirKind
+	report   *handReport
+	token    int
+	entry    *TournamentEntry
+	playerID int64
+	err      chan error
+	table    chan *Table
+	state    chan *TournamentState
+}
+
+// Director 單一錦標賽的賽事控制：盲注升級、淘汰名次、併桌與平衡
+//
+// 與牌桌相同，所有狀態由單一 goroutine 持有；牌桌每手結束後暫停並回報，
+// Director 處理淘汰與換桌後再讓牌桌繼續。
+type Director struct {
+	info        *TournamentInfo
+	store       TournamentStore
+	turnTimeout time.Duration
+	onExit      func()
+
+	inbox chan directorCmd
+	done  chan struct{}
+
+	tables  map[int]*Table
+	players map[int64]*TournamentEntry // 尚未淘汰的玩家
+
+	level      int
+	elapsed    time.Duration // 目前級別於 levelAt 之前已進行的時間
+	levelAt    time.Time
+	levelTimer *time.Timer
+	levelToken int
+	paused     bool
+	stopped    bool
+}
+
+func newDirector(info *TournamentInfo, store TournamentStore, turnTimeout time.Duration) *Director {
+	return &Director{
+		info:        info,
+		store:       store,
+		turnTimeout: turnTimeout,
+		onExit:      func() {},
+		inbox:       make(chan directorCmd, inboxSize),
+		done:        make(chan struct{}),
+		tables:      map[int]*Table{},
+		players:     map[int64]*TournamentEntry{},
+		level:       info.Level,
+		elapsed:     info.LevelElapsed,
+		paused:      info.Status == "paused",
+	}
+}
+
+// post 將指令送入 Director（已結束時丟棄）
+func (d *Director) post(c directorCmd) {
+	select {
+	case d.inbox <- c:
+	case <-d.done:
+	}
+}
+
+// request 送出需要回覆的指令
+func (d *Director) request(c directorCmd) error {
+	c.err = make(chan error, 1)
+	d.post(c)
+	select {
+	case err := <-c.err:
+		return err
+	case <-d.done:
+		return ErrTournamentNotRunning
+	}
+}
+
+// run Director 主迴圈
+func (d *Director) run() {
+	defer d.onExit()
+	for c := range d.inbox {
+		switch c.kind {
+		case dirHandDone:
+			d.handDone(c.report)
+		case dirLevelUp:
+			d.levelUp(c.token)
+		case dirPause:
+			c.err <- d.pause()
+		case dirResume:
+			c.err <- d.resume()
+		case dirCancel:
+			c.err <- d.cancel()
+		case dirRegister:
+			if _, ok := d.players[c.entry.PlayerID]; !ok {
+				d.place(c.entry)
+				d.saveStacks()
+			}
+		case dirLocate:
+			c.table <- d.locate(c.playerID)
+		case dirState:
+			c.state <- d.state()
+		}
+		if d.stopped {
+			close(d.done)
+			return
+		}
+	}
+}
+
+// ---- 開賽與座位 ----
+
+// seatAll 隨機分配初始座位，各桌人數差距不超過 1
+func seatAll(entries []TournamentEntry, seatsPerTable int) []TournamentEntry {
+	seated := append([]TournamentEntry{}, entries...)
+	rand.Shuffle(len(seated), func(i, j int) { seated[i], seated[j] = seated[j], seated[i] })
+	tables := tournament.TablesNeeded(len(seated), seatsPerTable)
+	for i := range seated {
+		seated[i].TableNo = i%tables + 1
+		seated[i].Seat = i/tables + 1
+	}
+	return seated
+}
+
+// open 建立牌桌並讓玩家入座（尚未分配座位的延遲報名玩家補到人數最少的牌桌）
+func (d *Director) open(entries []TournamentEntry) {
+	byTable := map[int][]movedPlayer{}
+	var unseated []TournamentEntry
+	for _, e := range entries {
+		if e.TableNo == 0 || e.Seat == 0 {
+			unseated = append(unseated, e)
+			continue
+		}
+		entry := e
+		d.players[e.PlayerID] = &entry
+		byTable[e.TableNo] = append(byTable[e.TableNo], movedPlayer{playerID: e.PlayerID, name: e.Name, seat: e.Seat, stack: e.Stack})
+	}
+	for no, players := range byTable {
+		t := d.createTable(no)
+		t.post(tourneyCommand(&tourneyOp{kind: opSeat, players: players}))
+	}
+	for i := range unseated {
+		d.place(&unseated[i])
+	}
+	d.saveStacks()
+	d.scheduleLevel()
+}
+
+// createTable 建立並啟動錦標賽牌桌
+func (d *Director) createTable(no int) *Table {
+	room := *d.info.Room
+	room.Name = tableName(d.info.Name, no)
+	room.MaxPlayers = d.info.SeatsPerTable
+	room.Rules = d.blinds().Rules(d.info.Room.Rules.Variant)
+	room.MinBuyIn, room.MaxBuyIn = 0, 0
+	room.AIEnabled = false
+	room.Tournament = &TableTag{TournamentID: d.info.ID, TableNo: no, Level: d.level}
+
+	t := newTable(&room, d.store, d.turnTimeout)
+	t.director = d
+	t.held = d.paused
+	d.tables[no] = t
+	go t.run()
+	return t
+}
+
+// place 讓玩家坐到人數最少且有空位的牌桌；全滿時開新桌
+func (d *Director) place(e *TournamentEntry) {
+	loads := d.loads()
+	best := 0
+	for _, l := range loads {
+		if l.Players >= d.info.SeatsPerTable {
+			continue
+		}
+		if best == 0 || l.Players < d.count(best) {
+			best = l.Table
+		}
+	}
+	var t *Table
+	if best == 0 {
+		best = 1
+		for no := range d.tables {
+			if no >= best {
+				best = no + 1
+			}
+		}
+		t = d.createTable(best)
+	} else {
+		t = d.tables[best]
+	}
+
+	entry := *e
+	entry.TableNo, entry.Seat = best, d.freeSeat(best)
+	d.players[entry.PlayerID] = &entry
+	t.post(tourneyCommand(&tourneyOp{
+		kind:    opSeat,
+		players: []movedPlayer{{playerID: entry.PlayerID, name: entry.Name, seat: entry.Seat, stack: entry.Stack}},
+	}))
+}
+
+// ---- 每手結束 ----
+
+// handDone 記錄淘汰、檢查是否產生冠軍，並以剛結束的牌桌進行併桌或平衡
+func (d *Director) handDone(r *handReport) {
+	for id, stack := range r.stacks {
+		if p := d.players[id]; p != nil {
+			p.Stack = stack
+		}
+	}
+
+	if len(r.busted) > 0 {
+		// 同一手被淘汰時，開局籌碼較多者名次較佳
+		sort.SliceStable(r.busted, func(i, j int) bool { return r.busted[i].startStack < r.busted[j].startStack })
+		placements := make([]Placement, 0, len(r.busted))
+		for _, b := range r.busted {
+			if _, ok := d.players[b.playerID]; !ok {
+				continue
+			}
+			placements = append(placements, Placement{PlayerID: b.playerID, Position: len(d.players)})
+			delete(d.players, b.playerID)
+		}
+		if err := d.store.Eliminate(d.info.ID, placements); err != nil {
+			log.Printf("錦標賽 %s 記錄淘汰失敗: %v", d.info.Code, err)
+		}
+		d.broadcast(fmt.Sprintf("剩餘 %d 位玩家", len(d.players)))
+	}
+
+	if len(d.players) <= 1 {
+		d.complete()
+		return
+	}
+
+	if _, ok := d.tables[r.table]; !ok {
+		return
+	}
+	d.balance(r.table)
+	d.saveStacks()
+	if t, ok := d.tables[r.table]; ok && !d.paused {
+		t.post(tourneyCommand(&tourneyOp{kind: opResume}))
+	}
+}
+
+// balance 拆除或平衡剛結束一手的牌桌
+func (d *Director) balance(idle int) {
+	plan := tournament.Balance(d.loads(), d.info.SeatsPerTable, idle)
+	if plan.Break == 0 && len(plan.Moves) == 0 {
+		return
+	}
+	t := d.tables[idle]
+	count := 0
+	for _, m := range plan.Moves {
+		count += m.Count
+	}
+	removed := d.remove(t, count, plan.Break != 0)
+
+	moved := removed.players
+	observers := removed.observers
+	for _, m := range plan.Moves {
+		n := m.Count
+		if n > len(moved) {
+			n = len(moved)
+		}
+		d.seatMoved(m.To, moved[:n], observers)
+		moved, observers = moved[n:], nil
+	}
+	if plan.Break != 0 {
+		// 理論上不會剩下玩家；保險起見補到其他牌桌
+		delete(d.tables, idle)
+		for _, p := range moved {
+			if e := d.players[p.playerID]; e != nil {
+				e.TableNo = 0
+				d.placeMoved(p)
+			}
+		}
+		if len(observers) > 0 {
+			d.seatMoved(d.lowestTable(), nil, observers)
+		}
+		t.post(tourneyCommand(&tourneyOp{kind: opClose}))
+	}
+}
+
+// seatMoved 將移出的玩家安排到目標牌桌的空位
+func (d *Director) seatMoved(to int, players []movedPlayer, observers []*Client) {
+	dest, ok := d.tables[to]
+	if !ok {
+		return
+	}
+	batch := make([]movedPlayer, 0, len(players))
+	for _, p := range players {
+		e := d.players[p.playerID]
+		if e == nil {
+			continue
+		}
+		p.seat = d.freeSeat(to)
+		e.TableNo, e.Seat, e.Stack = to, p.seat, p.stack
+		batch = append(batch, p)
+	}
+	dest.post(tourneyCommand(&tourneyOp{
+		kind:      opSeat,
+		players:   batch,
+		observers: observers,
+		message:   fmt.Sprintf("您已被移至第 %d 桌", to),
+	}))
+}
+
+// placeMoved 為拆桌後未被安排的玩家找座位
+func (d *Director) placeMoved(p movedPlayer) {
+	to := 0
+	for _, l := range d.loads() {
+		if l.Players < d.info.SeatsPerTable && (to == 0 || l.Players < d.count(to)) {
+			to = l.Table
+		}
+	}
+	if to == 0 {
+		return
+	}
+	d.seatMoved(to, []movedPlayer{p}, nil)
+}
+
+// remove 同步自牌桌移出玩家
+func (d *Director) remove(t *Table, count int, all bool) *removal {
+	reply := make(chan *removal, 1)
+	t.post(tourneyCommand(&tourneyOp{kind: opRemove, count: count, all: all, removed: reply}))
+	select {
+	case r := <-reply:
+		return r
+	case <-t.done:
+		return &removal{}
+	}
+}
+
+// ---- 盲注級別 ----
+
+func (d *Director) blinds() tournament.Level {
+	return d.info.Schedule[d.level]
+}
+
+// scheduleLevel 排程下一次升盲（暫停中或已是最後一級則不排程）
+func (d *Director) scheduleLevel() {
+	d.stopLevelTimer()
+	d.levelAt = time.Now()
+	dur := d.info.Schedule.Duration(d.level)
+	if d.paused || dur == 0 {
+		return
+	}
+	remaining := dur - d.elapsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	d.levelToken++
+	token := d.levelToken
+	d.levelTimer = time.AfterFunc(remaining, func() {
+		d.post(directorCmd{kind: dirLevelUp, token: token})
+	})
+}
+
+func (d *Director) stopLevelTimer() {
+	if d.levelTimer != nil {
+		d.levelTimer.Stop()
+		d.levelTimer = nil
+	}
+}
+
+// levelUp 升到下一個盲注級別（下一手生效）
+func (d *Director) levelUp(token int) {
+	if token != d.levelToken || d.paused || d.level >= len(d.info.Schedule)-1 {
+		return
+	}
+	d.level++
+	d.elapsed = 0
+	d.scheduleLevel()
+
+	l := d.blinds()
+	rules := l.Rules(d.info.Room.Rules.Variant)
+	message := fmt.Sprintf("盲注升至第 %d 級：%.2f/%.2f", d.level+1, l.SmallBlind, l.BigBlind)
+	if l.Ante > 0 {
+		message += fmt.Sprintf("，底注 %.2f", l.Ante)
+	}
+	for _, t := range d.tables {
+		t.post(tourneyCommand(&tourneyOp{kind: opRules, rules: rules, level: d.level, message: message}))
+	}
+	if err := d.store.SaveLevel(d.info.ID, d.level, 0, false); err != nil {
+		log.Printf("錦標賽 %s 保存級別失敗: %v", d.info.Code, err)
+	}
+}
+
+// levelElapsed 目前級別已進行的時間
+func (d *Director) levelElapsed() time.Duration {
+	if d.paused {
+		return d.elapsed
+	}
+	return d.elapsed + time.Since(d.levelAt)
+}
+
+// ---- 管理操作 ----
+
+func (d *Director) pause() error {
+	if d.paused {
+		return ErrTournamentPaused
+	}
+	d.elapsed = d.levelElapsed()
+	d.paused = true
+	d.stopLevelTimer()
+	for _, t := range d.tables {
+		t.post(tourneyCommand(&tourneyOp{kind: opHold}))
+	}
+	d.broadcast("錦標賽已暫停，進行中的牌局結束後將不再開新局")
+	return d.store.SaveLevel(d.info.ID, d.level, d.elapsed, true)
+}
+
+func (d *Director) resume() error {
+	if !d.paused {
+		return ErrTournamentNotPaused
+	}
+	d.paused = false
+	d.scheduleLevel()
+	d.broadcast("錦標賽恢復進行")
+	for _, t := range d.tables {
+		t.post(tourneyCommand(&tourneyOp{kind: opResume}))
+	}
+	return d.store.SaveLevel(d.info.ID, d.level, d.elapsed, false)
+}
+
+// cancel 關閉所有牌桌（進行中的場次作廢）並退款
+func (d *Director) cancel() error {
+	if err := d.store.CancelTournament(d.info.ID); err != nil {
+		return err
+	}
+	d.stop("錦標賽已取消，報名費已退還")
+	return nil
+}
+
+// complete 產生冠軍、派發獎金並關閉牌桌
+func (d *Director) complete() {
+	d.saveStacks()
+	var winner *TournamentEntry
+	for _, p := range d.players {
+		winner = p
+	}
+	message := "錦標賽結束"
+	if winner != nil {
+		if err := d.store.CompleteTournament(d.info.ID, winner.PlayerID); err != nil {
+			log.Printf("錦標賽 %s 結算失敗: %v", d.info.Code, err)
+		}
+		message = fmt.Sprintf("錦標賽結束，冠軍：%s", winner.Name)
+	}
+	d.stop(message)
+}
+
+func (d *Director) stop(message string) {
+	d.stopLevelTimer()
+	for no, t := range d.tables {
+		t.post(tourneyCommand(&tourneyOp{kind: opClose, message: message}))
+		delete(d.tables, no)
+	}
+	d.stopped = true
+}
+
+// ---- 查詢輔助 ----
+
+func (d *Director) broadcast(message string) {
+	for _, t := range d.tables {
+		t.post(tourneyCommand(&tourneyOp{kind: opNotice, message: message}))
+	}
+}
+
+func (d *Director) saveStacks() {
+	entries := make([]TournamentEntry, 0, len(d.players))
+	for _, p := range d.players {
+		entries = append(entries, *p)
+	}
+	if err := d.store.SaveStacks(d.info.ID, entries); err != nil {
+		log.Printf("錦標賽 %s 保存籌碼失敗: %v", d.info.Code, err)
+	}
+}
+
+// loads 各牌桌人數（依桌號排序，包含空桌）
+func (d *Director) loads() []tournament.TableLoad {
+	counts := map[int]int{}
+	for no := range d.tables {
+		counts[no] = 0
+	}
+	for _, p := range d.players {
+		if _, ok := counts[p.TableNo]; ok {
+			counts[p.TableNo]++
+		}
+	}
+	loads := make([]tournament.TableLoad, 0, len(counts))
+	for no, n := range counts {
+		loads = append(loads, tournament.TableLoad{Table: no, Players: n})
+	}
+	sort.Slice(loads, func(i, j int) bool { return loads[i].Table < loads[j].Table })
+	return loads
+}
+
+func (d *Director) count(table int) int {
+	n := 0
+	for _, p := range d.players {
+		if p.TableNo == table {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *Director) freeSeat(table int) int {
+	taken := map[int]bool{}
+	for _, p := range d.players {
+		if p.TableNo == table {
+			taken[p.Seat] = true
+		}
+	}
+	for n := 1; n <= d.info.SeatsPerTable; n++ {
+		if !taken[n] {
+			return n
+		}
+	}
+	return 0
+}
+
+func (d *Director) lowestTable() int {
+	lowest := 0
+	for no := range d.tables {
+		if lowest == 0 || no < lowest {
+			lowest = no
+		}
+	}
+	return lowest
+}
+
+// locate 玩家所在牌桌；已淘汰或未參賽的玩家以觀察者身分進入桌號最小的牌桌
+func (d *Director) locate(playerID int64) *Table {
+	if p, ok := d.players[playerID]; ok {
+		if t, ok := d.tables[p.TableNo]; ok {
+			return t
+		}
+	}
+	return d.tables[d.lowestTable()]
+}
+
+func (d *Director) state() *TournamentState {
+	s := &TournamentState{
+		ID:        d.info.ID,
+		Name:      d.info.Name,
+		Paused:    d.paused,
+		Level:     d.level,
+		Blinds:    d.blinds(),
+		Remaining: len(d.players),
+		Tables:    d.loads(),
+		Players:   make([]TournamentEntry, 0, len(d.players)),
+	}
+	if dur := d.info.Schedule.Duration(d.level); dur > 0 && !d.paused {
+		at := time.Now().Add(dur - d.levelElapsed())
+		s.NextLevelAt = &at
+	}
+	for _, p := range d.players {
+		s.Players = append(s.Players, *p)
+	}
+	sort.Slice(s.Players, func(i, j int) bool { return s.Players[i].Stack > s.Players[j].Stack })
+	return s
+}
+
+// ---- 管理器 ----
+
+// TournamentManager 管理進行中的錦標賽；每個錦標賽由一個 Director goroutine 執行
+type TournamentManager struct {
+	store       TournamentStore
+	turnTimeout time.Duration
+
+	mu        sync.Mutex
+	directors map[int64]*Director
+}
+
+// NewTournamentManager 建立錦標賽管理器
+func NewTournamentManager(store TournamentStore, turnTimeout time.Duration) *TournamentManager {
+	return &TournamentManager{store: store, turnTimeout: turnTimeout, directors: map[int64]*Director{}}
+}
+
+// Run 恢復重啟前進行中的錦標賽，之後定期啟動已到開賽時間的錦標賽（阻塞執行）
+func (m *TournamentManager) Run(interval time.Duration) {
+	first := true
+	for {
+		due, active, err := m.store.PendingTournaments()
+		if err != nil {
+			log.Printf("查詢待開賽錦標賽失敗: %v", err)
+		}
+		if first {
+			for _, id := range active {
+				if err := m.restore(id); err != nil {
+					log.Printf("恢復錦標賽 %d 失敗: %v", id, err)
+				}
+			}
+			first = false
+		}
+		for _, id := range due {
+			switch err := m.Start(id); {
+			case errors.Is(err, ErrNotEnoughEntrants):
+				if err := m.store.CancelTournament(id); err != nil {
+					log.Printf("取消錦標賽 %d 失敗: %v", id, err)
+				}
+			case err != nil && !errors.Is(err, ErrTournamentRunning):
+				log.Printf("錦標賽 %d 開賽失敗: %v", id, err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Start 開賽：隨機分配座位並啟動 Director
+func (m *TournamentManager) Start(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.directors[id]; ok {
+		return ErrTournamentRunning
+	}
+	info, entries, err := m.store.LoadTournament(id)
+	if err != nil {
+		return err
+	}
+	if info.Status != "registering" {
+		return ErrTournamentNotOpen
+	}
+	if len(entries) < info.MinEntrants || len(entries) < 2 {
+		return ErrNotEnoughEntrants
+	}
+	seated := seatAll(entries, info.SeatsPerTable)
+	if err := m.store.StartTournament(id, seated); err != nil {
+		return err
+	}
+	info.Status, info.Level, info.LevelElapsed = "running", 0, 0
+	m.launch(info, seated)
+	return nil
+}
+
+// restore 以保存的座位與籌碼恢復進行中的錦標賽
+func (m *TournamentManager) restore(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.directors[id]; ok {
+		return nil
+	}
+	info, entries, err := m.store.LoadTournament(id)
+	if err != nil {
+		return err
+	}
+	if info.Status != "running" && info.Status != "paused" {
+		return ErrTournamentNotRunning
+	}
+	m.launch(info, entries)
+	return nil
+}
+
+// launch 建立 Director 並開始執行（呼叫端持有 m.mu）
+func (m *TournamentManager) launch(info *TournamentInfo, entries []TournamentEntry) {
+	d := newDirector(info, m.store, m.turnTimeout)
+	d.onExit = func() {
+		m.mu.Lock()
+		delete(m.directors, info.ID)
+		m.mu.Unlock()
+	}
+	d.open(entries)
+	m.directors[info.ID] = d
+	go d.run()
+}
+
+func (m *TournamentManager) director(id int64) *Director {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.directors[id]
+}
+
+// Pause 暫停錦標賽（盲注計時停止，進行中的牌局打完後不再開新局）
+func (m *TournamentManager) Pause(id int64) error {
+	d := m.director(id)
+	if d == nil {
+		return ErrTournamentNotRunning
+	}
+	return d.request(directorCmd{kind: dirPause})
+}
+
+// Resume 恢復錦標賽
+func (m *TournamentManager) Resume(id int64) error {
+	d := m.director(id)
+	if d == nil {
+		return ErrTournamentNotRunning
+	}
+	return d.request(directorCmd{kind: dirResume})
+}
+
+// Cancel 取消錦標賽並退款（尚未開賽的錦標賽直接由 Store 處理）
+func (m *TournamentManager) Cancel(id int64) error {
+	if d := m.director(id); d != nil {
+		return d.request(directorCmd{kind: dirCancel})
+	}
+	return m.store.CancelTournament(id)
+}
+
+// Register 通知進行中的錦標賽有延遲報名的玩家（報名與扣款已由呼叫端完成）
+func (m *TournamentManager) Register(id int64, entry TournamentEntry) {
+	if d := m.director(id); d != nil {
+		entry.TableNo, entry.Seat = 0, 0
+		d.post(directorCmd{kind: dirRegister, entry: &entry})
+	}
+}
+
+// State 錦標賽即時狀態（未在進行中時回傳 nil）
+func (m *TournamentManager) State(id int64) *TournamentState {
+	d := m.director(id)
+	if d == nil {
+		return nil
+	}
+	reply := make(chan *TournamentState, 1)
+	d.post(directorCmd{kind: dirState, state: reply})
+	select {
+	case s := <-reply:
+		return s
+	case <-d.done:
+		return nil
+	}
+}
+
+// Locate 玩家應連線的牌桌
+func (m *TournamentManager) Locate(id, playerID int64) (*Table, error) {
+	d := m.director(id)
+	if d == nil {
+		return nil, ErrTournamentNotRunning
+	}
+	reply := make(chan *Table, 1)
+	d.post(directorCmd{kind: dirLocate, playerID: playerID, table: reply})
+	select {
+	case t := <-reply:
+		if t == nil {
+			return nil, ErrTournamentNotRunning
+		}
+		return t, nil
+	case <-d.done:
+		return nil, ErrTournamentNotRunning
+	}
+}
+
+// Serve 將已升級的連線加入玩家所在的牌桌並阻塞直到連線結束；換桌時連線隨玩家移動
+func (m *TournamentManager) Serve(id int64, conn *websocket.Conn, playerID int64, name string) error {
+	t, err := m.Locate(id, playerID)
+	if err != nil {
+		return err
+	}
+	c := newClient(t, conn, playerID, name)
+	t.post(command{kind: cmdJoin, client: c})
+	go c.writePump()
+	c.readPump()
+	return nil
+}
@@ -0,0 +1,237 @@
+package table
+
+import (
+	"fmt"
+	"log"
+
+	"nexus-gaming-backend/engine/poker"
+)
+
+type tourneyKind int
+
+const (
+	opSeat   tourneyKind = iota // 安排玩家（及觀察者）入座
+	opRemove                    // 移出玩家並回覆（拆桌時移出全部玩家與觀察者）
+	opHold                      // 暫停：進行中的一手結束後不再開新局
+	opResume                    // 恢復開局
+	opRules                     // 套用新的盲注級別（下一手生效）
+	opNotice                    // 廣播訊息
+	opClose                     // 關閉牌桌
+)
+
+// tourneyOp Director 送給錦標賽牌桌的指令
+type tourneyOp struct {
+	kind      tourneyKind
+	players   []movedPlayer
+	observers []*Client
+	count     int
+	all       bool
+	rules     poker.Rules
+	level     int
+	message   string
+	removed   chan *removal
+}
+
+// movedPlayer 換桌的玩家（連線隨玩家一起移動）
+type movedPlayer struct {
+	playerID   int64
+	name       string
+	seat       int
+	stack      float64
+	sittingOut bool
+	clients    []*Client
+}
+
+// removal 自牌桌移出的玩家與觀察者
+type removal struct {
+	players   []movedPlayer
+	observers []*Client
+}
+
+// bust 本手被淘汰的玩家
+type bust struct {
+	playerID   int64
+	startStack float64
+}
+
+// handReport 錦標賽牌桌每手結束後回報給 Director 的結果
+type handReport struct {
+	table  int
+	stacks map[int64]float64
+	busted []bust
+}
+
+// handleTourney 處理 Director 指令；回傳 false 表示牌桌已關閉
+func (t *Table) handleTourney(op *tourneyOp) bool {
+	switch op.kind {
+	case opSeat:
+		t.seatMoved(op)
+	case opRemove:
+		op.removed <- t.removePlayers(op.count, op.all)
+	case opHold:
+		t.held = true
+	case opResume:
+		t.held = false
+		t.maybeScheduleHand()
+	case opRules:
+		room := *t.room
+		room.Rules = op.rules
+		if room.Tournament != nil {
+			tag := *room.Tournament
+			tag.Level = op.level
+			room.Tournament = &tag
+		}
+		t.room = &room
+		if op.message != "" {
+			t.broadcast(ServerMessage{Type: OutInfo, Message: op.message})
+		}
+	case opNotice:
+		t.broadcast(ServerMessage{Type: OutInfo, Message: op.message})
+	case opClose:
+		t.shutdown(op.message)
+		return false
+	}
+	return true
+}
+
+// seatMoved 讓 Director 安排的玩家入座，並接手其連線
+func (t *Table) seatMoved(op *tourneyOp) {
+	for _, p := range op.players {
+		number := p.seat
+		if _, taken := t.seats[number]; taken || number < 1 || number > t.room.MaxPlayers {
+			number = t.emptySeat()
+			if number == 0 {
+				log.Printf("牌桌 %s 沒有空位安排玩家 %d", t.room.Name, p.playerID)
+				continue
+			}
+		}
+		s := &seat{number: number, playerID: p.playerID, name: p.name, stack: p.stack, sittingOut: p.sittingOut}
+		t.seats[number] = s
+		for _, c := range p.clients {
+			t.adopt(c)
+			s.client = c
+		}
+		t.broadcastSeat(s)
+		for _, c := range p.clients {
+			t.send(c, ServerMessage{Type: OutInfo, Message: op.message})
+			t.sendSnapshot(c)
+		}
+	}
+	for _, c := range op.observers {
+		t.adopt(c)
+		t.sendSnapshot(c)
+	}
+	t.maybeScheduleHand()
+}
+
+// adopt 接手其他牌桌移來的連線
+func (t *Table) adopt(c *Client) {
+	c.moveTo(t)
+	t.clients[c] = true
+}
+
+// removePlayers 移出玩家（不結清籌碼）；優先移出下一手將擔任大盲的座位
+func (t *Table) removePlayers(count int, all bool) *removal {
+	r := &removal{}
+	for _, s := range t.moveOrder() {
+		if !all && len(r.players) >= count {
+			break
+		}
+		if t.inHand(s.number) {
+			continue
+		}
+		p := movedPlayer{playerID: s.playerID, name: s.name, stack: s.stack, sittingOut: s.sittingOut}
+		for c := range t.clients {
+			if c.PlayerID == s.playerID {
+				delete(t.clients, c)
+				p.clients = append(p.clients, c)
+			}
+		}
+		delete(t.seats, s.number)
+		t.broadcast(ServerMessage{Type: OutSeatUpdate, Seat: &SeatView{Seat: s.number, Empty: true}})
+		r.players = append(r.players, p)
+	}
+	if all {
+		for c := range t.clients {
+			delete(t.clients, c)
+			r.observers = append(r.observers, c)
+		}
+	}
+	return r
+}
+
+// moveOrder 座位依下一手的大盲位起算排列
+func (t *Table) moveOrder() []*seat {
+	seats := t.orderedSeats()
+	if len(seats) == 0 {
+		return seats
+	}
+	start := 0
+	for i, s := range seats {
+		if s.number > t.button {
+			start = i
+			break
+		}
+	}
+	start = (start + 2) % len(seats)
+	return append(seats[start:], seats[:start]...)
+}
+
+func (t *Table) emptySeat() int {
+	for n := 1; n <= t.room.MaxPlayers; n++ {
+		if _, taken := t.seats[n]; !taken {
+			return n
+		}
+	}
+	return 0
+}
+
+// reportHand 錦標賽牌桌：移除淘汰玩家、暫停開局並回報 Director
+func (t *Table) reportHand(record *HandRecord) {
+	t.held = true
+	report := &handReport{table: t.room.Tournament.TableNo, stacks: map[int64]float64{}}
+	for _, r := range record.Seats {
+		s := t.seats[r.Seat]
+		if s == nil || s.isBot() || s.stack > 0 {
+			continue
+		}
+		report.busted = append(report.busted, bust{playerID: s.playerID, startStack: r.StartStack})
+		delete(t.seats, s.number)
+		t.broadcast(ServerMessage{Type: OutSeatUpdate, Seat: &SeatView{Seat: s.number, Empty: true}})
+		if s.client != nil {
+			t.send(s.client, ServerMessage{Type: OutInfo, Message: "您已被淘汰，可繼續觀看本桌"})
+		}
+	}
+	for _, s := range t.seats {
+		if !s.isBot() {
+			report.stacks[s.playerID] = s.stack
+		}
+	}
+	d := t.director
+	go d.post(directorCmd{kind: dirHandDone, report: report})
+}
+
+// shutdown 關閉牌桌：取消未完成的場次並中斷所有連線
+func (t *Table) shutdown(message string) {
+	t.stopTurnTimer()
+	if message != "" {
+		t.broadcast(ServerMessage{Type: OutInfo, Message: message})
+	}
+	t.abortPrepared()
+	t.hand = nil
+	for c := range t.clients {
+		delete(t.clients, c)
+		c.close()
+	}
+	close(t.done)
+}
+
+// tourneyCommand 包裝 Director 指令
+func tourneyCommand(op *tourneyOp) command {
+	return command{kind: cmdTourney, op: op}
+}
+
+// tableName 錦標賽牌桌名稱
+func tableName(tournament string, no int) string {
+	return fmt.Sprintf("%s 第 %d 桌", tournament, no)
+}
@@ -0,0 +1,252 @@
+// Package vault 敏感資料的信封加密
+//
+// 每筆資料以隨機產生的資料金鑰（AES-256-GCM）加密，資料金鑰再以主金鑰包裝後與密文一起保存為 JSON，
+// 可直接寫入原本的 JSON 欄位。主金鑰輪替時只需以新的主金鑰重新包裝資料金鑰（Rewrap），不需重新加密資料；
+// 舊主金鑰保留在設定中直到所有資料都已重新包裝。加密時以欄位名稱作為附加資料，密文無法搬到其他欄位解密。
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 加密相關錯誤
+var (
+	ErrNoMasterKey = errors.New("未設定主金鑰")
+	ErrUnknownKey  = errors.New("找不到包裝資料金鑰的主金鑰")
+	ErrInvalidKey  = errors.New("主金鑰必須為 base64 編碼的 32 位元組")
+	ErrCorrupted   = errors.New("密文格式錯誤或已遭竄改")
+)
+
+// envelopeVersion 信封格式版本
+const envelopeVersion = "v1"
+
+// keySize 主金鑰與資料金鑰長度（AES-256）
+const keySize = 32
+
+// Envelope 信封加密後的資料
+type Envelope struct {
+	Version    string `json:"enc"`
+	KeyID      string `json:"kid"` // 包裝資料金鑰的主金鑰
+	WrappedKey []byte `json:"dk"`  // 以主金鑰加密的資料金鑰（nonce 在前）
+	Nonce      []byte `json:"n"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// Vault 以主金鑰進行信封加密
+type Vault struct {
+	keys           map[string][]byte
+	active         string
+	fingerprintKey []byte
+}
+
+// New 建立加密器；active 為加密與重新包裝使用的主金鑰，fingerprintKey 用於計算不可逆的比對指紋（不隨主金鑰輪替）
+func New(keys map[string][]byte, active string, fingerprintKey []byte) (*Vault, error) {
+	if len(keys) == 0 || active == "" {
+		return nil, ErrNoMasterKey
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, active)
+	}
+	for _, k := range keys {
+		if len(k) != keySize {
+			return nil, ErrInvalidKey
+		}
+	}
+	if len(fingerprintKey) == 0 {
+		return nil, errors.New("未設定指紋金鑰")
+	}
+	return &Vault{keys: keys, active: active, fingerprintKey: fingerprintKey}, nil
+}
+
+// ParseKeys 解析「金鑰ID:base64 金鑰」以逗號分隔的主金鑰列表
+func ParseKeys(spec string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(item, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("主金鑰格式錯誤（應為 ID:base64）: %q", item)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidKey, id)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// ActiveKeyID 目前使用的主金鑰ID
+func (v *Vault) ActiveKeyID() string {
+	return v.active
+}
+
+// Encrypt 以新的資料金鑰加密並以目前的主金鑰包裝，回傳信封 JSON；context 為附加資料（欄位名稱）
+func (v *Vault) Encrypt(plaintext []byte, context string) ([]byte, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	nonce, ciphertext, err := seal(dataKey, plaintext, []byte(context))
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := v.wrap(v.active, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{
+		Version:    envelopeVersion,
+		KeyID:      v.active,
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	})
+}
+
+// Decrypt 解開信封；尚未加密的舊資料原樣回傳
+func (v *Vault) Decrypt(data []byte, context string) ([]byte, error) {
+	env, ok := parse(data)
+	if !ok {
+		return data, nil
+	}
+	dataKey, err := v.unwrap(env)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(dataKey, env.Nonce, env.Ciphertext, []byte(context))
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return plaintext, nil
+}
+
+// Rewrap 以目前的主金鑰重新包裝資料金鑰（尚未加密的舊資料直接加密），回傳新內容與是否有變更
+func (v *Vault) Rewrap(data []byte, context string) ([]byte, bool, error) {
+	env, ok := parse(data)
+	if !ok {
+		out, err := v.Encrypt(data, context)
+		return out, err == nil, err
+	}
+	if env.KeyID == v.active {
+		return data, false, nil
+	}
+	dataKey, err := v.unwrap(env)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := open(dataKey, env.Nonce, env.Ciphertext, []byte(context)); err != nil {
+		return nil, false, ErrCorrupted
+	}
+	if env.WrappedKey, err = v.wrap(v.active, dataKey); err != nil {
+		return nil, false, err
+	}
+	env.KeyID = v.active
+	out, err := json.Marshal(env)
+	return out, err == nil, err
+}
+
+// Fingerprint 計算值的 HMAC-SHA256 指紋（用於跨玩家比對相同帳號，無法還原原值）
+func (v *Vault) Fingerprint(value string) string {
+	mac := hmac.New(sha256.New, v.fingerprintKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted 是否為信封加密格式
+func IsEncrypted(data []byte) bool {
+	_, ok := parse(data)
+	return ok
+}
+
+// KeyID 包裝資料金鑰的主金鑰ID（未加密時為空字串）
+func KeyID(data []byte) string {
+	env, _ := parse(data)
+	return env.KeyID
+}
+
+func (v *Vault) wrap(keyID string, dataKey []byte) ([]byte, error) {
+	nonce, wrapped, err := seal(v.keys[keyID], dataKey, []byte(keyID))
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, wrapped...), nil
+}
+
+func (v *Vault) unwrap(env Envelope) ([]byte, error) {
+	key, ok := v.keys[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, env.KeyID)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.WrappedKey) < gcm.NonceSize() {
+		return nil, ErrCorrupted
+	}
+	n := gcm.NonceSize()
+	dataKey, err := gcm.Open(nil, env.WrappedKey[:n], env.WrappedKey[n:], []byte(env.KeyID))
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return dataKey, nil
+}
+
+// parse 解析信封；不是信封格式（舊的明文 JSON）時回傳 false
+func parse(data []byte) (Envelope, bool) {
+	var env Envelope
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return env, false
+	}
+	if err := json.Unmarshal(trimmed, &env); err != nil || env.Version != envelopeVersion || env.KeyID == "" {
+		return Envelope{}, false
+	}
+	return env, true
+}
+
+func seal(key, plaintext, additional []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, additional), nil
+}
+
+func open(key, nonce, ciphertext, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrCorrupted
+	}
+	return gcm.Open(nil, nonce, ciphertext, additional)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,221 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const testContext = "payment_methods.account_info"
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func newTestVault(t *testing.T, active string, ids ...string) *Vault {
+	t.Helper()
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		keys[id] = testKey(byte(i + 1))
+	}
+	v, err := New(keys, active, []byte("fingerprint"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return v
+}
+
+func envelopeOf(t *testing.T, data []byte) Envelope {
+	t.Helper()
+	env, ok := parse(data)
+	if !ok {
+		t.Fatalf("not an envelope: %s", data)
+	}
+	return env
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	v := newTestVault(t, "k1", "k1")
+	plaintext := []byte(`{"account_number":"1234567890"}`)
+
+	data, err := v.Encrypt(plaintext, testContext)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(data) || KeyID(data) != "k1" {
+		t.Fatalf("envelope = %s, want encrypted with k1", data)
+	}
+	if bytes.Contains(data, []byte("1234567890")) {
+		t.Fatal("envelope contains the plaintext")
+	}
+	got, err := v.Decrypt(data, testContext)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt = %s, want %s", got, plaintext)
+	}
+
+	// 每次加密使用新的資料金鑰與 nonce
+	again, err := v.Encrypt(plaintext, testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(again, data) {
+		t.Error("encrypting twice produced identical envelopes")
+	}
+
+	// 尚未加密的舊資料原樣回傳
+	legacy := []byte(`{"account_number":"legacy"}`)
+	if got, err := v.Decrypt(legacy, testContext); err != nil || !bytes.Equal(got, legacy) {
+		t.Errorf("Decrypt(legacy) = %s, %v", got, err)
+	}
+}
+
+func TestDecryptWithRotatedOutKey(t *testing.T) {
+	old := newTestVault(t, "k1", "k1")
+	plaintext := []byte(`{"iban":"DE00"}`)
+	data, err := old.Encrypt(plaintext, testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 輪替後舊主金鑰仍保留在設定中，舊資料可解密
+	rotated := newTestVault(t, "k2", "k1", "k2")
+	if got, err := rotated.Decrypt(data, testContext); err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt with old key still configured = %s, %v", got, err)
+	}
+
+	// 舊主金鑰已自設定移除而資料尚未重新包裝
+	removed, err := New(map[string][]byte{"k2": testKey(2)}, "k2", []byte("fingerprint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := removed.Decrypt(data, testContext); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Decrypt without old key: error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	v := newTestVault(t, "k1", "k1", "k2")
+	data, err := v.Encrypt([]byte(`{"account_number":"1234567890"}`), testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Envelope)
+	}{
+		{"ciphertext", func(e *Envelope) { e.Ciphertext[0] ^= 0xff }},
+		{"nonce", func(e *Envelope) { e.Nonce[0] ^= 0xff }},
+		{"wrapped key", func(e *Envelope) { e.WrappedKey[len(e.WrappedKey)-1] ^= 0xff }},
+		{"truncated nonce", func(e *Envelope) { e.Nonce = e.Nonce[:4] }},
+		{"truncated wrapped key", func(e *Envelope) { e.WrappedKey = e.WrappedKey[:4] }},
+		{"key id swapped", func(e *Envelope) { e.KeyID = "k2" }},
+	}
+	for _, tt := range tests {
+		env := envelopeOf(t, data)
+		tt.mutate(&env)
+		tampered, err := json.Marshal(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v.Decrypt(tampered, testContext); !errors.Is(err, ErrCorrupted) {
+			t.Errorf("%s: error = %v, want ErrCorrupted", tt.name, err)
+		}
+	}
+
+	// 附加資料綁定欄位：密文搬到其他欄位無法解密
+	if _, err := v.Decrypt(data, "withdrawals.account_info"); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("other context: error = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestRewrapThenRereadOldRecords(t *testing.T) {
+	old := newTestVault(t, "k1", "k1")
+	records := map[string][]byte{
+		"encrypted": []byte(`{"account_number":"111"}`),
+		"legacy":    []byte(`{"account_number":"222"}`),
+	}
+	stored := map[string][]byte{"legacy": records["legacy"]}
+	var err error
+	if stored["encrypted"], err = old.Encrypt(records["encrypted"], testContext); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestVault(t, "k2", "k1", "k2")
+	for name, data := range stored {
+		out, changed, err := rotated.Rewrap(data, testContext)
+		if err != nil {
+			t.Fatalf("%s: Rewrap: %v", name, err)
+		}
+		if !changed || KeyID(out) != "k2" {
+			t.Fatalf("%s: Rewrap changed = %v, key = %q; want true, k2", name, changed, KeyID(out))
+		}
+		if name == "encrypted" {
+			before, after := envelopeOf(t, data), envelopeOf(t, out)
+			if !bytes.Equal(before.Ciphertext, after.Ciphertext) {
+				t.Errorf("%s: rewrap re-encrypted the data instead of only rewrapping the data key", name)
+			}
+		}
+		stored[name] = out
+
+		// 已是目前主金鑰的資料不再變更
+		if _, changed, err := rotated.Rewrap(out, testContext); err != nil || changed {
+			t.Errorf("%s: second Rewrap changed = %v, err = %v", name, changed, err)
+		}
+	}
+
+	// 全部重新包裝後移除舊主金鑰，舊資料仍可讀取
+	keys, err := ParseKeys("k2:" + base64.StdEncoding.EncodeToString(testKey(2)))
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	current, err := New(keys, "k2", []byte("fingerprint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range stored {
+		got, err := current.Decrypt(data, testContext)
+		if err != nil || !bytes.Equal(got, records[name]) {
+			t.Errorf("%s: Decrypt after rotation = %s, %v; want %s", name, got, err, records[name])
+		}
+	}
+}
+
+func TestRewrapRejectsTampering(t *testing.T) {
+	old := newTestVault(t, "k1", "k1")
+	data, err := old.Encrypt([]byte(`{"account_number":"111"}`), testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := envelopeOf(t, data)
+	env.Ciphertext[0] ^= 0xff
+	tampered, _ := json.Marshal(env)
+
+	rotated := newTestVault(t, "k2", "k1", "k2")
+	if _, _, err := rotated.Rewrap(tampered, testContext); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("Rewrap(tampered): error = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestNewAndParseKeysValidation(t *testing.T) {
+	if _, err := New(nil, "k1", []byte("f")); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("no keys: error = %v, want ErrNoMasterKey", err)
+	}
+	if _, err := New(map[string][]byte{"k1": testKey(1)}, "k2", []byte("f")); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown active key: error = %v, want ErrUnknownKey", err)
+	}
+	if _, err := New(map[string][]byte{"k1": []byte("short")}, "k1", []byte("f")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("short key: error = %v, want ErrInvalidKey", err)
+	}
+	if _, err := ParseKeys("k1:" + base64.StdEncoding.EncodeToString([]byte("short"))); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("ParseKeys short key: error = %v, want ErrInvalidKey", err)
+	}
+	if _, err := ParseKeys("missing-separator"); err == nil {
+		t.Error("ParseKeys without separator: expected error")
+	}
+}
@@ -0,0 +1,28 @@
+-- AI 玩家相關表結構
+-- 建立時間: 2026-10-19
+-- AI 玩家的牌局結果獨立記錄，不寫入 game_participations，避免影響玩家分析
+
+USE nexus_gaming;
+
+-- 建立 AI 玩家牌局結果表
+CREATE TABLE IF NOT EXISTS ai_game_results (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    session_id BIGINT NOT NULL COMMENT '場次ID',
+    bot_id VARCHAR(64) NOT NULL COMMENT 'AI玩家識別碼',
+    seat_number INT NOT NULL COMMENT '座位號',
+    difficulty ENUM('easy', 'medium', 'hard', 'expert') NOT NULL COMMENT 'AI難度',
+    strategy VARCHAR(50) NOT NULL COMMENT '策略代碼',
+    initial_chips DECIMAL(15,2) DEFAULT 0.00 COMMENT '初始籌碼',
+    final_chips DECIMAL(15,2) DEFAULT 0.00 COMMENT '最終籌碼',
+    total_bet DECIMAL(15,2) DEFAULT 0.00 COMMENT '總下注金額',
+    total_win DECIMAL(15,2) DEFAULT 0.00 COMMENT '總贏得金額',
+    net_result DECIMAL(15,2) GENERATED ALWAYS AS (total_win - total_bet) COMMENT '淨結果',
+    hand_category VARCHAR(32) COMMENT '攤牌牌型',
+    folded BOOLEAN DEFAULT FALSE COMMENT '是否蓋牌',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_session_bot (session_id, bot_id),
+    INDEX idx_session_id (session_id),
+    INDEX idx_difficulty (difficulty),
+    INDEX idx_created_at (created_at),
+    FOREIGN KEY (session_id) REFERENCES game_sessions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='AI玩家牌局結果表';
@@ -0,0 +1,43 @@
+-- 牌局歷史相關表結構
+-- 建立時間: 2026-10-19
+-- 每手牌寫入一筆標準格式（nexus-hh/1）的牌局歷史，寫入後不可修改或刪除，供爭議處理與稽核使用
+
+USE nexus_gaming;
+
+-- 建立牌局歷史表
+CREATE TABLE IF NOT EXISTS hand_histories (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    session_id BIGINT NOT NULL UNIQUE COMMENT '場次ID',
+    session_code VARCHAR(64) NOT NULL COMMENT '場次代碼',
+    room_id BIGINT NOT NULL COMMENT '房間ID',
+    game_type ENUM('texas_holdem', 'stud_poker') NOT NULL COMMENT '遊戲類型',
+    format VARCHAR(20) NOT NULL COMMENT '歷史格式版本',
+    history MEDIUMTEXT NOT NULL COMMENT '牌局歷史 JSON（保留原始位元組以便雜湊驗證）',
+    content_hash CHAR(64) NOT NULL COMMENT '歷史內容 SHA-256',
+    started_at TIMESTAMP NULL COMMENT '開始時間',
+    finished_at TIMESTAMP NULL COMMENT '結束時間',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_session_code (session_code),
+    INDEX idx_room_id (room_id),
+    INDEX idx_finished_at (finished_at),
+    CONSTRAINT chk_history_json CHECK (JSON_VALID(history)),
+    FOREIGN KEY (session_id) REFERENCES game_sessions(id),
+    FOREIGN KEY (room_id) REFERENCES game_rooms(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='牌局歷史表（不可變）';
+
+-- 禁止修改或刪除牌局歷史
+DELIMITER //
+CREATE TRIGGER IF NOT EXISTS hand_histories_no_update
+BEFORE UPDATE ON hand_histories
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'hand_histories is immutable';
+END//
+
+CREATE TRIGGER IF NOT EXISTS hand_histories_no_delete
+BEFORE DELETE ON hand_histories
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'hand_histories is immutable';
+END//
+DELIMITER ;
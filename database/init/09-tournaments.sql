@@ -0,0 +1,59 @@
+-- 錦標賽相關表結構
+-- 建立時間: 2026-10-19
+-- 多桌撲克錦標賽的定義（報名費、盲注級別、獎金結構）與參賽紀錄；每手牌仍寫入 game_sessions（session_type = 'tournament'）
+
+USE nexus_gaming;
+
+-- 建立錦標賽表
+CREATE TABLE IF NOT EXISTS tournaments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tournament_code VARCHAR(32) NOT NULL UNIQUE COMMENT '錦標賽代碼',
+    name VARCHAR(100) NOT NULL COMMENT '錦標賽名稱',
+    room_id BIGINT NOT NULL COMMENT '所屬房間（決定遊戲類型，場次掛在此房間下）',
+    buy_in DECIMAL(10,2) NOT NULL COMMENT '報名費（計入獎金池）',
+    fee DECIMAL(10,2) DEFAULT 0.00 COMMENT '服務費（平台收入）',
+    starting_stack DECIMAL(15,2) NOT NULL COMMENT '起始籌碼',
+    seats_per_table INT DEFAULT 9 COMMENT '每桌座位數',
+    min_entrants INT DEFAULT 2 COMMENT '最少參賽人數',
+    max_entrants INT DEFAULT 180 COMMENT '最多參賽人數',
+    blind_schedule JSON NOT NULL COMMENT '盲注級別表',
+    payout_structure JSON NOT NULL COMMENT '獎金結構',
+    late_reg_levels INT DEFAULT 0 COMMENT '延遲報名開放的級別數（0 表示不開放）',
+    current_level INT DEFAULT 0 COMMENT '目前級別（從 0 起算）',
+    level_elapsed INT DEFAULT 0 COMMENT '目前級別已進行秒數（暫停或重啟時保存）',
+    status ENUM('registering', 'running', 'paused', 'finished', 'cancelled') DEFAULT 'registering' COMMENT '錦標賽狀態',
+    entrants INT DEFAULT 0 COMMENT '參賽人數',
+    prize_pool DECIMAL(15,2) DEFAULT 0.00 COMMENT '獎金池',
+    starts_at TIMESTAMP NOT NULL COMMENT '預定開賽時間',
+    started_at TIMESTAMP NULL COMMENT '實際開賽時間',
+    finished_at TIMESTAMP NULL COMMENT '結束時間',
+    created_by INT COMMENT '建立者',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_status (status),
+    INDEX idx_starts_at (starts_at),
+    FOREIGN KEY (room_id) REFERENCES game_rooms(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='錦標賽表';
+
+-- 建立錦標賽參賽紀錄表
+CREATE TABLE IF NOT EXISTS tournament_entries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tournament_id BIGINT NOT NULL COMMENT '錦標賽ID',
+    player_id BIGINT NOT NULL COMMENT '玩家ID',
+    status ENUM('registered', 'playing', 'busted', 'finished', 'refunded', 'unregistered') DEFAULT 'registered' COMMENT '參賽狀態',
+    chips DECIMAL(15,2) DEFAULT 0.00 COMMENT '目前籌碼',
+    table_no INT COMMENT '牌桌編號',
+    seat_number INT COMMENT '座位號',
+    finish_position INT COMMENT '名次',
+    prize DECIMAL(15,2) DEFAULT 0.00 COMMENT '獎金',
+    buy_in_transaction_id VARCHAR(64) COMMENT '報名扣款交易',
+    prize_transaction_id VARCHAR(64) COMMENT '獎金入帳交易',
+    registered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '報名時間',
+    busted_at TIMESTAMP NULL COMMENT '淘汰時間',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_tournament_player (tournament_id, player_id),
+    INDEX idx_player_id (player_id),
+    INDEX idx_status (status),
+    FOREIGN KEY (tournament_id) REFERENCES tournaments(id),
+    FOREIGN KEY (player_id) REFERENCES players(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='錦標賽參賽紀錄表';
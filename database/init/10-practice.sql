@@ -0,0 +1,22 @@
+-- 練習模式相關表結構
+-- 建立時間: 2026-10-19
+-- 練習場（session_type = 'practice'）使用獨立的遊戲幣餘額，不寫入 player_wallets 與 transactions；牌局結果仍寫入玩家遊戲會話分析表並標記 is_practice
+
+USE nexus_gaming;
+
+-- 建立練習遊戲幣錢包表
+CREATE TABLE IF NOT EXISTS practice_wallets (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    player_id BIGINT NOT NULL UNIQUE COMMENT '玩家ID',
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '遊戲幣餘額',
+    replenish_count INT NOT NULL DEFAULT 0 COMMENT '自動補充次數',
+    last_replenished_at TIMESTAMP NULL COMMENT '最近補充時間',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='練習遊戲幣錢包表';
+
+-- 玩家遊戲會話分析表加入練習場標記（偏好分析可選擇納入或排除）
+ALTER TABLE player_game_sessions
+    ADD COLUMN is_practice BOOLEAN NOT NULL DEFAULT FALSE COMMENT '是否為練習場' AFTER is_completed,
+    ADD INDEX idx_is_practice (is_practice);
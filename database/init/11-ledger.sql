@@ -0,0 +1,79 @@
+-- 複式記帳總帳相關表結構
+-- 建立時間: 2026-10-19
+-- 每筆資金異動寫入一張借貸平衡的傳票（分錄金額合計為 0），涵蓋玩家、平台、紅利與代理帳戶；分錄過帳後不可修改，更正只能以沖銷傳票進行
+
+USE nexus_gaming;
+
+-- 建立總帳帳戶表
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    account_code VARCHAR(100) NOT NULL UNIQUE COMMENT '帳戶代碼（類型:擁有者/用途:幣別）',
+    account_type ENUM('player', 'house', 'bonus', 'agent') NOT NULL COMMENT '帳戶類型',
+    owner_id BIGINT NULL COMMENT '擁有者ID（玩家或代理商；平台與紅利帳戶為 NULL）',
+    purpose VARCHAR(50) NOT NULL DEFAULT '' COMMENT '帳戶用途（平台帳戶區分 cash、game、commission、adjustment 等）',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_owner (account_type, owner_id),
+    INDEX idx_currency (currency)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='總帳帳戶表';
+
+-- 建立傳票表
+CREATE TABLE IF NOT EXISTS ledger_journals (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    journal_code VARCHAR(64) NOT NULL UNIQUE COMMENT '傳票編號',
+    journal_type VARCHAR(30) NOT NULL COMMENT '傳票類型（對應 transaction_type 或 reversal 等）',
+    transaction_id VARCHAR(64) NULL UNIQUE COMMENT '對應的交易流水號（transactions.transaction_id）',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    reversal_of BIGINT NULL UNIQUE COMMENT '沖銷的原傳票ID（每張傳票最多沖銷一次）',
+    description VARCHAR(255) COMMENT '摘要',
+    operator_id INT NULL COMMENT '操作員ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_journal_type (journal_type),
+    INDEX idx_created_at (created_at),
+    FOREIGN KEY (reversal_of) REFERENCES ledger_journals(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='總帳傳票表（不可變）';
+
+-- 建立分錄表（金額為正表示增加該帳戶餘額）
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    journal_id BIGINT NOT NULL COMMENT '傳票ID',
+    account_id BIGINT NOT NULL COMMENT '帳戶ID',
+    amount DECIMAL(15,2) NOT NULL COMMENT '分錄金額（正數增加、負數減少帳戶餘額）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_journal_id (journal_id),
+    INDEX idx_account_id (account_id),
+    CONSTRAINT chk_entry_amount CHECK (amount <> 0),
+    FOREIGN KEY (journal_id) REFERENCES ledger_journals(id),
+    FOREIGN KEY (account_id) REFERENCES ledger_accounts(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='總帳分錄表（不可變）';
+
+-- 禁止修改或刪除已過帳的傳票與分錄
+DELIMITER //
+CREATE TRIGGER IF NOT EXISTS ledger_journals_no_update
+BEFORE UPDATE ON ledger_journals
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'ledger_journals is immutable';
+END//
+
+CREATE TRIGGER IF NOT EXISTS ledger_journals_no_delete
+BEFORE DELETE ON ledger_journals
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'ledger_journals is immutable';
+END//
+
+CREATE TRIGGER IF NOT EXISTS ledger_entries_no_update
+BEFORE UPDATE ON ledger_entries
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'ledger_entries is immutable';
+END//
+
+CREATE TRIGGER IF NOT EXISTS ledger_entries_no_delete
+BEFORE DELETE ON ledger_entries
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'ledger_entries is immutable';
+END//
+DELIMITER ;
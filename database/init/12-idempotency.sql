@@ -0,0 +1,17 @@
+-- 冪等請求相關表結構
+-- 建立時間: 2026-10-19
+-- 以 Idempotency-Key 標頭去除重複的資金請求：同一作用範圍內的鍵只會成功執行一次，重試時重播第一次的回應
+
+USE nexus_gaming;
+
+-- 建立冪等鍵表
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    scope VARCHAR(100) NOT NULL COMMENT '作用範圍（端點與資源，例如 player_deposit:12）',
+    idempotency_key VARCHAR(128) NOT NULL COMMENT '客戶端提供的冪等鍵',
+    request_hash CHAR(64) NOT NULL COMMENT '請求內容 SHA-256（同一鍵不可用於不同內容）',
+    response_body MEDIUMTEXT NULL COMMENT '第一次成功執行的回應資料（JSON）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_scope_key (scope, idempotency_key),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='冪等鍵表';
@@ -0,0 +1,30 @@
+-- 錢包凍結相關表結構
+-- 建立時間: 2026-10-19
+-- 提領申請與牌桌帶入籌碼先自可用餘額凍結至 player_wallets.frozen_balance，之後扣款（capture）、釋放（release）或逾期自動釋放；凍結只在可用與凍結餘額間移動，不寫入總帳
+
+USE nexus_gaming;
+
+-- 建立錢包凍結表
+CREATE TABLE IF NOT EXISTS wallet_holds (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    hold_code VARCHAR(64) NOT NULL UNIQUE COMMENT '凍結編號',
+    player_id BIGINT NOT NULL COMMENT '玩家ID',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    amount DECIMAL(15,2) NOT NULL COMMENT '凍結金額',
+    captured_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '實際扣款金額',
+    reason VARCHAR(50) NOT NULL COMMENT '凍結原因（withdrawal、table_buy_in 等）',
+    reference_type VARCHAR(50) NOT NULL COMMENT '參考類型（withdrawal、table、game_session 等）',
+    reference_id VARCHAR(100) NOT NULL COMMENT '參考編號（提領訂單號、房間代碼或場次ID）',
+    status ENUM('active', 'captured', 'released', 'expired') NOT NULL DEFAULT 'active' COMMENT '凍結狀態',
+    expires_at TIMESTAMP NULL COMMENT '自動釋放時間（NULL 表示不逾期）',
+    transaction_id VARCHAR(64) NULL COMMENT '結清時產生的交易流水號',
+    operator_id INT NULL COMMENT '操作員ID',
+    settled_at TIMESTAMP NULL COMMENT '結清時間',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_player_status (player_id, status),
+    INDEX idx_reference (reference_type, reference_id),
+    INDEX idx_status_expires (status, expires_at),
+    CONSTRAINT chk_hold_amount CHECK (amount > 0),
+    FOREIGN KEY (player_id) REFERENCES players(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='錢包凍結表';
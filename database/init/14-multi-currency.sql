@@ -0,0 +1,76 @@
+-- 多幣別錢包與匯率相關表結構
+-- 建立時間: 2026-10-19
+-- 玩家可持有多個幣別錢包，牌桌以房間設定的幣別下注；幣別兌換以雙幣別傳票過帳至平台匯兌帳戶，報表可依歷史匯率合併為報表幣別
+
+USE nexus_gaming;
+
+-- 建立幣別表
+CREATE TABLE IF NOT EXISTS currencies (
+    code VARCHAR(10) PRIMARY KEY COMMENT '幣別代碼（ISO 4217）',
+    name VARCHAR(50) NOT NULL COMMENT '幣別名稱',
+    symbol VARCHAR(10) COMMENT '符號',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE COMMENT '是否開放錢包與兌換',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='幣別表';
+
+INSERT IGNORE INTO currencies (code, name, symbol) VALUES
+('TWD', '新台幣', 'NT$'),
+('USD', '美元', '$'),
+('CNY', '人民幣', '¥'),
+('HKD', '港幣', 'HK$'),
+('JPY', '日圓', '¥');
+
+-- 建立匯率表（只新增不修改，保留歷史匯率供報表換算）
+CREATE TABLE IF NOT EXISTS fx_rates (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    base_currency VARCHAR(10) NOT NULL COMMENT '基準幣別',
+    quote_currency VARCHAR(10) NOT NULL COMMENT '報價幣別',
+    rate DECIMAL(20,10) NOT NULL COMMENT '1 單位基準幣可兌換的報價幣數量',
+    source ENUM('static', 'override') NOT NULL DEFAULT 'static' COMMENT '來源（靜態匯率表或管理員覆寫）',
+    effective_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '生效時間',
+    expires_at TIMESTAMP NULL COMMENT '覆寫失效時間（NULL 表示直到下一筆覆寫）',
+    note VARCHAR(255) COMMENT '備註',
+    created_by INT NULL COMMENT '建立者ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_pair_source_effective (base_currency, quote_currency, source, effective_at),
+    CONSTRAINT chk_fx_rate_positive CHECK (rate > 0),
+    CONSTRAINT chk_fx_pair CHECK (base_currency <> quote_currency),
+    FOREIGN KEY (base_currency) REFERENCES currencies(code),
+    FOREIGN KEY (quote_currency) REFERENCES currencies(code),
+    FOREIGN KEY (created_by) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='匯率表';
+
+INSERT INTO fx_rates (base_currency, quote_currency, rate, source, effective_at, note) VALUES
+('USD', 'TWD', 32.0000000000, 'static', '2026-01-01 00:00:00', '初始靜態匯率'),
+('USD', 'CNY', 7.2000000000, 'static', '2026-01-01 00:00:00', '初始靜態匯率'),
+('USD', 'HKD', 7.8000000000, 'static', '2026-01-01 00:00:00', '初始靜態匯率'),
+('USD', 'JPY', 150.0000000000, 'static', '2026-01-01 00:00:00', '初始靜態匯率');
+
+-- 建立幣別兌換紀錄表
+CREATE TABLE IF NOT EXISTS fx_conversions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    conversion_code VARCHAR(64) NOT NULL UNIQUE COMMENT '兌換編號',
+    player_id BIGINT NOT NULL COMMENT '玩家ID',
+    from_currency VARCHAR(10) NOT NULL COMMENT '轉出幣別',
+    from_amount DECIMAL(15,2) NOT NULL COMMENT '轉出金額',
+    to_currency VARCHAR(10) NOT NULL COMMENT '轉入幣別',
+    to_amount DECIMAL(15,2) NOT NULL COMMENT '轉入金額',
+    rate DECIMAL(20,10) NOT NULL COMMENT '採用的匯率（from → to）',
+    rate_source VARCHAR(50) NOT NULL COMMENT '匯率來源',
+    debit_transaction_id VARCHAR(64) NOT NULL COMMENT '轉出交易流水號',
+    credit_transaction_id VARCHAR(64) NOT NULL COMMENT '轉入交易流水號',
+    operator_id INT NULL COMMENT '操作員ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_player_id (player_id),
+    INDEX idx_created_at (created_at),
+    FOREIGN KEY (player_id) REFERENCES players(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='幣別兌換紀錄表';
+
+-- 交易類型新增幣別兌換
+ALTER TABLE transactions
+    MODIFY COLUMN transaction_type ENUM('deposit', 'withdrawal', 'bet', 'win', 'bonus', 'commission', 'refund', 'adjustment', 'fx_conversion') NOT NULL COMMENT '交易類型';
+
+-- 房間下注幣別（NULL 表示系統預設幣別）
+ALTER TABLE game_rooms
+    ADD COLUMN currency VARCHAR(10) NULL COMMENT '下注幣別（NULL 表示系統預設幣別）' AFTER max_bet;
@@ -0,0 +1,25 @@
+-- 支付閘道相關表結構
+-- 建立時間: 2026-10-19
+-- 儲值經由支付閘道轉接介面建立付款意圖，閘道回呼以簽章驗證並依事件ID去重，逾期未付款的訂單由排程標記為 expired
+
+USE nexus_gaming;
+
+-- 建立支付回呼事件表（同一提供商的事件ID只處理一次，防止回呼重送）
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL COMMENT '支付提供商',
+    event_id VARCHAR(100) NOT NULL COMMENT '閘道事件ID',
+    gateway_transaction_id VARCHAR(100) NOT NULL COMMENT '支付閘道交易ID',
+    reference_id VARCHAR(64) COMMENT '本系統訂單號',
+    event_status VARCHAR(20) NOT NULL COMMENT '事件回報的交易狀態',
+    payload JSON NOT NULL COMMENT '原始回呼內容',
+    result VARCHAR(50) NOT NULL DEFAULT 'received' COMMENT '處理結果',
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_provider_event (provider, event_id),
+    INDEX idx_gateway_transaction (provider, gateway_transaction_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='支付回呼事件表';
+
+-- 儲值訂單依閘道交易與逾期時間查詢
+ALTER TABLE deposits
+    ADD INDEX idx_gateway_transaction (payment_provider, gateway_transaction_id),
+    ADD INDEX idx_status_expired (status, expired_at);
@@ -0,0 +1,31 @@
+-- 提領審核流程相關表結構
+-- 建立時間: 2026-10-19
+-- 提領申請依規則評分後自動核准或轉入人工審核佇列，支援指派、備註、超過限額的雙人覆核，核准後經支付閘道出款
+
+USE nexus_gaming;
+
+-- 提領申請加上審核佇列、雙人覆核與出款欄位
+ALTER TABLE withdrawals
+    ADD COLUMN risk_checks JSON COMMENT '風險規則檢查結果' AFTER risk_score,
+    ADD COLUMN assigned_to INT NULL COMMENT '指派審核員ID' AFTER review_status,
+    ADD COLUMN assigned_at TIMESTAMP NULL COMMENT '指派時間' AFTER assigned_to,
+    ADD COLUMN requires_second_approval BOOLEAN NOT NULL DEFAULT FALSE COMMENT '是否需雙人覆核' AFTER assigned_at,
+    ADD COLUMN first_approver_id INT NULL COMMENT '第一位核准審核員ID' AFTER requires_second_approval,
+    ADD COLUMN first_approved_at TIMESTAMP NULL COMMENT '第一位核准時間' AFTER first_approver_id,
+    ADD COLUMN payout_provider VARCHAR(50) NULL COMMENT '出款支付提供商' AFTER processed_at,
+    ADD INDEX idx_review_queue (status, review_status, assigned_to),
+    ADD FOREIGN KEY (assigned_to) REFERENCES users(id),
+    ADD FOREIGN KEY (first_approver_id) REFERENCES users(id);
+
+-- 建立提領審核紀錄表（指派、備註、核准與駁回的歷程，只新增不修改）
+CREATE TABLE IF NOT EXISTS withdrawal_review_notes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    withdrawal_id VARCHAR(64) NOT NULL COMMENT '提領訂單號',
+    user_id INT NULL COMMENT '操作者ID（系統操作為 NULL）',
+    action ENUM('note', 'assign', 'first_approval', 'approve', 'reject', 'payout', 'payout_failed') NOT NULL COMMENT '動作',
+    note TEXT COMMENT '內容',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_withdrawal_id (withdrawal_id, id),
+    FOREIGN KEY (withdrawal_id) REFERENCES withdrawals(withdrawal_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='提領審核紀錄表';
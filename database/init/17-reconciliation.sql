@@ -0,0 +1,77 @@
+-- 對帳結帳相關表結構
+-- 建立時間: 2026-10-19
+-- 每日依幣別彙總交易並與錢包異動、總帳及支付閘道紀錄比對，寫入不可修改的 financial_reports 快照；已結帳的日期禁止回溯寫入或修改交易
+
+USE nexus_gaming;
+
+-- 財務報表快照加上對帳結果欄位
+ALTER TABLE financial_reports
+    ADD COLUMN total_refunds DECIMAL(20,2) DEFAULT 0.00 COMMENT '總退款' AFTER total_wins,
+    ADD COLUMN total_adjustments DECIMAL(20,2) DEFAULT 0.00 COMMENT '總調整' AFTER commission_earned,
+    ADD COLUMN reconciliation_status ENUM('balanced', 'mismatched') NOT NULL DEFAULT 'balanced' COMMENT '對帳結果' AFTER currency,
+    ADD COLUMN mismatch_count INT NOT NULL DEFAULT 0 COMMENT '差異筆數' AFTER reconciliation_status,
+    ADD COLUMN details JSON COMMENT '對帳明細（錢包異動、閘道紀錄與差異交易）' AFTER mismatch_count,
+    ADD COLUMN generated_by INT NULL COMMENT '產生者ID（排程為 NULL）' AFTER details;
+
+-- 建立結帳日期表（結帳後該日的交易與傳票不可回溯寫入或修改）
+CREATE TABLE IF NOT EXISTS reconciliation_closed_days (
+    close_date DATE PRIMARY KEY COMMENT '結帳日期',
+    closed_by INT NULL COMMENT '結帳者ID（排程為 NULL）',
+    closed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (closed_by) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='結帳日期表';
+
+DELIMITER //
+-- 財務報表快照寫入後不可修改或刪除
+CREATE TRIGGER IF NOT EXISTS financial_reports_no_update
+BEFORE UPDATE ON financial_reports
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'financial_reports is immutable';
+END//
+
+CREATE TRIGGER IF NOT EXISTS financial_reports_no_delete
+BEFORE DELETE ON financial_reports
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'financial_reports is immutable';
+END//
+
+-- 已結帳日期的交易不可回溯寫入、修改或刪除
+CREATE TRIGGER IF NOT EXISTS transactions_closed_day_insert
+BEFORE INSERT ON transactions
+FOR EACH ROW
+BEGIN
+    IF EXISTS (SELECT 1 FROM reconciliation_closed_days WHERE close_date = DATE(COALESCE(NEW.created_at, NOW()))) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'transaction date is closed';
+    END IF;
+END//
+
+CREATE TRIGGER IF NOT EXISTS transactions_closed_day_update
+BEFORE UPDATE ON transactions
+FOR EACH ROW
+BEGIN
+    IF EXISTS (SELECT 1 FROM reconciliation_closed_days WHERE close_date IN (DATE(OLD.created_at), DATE(NEW.created_at))) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'transaction date is closed';
+    END IF;
+END//
+
+CREATE TRIGGER IF NOT EXISTS transactions_closed_day_delete
+BEFORE DELETE ON transactions
+FOR EACH ROW
+BEGIN
+    IF EXISTS (SELECT 1 FROM reconciliation_closed_days WHERE close_date = DATE(OLD.created_at)) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'transaction date is closed';
+    END IF;
+END//
+
+-- 已結帳日期不可回溯過帳
+CREATE TRIGGER IF NOT EXISTS ledger_journals_closed_day_insert
+BEFORE INSERT ON ledger_journals
+FOR EACH ROW
+BEGIN
+    IF EXISTS (SELECT 1 FROM reconciliation_closed_days WHERE close_date = DATE(COALESCE(NEW.created_at, NOW()))) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'journal date is closed';
+    END IF;
+END//
+DELIMITER ;
@@ -0,0 +1,95 @@
+-- 紅利引擎相關表結構
+-- 建立時間: 2026-10-19
+-- 紅利活動定義與自動發放（首儲、推薦、會員等級）、獨立的紅利餘額、各遊戲流水貢獻比例、牌桌帶入的紅利動用與紅利異動記錄
+
+USE nexus_gaming;
+
+-- 建立紅利活動表
+CREATE TABLE IF NOT EXISTS bonus_campaigns (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    campaign_code VARCHAR(50) NOT NULL UNIQUE COMMENT '活動代碼',
+    name VARCHAR(100) NOT NULL COMMENT '活動名稱',
+    description TEXT COMMENT '活動說明',
+    trigger_type ENUM('first_deposit', 'referral', 'loyalty_tier', 'manual') NOT NULL COMMENT '發放條件（首儲、推薦人於被推薦玩家首儲時、達到會員等級、人工）',
+    bonus_type ENUM('welcome', 'deposit', 'cashback', 'referral', 'loyalty', 'promotion', 'manual') NOT NULL COMMENT '發放的獎金類型',
+    currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '幣別',
+    fixed_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '固定金額',
+    percent DECIMAL(5,2) NOT NULL DEFAULT 0.00 COMMENT '儲值金額百分比（首儲與推薦活動）',
+    max_amount DECIMAL(15,2) NULL COMMENT '單筆發放上限',
+    min_deposit DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '最低儲值金額',
+    loyalty_tier INT NULL COMMENT '會員等級門檻（loyalty_tier 活動）',
+    wagering_requirement DECIMAL(5,2) NOT NULL DEFAULT 0.00 COMMENT '流水要求倍數',
+    valid_days INT NOT NULL DEFAULT 30 COMMENT '發放後有效天數',
+    consume_policy ENUM('bonus_first', 'cash_first') NOT NULL DEFAULT 'cash_first' COMMENT '紅利動用順序（先於或後於現金）',
+    starts_at TIMESTAMP NULL COMMENT '活動開始時間',
+    ends_at TIMESTAMP NULL COMMENT '活動結束時間',
+    status ENUM('active', 'paused', 'ended') NOT NULL DEFAULT 'active' COMMENT '活動狀態',
+    created_by INT NULL COMMENT '建立者ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_trigger (trigger_type, status, currency),
+    FOREIGN KEY (created_by) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='紅利活動表';
+
+-- 獎金記錄加上活動、紅利餘額與動用順序
+ALTER TABLE bonuses
+    ADD COLUMN campaign_id INT NULL COMMENT '紅利活動ID' AFTER player_id,
+    ADD COLUMN balance DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '紅利餘額（未轉為現金的紅利資金）' AFTER currency,
+    ADD COLUMN consume_policy ENUM('bonus_first', 'cash_first') NOT NULL DEFAULT 'cash_first' COMMENT '紅利動用順序' AFTER balance,
+    ADD COLUMN released_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '完成流水後轉為現金的金額' AFTER wagered_amount,
+    ADD COLUMN forfeited_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '沒收或逾期的紅利金額' AFTER released_amount,
+    ADD COLUMN forfeited_at TIMESTAMP NULL COMMENT '沒收或逾期時間' AFTER completed_at,
+    ADD UNIQUE KEY uk_campaign_grant (campaign_id, player_id, source_reference),
+    ADD INDEX idx_player_active (player_id, currency, status),
+    ADD FOREIGN KEY (campaign_id) REFERENCES bonus_campaigns(id);
+
+-- 建立遊戲流水貢獻比例表（未列出的遊戲類型不計入流水）
+CREATE TABLE IF NOT EXISTS bonus_game_contributions (
+    game_type VARCHAR(30) PRIMARY KEY COMMENT '遊戲類型（games.game_type）',
+    contribution_pct DECIMAL(5,2) NOT NULL DEFAULT 100.00 COMMENT '下注額計入流水的百分比',
+    updated_by INT NULL COMMENT '更新者ID',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (updated_by) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='遊戲流水貢獻比例表';
+
+INSERT IGNORE INTO bonus_game_contributions (game_type, contribution_pct) VALUES
+('slots', 100.00),
+('texas_holdem', 20.00),
+('stud_poker', 20.00),
+('roulette', 20.00),
+('baccarat', 10.00),
+('blackjack', 10.00);
+
+-- 建立紅利動用表（牌桌帶入時動用的紅利，離座時依剩餘籌碼比例返還）
+CREATE TABLE IF NOT EXISTS bonus_stakes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    bonus_id VARCHAR(64) NOT NULL COMMENT '獎金ID',
+    player_id BIGINT NOT NULL COMMENT '玩家ID',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    amount DECIMAL(15,2) NOT NULL COMMENT '動用金額',
+    returned_amount DECIMAL(15,2) NULL COMMENT '結清時返還的金額',
+    reference_type VARCHAR(50) NOT NULL COMMENT '參考類型',
+    reference_id VARCHAR(100) NOT NULL COMMENT '參考ID（房間代碼等）',
+    status ENUM('open', 'settled') NOT NULL DEFAULT 'open' COMMENT '狀態',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    settled_at TIMESTAMP NULL COMMENT '結清時間',
+    INDEX idx_reference (player_id, reference_type, reference_id, status),
+    INDEX idx_bonus_id (bonus_id),
+    FOREIGN KEY (bonus_id) REFERENCES bonuses(bonus_id),
+    FOREIGN KEY (player_id) REFERENCES players(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='紅利動用表';
+
+-- 建立紅利異動記錄表
+CREATE TABLE IF NOT EXISTS bonus_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    bonus_id VARCHAR(64) NOT NULL COMMENT '獎金ID',
+    event_type ENUM('grant', 'stake', 'return', 'wager', 'release', 'forfeit', 'expire') NOT NULL COMMENT '異動類型',
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '紅利餘額異動（正數增加、負數減少）',
+    wagered DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '計入的流水',
+    game_type VARCHAR(30) NULL COMMENT '遊戲類型',
+    reference_type VARCHAR(50) NULL COMMENT '參考類型',
+    reference_id VARCHAR(100) NULL COMMENT '參考ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_bonus_id (bonus_id, created_at),
+    FOREIGN KEY (bonus_id) REFERENCES bonuses(bonus_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='紅利異動記錄表';
@@ -0,0 +1,43 @@
+-- 支付方式保管相關表結構
+-- 建立時間: 2026-10-19
+-- 支付方式、提領帳戶與代理/荷官銀行帳戶的信封加密、遮罩帳號與帳號指紋、支付方式驗證與詐欺封鎖
+
+USE nexus_gaming;
+
+-- 支付方式：帳戶資訊改以信封加密保存，另存遮罩帳號與帳號指紋
+ALTER TABLE payment_methods
+    MODIFY COLUMN account_info JSON NOT NULL COMMENT '帳戶資訊（信封加密）',
+    ADD COLUMN masked_number VARCHAR(64) NULL COMMENT '遮罩後的帳號（僅保留末四碼）' AFTER account_info,
+    ADD COLUMN fingerprint CHAR(64) NULL COMMENT '帳號指紋（HMAC-SHA256，用於跨玩家比對）' AFTER masked_number,
+    ADD COLUMN verified_at TIMESTAMP NULL COMMENT '驗證時間' AFTER is_verified,
+    ADD COLUMN verified_by INT NULL COMMENT '驗證人員ID' AFTER verified_at,
+    ADD COLUMN blocked_reason VARCHAR(255) NULL COMMENT '封鎖原因' AFTER status,
+    ADD INDEX idx_fingerprint (fingerprint),
+    ADD FOREIGN KEY (verified_by) REFERENCES users(id) ON DELETE SET NULL;
+
+-- 建立詐欺封鎖帳號表
+CREATE TABLE IF NOT EXISTS blocked_payment_fingerprints (
+    fingerprint CHAR(64) PRIMARY KEY COMMENT '帳號指紋',
+    method_type VARCHAR(30) NOT NULL COMMENT '方式類型',
+    masked_number VARCHAR(64) NOT NULL COMMENT '遮罩後的帳號',
+    reason VARCHAR(255) NOT NULL COMMENT '封鎖原因',
+    reported_player_id BIGINT NULL COMMENT '通報時的玩家ID',
+    reported_method_id INT NULL COMMENT '通報時的支付方式ID',
+    blocked_by INT NULL COMMENT '封鎖人員ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reported_player_id) REFERENCES players(id) ON DELETE SET NULL,
+    FOREIGN KEY (blocked_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='詐欺封鎖帳號表';
+
+-- 提領：帳戶資訊改以信封加密保存，可指定使用的支付方式
+ALTER TABLE withdrawals
+    MODIFY COLUMN account_info JSON NOT NULL COMMENT '提領帳戶資訊（信封加密）',
+    ADD COLUMN payment_method_id INT NULL COMMENT '使用的支付方式ID' AFTER withdrawal_method,
+    ADD INDEX idx_payment_method (payment_method_id);
+
+-- 代理與荷官銀行帳戶改以信封加密保存
+ALTER TABLE agents
+    MODIFY COLUMN bank_account JSON COMMENT '銀行帳戶資訊（信封加密）';
+
+ALTER TABLE dealers
+    MODIFY COLUMN bank_account JSON COMMENT '銀行帳戶資訊（信封加密）';
@@ -0,0 +1,48 @@
+-- 代理商與經銷商管理相關表結構
+-- 建立時間: 2026-10-19
+-- 玩家所屬代理商/經銷商改為參照代理商與經銷商表、修正經銷商玩家數觸發器、狀態異動記錄
+
+USE nexus_gaming;
+
+-- 玩家的所屬代理商與經銷商原本參照使用者表，與經銷商玩家數觸發器不一致，改為參照代理商與經銷商表
+ALTER TABLE players
+    DROP FOREIGN KEY players_ibfk_2,
+    DROP FOREIGN KEY players_ibfk_3;
+
+ALTER TABLE players
+    ADD CONSTRAINT fk_players_agent FOREIGN KEY (agent_id) REFERENCES agents(id),
+    ADD CONSTRAINT fk_players_dealer FOREIGN KEY (dealer_id) REFERENCES dealers(id);
+
+-- 經銷商玩家數觸發器：原本以 != 比較，由 NULL 指派經銷商或移出經銷商時不會更新
+DROP TRIGGER IF EXISTS update_dealer_player_count_update;
+
+DELIMITER //
+CREATE TRIGGER update_dealer_player_count_update
+AFTER UPDATE ON players
+FOR EACH ROW
+BEGIN
+    IF NOT (OLD.dealer_id <=> NEW.dealer_id) THEN
+        IF OLD.dealer_id IS NOT NULL THEN
+            UPDATE dealers SET current_players = current_players - 1 WHERE id = OLD.dealer_id;
+        END IF;
+        IF NEW.dealer_id IS NOT NULL THEN
+            UPDATE dealers SET current_players = current_players + 1 WHERE id = NEW.dealer_id;
+        END IF;
+    END IF;
+END//
+DELIMITER ;
+
+-- 建立代理商/經銷商狀態異動記錄表
+CREATE TABLE IF NOT EXISTS agent_status_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    target_type ENUM('agent', 'dealer') NOT NULL COMMENT '對象類型',
+    target_id INT NOT NULL COMMENT '代理商或經銷商ID',
+    from_status VARCHAR(20) NOT NULL COMMENT '原狀態',
+    to_status VARCHAR(20) NOT NULL COMMENT '新狀態',
+    reason VARCHAR(255) COMMENT '原因',
+    details JSON COMMENT '終止時的經銷商與玩家移轉結果',
+    operator_id INT NULL COMMENT '操作人員ID（系統排程為NULL）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_target (target_type, target_id),
+    FOREIGN KEY (operator_id) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='代理商/經銷商狀態異動記錄表';
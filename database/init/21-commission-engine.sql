@@ -0,0 +1,62 @@
+-- 分潤計算相關表結構
+-- 建立時間: 2026-10-19
+-- 分潤配置的負營收結轉設定、分潤計算批次、佣金記錄改為對應代理商與經銷商並可追溯計算批次與來源交易
+
+USE nexus_gaming;
+
+-- 分潤配置：負營收是否結轉至下期沖抵
+ALTER TABLE commission_configs
+    ADD COLUMN negative_carryover BOOLEAN DEFAULT TRUE COMMENT '負淨營收是否結轉至下期沖抵' AFTER tier_config;
+
+-- 建立分潤計算批次表（每個對象、遊戲類型、幣別與期間一筆）
+CREATE TABLE IF NOT EXISTS commission_runs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    run_id VARCHAR(64) NOT NULL UNIQUE COMMENT '計算批次編號',
+    target_type ENUM('agent', 'dealer') NOT NULL COMMENT '對象類型',
+    target_id INT NOT NULL COMMENT '代理商或經銷商ID',
+    agent_id INT NOT NULL COMMENT '所屬代理商ID（代理商為自己）',
+    config_id INT NULL COMMENT '適用的分潤配置ID（NULL 表示使用基礎佣金比率）',
+    game_type VARCHAR(50) NOT NULL DEFAULT 'all' COMMENT '遊戲類型（all=未另外配置的遊戲類型）',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    period_start DATE NOT NULL COMMENT '期間開始',
+    period_end DATE NOT NULL COMMENT '期間結束',
+    commission_type ENUM('revenue_share', 'cpa', 'hybrid') NOT NULL COMMENT '佣金類型',
+    total_bets DECIMAL(20,2) DEFAULT 0.00 COMMENT '總下注',
+    total_wins DECIMAL(20,2) DEFAULT 0.00 COMMENT '總派彩（含退款）',
+    net_revenue DECIMAL(20,2) DEFAULT 0.00 COMMENT '本期淨營收',
+    carry_in DECIMAL(20,2) DEFAULT 0.00 COMMENT '上期結轉的負營收',
+    commissionable_revenue DECIMAL(20,2) DEFAULT 0.00 COMMENT '可計佣營收（淨營收加上期結轉）',
+    carry_out DECIMAL(20,2) DEFAULT 0.00 COMMENT '結轉至下期的負營收',
+    active_players INT DEFAULT 0 COMMENT '有下注的玩家數',
+    new_players INT DEFAULT 0 COMMENT '符合 CPA 的新玩家數',
+    qualified BOOLEAN DEFAULT TRUE COMMENT '是否達到最低玩家數與最低營收要求',
+    rate DECIMAL(5,4) DEFAULT 0.0000 COMMENT '適用的營收分成比率（含階梯）',
+    revenue_commission DECIMAL(15,2) DEFAULT 0.00 COMMENT '營收分成佣金',
+    cpa_commission DECIMAL(15,2) DEFAULT 0.00 COMMENT 'CPA 佣金',
+    override_commission DECIMAL(15,2) DEFAULT 0.00 COMMENT '上級代理商差額佣金合計',
+    details JSON COMMENT '計算明細（階梯、上級差額）',
+    created_by INT NULL COMMENT '觸發者ID（排程為NULL）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_run (target_type, target_id, game_type, currency, period_start, period_end),
+    INDEX idx_agent_id (agent_id),
+    INDEX idx_period (period_start, period_end),
+    FOREIGN KEY (agent_id) REFERENCES agents(id),
+    FOREIGN KEY (config_id) REFERENCES commission_configs(id) ON DELETE SET NULL,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分潤計算批次表';
+
+-- 佣金記錄：受益者原本參照使用者表，改為參照代理商表，並可指定經銷商為受益者
+ALTER TABLE commissions
+    DROP FOREIGN KEY commissions_ibfk_1;
+
+ALTER TABLE commissions
+    ADD COLUMN dealer_id INT NULL COMMENT '受益經銷商ID（NULL 表示受益者為代理商）' AFTER agent_id,
+    ADD COLUMN run_id BIGINT NULL COMMENT '計算批次ID' AFTER commission_id,
+    ADD COLUMN game_type VARCHAR(50) NULL COMMENT '來源交易的遊戲類型' AFTER commission_type,
+    ADD COLUMN override_level INT NOT NULL DEFAULT 0 COMMENT '0=直接佣金，n=第 n 層上級差額佣金' AFTER game_type,
+    ADD UNIQUE KEY unique_run_source (run_id, source_transaction_id, commission_type, override_level),
+    ADD INDEX idx_dealer_id (dealer_id),
+    ADD INDEX idx_source_transaction_id (source_transaction_id),
+    ADD CONSTRAINT fk_commissions_agent FOREIGN KEY (agent_id) REFERENCES agents(id),
+    ADD CONSTRAINT fk_commissions_dealer FOREIGN KEY (dealer_id) REFERENCES dealers(id),
+    ADD CONSTRAINT fk_commissions_run FOREIGN KEY (run_id) REFERENCES commission_runs(id) ON DELETE CASCADE;
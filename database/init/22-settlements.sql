@@ -0,0 +1,60 @@
+-- 代理商與經銷商結算相關表結構
+-- 建立時間: 2026-10-19
+-- 結算依幣別產生並保存版本、爭議與已支付金額，結算調整記錄、結算版本快照、佣金記錄對應結算
+
+USE nexus_gaming;
+
+-- 代理商結算：幣別、版本、已支付金額與爭議資訊
+ALTER TABLE agent_settlements
+    ADD COLUMN currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '幣別' AFTER settlement_period_end,
+    ADD COLUMN version INT NOT NULL DEFAULT 1 COMMENT '目前版本' AFTER status,
+    ADD COLUMN paid_amount DECIMAL(15,2) DEFAULT 0.00 COMMENT '已支付金額（爭議重算後只支付差額）' AFTER total_payout,
+    ADD COLUMN dispute_reason VARCHAR(255) NULL COMMENT '爭議原因' AFTER approved_at,
+    ADD COLUMN disputed_by INT NULL COMMENT '提出爭議者ID' AFTER dispute_reason,
+    ADD COLUMN disputed_at TIMESTAMP NULL COMMENT '提出爭議時間' AFTER disputed_by,
+    ADD UNIQUE KEY unique_agent_period (agent_id, settlement_period_start, settlement_period_end, currency),
+    ADD CONSTRAINT fk_agent_settlements_disputed_by FOREIGN KEY (disputed_by) REFERENCES users(id);
+
+-- 經銷商結算：幣別、版本、已支付金額與爭議資訊
+ALTER TABLE dealer_settlements
+    ADD COLUMN currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '幣別' AFTER settlement_period_end,
+    ADD COLUMN version INT NOT NULL DEFAULT 1 COMMENT '目前版本' AFTER status,
+    ADD COLUMN paid_amount DECIMAL(15,2) DEFAULT 0.00 COMMENT '已支付金額（爭議重算後只支付差額）' AFTER total_payout,
+    ADD COLUMN dispute_reason VARCHAR(255) NULL COMMENT '爭議原因' AFTER approved_at,
+    ADD COLUMN disputed_by INT NULL COMMENT '提出爭議者ID' AFTER dispute_reason,
+    ADD COLUMN disputed_at TIMESTAMP NULL COMMENT '提出爭議時間' AFTER disputed_by,
+    ADD UNIQUE KEY unique_dealer_period (dealer_id, settlement_period_start, settlement_period_end, currency),
+    ADD CONSTRAINT fk_dealer_settlements_disputed_by FOREIGN KEY (disputed_by) REFERENCES users(id);
+
+-- 佣金記錄：所屬結算（代理商與經銷商結算的結算ID不重複）
+ALTER TABLE commissions
+    ADD COLUMN settlement_id VARCHAR(64) NULL COMMENT '所屬結算ID' AFTER status,
+    ADD INDEX idx_settlement_id (settlement_id);
+
+-- 建立結算調整記錄表
+CREATE TABLE IF NOT EXISTS settlement_adjustments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    settlement_type ENUM('agent', 'dealer') NOT NULL COMMENT '結算類型',
+    settlement_id VARCHAR(64) NOT NULL COMMENT '結算ID',
+    amount DECIMAL(15,2) NOT NULL COMMENT '調整金額（負數為扣減）',
+    reason VARCHAR(255) NOT NULL COMMENT '調整原因',
+    created_by INT NOT NULL COMMENT '調整者ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_settlement (settlement_type, settlement_id),
+    FOREIGN KEY (created_by) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='結算調整記錄表';
+
+-- 建立結算版本表（每次計算、調整與爭議重算保存完整快照）
+CREATE TABLE IF NOT EXISTS settlement_versions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    settlement_type ENUM('agent', 'dealer') NOT NULL COMMENT '結算類型',
+    settlement_id VARCHAR(64) NOT NULL COMMENT '結算ID',
+    version INT NOT NULL COMMENT '版本',
+    status VARCHAR(20) NOT NULL COMMENT '快照時的狀態',
+    reason VARCHAR(255) NOT NULL COMMENT '產生此版本的原因',
+    snapshot JSON NOT NULL COMMENT '結算快照',
+    created_by INT NULL COMMENT '操作人員ID（排程為NULL）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_settlement_version (settlement_type, settlement_id, version),
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='結算版本表';
@@ -0,0 +1,25 @@
+-- 代理商與經銷商每日業績統計
+-- 建立時間: 2026-10-19
+-- 業績統計由交易、玩家與遊戲參與記錄重建，金額以統計幣別（預設幣別）依當日匯率換算，記錄統計幣別與重建時間
+
+USE nexus_gaming;
+
+-- 代理商業績統計：統計幣別、有下注玩家數（平均下注的分母）與重建時間
+ALTER TABLE agent_performance_stats
+    ADD COLUMN currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '統計幣別' AFTER stat_date,
+    ADD COLUMN betting_players INT DEFAULT 0 COMMENT '有下注的玩家數' AFTER new_players,
+    ADD COLUMN total_refunds DECIMAL(15,2) DEFAULT 0.00 COMMENT '總退款' AFTER total_wins,
+    ADD COLUMN total_bonuses DECIMAL(15,2) DEFAULT 0.00 COMMENT '總紅利' AFTER total_refunds,
+    ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '重建時間';
+
+-- 經銷商業績統計：同代理商
+ALTER TABLE dealer_performance_stats
+    ADD COLUMN currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '統計幣別' AFTER stat_date,
+    ADD COLUMN betting_players INT DEFAULT 0 COMMENT '有下注的玩家數' AFTER new_players,
+    ADD COLUMN total_refunds DECIMAL(15,2) DEFAULT 0.00 COMMENT '總退款' AFTER total_wins,
+    ADD COLUMN total_bonuses DECIMAL(15,2) DEFAULT 0.00 COMMENT '總紅利' AFTER total_refunds,
+    ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '重建時間';
+
+-- 依統計日期與代理商查詢經銷商業績（排名）
+ALTER TABLE dealer_performance_stats
+    ADD INDEX idx_agent_date (agent_id, stat_date);
@@ -0,0 +1,65 @@
+-- 玩家移轉相關表結構
+-- 建立時間: 2026-10-19
+-- 玩家歸屬歷程（佣金依交易當時的歸屬計算）、玩家移轉記錄、新玩家的初始歸屬觸發器
+
+USE nexus_gaming;
+
+-- 建立玩家歸屬歷程表（effective_from 起生效、effective_to 起失效，目前的歸屬 effective_to 為 NULL）
+CREATE TABLE IF NOT EXISTS player_attributions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    player_id BIGINT NOT NULL COMMENT '玩家ID',
+    agent_id INT NULL COMMENT '所屬代理商ID',
+    dealer_id INT NULL COMMENT '所屬經銷商ID',
+    effective_from TIMESTAMP NOT NULL COMMENT '生效時間',
+    effective_to TIMESTAMP NULL COMMENT '失效時間（目前的歸屬為NULL）',
+    batch_id VARCHAR(64) NULL COMMENT '產生此歸屬的移轉批次（初始歸屬為NULL）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_player_effective (player_id, effective_from),
+    INDEX idx_agent (agent_id, effective_from),
+    INDEX idx_dealer (dealer_id, effective_from),
+    FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE,
+    FOREIGN KEY (agent_id) REFERENCES agents(id),
+    FOREIGN KEY (dealer_id) REFERENCES dealers(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='玩家歸屬歷程表';
+
+-- 既有玩家以建立時間為起點建立初始歸屬
+INSERT INTO player_attributions (player_id, agent_id, dealer_id, effective_from)
+SELECT p.id, p.agent_id, p.dealer_id, p.created_at
+FROM players p
+WHERE NOT EXISTS (SELECT 1 FROM player_attributions pa WHERE pa.player_id = p.id);
+
+-- 建立玩家移轉記錄表
+CREATE TABLE IF NOT EXISTS player_transfers (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    batch_id VARCHAR(64) NOT NULL COMMENT '移轉批次編號（同一次請求的玩家相同）',
+    player_id BIGINT NOT NULL COMMENT '玩家ID',
+    from_agent_id INT NULL COMMENT '原代理商ID',
+    from_dealer_id INT NULL COMMENT '原經銷商ID',
+    to_agent_id INT NULL COMMENT '新代理商ID（釋出為NULL）',
+    to_dealer_id INT NULL COMMENT '新經銷商ID',
+    effective_at TIMESTAMP NOT NULL COMMENT '生效時間（此時間起的佣金歸新歸屬）',
+    source ENUM('manual', 'agent_termination', 'dealer_termination', 'dealer_move') NOT NULL DEFAULT 'manual' COMMENT '移轉來源',
+    reason VARCHAR(255) NOT NULL COMMENT '移轉原因',
+    created_by INT NULL COMMENT '操作人員ID（系統排程為NULL）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_batch_id (batch_id),
+    INDEX idx_player_id (player_id),
+    INDEX idx_from_agent (from_agent_id),
+    INDEX idx_from_dealer (from_dealer_id),
+    INDEX idx_to_agent (to_agent_id),
+    INDEX idx_to_dealer (to_dealer_id),
+    INDEX idx_created_at (created_at),
+    FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='玩家移轉記錄表';
+
+-- 新玩家建立時一併建立初始歸屬（之後的歸屬異動由移轉流程維護）
+DELIMITER //
+CREATE TRIGGER IF NOT EXISTS create_player_attribution_insert
+AFTER INSERT ON players
+FOR EACH ROW
+BEGIN
+    INSERT INTO player_attributions (player_id, agent_id, dealer_id, effective_from)
+    VALUES (NEW.id, NEW.agent_id, NEW.dealer_id, COALESCE(NEW.created_at, CURRENT_TIMESTAMP));
+END//
+DELIMITER ;
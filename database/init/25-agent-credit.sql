@@ -0,0 +1,79 @@
+-- 代理商籌碼與信用額度相關表結構
+-- 建立時間: 2026-10-19
+-- 代理商與經銷商的籌碼錢包與信用額度、錢包異動記錄、額度使用率警示，結算支付時抵扣的信用額度
+
+USE nexus_gaming;
+
+-- 總帳帳戶：新增經銷商帳戶類型（代理商與經銷商的籌碼餘額）
+ALTER TABLE ledger_accounts
+    MODIFY COLUMN account_type ENUM('player', 'house', 'bonus', 'agent', 'dealer') NOT NULL COMMENT '帳戶類型';
+
+-- 建立代理商錢包表（每個代理商或經銷商每個幣別一個錢包）
+CREATE TABLE IF NOT EXISTS agent_wallets (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    owner_type ENUM('agent', 'dealer') NOT NULL COMMENT '擁有者類型',
+    owner_id INT NOT NULL COMMENT '代理商或經銷商ID',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '持有籌碼',
+    credit_limit DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '信用額度（代理商含所有下級的使用額度）',
+    credit_used DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '自身已使用的信用額度（尚未償還）',
+    alert_level ENUM('none', 'warning', 'critical') NOT NULL DEFAULT 'none' COMMENT '目前的使用率警示等級',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_owner_currency (owner_type, owner_id, currency),
+    CHECK (balance >= 0),
+    CHECK (credit_limit >= 0),
+    CHECK (credit_used >= 0)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='代理商錢包表';
+
+-- 建立代理商錢包異動記錄表
+CREATE TABLE IF NOT EXISTS agent_wallet_transactions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    transaction_id VARCHAR(64) NOT NULL UNIQUE COMMENT '異動流水號',
+    owner_type ENUM('agent', 'dealer') NOT NULL COMMENT '擁有者類型',
+    owner_id INT NOT NULL COMMENT '代理商或經銷商ID',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    transaction_type ENUM('chip_purchase', 'credit_draw', 'credit_repayment', 'player_deposit', 'player_withdrawal', 'settlement_netting') NOT NULL COMMENT '異動類型',
+    amount DECIMAL(15,2) NOT NULL COMMENT '金額',
+    balance_before DECIMAL(15,2) NOT NULL COMMENT '異動前籌碼',
+    balance_after DECIMAL(15,2) NOT NULL COMMENT '異動後籌碼',
+    credit_used_before DECIMAL(15,2) NOT NULL COMMENT '異動前已使用額度',
+    credit_used_after DECIMAL(15,2) NOT NULL COMMENT '異動後已使用額度',
+    player_id BIGINT NULL COMMENT '玩家ID（玩家儲值與提領）',
+    player_transaction_id VARCHAR(64) NULL COMMENT '玩家交易流水號',
+    settlement_id VARCHAR(64) NULL COMMENT '結算ID（結算抵扣）',
+    journal_code VARCHAR(64) NOT NULL COMMENT '總帳傳票編號',
+    description VARCHAR(255) NULL COMMENT '說明',
+    operator_id INT NULL COMMENT '操作人員ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_owner (owner_type, owner_id, created_at),
+    INDEX idx_player_id (player_id),
+    INDEX idx_settlement_id (settlement_id),
+    FOREIGN KEY (player_id) REFERENCES players(id),
+    FOREIGN KEY (operator_id) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='代理商錢包異動記錄表';
+
+-- 建立信用額度警示表（使用率向上跨越門檻時產生）
+CREATE TABLE IF NOT EXISTS agent_credit_alerts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    owner_type ENUM('agent', 'dealer') NOT NULL COMMENT '擁有者類型',
+    owner_id INT NOT NULL COMMENT '代理商或經銷商ID',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    alert_level ENUM('warning', 'critical') NOT NULL COMMENT '警示等級',
+    utilisation DECIMAL(7,4) NOT NULL COMMENT '使用率',
+    exposure DECIMAL(15,2) NOT NULL COMMENT '已使用額度（代理商含所有下級）',
+    credit_limit DECIMAL(15,2) NOT NULL COMMENT '信用額度',
+    acknowledged_by INT NULL COMMENT '確認者ID',
+    acknowledged_at TIMESTAMP NULL COMMENT '確認時間',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_owner (owner_type, owner_id),
+    INDEX idx_created_at (created_at),
+    FOREIGN KEY (acknowledged_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='信用額度警示表';
+
+-- 結算：支付時以佣金抵扣的信用額度
+ALTER TABLE agent_settlements
+    ADD COLUMN credit_netted DECIMAL(15,2) DEFAULT 0.00 COMMENT '抵扣信用額度的金額（實際支付 = 已支付金額 - 抵扣金額）' AFTER paid_amount;
+
+ALTER TABLE dealer_settlements
+    ADD COLUMN credit_netted DECIMAL(15,2) DEFAULT 0.00 COMMENT '抵扣信用額度的金額（實際支付 = 已支付金額 - 抵扣金額）' AFTER paid_amount;
@@ -0,0 +1,9 @@
+-- 玩家遊戲歷史查詢索引
+-- 建立時間: 2026-10-19
+-- 玩家遊戲歷史以 (join_time, id) 做鍵集分頁，需要依玩家與加入時間排序的複合索引
+
+USE nexus_gaming;
+
+-- 遊戲參與記錄：玩家遊戲歷史分頁（InnoDB 次級索引隱含主鍵 id，可直接依 join_time, id 排序）
+ALTER TABLE game_participations
+    ADD INDEX idx_player_join_time (player_id, join_time);
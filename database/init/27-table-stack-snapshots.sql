@@ -0,0 +1,22 @@
+-- 牌桌籌碼快照表結構
+-- 建立時間: 2026-10-19
+-- 真錢牌桌只存在於記憶體，每手結束時記錄玩家籌碼；伺服器重新啟動時依最後快照結清帶入凍結與紅利帶入
+
+USE nexus_gaming;
+
+-- 建立牌桌籌碼快照表（入座時建立、每手結束時更新、離座結清時刪除）
+CREATE TABLE IF NOT EXISTS table_stack_snapshots (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    room_id BIGINT NOT NULL COMMENT '房間ID',
+    room_code VARCHAR(32) NOT NULL COMMENT '房間代碼（帶入凍結的參考ID）',
+    player_id BIGINT NOT NULL COMMENT '玩家ID',
+    currency VARCHAR(10) NOT NULL COMMENT '幣別',
+    stack DECIMAL(15,2) NOT NULL COMMENT '目前籌碼（入座時為帶入金額，之後為最後一手結束時的籌碼）',
+    session_id BIGINT NULL COMMENT '最後一手的場次ID（尚未打完任何一手為 NULL）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_room_player (room_code, player_id),
+    INDEX idx_player_id (player_id),
+    FOREIGN KEY (room_id) REFERENCES game_rooms(id),
+    FOREIGN KEY (player_id) REFERENCES players(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='牌桌籌碼快照表';